@@ -7,7 +7,4 @@ require (
 	k8s.io/utils v0.0.0-20260108192941-914a6e750570
 )
 
-require (
-	github.com/tylergannon/go-signal v0.1.0 // indirect
-	golang.org/x/sys v0.40.0 // indirect
-)
+require golang.org/x/sys v0.40.0 // indirect
@@ -0,0 +1,210 @@
+package internal
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tylergannon/svelte-check-server/pkg/types"
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// GitHub Actions
+// =============================================================================
+
+// Files GitHub Actions names in the environment of a step: step outputs are
+// appended to GITHUB_OUTPUT and the job summary's markdown to
+// GITHUB_STEP_SUMMARY.
+const (
+	EnvGitHubOutput      = "GITHUB_OUTPUT"
+	EnvGitHubStepSummary = "GITHUB_STEP_SUMMARY"
+)
+
+// maxSummaryDiagnostics bounds the diagnostics listed in a job summary, which
+// GitHub truncates at 1MiB; the annotations still cover every one.
+const maxSummaryDiagnostics = 50
+
+// WriteAnnotations writes a workflow command for each of result's
+// diagnostics and failures, which GitHub shows as an annotation on the
+// diagnostic's file and line.
+func WriteAnnotations(w io.Writer, result SvelteWatchCheckComplete) {
+	for _, d := range result.Diagnostics {
+		level := "error"
+		if d.Type == "WARNING" {
+			level = "warning"
+		}
+		title := cmp.Or(d.Checker, "svelte-check")
+		if d.Code != nil {
+			title += fmt.Sprintf(" (%v)", d.Code)
+		}
+		fmt.Fprintf(w, "::%s file=%s,line=%d,col=%d,endLine=%d,endColumn=%d,title=%s::%s\n",
+			level, escapeProperty(d.Filename),
+			d.Start.Line+1, d.Start.Character+1, d.End.Line+1, d.End.Character+1, // 0-based
+			escapeProperty(title), escapeData(d.Message))
+	}
+	for _, f := range result.Failures {
+		fmt.Fprintf(w, "::error title=svelte-check failure::%s\n", escapeData(f))
+	}
+}
+
+// escapeData escapes the message of a workflow command.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// FormatJobSummary returns the markdown of a job summary for result: the
+// verdict and counts, and a table of the first maxSummaryDiagnostics
+// diagnostics.
+func FormatJobSummary(result SvelteWatchCheckComplete, verdict Severity) string {
+	var sb strings.Builder
+	if verdict == "" {
+		sb.WriteString("## ✅ svelte-check passed\n\n")
+	} else {
+		fmt.Fprintf(&sb, "## ❌ svelte-check failed on %ss\n\n", verdict)
+	}
+	fmt.Fprintf(&sb, "| Errors | Warnings | Files checked | Files with problems |\n|---:|---:|---:|---:|\n| %d | %d | %d | %d |\n",
+		result.ErrorCount, result.WarningCount, result.FileCount, result.FilesWithProblems)
+	if n := result.Baselined + result.Suppressed; n > 0 {
+		fmt.Fprintf(&sb, "\n%d baselined or ignored diagnostics are not counted.\n", n)
+	}
+
+	if len(result.Failures) > 0 {
+		sb.WriteString("\n### Failures\n\n")
+		for _, f := range result.Failures {
+			fmt.Fprintf(&sb, "- %s\n", escapeMarkdown(f))
+		}
+	}
+	if len(result.Diagnostics) > 0 {
+		sb.WriteString("\n### Diagnostics\n\n| | File | Message |\n|---|---|---|\n")
+		for i, d := range result.Diagnostics {
+			if i == maxSummaryDiagnostics {
+				fmt.Fprintf(&sb, "\n…and %d more; see the annotations.\n", len(result.Diagnostics)-i)
+				break
+			}
+			icon := "❌"
+			if d.Type == "WARNING" {
+				icon = "⚠️"
+			}
+			fmt.Fprintf(&sb, "| %s | `%s:%d:%d` | %s |\n", icon, d.Filename, d.Start.Line+1, d.Start.Character+1, escapeMarkdown(d.Message))
+		}
+	}
+	return sb.String()
+}
+
+// escapeMarkdown keeps a message on one line of a markdown table.
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("|", `\|`, "\r", "", "\n", "<br>").Replace(s)
+}
+
+// GitHubOutputs returns the step outputs for result, one name=value line
+// each.
+func GitHubOutputs(result SvelteWatchCheckComplete) string {
+	return fmt.Sprintf("error_count=%d\nwarning_count=%d\n", result.ErrorCount, result.WarningCount)
+}
+
+// appendGitHubFile appends content to the file named by the environment
+// variable env, if it is set, as GitHub Actions expects of a step.
+func appendGitHubFile(env, content string) error {
+	path := os.Getenv(env)
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(content)
+	return errors.Join(err, f.Close())
+}
+
+// =============================================================================
+// One-Shot Checks
+// =============================================================================
+
+// checkOnce runs the checkers lc configures until each has completed one
+// check, and returns their merged result as /check would serve it: with
+// ignore rules and the workspace's baseline applied. only, if set, selects a
+// single project. svelte-kit sync runs first unless lc disables it, since a
+// fresh clone has no generated types.
+func checkOnce(ctx context.Context, lc launchConfig, only string, executor kexec.Interface) (SvelteWatchCheckComplete, error) {
+	workspace := lc.runner.WorkspacePath
+	if lc.syncOnStart {
+		NewSyncTracker(workspace, lc.runner.PackageManager, executor).SyncAll(ctx, projectDirs(lc.projects))
+	}
+
+	var projects []Project
+	if len(lc.projects) == 0 {
+		if only != "" {
+			return SvelteWatchCheckComplete{}, fmt.Errorf("%w %q: no projects configured", ErrUnknownProject, only)
+		}
+		projects = []Project{{Runner: lc.newChecker(lc.runner, executor)}}
+	}
+	for i, c := range ProjectRunnerConfigs(lc.runner, lc.projects) {
+		if p := lc.projects[i]; only == "" || p.Name == only {
+			projects = append(projects, Project{Name: p.Name, Dir: p.Dir, Runner: lc.newChecker(c, executor)})
+		}
+	}
+	if len(projects) == 0 {
+		return SvelteWatchCheckComplete{}, fmt.Errorf("%w %q", ErrUnknownProject, only)
+	}
+
+	for _, p := range projects {
+		defer p.Runner.Stop()
+		if err := p.Runner.Start(ctx); err != nil {
+			return SvelteWatchCheckComplete{}, err
+		}
+	}
+	if err := waitReady(ctx, projects, lc.startupTimeout); err != nil {
+		return SvelteWatchCheckComplete{}, err
+	}
+	results := make([]SvelteWatchCheckComplete, 0, len(projects))
+	for _, p := range projects {
+		result, err := p.Runner.GetLatestEvent(ctx)
+		if err != nil {
+			return SvelteWatchCheckComplete{}, err
+		}
+		results = append(results, p.qualify(result))
+	}
+
+	result := MergeResults(results)
+	if len(results) == 1 {
+		result = results[0]
+	}
+	same := func(result SvelteWatchCheckComplete) SvelteWatchCheckComplete { return result }
+	result = lc.ignore.Apply(result, same)
+	baseline, err := LoadBaseline(filepath.Join(workspace, BaselineFileName))
+	switch {
+	case err == nil:
+		result = baseline.Apply(result, same)
+	case !errors.Is(err, fs.ErrNotExist):
+		return SvelteWatchCheckComplete{}, err
+	}
+	result.SchemaVersion = types.SchemaVersion
+	return result, nil
+}
+
+// writeReport writes result to path as JSON, or as a markdown summary if
+// path ends in .md.
+func writeReport(path string, result SvelteWatchCheckComplete, verdict Severity) error {
+	if strings.EqualFold(filepath.Ext(path), ".md") {
+		return os.WriteFile(path, []byte(FormatJobSummary(result, verdict)), 0o644)
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteAnnotations(t *testing.T) {
+	var sb strings.Builder
+	WriteAnnotations(&sb, SvelteWatchCheckComplete{
+		Diagnostics: []Diagnostic{
+			{Type: "ERROR", Filename: "src/a,b.ts", Start: Position{Line: 4, Character: 2}, End: Position{Line: 4, Character: 9}, Message: "Type 'string'\nis not 100% assignable", Code: 2322},
+			{Type: "WARNING", Filename: "src/App.svelte", Message: "Unused CSS selector", Code: "css_unused_selector", Checker: "svelte-check"},
+		},
+		Failures: []string{"language server crashed"},
+	})
+
+	want := "::error file=src/a%2Cb.ts,line=5,col=3,endLine=5,endColumn=10,title=svelte-check (2322)::Type 'string'%0Ais not 100%25 assignable\n" +
+		"::warning file=src/App.svelte,line=1,col=1,endLine=1,endColumn=1,title=svelte-check (css_unused_selector)::Unused CSS selector\n" +
+		"::error title=svelte-check failure::language server crashed\n"
+	if sb.String() != want {
+		t.Errorf("WriteAnnotations() =\n%s\nwant\n%s", sb.String(), want)
+	}
+}
+
+func TestFormatJobSummary(t *testing.T) {
+	result := SvelteWatchCheckComplete{ErrorCount: maxSummaryDiagnostics + 2, FileCount: 10, FilesWithProblems: 1}
+	for range result.ErrorCount {
+		result.Diagnostics = append(result.Diagnostics, Diagnostic{Type: "ERROR", Filename: "src/a.ts", Message: "a | b"})
+	}
+	out := FormatJobSummary(result, SeverityError)
+	for _, want := range []string{
+		"## ❌ svelte-check failed on errors\n",
+		"| 52 | 0 | 10 | 1 |\n",
+		"| ❌ | `src/a.ts:1:1` | a \\| b |\n",
+		"…and 2 more; see the annotations.\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("FormatJobSummary() missing %q in:\n%s", want, out)
+		}
+	}
+	if got := strings.Count(out, "src/a.ts"); got != maxSummaryDiagnostics {
+		t.Errorf("summary lists %d diagnostics, want %d", got, maxSummaryDiagnostics)
+	}
+
+	if out := FormatJobSummary(SvelteWatchCheckComplete{}, ""); !strings.HasPrefix(out, "## ✅ svelte-check passed\n") {
+		t.Errorf("FormatJobSummary() = %q, want passed", out)
+	}
+}
+
+func TestAppendGitHubFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output")
+	t.Setenv(EnvGitHubOutput, path)
+	if err := os.WriteFile(path, []byte("other=1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendGitHubFile(EnvGitHubOutput, GitHubOutputs(SvelteWatchCheckComplete{ErrorCount: 3, WarningCount: 1})); err != nil {
+		t.Fatalf("appendGitHubFile failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if want := "other=1\nerror_count=3\nwarning_count=1\n"; string(data) != want {
+		t.Errorf("outputs = %q, want %q", data, want)
+	}
+
+	t.Setenv(EnvGitHubStepSummary, "")
+	if err := appendGitHubFile(EnvGitHubStepSummary, "ignored"); err != nil {
+		t.Errorf("appendGitHubFile without the variable = %v, want nil", err)
+	}
+}
+
+// TestCheckOnce tests that a one-shot check stops after the first result and
+// applies the workspace's baseline.
+func TestCheckOnce(t *testing.T) {
+	checkLeaks(t)
+	workspace := t.TempDir()
+	old := Diagnostic{Type: "ERROR", Filename: "src/old.ts", Message: "Old error", Code: 2322.0}
+	if err := WriteBaseline(filepath.Join(workspace, BaselineFileName), NewBaseline([]Diagnostic{old})); err != nil {
+		t.Fatal(err)
+	}
+	output := `1770255834000 START "/workspace"
+1770255834100 {"type":"ERROR","filename":"src/old.ts","start":{"line":0,"character":0},"end":{"line":0,"character":1},"message":"Old error","code":2322}
+1770255834200 {"type":"ERROR","filename":"src/new.ts","start":{"line":2,"character":0},"end":{"line":2,"character":1},"message":"New error","code":2322}
+1770255834300 COMPLETED 10 FILES 2 ERRORS 0 WARNINGS 2 FILES_WITH_PROBLEMS
+`
+	lc := launchConfig{runner: RunnerConfig{WorkspacePath: workspace}, policy: DefaultCheckPolicy}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := checkOnce(ctx, lc, "", NewFakeExecutor(output, ""))
+	if err != nil {
+		t.Fatalf("checkOnce failed: %v", err)
+	}
+	if result.ErrorCount != 1 || result.Baselined != 1 || len(result.Diagnostics) != 1 || result.Diagnostics[0].Filename != "src/new.ts" {
+		t.Errorf("result = %+v, want the new error only", result)
+	}
+	if verdict := lc.policy.Verdict(result); verdict != SeverityError {
+		t.Errorf("Verdict() = %q, want error", verdict)
+	}
+
+	if _, err := checkOnce(ctx, lc, "web", NewFakeExecutor(output, "")); err == nil {
+		t.Error("checkOnce with an unknown project succeeded, want an error")
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	dir := t.TempDir()
+	result := SvelteWatchCheckComplete{Timestamp: 1770255834000, ErrorCount: 2}
+	if err := writeReport(filepath.Join(dir, "report.json"), result, SeverityError); err != nil {
+		t.Fatalf("writeReport failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "report.json"))
+	if !strings.Contains(string(data), `"errorCount": 2`) {
+		t.Errorf("JSON report = %s", data)
+	}
+
+	if err := writeReport(filepath.Join(dir, "report.md"), result, SeverityError); err != nil {
+		t.Fatalf("writeReport failed: %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "report.md"))
+	if !strings.HasPrefix(string(data), "## ❌ svelte-check failed on errors") {
+		t.Errorf("markdown report = %s", data)
+	}
+}
@@ -0,0 +1,439 @@
+package internal
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// Review Comments
+// =============================================================================
+
+// reviewMarker begins the key hidden at the end of each comment's body, by
+// which the comments of an earlier run are recognized.
+const reviewMarker = "<!-- svelte-check-server:"
+
+// ReviewComment is a comment on one line of a pull request's new version.
+type ReviewComment struct {
+	Path string // relative to the repository root
+	Line int    // 1-based
+	Body string
+}
+
+// ReviewProvider reads and comments on a pull or merge request.
+type ReviewProvider interface {
+	// ChangedLines returns the lines the request adds or changes, by path.
+	ChangedLines(ctx context.Context) (map[string]map[int]bool, error)
+
+	// CommentBodies returns the bodies of the request's comments.
+	CommentBodies(ctx context.Context) ([]string, error)
+
+	// Comment posts c.
+	Comment(ctx context.Context, c ReviewComment) error
+}
+
+// AnnotateResult counts what Annotate did with a result's diagnostics.
+type AnnotateResult struct {
+	Posted      int `json:"posted"`
+	Duplicates  int `json:"duplicates"`  // commented on by an earlier run
+	OutsideDiff int `json:"outsideDiff"` // on lines the request does not change
+}
+
+// Annotate comments on each of result's diagnostics that is on a line the
+// request changes, unless an earlier run already did. prefix is the
+// workspace's directory within the repository, e.g. "apps/web", which
+// diagnostics' workspace-relative filenames lack.
+func Annotate(ctx context.Context, p ReviewProvider, result SvelteWatchCheckComplete, prefix string) (AnnotateResult, error) {
+	var res AnnotateResult
+	changed, err := p.ChangedLines(ctx)
+	if err != nil {
+		return res, fmt.Errorf("listing changed lines: %w", err)
+	}
+	bodies, err := p.CommentBodies(ctx)
+	if err != nil {
+		return res, fmt.Errorf("listing comments: %w", err)
+	}
+	posted := commentKeys(bodies)
+
+	for _, d := range result.Diagnostics {
+		file := path.Join(prefix, d.Filename)
+		line := d.Start.Line + 1 // 0-based
+		if !changed[file][line] {
+			res.OutsideDiff++
+			continue
+		}
+		key := reviewKey(file, line, d)
+		if posted[key] {
+			res.Duplicates++
+			continue
+		}
+		if err := p.Comment(ctx, ReviewComment{Path: file, Line: line, Body: reviewBody(d, key)}); err != nil {
+			return res, fmt.Errorf("commenting on %s:%d: %w", file, line, err)
+		}
+		posted[key] = true
+		res.Posted++
+	}
+	return res, nil
+}
+
+// reviewKey identifies a diagnostic on a line, so that it is commented on
+// once however often the request is annotated.
+func reviewKey(file string, line int, d Diagnostic) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%d\x00%s\x00%v\x00%s", file, line, d.Type, d.Code, d.Message))
+	return hex.EncodeToString(sum[:8])
+}
+
+// reviewBody returns the markdown of d's comment, ending in key.
+func reviewBody(d Diagnostic, key string) string {
+	severity := "Error"
+	if d.Type == "WARNING" {
+		severity = "Warning"
+	}
+	source := cmp.Or(d.Checker, "svelte-check")
+	if d.Code != nil {
+		source += fmt.Sprintf(" (%v)", d.Code)
+	}
+	return fmt.Sprintf("**%s** from %s:\n\n%s\n\n%s%s -->", severity, source, d.Message, reviewMarker, key)
+}
+
+// reviewKeyPattern finds the keys of comment bodies.
+var reviewKeyPattern = regexp.MustCompile(regexp.QuoteMeta(reviewMarker) + `([0-9a-f]+) -->`)
+
+// commentKeys returns the keys found in bodies.
+func commentKeys(bodies []string) map[string]bool {
+	keys := make(map[string]bool)
+	for _, body := range bodies {
+		for _, m := range reviewKeyPattern.FindAllStringSubmatch(body, -1) {
+			keys[m[1]] = true
+		}
+	}
+	return keys
+}
+
+// addedLines returns the new version's numbers of the lines a unified diff
+// adds.
+func addedLines(diff string) map[int]bool {
+	lines := make(map[int]bool)
+	next := 0 // the next new line's number; 0 outside a hunk
+	for line := range strings.SplitSeq(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			// @@ -12,4 +12,5 @@ optional section heading
+			next = 0
+			if _, after, ok := strings.Cut(line, " +"); ok {
+				start, _, _ := strings.Cut(after, " ")
+				start, _, _ = strings.Cut(start, ",")
+				next, _ = strconv.Atoi(start)
+			}
+		case next == 0, strings.HasPrefix(line, "-"), strings.HasPrefix(line, `\`):
+			// Outside a hunk, a removed line, or "\ No newline at end of file".
+		case strings.HasPrefix(line, "+"):
+			lines[next] = true
+			next++
+		default:
+			next++
+		}
+	}
+	return lines
+}
+
+// repoPrefix returns the workspace's directory within its git repository,
+// e.g. "apps/web", or "" at the root.
+func repoPrefix(executor kexec.Interface, workspace string) (string, error) {
+	cmd := executor.Command("git", "rev-parse", "--show-prefix")
+	cmd.SetDir(workspace)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse --show-prefix: %w", err)
+	}
+	return strings.TrimSuffix(strings.TrimSpace(string(out)), "/"), nil
+}
+
+// =============================================================================
+// Providers
+// =============================================================================
+
+// Review providers for NewReviewProvider.
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+)
+
+// NewReviewProvider returns the provider for request number pr of repo,
+// taking what is not given from the CI environment: for GitHub,
+// GITHUB_TOKEN, GITHUB_REPOSITORY ("owner/name"), GITHUB_API_URL, and the
+// pull request of GITHUB_REF; for GitLab, GITLAB_TOKEN, CI_PROJECT_ID,
+// CI_API_V4_URL, and CI_MERGE_REQUEST_IID.
+func NewReviewProvider(provider, repo string, pr int) (ReviewProvider, error) {
+	switch provider {
+	case ProviderGitHub:
+		g := &GitHubReviews{
+			APIURL: cmp.Or(os.Getenv("GITHUB_API_URL"), "https://api.github.com"),
+			Repo:   cmp.Or(repo, os.Getenv("GITHUB_REPOSITORY")),
+			PR:     pr,
+			Token:  os.Getenv("GITHUB_TOKEN"),
+		}
+		if g.PR == 0 {
+			// refs/pull/123/merge in workflows run for pull requests.
+			if n, ok := strings.CutPrefix(os.Getenv("GITHUB_REF"), "refs/pull/"); ok {
+				n, _, _ = strings.Cut(n, "/")
+				g.PR, _ = strconv.Atoi(n)
+			}
+		}
+		return g, requireReview(g.Repo, "GITHUB_REPOSITORY", g.PR, g.Token, "GITHUB_TOKEN")
+	case ProviderGitLab:
+		g := &GitLabReviews{
+			APIURL:  cmp.Or(os.Getenv("CI_API_V4_URL"), "https://gitlab.com/api/v4"),
+			Project: cmp.Or(repo, os.Getenv("CI_PROJECT_ID")),
+			MR:      pr,
+			Token:   os.Getenv("GITLAB_TOKEN"),
+		}
+		if g.MR == 0 {
+			g.MR, _ = strconv.Atoi(os.Getenv("CI_MERGE_REQUEST_IID"))
+		}
+		return g, requireReview(g.Project, "CI_PROJECT_ID", g.MR, g.Token, "GITLAB_TOKEN")
+	}
+	return nil, fmt.Errorf("unknown provider %q: want github or gitlab", provider)
+}
+
+// requireReview reports a missing repository, request number, or token.
+func requireReview(repo, repoEnv string, pr int, token, tokenEnv string) error {
+	switch {
+	case repo == "":
+		return fmt.Errorf("no repository: pass --repo or set %s", repoEnv)
+	case pr <= 0:
+		return errors.New("no pull or merge request: pass --pr")
+	case token == "":
+		return fmt.Errorf("no token: set %s", tokenEnv)
+	}
+	return nil
+}
+
+// GitHubReviews comments on a GitHub pull request through the REST API.
+type GitHubReviews struct {
+	APIURL string // e.g. https://api.github.com
+	Repo   string // owner/name
+	PR     int
+	Token  string
+	Client *http.Client // http.DefaultClient if nil
+
+	headSHA string // the commit comments are made on, once fetched
+}
+
+func (g *GitHubReviews) url(format string, args ...any) string {
+	return fmt.Sprintf("%s/repos/%s/pulls/%d", strings.TrimSuffix(g.APIURL, "/"), g.Repo, g.PR) + fmt.Sprintf(format, args...)
+}
+
+func (g *GitHubReviews) header() http.Header {
+	return http.Header{
+		"Authorization":        {"Bearer " + g.Token},
+		"Accept":               {"application/vnd.github+json"},
+		"X-Github-Api-Version": {"2022-11-28"},
+	}
+}
+
+// ChangedLines returns the lines the pull request's files add.
+func (g *GitHubReviews) ChangedLines(ctx context.Context) (map[string]map[int]bool, error) {
+	files, err := getPages[struct {
+		Filename string `json:"filename"`
+		Patch    string `json:"patch"`
+	}](ctx, g.Client, g.url("/files"), g.header())
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]map[int]bool, len(files))
+	for _, f := range files {
+		changed[f.Filename] = addedLines(f.Patch)
+	}
+	return changed, nil
+}
+
+// CommentBodies returns the bodies of the pull request's review comments.
+func (g *GitHubReviews) CommentBodies(ctx context.Context) ([]string, error) {
+	comments, err := getPages[struct {
+		Body string `json:"body"`
+	}](ctx, g.Client, g.url("/comments"), g.header())
+	bodies := make([]string, len(comments))
+	for i, c := range comments {
+		bodies[i] = c.Body
+	}
+	return bodies, err
+}
+
+// Comment posts c as a review comment on the pull request's head commit.
+func (g *GitHubReviews) Comment(ctx context.Context, c ReviewComment) error {
+	if g.headSHA == "" {
+		var pr struct {
+			Head struct {
+				SHA string `json:"sha"`
+			} `json:"head"`
+		}
+		if err := requestJSON(ctx, g.Client, http.MethodGet, g.url(""), g.header(), nil, &pr); err != nil {
+			return err
+		}
+		g.headSHA = pr.Head.SHA
+	}
+	body := map[string]any{"body": c.Body, "commit_id": g.headSHA, "path": c.Path, "line": c.Line, "side": "RIGHT"}
+	return requestJSON(ctx, g.Client, http.MethodPost, g.url("/comments"), g.header(), body, nil)
+}
+
+// GitLabReviews comments on a GitLab merge request through the REST API.
+type GitLabReviews struct {
+	APIURL  string // e.g. https://gitlab.com/api/v4
+	Project string // ID or "group/name"
+	MR      int    // the merge request's IID
+	Token   string
+	Client  *http.Client // http.DefaultClient if nil
+
+	refs *gitLabDiffRefs // the versions comments are positioned in, once fetched
+}
+
+type gitLabDiffRefs struct {
+	BaseSHA  string `json:"base_sha"`
+	StartSHA string `json:"start_sha"`
+	HeadSHA  string `json:"head_sha"`
+}
+
+func (g *GitLabReviews) url(format string, args ...any) string {
+	return fmt.Sprintf("%s/projects/%s/merge_requests/%d", strings.TrimSuffix(g.APIURL, "/"), url.PathEscape(g.Project), g.MR) + fmt.Sprintf(format, args...)
+}
+
+func (g *GitLabReviews) header() http.Header {
+	return http.Header{"Private-Token": {g.Token}}
+}
+
+// ChangedLines returns the lines the merge request's diffs add.
+func (g *GitLabReviews) ChangedLines(ctx context.Context) (map[string]map[int]bool, error) {
+	diffs, err := getPages[struct {
+		NewPath     string `json:"new_path"`
+		Diff        string `json:"diff"`
+		DeletedFile bool   `json:"deleted_file"`
+	}](ctx, g.Client, g.url("/diffs"), g.header())
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]map[int]bool, len(diffs))
+	for _, d := range diffs {
+		if !d.DeletedFile {
+			changed[d.NewPath] = addedLines(d.Diff)
+		}
+	}
+	return changed, nil
+}
+
+// CommentBodies returns the bodies of the merge request's notes.
+func (g *GitLabReviews) CommentBodies(ctx context.Context) ([]string, error) {
+	notes, err := getPages[struct {
+		Body string `json:"body"`
+	}](ctx, g.Client, g.url("/notes"), g.header())
+	bodies := make([]string, len(notes))
+	for i, n := range notes {
+		bodies[i] = n.Body
+	}
+	return bodies, err
+}
+
+// Comment starts a discussion on c's line of the merge request's latest
+// version.
+func (g *GitLabReviews) Comment(ctx context.Context, c ReviewComment) error {
+	if g.refs == nil {
+		var mr struct {
+			DiffRefs gitLabDiffRefs `json:"diff_refs"`
+		}
+		if err := requestJSON(ctx, g.Client, http.MethodGet, g.url(""), g.header(), nil, &mr); err != nil {
+			return err
+		}
+		g.refs = &mr.DiffRefs
+	}
+	body := map[string]any{
+		"body": c.Body,
+		"position": map[string]any{
+			"position_type": "text",
+			"base_sha":      g.refs.BaseSHA,
+			"start_sha":     g.refs.StartSHA,
+			"head_sha":      g.refs.HeadSHA,
+			"old_path":      c.Path,
+			"new_path":      c.Path,
+			"new_line":      c.Line,
+		},
+	}
+	return requestJSON(ctx, g.Client, http.MethodPost, g.url("/discussions"), g.header(), body, nil)
+}
+
+// =============================================================================
+// REST Requests
+// =============================================================================
+
+// reviewPageSize is how many items each page of a listing requests; GitHub
+// and GitLab allow at most 100.
+const reviewPageSize = 100
+
+// getPages GETs the pages of a listing, which both GitHub and GitLab number
+// with ?page=, until one comes back short.
+func getPages[T any](ctx context.Context, client *http.Client, listURL string, header http.Header) ([]T, error) {
+	var all []T
+	for page := 1; ; page++ {
+		var items []T
+		pageURL := fmt.Sprintf("%s?per_page=%d&page=%d", listURL, reviewPageSize, page)
+		if err := requestJSON(ctx, client, http.MethodGet, pageURL, header, nil, &items); err != nil {
+			return all, err
+		}
+		all = append(all, items...)
+		if len(items) < reviewPageSize {
+			return all, nil
+		}
+	}
+}
+
+// requestJSON sends in, if not nil, as JSON and decodes the response into
+// out, if not nil, failing on a non-2xx response.
+func requestJSON(ctx context.Context, client *http.Client, method, url string, header http.Header, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header = header.Clone()
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", method, req.URL.Path, resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAddedLines(t *testing.T) {
+	diff := "@@ -1,3 +1,4 @@ <script>\n context\n-removed\n+added 2\n+added 3\n context\n\\ No newline at end of file\n@@ -20 +21,2 @@\n+added 21\n context"
+	got := addedLines(diff)
+	if len(got) != 3 || !got[2] || !got[3] || !got[21] {
+		t.Errorf("addedLines() = %v, want 2, 3, and 21", got)
+	}
+}
+
+// fakeReviews is a ReviewProvider recording the comments posted.
+type fakeReviews struct {
+	changed map[string]map[int]bool
+	bodies  []string
+	posted  []ReviewComment
+}
+
+func (f *fakeReviews) ChangedLines(context.Context) (map[string]map[int]bool, error) {
+	return f.changed, nil
+}
+
+func (f *fakeReviews) CommentBodies(context.Context) ([]string, error) { return f.bodies, nil }
+
+func (f *fakeReviews) Comment(_ context.Context, c ReviewComment) error {
+	f.posted = append(f.posted, c)
+	return nil
+}
+
+// TestAnnotate tests that only diagnostics on changed lines are commented
+// on, and only once across runs.
+func TestAnnotate(t *testing.T) {
+	result := SvelteWatchCheckComplete{Diagnostics: []Diagnostic{
+		{Type: "ERROR", Filename: "src/a.ts", Start: Position{Line: 1}, Code: 2322, Message: "Type mismatch"},
+		{Type: "WARNING", Filename: "src/a.ts", Start: Position{Line: 9}, Message: "Unchanged line"},
+		{Type: "ERROR", Filename: "src/b.ts", Start: Position{Line: 1}, Message: "Unchanged file"},
+	}}
+	reviews := &fakeReviews{changed: map[string]map[int]bool{"apps/web/src/a.ts": {2: true}}}
+
+	got, err := Annotate(context.Background(), reviews, result, "apps/web")
+	if err != nil {
+		t.Fatalf("Annotate failed: %v", err)
+	}
+	if got != (AnnotateResult{Posted: 1, OutsideDiff: 2}) {
+		t.Errorf("Annotate() = %+v, want 1 posted and 2 outside the diff", got)
+	}
+	if len(reviews.posted) != 1 {
+		t.Fatalf("posted %d comments, want 1", len(reviews.posted))
+	}
+	c := reviews.posted[0]
+	if c.Path != "apps/web/src/a.ts" || c.Line != 2 || !strings.HasPrefix(c.Body, "**Error** from svelte-check (2322):\n\nType mismatch") {
+		t.Errorf("comment = %+v", c)
+	}
+
+	// The next run finds its comment.
+	reviews.bodies, reviews.posted = []string{"LGTM", c.Body}, nil
+	got, _ = Annotate(context.Background(), reviews, result, "apps/web")
+	if got != (AnnotateResult{Duplicates: 1, OutsideDiff: 2}) || len(reviews.posted) != 0 {
+		t.Errorf("Annotate() again = %+v, posted %d; want a duplicate", got, len(reviews.posted))
+	}
+}
+
+// TestGitHubReviews tests the GitHub provider against a fake API.
+func TestGitHubReviews(t *testing.T) {
+	var mu sync.Mutex
+	var posted map[string]any
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/acme/web/pulls/7/files":
+			_, _ = w.Write([]byte(`[{"filename": "src/a.ts", "patch": "@@ -1 +1,2 @@\n context\n+added"}]`))
+		case "GET /repos/acme/web/pulls/7/comments":
+			_, _ = w.Write([]byte(`[{"body": "Nice"}]`))
+		case "GET /repos/acme/web/pulls/7":
+			_, _ = w.Write([]byte(`{"head": {"sha": "abc123"}}`))
+		case "POST /repos/acme/web/pulls/7/comments":
+			mu.Lock()
+			defer mu.Unlock()
+			_ = json.NewDecoder(r.Body).Decode(&posted)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	g := &GitHubReviews{APIURL: api.URL, Repo: "acme/web", PR: 7, Token: "secret"}
+	result := SvelteWatchCheckComplete{Diagnostics: []Diagnostic{{Type: "ERROR", Filename: "src/a.ts", Start: Position{Line: 1}, Message: "Boom"}}}
+	got, err := Annotate(context.Background(), g, result, "")
+	if err != nil {
+		t.Fatalf("Annotate failed: %v", err)
+	}
+	if got.Posted != 1 {
+		t.Errorf("Annotate() = %+v, want 1 posted", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if posted["commit_id"] != "abc123" || posted["path"] != "src/a.ts" || posted["line"] != float64(2) || posted["side"] != "RIGHT" {
+		t.Errorf("posted %v", posted)
+	}
+
+	g.Token = "wrong"
+	if _, err := g.ChangedLines(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("ChangedLines() with a bad token = %v, want a 401 error", err)
+	}
+}
@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// Audit Log
+// =============================================================================
+
+// DefaultAuditLogMaxSize is the size at which the audit log is rotated when
+// no other size is configured.
+const DefaultAuditLogMaxSize = 10 << 20
+
+// auditLogBackups is how many rotated files are kept, as <path>.1 (the
+// newest) to <path>.3.
+const auditLogBackups = 3
+
+// AuditLog appends a JSON line for every watcher event, git trigger,
+// restart, sync, and completed check, so the daemon's decisions can be
+// reconstructed later. A nil *AuditLog records nothing.
+type AuditLog struct {
+	path    string
+	maxSize int64
+	kinds   map[string]bool // the kinds recorded; nil for all
+	next    *AuditLog       // also records every entry; see Tee
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenAuditLog opens path for appending, creating it if needed. The file is
+// rotated once it would grow past maxSize; 0 or less means
+// DefaultAuditLogMaxSize.
+func OpenAuditLog(path string, maxSize int64) (*AuditLog, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultAuditLogMaxSize
+	}
+	a := &AuditLog{path: path, maxSize: maxSize}
+	if err := a.openLocked(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *AuditLog) openLocked() error {
+	f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	a.file, a.size = f, info.Size()
+	return nil
+}
+
+// Record appends an entry of the given kind, e.g. "event" or "restart",
+// with fields alongside its time and kind.
+func (a *AuditLog) Record(kind string, fields map[string]any) {
+	if a == nil {
+		return
+	}
+	a.next.Record(kind, fields)
+	if a.kinds != nil && !a.kinds[kind] {
+		return
+	}
+	entry := make(map[string]any, len(fields)+2)
+	for k, v := range fields {
+		entry[k] = v
+	}
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["kind"] = kind
+	line, err := json.Marshal(entry)
+	if err != nil {
+		logger("audit").Warn("could not encode audit entry", "error", err)
+		return
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return
+	}
+	if a.size > 0 && a.size+int64(len(line)) > a.maxSize {
+		if err := a.rotateLocked(); err != nil {
+			logger("audit").Warn("could not rotate audit log", "error", err)
+			return
+		}
+	}
+	n, err := a.file.Write(line)
+	a.size += int64(n)
+	if err != nil {
+		logger("audit").Warn("could not write audit log", "error", err)
+	}
+}
+
+// rotateLocked shifts <path>.N to <path>.N+1, dropping the oldest, moves the
+// log to <path>.1, and starts a new one. a.mu must be held.
+func (a *AuditLog) rotateLocked() error {
+	if err := a.file.Close(); err != nil {
+		return err
+	}
+	a.file = nil
+	for i := auditLogBackups - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", a.path, i), fmt.Sprintf("%s.%d", a.path, i+1))
+	}
+	if err := os.Rename(a.path, a.path+".1"); err != nil {
+		// Keep appending to the current file.
+		return cmp.Or(a.openLocked(), err)
+	}
+	return a.openLocked()
+}
+
+// Tee makes a also record every entry to next, and returns a, or next if a
+// is nil.
+func (a *AuditLog) Tee(next *AuditLog) *AuditLog {
+	if a == nil {
+		return next
+	}
+	a.next = next
+	return a
+}
+
+// Close closes the log file, and that of any log it tees to.
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	err := a.next.Close()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return err
+	}
+	err = cmp.Or(a.file.Close(), err)
+	a.file = nil
+	return err
+}
+
+// recordCheck records a completed check of the named checker in workspace.
+func (a *AuditLog) recordCheck(checker, workspace string, result SvelteWatchCheckComplete) {
+	a.Record("check", map[string]any{
+		"checker":           checker,
+		"workspace":         workspace,
+		"check":             result.Timestamp,
+		"files":             result.FileCount,
+		"errors":            result.ErrorCount,
+		"warnings":          result.WarningCount,
+		"filesWithProblems": result.FilesWithProblems,
+		"failures":          len(result.Failures),
+		"introduced":        len(result.Introduced),
+		"resolved":          len(result.Resolved),
+	})
+}
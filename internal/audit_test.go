@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// readAuditLog returns the entries of the audit log at path.
+func readAuditLog(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read audit log: %v", err)
+	}
+	var entries []map[string]any
+	for line := range strings.Lines(string(data)) {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("audit line %q is not JSON: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestAuditLog_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	a, err := OpenAuditLog(path, 0)
+	if err != nil {
+		t.Fatalf("OpenAuditLog failed: %v", err)
+	}
+	a.Record("restart", map[string]any{"reason": "request"})
+	a.Record("stop", nil)
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	entries := readAuditLog(t, path)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if entries[0]["kind"] != "restart" || entries[0]["reason"] != "request" || entries[1]["kind"] != "stop" {
+		t.Errorf("entries = %v, want a request restart then stop", entries)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, entries[0]["time"].(string)); err != nil {
+		t.Errorf("time = %v: %v", entries[0]["time"], err)
+	}
+
+	// Recording after Close, or on a nil log, does nothing.
+	a.Record("event", nil)
+	var nilLog *AuditLog
+	nilLog.Record("event", nil)
+	if err := nilLog.Close(); err != nil {
+		t.Errorf("Close on nil = %v", err)
+	}
+}
+
+func TestAuditLog_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	a, err := OpenAuditLog(path, 200)
+	if err != nil {
+		t.Fatalf("OpenAuditLog failed: %v", err)
+	}
+	defer func() { _ = a.Close() }()
+
+	for i := range 40 {
+		a.Record("event", map[string]any{"n": i, "path": "src/lib/component.svelte"})
+	}
+
+	for i := 1; i <= auditLogBackups; i++ {
+		if _, err := os.Stat(fmt.Sprintf("%s.%d", path, i)); err != nil {
+			t.Errorf("backup %d missing: %v", i, err)
+		}
+	}
+	if _, err := os.Stat(path + ".4"); !os.IsNotExist(err) {
+		t.Errorf("a fourth backup was kept: %v", err)
+	}
+	entries := readAuditLog(t, path)
+	if len(entries) == 0 || entries[len(entries)-1]["n"] != float64(39) {
+		t.Errorf("current log = %v, want it to end with the last entry", entries)
+	}
+	if info, _ := os.Stat(path); info.Size() > 200 {
+		t.Errorf("current log is %d bytes, want at most 200", info.Size())
+	}
+}
+
+func TestWatcher_Audit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := OpenAuditLog(path, 0)
+	if err != nil {
+		t.Fatalf("OpenAuditLog failed: %v", err)
+	}
+	defer func() { _ = audit.Close() }()
+
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := NewFakeFSWatcher()
+		config := WatcherConfig{
+			WorkspacePath:    "/ws",
+			NonRecursiveDirs: []string{"."},
+			Ignore:           []string{"coverage/**"},
+			Audit:            audit,
+		}
+		w := NewWatcher(config, WatcherCallbacks{OnRestart: func() {}}, fsWatcher, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go w.Start(ctx)
+		synctest.Wait()
+
+		fsWatcher.events <- fsnotify.Event{Name: "/ws/coverage/index.html", Op: fsnotify.Write}
+		fsWatcher.events <- fsnotify.Event{Name: "/ws/tsconfig.json", Op: fsnotify.Write}
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+	})
+
+	var got []string
+	for _, e := range readAuditLog(t, path) {
+		switch e["kind"] {
+		case "event":
+			got = append(got, fmt.Sprintf("%v %v ignored=%v", e["path"], e["op"], e["ignored"]))
+		case "restart":
+			got = append(got, "restart "+e["reason"].(string))
+		}
+	}
+	want := "coverage/index.html WRITE ignored=true, tsconfig.json WRITE ignored=false, restart watcher"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("audit = %q, want %q", strings.Join(got, ", "), want)
+	}
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// =============================================================================
+// Auto-Watched Directories
+// =============================================================================
+
+// ValidateAutoWatch checks that patterns are valid globs of directories
+// inside the workspace. Each "*" matches within one path segment; "**" is
+// not supported.
+func ValidateAutoWatch(patterns []string) error {
+	for _, p := range patterns {
+		clean := path.Clean(p)
+		switch {
+		case path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../"):
+			return fmt.Errorf("invalid auto-watch pattern %q: must be relative to the workspace and inside it", p)
+		case clean == ".":
+			return fmt.Errorf("invalid auto-watch pattern %q: matches the workspace itself", p)
+		case strings.Contains(clean, "**"):
+			return fmt.Errorf("invalid auto-watch pattern %q: ** is not supported", p)
+		}
+		if _, err := path.Match(clean, ""); err != nil {
+			return fmt.Errorf("invalid auto-watch pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// autoWatchMatch reports whether the workspace-relative directory rel
+// matches pattern (full) or is an ancestor of directories that could
+// (partial). The workspace itself, ".", is an ancestor of every match.
+func autoWatchMatch(pattern, rel string) (full, partial bool) {
+	segments := strings.Split(path.Clean(pattern), "/")
+	var parts []string
+	if rel != "." {
+		parts = strings.Split(filepath.ToSlash(rel), "/")
+	}
+	if len(parts) > len(segments) {
+		return false, false
+	}
+	for i, part := range parts {
+		if ok, _ := path.Match(segments[i], part); !ok {
+			return false, false
+		}
+	}
+	return len(parts) == len(segments), len(parts) < len(segments)
+}
+
+// autoWatch watches rel, relative to the workspace, recursively if it
+// matches an AutoWatch pattern. Ancestors of possible matches are watched
+// non-recursively, so that matches created later are seen, and searched
+// for existing matches.
+func (w *Watcher) autoWatch(rel string) {
+	if len(w.config.AutoWatch) == 0 {
+		return
+	}
+	w.dirsMu.Lock()
+	defer w.dirsMu.Unlock()
+	w.autoWatchLocked(filepath.Clean(rel))
+}
+
+// autoWatchLocked is autoWatch with dirsMu held.
+func (w *Watcher) autoWatchLocked(rel string) {
+	full, partial := false, false
+	for _, p := range w.config.AutoWatch {
+		f, pa := autoWatchMatch(p, rel)
+		full, partial = full || f, partial || pa
+	}
+	if !full && !partial || (rel != "." && w.ignored(rel)) {
+		return
+	}
+	abs := filepath.Join(w.config.WorkspacePath, rel)
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		return
+	}
+	covered := slices.ContainsFunc(w.config.RecursiveDirs, func(d string) bool {
+		d = filepath.Join(w.config.WorkspacePath, d)
+		return d == abs || isWithin(d, abs)
+	})
+	if covered {
+		return
+	}
+
+	if full {
+		if err := w.fs().Add(abs, true); err != nil {
+			w.logger.Warn("could not watch directory recursively", "path", abs, "error", err)
+			return
+		}
+		w.config.RecursiveDirs = append(w.config.RecursiveDirs, rel)
+		w.logger.Info("watching a directory that matches an auto-watch pattern", "path", rel)
+		return
+	}
+
+	watched := slices.ContainsFunc(w.config.NonRecursiveDirs, func(d string) bool { return filepath.Clean(d) == rel })
+	if !watched {
+		if err := w.fs().Add(abs, false); err != nil {
+			w.logger.Warn("could not watch directory", "path", abs, "error", err)
+			return
+		}
+		w.config.NonRecursiveDirs = append(w.config.NonRecursiveDirs, rel)
+	}
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			w.autoWatchLocked(filepath.Join(rel, e.Name()))
+		}
+	}
+}
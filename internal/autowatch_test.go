@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestAutoWatchMatch(t *testing.T) {
+	for _, tt := range []struct {
+		rel           string
+		full, partial bool
+	}{
+		{".", false, true},
+		{"packages", false, true},
+		{"packages/ui", false, true},
+		{"packages/ui/src", true, false},
+		{"packages/ui/src/lib", false, false},
+		{"apps", false, false},
+	} {
+		full, partial := autoWatchMatch("packages/*/src", filepath.FromSlash(tt.rel))
+		if full != tt.full || partial != tt.partial {
+			t.Errorf("autoWatchMatch(%q) = %v, %v; want %v, %v", tt.rel, full, partial, tt.full, tt.partial)
+		}
+	}
+}
+
+func TestValidateAutoWatch(t *testing.T) {
+	if err := ValidateAutoWatch([]string{"packages/*/src", "apps/web/src"}); err != nil {
+		t.Errorf("ValidateAutoWatch() = %v", err)
+	}
+	for _, p := range []string{"../other/src", "/abs/src", ".", "packages/**", "packages/[/src"} {
+		if err := ValidateAutoWatch([]string{p}); err == nil {
+			t.Errorf("ValidateAutoWatch(%q) succeeded, want an error", p)
+		}
+	}
+}
+
+func TestWatcher_AutoWatch(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"packages/ui/src/index.ts":    "",
+		"packages/ui/package.json":    "{}",
+		"packages/empty/package.json": "{}",
+	})
+
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := NewFakeFSWatcher()
+		config := WatcherConfig{
+			WorkspacePath:    root,
+			NonRecursiveDirs: []string{"."},
+			AutoWatch:        []string{"packages/*/src"},
+		}
+		w := NewWatcher(config, WatcherCallbacks{}, fsWatcher, nil)
+		defer w.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go w.Start(ctx)
+		synctest.Wait()
+
+		want := []addedPath{
+			{root, false},
+			{filepath.Join(root, "packages"), false},
+			{filepath.Join(root, "packages", "empty"), false},
+			{filepath.Join(root, "packages", "ui"), false},
+			{filepath.Join(root, "packages", "ui", "src"), true},
+		}
+		if !slices.Equal(fsWatcher.addedPaths, want) {
+			t.Errorf("added paths = %v, want %v", fsWatcher.addedPaths, want)
+		}
+
+		// Scaffold a package with mkdir -p: only the package directory's
+		// creation is seen.
+		if err := os.MkdirAll(filepath.Join(root, "packages", "forms", "src", "lib"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		fsWatcher.events <- fsnotify.Event{Name: filepath.Join(root, "packages", "forms"), Op: fsnotify.Create}
+		time.Sleep(eventBatchWindow)
+		synctest.Wait()
+
+		want = append(want,
+			addedPath{filepath.Join(root, "packages", "forms"), false},
+			addedPath{filepath.Join(root, "packages", "forms", "src"), true},
+		)
+		if !slices.Equal(fsWatcher.addedPaths, want) {
+			t.Errorf("added paths = %v, want %v", fsWatcher.addedPaths, want)
+		}
+		dirs := w.WatchDirs()
+		if !slices.Contains(dirs.Recursive, filepath.Join("packages", "forms", "src")) {
+			t.Errorf("WatchDirs().Recursive = %v, want packages/forms/src", dirs.Recursive)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// Baseline
+// =============================================================================
+
+// BaselineFileName is the file, in the workspace root, listing diagnostics
+// that predate adoption and are excluded from /check results.
+const BaselineFileName = ".svelte-check-baseline.json"
+
+// Baseline is the content of a baseline file. Filenames are relative to the
+// workspace, as in merged results.
+type Baseline struct {
+	CreatedAt   time.Time    `json:"createdAt"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// NewBaseline snapshots diagnostics, sorted by file and position so the file
+// diffs cleanly under version control.
+func NewBaseline(diags []Diagnostic) Baseline {
+	diags = slices.Clone(diags)
+	for i := range diags {
+		diags[i].Timestamp = 0
+	}
+	slices.SortStableFunc(diags, func(a, b Diagnostic) int {
+		return cmp.Or(
+			strings.Compare(a.Filename, b.Filename),
+			cmp.Compare(a.Start.Line, b.Start.Line),
+			cmp.Compare(a.Start.Character, b.Start.Character),
+		)
+	})
+	return Baseline{CreatedAt: time.Now().UTC(), Diagnostics: diags}
+}
+
+// WriteBaseline writes b to path.
+func WriteBaseline(path string, b Baseline) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// LoadBaseline reads the baseline file at path.
+func LoadBaseline(path string) (Baseline, error) {
+	var b Baseline
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return b, err
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		return b, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// Apply removes baselined diagnostics from result, matching them as
+// DiffDiagnostics does so findings shifted by later edits stay suppressed.
+// Counts are reduced accordingly and Baselined records how many were removed.
+// Matching uses qualify(result), which must preserve the order of
+// diagnostics, so per-project results can be matched against workspace-
+// relative filenames.
+func (b Baseline) Apply(result SvelteWatchCheckComplete, qualify func(SvelteWatchCheckComplete) SvelteWatchCheckComplete) SvelteWatchCheckComplete {
+	if len(b.Diagnostics) == 0 {
+		return result
+	}
+	result, n := filterResult(result, qualify(result), func(diags []Diagnostic) []bool {
+		_, matched := matchDiagnostics(b.Diagnostics, diags)
+		return matched
+	})
+	result.Baselined += n
+	return result
+}
+
+// baselineFile caches a baseline file, reloading it when it changes so a
+// rewritten baseline takes effect without restarting the daemon.
+type baselineFile struct {
+	path string
+
+	mu       sync.Mutex
+	modTime  time.Time
+	size     int64
+	baseline Baseline
+}
+
+// get returns the current baseline. A missing or unreadable file yields an
+// empty baseline; read errors are logged.
+func (f *baselineFile) get() Baseline {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger("baseline").Warn("could not read baseline", "path", f.path, "error", err)
+		}
+		f.modTime, f.size, f.baseline = time.Time{}, 0, Baseline{}
+		return f.baseline
+	}
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.baseline
+	}
+
+	b, err := LoadBaseline(f.path)
+	if err != nil {
+		logger("baseline").Warn("ignoring baseline", "path", f.path, "error", err)
+	}
+	f.modTime, f.size, f.baseline = info.ModTime(), info.Size(), b
+	return b
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func identity(r SvelteWatchCheckComplete) SvelteWatchCheckComplete { return r }
+
+func TestBaseline_Apply(t *testing.T) {
+	b := NewBaseline([]Diagnostic{
+		diagAt("src/legacy.ts", 10, 2322, "Type mismatch"),
+		{Type: "WARNING", Filename: "src/a.svelte", Start: Position{Line: 3}, Code: "a11y", Message: "Missing alt"},
+	})
+
+	result := SvelteWatchCheckComplete{
+		Diagnostics: []Diagnostic{
+			// Moved down by an edit above it: still baselined.
+			diagAt("src/legacy.ts", 14, 2322, "Type mismatch"),
+			{Type: "WARNING", Filename: "src/a.svelte", Start: Position{Line: 3}, Code: "a11y", Message: "Missing alt"},
+			diagAt("src/a.svelte", 8, 2304, "Cannot find name 'x'"),
+		},
+		ErrorCount:        2,
+		WarningCount:      1,
+		FilesWithProblems: 2,
+		Introduced:        []Diagnostic{diagAt("src/a.svelte", 8, 2304, "Cannot find name 'x'")},
+	}
+
+	got := b.Apply(result, identity)
+	if len(got.Diagnostics) != 1 || got.Diagnostics[0].Message != "Cannot find name 'x'" {
+		t.Errorf("Diagnostics = %+v, want only the new error", got.Diagnostics)
+	}
+	if got.Baselined != 2 || got.ErrorCount != 1 || got.WarningCount != 0 || got.FilesWithProblems != 1 {
+		t.Errorf("Baselined, ErrorCount, WarningCount, FilesWithProblems = %d, %d, %d, %d; want 2, 1, 0, 1",
+			got.Baselined, got.ErrorCount, got.WarningCount, got.FilesWithProblems)
+	}
+	if len(got.Introduced) != 1 {
+		t.Errorf("Introduced = %+v, want the new error kept", got.Introduced)
+	}
+}
+
+func TestBaseline_Apply_Qualified(t *testing.T) {
+	b := NewBaseline([]Diagnostic{diagAt("apps/web/src/a.ts", 1, 2322, "Type mismatch")})
+	p := Project{Name: "web", Dir: "apps/web"}
+
+	result := SvelteWatchCheckComplete{
+		Diagnostics: []Diagnostic{diagAt("src/a.ts", 1, 2322, "Type mismatch")},
+		ErrorCount:  1,
+	}
+	got := b.Apply(result, p.qualify)
+	if len(got.Diagnostics) != 0 || got.Baselined != 1 {
+		t.Errorf("Apply = %+v, want the project-relative diagnostic baselined", got)
+	}
+}
+
+func TestBaselineFile_Reloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), BaselineFileName)
+	f := &baselineFile{path: path}
+
+	if got := f.get(); len(got.Diagnostics) != 0 {
+		t.Errorf("missing file: get() = %+v, want empty", got)
+	}
+
+	if err := WriteBaseline(path, NewBaseline([]Diagnostic{diagAt("src/a.ts", 1, 2322, "x")})); err != nil {
+		t.Fatalf("WriteBaseline failed: %v", err)
+	}
+	if got := f.get(); len(got.Diagnostics) != 1 {
+		t.Errorf("after write: get() = %+v, want 1 diagnostic", got)
+	}
+
+	if err := WriteBaseline(path, NewBaseline(nil)); err != nil {
+		t.Fatalf("WriteBaseline failed: %v", err)
+	}
+	// Make the rewrite visible even on filesystems with coarse timestamps.
+	later := time.Now().Add(time.Hour)
+	_ = os.Chtimes(path, later, later)
+	if got := f.get(); len(got.Diagnostics) != 0 {
+		t.Errorf("after rewrite: get() = %+v, want empty", got)
+	}
+}
+
+// TestServer_HandleCheck_Baseline tests that baselined diagnostics are
+// excluded from /check, and included with ?baseline=false.
+func TestServer_HandleCheck_Baseline(t *testing.T) {
+	socketPath := testSocketPath(t)
+
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(`1770255832071 START "/workspace"
+1770255834342 {"type":"ERROR","filename":"src/a.ts","start":{"line":4,"character":0},"end":{"line":4,"character":1},"message":"Legacy error","code":2322}
+1770255834342 COMPLETED 100 FILES 1 ERRORS 0 WARNINGS 1 FILES_WITH_PROBLEMS
+`, "")))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	path := filepath.Join(t.TempDir(), BaselineFileName)
+	if err := WriteBaseline(path, NewBaseline([]Diagnostic{diagAt("src/a.ts", 2, 2322, "Legacy error")})); err != nil {
+		t.Fatalf("WriteBaseline failed: %v", err)
+	}
+
+	s := NewServer(socketPath, r)
+	s.SetBaselineFile(path)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	get := func(query string) (int, SvelteWatchCheckComplete) {
+		t.Helper()
+		resp, err := unixHTTPClient(socketPath).Get("http://unix/check?format=json" + query)
+		if err != nil {
+			t.Fatalf("GET /check failed: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		var result SvelteWatchCheckComplete
+		if err := json.Unmarshal(body, &result); err != nil {
+			t.Fatalf("invalid JSON: %v\n%s", err, body)
+		}
+		return resp.StatusCode, result
+	}
+
+	status, result := get("")
+	if status != 200 || result.ErrorCount != 0 || result.Baselined != 1 {
+		t.Errorf("baselined: status %d, %d errors, %d baselined; want 200, 0, 1", status, result.ErrorCount, result.Baselined)
+	}
+
+	status, result = get("&baseline=false")
+	if status != 500 || result.ErrorCount != 1 || result.Baselined != 0 {
+		t.Errorf("baseline=false: status %d, %d errors, %d baselined; want 500, 1, 0", status, result.ErrorCount, result.Baselined)
+	}
+}
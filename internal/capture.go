@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// Output Capture
+// =============================================================================
+
+const (
+	// crashOutputLines is how many lines of combined output a CrashReport keeps.
+	crashOutputLines = 100
+	// crashStderrLines is how many lines of stderr a CrashReport keeps.
+	crashStderrLines = 50
+)
+
+// CrashReport describes the most recent unexpected exit of svelte-check.
+type CrashReport struct {
+	At     time.Time `json:"at"`
+	Exit   string    `json:"exit"`
+	Stderr []string  `json:"stderr"`
+	Output []string  `json:"output"` // last lines of stdout and stderr, interleaved
+}
+
+// lineTail retains the last max lines added to it.
+type lineTail struct {
+	max   int
+	lines []string
+}
+
+func newLineTail(max int) *lineTail {
+	return &lineTail{max: max}
+}
+
+func (t *lineTail) add(line string) {
+	if len(t.lines) == t.max {
+		copy(t.lines, t.lines[1:])
+		t.lines = t.lines[:t.max-1]
+	}
+	t.lines = append(t.lines, line)
+}
+
+func (t *lineTail) snapshot() []string {
+	return append([]string(nil), t.lines...)
+}
+
+// outputCapture receives the child's stdout and stderr, keeps a tail of each
+// for crash reports, and forwards whole lines from both streams to a single
+// reader for the interpreter. Lines are never split across streams.
+type outputCapture struct {
+	mu     sync.Mutex
+	pw     *io.PipeWriter
+	output *lineTail
+	stderr *lineTail
+	stdout *captureStream
+	errout *captureStream
+}
+
+// newOutputCapture returns a capture and the reader that yields its merged lines.
+// The reader reaches EOF after Close.
+func newOutputCapture() (*outputCapture, io.Reader) {
+	pr, pw := io.Pipe()
+	c := &outputCapture{
+		pw:     pw,
+		output: newLineTail(crashOutputLines),
+		stderr: newLineTail(crashStderrLines),
+	}
+	c.stdout = &captureStream{capture: c}
+	c.errout = &captureStream{capture: c, isStderr: true}
+	return c, pr
+}
+
+// Stdout returns the writer to attach to the child's stdout.
+func (c *outputCapture) Stdout() io.Writer { return c.stdout }
+
+// Stderr returns the writer to attach to the child's stderr.
+func (c *outputCapture) Stderr() io.Writer { return c.errout }
+
+func (c *outputCapture) addLine(line []byte, isStderr bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.output.add(string(line))
+	if isStderr {
+		c.stderr.add(string(line))
+	}
+	// Write errors mean the reader is gone; keep capturing tails regardless.
+	_, _ = c.pw.Write(append(line, '\n'))
+}
+
+// Close flushes any unterminated final lines and signals EOF to the reader.
+// Call it once the child has exited and its output has been fully copied.
+func (c *outputCapture) Close() {
+	c.stdout.flush()
+	c.errout.flush()
+	_ = c.pw.Close()
+}
+
+// Tails returns the captured stderr and combined output lines.
+func (c *outputCapture) Tails() (stderr, output []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stderr.snapshot(), c.output.snapshot()
+}
+
+// captureStream splits one stream into lines for its outputCapture.
+type captureStream struct {
+	capture  *outputCapture
+	isStderr bool
+
+	mu      sync.Mutex
+	partial []byte
+}
+
+func (s *captureStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.partial = append(s.partial, p...)
+	for {
+		i := bytes.IndexByte(s.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.TrimSuffix(s.partial[:i], []byte("\r"))
+		s.capture.addLine(bytes.Clone(line), s.isStderr)
+		s.partial = s.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+func (s *captureStream) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.partial) > 0 {
+		s.capture.addLine(bytes.Clone(s.partial), s.isStderr)
+		s.partial = nil
+	}
+}
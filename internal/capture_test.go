@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestLineTail_KeepsLastLines(t *testing.T) {
+	tail := newLineTail(3)
+	for _, line := range []string{"a", "b", "c", "d", "e"} {
+		tail.add(line)
+	}
+
+	if got, want := tail.snapshot(), []string{"c", "d", "e"}; !slices.Equal(got, want) {
+		t.Errorf("snapshot() = %q, want %q", got, want)
+	}
+}
+
+func TestOutputCapture_MergesWholeLines(t *testing.T) {
+	capture, reader := newOutputCapture()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		done <- string(data)
+	}()
+
+	// Partial writes from both streams must not interleave mid-line.
+	_, _ = capture.Stdout().Write([]byte("1770255832071 STA"))
+	_, _ = capture.Stderr().Write([]byte("warn: slow\r\n"))
+	_, _ = capture.Stdout().Write([]byte("RT \"/workspace\"\nunterminated"))
+	capture.Close()
+
+	want := "warn: slow\n1770255832071 START \"/workspace\"\nunterminated\n"
+	if got := <-done; got != want {
+		t.Errorf("merged output = %q, want %q", got, want)
+	}
+
+	stderr, output := capture.Tails()
+	if want := []string{"warn: slow"}; !slices.Equal(stderr, want) {
+		t.Errorf("stderr tail = %q, want %q", stderr, want)
+	}
+	if len(output) != 3 {
+		t.Errorf("output tail has %d lines, want 3: %q", len(output), output)
+	}
+}
+
+func TestOutputCapture_KeepsTailsWhenReaderGone(t *testing.T) {
+	capture, reader := newOutputCapture()
+	_ = reader.(io.Closer).Close()
+
+	_, _ = capture.Stderr().Write([]byte("still recorded\n"))
+	capture.Close()
+
+	stderr, _ := capture.Tails()
+	if want := []string{"still recorded"}; !slices.Equal(stderr, want) {
+		t.Errorf("stderr tail = %q, want %q", stderr, want)
+	}
+}
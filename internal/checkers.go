@@ -0,0 +1,529 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// Checkers
+// =============================================================================
+
+// CheckerKind names a supported checking tool.
+type CheckerKind string
+
+const (
+	CheckerSvelteCheck CheckerKind = "svelte-check"
+	CheckerTsc         CheckerKind = "tsc"
+	CheckerESLint      CheckerKind = "eslint"
+)
+
+// ParseCheckerKind validates a checker name from the command line or config file.
+func ParseCheckerKind(name string) (CheckerKind, error) {
+	switch k := CheckerKind(name); k {
+	case CheckerSvelteCheck, CheckerTsc, CheckerESLint:
+		return k, nil
+	default:
+		return "", fmt.Errorf("unknown checker %q (want svelte-check, tsc, or eslint)", name)
+	}
+}
+
+// Checker is a long-lived source of check results served by the daemon.
+// Runner (svelte-check or tsc in watch mode), OneShotChecker (eslint), and
+// CheckerSet implement it.
+type Checker interface {
+	Name() string
+	Start(ctx context.Context) error
+	Stop()
+	Restart(ctx context.Context) error
+	GetLatestEvent(ctx context.Context) (SvelteWatchCheckComplete, error)
+	Status() RunnerStatus
+	LastCrash() *CrashReport
+}
+
+// Rerunner is implemented by checkers without a watch mode of their own. They
+// are rerun when source files change.
+type Rerunner interface {
+	Rerun()
+}
+
+// DependencyNotifier is implemented by checkers that tell their subscribers
+// when the workspace's dependencies change. See DependenciesChanged.
+type DependencyNotifier interface {
+	NotifyDependenciesChanged(lockfile string)
+}
+
+// ReadyWaiter is implemented by checkers that can report whether their
+// process started successfully. See Runner.WaitReady.
+type ReadyWaiter interface {
+	WaitReady(ctx context.Context) error
+}
+
+// CheckerStatus is the health of one checker within a CheckerSet.
+type CheckerStatus struct {
+	Name   string       `json:"name"`
+	Status RunnerStatus `json:"status"`
+}
+
+// NewChecker returns the Checker for the given kinds: a plain Runner for
+// svelte-check alone, or a CheckerSet running each kind side by side.
+// config.Command and config.ExtraArgs apply to svelte-check only.
+func NewChecker(config RunnerConfig, kinds []CheckerKind, executor kexec.Interface) Checker {
+	if len(kinds) == 0 || slices.Equal(kinds, []CheckerKind{CheckerSvelteCheck}) {
+		return NewRunnerWithConfig(config, WithExecutor(executor))
+	}
+
+	var checkers []Checker
+	for _, kind := range kinds {
+		switch kind {
+		case CheckerSvelteCheck:
+			checkers = append(checkers, NewRunnerWithConfig(config, WithExecutor(executor)))
+		case CheckerTsc:
+			c := config
+			c.Checker, c.Command, c.ExtraArgs = CheckerTsc, nil, nil
+			c.StateFile = QualifyStateFile(config.StateFile, string(kind))
+			checkers = append(checkers, NewRunnerWithConfig(c, WithExecutor(executor)))
+		case CheckerESLint:
+			c := config
+			c.StateFile = QualifyStateFile(config.StateFile, string(kind))
+			checkers = append(checkers, NewESLintChecker(c, executor))
+		}
+	}
+	return NewCheckerSet(checkers...)
+}
+
+// commandLines describes the commands a checker runs, for logging.
+func commandLines(c Checker) []string {
+	switch c := c.(type) {
+	case *Runner:
+		name, args := c.config.command(true)
+		return []string{strings.Join(append([]string{name}, args...), " ")}
+	case *OneShotChecker:
+		name, args := c.command()
+		return []string{strings.Join(append([]string{name}, args...), " ")}
+	case *CheckerSet:
+		var lines []string
+		for _, sub := range c.checkers {
+			lines = append(lines, commandLines(sub)...)
+		}
+		return lines
+	}
+	return nil
+}
+
+// =============================================================================
+// Checker Set
+// =============================================================================
+
+// CheckerSet runs several checkers concurrently and serves their merged
+// results. Each diagnostic is tagged with the checker that reported it.
+type CheckerSet struct {
+	checkers []Checker
+}
+
+// NewCheckerSet creates a CheckerSet over the given checkers.
+func NewCheckerSet(checkers ...Checker) *CheckerSet {
+	return &CheckerSet{checkers: checkers}
+}
+
+// Checkers returns the checkers in the set.
+func (s *CheckerSet) Checkers() []Checker {
+	return s.checkers
+}
+
+// Name returns the checker names joined with "+".
+func (s *CheckerSet) Name() string {
+	var name string
+	for i, c := range s.checkers {
+		if i > 0 {
+			name += "+"
+		}
+		name += c.Name()
+	}
+	return name
+}
+
+// Start starts every checker. If one fails, those already started are stopped.
+func (s *CheckerSet) Start(ctx context.Context) error {
+	for i, c := range s.checkers {
+		if err := c.Start(ctx); err != nil {
+			for _, started := range s.checkers[:i] {
+				started.Stop()
+			}
+			return fmt.Errorf("start %s: %w", c.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Stop stops every checker.
+func (s *CheckerSet) Stop() {
+	for _, c := range s.checkers {
+		c.Stop()
+	}
+}
+
+// Restart restarts every checker concurrently.
+func (s *CheckerSet) Restart(ctx context.Context) error {
+	errs := make([]error, len(s.checkers))
+	var wg sync.WaitGroup
+	for i, c := range s.checkers {
+		wg.Go(func() {
+			if err := c.Restart(ctx); err != nil {
+				errs[i] = fmt.Errorf("restart %s: %w", c.Name(), err)
+			}
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// WaitReady waits for every checker that supports it to become ready and
+// returns the first failure.
+func (s *CheckerSet) WaitReady(ctx context.Context) error {
+	errs := make([]error, len(s.checkers))
+	var wg sync.WaitGroup
+	for i, c := range s.checkers {
+		if w, ok := c.(ReadyWaiter); ok {
+			wg.Go(func() { errs[i] = w.WaitReady(ctx) })
+		}
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rerun reruns the checkers that have no watch mode of their own.
+func (s *CheckerSet) Rerun() {
+	for _, c := range s.checkers {
+		if r, ok := c.(Rerunner); ok {
+			r.Rerun()
+		}
+	}
+}
+
+// NotifyDependenciesChanged notifies the checkers that report dependency
+// changes.
+func (s *CheckerSet) NotifyDependenciesChanged(lockfile string) {
+	for _, c := range s.checkers {
+		if n, ok := c.(DependencyNotifier); ok {
+			n.NotifyDependenciesChanged(lockfile)
+		}
+	}
+}
+
+// GetLatestEvent blocks until every checker has a completed result, or ctx is
+// done, and returns them merged. Diagnostics are tagged with their checker
+// and, where the tool does not set one, a Source.
+func (s *CheckerSet) GetLatestEvent(ctx context.Context) (SvelteWatchCheckComplete, error) {
+	results := make([]SvelteWatchCheckComplete, len(s.checkers))
+	errs := make([]error, len(s.checkers))
+	var wg sync.WaitGroup
+	for i, c := range s.checkers {
+		wg.Go(func() {
+			var result SvelteWatchCheckComplete
+			result, errs[i] = c.GetLatestEvent(ctx)
+			results[i] = tagChecker(c, result)
+		})
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return SvelteWatchCheckComplete{}, err
+		}
+	}
+	return MergeResults(results), nil
+}
+
+// PeekLatest merges every checker's most recent result without blocking. It
+// reports ok == false unless every checker has one.
+func (s *CheckerSet) PeekLatest() (SvelteWatchCheckComplete, bool) {
+	results := make([]SvelteWatchCheckComplete, len(s.checkers))
+	for i, c := range s.checkers {
+		p, ok := c.(ResultPeeker)
+		if !ok {
+			return SvelteWatchCheckComplete{}, false
+		}
+		result, ok := p.PeekLatest()
+		if !ok {
+			return SvelteWatchCheckComplete{}, false
+		}
+		results[i] = tagChecker(c, result)
+	}
+	return MergeResults(results), true
+}
+
+// tagChecker returns a copy of result with each diagnostic tagged with the
+// checker and, where the tool does not set one, a Source.
+func tagChecker(c Checker, result SvelteWatchCheckComplete) SvelteWatchCheckComplete {
+	return mapDiagnostics(result, func(d *Diagnostic) {
+		d.Checker = c.Name()
+		if d.Source == "" {
+			d.Source = c.Name()
+		}
+	})
+}
+
+// checkerStatePriority orders states from healthiest to least healthy; the
+// set reports the least healthy state of its checkers.
+var checkerStatePriority = []RunnerState{
+	RunnerStateStopped,
+	RunnerStateReady,
+	RunnerStateChecking,
+	RunnerStateStarting,
+	RunnerStateDegraded,
+	RunnerStateFailed,
+}
+
+// Status summarizes the checkers: the least healthy state, summed counters,
+// the timings of the slowest checker, and each checker's own status under
+// Checkers.
+func (s *CheckerSet) Status() RunnerStatus {
+	var status RunnerStatus
+	worst := -1
+	for i, c := range s.checkers {
+		cs := c.Status()
+		if i == 0 {
+			status.PackageManager = cs.PackageManager
+			status.Resources = cs.Resources
+			status.MemoryLimitBytes = cs.MemoryLimitBytes
+		}
+		if p := slices.Index(checkerStatePriority, cs.State); p > worst {
+			worst = p
+			status.State = cs.State
+		}
+		status.AutoRestarts += cs.AutoRestarts
+		status.ConsecutiveCrashes += cs.ConsecutiveCrashes
+		status.MemoryRestarts += cs.MemoryRestarts
+		status.Panics += cs.Panics
+		if p := cs.LastPanic; p != nil && (status.LastPanic == nil || p.At.After(status.LastPanic.At)) {
+			status.LastPanic = p
+		}
+		if cs.LastExitAt.After(status.LastExitAt) {
+			status.LastExit, status.LastExitAt = c.Name()+": "+cs.LastExit, cs.LastExitAt
+		}
+		if cs.Timings != nil && (status.Timings == nil || cs.Timings.LastMs > status.Timings.LastMs) {
+			status.Timings = cs.Timings // the slowest checker bounds the merged result
+		}
+		if c := cs.LastCycle; c != nil && (status.LastCycle == nil || c.CompletedAt.After(status.LastCycle.CompletedAt)) {
+			status.LastCycle = c // the merged result completes with the last checker
+		}
+		status.Checkers = append(status.Checkers, CheckerStatus{Name: c.Name(), Status: cs})
+	}
+	status.LastCrash = s.LastCrash()
+	return status
+}
+
+// LastCrash returns the most recent crash report of any checker.
+func (s *CheckerSet) LastCrash() *CrashReport {
+	var latest *CrashReport
+	for _, c := range s.checkers {
+		if r := c.LastCrash(); r != nil && (latest == nil || r.At.After(latest.At)) {
+			latest = r
+		}
+	}
+	return latest
+}
+
+// =============================================================================
+// One-Shot Checker
+// =============================================================================
+
+// OneShotChecker runs a tool without a watch mode (such as eslint) once on
+// Start and again on every Rerun or Restart, parsing its output into a result.
+// A run in progress is canceled when a newer one begins.
+type OneShotChecker struct {
+	name          string
+	workspacePath string
+	pm            PackageManager
+	env           EnvConfig
+	executor      kexec.Interface
+	command       func() (string, []string)
+	parse         func(output []byte, workspacePath string) (SvelteWatchCheckComplete, error)
+	audit         *AuditLog
+
+	mu         sync.Mutex
+	ctx        context.Context
+	cancel     context.CancelFunc
+	generation int
+	state      RunnerState
+	lastExit   string
+	lastExitAt time.Time
+	timer      checkTimer
+
+	// last is the most recent completed result, or the one loaded from
+	// store until a run completes.
+	store resultStore
+	last  *SvelteWatchCheckComplete
+
+	latest *valueSignal[SvelteWatchCheckComplete]
+}
+
+// Name returns the tool name.
+func (c *OneShotChecker) Name() string {
+	return c.name
+}
+
+// Start runs the tool in the background.
+func (c *OneShotChecker) Start(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ctx = ctx
+	c.runLocked()
+	return nil
+}
+
+// Stop cancels any run in progress.
+func (c *OneShotChecker) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+	c.state = RunnerStateStopped
+}
+
+// Restart starts a fresh run, canceling any in progress. As for Runner, the
+// run is under the context given to Start, or ctx if there was none.
+func (c *OneShotChecker) Restart(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ctx == nil {
+		c.ctx = ctx
+	}
+	c.runLocked()
+	return nil
+}
+
+// Rerun starts a fresh run if the checker has been started.
+func (c *OneShotChecker) Rerun() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ctx == nil || c.state == RunnerStateStopped {
+		return
+	}
+	c.runLocked()
+}
+
+// runLocked cancels any current run and starts a new one. c.mu must be held.
+func (c *OneShotChecker) runLocked() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.generation++
+	generation := c.generation
+	runCtx, cancel := context.WithCancel(c.ctx)
+	c.cancel = cancel
+	c.state = RunnerStateChecking
+	c.timer.start(0)
+	c.latest.Invalidate()
+	c.audit.Record("check-start", map[string]any{"checker": c.name, "workspace": c.workspacePath})
+
+	go c.run(runCtx, generation)
+}
+
+func (c *OneShotChecker) run(ctx context.Context, generation int) {
+	name, args := c.command()
+	cmd := c.executor.CommandContext(ctx, name, args...)
+	cmd.SetDir(c.workspacePath)
+	c.env.apply(cmd)
+	setProcessGroup(cmd, StopPolicy{})
+	output, runErr := cmd.Output()
+
+	result, err := c.parse(output, c.workspacePath)
+	if err != nil {
+		// Surface the failure as a diagnostic rather than leaving readers blocked.
+		result = SvelteWatchCheckComplete{
+			Timestamp:  time.Now().UnixMilli(),
+			ErrorCount: 1,
+			Diagnostics: []Diagnostic{{
+				Timestamp: time.Now().UnixMilli(),
+				Type:      "ERROR",
+				Message:   fmt.Sprintf("%s failed (%s): %v", c.name, describeExit(runErr), err),
+			}},
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation || ctx.Err() != nil {
+		return // superseded or stopped
+	}
+	c.lastExit = describeExit(runErr)
+	c.lastExitAt = time.Now()
+	c.timer.complete(0)
+	c.state = RunnerStateReady
+	if err != nil {
+		c.state = RunnerStateFailed
+		logger("runner").Error("check failed", "checker", c.name, "error", err)
+		c.audit.Record("failure", map[string]any{"checker": c.name, "workspace": c.workspacePath, "message": err.Error()})
+	} else {
+		logger("runner").Info("check completed", "checker", c.name, "errors", result.ErrorCount, "warnings", result.WarningCount)
+	}
+	c.last = &result
+	c.latest.Set(result)
+	c.store.save(result)
+	if err == nil {
+		c.audit.recordCheck(c.name, c.workspacePath, result)
+	}
+}
+
+// PeekLatest returns the most recent result without blocking, marked stale
+// while a run is in progress or if it was persisted by a previous daemon.
+func (c *OneShotChecker) PeekLatest() (SvelteWatchCheckComplete, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.last == nil {
+		return SvelteWatchCheckComplete{}, false
+	}
+	result := *c.last
+	if !result.Stale && c.state == RunnerStateChecking {
+		result.Stale = true
+		result.StaleReason = fmt.Sprintf("%s is %s", c.name, c.state)
+	}
+	return result, true
+}
+
+// GetLatestEvent blocks until a run completes, or ctx is done, and returns
+// its result.
+func (c *OneShotChecker) GetLatestEvent(ctx context.Context) (SvelteWatchCheckComplete, error) {
+	return c.latest.Wait(ctx)
+}
+
+// Status returns a snapshot of the checker's health.
+func (c *OneShotChecker) Status() RunnerStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return RunnerStatus{
+		State:          c.state,
+		PackageManager: c.pm,
+		LastExit:       c.lastExit,
+		LastExitAt:     c.lastExitAt,
+		Timings:        c.timer.summary(),
+	}
+}
+
+// LastCrash always returns nil: a one-shot tool exiting is expected.
+func (c *OneShotChecker) LastCrash() *CrashReport {
+	return nil
+}
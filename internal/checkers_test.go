@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// TestNewChecker tests that svelte-check alone keeps a plain Runner and any
+// other combination builds a CheckerSet.
+func TestNewChecker(t *testing.T) {
+	executor := NewFakeExecutor("", "")
+	config := RunnerConfig{WorkspacePath: "/workspace", Command: []string{"my-check"}}
+
+	if _, ok := NewChecker(config, nil, executor).(*Runner); !ok {
+		t.Error("NewChecker(nil) should return a *Runner")
+	}
+
+	c := NewChecker(config, []CheckerKind{CheckerSvelteCheck, CheckerTsc, CheckerESLint}, executor)
+	set, ok := c.(*CheckerSet)
+	if !ok {
+		t.Fatalf("NewChecker() = %T, want *CheckerSet", c)
+	}
+	if set.Name() != "svelte-check+tsc+eslint" {
+		t.Errorf("Name() = %q", set.Name())
+	}
+
+	lines := commandLines(set)
+	if len(lines) != 3 {
+		t.Fatalf("commandLines() = %q, want 3 lines", lines)
+	}
+	if !strings.HasPrefix(lines[0], "my-check") {
+		t.Errorf("svelte-check command = %q, want the custom command", lines[0])
+	}
+	if !strings.Contains(lines[1], "tsc --noEmit --pretty false --watch --preserveWatchOutput") {
+		t.Errorf("tsc command = %q, want the default tsc watch command", lines[1])
+	}
+	if !strings.Contains(lines[2], "eslint --format json .") {
+		t.Errorf("eslint command = %q", lines[2])
+	}
+}
+
+// TestParseCheckerKind tests validation of checker names.
+func TestParseCheckerKind(t *testing.T) {
+	if k, err := ParseCheckerKind("tsc"); err != nil || k != CheckerTsc {
+		t.Errorf("ParseCheckerKind(tsc) = %q, %v", k, err)
+	}
+	if _, err := ParseCheckerKind("prettier"); err == nil {
+		t.Error("expected error for unknown checker")
+	}
+}
+
+// TestCheckerSet_GetLatestEvent tests that results are merged and tagged with
+// the checker that produced them.
+func TestCheckerSet_GetLatestEvent(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		svelteOutput := `1770255832071 START "/workspace"
+1770255834342 {"type":"ERROR","filename":"src/a.svelte","start":{"line":0,"character":0},"end":{"line":0,"character":1},"message":"Svelte error","code":2322,"source":"svelte"}
+1770255834342 COMPLETED 100 FILES 1 ERRORS 0 WARNINGS 1 FILES_WITH_PROBLEMS
+`
+		tscOutput := `[12:00:00 PM] Starting compilation in watch mode...
+
+vite.config.ts(1,1): error TS2304: Cannot find name 'x'.
+scripts/b.ts(2,2): warning TS6133: 'y' is declared but its value is never read.
+
+[12:00:01 PM] Found 1 error. Watching for file changes.
+`
+		svelte := NewRunnerWithConfig(RunnerConfig{WorkspacePath: "/workspace"}, WithExecutor(NewFakeExecutor(svelteOutput, "")))
+		tsc := NewRunnerWithConfig(RunnerConfig{WorkspacePath: "/workspace", Checker: CheckerTsc}, WithExecutor(NewFakeExecutor(tscOutput, "")))
+		set := NewCheckerSet(svelte, tsc)
+
+		if err := set.Start(context.Background()); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		defer set.Stop()
+		if err := set.WaitReady(context.Background()); err != nil {
+			t.Fatalf("WaitReady failed: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		synctest.Wait()
+
+		result := latestEvent(t, set)
+		if result.FileCount != 100 || result.ErrorCount != 2 || result.WarningCount != 1 || result.FilesWithProblems != 3 {
+			t.Errorf("counts = %d files, %d errors, %d warnings, %d with problems; want 100, 2, 1, 3",
+				result.FileCount, result.ErrorCount, result.WarningCount, result.FilesWithProblems)
+		}
+		if len(result.Diagnostics) != 3 {
+			t.Fatalf("got %d diagnostics, want 3", len(result.Diagnostics))
+		}
+		if d := result.Diagnostics[0]; d.Checker != "svelte-check" || d.Source != "svelte" {
+			t.Errorf("svelte-check diagnostic checker/source = %q/%q", d.Checker, d.Source)
+		}
+		if d := result.Diagnostics[1]; d.Checker != "tsc" || d.Source != "ts" {
+			t.Errorf("tsc diagnostic checker/source = %q/%q", d.Checker, d.Source)
+		}
+
+		status := set.Status()
+		if status.State != RunnerStateReady {
+			t.Errorf("State = %q, want %q", status.State, RunnerStateReady)
+		}
+		if len(status.Checkers) != 2 || status.Checkers[1].Name != "tsc" {
+			t.Errorf("Checkers = %+v, want svelte-check and tsc", status.Checkers)
+		}
+	})
+}
+
+// TestCheckerSet_PeekLatest tests that nothing is returned until every
+// checker has a result, and that peeked results are tagged like merged ones.
+func TestCheckerSet_PeekLatest(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		svelteOutput := `1770255832071 START "/workspace"
+1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+`
+		tscOutput := `[12:00:00 PM] Starting compilation in watch mode...
+
+vite.config.ts(1,1): error TS2304: Cannot find name 'x'.
+
+[12:00:01 PM] Found 1 error. Watching for file changes.
+`
+		svelte := NewRunnerWithConfig(RunnerConfig{WorkspacePath: "/workspace"}, WithExecutor(NewFakeExecutor(svelteOutput, "")))
+		tsc := NewRunnerWithConfig(RunnerConfig{WorkspacePath: "/workspace", Checker: CheckerTsc}, WithExecutor(NewFakeExecutor(tscOutput, "")))
+		set := NewCheckerSet(svelte, tsc)
+
+		if _, ok := set.PeekLatest(); ok {
+			t.Error("PeekLatest() before any check: ok = true, want false")
+		}
+
+		_ = set.Start(context.Background())
+		defer set.Stop()
+		time.Sleep(10 * time.Millisecond)
+		synctest.Wait()
+
+		result, ok := set.PeekLatest()
+		if !ok || result.ErrorCount != 1 || result.Stale {
+			t.Fatalf("PeekLatest() = %+v, %v; want the fresh merged result", result, ok)
+		}
+		if d := result.Diagnostics[0]; d.Checker != "tsc" {
+			t.Errorf("diagnostic checker = %q, want tsc", d.Checker)
+		}
+	})
+}
+
+// TestOneShotChecker_Rerun tests that eslint runs on Start and again on Rerun,
+// and that an unparseable run is surfaced as an error diagnostic.
+func TestOneShotChecker_Rerun(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		outputs := []string{
+			`[{"filePath": "/workspace/src/a.ts", "messages": [{"ruleId": "eqeqeq", "severity": 2, "message": "Use ===", "line": 1, "column": 1}]}]`,
+			`[{"filePath": "/workspace/src/a.ts", "messages": []}]`,
+			"Oops! Something went wrong!",
+		}
+		executor := &FakeExecutor{}
+		executor.newCmd = func() *FakeCmd {
+			out := outputs[0]
+			outputs = outputs[1:]
+			return &FakeCmd{stdout: io.NopCloser(bytes.NewBufferString(out))}
+		}
+		c := NewESLintChecker(RunnerConfig{WorkspacePath: "/workspace"}, executor)
+
+		if err := c.Start(context.Background()); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		defer c.Stop()
+		synctest.Wait()
+
+		if result := latestEvent(t, c); result.ErrorCount != 1 || result.Diagnostics[0].Filename != "src/a.ts" {
+			t.Errorf("first run = %+v, want one error in src/a.ts", result)
+		}
+
+		c.Rerun()
+		synctest.Wait()
+		if result := latestEvent(t, c); result.ErrorCount != 0 || result.FileCount != 1 {
+			t.Errorf("rerun = %+v, want a clean result", result)
+		}
+
+		c.Rerun()
+		synctest.Wait()
+		if result := latestEvent(t, c); result.ErrorCount != 1 || c.Status().State != RunnerStateFailed {
+			t.Errorf("failed run = %+v (state %q), want an error diagnostic and failed state", result, c.Status().State)
+		}
+
+		c.Stop()
+		c.Rerun()
+		if c.Status().State != RunnerStateStopped {
+			t.Error("Rerun after Stop should not start a run")
+		}
+	})
+}
@@ -1,19 +1,28 @@
 package internal
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"maps"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
 	kexec "k8s.io/utils/exec"
 )
 
-// stringSlice is a flag.Value that collects multiple -r or -d flags.
+// stringSlice is a flag.Value that collects repeated flags such as -r, -d, and --env.
 type stringSlice []string
 
 func (s *stringSlice) String() string {
@@ -25,6 +34,179 @@ func (s *stringSlice) Set(value string) error {
 	return nil
 }
 
+// runnerFlags holds the flags shared by commands that run svelte-check.
+type runnerFlags struct {
+	tsconfig        string
+	packageManager  string
+	command         string
+	monitorInterval string
+	maxMemory       string
+	startupTimeout  string
+	restartCooldown string
+	lockfileGrace   string
+	noLockRestart   bool
+	interruptGrace  string
+	terminateGrace  string
+	historySize     int
+	failOn          string
+	failureStatus   int
+	exitCodes       string
+	watchIgnore     stringSlice
+	watchBackend    string
+	pollInterval    string
+	pollFallback    bool
+	followSymlinks  bool
+	maxWatchers     int
+	auditLog        string
+	auditLogMaxSize string
+	journal         string
+	journalMaxSize  string
+	otlpEndpoint    string
+	autoWatch       string
+	noSync          bool
+	monorepo        bool
+	checkers        string
+	env             stringSlice
+	inheritEnv      string
+	denyEnv         string
+	container       bool
+}
+
+// launchConfig is the result of merging runnerFlags over the config file.
+type launchConfig struct {
+	runner          RunnerConfig    // workspace-wide settings
+	projects        []ProjectConfig // empty for a single project at the root
+	checkers        []CheckerKind   // tools run for each project
+	startupTimeout  time.Duration   // 0 waits indefinitely
+	restartCooldown time.Duration   // minimum time between watcher-triggered restarts
+	lockfileRestart bool            // restart after a lockfile changes
+	lockfileGrace   time.Duration   // wait after the last lockfile change
+	syncOnStart     bool            // run svelte-kit sync before the first check
+	policy          CheckPolicy     // which results fail /check
+	ignore          *IgnoreRules    // diagnostics removed from /check results
+	watchIgnore     []string        // globs the watcher skips
+	watchSkipDirs   []string        // nil for DefaultSkipDirs
+	watchBackend    string          // WatchBackendNotify, WatchBackendPoll, or WatchBackendWatchman
+	pollInterval    time.Duration   // scan interval of the poll backend
+	pollFallback    bool            // poll directories the notify backend cannot watch
+	followSymlinks  bool            // descend into symlinked directories
+	maxWatchers     int             // filesystem watchers the daemon may open
+	syncOn          []string        // nil for each project's syncGlobs
+	restartOn       []string        // nil for each project's restartGlobs
+	rules           []RuleConfig    // converted by EventRules when starting
+	notify          NotifyConfig    // converted by Notifications when starting
+	hooks           ExecHooksConfig // converted by NewExecHooks when starting
+	autoWatch       []string        // globs of directories watched once they exist
+	auditLog        string          // absolute path of the audit log; "" for none
+	auditLogMaxSize int64           // size at which the audit log is rotated
+	journal         string          // absolute path of the event journal; "" for none
+	journalMaxSize  int64           // size at which the journal is rotated
+	tracer          TracerConfig    // where to export traces; no Endpoint for none
+}
+
+// newFSWatcher creates the filesystem watcher for the configured backend.
+// The notify backend counts against limit.
+func (lc launchConfig) newFSWatcher(executor kexec.Interface, limit *WatcherLimit) (FSWatcher, error) {
+	switch lc.watchBackend {
+	case WatchBackendPoll:
+		w := NewPollingFSWatcher(lc.pollInterval)
+		if lc.watchSkipDirs != nil {
+			w.SetSkipDirs(lc.watchSkipDirs)
+		}
+		return w, nil
+	case WatchBackendWatchman:
+		sock, err := WatchmanSocket(executor)
+		if err != nil {
+			return nil, err
+		}
+		w, err := NewWatchmanFSWatcher(sock)
+		if err != nil {
+			return nil, err
+		}
+		if lc.watchSkipDirs != nil {
+			w.SetSkipDirs(lc.watchSkipDirs)
+		}
+		return w, nil
+	}
+
+	w, err := NewRealFSWatcherWithLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	if lc.watchSkipDirs != nil {
+		w.SetSkipDirs(lc.watchSkipDirs)
+	}
+	w.SetFollowSymlinks(lc.followSymlinks)
+	if lc.pollFallback {
+		w.SetPollFallback(lc.pollInterval)
+	}
+	return w, nil
+}
+
+// newChecker creates the Checker for one project.
+func (lc launchConfig) newChecker(config RunnerConfig, executor kexec.Interface) Checker {
+	return NewChecker(config, lc.checkers, executor)
+}
+
+// register adds the flags to fs. Resource flags only apply to the watch
+// process and are registered when watch is true.
+func (f *runnerFlags) register(fs *flag.FlagSet, watch bool) {
+	fs.StringVar(&f.tsconfig, "tsconfig", "", "Path to tsconfig.json (not with --command)")
+	fs.StringVar(&f.packageManager, "package-manager", "", "Package manager: bun, npm, pnpm, yarn, or deno")
+	fs.StringVar(&f.command, "command", "", "Custom svelte-check command (replaces the default invocation)")
+	fs.BoolVar(&f.monorepo, "monorepo", false, "Run one svelte-check per Svelte package in the workspace")
+	fs.Var(&f.env, "env", "Set KEY=VALUE in the svelte-check environment (can be repeated)")
+	fs.StringVar(&f.inheritEnv, "inherit-env", "", "Comma-separated variables (or globs) to pass through from this environment; default all")
+	fs.StringVar(&f.denyEnv, "deny-env", "", "Comma-separated variables (or globs) never passed through")
+	if watch {
+		fs.StringVar(&f.checkers, "checkers", "", "Comma-separated checkers to run: svelte-check, tsc, eslint (default: svelte-check)")
+		fs.StringVar(&f.monitorInterval, "monitor-interval", "", "How often to sample svelte-check memory/CPU (default 10s, 0 disables)")
+		fs.StringVar(&f.maxMemory, "max-memory", "", "Restart svelte-check when it exceeds this much memory, e.g. 4GB")
+		fs.StringVar(&f.restartCooldown, "restart-cooldown", "", "Minimum time between restarts triggered by file or git changes (default 5s, 0 disables)")
+		fs.StringVar(&f.lockfileGrace, "lockfile-grace", "", "Restart svelte-check this long after a lockfile stops changing (default 3s)")
+		fs.BoolVar(&f.noLockRestart, "no-lockfile-restart", false, "Do not restart svelte-check when a lockfile changes")
+		fs.StringVar(&f.interruptGrace, "interrupt-grace", "", "When stopping svelte-check, wait this long after SIGINT before SIGTERM (default 3s, 0 skips SIGINT)")
+		fs.StringVar(&f.terminateGrace, "terminate-grace", "", "When stopping svelte-check, wait this long after SIGTERM before SIGKILL (default 10s)")
+		fs.IntVar(&f.historySize, "history-size", 0, "Completed check results to retain per checker (default 20)")
+		fs.StringVar(&f.failOn, "fail-on", "", "Comma-separated severities that fail check: failure, error, warning (default: failure,error)")
+		fs.IntVar(&f.failureStatus, "failure-status", 0, "HTTP status of /check when svelte-check reports a FAILURE: 502 or 500 (default 502)")
+		fs.StringVar(&f.exitCodes, "exit-codes", "", "Comma-separated SEVERITY=CODE exit codes for check (default: failure=2,error=1,warning=1)")
+		fs.Var(&f.watchIgnore, "ignore", "Glob of paths the watcher skips, relative to the workspace (can be repeated)")
+		fs.StringVar(&f.watchBackend, "watch-backend", "", "How to watch files: notify, poll, or watchman (default notify)")
+		fs.StringVar(&f.pollInterval, "poll-interval", "", "How often the poll backend scans for changes (default 1s)")
+		fs.BoolVar(&f.pollFallback, "poll-fallback", false, "Poll directories that cannot be watched because the OS ran out of watches")
+		fs.BoolVar(&f.followSymlinks, "follow-symlinks", false, "Watch inside symlinked directories")
+		fs.IntVar(&f.maxWatchers, "max-watchers", 0, "Maximum filesystem watchers the server opens (default 100)")
+		fs.StringVar(&f.autoWatch, "auto-watch", "", "Comma-separated globs of directories to watch recursively, including those created later, e.g. packages/*/src")
+		fs.StringVar(&f.auditLog, "audit-log", "", "Append watcher events, restarts, syncs, and checks as JSON lines to this file")
+		fs.StringVar(&f.auditLogMaxSize, "audit-log-max-size", "", "Rotate the audit log at this size, e.g. 50MB (default 10MB)")
+		fs.StringVar(&f.journal, "journal", "", "Append check starts, summaries, failures, restarts, and syncs as JSON lines to this file")
+		fs.StringVar(&f.journalMaxSize, "journal-max-size", "", "Rotate the journal at this size, e.g. 50MB (default 10MB)")
+		fs.StringVar(&f.otlpEndpoint, "otlp-endpoint", "", "Export traces of check cycles and requests to this OTLP/HTTP URL (default from OTEL_EXPORTER_OTLP_ENDPOINT)")
+		fs.BoolVar(&f.noSync, "no-sync", false, "Skip running svelte-kit sync before the first check")
+		fs.StringVar(&f.startupTimeout, "startup-timeout", "", "Fail if svelte-check has not started a check within this long (default 60s, 0 waits indefinitely)")
+		fs.BoolVar(&f.container, "container", false, "Run as a container's daemon: poll for changes, serve TCP when a token is set, and reap zombies as PID 1")
+	}
+}
+
+// defaultMonitorInterval is used when neither flag nor config file sets one.
+const defaultMonitorInterval = 10 * time.Second
+
+// defaultRestartCooldown spaces out restarts during bursts of git activity
+// such as an interactive rebase.
+const defaultRestartCooldown = 5 * time.Second
+
+// defaultLockfileGrace lets a package install finish before restarting.
+const defaultLockfileGrace = 3 * time.Second
+
+// defaultStartupTimeout bounds how long start waits for the first check to
+// begin when neither flag nor config file sets a timeout.
+const defaultStartupTimeout = 60 * time.Second
+
+// requestTimeout bounds the requests of commands that do not wait for a
+// check, such as stop and status.
+const requestTimeout = 10 * time.Second
+
 // Run is the main entry point for the CLI.
 func Run() {
 	if len(os.Args) < 2 {
@@ -40,8 +222,36 @@ func Run() {
 		cmdStart(args)
 	case "check":
 		cmdCheck(args)
+	case "wait":
+		cmdWait(args)
+	case "watch":
+		cmdWatch(args)
 	case "stop":
 		cmdStop(args)
+	case "status":
+		cmdStatus(args)
+	case "restart":
+		cmdRestart(args)
+	case "watch-dirs":
+		cmdWatchDirs(args)
+	case "baseline":
+		cmdBaseline(args)
+	case "action":
+		cmdAction(args)
+	case "hook-impl":
+		cmdHookImpl(args)
+	case "annotate":
+		cmdAnnotate(args)
+	case "statusline":
+		cmdStatusline(args)
+	case "ci":
+		cmdCI(args)
+	case "exec":
+		cmdExec(args)
+	case "healthcheck":
+		cmdHealthcheck(args)
+	case "service":
+		cmdService(args)
 	case "help", "-h", "--help":
 		printUsage()
 	default:
@@ -55,52 +265,346 @@ func printUsage() {
 	fmt.Println(`svelte-check-server - Fast svelte-check with persistent watch process
 
 Usage:
-  svelte-check-server <command> [options]
+  svelte-check-server <command> [options] [-- <svelte-check args>]
 
 Commands:
   start     Start the server (runs svelte-check --watch in background)
   check     Get check results (falls back to direct execution if server not running)
+  wait      Wait until a check passes, then print its result
+  watch     Print every result as checks complete, e.g. for a VS Code task
   stop      Stop the server
+  status    Show the server's health, restart counters, and check timings
+  restart   Restart the checkers of a running server
+  watch-dirs
+            Show or change the directories a running server watches
+  baseline  'baseline write' records current diagnostics in .svelte-check-baseline.json
+            so they are excluded from check results
+  action    Check once in GitHub Actions: annotate diagnostics, write a job summary,
+            and set the error_count and warning_count outputs
+  hook-impl Check the given or staged files, as a pre-commit hook
+  annotate  Comment on a pull or merge request's changed lines that have diagnostics
+  statusline
+            Print a short summary of the latest result for a shell prompt
+  ci        Run one check without a server, print it, and exit by policy
+  exec      Run a command once the current check passes, e.g. exec -- playwright test
+  healthcheck
+            Exit 0 if the server is healthy, else print why and exit 1
+  service   'service install' runs the server as a systemd user service or launchd
+            agent that survives reboots; 'service uninstall' removes it
 
 Options for 'start':
   -w, --workspace <path>   Working directory (default: current directory)
   -r <dir>                 Add recursive watch directory (can be repeated)
   -d <dir>                 Add non-recursive watch directory (can be repeated)
-  --tsconfig <path>        Path to tsconfig.json
+  --tsconfig <path>        Path to tsconfig.json (not with --command)
+  --package-manager <pm>   bun, npm, pnpm, yarn, or deno (default: detected from lockfile)
+  --command <cmd>          Replace the svelte-check invocation entirely
+                           (must include --watch --output machine-verbose)
+  --monorepo               Run svelte-check in every Svelte package listed by
+                           pnpm-workspace.yaml or package.json "workspaces"
+  --env <KEY=VALUE>        Set a variable for svelte-check, e.g.
+                           NODE_OPTIONS=--max-old-space-size=8192 (can be repeated)
+  --inherit-env <list>     Only pass these variables (or globs) through (default: all)
+  --deny-env <list>        Never pass these variables (or globs) through
+  --checkers <list>        Checkers to run and merge: svelte-check, tsc, eslint
+                           (default: svelte-check)
+  --monitor-interval <d>   Sample svelte-check memory/CPU every <d> (default: 10s, 0 disables)
+  --max-memory <size>      Restart svelte-check above this RSS, e.g. 4GB (default: no limit)
+  --restart-cooldown <d>   Minimum time between restarts on file or git changes;
+                           bursts are coalesced (default: 5s, 0 disables)
+  --lockfile-grace <d>     Restart <d> after a lockfile stops changing, e.g. after
+                           an install (default: 3s)
+  --no-lockfile-restart    Only mark results stale when a lockfile changes
+  --interrupt-grace <d>    When stopping svelte-check, wait <d> after SIGINT before
+                           SIGTERM (default: 3s, 0 skips SIGINT)
+  --terminate-grace <d>    Wait <d> after SIGTERM before SIGKILL (default: 10s)
+  --history-size <n>       Completed results to retain per checker (default: 20)
+  --fail-on <list>         Severities that fail 'check': failure, error, warning
+                           (default: failure,error)
+  --failure-status <code>  HTTP status of /check on a svelte-check FAILURE:
+                           502 or 500 (default: 502)
+  --exit-codes <list>      Exit codes of 'check' per severity, e.g. warning=3
+                           (default: failure=2,error=1,warning=1)
+  --ignore <glob>          Do not watch matching paths, e.g. 'coverage/**' or
+                           '**/*.stories.ts' (can be repeated)
+  --watch-backend <b>      notify (default); poll, for Docker bind mounts and
+                           network filesystems that do not deliver change events;
+                           or watchman, to use a Watchman daemon in very large
+                           repositories
+  --poll-interval <d>      How often the poll backend scans (default: 1s)
+  --poll-fallback          Poll directories that cannot be watched because the
+                           OS ran out of inotify watches or file descriptors
+  --follow-symlinks        Watch inside symlinked directories, e.g. src/lib/shared
+                           linking into a sibling package (cycles are detected)
+  --max-watchers <n>       Maximum filesystem watchers the server opens (default:
+                           100); see "watcher" in status for current usage
+  --auto-watch <globs>     Watch directories matching these comma-separated globs
+                           recursively, including ones created later, e.g.
+                           packages/*/src for newly scaffolded packages
+  --audit-log <path>       Append every watcher event, git trigger, restart, sync,
+                           and check summary to <path> as JSON lines
+  --audit-log-max-size <s> Rotate the audit log at <s>, keeping 3 old files
+                           (default: 10MB)
+  --journal <path>         Append every check's start, summary, and failures, and
+                           restarts and syncs, to <path> as JSON lines
+  --journal-max-size <s>   Rotate the journal at <s>, keeping 3 old files
+                           (default: 10MB)
+  --otlp-endpoint <url>    Export traces of check cycles and requests as OTLP/HTTP
+                           JSON (default: OTEL_EXPORTER_OTLP_ENDPOINT + /v1/traces)
+  --no-sync                Do not run svelte-kit sync before the first check
+  --startup-timeout <d>    Fail if svelte-check has not started a check within <d>
+                           (default: 60s, 0 waits indefinitely)
+  --listen <addr>          Also serve on a TCP address, e.g. 0.0.0.0:7420 in a
+                           container; clients must send SVELTE_CHECK_SERVER_TOKEN
+  --container              Defaults for a container or devcontainer sidecar: the
+                           poll watch backend, the socket at
+                           SVELTE_CHECK_SERVER_SOCKET (e.g. in a shared volume),
+                           --listen 0.0.0.0:7420 when a token is set, and, as PID
+                           1, forwarding signals and reaping zombie processes
+  --log-level <level>      debug, info, warn, or error (default: info)
+  --log-format <format>    text or json, one object per line (default: text)
+  --debug <subsystems>     Also log debug entries of these subsystems, e.g.
+                           watcher,git,interpreter for every event and output line
 
 Options for 'check':
   -w, --workspace <path>   Working directory (default: current directory)
-  --tsconfig <path>        Path to tsconfig.json
+  --tsconfig <path>        Path to tsconfig.json (not with --command)
+  --package-manager <pm>   bun, npm, pnpm, yarn, or deno (default: detected from lockfile)
+  --command <cmd>          Replace the svelte-check invocation for direct runs
+  --monorepo               Check every workspace package (direct runs only)
+  --env, --inherit-env, --deny-env
+                           Environment for direct runs, as for 'start'
+  --project <name>         Only report this project or package (default: all merged)
+  --format <human|json>    Output format (default: human)
+  --timeout <duration>     Timeout waiting for check to complete (default: 2m)
+  --stale                  Return the most recent result at once, even one from
+                           before the server restarted, marked stale
+  --hash-inputs            Also print a digest of the files git does not ignore
+                           under the workspace or project, and their contents, to
+                           cache passing checks in turborepo, nx, or bazel; files
+                           outside the project's directory are not covered
+  --link-scheme <scheme>   On a terminal, link each diagnostic's location as an
+                           OSC 8 hyperlink: file, vscode, cursor, another editor's
+                           URL scheme, or none (default: SVELTE_CHECK_LINK_SCHEME,
+                           else file)
+
+Options for 'wait':
+  -w, --workspace <path>   Working directory (default: current directory)
+  --project <name>         Only wait for this project (default: all merged)
   --format <human|json>    Output format (default: human)
+  --timeout <duration>     Give up and exit 1 after <duration> (default: 10m)
+  --link-scheme <scheme>   Link diagnostics, as for 'check'
+
+Options for 'watch':
+  -w, --workspace <path>   Working directory (default: current directory)
+  --project <name>         Only watch this project (default: all merged)
+  --format <format>        human, json (one result per line), or vscode (see the
+                           problemMatcher in the README) (default: human)
+  --link-scheme <scheme>   Link diagnostics, as for 'check'
+  Prints the current result, then each new one. Without a running server, starts
+  one, taking the options of 'start', and stops it on exit.
+
+Options for 'status':
+  -w, --workspace <path>   Working directory (default: current directory)
+  --format <human|json>    Output format (default: human)
+
+Options for 'restart':
+  -w, --workspace <path>   Working directory (default: current directory)
+  --project <name>         Only restart this project (default: all)
+
+Options for 'watch-dirs':
+  -w, --workspace <path>   Working directory (default: current directory)
+  -r <dir>                 Start watching a directory recursively (can be repeated)
+  -d <dir>                 Start watching a directory non-recursively (can be repeated)
+  --remove <dir>           Stop watching a directory (can be repeated)
+
+Options for 'baseline write':
+  -w, --workspace <path>   Working directory (default: current directory)
   --timeout <duration>     Timeout waiting for check to complete (default: 2m)
 
+Options for 'action':
+  -w, --workspace <path>   Working directory (default: current directory)
+  --tsconfig, --package-manager, --command, --monorepo, --env, --inherit-env,
+  --deny-env               As for 'start', when no server is running
+  --project <name>         Only check this project (default: all merged)
+  --timeout <duration>     Give up after <duration> (default: 15m)
+  Diagnostics are printed as ::error and ::warning workflow commands; the job
+  summary and outputs go to $GITHUB_STEP_SUMMARY and $GITHUB_OUTPUT when set.
+
+Options for 'hook-impl' [files...]:
+  -w, --workspace <path>   Working directory (default: current directory)
+  --tsconfig, --package-manager, --command, --monorepo, --env, --inherit-env,
+  --deny-env               As for 'start', when no server is running
+  --staged                 Without files, report on the files staged in git
+  --project <name>         Only check this project (default: all merged)
+  --format <format>        human, quickfix (file:line:col: type: message), or json
+                           (default: human)
+  --timeout <duration>     Give up after <duration> (default: 10m)
+  --link-scheme <scheme>   Link diagnostics, as for 'check'
+  Only diagnostics in the given files count, and no check runs when none of
+  them is a .svelte, .ts, or .js file. Exits as 'check' does.
+
+Options for 'annotate':
+  -w, --workspace <path>   Working directory (default: current directory)
+  --tsconfig, --package-manager, --command, --monorepo, --env, --inherit-env,
+  --deny-env               As for 'start', when no server is running
+  --provider <provider>    github or gitlab (default: github)
+  --pr <n>                 Pull or merge request number (default: from
+                           $GITHUB_REF or $CI_MERGE_REQUEST_IID)
+  --repo <repo>            owner/name on GitHub, project ID or path on GitLab
+                           (default: $GITHUB_REPOSITORY or $CI_PROJECT_ID)
+  --project <name>         Only check this project (default: all merged)
+  --timeout <duration>     Give up after <duration> (default: 15m)
+  The token is read from $GITHUB_TOKEN or $GITLAB_TOKEN. Diagnostics already
+  commented on by an earlier run are skipped, as are those on unchanged lines.
+
+Options for 'ci':
+  -w, --workspace <path>   Working directory (default: current directory)
+  --tsconfig, --package-manager, --command, --monorepo, --env, --inherit-env,
+  --deny-env               As for 'start'
+  --project <name>         Only check this project (default: all merged)
+  --format <format>        human, json, quickfix, or github (workflow command
+                           annotations) (default: human)
+  --report <path>          Also write the result to <path>: JSON, or a
+                           markdown summary if <path> ends in .md
+  --timeout <duration>     Give up after <duration> (default: 15m)
+  --link-scheme <scheme>   Link diagnostics, as for 'check'
+  Runs the checkers until each completes one cycle, then stops them; no
+  socket or watcher is left behind. Exits as 'check' does.
+
+Options for 'exec' -- <command> [args...]:
+  -w, --workspace <path>   Working directory (default: current directory)
+  --tsconfig, --package-manager, --command, --monorepo, --env, --inherit-env,
+  --deny-env               As for 'start', when no server is running
+  --project <name>         Only gate on this project (default: all merged)
+  --timeout <duration>     Give up waiting for the check after <duration>
+                           (default: 10m)
+  --link-scheme <scheme>   Link diagnostics, as for 'check'
+  Waits for the check in progress, if any. If the result passes, runs
+  <command> in place of this process, which exits as it does; otherwise prints
+  the result and exits as 'check' does, without running it.
+
+Options for 'statusline':
+  -w, --workspace <path>   Working directory (default: current directory)
+  --style <style>          emoji (✖3 ⚠12 2m) or plain (E3 W12 2m) (default: emoji)
+  --project <name>         Only this project (default: all merged)
+  Never waits for a check: prints the latest result and its age, or nothing
+  and exits 1 if the server does not answer within 100ms.
+
+Options for 'healthcheck':
+  -w, --workspace <path>   Working directory (default: current directory)
+  --timeout <duration>     Give up after <duration> (default: 3s)
+  For Docker HEALTHCHECK: exits 1 if the server does not answer, or a checker
+  or subsystem has stopped or given up restarting.
+
+Options for 'service install' and 'service uninstall':
+  -w, --workspace <path>   Working directory (default: current directory)
+  On Linux, writes a systemd user service and socket to ~/.config/systemd/user;
+  systemd listens on the socket and starts the server on the first request. On
+  macOS, writes a launchd agent to ~/Library/LaunchAgents that starts the server
+  at login, logging to ~/Library/Logs/svelte-check-server. The server runs this
+  binary with the current PATH; settings come from the config file.
+
+Arguments after '--' are passed through to svelte-check for 'start' and 'check', e.g.
+  svelte-check-server start -- --ignore "dist/**" --diagnostic-sources js,svelte
+
+Configuration:
+  Settings may also be placed in .svelte-check-server.json in the workspace
+  ("tsconfig", "packageManager", "command", "args", "monitorInterval",
+  "maxMemory", "syncOnStart", "startupTimeout", "restartCooldown",
+  "lockfileRestart", "lockfileGrace", "interruptGrace", "terminateGrace",
+  "historySize", "failOn", "failureStatus", "exitCodes", "ignore",
+  "excludeGenerated", "excludeDiagnostics", "watchIgnore", "watchSkipDirs",
+  "watchBackend", "pollInterval", "pollFallback", "followSymlinks", "syncOn",
+  "restartOn", "rules", "autoWatch", "maxWatchers", "auditLog",
+  "auditLogMaxSize", "journal", "journalMaxSize", "otlpEndpoint", "notify",
+  "hooks", "projects", "monorepo", "checkers", "env", "inheritEnv",
+  "denyEnv").
+  Flags take precedence; --env adds to "env". --tsconfig overrides "projects"
+  with a single project.
+
+JSON output:
+  Every command accepts --json to print one JSON document on stdout instead of
+  text: check and wait print the check result, status the /status response,
+  start the socket and PID, and stop, restart, watch-dirs, and baseline write
+  what they did. Failures print {"error": {"code", "message"}}, where code is
+  not_running, startup_failed, timeout, version_mismatch, or failed, and exit
+  non-zero.
+
+Version checks:
+  Commands that talk to a running server warn when it is older than the
+  client, e.g. still running after an upgrade, and should be restarted.
+  --strict-version makes this an error.
+
+Remote servers:
+  Commands other than start connect to SVELTE_CHECK_SERVER_ADDR, the host:port
+  of a server started with --listen, sending SVELTE_CHECK_SERVER_TOKEN, or to
+  SVELTE_CHECK_SERVER_SOCKET, e.g. a socket forwarded with ssh -L, when set.
+
 Defaults:
   - Watch '.' non-recursively
-  - Watch './src' recursively
-  - Watch '.git/HEAD' and current branch ref for git changes`)
+  - Watch './src' recursively (each project's or package's src in multi-project mode),
+    plus kit.files.routes and kit.files.lib from svelte.config when outside src
+  - Watch '.git/HEAD' and current branch ref for git changes, and those of
+    submodules inside the workspace
+  - Run svelte-kit sync in SvelteKit projects before the first check, and again
+    when route files or directories, param matchers, src/app.d.ts, or
+    src/hooks.server.* change ("syncOn" overrides the latter three)
+  - Restart svelte-check when *.d.ts files in a project root or its types
+    directory change ("restartOn" overrides the globs)
+  - Suppress diagnostics in .svelte-kit and node_modules directories and in a
+    project's own build directory
+  - Never descend into node_modules, .git, or .svelte-kit directories, or a
+    project's own build, dist, or kit.outDir, when watching recursively
+    ("watchSkipDirs" overrides the list)`)
 }
 
 func cmdStart(args []string) {
 	fs := flag.NewFlagSet("start", flag.ExitOnError)
 
 	var workspace string
-	var tsconfig string
+	var rf runnerFlags
 	var recursiveDirs stringSlice
 	var nonRecursiveDirs stringSlice
+	var listen string
 
 	fs.StringVar(&workspace, "w", ".", "Working directory")
 	fs.StringVar(&workspace, "workspace", ".", "Working directory")
-	fs.StringVar(&tsconfig, "tsconfig", "", "Path to tsconfig.json")
+	rf.register(fs, true)
 	fs.Var(&recursiveDirs, "r", "Recursive watch directory (can be repeated)")
 	fs.Var(&nonRecursiveDirs, "d", "Non-recursive watch directory (can be repeated)")
+	fs.StringVar(&listen, "listen", "", "Also serve on this TCP address, requiring "+EnvServerToken)
+	logs := registerLogFlags(fs)
+	out := registerJSON(fs)
 
 	if err := fs.Parse(args); err != nil {
 		os.Exit(1)
 	}
-
-	if len(recursiveDirs) == 0 && len(nonRecursiveDirs) == 0 {
-		nonRecursiveDirs = []string{"."}
-		recursiveDirs = []string{"./src"}
+	if err := logs.apply(os.Stderr); err != nil {
+		out.fail(errCodeFailed, 1, "%v", err)
+	}
+	listener, err := ActivationListener()
+	if err != nil {
+		out.fail(errCodeFailed, 1, "%v", err)
+	}
+	var socketPath string
+	if rf.container {
+		if os.Getpid() == 1 {
+			runAsInit()
+		}
+		// Serve where clients in other containers look, e.g. a shared
+		// volume, and on TCP for the host when a token is set.
+		if socketPath = os.Getenv(EnvServerSocket); socketPath != "" {
+			if err := os.MkdirAll(filepath.Dir(socketPath), 0o755); err != nil {
+				out.fail(errCodeFailed, 1, "%v", err)
+			}
+		}
+		if listen == "" && os.Getenv(EnvServerToken) != "" {
+			listen = ContainerListenAddr
+		}
+	}
+	if listen != "" && os.Getenv(EnvServerToken) == "" {
+		out.fail(errCodeFailed, 1, "--listen requires a token in %s", EnvServerToken)
 	}
 
 	if workspace == "." {
@@ -111,98 +615,41 @@ func cmdStart(args []string) {
 		}
 	}
 
-	socketPath, err := SocketPathForWorkspace(workspace)
-	if err != nil {
-		log.Fatalf("Failed to get socket path: %v", err)
-	}
-
-	if SocketExists(socketPath) {
-		log.Fatalf("Server already running (socket exists at %s)", socketPath)
-	}
-
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Create the real executor for production use
-	executor := kexec.New()
-
-	r := NewRunner(workspace, tsconfig, executor)
-	if err := r.Start(ctx); err != nil {
-		log.Fatalf("Failed to start svelte-check: %v", err)
-	}
-
-	srv := NewServer(socketPath, r)
-	if err := srv.Start(); err != nil {
-		r.Stop()
-		log.Fatalf("Failed to start server: %v", err)
-	}
-
-	watcherConfig := WatcherConfig{
-		WorkspacePath:    workspace,
+	lc := rf.resolve(workspace, fs.Args())
+	d, err := startDaemon(ctx, lc, DaemonOptions{
+		Workspace:        workspace,
 		RecursiveDirs:    recursiveDirs,
 		NonRecursiveDirs: nonRecursiveDirs,
-	}
-
-	callbacks := WatcherCallbacks{
-		OnRestart: func() {
-			log.Println("File change detected, restarting svelte-check...")
-			if err := r.Restart(ctx); err != nil {
-				log.Printf("Failed to restart svelte-check: %v", err)
-			}
-		},
-		OnSvelteSync: func() {
-			log.Println("Running svelte-kit sync...")
-			if err := RunSvelteKitSync(ctx, workspace, executor); err != nil {
-				log.Printf("svelte-kit sync failed: %v", err)
-			} else {
-				log.Println("svelte-kit sync completed")
-			}
-		},
-	}
-
-	fsWatcher, err := NewRealFSWatcher()
+		SocketPath:       socketPath,
+		Listener:         listener,
+		Listen:           listen,
+		Token:            os.Getenv(EnvServerToken),
+	})
 	if err != nil {
-		_ = srv.Stop(ctx)
-		r.Stop()
-		log.Fatalf("Failed to create filesystem watcher: %v", err)
+		out.startupFailed(err)
 	}
-
-	gitBranchWatcher, err := NewRealGitBranchWatcher(workspace, executor)
-	if err != nil {
-		_ = srv.Stop(ctx)
-		r.Stop()
-		log.Fatalf("Failed to create git branch watcher: %v", err)
+	if out.json {
+		out.result(startInfo{Workspace: workspace, Socket: d.SocketPath(), TCPAddr: d.srv.TCPAddr(), PID: os.Getpid()}, nil)
 	}
 
-	w := NewWatcher(watcherConfig, callbacks, fsWatcher, gitBranchWatcher)
-
-	// Start git branch watcher in background
-	go gitBranchWatcher.Start(ctx)
-
-	go w.Start(ctx)
-
-	log.Printf("Server started on %s", socketPath)
-	log.Printf("Watching directories: %v (non-recursive), %v (recursive)", nonRecursiveDirs, recursiveDirs)
-
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	select {
 	case <-sigCh:
-	case <-srv.ShutdownCh():
+	case <-d.Done():
 	}
 
 	log.Println("Shutting down...")
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), daemonStopTimeout)
 	defer shutdownCancel()
 
-	_ = w.Close()
-	_ = gitBranchWatcher.Close()
-	r.Stop()
-	if err := srv.Stop(shutdownCtx); err != nil {
+	if err := d.Stop(shutdownCtx); err != nil {
 		log.Printf("Error stopping server: %v", err)
 	}
-
 	log.Println("Server stopped")
 }
 
@@ -210,19 +657,33 @@ func cmdCheck(args []string) {
 	fs := flag.NewFlagSet("check", flag.ExitOnError)
 
 	var workspace string
-	var tsconfig string
+	var rf runnerFlags
+	var project string
 	var timeout time.Duration
 	var format string
+	var allowStale bool
+	var hashInputs bool
 
 	fs.StringVar(&workspace, "w", ".", "Working directory")
 	fs.StringVar(&workspace, "workspace", ".", "Working directory")
-	fs.StringVar(&tsconfig, "tsconfig", "", "Path to tsconfig.json")
+	rf.register(fs, false)
+	fs.StringVar(&project, "project", "", "Only report this project (default: all projects)")
 	fs.DurationVar(&timeout, "timeout", 120*time.Second, "Timeout waiting for check to complete")
 	fs.StringVar(&format, "format", "human", "Output format: human or json")
+	fs.BoolVar(&allowStale, "stale", false, "Return the most recent result at once instead of waiting for a check in progress")
+	fs.BoolVar(&hashInputs, "hash-inputs", false, "Also print a digest of the files under the workspace or project and their contents, for build caches")
+	links := registerLinks(fs)
+	out := registerJSON(fs)
+	versions := registerVersionCheck(fs)
 
 	if err := fs.Parse(args); err != nil {
 		os.Exit(1)
 	}
+	// --format json also gets JSON errors and direct runs.
+	out.json = out.json || format == "json"
+	if out.json {
+		format = "json"
+	}
 
 	if workspace == "." {
 		var err error
@@ -234,35 +695,229 @@ func cmdCheck(args []string) {
 
 	ctx := context.Background()
 
-	c, err := NewClient(workspace)
+	c, err := NewClient(workspace, clientOptionsFromEnv()...)
 	if err != nil {
 		log.Fatalf("Failed to create client: %v", err)
 	}
 
-	if !c.IsServerRunning() {
-		log.Println("Server not running, running svelte-check directly...")
+	// The inputs are hashed before the check, so that a change made while it
+	// runs gives the next check a new hash rather than this one a wrong one.
+	var inputHash string
+	if hashInputs {
+		lc := rf.resolve(c.Workspace(), fs.Args())
+		if inputHash, err = InputHash(kexec.New(), inputDir(c.Workspace(), lc.projects, project)); err != nil {
+			out.fail(errCodeFailed, 1, "Failed to hash inputs: %v", err)
+		}
+	}
+
+	if err := c.Probe(ctx); err != nil {
+		log.Printf("No server (%v), running svelte-check directly...", err)
 		executor := kexec.New()
-		output, exitCode := RunOnce(ctx, workspace, tsconfig, executor)
-		fmt.Print(output)
-		os.Exit(exitCode)
+		lc := rf.resolve(c.Workspace(), fs.Args())
+		os.Exit(runProjectsOnce(ctx, lc.runner, lc.projects, project, executor, inputHash, out))
+	}
+	versions.check(c, out)
+	linker, err := links.linker(c.Workspace())
+	if err != nil {
+		out.fail(errCodeFailed, 1, "%v", err)
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	output, hasErrors, err := c.Check(ctx, format)
+	resp, err := checkLinked(ctx, c, CheckOptions{Project: project, Format: format, AllowStale: allowStale}, linker)
+	if errors.Is(err, context.DeadlineExceeded) {
+		out.fail(errCodeTimeout, 1, "No check result within %s", timeout)
+	}
+	if err != nil {
+		out.fail(errCodeFailed, 1, "Failed to get check results: %v", err)
+	}
+
+	output := resp.Output
+	if inputHash != "" {
+		output = withInputHash(output, format, inputHash)
+	}
+	fmt.Print(output)
+	if output != "" && output[len(output)-1] != '\n' {
+		fmt.Println()
+	}
+
+	if resp.ExitCode != 0 {
+		os.Exit(resp.ExitCode)
+	}
+}
+
+// checkLinked is CheckWith, with the human output formatted here to link
+// diagnostics when linker is set, since the server cannot tell whether the
+// output goes to a terminal.
+func checkLinked(ctx context.Context, c *Client, opts CheckOptions, linker *Linker) (CheckResponse, error) {
+	if linker == nil || opts.Format != "human" {
+		return c.CheckWith(ctx, opts)
+	}
+	resp, err := c.CheckTyped(ctx, opts)
+	if err != nil {
+		return CheckResponse{}, err
+	}
+	return CheckResponse{Output: FormatHumanLinked(resp.Result, linker), Verdict: resp.Verdict, ExitCode: resp.ExitCode}, nil
+}
+
+func cmdWait(args []string) {
+	fs := flag.NewFlagSet("wait", flag.ExitOnError)
+
+	var workspace string
+	var project string
+	var timeout time.Duration
+	var format string
+
+	fs.StringVar(&workspace, "w", ".", "Working directory")
+	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	fs.StringVar(&project, "project", "", "Only wait for this project (default: all projects)")
+	fs.DurationVar(&timeout, "timeout", 10*time.Minute, "Give up after this long")
+	fs.StringVar(&format, "format", "human", "Output format: human or json")
+	links := registerLinks(fs)
+	out := registerJSON(fs)
+	versions := registerVersionCheck(fs)
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+	out.json = out.json || format == "json"
+
+	if workspace == "." {
+		var err error
+		workspace, err = os.Getwd()
+		if err != nil {
+			log.Fatalf("Failed to get working directory: %v", err)
+		}
+	}
+
+	c, err := NewClient(workspace, clientOptionsFromEnv()...)
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+
+	if !c.IsServerRunning() {
+		out.notRunning()
+	}
+	versions.check(c, out)
+	linker, err := links.linker(c.Workspace())
+	if err != nil {
+		out.fail(errCodeFailed, 1, "%v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result, err := c.WaitForClean(ctx, project)
+	if errors.Is(err, context.DeadlineExceeded) {
+		out.fail(errCodeTimeout, 1, "No passing check within %s", timeout)
+	}
 	if err != nil {
-		log.Fatalf("Failed to get check results: %v", err)
+		out.fail(errCodeFailed, 1, "Failed to wait for a passing check: %v", err)
 	}
 
+	if out.json {
+		// One line, as GET /check?format=json serves it.
+		_ = json.NewEncoder(os.Stdout).Encode(result)
+		return
+	}
+	output := FormatHumanLinked(result, linker)
 	fmt.Print(output)
 	if output != "" && output[len(output)-1] != '\n' {
 		fmt.Println()
 	}
+}
+
+func cmdWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
 
-	if hasErrors {
+	var workspace string
+	var rf runnerFlags
+	var project string
+	var format string
+
+	fs.StringVar(&workspace, "w", ".", "Working directory")
+	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	rf.register(fs, true)
+	fs.StringVar(&project, "project", "", "Only watch this project (default: all projects)")
+	fs.StringVar(&format, "format", "human", "Output format: human, json, or vscode")
+	links := registerLinks(fs)
+	logs := registerLogFlags(fs)
+	out := registerJSON(fs)
+
+	if err := fs.Parse(args); err != nil {
 		os.Exit(1)
 	}
+	out.json = out.json || format == "json"
+	if !out.json && format != "human" && format != "vscode" {
+		out.fail(errCodeFailed, 1, "Unknown format %q: want human, json, or vscode", format)
+	}
+	if err := logs.apply(os.Stderr); err != nil {
+		out.fail(errCodeFailed, 1, "%v", err)
+	}
+
+	if workspace == "." {
+		var err error
+		workspace, err = os.Getwd()
+		if err != nil {
+			log.Fatalf("Failed to get working directory: %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	c, err := NewClient(workspace, clientOptionsFromEnv()...)
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+	linker, err := links.linker(c.Workspace())
+	if err != nil {
+		out.fail(errCodeFailed, 1, "%v", err)
+	}
+
+	// Without a server, this one runs for as long as the command does, so
+	// a VS Code task can own it.
+	var daemonDone <-chan struct{}
+	if !c.IsServerRunning() {
+		lc := rf.resolve(c.Workspace(), fs.Args())
+		d, err := startDaemon(ctx, lc, DaemonOptions{Workspace: c.Workspace()})
+		if err != nil {
+			out.startupFailed(err)
+		}
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), daemonStopTimeout)
+			defer cancel()
+			if err := d.Stop(shutdownCtx); err != nil {
+				log.Printf("Error stopping server: %v", err)
+			}
+		}()
+		daemonDone = d.Done()
+	}
+
+	results, err := c.Subscribe(ctx, project)
+	if err != nil {
+		out.fail(errCodeFailed, 1, "Failed to watch results: %v", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				return
+			}
+			switch {
+			case out.json:
+				_ = enc.Encode(result)
+			case format == "vscode":
+				fmt.Print(FormatVSCode(result))
+			default:
+				fmt.Println(FormatHumanLinked(result, linker))
+			}
+		case <-daemonDone:
+			return
+		}
+	}
 }
 
 func cmdStop(args []string) {
@@ -272,6 +927,7 @@ func cmdStop(args []string) {
 
 	fs.StringVar(&workspace, "w", ".", "Working directory")
 	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	out := registerJSON(fs)
 
 	if err := fs.Parse(args); err != nil {
 		os.Exit(1)
@@ -285,21 +941,1197 @@ func cmdStop(args []string) {
 		}
 	}
 
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
 
-	c, err := NewClient(workspace)
+	c, err := NewClient(workspace, clientOptionsFromEnv()...)
 	if err != nil {
 		log.Fatalf("Failed to create client: %v", err)
 	}
 
 	if !c.IsServerRunning() {
-		fmt.Println("Server is not running")
+		out.result(stopResult{WasRunning: false}, func() { fmt.Println("Server is not running") })
 		return
 	}
 
 	if err := c.Stop(ctx); err != nil {
-		log.Fatalf("Failed to stop server: %v", err)
+		out.fail(errCodeFailed, 1, "Failed to stop server: %v", err)
+	}
+
+	out.result(stopResult{WasRunning: true}, func() { fmt.Println("Server stopped") })
+}
+
+func cmdRestart(args []string) {
+	fs := flag.NewFlagSet("restart", flag.ExitOnError)
+
+	var workspace string
+	var project string
+
+	fs.StringVar(&workspace, "w", ".", "Working directory")
+	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	fs.StringVar(&project, "project", "", "Only restart this project (default: all projects)")
+	out := registerJSON(fs)
+	versions := registerVersionCheck(fs)
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if workspace == "." {
+		var err error
+		workspace, err = os.Getwd()
+		if err != nil {
+			log.Fatalf("Failed to get working directory: %v", err)
+		}
+	}
+
+	c, err := NewClient(workspace, clientOptionsFromEnv()...)
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+
+	if !c.IsServerRunning() {
+		out.notRunning()
+	}
+	versions.check(c, out)
+
+	// The server answers once the restarted checkers have started.
+	ctx, cancel := context.WithTimeout(context.Background(), defaultStartupTimeout)
+	defer cancel()
+
+	if err := c.Restart(ctx, project); err != nil {
+		out.fail(errCodeFailed, 1, "Failed to restart: %v", err)
+	}
+
+	out.result(restartResult{Restarted: true, Project: project}, func() { fmt.Println("Restarted") })
+}
+
+func cmdWatchDirs(args []string) {
+	fs := flag.NewFlagSet("watch-dirs", flag.ExitOnError)
+
+	var workspace string
+	var recursive, nonRecursive, remove stringSlice
+
+	fs.StringVar(&workspace, "w", ".", "Working directory")
+	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	fs.Var(&recursive, "r", "Start watching a directory recursively (can be repeated)")
+	fs.Var(&nonRecursive, "d", "Start watching a directory non-recursively (can be repeated)")
+	fs.Var(&remove, "remove", "Stop watching a directory (can be repeated)")
+	out := registerJSON(fs)
+	versions := registerVersionCheck(fs)
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if workspace == "." {
+		var err error
+		workspace, err = os.Getwd()
+		if err != nil {
+			log.Fatalf("Failed to get working directory: %v", err)
+		}
+	}
+
+	c, err := NewClient(workspace, clientOptionsFromEnv()...)
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
 	}
 
-	fmt.Println("Server stopped")
+	if !c.IsServerRunning() {
+		out.notRunning()
+	}
+	versions.check(c, out)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	dirs, err := c.WatchDirs(ctx)
+	if err != nil {
+		out.fail(errCodeFailed, 1, "Failed to get watch directories: %v", err)
+	}
+	if len(recursive) > 0 || len(nonRecursive) > 0 || len(remove) > 0 {
+		dirs = dirs.apply(recursive, nonRecursive, remove)
+		if dirs, err = c.SetWatchDirs(ctx, dirs); err != nil {
+			out.fail(errCodeFailed, 1, "Failed to set watch directories: %v", err)
+		}
+	}
+
+	out.result(dirs, func() {
+		fmt.Printf("Recursive:     %s\n", strings.Join(dirs.Recursive, " "))
+		fmt.Printf("Non-recursive: %s\n", strings.Join(dirs.NonRecursive, " "))
+	})
+}
+
+func cmdBaseline(args []string) {
+	if len(args) == 0 || args[0] != "write" {
+		fmt.Fprintln(os.Stderr, "Usage: svelte-check-server baseline write [options]")
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("baseline write", flag.ExitOnError)
+
+	var workspace string
+	var timeout time.Duration
+
+	fs.StringVar(&workspace, "w", ".", "Working directory")
+	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	fs.DurationVar(&timeout, "timeout", 120*time.Second, "Timeout waiting for check to complete")
+	out := registerJSON(fs)
+	versions := registerVersionCheck(fs)
+
+	if err := fs.Parse(args[1:]); err != nil {
+		os.Exit(1)
+	}
+
+	if workspace == "." {
+		var err error
+		workspace, err = os.Getwd()
+		if err != nil {
+			log.Fatalf("Failed to get working directory: %v", err)
+		}
+	}
+
+	c, err := NewClient(workspace, clientOptionsFromEnv()...)
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+
+	if !c.IsServerRunning() {
+		if out.json {
+			out.fail(errCodeNotRunning, 1, "server is not running; start it to record a baseline")
+		}
+		fmt.Println("Server is not running; start it to record a baseline")
+		os.Exit(1)
+	}
+	versions.check(c, out)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resp, err := c.CheckTyped(ctx, CheckOptions{IgnoreBaseline: true})
+	if errors.Is(err, context.DeadlineExceeded) {
+		out.fail(errCodeTimeout, 1, "No check result within %s", timeout)
+	}
+	if err != nil {
+		out.fail(errCodeFailed, 1, "Failed to get check results: %v", err)
+	}
+	result := resp.Result
+
+	path := filepath.Join(c.Workspace(), BaselineFileName)
+	if err := WriteBaseline(path, NewBaseline(result.Diagnostics)); err != nil {
+		out.fail(errCodeFailed, 1, "Failed to write baseline: %v", err)
+	}
+	out.result(baselineResult{Path: path, Diagnostics: len(result.Diagnostics)}, func() {
+		fmt.Printf("Wrote %d diagnostics to %s\n", len(result.Diagnostics), BaselineFileName)
+	})
+}
+
+func cmdStatus(args []string) {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+
+	var workspace string
+	var format string
+
+	fs.StringVar(&workspace, "w", ".", "Working directory")
+	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	fs.StringVar(&format, "format", "human", "Output format: human or json")
+	out := registerJSON(fs)
+	versions := registerVersionCheck(fs)
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+	out.json = out.json || format == "json"
+
+	if workspace == "." {
+		var err error
+		workspace, err = os.Getwd()
+		if err != nil {
+			log.Fatalf("Failed to get working directory: %v", err)
+		}
+	}
+
+	c, err := NewClient(workspace, clientOptionsFromEnv()...)
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+
+	if !c.IsServerRunning() {
+		out.notRunning()
+	}
+	versions.check(c, out)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	status, err := c.Status(ctx)
+	if err != nil {
+		out.fail(errCodeFailed, 1, "Failed to get status: %v", err)
+	}
+
+	out.result(status, func() { fmt.Print(FormatStatus(status)) })
+}
+
+func cmdAction(args []string) {
+	fs := flag.NewFlagSet("action", flag.ExitOnError)
+
+	var workspace string
+	var rf runnerFlags
+	var project string
+	var timeout time.Duration
+
+	fs.StringVar(&workspace, "w", ".", "Working directory")
+	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	rf.register(fs, false)
+	fs.StringVar(&project, "project", "", "Only check this project (default: all projects)")
+	fs.DurationVar(&timeout, "timeout", 15*time.Minute, "Give up after this long")
+	out := registerJSON(fs)
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if workspace == "." {
+		var err error
+		workspace, err = os.Getwd()
+		if err != nil {
+			log.Fatalf("Failed to get working directory: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	c, err := NewClient(workspace, clientOptionsFromEnv()...)
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+
+	// A self-hosted runner may keep a server running between jobs.
+	resp := serverOrDirectResult(ctx, c, rf.resolve(c.Workspace(), fs.Args()), project, timeout, out)
+	result := resp.Result
+
+	if err := appendGitHubFile(EnvGitHubStepSummary, FormatJobSummary(result, resp.Verdict)); err != nil {
+		log.Printf("Failed to write the job summary: %v", err)
+	}
+	if err := appendGitHubFile(EnvGitHubOutput, GitHubOutputs(result)); err != nil {
+		out.fail(errCodeFailed, 1, "Failed to set outputs: %v", err)
+	}
+	out.result(result, func() {
+		WriteAnnotations(os.Stdout, result)
+		fmt.Printf("svelte-check: %d errors, %d warnings (%d files checked)\n", result.ErrorCount, result.WarningCount, result.FileCount)
+	})
+	os.Exit(resp.ExitCode)
+}
+
+// serverOrDirectResult returns the running server's result for project,
+// which it answers at once, or without a server, checks once directly as the
+// server would and judges the result by lc's policy. It exits on failure.
+func serverOrDirectResult(ctx context.Context, c *Client, lc launchConfig, project string, timeout time.Duration, out *cliOutput) TypedCheckResponse {
+	err := c.Probe(ctx)
+	if err == nil {
+		resp, err := c.CheckTyped(ctx, CheckOptions{Project: project})
+		if errors.Is(err, context.DeadlineExceeded) {
+			out.fail(errCodeTimeout, 1, "No check result within %s", timeout)
+		}
+		if err != nil {
+			out.fail(errCodeFailed, 1, "Failed to get check results: %v", err)
+		}
+		return resp
+	}
+	log.Printf("No server (%v), running the checkers directly...", err)
+
+	result, err := checkOnce(ctx, lc, project, NewExecutor())
+	if errors.Is(err, context.DeadlineExceeded) {
+		out.fail(errCodeTimeout, 1, "No check result within %s", timeout)
+	}
+	var startupErr *StartupError
+	if errors.As(err, &startupErr) {
+		out.startupFailed(err)
+	}
+	if err != nil {
+		out.fail(errCodeFailed, 1, "Check failed: %v", err)
+	}
+	verdict := lc.policy.Verdict(result)
+	return TypedCheckResponse{Result: result, Verdict: verdict, ExitCode: lc.policy.ExitCode(verdict)}
+}
+
+func cmdHookImpl(args []string) {
+	fs := flag.NewFlagSet("hook-impl", flag.ExitOnError)
+
+	var workspace string
+	var rf runnerFlags
+	var project string
+	var staged bool
+	var format string
+	var timeout time.Duration
+
+	fs.StringVar(&workspace, "w", ".", "Working directory")
+	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	rf.register(fs, false)
+	fs.StringVar(&project, "project", "", "Only check this project (default: all projects)")
+	fs.BoolVar(&staged, "staged", false, "Without files, report on the files staged in git")
+	fs.StringVar(&format, "format", "human", "Output format: human, quickfix, or json")
+	fs.DurationVar(&timeout, "timeout", 10*time.Minute, "Give up after this long")
+	links := registerLinks(fs)
+	out := registerJSON(fs)
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+	out.json = out.json || format == "json"
+	if !out.json && format != "human" && format != "quickfix" {
+		out.fail(errCodeFailed, 1, "Unknown format %q: want human, quickfix, or json", format)
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		log.Fatalf("Failed to get working directory: %v", err)
+	}
+	if workspace == "." {
+		workspace = dir
+	}
+
+	c, err := NewClient(workspace, clientOptionsFromEnv()...)
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+	linker, err := links.linker(c.Workspace())
+	if err != nil {
+		out.fail(errCodeFailed, 1, "%v", err)
+	}
+
+	// pre-commit passes the staged files matching the hook's "files"
+	// pattern, or every file with --all-files, relative to the repository.
+	files := workspaceFiles(c.Workspace(), dir, fs.Args())
+	scoped := len(files) > 0
+	if !scoped && staged {
+		if files, err = stagedFiles(NewExecutor(), c.Workspace()); err != nil {
+			out.fail(errCodeFailed, 1, "Failed to list staged files: %v", err)
+		}
+		scoped = true
+	}
+	if scoped && !checkable(files) {
+		out.result(SvelteWatchCheckComplete{}, func() {})
+		return
+	}
+
+	lc := rf.resolve(c.Workspace(), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result := serverOrDirectResult(ctx, c, lc, project, timeout, out).Result
+	if scoped {
+		result = onlyFiles(result, files)
+	}
+
+	out.result(result, func() {
+		if format == "quickfix" {
+			fmt.Print(FormatQuickfix(result))
+		} else {
+			fmt.Print(FormatHumanLinked(result, linker))
+		}
+	})
+	os.Exit(lc.policy.ExitCode(lc.policy.Verdict(result)))
+}
+
+func cmdCI(args []string) {
+	fs := flag.NewFlagSet("ci", flag.ExitOnError)
+
+	var workspace string
+	var rf runnerFlags
+	var project string
+	var format string
+	var report string
+	var timeout time.Duration
+
+	fs.StringVar(&workspace, "w", ".", "Working directory")
+	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	rf.register(fs, false)
+	fs.StringVar(&project, "project", "", "Only check this project (default: all projects)")
+	fs.StringVar(&format, "format", "human", "Output format: human, json, quickfix, or github")
+	fs.StringVar(&report, "report", "", "Also write the result to this file")
+	fs.DurationVar(&timeout, "timeout", 15*time.Minute, "Give up after this long")
+	links := registerLinks(fs)
+	out := registerJSON(fs)
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+	out.json = out.json || format == "json"
+	if !out.json && format != "human" && format != "quickfix" && format != "github" {
+		out.fail(errCodeFailed, 1, "Unknown format %q: want human, json, quickfix, or github", format)
+	}
+
+	if workspace == "." {
+		var err error
+		workspace, err = os.Getwd()
+		if err != nil {
+			log.Fatalf("Failed to get working directory: %v", err)
+		}
+	}
+	workspace, err := filepath.Abs(workspace)
+	if err != nil {
+		log.Fatalf("Failed to resolve workspace: %v", err)
+	}
+	linker, err := links.linker(workspace)
+	if err != nil {
+		out.fail(errCodeFailed, 1, "%v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	lc := rf.resolve(workspace, fs.Args())
+	result, err := checkOnce(ctx, lc, project, NewExecutor())
+	if errors.Is(err, context.DeadlineExceeded) {
+		out.fail(errCodeTimeout, 1, "No check result within %s", timeout)
+	}
+	var startupErr *StartupError
+	if errors.As(err, &startupErr) {
+		out.startupFailed(err)
+	}
+	if err != nil {
+		out.fail(errCodeFailed, 1, "Check failed: %v", err)
+	}
+	verdict := lc.policy.Verdict(result)
+
+	if report != "" {
+		if err := writeReport(report, result, verdict); err != nil {
+			out.fail(errCodeFailed, 1, "Failed to write the report: %v", err)
+		}
+	}
+	out.result(result, func() {
+		switch format {
+		case "quickfix":
+			fmt.Print(FormatQuickfix(result))
+		case "github":
+			WriteAnnotations(os.Stdout, result)
+			fmt.Printf("svelte-check: %d errors, %d warnings (%d files checked)\n", result.ErrorCount, result.WarningCount, result.FileCount)
+		default:
+			fmt.Print(FormatHumanLinked(result, linker))
+		}
+	})
+	os.Exit(lc.policy.ExitCode(verdict))
+}
+
+func cmdExec(args []string) {
+	fs := flag.NewFlagSet("exec", flag.ExitOnError)
+
+	var workspace string
+	var rf runnerFlags
+	var project string
+	var timeout time.Duration
+
+	fs.StringVar(&workspace, "w", ".", "Working directory")
+	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	rf.register(fs, false)
+	fs.StringVar(&project, "project", "", "Only gate on this project (default: all projects)")
+	fs.DurationVar(&timeout, "timeout", 10*time.Minute, "Give up waiting for the check after this long")
+	links := registerLinks(fs)
+	out := registerJSON(fs)
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+	command := fs.Args()
+	if len(command) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: svelte-check-server exec [options] -- <command> [args...]")
+		os.Exit(1)
+	}
+
+	var err error
+	if workspace == "." {
+		workspace, err = os.Getwd()
+		if err != nil {
+			log.Fatalf("Failed to get working directory: %v", err)
+		}
+	}
+
+	c, err := NewClient(workspace, clientOptionsFromEnv()...)
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+	linker, err := links.linker(c.Workspace())
+	if err != nil {
+		out.fail(errCodeFailed, 1, "%v", err)
+	}
+
+	gate := &execGate{
+		lookPath: exec.LookPath,
+		check: func() TypedCheckResponse {
+			lc := rf.resolve(c.Workspace(), nil)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			return serverOrDirectResult(ctx, c, lc, project, timeout, out)
+		},
+		// The command replaces this process, so its exit status and the
+		// signals sent to it are its own.
+		run: func(path string, command []string) error {
+			return syscall.Exec(path, command, os.Environ())
+		},
+		out:    out,
+		linker: linker,
+	}
+	os.Exit(gate.exitCode(command))
+}
+
+func cmdStatusline(args []string) {
+	fs := flag.NewFlagSet("statusline", flag.ExitOnError)
+
+	var workspace string
+	var style string
+	var project string
+
+	fs.StringVar(&workspace, "w", ".", "Working directory")
+	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	fs.StringVar(&style, "style", StatuslineEmoji, "emoji or plain")
+	fs.StringVar(&project, "project", "", "Only this project (default: all projects)")
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	// A prompt is drawn after this returns, so everything counts against
+	// the budget, and a slow or absent server prints nothing.
+	ctx, cancel := context.WithTimeout(context.Background(), statuslineBudget)
+	defer cancel()
+
+	c, err := NewClient(workspace, clientOptionsFromEnv()...)
+	if err != nil {
+		os.Exit(1)
+	}
+	line, err := c.Statusline(ctx, style, project)
+	if err != nil {
+		os.Exit(1)
+	}
+	fmt.Println(line)
+}
+
+func cmdHealthcheck(args []string) {
+	fs := flag.NewFlagSet("healthcheck", flag.ExitOnError)
+
+	var workspace string
+	var timeout time.Duration
+
+	fs.StringVar(&workspace, "w", ".", "Working directory")
+	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	fs.DurationVar(&timeout, "timeout", 3*time.Second, "Timeout waiting for the server")
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	c, err := NewClient(workspace, clientOptionsFromEnv()...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := c.Healthz(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func cmdService(args []string) {
+	if len(args) == 0 || (args[0] != "install" && args[0] != "uninstall") {
+		fmt.Fprintln(os.Stderr, "Usage: svelte-check-server service install|uninstall [options]")
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("service "+args[0], flag.ExitOnError)
+
+	var workspace string
+
+	fs.StringVar(&workspace, "w", ".", "Working directory")
+	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	out := registerJSON(fs)
+
+	if err := fs.Parse(args[1:]); err != nil {
+		os.Exit(1)
+	}
+
+	workspace, err := filepath.Abs(workspace)
+	if err != nil {
+		out.fail(errCodeFailed, 1, "%v", err)
+	}
+	services, err := NewServices(NewExecutor())
+	if err != nil {
+		out.fail(errCodeFailed, 1, "%v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	var files []string
+	verb := "Removed"
+	if args[0] == "install" {
+		binary, err := os.Executable()
+		if err != nil {
+			out.fail(errCodeFailed, 1, "Failed to find this executable: %v", err)
+		}
+		socketPath, err := SocketPathForWorkspace(workspace)
+		if err != nil {
+			out.fail(errCodeFailed, 1, "Failed to get socket path: %v", err)
+		}
+		verb = "Wrote"
+		if files, err = services.Install(ctx, ServiceConfig{
+			Workspace: workspace,
+			Binary:    binary,
+			Socket:    socketPath,
+			Path:      os.Getenv("PATH"),
+		}); err != nil {
+			out.fail(errCodeFailed, 1, "%v", err)
+		}
+	} else if files, err = services.Uninstall(ctx, workspace); err != nil {
+		out.fail(errCodeFailed, 1, "%v", err)
+	}
+	out.result(serviceResult{Files: files}, func() {
+		for _, f := range files {
+			fmt.Printf("%s %s\n", verb, f)
+		}
+	})
+}
+
+func cmdAnnotate(args []string) {
+	fs := flag.NewFlagSet("annotate", flag.ExitOnError)
+
+	var workspace string
+	var rf runnerFlags
+	var provider, repo, project string
+	var pr int
+	var timeout time.Duration
+
+	fs.StringVar(&workspace, "w", ".", "Working directory")
+	fs.StringVar(&workspace, "workspace", ".", "Working directory")
+	rf.register(fs, false)
+	fs.StringVar(&provider, "provider", ProviderGitHub, "github or gitlab")
+	fs.IntVar(&pr, "pr", 0, "Pull or merge request number")
+	fs.StringVar(&repo, "repo", "", "Repository: owner/name on GitHub, project ID or path on GitLab")
+	fs.StringVar(&project, "project", "", "Only check this project (default: all projects)")
+	fs.DurationVar(&timeout, "timeout", 15*time.Minute, "Give up after this long")
+	out := registerJSON(fs)
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if workspace == "." {
+		var err error
+		workspace, err = os.Getwd()
+		if err != nil {
+			log.Fatalf("Failed to get working directory: %v", err)
+		}
+	}
+
+	reviews, err := NewReviewProvider(provider, repo, pr)
+	if err != nil {
+		out.fail(errCodeFailed, 1, "%v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	c, err := NewClient(workspace, clientOptionsFromEnv()...)
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+	// Diagnostics' filenames are relative to the workspace, and the diff's to
+	// the repository.
+	prefix, err := repoPrefix(NewExecutor(), c.Workspace())
+	if err != nil {
+		out.fail(errCodeFailed, 1, "Failed to find the repository: %v", err)
+	}
+
+	result := serverOrDirectResult(ctx, c, rf.resolve(c.Workspace(), fs.Args()), project, timeout, out).Result
+	annotated, err := Annotate(ctx, reviews, result, prefix)
+	if err != nil {
+		out.fail(errCodeFailed, 1, "Failed to annotate (%d comments posted): %v", annotated.Posted, err)
+	}
+	out.result(annotated, func() {
+		fmt.Printf("Posted %d comments (%d already posted, %d diagnostics outside the diff)\n",
+			annotated.Posted, annotated.Duplicates, annotated.OutsideDiff)
+	})
+}
+
+// runProjectsOnce runs svelte-check once for the selected project, or for
+// each project in turn, prints the output, and returns the highest exit code.
+// With --json, the outputs are printed as a directResult once all have run.
+// A non-empty inputHash is printed after them, as check --hash-inputs does.
+func runProjectsOnce(ctx context.Context, base RunnerConfig, projects []ProjectConfig, only string, executor kexec.Interface, inputHash string, out *cliOutput) int {
+	result := directResult{Direct: true, InputHash: inputHash}
+	run := func(project string, config RunnerConfig) {
+		if project != "" && !out.json {
+			fmt.Printf("==> %s\n", project)
+		}
+		output, code := RunOnce(ctx, config, executor)
+		if !out.json {
+			fmt.Print(output)
+		}
+		result.Runs = append(result.Runs, directRun{Project: project, ExitCode: code, Output: output})
+		result.ExitCode = max(result.ExitCode, code)
+	}
+
+	if len(projects) == 0 {
+		if only != "" {
+			out.fail(errCodeFailed, 1, "Unknown project %q: no projects configured", only)
+		}
+		run("", base)
+	} else {
+		configs := ProjectRunnerConfigs(base, projects)
+		for i, p := range projects {
+			if only == "" || p.Name == only {
+				run(p.Name, configs[i])
+			}
+		}
+		if len(result.Runs) == 0 {
+			out.fail(errCodeFailed, 1, "Unknown project %q", only)
+		}
+	}
+	if out.json {
+		out.result(result, nil)
+	} else if inputHash != "" {
+		fmt.Print(withInputHash("", "human", inputHash))
+	}
+	return result.ExitCode
+}
+
+// projectDirs returns the distinct project directories, or "." when there
+// are no projects.
+func projectDirs(projects []ProjectConfig) []string {
+	dirs := []string{"."}
+	if len(projects) > 0 {
+		dirs = nil
+		for _, p := range projects {
+			dir := filepath.Clean(p.Dir)
+			if !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+// defaultWatchDirs returns the directories watched when none are given: the
+// workspace root non-recursively plus each project's root, and its src,
+// routes, and lib directories, from kits by project directory.
+func defaultWatchDirs(projects []ProjectConfig, kits map[string]KitPaths) (nonRecursive, recursive []string) {
+	nonRecursive = []string{"."}
+	for _, dir := range projectDirs(projects) {
+		if dir != "." {
+			nonRecursive = append(nonRecursive, "./"+filepath.ToSlash(dir))
+		}
+		kit, ok := kits[dir]
+		if !ok {
+			kit = DefaultKitPaths
+		}
+		for _, sub := range kit.watchDirs() {
+			recursive = append(recursive, "./"+filepath.ToSlash(filepath.Join(dir, sub)))
+		}
+	}
+	return nonRecursive, recursive
+}
+
+// routeDirs returns the workspace-relative routes directory of each project.
+func routeDirs(projects []ProjectConfig, kits map[string]KitPaths) []string {
+	var dirs []string
+	for _, dir := range projectDirs(projects) {
+		kit, ok := kits[dir]
+		if !ok {
+			kit = DefaultKitPaths
+		}
+		dirs = append(dirs, filepath.Join(dir, kit.Routes))
+	}
+	return dirs
+}
+
+// syncGlobs returns the workspace-relative globs of each project's further
+// svelte-kit sync inputs.
+func syncGlobs(projects []ProjectConfig, kits map[string]KitPaths) []string {
+	var globs []string
+	for _, dir := range projectDirs(projects) {
+		kit, ok := kits[dir]
+		if !ok {
+			kit = DefaultKitPaths
+		}
+		for _, g := range kit.syncGlobs() {
+			globs = append(globs, path.Join(filepath.ToSlash(dir), g))
+		}
+	}
+	return globs
+}
+
+// defaultRestartGlobs match ambient type declarations outside src, relative
+// to a project.
+var defaultRestartGlobs = []string{"*.d.ts", "types/**/*.d.ts"}
+
+// restartGlobs returns the workspace-relative globs of each project's
+// ambient type declarations.
+func restartGlobs(projects []ProjectConfig) []string {
+	var globs []string
+	for _, dir := range projectDirs(projects) {
+		for _, g := range defaultRestartGlobs {
+			globs = append(globs, path.Join(filepath.ToSlash(dir), g))
+		}
+	}
+	return globs
+}
+
+// withRootSkipDirs adds the paths of each project's DefaultRootSkipDirs and
+// kit.outDir to skipDirs, so build and generated output is not watched while
+// directories of the same names deeper in a project are.
+func withRootSkipDirs(workspace string, skipDirs []string, kits map[string]KitPaths) []string {
+	skipDirs = slices.Clone(skipDirs)
+	for _, dir := range slices.Sorted(maps.Keys(kits)) {
+		root := filepath.Join(workspace, dir)
+		for _, name := range append(slices.Clone(DefaultRootSkipDirs), kits[dir].OutDir) {
+			path := filepath.Join(root, name)
+			if isWithin(root, path) && !skippedDir(skipDirs, path) {
+				skipDirs = append(skipDirs, path)
+			}
+		}
+	}
+	return skipDirs
+}
+
+// resolve is like load, but invalid settings are fatal.
+func (f *runnerFlags) resolve(workspace string, extraArgs []string) launchConfig {
+	lc, err := f.load(workspace, extraArgs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return lc
+}
+
+// load merges the flags over the workspace config file. extraArgs are the
+// arguments given after "--" and are appended to the config file's args. An
+// explicit --tsconfig selects a single project and ignores the configured
+// ones.
+func (f *runnerFlags) load(workspace string, extraArgs []string) (launchConfig, error) {
+	cfg, err := LoadConfig(workspace)
+	if err != nil {
+		return launchConfig{}, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	projects := cfg.Projects
+	if f.monorepo || cfg.Monorepo {
+		if len(projects) > 0 {
+			return launchConfig{}, errors.New("monorepo mode cannot be combined with configured projects")
+		}
+		projects, err = DiscoverWorkspacePackages(workspace)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("failed to discover workspace packages: %w", err)
+		}
+		if len(projects) == 0 {
+			return launchConfig{}, errors.New("no Svelte packages found in workspace")
+		}
+	}
+	if f.tsconfig != "" {
+		projects = nil
+	}
+	if err := ValidateProjects(projects); err != nil {
+		return launchConfig{}, fmt.Errorf("invalid projects: %w", err)
+	}
+
+	tsconfig := cmp.Or(f.tsconfig, cfg.Tsconfig)
+	packageManager := cmp.Or(f.packageManager, cfg.PackageManager)
+	command := cmp.Or(f.command, cfg.Command)
+	if tsconfig != "" && command != "" {
+		// A custom command replaces the invocation that would pass it.
+		return launchConfig{}, errors.New("--tsconfig has no effect with --command: pass the tsconfig in the command instead")
+	}
+	monitorInterval := cmp.Or(f.monitorInterval, cfg.MonitorInterval)
+	maxMemory := cmp.Or(f.maxMemory, cfg.MaxMemory)
+	startupTimeout := cmp.Or(f.startupTimeout, cfg.StartupTimeout)
+	restartCooldown := cmp.Or(f.restartCooldown, cfg.RestartCooldown)
+	lockfileGrace := cmp.Or(f.lockfileGrace, cfg.LockfileGrace)
+	interruptGrace := cmp.Or(f.interruptGrace, cfg.InterruptGrace)
+	terminateGrace := cmp.Or(f.terminateGrace, cfg.TerminateGrace)
+
+	rc := RunnerConfig{
+		WorkspacePath: workspace,
+		TsconfigPath:  tsconfig,
+		ExtraArgs:     append(slices.Clone(cfg.Args), extraArgs...),
+	}
+
+	if packageManager != "" {
+		rc.PackageManager, err = ParsePackageManager(packageManager)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid package manager: %w", err)
+		}
+	}
+
+	if command != "" {
+		rc.Command, err = SplitCommandLine(command)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid command: %w", err)
+		}
+	}
+
+	rc.Resources.Interval = defaultMonitorInterval
+	if monitorInterval != "" {
+		rc.Resources.Interval, err = time.ParseDuration(monitorInterval)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid monitor interval: %w", err)
+		}
+	}
+
+	if maxMemory != "" {
+		rc.Resources.MaxRSSBytes, err = ParseByteSize(maxMemory)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid max memory: %w", err)
+		}
+		if rc.Resources.Interval <= 0 {
+			return launchConfig{}, errors.New("--max-memory requires resource monitoring (--monitor-interval > 0)")
+		}
+	}
+
+	lc := launchConfig{
+		projects:        projects,
+		startupTimeout:  defaultStartupTimeout,
+		restartCooldown: defaultRestartCooldown,
+		syncOnStart:     !f.noSync && (cfg.SyncOnStart == nil || *cfg.SyncOnStart),
+		lockfileRestart: !f.noLockRestart && (cfg.LockfileRestart == nil || *cfg.LockfileRestart),
+		lockfileGrace:   defaultLockfileGrace,
+	}
+	if startupTimeout != "" {
+		lc.startupTimeout, err = time.ParseDuration(startupTimeout)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid startup timeout: %w", err)
+		}
+	}
+	if restartCooldown != "" {
+		lc.restartCooldown, err = time.ParseDuration(restartCooldown)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid restart cooldown: %w", err)
+		}
+	}
+	if lockfileGrace != "" {
+		lc.lockfileGrace, err = time.ParseDuration(lockfileGrace)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid lockfile grace period: %w", err)
+		}
+	}
+
+	rc.StopPolicy = DefaultStopPolicy
+	if interruptGrace != "" {
+		rc.StopPolicy.InterruptGrace, err = time.ParseDuration(interruptGrace)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid interrupt grace period: %w", err)
+		}
+	}
+	if terminateGrace != "" {
+		rc.StopPolicy.TerminateGrace, err = time.ParseDuration(terminateGrace)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid terminate grace period: %w", err)
+		}
+	}
+	if err := rc.StopPolicy.Validate(); err != nil {
+		return launchConfig{}, fmt.Errorf("invalid stop policy: %w", err)
+	}
+
+	rc.History = DefaultHistoryLimits
+	if historySize := cmp.Or(f.historySize, cfg.HistorySize); historySize != 0 {
+		if historySize < 1 {
+			return launchConfig{}, fmt.Errorf("invalid history size: %d (must be at least 1)", historySize)
+		}
+		rc.History.Size = historySize
+	}
+
+	lc.policy = DefaultCheckPolicy
+	if f.failOn != "" || len(cfg.FailOn) > 0 {
+		names := cfg.FailOn
+		if f.failOn != "" {
+			names = splitList(f.failOn)
+		}
+		lc.policy.FailOn, err = ParseSeverities(names)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid --fail-on: %w", err)
+		}
+	}
+	lc.policy.FailureStatus = cmp.Or(f.failureStatus, cfg.FailureStatus, lc.policy.FailureStatus)
+	lc.policy.ExitCodes = maps.Clone(lc.policy.ExitCodes)
+	for name, code := range cfg.ExitCodes {
+		sev, err := ParseSeverity(name)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid exitCodes: %w", err)
+		}
+		lc.policy.ExitCodes[sev] = code
+	}
+	if f.exitCodes != "" {
+		codes, err := ParseExitCodes(splitList(f.exitCodes))
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid --exit-codes: %w", err)
+		}
+		maps.Copy(lc.policy.ExitCodes, codes)
+	}
+	if err := lc.policy.Validate(); err != nil {
+		return launchConfig{}, fmt.Errorf("invalid check policy: %w", err)
+	}
+	lc.watchSkipDirs = cfg.WatchSkipDirs
+	lc.syncOn = cfg.SyncOn
+	if err := ValidateGlobs(lc.syncOn); err != nil {
+		return launchConfig{}, fmt.Errorf("invalid syncOn: %w", err)
+	}
+	lc.restartOn = cfg.RestartOn
+	if err := ValidateGlobs(lc.restartOn); err != nil {
+		return launchConfig{}, fmt.Errorf("invalid restartOn: %w", err)
+	}
+	lc.rules = cfg.Rules
+	lc.notify = cfg.Notify
+	lc.hooks = cfg.Hooks
+	if _, err := lc.notify.Notifications(); err != nil {
+		return launchConfig{}, fmt.Errorf("invalid notify: %w", err)
+	}
+	lc.autoWatch = cfg.AutoWatch
+	if f.autoWatch != "" {
+		lc.autoWatch = splitList(f.autoWatch)
+	}
+	if err := ValidateAutoWatch(lc.autoWatch); err != nil {
+		return launchConfig{}, fmt.Errorf("invalid auto-watch: %w", err)
+	}
+	if lc.auditLog = cmp.Or(f.auditLog, cfg.AuditLog); lc.auditLog != "" && !filepath.IsAbs(lc.auditLog) {
+		lc.auditLog = filepath.Join(workspace, lc.auditLog)
+	}
+	if size := cmp.Or(f.auditLogMaxSize, cfg.AuditLogMaxSize); size != "" {
+		lc.auditLogMaxSize, err = ParseByteSize(size)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid audit log max size: %w", err)
+		}
+	}
+	if lc.journal = cmp.Or(f.journal, cfg.Journal); lc.journal != "" && !filepath.IsAbs(lc.journal) {
+		lc.journal = filepath.Join(workspace, lc.journal)
+	}
+	if size := cmp.Or(f.journalMaxSize, cfg.JournalMaxSize); size != "" {
+		lc.journalMaxSize, err = ParseByteSize(size)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid journal max size: %w", err)
+		}
+	}
+	lc.tracer = TracerConfigFromEnv(cmp.Or(f.otlpEndpoint, cfg.OTLPEndpoint))
+	// Bind mounts into a container often deliver no inotify events for
+	// changes made on the host.
+	backend := WatchBackendNotify
+	if f.container {
+		backend = WatchBackendPoll
+	}
+	lc.watchBackend = cmp.Or(f.watchBackend, cfg.WatchBackend, backend)
+	switch lc.watchBackend {
+	case WatchBackendNotify, WatchBackendPoll, WatchBackendWatchman:
+	default:
+		return launchConfig{}, fmt.Errorf("invalid watch backend %q (want notify, poll, or watchman)", lc.watchBackend)
+	}
+	lc.pollFallback = f.pollFallback || cfg.PollFallback
+	lc.followSymlinks = f.followSymlinks || cfg.FollowSymlinks
+	lc.maxWatchers = cmp.Or(f.maxWatchers, cfg.MaxWatchers, DefaultMaxWatchers)
+	if lc.maxWatchers < 1 {
+		return launchConfig{}, fmt.Errorf("invalid max watchers: %d (must be at least 1)", lc.maxWatchers)
+	}
+	lc.pollInterval = DefaultPollInterval
+	if pollInterval := cmp.Or(f.pollInterval, cfg.PollInterval); pollInterval != "" {
+		lc.pollInterval, err = time.ParseDuration(pollInterval)
+		if err != nil || lc.pollInterval <= 0 {
+			return launchConfig{}, fmt.Errorf("invalid poll interval: %q", pollInterval)
+		}
+	}
+	lc.watchIgnore = append(slices.Clone(cfg.WatchIgnore), f.watchIgnore...)
+	if err := ValidateGlobs(lc.watchIgnore); err != nil {
+		return launchConfig{}, fmt.Errorf("invalid --ignore: %w", err)
+	}
+
+	if rules := SuppressionRules(cfg); len(rules) > 0 {
+		lc.ignore, err = CompileIgnoreRules(rules)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid config: %w", err)
+		}
+	}
+
+	rc.Env = EnvConfig{
+		Set:     maps.Clone(cfg.Env),
+		Inherit: cfg.InheritEnv,
+		Deny:    cfg.DenyEnv,
+	}
+	flagEnv, err := ParseEnvAssignments(f.env)
+	if err != nil {
+		return launchConfig{}, fmt.Errorf("invalid --env: %w", err)
+	}
+	if len(flagEnv) > 0 {
+		if rc.Env.Set == nil {
+			rc.Env.Set = make(map[string]string)
+		}
+		maps.Copy(rc.Env.Set, flagEnv)
+	}
+	if f.inheritEnv != "" {
+		rc.Env.Inherit = splitList(f.inheritEnv)
+	}
+	if f.denyEnv != "" {
+		rc.Env.Deny = splitList(f.denyEnv)
+	}
+	if err := rc.Env.Validate(); err != nil {
+		return launchConfig{}, fmt.Errorf("invalid environment config: %w", err)
+	}
+
+	checkerNames := cfg.Checkers
+	if f.checkers != "" {
+		checkerNames = splitList(f.checkers)
+	}
+	for _, name := range checkerNames {
+		kind, err := ParseCheckerKind(name)
+		if err != nil {
+			return launchConfig{}, fmt.Errorf("invalid checkers: %w", err)
+		}
+		if !slices.Contains(lc.checkers, kind) {
+			lc.checkers = append(lc.checkers, kind)
+		}
+	}
+
+	lc.runner = rc.withDefaults()
+	return lc, nil
+}
+
+// waitReady waits for each project's checkers to start their first check,
+// failing after timeout (0 waits indefinitely).
+func waitReady(ctx context.Context, projects []Project, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("timed out after %v", timeout))
+		defer cancel()
+	}
+	for _, p := range projects {
+		w, ok := p.Runner.(ReadyWaiter)
+		if !ok {
+			continue
+		}
+		if err := w.WaitReady(ctx); err != nil {
+			if p.Name != "" {
+				return fmt.Errorf("project %s: %w", p.Name, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// logStartupError logs why start failed, with the checker's captured output.
+func logStartupError(err error) {
+	log.Printf("Failed to start: %v", err)
+	var startupErr *StartupError
+	if errors.As(err, &startupErr) {
+		for _, line := range startupErr.Output {
+			log.Printf("  %s", line)
+		}
+	}
+}
+
+// splitList splits a comma-separated flag value, trimming spaces and
+// dropping empty entries.
+func splitList(s string) []string {
+	var items []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
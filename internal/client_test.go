@@ -3,9 +3,11 @@ package internal
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"os"
 	"strings"
 	"testing"
 	"testing/synctest"
@@ -249,3 +251,45 @@ func TestCheck_RespectsContextCancellation(t *testing.T) {
 		}
 	})
 }
+
+// TestNewClient_Timeouts tests that a server which accepts connections but
+// never answers is bounded by WithResponseTimeout, or else by the context.
+func TestNewClient_Timeouts(t *testing.T) {
+	// The socket is named after the workspace, so keep its path short.
+	workspace, err := os.MkdirTemp("", "scs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(workspace) })
+	socketPath, err := SocketPathForWorkspace(workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+
+	c, err := NewClient(workspace, WithResponseTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	start := time.Now()
+	if _, err := c.CheckWith(context.Background(), CheckOptions{}); err == nil {
+		t.Error("CheckWith succeeded without a response")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("CheckWith took %v, want the 50ms response timeout", elapsed)
+	}
+
+	c, err = NewClient(workspace)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.CheckWith(ctx, CheckOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CheckWith() = %v, want the context's deadline", err)
+	}
+}
@@ -0,0 +1,276 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// =============================================================================
+// Config File
+// =============================================================================
+
+// ConfigFileName is the optional per-workspace configuration file.
+// Command-line flags take precedence over values set in this file.
+const ConfigFileName = ".svelte-check-server.json"
+
+// Config holds settings loaded from ConfigFileName.
+type Config struct {
+	Tsconfig       string `json:"tsconfig,omitempty"`
+	PackageManager string `json:"packageManager,omitempty"`
+
+	// Command replaces the svelte-check invocation entirely, e.g.
+	// "npx svelte-check --watch --output machine-verbose".
+	Command string `json:"command,omitempty"`
+
+	// Args are appended to the svelte-check invocation, before any
+	// arguments given after "--" on the command line.
+	Args []string `json:"args,omitempty"`
+
+	// MonitorInterval is how often svelte-check's memory and CPU are sampled,
+	// as a Go duration ("10s"). "0" disables monitoring.
+	MonitorInterval string `json:"monitorInterval,omitempty"`
+
+	// MaxMemory restarts svelte-check when its process tree exceeds this much
+	// resident memory, e.g. "4GB".
+	MaxMemory string `json:"maxMemory,omitempty"`
+
+	// Projects runs one svelte-check per entry, e.g. for tsconfig.json and
+	// tsconfig.node.json side by side. Empty means a single project at the
+	// workspace root.
+	Projects []ProjectConfig `json:"projects,omitempty"`
+
+	// Monorepo discovers projects from the workspace's package globs, as the
+	// --monorepo flag does.
+	Monorepo bool `json:"monorepo,omitempty"`
+
+	// SyncOnStart runs `svelte-kit sync` before svelte-check first starts, so
+	// fresh clones do not report missing ./$types. Defaults to true.
+	SyncOnStart *bool `json:"syncOnStart,omitempty"`
+
+	// StartupTimeout is how long start waits for svelte-check to begin its
+	// first check before failing, as a Go duration ("60s"). "0" waits
+	// indefinitely.
+	StartupTimeout string `json:"startupTimeout,omitempty"`
+
+	// RestartCooldown is the minimum time between restarts triggered by file
+	// or git changes, as a Go duration ("5s"). "0" disables throttling.
+	RestartCooldown string `json:"restartCooldown,omitempty"`
+
+	// LockfileRestart restarts svelte-check after a package manager lockfile
+	// changes, so newly installed types are picked up. Defaults to true.
+	LockfileRestart *bool `json:"lockfileRestart,omitempty"`
+
+	// LockfileGrace is how long after the last lockfile change to restart, as
+	// a Go duration ("3s"), so an install in progress can finish.
+	LockfileGrace string `json:"lockfileGrace,omitempty"`
+
+	// InterruptGrace is how long to wait after SIGINT before sending SIGTERM
+	// when stopping svelte-check, as a Go duration ("3s"). "0" skips SIGINT.
+	InterruptGrace string `json:"interruptGrace,omitempty"`
+
+	// TerminateGrace is how long to wait after SIGTERM before sending SIGKILL.
+	TerminateGrace string `json:"terminateGrace,omitempty"`
+
+	// HistorySize is how many completed results each checker retains.
+	// Defaults to 20.
+	HistorySize int `json:"historySize,omitempty"`
+
+	// FailOn lists what fails GET /check and the check command: "failure",
+	// "error", "warning". Defaults to ["failure", "error"].
+	FailOn []string `json:"failOn,omitempty"`
+
+	// FailureStatus is the HTTP status GET /check returns when svelte-check
+	// reports a FAILURE: 502 (the default) or 500.
+	FailureStatus int `json:"failureStatus,omitempty"`
+
+	// ExitCodes overrides the check command's exit code per severity, e.g.
+	// {"warning": 3}. Defaults: failure 2, error 1, warning 1.
+	ExitCodes map[string]int `json:"exitCodes,omitempty"`
+
+	// Ignore suppresses matching diagnostics from /check results, e.g.
+	// [{"code": "css_unused_selector", "glob": "src/legacy/**"}].
+	Ignore []IgnoreRule `json:"ignore,omitempty"`
+
+	// ExcludeGenerated suppresses diagnostics in .svelte-kit and node_modules
+	// directories and in each project's own build directory. Defaults to true.
+	ExcludeGenerated *bool `json:"excludeGenerated,omitempty"`
+
+	// ExcludeDiagnostics lists further globs, such as "dist/**", whose
+	// diagnostics are suppressed.
+	ExcludeDiagnostics []string `json:"excludeDiagnostics,omitempty"`
+
+	// WatchIgnore lists globs of paths the watcher skips, as --ignore does.
+	WatchIgnore []string `json:"watchIgnore,omitempty"`
+
+	// WatchBackend selects how files are watched: "notify" (the default),
+	// "poll" for bind mounts and network filesystems without change events,
+	// or "watchman" to subscribe to a Watchman daemon in very large
+	// repositories.
+	WatchBackend string `json:"watchBackend,omitempty"`
+
+	// PollInterval is how often the "poll" backend scans, as a Go duration
+	// ("1s").
+	PollInterval string `json:"pollInterval,omitempty"`
+
+	// SyncOn lists workspace-relative globs of files, besides route files
+	// and directories, whose changes run svelte-kit sync. It replaces the
+	// default of each project's param matchers, src/app.d.ts, and
+	// src/hooks.server.*; [] disables them.
+	SyncOn []string `json:"syncOn,omitempty"`
+
+	// RestartOn lists workspace-relative globs of files whose changes
+	// restart svelte-check, for ambient type declarations its own watcher
+	// may miss. It replaces the default of each project's *.d.ts and
+	// types/**/*.d.ts; [] disables them.
+	RestartOn []string `json:"restartOn,omitempty"`
+
+	// Rules run a command, svelte-kit sync, or a restart when matching files
+	// change, e.g. [{"name": "codegen", "globs": ["schema.graphql"],
+	// "run": "npm run codegen"}].
+	Rules []RuleConfig `json:"rules,omitempty"`
+
+	// Notify sends changes of the workspace's health, such as errors
+	// appearing or being fixed, to webhooks.
+	Notify NotifyConfig `json:"notify,omitzero"`
+
+	// Hooks run shell commands when a check completes, errors are
+	// introduced or fixed, or svelte-kit sync fails.
+	Hooks ExecHooksConfig `json:"hooks,omitzero"`
+
+	// AutoWatch lists globs of directories, relative to the workspace, to
+	// watch recursively as soon as they exist, e.g. ["packages/*/src"] to
+	// cover packages scaffolded while the daemon runs.
+	AutoWatch []string `json:"autoWatch,omitempty"`
+
+	// FollowSymlinks makes recursive watches descend into symlinked
+	// directories, e.g. src/lib/shared linking to a sibling package.
+	FollowSymlinks bool `json:"followSymlinks,omitempty"`
+
+	// MaxWatchers caps the filesystem watchers the daemon opens (default
+	// 100).
+	MaxWatchers int `json:"maxWatchers,omitempty"`
+
+	// AuditLog is a file, relative to the workspace unless absolute, that
+	// every watcher event, git trigger, restart, sync, and completed check
+	// is appended to as a JSON line.
+	AuditLog string `json:"auditLog,omitempty"`
+
+	// AuditLogMaxSize is the size at which the audit log is rotated, e.g.
+	// "50MB" (default 10MB).
+	AuditLogMaxSize string `json:"auditLogMaxSize,omitempty"`
+
+	// Journal is a file, relative to the workspace unless absolute, that
+	// every check's start, summary, and failures, and the restarts and syncs
+	// between them, are appended to as JSON lines.
+	Journal string `json:"journal,omitempty"`
+
+	// JournalMaxSize is the size at which the journal is rotated, e.g.
+	// "50MB" (default 10MB).
+	JournalMaxSize string `json:"journalMaxSize,omitempty"`
+
+	// OTLPEndpoint is the OTLP/HTTP traces URL check cycles and requests are
+	// exported to, e.g. "http://localhost:4318/v1/traces". The standard
+	// OTEL_EXPORTER_OTLP_* variables are used when it is not set.
+	OTLPEndpoint string `json:"otlpEndpoint,omitempty"`
+
+	// PollFallback polls directories that cannot be watched because the OS
+	// ran out of watches, instead of missing their changes.
+	PollFallback bool `json:"pollFallback,omitempty"`
+
+	// WatchSkipDirs replaces the directory names recursive watches never
+	// descend into (node_modules, .git, .svelte-kit, and each project's own
+	// build, dist, and kit.outDir). An empty list watches everything.
+	WatchSkipDirs []string `json:"watchSkipDirs,omitempty"`
+
+	// Checkers lists the tools to run and merge: "svelte-check", "tsc",
+	// "eslint". Empty means svelte-check only.
+	Checkers []string `json:"checkers,omitempty"`
+
+	// Env sets variables for spawned checkers, e.g.
+	// {"NODE_OPTIONS": "--max-old-space-size=8192"}. Values may reference the
+	// daemon's environment as $NAME.
+	Env map[string]string `json:"env,omitempty"`
+
+	// InheritEnv, when set, limits the daemon variables passed to checkers to
+	// these names or glob patterns.
+	InheritEnv []string `json:"inheritEnv,omitempty"`
+
+	// DenyEnv lists daemon variables (names or glob patterns) never passed to
+	// checkers.
+	DenyEnv []string `json:"denyEnv,omitempty"`
+}
+
+// LoadConfig reads ConfigFileName from the workspace.
+// A missing file is not an error and yields the zero Config.
+func LoadConfig(workspacePath string) (Config, error) {
+	var cfg Config
+
+	data, err := os.ReadFile(filepath.Join(workspacePath, ConfigFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", ConfigFileName, err)
+	}
+	return cfg, nil
+}
+
+// SplitCommandLine splits a command string into argv, honoring single and
+// double quotes and backslash escapes the way a POSIX shell would. It does not
+// perform variable expansion or globbing.
+func SplitCommandLine(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' && i+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[i+1]) {
+				i++
+				cur.WriteRune(runes[i])
+			} else {
+				cur.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == '\\':
+			if i+1 < len(runes) {
+				i++
+				cur.WriteRune(runes[i])
+			}
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(c)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in command", quote)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
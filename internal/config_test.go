@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestLoadConfig_MissingFile(t *testing.T) {
+	cfg, err := LoadConfig(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !reflect.DeepEqual(cfg, Config{}) {
+		t.Errorf("LoadConfig() = %+v, want zero Config", cfg)
+	}
+}
+
+func TestLoadConfig_ParsesFields(t *testing.T) {
+	dir := t.TempDir()
+	data := `{"tsconfig": "tsconfig.app.json", "packageManager": "pnpm", "command": "npx svelte-check --watch --output machine-verbose", "args": ["--fail-on-warnings"], "monitorInterval": "5s", "maxMemory": "4GB", "projects": [{"name": "app"}, {"name": "node", "tsconfig": "tsconfig.node.json", "dir": "tools"}], "env": {"NODE_OPTIONS": "--max-old-space-size=8192"}, "denyEnv": ["AWS_*"], "syncOnStart": false, "failOn": ["warning"], "failureStatus": 500, "exitCodes": {"warning": 3}}`
+	if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	cfg, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	want := Config{
+		Tsconfig:        "tsconfig.app.json",
+		PackageManager:  "pnpm",
+		Command:         "npx svelte-check --watch --output machine-verbose",
+		Args:            []string{"--fail-on-warnings"},
+		MonitorInterval: "5s",
+		MaxMemory:       "4GB",
+		Projects: []ProjectConfig{
+			{Name: "app"},
+			{Name: "node", Tsconfig: "tsconfig.node.json", Dir: "tools"},
+		},
+		Env:           map[string]string{"NODE_OPTIONS": "--max-old-space-size=8192"},
+		DenyEnv:       []string{"AWS_*"},
+		SyncOnStart:   new(bool),
+		FailOn:        []string{"warning"},
+		FailureStatus: 500,
+		ExitCodes:     map[string]int{"warning": 3},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("LoadConfig() = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoadConfig_InvalidJSON(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte("{"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	if _, err := LoadConfig(dir); err == nil {
+		t.Error("LoadConfig should fail on invalid JSON")
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"simple", "npx svelte-check --watch", []string{"npx", "svelte-check", "--watch"}},
+		{"extra whitespace", "  npx   svelte-check\t--watch ", []string{"npx", "svelte-check", "--watch"}},
+		{"double quotes", `svelte-check --ignore "dist/**, build"`, []string{"svelte-check", "--ignore", "dist/**, build"}},
+		{"single quotes", `sh -c 'echo "hi"'`, []string{"sh", "-c", `echo "hi"`}},
+		{"escaped space", `my\ tool arg`, []string{"my tool", "arg"}},
+		{"escaped quote in double quotes", `echo "a\"b"`, []string{"echo", `a"b`}},
+		{"empty quoted arg", `cmd ""`, []string{"cmd", ""}},
+		{"adjacent quotes join", `--flag="a b"c`, []string{"--flag=a bc"}},
+		{"empty", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitCommandLine(tt.input)
+			if err != nil {
+				t.Fatalf("SplitCommandLine(%q) failed: %v", tt.input, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SplitCommandLine(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitCommandLine_UnterminatedQuote(t *testing.T) {
+	if _, err := SplitCommandLine(`svelte-check --ignore "dist`); err == nil {
+		t.Error("SplitCommandLine should fail on unterminated quote")
+	}
+}
+
+func TestRunnerFlags_TsconfigWithCommand(t *testing.T) {
+	dir := t.TempDir()
+	f := runnerFlags{tsconfig: "tsconfig.app.json", command: "npx svelte-check --watch"}
+	if _, err := f.load(dir, nil); err == nil {
+		t.Error("load with --tsconfig and --command succeeded")
+	}
+
+	// The config file's settings are rejected the same way.
+	if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(`{"tsconfig": "tsconfig.app.json"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&runnerFlags{command: "npx svelte-check --watch"}).load(dir, nil); err == nil {
+		t.Error(`load with "tsconfig" and --command succeeded`)
+	}
+	if _, err := (&runnerFlags{}).load(dir, nil); err != nil {
+		t.Errorf("load with only a tsconfig failed: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// =============================================================================
+// Health Checks
+// =============================================================================
+
+// ContainerListenAddr is where start --container serves TCP when a token is
+// set, so that the host or other containers can reach it.
+const ContainerListenAddr = "0.0.0.0:7420"
+
+// unhealthy returns why the server is unhealthy: each checker that has
+// stopped or given up restarting, and each subsystem that has given up. A
+// checker restarting after a crash is still healthy.
+func unhealthy(status Status) []string {
+	var reasons []string
+	var check func(name string, r RunnerStatus)
+	check = func(name string, r RunnerStatus) {
+		if r.State == RunnerStateFailed || r.State == RunnerStateStopped {
+			reasons = append(reasons, fmt.Sprintf("%s is %s", name, r.State))
+		}
+		for _, c := range r.Checkers {
+			check(name+"/"+c.Name, c.Status)
+		}
+	}
+	if len(status.Projects) == 0 {
+		check("checker", status.Runner)
+	}
+	for _, p := range status.Projects {
+		check(p.Name, p.Runner)
+	}
+	for _, s := range status.Subsystems {
+		if s.State == SubsystemFailed {
+			reasons = append(reasons, fmt.Sprintf("subsystem %s failed: %s", s.Name, s.LastFailure))
+		}
+	}
+	return reasons
+}
+
+// handleHealthz serves GET /healthz: 200 "ok", or 503 with the reasons the
+// server is unhealthy, one per line, for container health checks.
+func (s *Server) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if reasons := unhealthy(s.Status()); len(reasons) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, strings.Join(reasons, "\n")+"\n")
+		return
+	}
+	_, _ = io.WriteString(w, "ok\n")
+}
+
+// Healthz returns nil if the server reports itself healthy, or an error
+// with its reasons.
+func (c *Client) Healthz(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://unix/healthz", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unhealthy (status %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// =============================================================================
+// Init Process
+// =============================================================================
+
+// initSignals are forwarded by runAsInit to the daemon.
+var initSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGUSR2}
+
+// runAsInit runs this command again as a child and serves as its init
+// process, for a container without one whose entrypoint is the daemon. It
+// forwards signals to the child and reaps every process reparented to PID
+// 1, such as svelte-check's orphaned workers, which would otherwise remain
+// zombies. It exits with the child's status once the child exits.
+func runAsInit() {
+	children := make(chan os.Signal, 1)
+	signal.Notify(children, syscall.SIGCHLD)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, initSignals...)
+
+	self, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to find this executable: %v", err)
+	}
+	cmd := exec.Command(self, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		log.Fatalf("Failed to start the server: %v", err)
+	}
+
+	for {
+		select {
+		case sig := <-signals:
+			_ = cmd.Process.Signal(sig)
+		case <-children:
+			if status, ok := reap(cmd.Process.Pid); ok {
+				os.Exit(exitStatus(status))
+			}
+		}
+	}
+}
+
+// reap waits for every exited child without blocking, and returns the
+// status of pid if it was among them.
+func reap(pid int) (syscall.WaitStatus, bool) {
+	var result syscall.WaitStatus
+	found := false
+	for {
+		var status syscall.WaitStatus
+		reaped, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || reaped <= 0 {
+			return result, found
+		}
+		if reaped == pid {
+			result, found = status, true
+		}
+	}
+}
+
+// exitStatus converts a child's wait status to an exit code as a shell
+// would: its own, or 128 plus the signal that killed it.
+func exitStatus(status syscall.WaitStatus) int {
+	if status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return status.ExitStatus()
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestServer_Healthz tests that the server is unhealthy until its checker
+// runs, and healthy while it does.
+func TestServer_Healthz(t *testing.T) {
+	socketPath := testSocketPath(t)
+	executor := NewFakeExecutor(checkOutput("1770255834000", 2), "")
+	r := NewRunner("/workspace", WithExecutor(executor))
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+	c := &Client{socketPath: socketPath, httpClient: unixHTTPClient(socketPath)}
+
+	if err := c.Healthz(context.Background()); err == nil || !strings.Contains(err.Error(), "checker is stopped") {
+		t.Errorf("Healthz() before Start = %v, want checker is stopped", err)
+	}
+
+	_ = r.Start(context.Background())
+	defer r.Stop()
+	if _, err := r.GetLatestEvent(context.Background()); err != nil {
+		t.Fatalf("GetLatestEvent failed: %v", err)
+	}
+	// Errors in the result do not make the server unhealthy.
+	if err := c.Healthz(context.Background()); err != nil {
+		t.Errorf("Healthz() = %v, want nil", err)
+	}
+}
+
+func TestUnhealthy(t *testing.T) {
+	status := Status{
+		Projects: []ProjectStatus{
+			{Name: "web", Runner: RunnerStatus{State: RunnerStateDegraded}},
+			{Name: "docs", Runner: RunnerStatus{State: RunnerStateReady, Checkers: []CheckerStatus{
+				{Name: "tsc", Status: RunnerStatus{State: RunnerStateFailed}},
+			}}},
+		},
+		Subsystems: []SubsystemStatus{
+			{Name: "watcher", State: SubsystemRunning},
+			{Name: "git", State: SubsystemFailed, LastFailure: "no such file"},
+		},
+	}
+	want := "docs/tsc is failed\nsubsystem git failed: no such file"
+	if got := strings.Join(unhealthy(status), "\n"); got != want {
+		t.Errorf("unhealthy() = %q, want %q", got, want)
+	}
+}
+
+func TestRunnerFlags_Container(t *testing.T) {
+	f := runnerFlags{container: true}
+	lc, err := f.load(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if lc.watchBackend != WatchBackendPoll {
+		t.Errorf("watchBackend = %q, want %q", lc.watchBackend, WatchBackendPoll)
+	}
+}
@@ -0,0 +1,427 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// Daemon
+// =============================================================================
+
+// DaemonOptions configures RunDaemon. Everything else comes from the
+// workspace's config file, as for the start command.
+type DaemonOptions struct {
+	// Workspace is the directory to check.
+	Workspace string
+
+	// RecursiveDirs and NonRecursiveDirs are the workspace-relative
+	// directories to watch. When both are empty, the workspace root and
+	// each project's src, routes, and lib directories are watched.
+	RecursiveDirs    []string
+	NonRecursiveDirs []string
+
+	// Args are appended to the config file's svelte-check arguments.
+	Args []string
+
+	// SocketPath is where to serve; empty for the workspace's socket, which
+	// clients find by workspace.
+	SocketPath string
+
+	// Listener, if set, serves the socket in place of creating it at
+	// SocketPath, e.g. one passed by systemd (see ActivationListener).
+	Listener net.Listener
+
+	// Listen, if set, also serves on this TCP address, requiring Token of
+	// clients (see Server.ListenTCP).
+	Listen string
+	Token  string
+
+	// Executor runs svelte-check, svelte-kit sync, and git; nil for the
+	// real one.
+	Executor kexec.Interface
+
+	// FSWatcher reports file changes in the watched directories; nil for the
+	// configured backend. The daemon closes it when it stops.
+	FSWatcher FSWatcher
+}
+
+// Daemon is a running daemon: its checkers, server, and watchers.
+type Daemon struct {
+	srv        *Server
+	projects   []Project
+	watcher    *Watcher
+	gitWatcher *RealGitBranchWatcher
+	audit      *AuditLog // tees to the journal, if any
+	tracer     *Tracer
+	supervisor *Supervisor
+	lock       *os.File // see claimSocket
+	cancel     context.CancelFunc
+	ended      <-chan struct{} // closed when the daemon's context is done
+	stopOnce   sync.Once
+	stopErr    error
+}
+
+// RunDaemon starts a daemon for opts.Workspace, as the start command does,
+// and returns once its checkers have started their first check. It runs
+// until Stop is called, a client requests POST /stop (see Done), or ctx is
+// done.
+func RunDaemon(ctx context.Context, opts DaemonOptions) (*Daemon, error) {
+	if opts.Workspace == "" {
+		return nil, errors.New("no workspace")
+	}
+	workspace, err := filepath.Abs(opts.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	opts.Workspace = workspace
+	lc, err := (&runnerFlags{}).load(workspace, opts.Args)
+	if err != nil {
+		return nil, err
+	}
+	d, err := startDaemon(ctx, lc, opts)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-d.ended
+		stopCtx, cancel := context.WithTimeout(context.Background(), daemonStopTimeout)
+		defer cancel()
+		_ = d.Stop(stopCtx)
+	}()
+	return d, nil
+}
+
+// daemonStopTimeout bounds how long shutting down the server may take.
+const daemonStopTimeout = 5 * time.Second
+
+// startDaemon wires the checkers, server, and watchers of lc and starts
+// them. opts.Args is ignored; lc already holds them.
+func startDaemon(ctx context.Context, lc launchConfig, opts DaemonOptions) (*Daemon, error) {
+	workspace := opts.Workspace
+	daemonLog := logger("daemon").With("workspace", workspace)
+	socketPath := opts.SocketPath
+	if socketPath == "" {
+		var err error
+		if socketPath, err = SocketPathForWorkspace(workspace); err != nil {
+			return nil, fmt.Errorf("failed to get socket path: %w", err)
+		}
+	}
+	claim := claimSocket
+	if opts.Listener != nil {
+		claim = lockSocket
+	}
+	lock, err := claim(socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	d := &Daemon{cancel: cancel, ended: ctx.Done(), lock: lock}
+	started := false
+	defer func() {
+		if !started {
+			cancel()
+			_ = lock.Close()
+		}
+	}()
+
+	runnerConfig, projectConfigs := lc.runner, lc.projects
+	pm := runnerConfig.PackageManager
+	executor := opts.Executor
+	if executor == nil {
+		executor = NewExecutor()
+	}
+
+	rules, err := EventRules(ctx, lc.rules, workspace, runnerConfig.Env, executor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules: %w", err)
+	}
+
+	recursiveDirs, nonRecursiveDirs := opts.RecursiveDirs, opts.NonRecursiveDirs
+	kits := make(map[string]KitPaths) // by project directory
+	for _, dir := range projectDirs(projectConfigs) {
+		kits[dir] = LoadKitPaths(ctx, filepath.Join(workspace, dir), executor)
+	}
+	if len(recursiveDirs) == 0 && len(nonRecursiveDirs) == 0 {
+		nonRecursiveDirs, recursiveDirs = defaultWatchDirs(projectConfigs, kits)
+	}
+	if lc.watchSkipDirs == nil {
+		lc.watchSkipDirs = withRootSkipDirs(workspace, DefaultSkipDirs, kits)
+	}
+	if lc.syncOn == nil {
+		lc.syncOn = syncGlobs(projectConfigs, kits)
+	}
+	if lc.restartOn == nil {
+		lc.restartOn = restartGlobs(projectConfigs)
+	}
+
+	// Persist each result so that after a restart, /check?stale=true can
+	// serve it while the first check runs.
+	runnerConfig.StateFile = socketPath + StateFileSuffix
+	runnerConfig.CrashDir = socketPath + CrashDirSuffix
+
+	if lc.auditLog != "" {
+		d.audit, err = OpenAuditLog(lc.auditLog, lc.auditLogMaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+	}
+	if lc.journal != "" {
+		journal, err := OpenJournal(lc.journal, lc.journalMaxSize)
+		if err != nil {
+			_ = d.audit.Close()
+			return nil, fmt.Errorf("failed to open journal: %w", err)
+		}
+		d.audit = d.audit.Tee(journal)
+	}
+	if d.audit != nil {
+		defer func() {
+			if !started {
+				_ = d.audit.Close()
+			}
+		}()
+		d.audit.Record("start", map[string]any{"workspace": workspace})
+		runnerConfig.Audit = d.audit
+	}
+	if lc.tracer.Endpoint != "" {
+		d.tracer = NewTracer(lc.tracer)
+		defer func() {
+			if !started {
+				_ = d.tracer.Close(context.Background())
+			}
+		}()
+		runnerConfig.Tracer = d.tracer
+	}
+
+	if len(projectConfigs) == 0 {
+		d.projects = []Project{{Runner: lc.newChecker(runnerConfig, executor)}}
+	} else {
+		for i, c := range ProjectRunnerConfigs(runnerConfig, projectConfigs) {
+			c.StateFile = QualifyStateFile(c.StateFile, projectConfigs[i].Name)
+			d.projects = append(d.projects, Project{
+				Name:   projectConfigs[i].Name,
+				Dir:    projectConfigs[i].Dir,
+				Runner: lc.newChecker(c, executor),
+			})
+		}
+	}
+	projects := d.projects
+	defer func() {
+		if !started {
+			d.stopRunners()
+		}
+	}()
+
+	// Generate ./$types before the first check so a fresh clone does not
+	// report missing types until a route file happens to change.
+	hooks := NewExecHooks(lc.hooks, workspace, runnerConfig.Env, executor)
+	syncs := NewSyncTracker(workspace, pm, executor)
+	syncs.SetAuditLog(d.audit)
+	syncs.SetExecHooks(hooks)
+	if lc.syncOnStart {
+		syncs.SyncAll(ctx, projectDirs(projectConfigs))
+	}
+
+	for _, p := range projects {
+		if err := p.Runner.Start(ctx); err != nil {
+			return nil, fmt.Errorf("failed to start svelte-check: %w", err)
+		}
+	}
+
+	if err := waitReady(ctx, projects, lc.startupTimeout); err != nil {
+		return nil, err
+	}
+
+	srv := NewServer(socketPath, projects[0].Runner)
+	if len(projectConfigs) > 0 {
+		srv = NewProjectServer(socketPath, projects)
+	}
+	srv.SetSyncTracker(syncs)
+	srv.SetCheckPolicy(lc.policy)
+	srv.SetBaselineFile(filepath.Join(workspace, BaselineFileName))
+	srv.SetIgnoreRules(lc.ignore)
+	srv.SetAuditLog(d.audit)
+	srv.SetTracer(d.tracer)
+	notifications, err := lc.notify.Notifications()
+	if err != nil {
+		return nil, fmt.Errorf("invalid notify: %w", err)
+	}
+	srv.SetNotifications(notifications)
+	srv.SetExecHooks(hooks)
+	if opts.Listener != nil {
+		srv.SetListener(opts.Listener)
+	}
+	if opts.Listen != "" {
+		if err := srv.ListenTCP(opts.Listen, opts.Token); err != nil {
+			return nil, err
+		}
+	}
+	d.srv = srv
+
+	limit := NewWatcherLimit(lc.maxWatchers)
+	watcherConfig := WatcherConfig{
+		WorkspacePath:     workspace,
+		RecursiveDirs:     recursiveDirs,
+		NonRecursiveDirs:  nonRecursiveDirs,
+		RestartCooldown:   lc.restartCooldown,
+		RestartOnLockfile: lc.lockfileRestart,
+		LockfileGrace:     lc.lockfileGrace,
+		Ignore:            lc.watchIgnore,
+		RouteDirs:         routeDirs(projectConfigs, kits),
+		SyncOn:            lc.syncOn,
+		RestartOn:         lc.restartOn,
+		Limit:             limit,
+		Audit:             d.audit,
+		Rules:             rules,
+		AutoWatch:         lc.autoWatch,
+	}
+
+	callbacks := WatcherCallbacks{
+		OnRestart: func() {
+			daemonLog.Info("change detected, restarting checkers")
+			ctx := ContextWithTrigger(ctx, d.watcher.LastTrigger())
+			for _, p := range projects {
+				if err := p.Runner.Restart(ctx); err != nil {
+					daemonLog.Error("failed to restart checker", "project", p.Name, "error", err)
+				}
+			}
+		},
+		OnSvelteSync: func() {
+			// Each sync is traced as part of the next check cycle of the
+			// first project in its directory.
+			for _, dir := range projectDirs(projectConfigs) {
+				syncs.SyncAll(traceCycle(ctx, projectIn(projects, dir).Runner, "sync"), []string{dir})
+			}
+		},
+		OnDependenciesChanged: srv.DependenciesChanged,
+	}
+
+	if slices.Contains(lc.checkers, CheckerESLint) {
+		callbacks.OnSourceChange = func() {
+			for _, p := range projects {
+				if r, ok := p.Runner.(Rerunner); ok {
+					r.Rerun()
+				}
+			}
+		}
+	}
+
+	fsWatcher := opts.FSWatcher
+	if fsWatcher == nil {
+		if fsWatcher, err = lc.newFSWatcher(executor, limit); err != nil {
+			return nil, fmt.Errorf("failed to create filesystem watcher: %w", err)
+		}
+	}
+
+	d.gitWatcher, err = NewRealGitBranchWatcherWithLimit(workspace, executor, limit)
+	if err != nil {
+		_ = fsWatcher.Close()
+		return nil, fmt.Errorf("failed to create git branch watcher: %w", err)
+	}
+
+	d.watcher = NewWatcher(watcherConfig, callbacks, fsWatcher, d.gitWatcher)
+	srv.SetWatcher(d.watcher)
+
+	if err := srv.Start(ctx); err != nil {
+		_ = d.watcher.Close()
+		_ = d.gitWatcher.Close()
+		return nil, fmt.Errorf("failed to start server: %w", err)
+	}
+	started = true
+
+	d.supervisor = NewSupervisor(ctx, RestartPolicy{})
+	srv.SetSupervisor(d.supervisor)
+	d.supervisor.Go("git watcher", d.gitWatcher.Start, d.gitWatcher.Reopen)
+	d.supervisor.Go("file watcher", d.watcher.Start, func() error {
+		if opts.FSWatcher != nil {
+			return errors.New("cannot replace the injected filesystem watcher")
+		}
+		fsWatcher, err := lc.newFSWatcher(executor, limit)
+		if err != nil {
+			return err
+		}
+		d.watcher.ReplaceFSWatcher(fsWatcher)
+		return nil
+	})
+
+	daemonLog.Info("server started", "socket", socketPath)
+	if addr := srv.TCPAddr(); addr != "" {
+		daemonLog.Info("also serving on tcp", "addr", addr)
+	}
+	for _, p := range projects {
+		for _, line := range commandLines(p.Runner) {
+			if p.Name != "" {
+				daemonLog.Info("running", "project", p.Name, "command", line)
+			} else {
+				daemonLog.Info("running", "command", line)
+			}
+		}
+	}
+	daemonLog.Info("watching directories", "nonRecursive", nonRecursiveDirs, "recursive", recursiveDirs)
+	return d, nil
+}
+
+// stopRunners stops every project's checker.
+func (d *Daemon) stopRunners() {
+	for _, p := range d.projects {
+		p.Runner.Stop()
+	}
+}
+
+// projectIn returns the first of projects in dir, relative to the workspace,
+// or the first project if none is.
+func projectIn(projects []Project, dir string) Project {
+	for _, p := range projects {
+		if filepath.Clean(p.Dir) == dir {
+			return p
+		}
+	}
+	return projects[0]
+}
+
+// Stop shuts the daemon down: its watchers, checkers, and server, removing
+// the socket. Later calls return the first call's result.
+func (d *Daemon) Stop(ctx context.Context) error {
+	d.stopOnce.Do(func() {
+		// Stop supervising first, so closing the watchers ends their loops
+		// rather than restarting them.
+		d.supervisor.Stop()
+		_ = d.watcher.Close()
+		_ = d.gitWatcher.Close()
+		d.stopRunners()
+		d.stopErr = d.srv.Stop(ctx)
+		_ = d.tracer.Close(ctx)
+		_ = d.lock.Close()
+		d.cancel()
+		d.audit.Record("stop", nil)
+		_ = d.audit.Close()
+	})
+	return d.stopErr
+}
+
+// Done returns a channel that is closed when a client requests POST /stop.
+// The daemon keeps running until Stop is called.
+func (d *Daemon) Done() <-chan struct{} {
+	return d.srv.ShutdownCh()
+}
+
+// Status returns a snapshot of the daemon's health, as served by GET
+// /status.
+func (d *Daemon) Status() Status {
+	return d.srv.Status()
+}
+
+// SocketPath returns the path of the socket the daemon serves on.
+func (d *Daemon) SocketPath() string {
+	return d.srv.SocketPath()
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestRunDaemon tests running the daemon in-process: it serves its
+// workspace's socket, refuses a second daemon, and stops with its context.
+func TestRunDaemon(t *testing.T) {
+	// The socket is named after the workspace, so keep its path short.
+	workspace, err := os.MkdirTemp("", "scs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(workspace) })
+	writeFiles(t, workspace, map[string]string{
+		ConfigFileName: `{"syncOnStart": false, "monitorInterval": "0"}`,
+	})
+	// svelte-check and git each get their own command.
+	executor := NewFakeExecutor("", "")
+	executor.newCmd = func() *FakeCmd { return newFakeCmd(checkOutput("1770255834000", 1)) }
+	opts := DaemonOptions{Workspace: workspace, Executor: executor}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, err := RunDaemon(ctx, opts)
+	if err != nil {
+		t.Fatalf("RunDaemon failed: %v", err)
+	}
+
+	c, err := NewClient(workspace)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if c.SocketPath() != d.SocketPath() {
+		t.Errorf("client socket %s, want the daemon's %s", c.SocketPath(), d.SocketPath())
+	}
+	resp, err := c.CheckWith(ctx, CheckOptions{})
+	if err != nil || resp.ExitCode != 1 {
+		t.Errorf("CheckWith() = %+v, %v; want the failing result", resp, err)
+	}
+	if status := d.Status(); status.Runner.State == "" {
+		t.Errorf("Status() = %+v, want the runner's state", status)
+	}
+
+	if _, err := RunDaemon(ctx, opts); err == nil {
+		t.Error("second RunDaemon for the workspace succeeded")
+	}
+
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for SocketExists(d.SocketPath()) {
+		if time.Now().After(deadline) {
+			t.Fatal("socket not removed after cancelling")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
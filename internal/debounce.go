@@ -49,3 +49,123 @@ func (d *Debouncer) Stop() {
 		d.timer = nil
 	}
 }
+
+// Throttle runs a callback at most once per cooldown. A Trigger during the
+// cooldown is deferred until it ends, and further triggers in the meantime
+// are coalesced into that single deferred call, so the last trigger is never
+// lost.
+//
+// The zero value is not usable; use NewThrottle to create a Throttle.
+type Throttle struct {
+	cooldown time.Duration
+	callback func()
+
+	mu         sync.Mutex
+	last       time.Time   // when the callback last ran
+	timer      *time.Timer // pending deferred call, nil if none
+	held       bool        // calls wait for Release
+	pending    bool        // a call waits for Release
+	calls      int
+	suppressed int
+}
+
+// NewThrottle creates a Throttle that calls callback at most once per
+// cooldown. A zero cooldown calls callback on every Trigger.
+func NewThrottle(cooldown time.Duration, callback func()) *Throttle {
+	return &Throttle{
+		cooldown: cooldown,
+		callback: callback,
+	}
+}
+
+// Trigger calls the callback now if the cooldown has passed, or schedules a
+// call for when it ends. Triggers that join an already scheduled call are
+// counted as suppressed.
+func (t *Throttle) Trigger() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.timer != nil || (t.held && t.pending) {
+		t.suppressed++
+		return
+	}
+	if t.held {
+		t.pending = true
+		return
+	}
+	t.triggerLocked()
+}
+
+// triggerLocked calls the callback or schedules it. t.mu must be held.
+func (t *Throttle) triggerLocked() {
+	wait := t.cooldown - time.Since(t.last)
+	if t.last.IsZero() || wait <= 0 {
+		t.fireLocked()
+		go t.callback()
+		return
+	}
+	t.timer = time.AfterFunc(wait, func() {
+		t.mu.Lock()
+		t.timer = nil
+		t.fireLocked()
+		t.mu.Unlock()
+		t.callback()
+	})
+}
+
+// Hold defers calls until Release, e.g. while git rewrites the working tree.
+// Triggers in the meantime, and a call already scheduled, are coalesced into
+// one call at Release.
+func (t *Throttle) Hold() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.held = true
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+		t.pending = true
+	}
+}
+
+// Release ends a Hold, making the call deferred by it, if any, subject to
+// the cooldown as usual.
+func (t *Throttle) Release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.held {
+		return
+	}
+	t.held = false
+	if t.pending {
+		t.pending = false
+		t.triggerLocked()
+	}
+}
+
+// fireLocked records a callback invocation. t.mu must be held.
+func (t *Throttle) fireLocked() {
+	t.last = time.Now()
+	t.calls++
+}
+
+// Counts returns how many times the callback has run and how many triggers
+// were coalesced into another call.
+func (t *Throttle) Counts() (calls, suppressed int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.calls, t.suppressed
+}
+
+// Stop cancels any deferred call. It is safe to call Trigger again after Stop.
+func (t *Throttle) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.pending = false
+}
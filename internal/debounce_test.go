@@ -132,3 +132,133 @@ func TestDebouncer_TwoSeparateBursts(t *testing.T) {
 		}
 	})
 }
+
+func TestThrottle_FirstTriggerRunsImmediately(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var called atomic.Int32
+		th := NewThrottle(time.Second, func() { called.Add(1) })
+
+		th.Trigger()
+		synctest.Wait()
+		if called.Load() != 1 {
+			t.Errorf("callback count = %d, want 1", called.Load())
+		}
+	})
+}
+
+func TestThrottle_CoalescesDuringCooldown(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var called atomic.Int32
+		th := NewThrottle(time.Second, func() { called.Add(1) })
+
+		th.Trigger()
+		synctest.Wait()
+
+		// Three more triggers within the cooldown become one deferred call.
+		for range 3 {
+			time.Sleep(100 * time.Millisecond)
+			th.Trigger()
+		}
+		synctest.Wait()
+		if called.Load() != 1 {
+			t.Fatalf("callback count = %d during cooldown, want 1", called.Load())
+		}
+
+		time.Sleep(time.Second)
+		synctest.Wait()
+		if called.Load() != 2 {
+			t.Errorf("callback count = %d after cooldown, want 2", called.Load())
+		}
+
+		calls, suppressed := th.Counts()
+		if calls != 2 || suppressed != 2 {
+			t.Errorf("Counts() = %d, %d; want 2, 2", calls, suppressed)
+		}
+	})
+}
+
+func TestThrottle_ZeroCooldown(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var called atomic.Int32
+		th := NewThrottle(0, func() { called.Add(1) })
+
+		th.Trigger()
+		th.Trigger()
+		synctest.Wait()
+		if called.Load() != 2 {
+			t.Errorf("callback count = %d, want 2", called.Load())
+		}
+	})
+}
+
+func TestThrottle_Stop_CancelsDeferred(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var called atomic.Int32
+		th := NewThrottle(time.Second, func() { called.Add(1) })
+
+		th.Trigger()
+		th.Trigger()
+		th.Stop()
+
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+		if called.Load() != 1 {
+			t.Errorf("callback count = %d, want 1 (deferred call canceled)", called.Load())
+		}
+	})
+}
+
+func TestThrottle_Hold_DefersUntilRelease(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var called atomic.Int32
+		th := NewThrottle(time.Second, func() { called.Add(1) })
+
+		th.Hold()
+		for range 3 {
+			th.Trigger()
+			time.Sleep(time.Second)
+		}
+		synctest.Wait()
+		if called.Load() != 0 {
+			t.Fatalf("callback count = %d while held, want 0", called.Load())
+		}
+
+		th.Release()
+		synctest.Wait()
+		if called.Load() != 1 {
+			t.Errorf("callback count = %d after Release, want 1", called.Load())
+		}
+
+		// Releasing without triggers calls nothing.
+		th.Hold()
+		th.Release()
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+		if called.Load() != 1 {
+			t.Errorf("callback count = %d after empty hold, want 1", called.Load())
+		}
+	})
+}
+
+func TestThrottle_Hold_DefersScheduledCall(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var called atomic.Int32
+		th := NewThrottle(time.Second, func() { called.Add(1) })
+
+		th.Trigger()
+		th.Trigger() // deferred to the end of the cooldown
+		th.Hold()
+
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+		if called.Load() != 1 {
+			t.Fatalf("callback count = %d while held, want 1", called.Load())
+		}
+
+		th.Release()
+		synctest.Wait()
+		if called.Load() != 2 {
+			t.Errorf("callback count = %d after Release, want 2", called.Load())
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+)
+
+// =============================================================================
+// Self-Diagnostics
+// =============================================================================
+
+// DebugInfo is the daemon's own health, served by GET /debug/info: the data
+// needed to tell why a long-running daemon stopped responding, e.g. leaked
+// goroutines or file descriptors, or a subscriber that stopped reading.
+type DebugInfo struct {
+	Version    string    `json:"version"`
+	PID        int       `json:"pid"`
+	StartedAt  time.Time `json:"startedAt"`
+	Goroutines int       `json:"goroutines"`
+	OpenFDs    int       `json:"openFds"` // -1 where they cannot be counted
+
+	// Watchers is how many filesystem watchers are open, of at most
+	// MaxWatchers, and Watches how many paths they watch.
+	Watchers    int `json:"watchers"`
+	MaxWatchers int `json:"maxWatchers"`
+	Watches     int `json:"watches"`
+
+	Memory   DebugMemory        `json:"memory"`
+	Checkers []CheckerDebugInfo `json:"checkers"`
+	Backlogs []ChannelBacklog   `json:"backlogs"`
+}
+
+// DebugMemory is a subset of the Go runtime's memory statistics.
+type DebugMemory struct {
+	HeapAllocBytes  uint64    `json:"heapAllocBytes"`
+	HeapInuseBytes  uint64    `json:"heapInuseBytes"`
+	HeapObjects     uint64    `json:"heapObjects"`
+	StackInuseBytes uint64    `json:"stackInuseBytes"`
+	SysBytes        uint64    `json:"sysBytes"`
+	NumGC           uint32    `json:"numGC"`
+	LastGC          time.Time `json:"lastGC,omitzero"`
+}
+
+// CheckerDebugInfo is the process tree of one checker.
+type CheckerDebugInfo struct {
+	Project   string         `json:"project,omitempty"`
+	Checker   string         `json:"checker"`
+	PID       int            `json:"pid,omitempty"` // 0 when no process is running
+	Processes []ProcessDebug `json:"processes,omitempty"`
+}
+
+// ProcessDebug is one process of a checker's tree.
+type ProcessDebug struct {
+	PID      int   `json:"pid"`
+	PPID     int   `json:"ppid"`
+	RSSBytes int64 `json:"rssBytes"`
+}
+
+// ChannelBacklog is how many items wait in one of the daemon's queues, e.g.
+// the events a /events subscriber has yet to read.
+type ChannelBacklog struct {
+	Name string `json:"name"`
+	Len  int    `json:"len"`
+	Cap  int    `json:"cap"`
+}
+
+// debugReporter is implemented by checkers that run a long-lived process and
+// fan its events out to subscribers.
+type debugReporter interface {
+	Name() string
+	processPID() int
+	subscriberBacklogs() []int
+}
+
+// processPID returns the PID of the current svelte-check process, or 0.
+func (r *Runner) processPID() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if pcmd, ok := r.cmd.(ProcessCmd); ok {
+		return pcmd.Pid()
+	}
+	return 0
+}
+
+// subscriberBacklogs returns how many events each subscriber has yet to
+// read.
+func (r *Runner) subscriberBacklogs() []int {
+	return r.events.backlogs()
+}
+
+// DebugInfo returns a snapshot of the daemon's own health.
+func (s *Server) DebugInfo() DebugInfo {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	info := DebugInfo{
+		Version:    CurrentVersion().Version,
+		PID:        os.Getpid(),
+		StartedAt:  s.startedAt,
+		Goroutines: runtime.NumGoroutine(),
+		OpenFDs:    countOpenFDs(),
+		Memory: DebugMemory{
+			HeapAllocBytes:  mem.HeapAlloc,
+			HeapInuseBytes:  mem.HeapInuse,
+			HeapObjects:     mem.HeapObjects,
+			StackInuseBytes: mem.StackInuse,
+			SysBytes:        mem.Sys,
+			NumGC:           mem.NumGC,
+		},
+		Checkers: []CheckerDebugInfo{},
+		Backlogs: []ChannelBacklog{},
+	}
+	if mem.LastGC != 0 {
+		info.Memory.LastGC = time.Unix(0, int64(mem.LastGC))
+	}
+	if s.watcher != nil {
+		ws := s.watcher.Status()
+		info.Watchers, info.MaxWatchers, info.Watches = ws.Watchers, ws.MaxWatchers, ws.Watches
+	}
+
+	projects := s.projects
+	if len(projects) == 0 {
+		projects = []Project{{Runner: s.runner}}
+	}
+	var procs []processInfo
+	for _, p := range projects {
+		checkers := []Checker{p.Runner}
+		if set, ok := p.Runner.(*CheckerSet); ok {
+			checkers = set.Checkers()
+		}
+		for _, c := range checkers {
+			d, ok := c.(debugReporter)
+			if !ok {
+				continue
+			}
+			ci := CheckerDebugInfo{Project: p.Name, Checker: d.Name(), PID: d.processPID()}
+			if ci.PID != 0 {
+				if procs == nil {
+					procs, _ = listProcesses()
+				}
+				for _, proc := range processTree(procs, ci.PID) {
+					ci.Processes = append(ci.Processes, ProcessDebug{PID: proc.pid, PPID: proc.ppid, RSSBytes: proc.rssBytes})
+				}
+			}
+			info.Checkers = append(info.Checkers, ci)
+			for _, n := range d.subscriberBacklogs() {
+				info.Backlogs = append(info.Backlogs, ChannelBacklog{Name: ci.Checker + " subscriber", Len: n, Cap: SubscriberBuffer})
+			}
+		}
+	}
+	if s.tracer != nil {
+		info.Backlogs = append(info.Backlogs, ChannelBacklog{Name: "trace export queue", Len: s.tracer.queued(), Cap: traceQueueLimit})
+	}
+	return info
+}
+
+// countOpenFDs returns how many file descriptors the daemon has open, or -1
+// where the OS does not list them.
+func countOpenFDs() int {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		if entries, err := os.ReadDir(dir); err == nil {
+			return len(entries) - 1 // less the descriptor reading the directory
+		}
+	}
+	return -1
+}
+
+func (s *Server) handleDebugInfo(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(s.DebugInfo())
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// TestServer_DebugInfo tests that GET /debug/info reports the daemon's
+// runtime figures and each checker's subscribers.
+func TestServer_DebugInfo(t *testing.T) {
+	socketPath := testSocketPath(t)
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(checkOutput("1770255832071", 0), "")))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+	if _, err := r.GetLatestEvent(context.Background()); err != nil {
+		t.Fatalf("GetLatestEvent failed: %v", err)
+	}
+	_, unsubscribe := r.Subscribe()
+	defer unsubscribe()
+	r.events.publish(DependenciesChanged{Lockfile: "bun.lock"})
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	resp, err := unixHTTPClient(socketPath).Get("http://unix/debug/info")
+	if err != nil {
+		t.Fatalf("GET /debug/info failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var info DebugInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatalf("decoding /debug/info: %v", err)
+	}
+
+	if info.PID != os.Getpid() || info.Goroutines == 0 || info.Memory.SysBytes == 0 || info.StartedAt.IsZero() {
+		t.Errorf("info = %+v, want the daemon's runtime figures", info)
+	}
+	if _, err := os.Stat("/proc/self/fd"); err == nil && info.OpenFDs <= 0 {
+		t.Errorf("OpenFDs = %d, want a count", info.OpenFDs)
+	}
+	if len(info.Checkers) != 1 || info.Checkers[0].Checker != "svelte-check" {
+		t.Errorf("Checkers = %+v, want svelte-check", info.Checkers)
+	}
+	want := ChannelBacklog{Name: "svelte-check subscriber", Len: 1, Cap: SubscriberBuffer}
+	if len(info.Backlogs) != 1 || info.Backlogs[0] != want {
+		t.Errorf("Backlogs = %+v, want %+v", info.Backlogs, want)
+	}
+}
+
+// TestProcessTree tests that a process's descendants are found, parents
+// first, and other processes left out.
+func TestProcessTree(t *testing.T) {
+	procs := []processInfo{{pid: 1}, {pid: 12, ppid: 10}, {pid: 10, ppid: 1}, {pid: 11, ppid: 10}, {pid: 13, ppid: 12}, {pid: 20, ppid: 1}}
+	var got []int
+	for _, p := range processTree(procs, 10) {
+		got = append(got, p.pid)
+	}
+	if len(got) != 4 || got[0] != 10 || got[3] != 13 {
+		t.Errorf("processTree = %v, want 10, its children, then 13", got)
+	}
+	if processTree(procs, 99) != nil {
+		t.Error("processTree of a missing process is not nil")
+	}
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// =============================================================================
+// Result Diffs
+// =============================================================================
+
+// diffLineTolerance is how far a diagnostic may move between two results and
+// still count as the same one, e.g. after lines are inserted above it.
+const diffLineTolerance = 50
+
+// diagnosticKey identifies a diagnostic independent of its position.
+type diagnosticKey struct {
+	filename string
+	code     string
+	message  string
+}
+
+func keyOf(d Diagnostic) diagnosticKey {
+	return diagnosticKey{d.Filename, fmt.Sprint(d.Code), d.Message}
+}
+
+// DiffDiagnostics compares two consecutive results' diagnostics. Diagnostics
+// with the same file, code, and message are matched, preferring the same line
+// and then the nearest line within diffLineTolerance. introduced holds the
+// unmatched diagnostics of next, resolved those of prev, each in their
+// original order.
+func DiffDiagnostics(prev, next []Diagnostic) (introduced, resolved []Diagnostic) {
+	prevMatched, nextMatched := matchDiagnostics(prev, next)
+	for j, d := range next {
+		if !nextMatched[j] {
+			introduced = append(introduced, d)
+		}
+	}
+	for i, d := range prev {
+		if !prevMatched[i] {
+			resolved = append(resolved, d)
+		}
+	}
+	return introduced, resolved
+}
+
+// matchDiagnostics pairs diagnostics of prev and next as DiffDiagnostics
+// describes, reporting which of each were matched.
+func matchDiagnostics(prev, next []Diagnostic) (prevMatched, nextMatched []bool) {
+	byKey := make(map[diagnosticKey][]int) // indices into prev
+	for i, d := range prev {
+		k := keyOf(d)
+		byKey[k] = append(byKey[k], i)
+	}
+
+	prevMatched = make([]bool, len(prev))
+	nextMatched = make([]bool, len(next))
+
+	// Unchanged positions first, so a moved duplicate cannot claim them.
+	for j, d := range next {
+		for _, i := range byKey[keyOf(d)] {
+			if !prevMatched[i] && prev[i].Start.Line == d.Start.Line {
+				prevMatched[i], nextMatched[j] = true, true
+				break
+			}
+		}
+	}
+
+	// Then the closest remaining pairs within the tolerance.
+	type pair struct{ i, j, distance int }
+	var pairs []pair
+	for j, d := range next {
+		if nextMatched[j] {
+			continue
+		}
+		for _, i := range byKey[keyOf(d)] {
+			if prevMatched[i] {
+				continue
+			}
+			distance := abs(prev[i].Start.Line - d.Start.Line)
+			if distance <= diffLineTolerance {
+				pairs = append(pairs, pair{i, j, distance})
+			}
+		}
+	}
+	slices.SortStableFunc(pairs, func(a, b pair) int { return cmp.Compare(a.distance, b.distance) })
+	for _, p := range pairs {
+		if !prevMatched[p.i] && !nextMatched[p.j] {
+			prevMatched[p.i], nextMatched[p.j] = true, true
+		}
+	}
+	return prevMatched, nextMatched
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// withDiff returns result with Introduced and Resolved set relative to prev.
+func withDiff(result, prev SvelteWatchCheckComplete) SvelteWatchCheckComplete {
+	result.Introduced, result.Resolved = DiffDiagnostics(prev.Diagnostics, result.Diagnostics)
+	return result
+}
+
+// mapDiagnostics returns a copy of result with f applied to every diagnostic,
+// including those in Introduced and Resolved.
+func mapDiagnostics(result SvelteWatchCheckComplete, f func(*Diagnostic)) SvelteWatchCheckComplete {
+	for _, diags := range []*[]Diagnostic{&result.Diagnostics, &result.Introduced, &result.Resolved} {
+		*diags = slices.Clone(*diags)
+		for i := range *diags {
+			f(&(*diags)[i])
+		}
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func diagAt(file string, line int, code any, message string) Diagnostic {
+	return Diagnostic{Type: "ERROR", Filename: file, Start: Position{Line: line}, Code: code, Message: message}
+}
+
+func TestDiffDiagnostics(t *testing.T) {
+	prev := []Diagnostic{
+		diagAt("src/a.ts", 10, 2322, "Type mismatch"),
+		diagAt("src/a.ts", 40, 2322, "Type mismatch"),
+		diagAt("src/b.ts", 5, 2304, "Cannot find name 'x'"),
+		diagAt("src/c.ts", 1, 2304, "Cannot find name 'y'"),
+	}
+	next := []Diagnostic{
+		// Three lines were inserted above both mismatches in a.ts.
+		diagAt("src/a.ts", 13, 2322, "Type mismatch"),
+		diagAt("src/a.ts", 43, 2322, "Type mismatch"),
+		diagAt("src/b.ts", 5, 2304, "Cannot find name 'z'"),
+		// Moved further than the tolerance: a different diagnostic.
+		diagAt("src/c.ts", 1+diffLineTolerance+1, 2304, "Cannot find name 'y'"),
+	}
+
+	introduced, resolved := DiffDiagnostics(prev, next)
+	if len(introduced) != 2 || introduced[0].Message != "Cannot find name 'z'" || introduced[1].Filename != "src/c.ts" {
+		t.Errorf("introduced = %+v, want b.ts 'z' and the moved c.ts diagnostic", introduced)
+	}
+	if len(resolved) != 2 || resolved[0].Message != "Cannot find name 'x'" || resolved[1].Filename != "src/c.ts" {
+		t.Errorf("resolved = %+v, want b.ts 'x' and the original c.ts diagnostic", resolved)
+	}
+}
+
+func TestDiffDiagnostics_PrefersSameLine(t *testing.T) {
+	// One of two identical diagnostics was fixed; the survivor did not move.
+	prev := []Diagnostic{
+		diagAt("src/a.ts", 10, "a11y", "Missing alt"),
+		diagAt("src/a.ts", 12, "a11y", "Missing alt"),
+	}
+	next := []Diagnostic{diagAt("src/a.ts", 12, "a11y", "Missing alt")}
+
+	introduced, resolved := DiffDiagnostics(prev, next)
+	if len(introduced) != 0 {
+		t.Errorf("introduced = %+v, want none", introduced)
+	}
+	if len(resolved) != 1 || resolved[0].Start.Line != 10 {
+		t.Errorf("resolved = %+v, want the diagnostic on line 10", resolved)
+	}
+}
+
+// TestRunner_ResultDiff tests that each result carries what changed since
+// the previous one.
+func TestRunner_ResultDiff(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		output := `1770255832071 START "/workspace"
+1770255834342 {"type":"ERROR","filename":"src/a.ts","start":{"line":3,"character":0},"end":{"line":3,"character":1},"message":"Old error","code":2322}
+1770255834342 COMPLETED 100 FILES 1 ERRORS 0 WARNINGS 1 FILES_WITH_PROBLEMS
+1770255844663 START "/workspace"
+1770255844689 {"type":"ERROR","filename":"src/b.ts","start":{"line":0,"character":0},"end":{"line":0,"character":1},"message":"New error","code":2322}
+1770255844689 COMPLETED 100 FILES 1 ERRORS 0 WARNINGS 1 FILES_WITH_PROBLEMS
+`
+		r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(output, "")))
+		_ = r.Start(context.Background())
+		defer r.Stop()
+
+		time.Sleep(10 * time.Millisecond)
+		synctest.Wait()
+
+		history := r.History()
+		if len(history) != 2 {
+			t.Fatalf("len(History()) = %d, want 2", len(history))
+		}
+		if first := history[0].Result; first.Introduced != nil || first.Resolved != nil {
+			t.Errorf("first result diff = %+v / %+v, want none", first.Introduced, first.Resolved)
+		}
+		second := history[1].Result
+		if len(second.Introduced) != 1 || second.Introduced[0].Message != "New error" {
+			t.Errorf("Introduced = %+v, want New error", second.Introduced)
+		}
+		if len(second.Resolved) != 1 || second.Resolved[0].Message != "Old error" {
+			t.Errorf("Resolved = %+v, want Old error", second.Resolved)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// Child Environment
+// =============================================================================
+
+// EnvConfig controls the environment of spawned checker processes.
+// The zero value inherits the daemon's environment unchanged.
+type EnvConfig struct {
+	// Set adds or overrides variables, e.g. NODE_OPTIONS=--max-old-space-size=8192.
+	// Values may reference the daemon's environment as $NAME or ${NAME}, so
+	// "PATH": "/opt/node/bin:$PATH" prepends to the inherited PATH.
+	Set map[string]string
+
+	// Inherit, when non-empty, lists the only daemon variables passed through.
+	// Entries are names or glob patterns such as "npm_config_*".
+	Inherit []string
+
+	// Deny lists daemon variables never passed through, as names or patterns.
+	// Deny wins over Inherit; variables in Set are always passed.
+	Deny []string
+}
+
+// IsZero reports whether the config leaves the environment untouched.
+func (e EnvConfig) IsZero() bool {
+	return len(e.Set) == 0 && len(e.Inherit) == 0 && len(e.Deny) == 0
+}
+
+// Validate checks that every variable name and pattern is well formed.
+func (e EnvConfig) Validate() error {
+	for name := range e.Set {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+	}
+	for _, pattern := range slices.Concat(e.Inherit, e.Deny) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid environment pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Environ builds the child environment from base, the daemon's environment in
+// os.Environ form. Filtered base entries keep their order; Set entries follow
+// in sorted order.
+func (e EnvConfig) Environ(base []string) []string {
+	lookup := make(map[string]string, len(base))
+	for _, kv := range base {
+		name, value, _ := strings.Cut(kv, "=")
+		lookup[name] = value
+	}
+
+	var env []string
+	for _, kv := range base {
+		name, _, _ := strings.Cut(kv, "=")
+		if _, overridden := e.Set[name]; overridden {
+			continue
+		}
+		if len(e.Inherit) > 0 && !matchesEnvName(e.Inherit, name) {
+			continue
+		}
+		if matchesEnvName(e.Deny, name) {
+			continue
+		}
+		env = append(env, kv)
+	}
+
+	names := make([]string, 0, len(e.Set))
+	for name := range e.Set {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		value := os.Expand(e.Set[name], func(v string) string { return lookup[v] })
+		env = append(env, name+"="+value)
+	}
+	return env
+}
+
+// apply sets the child environment on cmd. A zero config leaves cmd to
+// inherit the daemon's environment.
+func (e EnvConfig) apply(cmd kexec.Cmd) {
+	if e.IsZero() {
+		return
+	}
+	cmd.SetEnv(e.Environ(os.Environ()))
+}
+
+// matchesEnvName reports whether name equals or matches any of the patterns.
+func matchesEnvName(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseEnvAssignments parses KEY=VALUE strings, as given to --env, into a map.
+func ParseEnvAssignments(assignments []string) (map[string]string, error) {
+	env := make(map[string]string, len(assignments))
+	for _, a := range assignments {
+		name, value, ok := strings.Cut(a, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid environment assignment %q (want KEY=VALUE)", a)
+		}
+		env[name] = value
+	}
+	return env, nil
+}
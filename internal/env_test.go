@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestEnvConfig_Environ tests overriding, allow/deny filtering, and expansion
+// of inherited values.
+func TestEnvConfig_Environ(t *testing.T) {
+	base := []string{"PATH=/usr/bin", "HOME=/home/me", "AWS_SECRET=x", "npm_config_cache=/c", "TSS_LOG=old"}
+
+	tests := []struct {
+		name string
+		env  EnvConfig
+		want []string
+	}{
+		{
+			name: "zero config keeps everything",
+			want: base,
+		},
+		{
+			name: "set overrides and expands",
+			env:  EnvConfig{Set: map[string]string{"PATH": "/opt/node/bin:$PATH", "TSS_LOG": "-level verbose", "NODE_OPTIONS": "--max-old-space-size=8192"}},
+			want: []string{"HOME=/home/me", "AWS_SECRET=x", "npm_config_cache=/c",
+				"NODE_OPTIONS=--max-old-space-size=8192", "PATH=/opt/node/bin:/usr/bin", "TSS_LOG=-level verbose"},
+		},
+		{
+			name: "deny removes matches",
+			env:  EnvConfig{Deny: []string{"AWS_*", "HOME"}},
+			want: []string{"PATH=/usr/bin", "npm_config_cache=/c", "TSS_LOG=old"},
+		},
+		{
+			name: "inherit limits to matches and set is always passed",
+			env:  EnvConfig{Inherit: []string{"PATH", "npm_config_*"}, Set: map[string]string{"HOME": "${HOME}/sandbox"}},
+			want: []string{"PATH=/usr/bin", "npm_config_cache=/c", "HOME=/home/me/sandbox"},
+		},
+		{
+			name: "deny wins over inherit",
+			env:  EnvConfig{Inherit: []string{"*"}, Deny: []string{"AWS_SECRET"}},
+			want: []string{"PATH=/usr/bin", "HOME=/home/me", "npm_config_cache=/c", "TSS_LOG=old"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.env.Environ(base); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Environ() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvConfig_Validate(t *testing.T) {
+	if err := (EnvConfig{Set: map[string]string{"A": "1"}, Deny: []string{"AWS_*"}}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (EnvConfig{Set: map[string]string{"A=B": "1"}}).Validate(); err == nil {
+		t.Error("expected error for name containing '='")
+	}
+	if err := (EnvConfig{Inherit: []string{"[a-"}}).Validate(); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
+
+func TestParseEnvAssignments(t *testing.T) {
+	got, err := ParseEnvAssignments([]string{"NODE_OPTIONS=--max-old-space-size=8192", "EMPTY="})
+	if err != nil {
+		t.Fatalf("ParseEnvAssignments failed: %v", err)
+	}
+	want := map[string]string{"NODE_OPTIONS": "--max-old-space-size=8192", "EMPTY": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseEnvAssignments() = %q, want %q", got, want)
+	}
+	if _, err := ParseEnvAssignments([]string{"NOEQUALS"}); err == nil {
+		t.Error("expected error for missing '='")
+	}
+}
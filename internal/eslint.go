@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// ESLint
+// =============================================================================
+
+// eslintFileResult is one entry of `eslint --format json` output.
+type eslintFileResult struct {
+	FilePath string          `json:"filePath"`
+	Messages []eslintMessage `json:"messages"`
+}
+
+type eslintMessage struct {
+	RuleID    *string `json:"ruleId"`
+	Severity  int     `json:"severity"` // 1 = warning, 2 = error
+	Message   string  `json:"message"`
+	Line      int     `json:"line"`
+	Column    int     `json:"column"`
+	EndLine   int     `json:"endLine"`
+	EndColumn int     `json:"endColumn"`
+}
+
+// NewESLintChecker returns a OneShotChecker that runs
+// `eslint --format json .` in the workspace through the package manager.
+func NewESLintChecker(config RunnerConfig, executor kexec.Interface) *OneShotChecker {
+	config = config.withDefaults()
+	c := &OneShotChecker{
+		name:          string(CheckerESLint),
+		workspacePath: config.WorkspacePath,
+		pm:            config.PackageManager,
+		env:           config.Env,
+		executor:      executor,
+		command: func() (string, []string) {
+			return config.PackageManager.Command("eslint", "--format", "json", ".")
+		},
+		parse:  ParseESLintOutput,
+		audit:  config.Audit,
+		state:  RunnerStateStopped,
+		store:  resultStore{path: config.StateFile, workspace: config.WorkspacePath},
+		latest: newValueSignal[SvelteWatchCheckComplete](),
+	}
+	if result, ok := c.store.load(); ok {
+		c.last = &result
+	}
+	return c
+}
+
+// ParseESLintOutput converts `eslint --format json` output into a check
+// result. File paths are made relative to workspacePath and positions 0-based.
+func ParseESLintOutput(output []byte, workspacePath string) (SvelteWatchCheckComplete, error) {
+	var files []eslintFileResult
+	if err := json.Unmarshal(output, &files); err != nil {
+		return SvelteWatchCheckComplete{}, fmt.Errorf("parse eslint output: %w", err)
+	}
+
+	now := time.Now().UnixMilli()
+	result := SvelteWatchCheckComplete{Timestamp: now, FileCount: len(files)}
+	for _, f := range files {
+		if len(f.Messages) == 0 {
+			continue
+		}
+		result.FilesWithProblems++
+
+		filename := f.FilePath
+		if rel, err := filepath.Rel(workspacePath, f.FilePath); err == nil && filepath.IsLocal(rel) {
+			filename = filepath.ToSlash(rel)
+		}
+
+		for _, m := range f.Messages {
+			d := Diagnostic{
+				Timestamp: now,
+				Type:      "WARNING",
+				Filename:  filename,
+				Start:     Position{Line: max(m.Line-1, 0), Character: max(m.Column-1, 0)},
+				End:       Position{Line: max(m.EndLine-1, 0), Character: max(m.EndColumn-1, 0)},
+				Message:   m.Message,
+				Source:    string(CheckerESLint),
+			}
+			if m.EndLine == 0 {
+				d.End = d.Start
+			}
+			if m.RuleID != nil {
+				d.Code = *m.RuleID
+			}
+			if m.Severity == 2 {
+				d.Type = "ERROR"
+				result.ErrorCount++
+			} else {
+				result.WarningCount++
+			}
+			result.Diagnostics = append(result.Diagnostics, d)
+		}
+	}
+	return result, nil
+}
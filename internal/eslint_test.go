@@ -0,0 +1,49 @@
+package internal
+
+import "testing"
+
+func TestParseESLintOutput(t *testing.T) {
+	output := `[
+  {"filePath": "/repo/src/lib/a.ts", "messages": [
+    {"ruleId": "no-unused-vars", "severity": 2, "message": "'x' is defined but never used.", "line": 3, "column": 7, "endLine": 3, "endColumn": 8},
+    {"ruleId": null, "severity": 1, "message": "Unused eslint-disable directive.", "line": 1, "column": 1}
+  ], "errorCount": 1, "warningCount": 1},
+  {"filePath": "/repo/src/routes/+page.svelte", "messages": [], "errorCount": 0, "warningCount": 0},
+  {"filePath": "/elsewhere/b.ts", "messages": [
+    {"ruleId": "eqeqeq", "severity": 1, "message": "Expected '===' and instead saw '=='.", "line": 5, "column": 9, "endLine": 5, "endColumn": 11}
+  ], "errorCount": 0, "warningCount": 1}
+]`
+
+	result, err := ParseESLintOutput([]byte(output), "/repo")
+	if err != nil {
+		t.Fatalf("ParseESLintOutput failed: %v", err)
+	}
+
+	if result.FileCount != 3 || result.ErrorCount != 1 || result.WarningCount != 2 || result.FilesWithProblems != 2 {
+		t.Errorf("counts = %d files, %d errors, %d warnings, %d with problems; want 3, 1, 2, 2",
+			result.FileCount, result.ErrorCount, result.WarningCount, result.FilesWithProblems)
+	}
+	if len(result.Diagnostics) != 3 {
+		t.Fatalf("got %d diagnostics, want 3", len(result.Diagnostics))
+	}
+
+	d := result.Diagnostics[0]
+	if d.Filename != "src/lib/a.ts" || d.Type != "ERROR" || d.Code != "no-unused-vars" || d.Source != "eslint" {
+		t.Errorf("Diagnostics[0] = %+v", d)
+	}
+	if d.Start != (Position{Line: 2, Character: 6}) || d.End != (Position{Line: 2, Character: 7}) {
+		t.Errorf("Diagnostics[0] range = %+v-%+v, want 0-based positions", d.Start, d.End)
+	}
+	if d := result.Diagnostics[1]; d.Code != nil || d.End != d.Start {
+		t.Errorf("Diagnostics[1] = %+v, want nil code and End == Start", d)
+	}
+	if d := result.Diagnostics[2]; d.Filename != "/elsewhere/b.ts" {
+		t.Errorf("file outside the workspace = %q, want absolute path kept", d.Filename)
+	}
+}
+
+func TestParseESLintOutput_Invalid(t *testing.T) {
+	if _, err := ParseESLintOutput([]byte("Oops! Something went wrong!"), "/repo"); err == nil {
+		t.Error("expected error for non-JSON output")
+	}
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tylergannon/svelte-check-server/pkg/types"
+)
+
+// =============================================================================
+// Conditional Checks
+// =============================================================================
+
+// resultETag returns the entity tag of a result served as JSON. AgeSeconds
+// is left out, as it changes on every request while the result does not;
+// clients recompute it from CheckedAt.
+func resultETag(result SvelteWatchCheckComplete) string {
+	result.AgeSeconds = 0
+	data, err := json.Marshal(result)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header lists etag.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// ageSeconds is how long ago t was, rounded to milliseconds.
+func ageSeconds(t time.Time) float64 {
+	return math.Round(time.Since(t).Seconds()*1000) / 1000
+}
+
+// checkCache holds the last JSON result a Client received for each /check
+// query, with its ETag, so that an unchanged result is not transferred again.
+type checkCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedCheck
+}
+
+type cachedCheck struct {
+	etag   string
+	result SvelteWatchCheckComplete
+}
+
+func (c *checkCache) get(query string) (cachedCheck, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[query]
+	return entry, ok
+}
+
+func (c *checkCache) put(query string, entry cachedCheck) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]cachedCheck)
+	}
+	c.entries[query] = entry
+}
+
+// TypedCheckResponse is a /check result decoded from JSON, as judged by the
+// daemon's CheckPolicy.
+type TypedCheckResponse struct {
+	Result   SvelteWatchCheckComplete
+	Verdict  Severity // the failing severity; empty if the check passed
+	ExitCode int      // the exit code the check command uses; 0 if the check passed
+}
+
+// CheckTyped is like CheckWith but requests JSON and decodes it, so callers
+// get counts, diagnostics, and timestamps without parsing output.
+// opts.Format is ignored.
+//
+// The client remembers the last result of each query and sends its ETag,
+// so an unchanged result is answered with 304 Not Modified and returned from
+// memory, with AgeSeconds brought up to date. Callers must not modify the
+// slices of a returned result.
+func (c *Client) CheckTyped(ctx context.Context, opts CheckOptions) (TypedCheckResponse, error) {
+	opts.Format = "json"
+	query := checkQuery(opts).Encode()
+	cached, ok := c.cache.get(query)
+
+	reply, err := c.check(ctx, opts, cached.etag)
+	if err != nil {
+		return TypedCheckResponse{}, err
+	}
+	if reply.notModified {
+		if !ok {
+			return TypedCheckResponse{}, fmt.Errorf("server returned 304 for an uncached result")
+		}
+		result := cached.result
+		if !result.CheckedAt.IsZero() {
+			result.AgeSeconds = ageSeconds(result.CheckedAt)
+		}
+		return TypedCheckResponse{Result: result, Verdict: reply.Verdict, ExitCode: reply.ExitCode}, nil
+	}
+
+	result, err := types.Decode([]byte(reply.Output))
+	if err != nil {
+		return TypedCheckResponse{}, fmt.Errorf("parsing check result: %w", err)
+	}
+	if reply.etag != "" {
+		c.cache.put(query, cachedCheck{etag: reply.etag, result: result})
+	}
+	return TypedCheckResponse{Result: result, Verdict: reply.Verdict, ExitCode: reply.ExitCode}, nil
+}
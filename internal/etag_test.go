@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// statusRecorder records the status codes of the responses it carries.
+type statusRecorder struct {
+	next http.RoundTripper
+
+	mu    sync.Mutex
+	codes []int
+}
+
+func (s *statusRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := s.next.RoundTrip(req)
+	if err == nil {
+		s.mu.Lock()
+		s.codes = append(s.codes, resp.StatusCode)
+		s.mu.Unlock()
+	}
+	return resp, err
+}
+
+// TestClient_CheckTyped_NotModified tests that an unchanged result is
+// answered with 304 and returned from the client's cache, with its verdict.
+func TestClient_CheckTyped_NotModified(t *testing.T) {
+	socketPath := testSocketPath(t)
+
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(checkOutput("1770255832071", 2), "")))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	httpClient := unixHTTPClient(socketPath)
+	recorder := &statusRecorder{next: httpClient.Transport}
+	httpClient.Transport = recorder
+	c := &Client{socketPath: socketPath, httpClient: httpClient}
+
+	ctx := context.Background()
+	first, err := c.CheckTyped(ctx, CheckOptions{})
+	if err != nil {
+		t.Fatalf("CheckTyped failed: %v", err)
+	}
+	second, err := c.CheckTyped(ctx, CheckOptions{})
+	if err != nil {
+		t.Fatalf("second CheckTyped failed: %v", err)
+	}
+
+	if len(recorder.codes) != 2 || recorder.codes[1] != http.StatusNotModified {
+		t.Errorf("status codes = %v, want the second to be 304", recorder.codes)
+	}
+	if second.Result.ErrorCount != 2 || second.Result.Timestamp != first.Result.Timestamp {
+		t.Errorf("cached result = %+v, want the first result", second.Result)
+	}
+	if second.Verdict != SeverityError || second.ExitCode != 1 {
+		t.Errorf("cached verdict = %q, exit code %d; want error, 1", second.Verdict, second.ExitCode)
+	}
+	if second.Result.AgeSeconds < first.Result.AgeSeconds {
+		t.Errorf("AgeSeconds = %v, want at least %v", second.Result.AgeSeconds, first.Result.AgeSeconds)
+	}
+
+	// A different query is not answered from another's cache.
+	if _, err := c.CheckTyped(ctx, CheckOptions{IgnoreBaseline: true}); err != nil {
+		t.Fatalf("CheckTyped(IgnoreBaseline) failed: %v", err)
+	}
+	if recorder.codes[2] == http.StatusNotModified {
+		t.Error("a new query was answered with 304")
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`"abc"`, true},
+		{`"x", W/"abc"`, true},
+		{`*`, true},
+		{`"abcd"`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `"abc"`); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
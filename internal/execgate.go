@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// Gated Commands
+// =============================================================================
+
+// execGate decides what the exec command does, leaving exiting to its caller
+// so that the decision can be tested.
+type execGate struct {
+	lookPath func(file string) (string, error)
+	check    func() TypedCheckResponse
+
+	// run runs the command found at path. exec replaces the process with it,
+	// so run only returns if that fails.
+	run func(path string, command []string) error
+
+	out    *cliOutput
+	linker *Linker
+}
+
+// exitCode runs command if the check passes and returns exec's exit status:
+// 127 if command is not found, which is looked up before the check so that a
+// typo is not reported minutes later; the check's exit code, after printing
+// its result, if it fails; otherwise the command's own, or 126 if it could
+// not be run.
+func (g *execGate) exitCode(command []string) int {
+	path, err := g.lookPath(command[0])
+	if err != nil {
+		g.out.report(errCodeFailed, "%v", err)
+		return 127
+	}
+
+	resp := g.check()
+	if resp.Verdict != "" {
+		g.out.result(resp.Result, func() {
+			fmt.Fprint(g.out.w, FormatHumanLinked(resp.Result, g.linker))
+		})
+		return cmp.Or(resp.ExitCode, 1)
+	}
+
+	err = g.run(path, command)
+	var exitErr kexec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return exitErr.ExitStatus()
+	}
+	g.out.report(errCodeFailed, "Failed to run %s: %v", command[0], err)
+	return 126
+}
@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+
+	kexec "k8s.io/utils/exec"
+)
+
+func TestExecGate(t *testing.T) {
+	failed := TypedCheckResponse{
+		Result: SvelteWatchCheckComplete{
+			ErrorCount:  1,
+			Diagnostics: []Diagnostic{{Type: "ERROR", Filename: "src/App.svelte", Message: "oops"}},
+		},
+		Verdict:  SeverityError,
+		ExitCode: 2,
+	}
+	for _, tc := range []struct {
+		name     string
+		command  string
+		resp     TypedCheckResponse
+		runErr   error
+		want     int
+		checked  bool
+		ran      bool
+		wantText string
+	}{
+		{name: "pass", command: "vite", runErr: kexec.CodeExitError{Err: errors.New("exit status 7"), Code: 7}, want: 7, checked: true, ran: true},
+		{name: "failure", command: "vite", resp: failed, want: 2, checked: true, wantText: "src/App.svelte:1:1 - ERROR: oops"},
+		{name: "missing command", command: "no-such-command", want: 127},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			executor := NewFakeExecutor("", "")
+			executor.setCmd(&FakeCmd{waitErr: tc.runErr})
+			var stdout bytes.Buffer
+			checked := false
+			gate := &execGate{
+				lookPath: func(file string) (string, error) {
+					if file != "vite" {
+						return "", exec.ErrNotFound
+					}
+					return "/bin/" + file, nil
+				},
+				check: func() TypedCheckResponse {
+					checked = true
+					return tc.resp
+				},
+				run: func(path string, command []string) error {
+					return executor.Command(path, command[1:]...).Run()
+				},
+				out: &cliOutput{w: &stdout},
+			}
+
+			if got := gate.exitCode([]string{tc.command, "build"}); got != tc.want {
+				t.Errorf("exitCode() = %d, want %d", got, tc.want)
+			}
+			if checked != tc.checked {
+				t.Errorf("checked = %v, want %v", checked, tc.checked)
+			}
+			if ran := executor.currentCmd().isStarted(); ran != tc.ran {
+				t.Errorf("ran the command = %v, want %v", ran, tc.ran)
+			} else if ran && (executor.name != "/bin/vite" || !slices.Equal(executor.args, []string{"build"})) {
+				t.Errorf("ran %s %v, want /bin/vite build", executor.name, executor.args)
+			}
+			if !strings.Contains(stdout.String(), tc.wantText) {
+				t.Errorf("output = %q, want %q", stdout.String(), tc.wantText)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// Exec Hooks
+// =============================================================================
+
+// Events an ExecHooksConfig runs commands on, named as its JSON keys.
+const (
+	HookCheckComplete    = "onCheckComplete"
+	HookErrorsIntroduced = "onErrorsIntroduced"
+	HookClean            = "onClean"
+	HookSyncFailed       = "onSyncFailed"
+)
+
+// hookTimeout bounds each hook command, which is killed if still running.
+const hookTimeout = time.Minute
+
+// ExecHooksConfig is the "hooks" object of the config file: shell commands
+// run in the workspace on events, for integrations the daemon has no
+// notifier for, e.g. {"onErrorsIntroduced": ["notify-send 'Types broke'"]}.
+type ExecHooksConfig struct {
+	// OnCheckComplete runs after every check cycle.
+	OnCheckComplete []string `json:"onCheckComplete,omitempty"`
+
+	// OnErrorsIntroduced runs when a result has more errors than the one
+	// before it.
+	OnErrorsIntroduced []string `json:"onErrorsIntroduced,omitempty"`
+
+	// OnClean runs when a result has no errors and the one before it had.
+	OnClean []string `json:"onClean,omitempty"`
+
+	// OnSyncFailed runs when svelte-kit sync fails.
+	OnSyncFailed []string `json:"onSyncFailed,omitempty"`
+}
+
+// IsZero reports whether c configures no commands.
+func (c ExecHooksConfig) IsZero() bool {
+	return len(c.OnCheckComplete)+len(c.OnErrorsIntroduced)+len(c.OnClean)+len(c.OnSyncFailed) == 0
+}
+
+// ExecHooks runs the commands of an ExecHooksConfig. Each receives the
+// event's JSON on stdin, a result as /check serves it or a SyncResult, and
+// its counts in SVELTE_CHECK_* environment variables. A failing command is
+// logged and does not stop the others. Methods on a nil *ExecHooks do
+// nothing.
+type ExecHooks struct {
+	config    ExecHooksConfig
+	workspace string
+	env       EnvConfig
+	executor  kexec.Interface
+
+	mu     sync.Mutex
+	seen   bool // a result has been observed; the first has no predecessor
+	errors int  // of the previous result
+}
+
+// NewExecHooks returns the ExecHooks of config, whose commands run in
+// workspace with the checkers' env, or nil if config has none.
+func NewExecHooks(config ExecHooksConfig, workspace string, env EnvConfig, executor kexec.Interface) *ExecHooks {
+	if config.IsZero() {
+		return nil
+	}
+	return &ExecHooks{config: config, workspace: workspace, env: env, executor: executor}
+}
+
+// Observe runs the hooks of a new result: OnCheckComplete, then
+// OnErrorsIntroduced if it has more errors than the previous result, or
+// OnClean if it fixed the last of them.
+func (h *ExecHooks) Observe(ctx context.Context, result SvelteWatchCheckComplete) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	seen, previous := h.seen, h.errors
+	h.seen, h.errors = true, result.ErrorCount
+	h.mu.Unlock()
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	vars := []string{
+		"SVELTE_CHECK_ERRORS=" + strconv.Itoa(result.ErrorCount),
+		"SVELTE_CHECK_WARNINGS=" + strconv.Itoa(result.WarningCount),
+		"SVELTE_CHECK_FILES=" + strconv.Itoa(result.FileCount),
+		"SVELTE_CHECK_FILES_WITH_PROBLEMS=" + strconv.Itoa(result.FilesWithProblems),
+	}
+	if seen {
+		vars = append(vars, "SVELTE_CHECK_PREVIOUS_ERRORS="+strconv.Itoa(previous))
+	}
+
+	h.run(ctx, HookCheckComplete, h.config.OnCheckComplete, data, vars)
+	switch {
+	case !seen:
+	case result.ErrorCount > previous:
+		h.run(ctx, HookErrorsIntroduced, h.config.OnErrorsIntroduced, data, vars)
+	case result.ErrorCount == 0 && previous > 0:
+		h.run(ctx, HookClean, h.config.OnClean, data, vars)
+	}
+}
+
+// SyncFailed runs the OnSyncFailed hooks for a failed svelte-kit sync.
+func (h *ExecHooks) SyncFailed(ctx context.Context, result SyncResult) {
+	if h == nil || len(h.config.OnSyncFailed) == 0 {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	h.run(ctx, HookSyncFailed, h.config.OnSyncFailed, data, []string{"SVELTE_CHECK_SYNC_DIR=" + result.Dir})
+}
+
+// run runs commands for event one after another, each with stdin and vars.
+func (h *ExecHooks) run(ctx context.Context, event string, commands []string, stdin []byte, vars []string) {
+	log := logger("hooks")
+	env := append(h.env.Environ(os.Environ()),
+		"SVELTE_CHECK_EVENT="+event,
+		"SVELTE_CHECK_WORKSPACE="+h.workspace)
+	env = append(env, vars...)
+	for _, command := range commands {
+		ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+		cmd := h.executor.CommandContext(ctx, "sh", "-c", command)
+		cmd.SetDir(h.workspace)
+		cmd.SetEnv(env)
+		cmd.SetStdin(bytes.NewReader(stdin))
+		out, err := cmd.CombinedOutput()
+		cancel()
+		if err != nil {
+			log.Warn("hook failed", "event", event, "command", command, "error", err, "output", strings.TrimSpace(string(out)))
+			continue
+		}
+		log.Debug("hook ran", "event", event, "command", command)
+	}
+}
+
+// SetExecHooks runs h on each new result, as /check would serve it. Call it
+// before Start.
+func (s *Server) SetExecHooks(h *ExecHooks) {
+	s.hooks = h
+}
+
+// SetExecHooks runs h's OnSyncFailed hooks when a sync fails.
+func (t *SyncTracker) SetExecHooks(h *ExecHooks) {
+	t.hooks = h
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestExecHooks tests which hooks results run, and what the commands
+// receive.
+func TestExecHooks(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "events")
+	record := `echo "$SVELTE_CHECK_EVENT $SVELTE_CHECK_ERRORS ${SVELTE_CHECK_PREVIOUS_ERRORS:--} $SVELTE_CHECK_SYNC_DIR" >> ` + log
+	h := NewExecHooks(ExecHooksConfig{
+		OnCheckComplete:    []string{record, "cat > last.json"},
+		OnErrorsIntroduced: []string{"exit 1", record},
+		OnClean:            []string{record},
+		OnSyncFailed:       []string{record},
+	}, dir, EnvConfig{}, NewExecutor())
+
+	ctx := context.Background()
+	for _, errors := range []int{1, 3, 2, 0} {
+		h.Observe(ctx, SvelteWatchCheckComplete{Timestamp: int64(errors), ErrorCount: errors})
+	}
+	h.SyncFailed(ctx, SyncResult{Dir: "apps/web", Error: "exit status 1"})
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("reading hook log: %v", err)
+	}
+	want := strings.Join([]string{
+		"onCheckComplete 1 - ",
+		"onCheckComplete 3 1 ",
+		"onErrorsIntroduced 3 1 ", // after the failing command
+		"onCheckComplete 2 3 ",
+		"onCheckComplete 0 2 ",
+		"onClean 0 2 ",
+		"onSyncFailed  - apps/web",
+	}, "\n") + "\n"
+	if string(data) != want {
+		t.Errorf("hooks ran:\n%s\nwant:\n%s", data, want)
+	}
+
+	var last SvelteWatchCheckComplete
+	if data, err := os.ReadFile(filepath.Join(dir, "last.json")); err != nil || json.Unmarshal(data, &last) != nil || last.Timestamp != 0 {
+		t.Errorf("stdin of the last hook = %s, %v; want the last result", data, err)
+	}
+
+	if NewExecHooks(ExecHooksConfig{}, dir, EnvConfig{}, NewExecutor()) != nil {
+		t.Error("NewExecHooks without commands is not nil")
+	}
+	var none *ExecHooks
+	none.Observe(ctx, SvelteWatchCheckComplete{})
+}
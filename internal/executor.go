@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// Executor
+// =============================================================================
+
+// ProcessCmd is implemented by commands that expose their running process.
+// The Runner uses it when available for resource monitoring; commands from
+// other kexec.Interface implementations (such as test fakes) may omit it.
+type ProcessCmd interface {
+	kexec.Cmd
+	Pid() int // 0 before Start
+}
+
+// ProcessGroupCmd is implemented by commands that can run in a process group of
+// their own, so that Stop reaches every process they spawn (e.g. the node
+// process behind `bun run svelte-check`).
+type ProcessGroupCmd interface {
+	kexec.Cmd
+	// SetProcessGroupCreation starts the process as the leader of a new group.
+	// Must be called before Start.
+	SetProcessGroupCreation(create bool)
+	// SetStopPolicy sets the signals and grace periods Stop escalates through.
+	SetStopPolicy(policy StopPolicy)
+}
+
+// StopPolicy governs how Stop escalates: SIGINT first, so tsserver can shut
+// down cleanly, then SIGTERM after InterruptGrace, then SIGKILL after
+// TerminateGrace.
+type StopPolicy struct {
+	InterruptGrace time.Duration // wait after SIGINT; 0 skips SIGINT
+	TerminateGrace time.Duration // wait after SIGTERM before SIGKILL
+}
+
+// DefaultStopPolicy is used when no StopPolicy is configured.
+var DefaultStopPolicy = StopPolicy{
+	InterruptGrace: 3 * time.Second,
+	TerminateGrace: 10 * time.Second,
+}
+
+// Validate reports grace periods that cannot be honored.
+func (p StopPolicy) Validate() error {
+	if p.InterruptGrace < 0 {
+		return fmt.Errorf("interrupt grace period must not be negative, got %v", p.InterruptGrace)
+	}
+	if p.TerminateGrace <= 0 {
+		return fmt.Errorf("terminate grace period must be positive, got %v", p.TerminateGrace)
+	}
+	return nil
+}
+
+// signalNames names the signals Stop sends, for logging.
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGINT:  "SIGINT",
+	syscall.SIGTERM: "SIGTERM",
+	syscall.SIGKILL: "SIGKILL",
+}
+
+// stopStep is one signal of a StopPolicy and how long to wait after it.
+type stopStep struct {
+	signal syscall.Signal
+	grace  time.Duration
+}
+
+// steps returns the signals to send in order. SIGKILL always follows the last.
+func (p StopPolicy) steps() []stopStep {
+	var steps []stopStep
+	if p.InterruptGrace > 0 {
+		steps = append(steps, stopStep{syscall.SIGINT, p.InterruptGrace})
+	}
+	return append(steps, stopStep{syscall.SIGTERM, p.TerminateGrace})
+}
+
+// NewExecutor returns a kexec.Interface backed by os/exec, equivalent to
+// kexec.New except that its commands implement ProcessCmd and ProcessGroupCmd.
+func NewExecutor() kexec.Interface {
+	return processExecutor{}
+}
+
+type processExecutor struct{}
+
+func (processExecutor) Command(cmd string, args ...string) kexec.Cmd {
+	return newProcessCmd(exec.Command(cmd, args...))
+}
+
+func (processExecutor) CommandContext(ctx context.Context, cmd string, args ...string) kexec.Cmd {
+	return newProcessCmd(exec.CommandContext(ctx, cmd, args...))
+}
+
+func (processExecutor) LookPath(file string) (string, error) {
+	path, err := exec.LookPath(file)
+	return path, wrapExecError(err)
+}
+
+// processCmd implements ProcessCmd and ProcessGroupCmd around an exec.Cmd.
+type processCmd struct {
+	cmd          *exec.Cmd
+	processGroup bool
+	stopPolicy   StopPolicy
+
+	waitOnce sync.Once
+	waited   chan struct{} // closed once Wait has returned
+}
+
+var (
+	_ ProcessCmd      = &processCmd{}
+	_ ProcessGroupCmd = &processCmd{}
+)
+
+func newProcessCmd(cmd *exec.Cmd) *processCmd {
+	return &processCmd{cmd: cmd, stopPolicy: DefaultStopPolicy, waited: make(chan struct{})}
+}
+
+func (c *processCmd) SetDir(dir string)       { c.cmd.Dir = dir }
+func (c *processCmd) SetStdin(in io.Reader)   { c.cmd.Stdin = in }
+func (c *processCmd) SetStdout(out io.Writer) { c.cmd.Stdout = out }
+func (c *processCmd) SetStderr(out io.Writer) { c.cmd.Stderr = out }
+func (c *processCmd) SetEnv(env []string)     { c.cmd.Env = env }
+
+func (c *processCmd) StdoutPipe() (io.ReadCloser, error) {
+	r, err := c.cmd.StdoutPipe()
+	return r, wrapExecError(err)
+}
+
+func (c *processCmd) StderrPipe() (io.ReadCloser, error) {
+	r, err := c.cmd.StderrPipe()
+	return r, wrapExecError(err)
+}
+
+func (c *processCmd) Start() error {
+	return wrapExecError(c.cmd.Start())
+}
+
+func (c *processCmd) Wait() error {
+	defer c.waitOnce.Do(func() { close(c.waited) })
+	return wrapExecError(c.cmd.Wait())
+}
+
+func (c *processCmd) Run() error {
+	return wrapExecError(c.cmd.Run())
+}
+
+func (c *processCmd) CombinedOutput() ([]byte, error) {
+	out, err := c.cmd.CombinedOutput()
+	return out, wrapExecError(err)
+}
+
+func (c *processCmd) Output() ([]byte, error) {
+	out, err := c.cmd.Output()
+	return out, wrapExecError(err)
+}
+
+// Pid returns the process ID, or 0 if the process has not started.
+func (c *processCmd) Pid() int {
+	if c.cmd.Process == nil {
+		return 0
+	}
+	return c.cmd.Process.Pid
+}
+
+// SetProcessGroupCreation starts the process in a new process group whose ID
+// is its PID. Stop and context cancellation then signal the whole group.
+func (c *processCmd) SetProcessGroupCreation(create bool) {
+	c.processGroup = create
+	if c.cmd.SysProcAttr == nil {
+		c.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	c.cmd.SysProcAttr.Setpgid = create
+
+	if create && c.cmd.Cancel != nil {
+		// CommandContext kills only the leader by default.
+		c.cmd.Cancel = func() error { return c.signal(syscall.SIGKILL) }
+	}
+}
+
+// SetStopPolicy sets the escalation used by Stop.
+func (c *processCmd) SetStopPolicy(policy StopPolicy) {
+	c.stopPolicy = policy
+}
+
+// signal sends sig to the process, or to its whole group if it leads one.
+func (c *processCmd) signal(sig syscall.Signal) error {
+	if c.processGroup {
+		return syscall.Kill(-c.cmd.Process.Pid, sig)
+	}
+	return c.cmd.Process.Signal(sig)
+}
+
+// groupAlive reports whether any process remains in the command's group.
+func (c *processCmd) groupAlive() bool {
+	return c.processGroup && syscall.Kill(-c.cmd.Process.Pid, 0) == nil
+}
+
+// Stop signals the process according to its StopPolicy, escalating to the
+// next signal whenever it has not exited within the grace period, and logs
+// which signal was needed. For a process group, exiting means every process
+// in the group has exited, so children that outlive the leader are escalated
+// too. Unlike kexec's Stop, it never inspects ProcessState concurrently with
+// Wait.
+func (c *processCmd) Stop() {
+	if c.cmd.Process == nil {
+		return
+	}
+
+	name := fmt.Sprintf("%s (pid %d)", filepath.Base(c.cmd.Path), c.cmd.Process.Pid)
+	steps := c.stopPolicy.steps()
+	_ = c.signal(steps[0].signal)
+
+	go func() {
+		for i, step := range steps {
+			if c.waitExit(step.grace) {
+				logger("executor").Info("process stopped", "process", name, "signal", signalNames[step.signal])
+				return
+			}
+			next := syscall.SIGKILL
+			if i+1 < len(steps) {
+				next = steps[i+1].signal
+			}
+			logger("executor").Warn("process still running, escalating", "process", name, "grace", step.grace, "signal", signalNames[step.signal], "next", signalNames[next])
+			_ = c.signal(next)
+		}
+	}()
+}
+
+// waitExit reports whether the process, and for a process group every
+// process in it, exits within timeout.
+func (c *processCmd) waitExit(timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	select {
+	case <-c.waited:
+	case <-deadline.C:
+		return false
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for c.groupAlive() {
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			return false
+		}
+	}
+	return true
+}
+
+// setProcessGroup runs cmd in its own process group when supported, so that
+// stopping it also stops any processes it spawns. A zero policy keeps the
+// command's default escalation.
+func setProcessGroup(cmd kexec.Cmd, policy StopPolicy) {
+	if g, ok := cmd.(ProcessGroupCmd); ok {
+		g.SetProcessGroupCreation(true)
+		if policy != (StopPolicy{}) {
+			g.SetStopPolicy(policy)
+		}
+	}
+}
+
+// wrapExecError converts os/exec errors to their kexec equivalents so callers
+// can type-assert kexec.ExitError as they would with kexec.New.
+func wrapExecError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var exitErr *exec.ExitError
+	var pathErr *fs.PathError
+	switch {
+	case errors.As(err, &exitErr):
+		return &kexec.ExitErrorWrapper{ExitError: exitErr}
+	case errors.Is(err, exec.ErrNotFound), errors.As(err, &pathErr):
+		return kexec.ErrExecutableNotFound
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startShell starts script with sh in a new process group and returns the
+// command and the PID the script writes to pidfile.
+func startShell(t *testing.T, script string) (*processCmd, int) {
+	t.Helper()
+	pidfile := filepath.Join(t.TempDir(), "pid")
+
+	cmd := NewExecutor().CommandContext(context.Background(), "sh", "-c", script, "sh", pidfile).(*processCmd)
+	cmd.SetProcessGroupCreation(true)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	go func() { _ = cmd.Wait() }()
+	return cmd, readPID(t, pidfile)
+}
+
+// readPID waits for a script to write a PID to pidfile.
+func readPID(t *testing.T, pidfile string) int {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		data, _ := os.ReadFile(pidfile)
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+			return pid
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("script did not write its child's PID")
+	return 0
+}
+
+// processAlive reports whether pid is running (and not a zombie).
+func processAlive(pid int) bool {
+	if data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat"); err == nil {
+		if i := strings.LastIndexByte(string(data), ')'); i >= 0 && i+2 < len(data) {
+			return data[i+2] != 'Z'
+		}
+	}
+	return syscall.Kill(pid, 0) == nil
+}
+
+// waitForExit fails the test unless pid exits within timeout.
+func waitForExit(t *testing.T, pid int, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !processAlive(pid) {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Errorf("process %d still running after %v", pid, timeout)
+}
+
+// TestProcessCmd_Stop_KillsProcessGroup tests that Stop reaches a grandchild,
+// as with the node process behind `bun run svelte-check`.
+func TestProcessCmd_Stop_KillsProcessGroup(t *testing.T) {
+	cmd, child := startShell(t, `sleep 60 & echo $! > "$1"; wait`)
+	// Background jobs of a non-interactive shell ignore SIGINT; start at SIGTERM.
+	cmd.SetStopPolicy(StopPolicy{TerminateGrace: 10 * time.Second})
+
+	cmd.Stop()
+	waitForExit(t, child, 2*time.Second)
+}
+
+// TestProcessCmd_Stop_EscalatesToKill tests that processes ignoring SIGINT
+// and SIGTERM are killed once both grace periods have passed.
+func TestProcessCmd_Stop_EscalatesToKill(t *testing.T) {
+	cmd, child := startShell(t, `trap "" INT TERM; sleep 60 & echo $! > "$1"; wait`)
+	cmd.SetStopPolicy(StopPolicy{InterruptGrace: 100 * time.Millisecond, TerminateGrace: 200 * time.Millisecond})
+
+	cmd.Stop()
+	time.Sleep(250 * time.Millisecond)
+	if !processAlive(child) {
+		t.Fatal("child exited before the grace periods despite ignoring SIGINT and SIGTERM")
+	}
+	waitForExit(t, child, 2*time.Second)
+}
+
+func TestStopPolicy_Steps(t *testing.T) {
+	steps := DefaultStopPolicy.steps()
+	if len(steps) != 2 || steps[0].signal != syscall.SIGINT || steps[1].signal != syscall.SIGTERM {
+		t.Errorf("DefaultStopPolicy.steps() = %+v, want SIGINT then SIGTERM", steps)
+	}
+
+	steps = StopPolicy{TerminateGrace: time.Second}.steps()
+	if len(steps) != 1 || steps[0].signal != syscall.SIGTERM {
+		t.Errorf("steps() without InterruptGrace = %+v, want SIGTERM only", steps)
+	}
+
+	if err := (StopPolicy{InterruptGrace: time.Second}).Validate(); err == nil {
+		t.Error("expected error for zero TerminateGrace")
+	}
+}
+
+// TestProcessCmd_Cancel_KillsProcessGroup tests that canceling the context of
+// a process-group command kills the whole group, not just the leader.
+func TestProcessCmd_Cancel_KillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pidfile := filepath.Join(t.TempDir(), "pid")
+	cmd := NewExecutor().CommandContext(ctx, "sh", "-c", `sleep 60 & echo $! > "$1"; wait`, "sh", pidfile)
+	setProcessGroup(cmd, StopPolicy{})
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	child := readPID(t, pidfile)
+
+	cancel()
+	_ = cmd.Wait()
+	waitForExit(t, child, 2*time.Second)
+}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// =============================================================================
+// Watch Exhaustion
+// =============================================================================
+
+// WatchDegradation describes directories an FSWatcher could not watch
+// because the OS ran out of watches.
+type WatchDegradation struct {
+	Reason    string `json:"reason"`
+	Limit     int    `json:"limit,omitempty"`  // fs.inotify.max_user_watches on Linux
+	Unwatched int    `json:"unwatched"`        // directories whose changes are missed
+	Polled    int    `json:"polled,omitempty"` // directories polled instead
+}
+
+// DegradationReporter is implemented by FSWatchers that can run out of OS
+// watches. Degradation returns nil while every directory is watched.
+type DegradationReporter interface {
+	Degradation() *WatchDegradation
+}
+
+// isWatchExhausted reports whether err from adding a watch means the OS ran
+// out of watches: ENOSPC from inotify when fs.inotify.max_user_watches is
+// reached, EMFILE from kqueue, which needs a file descriptor per watch.
+func isWatchExhausted(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE)
+}
+
+// inotifyWatchLimit returns fs.inotify.max_user_watches, or 0 if it cannot
+// be read, e.g. on other systems than Linux.
+func inotifyWatchLimit() int {
+	data, err := os.ReadFile("/proc/sys/fs/inotify/max_user_watches")
+	if err != nil {
+		return 0
+	}
+	limit, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return limit
+}
+
+// watchExhaustionWarning explains running out of watches and how to fix it.
+// polling says whether unwatchable directories are polled instead.
+func watchExhaustionWarning(err error, polling bool) string {
+	var sb strings.Builder
+	if limit := inotifyWatchLimit(); errors.Is(err, syscall.ENOSPC) && limit > 0 {
+		fmt.Fprintf(&sb, "Warning: ran out of inotify watches (fs.inotify.max_user_watches = %d): %v. ", limit, err)
+		fmt.Fprintf(&sb, "Raise the limit, e.g. `sudo sysctl fs.inotify.max_user_watches=%d`", max(2*limit, 524288))
+	} else {
+		fmt.Fprintf(&sb, "Warning: ran out of file descriptors for watches: %v. Raise the limit with `ulimit -n`", err)
+	}
+	sb.WriteString(`, skip directories with --ignore or "watchSkipDirs", or use --watch-backend poll or watchman. `)
+	if polling {
+		sb.WriteString("Directories that cannot be watched are polled instead.")
+	} else {
+		sb.WriteString("Changes in directories that cannot be watched are missed (see `svelte-check-server status`); --poll-fallback polls them instead.")
+	}
+	return sb.String()
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// exhaustWatches makes fw fail to watch directories below dir with ENOSPC.
+func exhaustWatches(fw *RealFSWatcher, dir string) {
+	add := fw.addWatch
+	fw.addWatch = func(path string) error {
+		if strings.HasPrefix(path, dir) {
+			return fmt.Errorf("%q: %w", path, syscall.ENOSPC)
+		}
+		return add(path)
+	}
+}
+
+func TestIsWatchExhausted(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("add: %w", syscall.ENOSPC), true},
+		{syscall.EMFILE, true},
+		{os.ErrNotExist, false},
+	} {
+		if got := isWatchExhausted(tt.err); got != tt.want {
+			t.Errorf("isWatchExhausted(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestWatchExhaustionWarning(t *testing.T) {
+	warning := watchExhaustionWarning(syscall.EMFILE, false)
+	for _, want := range []string{"ulimit -n", "--watch-backend poll or watchman", "--poll-fallback"} {
+		if !strings.Contains(warning, want) {
+			t.Errorf("warning missing %q: %s", want, warning)
+		}
+	}
+	if warning := watchExhaustionWarning(syscall.EMFILE, true); !strings.Contains(warning, "polled instead") {
+		t.Errorf("warning with fallback = %s, want polling mentioned", warning)
+	}
+}
+
+func TestRealFSWatcher_Exhausted_ReportsDegradation(t *testing.T) {
+	resetWatcherCount()
+	defer resetWatcherCount()
+
+	root := t.TempDir()
+	for _, dir := range []string{"src/lib/server", "src/routes"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+	}
+
+	fw, err := NewRealFSWatcher()
+	if err != nil {
+		t.Fatalf("NewRealFSWatcher failed: %v", err)
+	}
+	defer func() { _ = fw.Close() }()
+	exhaustWatches(fw, filepath.Join(root, "src", "lib"))
+
+	if d := fw.Degradation(); d != nil {
+		t.Fatalf("Degradation() before Add = %+v, want nil", d)
+	}
+	if err := fw.Add(root, true); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	d := fw.Degradation()
+	if d == nil {
+		t.Fatal("Degradation() = nil, want src/lib and src/lib/server unwatched")
+	}
+	if d.Unwatched != 2 || d.Polled != 0 || !strings.Contains(d.Reason, "no space left on device") {
+		t.Errorf("Degradation() = %+v, want 2 unwatched by ENOSPC", d)
+	}
+
+	// A rescan does not count the same directories twice.
+	_ = fw.Rescan()
+	if d := fw.Degradation(); d.Unwatched != 2 {
+		t.Errorf("Unwatched after Rescan = %d, want 2", d.Unwatched)
+	}
+
+	w := NewWatcher(WatcherConfig{WorkspacePath: root}, WatcherCallbacks{}, fw, nil)
+	if status := w.Status(); status.Degraded == nil || status.Degraded.Unwatched != 2 {
+		t.Errorf("Watcher.Status().Degraded = %+v, want 2 unwatched", status.Degraded)
+	}
+}
+
+func TestRealFSWatcher_Exhausted_PollFallback(t *testing.T) {
+	resetWatcherCount()
+	defer resetWatcherCount()
+
+	root := t.TempDir()
+	lib := filepath.Join(root, "src", "lib")
+	if err := os.MkdirAll(filepath.Join(lib, "server"), 0o755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+
+	fw, err := NewRealFSWatcher()
+	if err != nil {
+		t.Fatalf("NewRealFSWatcher failed: %v", err)
+	}
+	fw.SetPollFallback(10 * time.Millisecond)
+	exhaustWatches(fw, lib)
+
+	if err := fw.Add(root, true); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if d := fw.Degradation(); d == nil || d.Unwatched != 0 || d.Polled != 1 {
+		t.Fatalf("Degradation() = %+v, want src/lib polled", d)
+	}
+
+	path := filepath.Join(lib, "server", "db.ts")
+	writeFiles(t, lib, map[string]string{"server/db.ts": ""})
+	deadline := time.After(5 * time.Second)
+	for found := false; !found; {
+		select {
+		case event := <-fw.Events():
+			found = event.Name == path && event.Has(fsnotify.Create)
+		case <-deadline:
+			t.Fatalf("no CREATE event for %s from the poll fallback", path)
+		}
+	}
+
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	for range fw.Events() {
+		// The merged channel must be closed.
+	}
+}
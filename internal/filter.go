@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// =============================================================================
+// Diagnostic Filters
+// =============================================================================
+
+// DiagnosticFilter narrows a /check result to the diagnostics a client asks
+// for, with ?severity=, ?glob=, and ?code= (repeatable). Counts, Introduced,
+// and Resolved are narrowed alike, and the verdict follows the narrowed
+// result.
+type DiagnosticFilter struct {
+	// Severity is the least severe type kept, as svelte-check's
+	// --threshold: SeverityError keeps errors only; empty or
+	// SeverityWarning keeps warnings too.
+	Severity Severity
+
+	// Glob matches workspace-relative filenames; "**" matches any number
+	// of directories, e.g. "src/routes/**".
+	Glob string
+
+	// Codes keeps only diagnostics with one of these codes, e.g. "2322" or
+	// "a11y_missing_attribute".
+	Codes []string
+}
+
+// parseDiagnosticFilter reads a DiagnosticFilter from a /check query.
+func parseDiagnosticFilter(query url.Values) (DiagnosticFilter, error) {
+	f := DiagnosticFilter{Glob: query.Get("glob"), Codes: query["code"]}
+	if s := query.Get("severity"); s != "" {
+		sev, err := ParseSeverity(s)
+		if err != nil {
+			return f, err
+		}
+		if sev == SeverityFailure {
+			return f, fmt.Errorf("severity must be error or warning")
+		}
+		f.Severity = sev
+	}
+	if f.Glob != "" {
+		if _, err := path.Match(f.Glob, ""); err != nil {
+			return f, fmt.Errorf("invalid glob %q: %w", f.Glob, err)
+		}
+	}
+	return f, nil
+}
+
+// values encodes f as /check query parameters.
+func (f DiagnosticFilter) values(query url.Values) {
+	if f.Severity != "" {
+		query.Set("severity", string(f.Severity))
+	}
+	if f.Glob != "" {
+		query.Set("glob", f.Glob)
+	}
+	for _, code := range f.Codes {
+		query.Add("code", code)
+	}
+}
+
+// keeps reports whether d passes f.
+func (f DiagnosticFilter) keeps(d Diagnostic) bool {
+	if f.Severity == SeverityError && d.Type != "ERROR" {
+		return false
+	}
+	if f.Glob != "" && !matchDoubleStar(strings.Split(f.Glob, "/"), strings.Split(d.Filename, "/")) {
+		return false
+	}
+	if len(f.Codes) > 0 && !slices.Contains(f.Codes, fmt.Sprint(d.Code)) {
+		return false
+	}
+	return true
+}
+
+// Apply removes the diagnostics f does not keep from result. Matching uses
+// qualify(result), as for Baseline.Apply.
+func (f DiagnosticFilter) Apply(result SvelteWatchCheckComplete, qualify func(SvelteWatchCheckComplete) SvelteWatchCheckComplete) SvelteWatchCheckComplete {
+	if f.Severity != SeverityError && f.Glob == "" && len(f.Codes) == 0 {
+		return result
+	}
+	result, _ = filterResult(result, qualify(result), func(diags []Diagnostic) []bool {
+		removed := make([]bool, len(diags))
+		for i, d := range diags {
+			removed[i] = !f.keeps(d)
+		}
+		return removed
+	})
+	return result
+}
+
+// Summary is the counts of a result.
+type Summary struct {
+	FileCount         int
+	ErrorCount        int
+	WarningCount      int
+	FilesWithProblems int
+}
+
+// Diagnostics returns the diagnostics of the latest result of the named
+// project, or of all projects merged when project is empty, that pass f,
+// with the result's counts narrowed alike. Like Check, it waits for a check
+// in progress.
+func (c *Client) Diagnostics(ctx context.Context, project string, f DiagnosticFilter) ([]Diagnostic, Summary, error) {
+	resp, err := c.CheckTyped(ctx, CheckOptions{Project: project, Filter: f})
+	if err != nil {
+		return nil, Summary{}, err
+	}
+	result := resp.Result
+	return result.Diagnostics, Summary{
+		FileCount:         result.FileCount,
+		ErrorCount:        result.ErrorCount,
+		WarningCount:      result.WarningCount,
+		FilesWithProblems: result.FilesWithProblems,
+	}, nil
+}
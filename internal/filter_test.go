@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseDiagnosticFilter(t *testing.T) {
+	f, err := parseDiagnosticFilter(url.Values{"severity": {"Error"}, "glob": {"src/routes/**"}, "code": {"2322", "2304"}})
+	if err != nil {
+		t.Fatalf("parseDiagnosticFilter failed: %v", err)
+	}
+	if f.Severity != SeverityError || f.Glob != "src/routes/**" || len(f.Codes) != 2 {
+		t.Errorf("parseDiagnosticFilter() = %+v", f)
+	}
+
+	for _, query := range []url.Values{
+		{"severity": {"failure"}},
+		{"severity": {"info"}},
+		{"glob": {"src/[routes"}},
+	} {
+		if _, err := parseDiagnosticFilter(query); err == nil {
+			t.Errorf("parseDiagnosticFilter(%v) succeeded, want an error", query)
+		}
+	}
+}
+
+func TestDiagnosticFilter_Apply(t *testing.T) {
+	warning := Diagnostic{Type: "WARNING", Filename: "src/routes/+page.svelte", Code: "a11y_missing_attribute"}
+	result := SvelteWatchCheckComplete{
+		Diagnostics: []Diagnostic{
+			diagAt("src/routes/+page.ts", 3, 2322, "Type mismatch"),
+			diagAt("src/lib/a.ts", 5, 2304, "Cannot find name 'x'"),
+			warning,
+		},
+		Introduced:        []Diagnostic{warning},
+		ErrorCount:        2,
+		WarningCount:      1,
+		FilesWithProblems: 3,
+	}
+
+	for _, tt := range []struct {
+		name   string
+		filter DiagnosticFilter
+		files  []string
+		errors int
+	}{
+		{"none", DiagnosticFilter{}, []string{"src/routes/+page.ts", "src/lib/a.ts", "src/routes/+page.svelte"}, 2},
+		{"errors", DiagnosticFilter{Severity: SeverityError}, []string{"src/routes/+page.ts", "src/lib/a.ts"}, 2},
+		{"glob", DiagnosticFilter{Glob: "src/routes/**"}, []string{"src/routes/+page.ts", "src/routes/+page.svelte"}, 1},
+		{"codes", DiagnosticFilter{Codes: []string{"2304", "a11y_missing_attribute"}}, []string{"src/lib/a.ts", "src/routes/+page.svelte"}, 1},
+		{"all", DiagnosticFilter{Severity: SeverityError, Glob: "src/routes/**", Codes: []string{"2322"}}, []string{"src/routes/+page.ts"}, 1},
+	} {
+		got := tt.filter.Apply(result, identity)
+		var files []string
+		for _, d := range got.Diagnostics {
+			files = append(files, d.Filename)
+		}
+		if strings.Join(files, " ") != strings.Join(tt.files, " ") {
+			t.Errorf("%s: files = %v, want %v", tt.name, files, tt.files)
+		}
+		if got.ErrorCount != tt.errors || got.FilesWithProblems != len(tt.files) {
+			t.Errorf("%s: ErrorCount, FilesWithProblems = %d, %d; want %d, %d",
+				tt.name, got.ErrorCount, got.FilesWithProblems, tt.errors, len(tt.files))
+		}
+		if keptWarning := len(got.Introduced) == 1; keptWarning != (got.WarningCount == 1) {
+			t.Errorf("%s: Introduced = %v with %d warnings", tt.name, got.Introduced, got.WarningCount)
+		}
+	}
+}
+
+// TestClient_Diagnostics tests filtering through GET /check, including the
+// verdict of the narrowed result.
+func TestClient_Diagnostics(t *testing.T) {
+	socketPath := testSocketPath(t)
+
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(`1770255832071 START "/workspace"
+1770255834342 {"type":"ERROR","filename":"src/lib/a.ts","start":{"line":4,"character":0},"end":{"line":4,"character":1},"message":"Type mismatch","code":2322}
+1770255834342 {"type":"WARNING","filename":"src/routes/+page.svelte","start":{"line":1,"character":0},"end":{"line":1,"character":1},"message":"Missing alt","code":"a11y_missing_attribute"}
+1770255834342 COMPLETED 100 FILES 1 ERRORS 1 WARNINGS 2 FILES_WITH_PROBLEMS
+`, "")))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() { _ = s.Stop(context.Background()) }()
+	c := &Client{socketPath: socketPath, httpClient: unixHTTPClient(socketPath)}
+	ctx := context.Background()
+
+	diags, summary, err := c.Diagnostics(ctx, "", DiagnosticFilter{Glob: "src/routes/**"})
+	if err != nil {
+		t.Fatalf("Diagnostics failed: %v", err)
+	}
+	if len(diags) != 1 || diags[0].Code != "a11y_missing_attribute" {
+		t.Errorf("Diagnostics() = %+v, want the warning in src/routes", diags)
+	}
+	if summary != (Summary{FileCount: 100, WarningCount: 1, FilesWithProblems: 1}) {
+		t.Errorf("Summary = %+v, want 1 warning in 1 file of 100", summary)
+	}
+
+	resp, err := c.CheckWith(ctx, CheckOptions{Filter: DiagnosticFilter{Glob: "src/routes/**"}})
+	if err != nil || resp.ExitCode != 0 {
+		t.Errorf("CheckWith(src/routes) = %+v, %v; want a pass without the error elsewhere", resp, err)
+	}
+
+	resp, err = c.CheckWith(ctx, CheckOptions{Format: "json"})
+	if err != nil || !strings.HasPrefix(resp.Output, `{"schemaVersion":2,`) {
+		t.Errorf("CheckWith(json) = %q, %v; want the schema version first", resp.Output, err)
+	}
+
+	if _, _, err := c.Diagnostics(ctx, "", DiagnosticFilter{Severity: SeverityFailure}); err == nil {
+		t.Error("Diagnostics with severity failure succeeded, want an error")
+	}
+}
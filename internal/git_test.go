@@ -1,7 +1,13 @@
 package internal
 
 import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 )
 
 // TestParseGitHeadRef tests the parsing of .git/HEAD file content.
@@ -123,3 +129,207 @@ func TestParseGitHeadRef_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveGitDir(t *testing.T) {
+	t.Run("repository", func(t *testing.T) {
+		root := t.TempDir()
+		writeFiles(t, root, map[string]string{".git/HEAD": "ref: refs/heads/main\n"})
+
+		gitDir, commonDir, err := resolveGitDir(root)
+		want := filepath.Join(root, ".git")
+		if err != nil || gitDir != want || commonDir != want {
+			t.Errorf("resolveGitDir() = %q, %q, %v; want %q for both", gitDir, commonDir, err, want)
+		}
+	})
+
+	t.Run("worktree", func(t *testing.T) {
+		root := t.TempDir()
+		writeFiles(t, root, map[string]string{
+			"main/.git/HEAD":                        "ref: refs/heads/main\n",
+			"main/.git/worktrees/feature/HEAD":      "ref: refs/heads/feature\n",
+			"main/.git/worktrees/feature/commondir": "../..\n",
+			"feature/.git":                          "gitdir: ../main/.git/worktrees/feature\n",
+		})
+
+		gitDir, commonDir, err := resolveGitDir(filepath.Join(root, "feature"))
+		if err != nil {
+			t.Fatalf("resolveGitDir failed: %v", err)
+		}
+		if want := filepath.Join(root, "main", ".git", "worktrees", "feature"); gitDir != want {
+			t.Errorf("gitDir = %q, want %q", gitDir, want)
+		}
+		if want := filepath.Join(root, "main", ".git"); commonDir != want {
+			t.Errorf("commonDir = %q, want %q", commonDir, want)
+		}
+	})
+
+	t.Run("separate git dir", func(t *testing.T) {
+		root := t.TempDir()
+		store := filepath.Join(root, "store.git")
+		writeFiles(t, root, map[string]string{
+			"store.git/HEAD": "ref: refs/heads/main\n",
+			"work/.git":      "gitdir: " + store + "\n",
+		})
+
+		gitDir, commonDir, err := resolveGitDir(filepath.Join(root, "work"))
+		if err != nil || gitDir != store || commonDir != store {
+			t.Errorf("resolveGitDir() = %q, %q, %v; want %q for both", gitDir, commonDir, err, store)
+		}
+	})
+
+	t.Run("not a gitdir file", func(t *testing.T) {
+		root := t.TempDir()
+		writeFiles(t, root, map[string]string{".git": "garbage"})
+
+		if _, _, err := resolveGitDir(root); err == nil {
+			t.Error("resolveGitDir succeeded, want an error")
+		}
+	})
+}
+
+func TestRealGitBranchWatcher_Worktree_WatchesSharedRefs(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main/.git/refs/heads/feature":          "abc\n",
+		"main/.git/worktrees/feature/HEAD":      "ref: refs/heads/feature\n",
+		"main/.git/worktrees/feature/commondir": "../..\n",
+		"feature/.git":                          "gitdir: ../main/.git/worktrees/feature\n",
+	})
+	worktree := filepath.Join(root, "feature")
+
+	resetWatcherCount()
+	r, err := NewRealGitBranchWatcher(worktree, NewFakeExecutor(worktree+"\n", ""))
+	if err != nil {
+		t.Fatalf("NewRealGitBranchWatcher failed: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+
+	if want := filepath.Join(root, "main", ".git", "refs", "heads", "feature"); r.currentBranchRefPath() != want {
+		t.Errorf("currentBranchRefPath() = %q, want %q", r.currentBranchRefPath(), want)
+	}
+}
+
+func TestParsePackedRef(t *testing.T) {
+	content := `# pack-refs with: peeled fully-peeled sorted
+1111111111111111111111111111111111111111 refs/heads/feature/x
+2222222222222222222222222222222222222222 refs/heads/main
+3333333333333333333333333333333333333333 refs/tags/v1
+^4444444444444444444444444444444444444444
+`
+	for ref, want := range map[string]string{
+		"refs/heads/main":      "2222222222222222222222222222222222222222",
+		"refs/heads/feature/x": "1111111111111111111111111111111111111111",
+		"refs/heads/missing":   "",
+	} {
+		if got := parsePackedRef(content, ref); got != want {
+			t.Errorf("parsePackedRef(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
+
+func TestRealGitBranchWatcher_PackedRefs(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".git/HEAD":            "ref: refs/heads/main\n",
+		".git/refs/heads/main": "aaaa\n",
+	})
+	gitDir := filepath.Join(root, ".git")
+
+	resetWatcherCount()
+	r, err := NewRealGitBranchWatcher(root, NewFakeExecutor(root+"\n", ""))
+	if err != nil {
+		t.Fatalf("NewRealGitBranchWatcher failed: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Start(ctx)
+	time.Sleep(100 * time.Millisecond)
+
+	// git pack-refs writes packed-refs, then prunes the loose ref.
+	writeFiles(t, root, map[string]string{".git/packed-refs": "aaaa refs/heads/main\n"})
+	if err := os.Remove(filepath.Join(gitDir, "refs", "heads", "main")); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	select {
+	case <-r.BranchChanged():
+		t.Fatal("BranchChanged fired for packing an unchanged ref")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// Updating the packed branch, e.g. by fetch, rewrites packed-refs.
+	writeFiles(t, root, map[string]string{".git/packed-refs": "bbbb refs/heads/main\n"})
+	select {
+	case <-r.BranchChanged():
+	case <-time.After(5 * time.Second):
+		t.Fatal("BranchChanged did not fire for a packed-refs update")
+	}
+
+	// A commit writes a loose ref again.
+	writeFiles(t, root, map[string]string{".git/refs/heads/main": "cccc\n"})
+	select {
+	case <-r.BranchChanged():
+	case <-time.After(5 * time.Second):
+		t.Fatal("BranchChanged did not fire for a recreated loose ref")
+	}
+}
+
+func TestGitOperation(t *testing.T) {
+	for _, tt := range []struct {
+		files map[string]string
+		want  string
+	}{
+		{map[string]string{"HEAD": "ref: refs/heads/main\n"}, ""},
+		{map[string]string{"rebase-merge/onto": "abc"}, "rebase"},
+		{map[string]string{"rebase-apply/next": "1"}, "rebase"},
+		{map[string]string{"MERGE_HEAD": "abc"}, "merge"},
+		{map[string]string{"CHERRY_PICK_HEAD": "abc"}, "cherry-pick"},
+		{map[string]string{"index.lock": ""}, ""},
+	} {
+		gitDir := t.TempDir()
+		writeFiles(t, gitDir, tt.files)
+		if got := gitOperation(gitDir); got != tt.want {
+			t.Errorf("gitOperation() with %v = %q, want %q", slices.Collect(maps.Keys(tt.files)), got, tt.want)
+		}
+	}
+}
+
+func TestRealGitBranchWatcher_OperationChanged(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{".git/HEAD": "ref: refs/heads/main\n"})
+
+	resetWatcherCount()
+	r, err := NewRealGitBranchWatcher(root, NewFakeExecutor(root+"\n", ""))
+	if err != nil {
+		t.Fatalf("NewRealGitBranchWatcher failed: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Start(ctx)
+	time.Sleep(100 * time.Millisecond)
+
+	writeFiles(t, root, map[string]string{".git/rebase-merge/onto": "abc"})
+	select {
+	case <-r.OperationChanged():
+	case <-time.After(5 * time.Second):
+		t.Fatal("OperationChanged did not fire when the rebase started")
+	}
+	if got := r.Operation(); got != "rebase" {
+		t.Errorf("Operation() = %q, want rebase", got)
+	}
+
+	if err := os.RemoveAll(filepath.Join(root, ".git", "rebase-merge")); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	select {
+	case <-r.OperationChanged():
+	case <-time.After(5 * time.Second):
+		t.Fatal("OperationChanged did not fire when the rebase ended")
+	}
+	if got := r.Operation(); got != "" {
+		t.Errorf("Operation() = %q, want none", got)
+	}
+}
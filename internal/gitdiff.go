@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// =============================================================================
+// Git Change Filtering
+// =============================================================================
+
+// GitChange is a file a HEAD or branch change touched.
+type GitChange struct {
+	Path   string // absolute
+	Status string // as in git diff --name-status: "A", "M", "D", "T"
+}
+
+// GitDiffer is implemented by GitBranchWatchers that can list the files a
+// HEAD or branch change touched, so the Watcher can skip restarts for
+// changes that cannot affect the checks.
+type GitDiffer interface {
+	// Changes returns the files that differ between the commit checked out
+	// at the previous call, or at creation, and the current one. ok is false
+	// if that cannot be told, e.g. on an unborn branch or if git fails.
+	Changes() (changes []GitChange, ok bool)
+}
+
+// checkedExtensions are the extensions of files svelte-check and the other
+// checkers may read wherever they are, e.g. through tsconfig includes.
+var checkedExtensions = map[string]bool{
+	".svelte": true,
+	".ts":     true,
+	".mts":    true,
+	".cts":    true,
+	".tsx":    true,
+	".js":     true,
+	".mjs":    true,
+	".cjs":    true,
+	".jsx":    true,
+}
+
+// headCommit returns the commit HEAD of the work tree at dir points to, or
+// "" if there is none.
+func (r *RealGitBranchWatcher) headCommit(dir string) string {
+	cmd := r.executor.Command("git", "rev-parse", "--verify", "--quiet", "HEAD")
+	cmd.SetDir(dir)
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Changes runs git diff between the previously seen commit and HEAD, in the
+// repository and in each of its watched submodules. It is called from the
+// Watcher's loop only.
+func (r *RealGitBranchWatcher) Changes() ([]GitChange, bool) {
+	if r.gitRoot == "" {
+		return nil, false
+	}
+	prev := r.commit
+	r.commit = r.headCommit(r.gitRoot)
+	changes, ok := r.diff(r.gitRoot, r.diffRoot(), prev, r.commit)
+	for _, s := range r.submodules {
+		prev := s.commit
+		s.commit = r.headCommit(s.path)
+		subChanges, subOK := r.diff(s.path, s.path, prev, s.commit)
+		changes = append(changes, subChanges...)
+		ok = ok && subOK
+	}
+	return changes, ok
+}
+
+// diff returns the files that differ between commits prev and cur of the
+// work tree at dir, with paths under root.
+func (r *RealGitBranchWatcher) diff(dir, root, prev, cur string) ([]GitChange, bool) {
+	if prev == "" || cur == "" {
+		return nil, false
+	}
+	if prev == cur {
+		return nil, true
+	}
+
+	cmd := r.executor.Command("git", "diff", "--name-status", "--no-renames", "-z", prev, cur)
+	cmd.SetDir(dir)
+	out, err := cmd.Output()
+	if err != nil {
+		logger("git").Warn("git diff failed", "from", prev, "to", cur, "error", err)
+		return nil, false
+	}
+	return parseGitNameStatus(root, string(out)), true
+}
+
+// diffRoot returns the git root as reached from the workspace path, which
+// may run through a symlink git resolves, so that changed paths compare
+// equal to the Watcher's.
+func (r *RealGitBranchWatcher) diffRoot() string {
+	workspace, err := filepath.EvalSymlinks(r.workspacePath)
+	if err != nil {
+		return r.gitRoot
+	}
+	gitRoot, err := filepath.EvalSymlinks(r.gitRoot)
+	if err != nil {
+		return r.gitRoot
+	}
+	rel, err := filepath.Rel(workspace, gitRoot)
+	if err != nil {
+		return r.gitRoot
+	}
+	return filepath.Join(r.workspacePath, rel)
+}
+
+// parseGitNameStatus parses the output of git diff --name-status -z, whose
+// paths are relative to gitRoot, without renames or copies.
+func parseGitNameStatus(gitRoot, out string) []GitChange {
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	var changes []GitChange
+	for i := 0; i+1 < len(fields); i += 2 {
+		changes = append(changes, GitChange{
+			Path:   filepath.Join(gitRoot, filepath.FromSlash(fields[i+1])),
+			Status: fields[i],
+		})
+	}
+	return changes
+}
+
+// gitChanged restarts after a HEAD or branch change, described by what. When
+// the git watcher lists the files the change touched, the restart is skipped
+// if none of them can affect the checks, and svelte-kit sync runs first if
+// route files were added or removed.
+func (w *Watcher) gitChanged(what string) {
+	w.recordGitState()
+	if d, ok := w.gitBranchWatcher.(GitDiffer); ok {
+		if changes, ok := d.Changes(); ok {
+			relevant, sync := w.classifyGitChanges(changes)
+			switch {
+			case !relevant:
+				w.logger.Info("no checked files changed; not restarting", "change", what)
+				w.config.Audit.Record("git", map[string]any{"change": what, "action": "none"})
+				return
+			case sync:
+				w.logger.Info("route files added or removed, running svelte-kit sync and restarting svelte-check", "change", what)
+				w.config.Audit.Record("git", map[string]any{"change": what, "action": "sync and restart"})
+				w.stats.syncTriggered()
+				w.stats.restartTriggered(TriggerGit)
+				w.configDebouncer.Trigger()
+				return
+			}
+		}
+	}
+	w.logger.Info("restarting svelte-check", "change", what)
+	w.config.Audit.Record("git", map[string]any{"change": what, "action": "restart"})
+	w.stats.restartTriggered(TriggerGit)
+	w.restartDebouncer.Trigger()
+}
+
+// classifyGitChanges reports whether any of changes can affect the checks: a
+// file in a recursively watched directory, a project config file or
+// lockfile, a file matching RestartOn, or a source file anywhere in the
+// workspace, unless ignored. sync reports whether route files or svelte-kit
+// sync inputs were added or removed.
+func (w *Watcher) classifyGitChanges(changes []GitChange) (relevant, sync bool) {
+	recursiveDirs := w.WatchDirs().Recursive
+	for _, c := range changes {
+		if !isWithin(w.config.WorkspacePath, c.Path) || w.ignored(c.Path) {
+			continue
+		}
+		watched := slices.ContainsFunc(recursiveDirs, func(dir string) bool {
+			return isWithin(filepath.Join(w.config.WorkspacePath, dir), c.Path)
+		})
+		projectFile := (isProjectConfigFile(c.Path) || isLockfile(c.Path)) && w.isProjectRoot(filepath.Dir(c.Path))
+		restartOn := w.matchesGlob(w.config.RestartOn, c.Path)
+		if !watched && !projectFile && !restartOn && !checkedExtensions[filepath.Ext(c.Path)] {
+			continue
+		}
+		relevant = true
+		if (c.Status == "A" || c.Status == "D") && (w.isRouteFile(c.Path) || w.matchesGlob(w.config.SyncOn, c.Path)) {
+			sync = true
+		}
+	}
+	return relevant, sync
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	kexec "k8s.io/utils/exec"
+)
+
+func TestParseGitNameStatus(t *testing.T) {
+	out := "M\x00src/routes/+page.svelte\x00A\x00docs/new file.md\x00D\x00src/params/id.ts\x00"
+	want := []GitChange{
+		{Path: filepath.Join("/repo", "src", "routes", "+page.svelte"), Status: "M"},
+		{Path: filepath.Join("/repo", "docs", "new file.md"), Status: "A"},
+		{Path: filepath.Join("/repo", "src", "params", "id.ts"), Status: "D"},
+	}
+	if got := parseGitNameStatus("/repo", out); !slices.Equal(got, want) {
+		t.Errorf("parseGitNameStatus() = %v, want %v", got, want)
+	}
+	if got := parseGitNameStatus("/repo", ""); len(got) != 0 {
+		t.Errorf("parseGitNameStatus(\"\") = %v, want none", got)
+	}
+}
+
+func TestWatcher_ClassifyGitChanges(t *testing.T) {
+	config := WatcherConfig{
+		WorkspacePath:    "/ws",
+		RecursiveDirs:    []string{"src"},
+		NonRecursiveDirs: []string{"."},
+		Ignore:           []string{"src/generated/**"},
+		SyncOn:           []string{"src/params/*"},
+	}
+	w := NewWatcher(config, WatcherCallbacks{}, NewFakeFSWatcher(), nil)
+
+	for _, tt := range []struct {
+		name          string
+		changes       []GitChange
+		relevant, syn bool
+	}{
+		{"docs only", []GitChange{{"/ws/README.md", "M"}, {"/ws/docs/guide.md", "A"}}, false, false},
+		{"outside the workspace", []GitChange{{"/other/src/app.ts", "M"}}, false, false},
+		{"ignored", []GitChange{{"/ws/src/generated/api.ts", "M"}}, false, false},
+		{"source edit", []GitChange{{"/ws/src/lib/util.ts", "M"}}, true, false},
+		{"script outside src", []GitChange{{"/ws/scripts/build.ts", "M"}}, true, false},
+		{"config", []GitChange{{"/ws/package.json", "M"}}, true, false},
+		{"route edit", []GitChange{{"/ws/src/routes/+page.ts", "M"}}, true, false},
+		{"route added", []GitChange{{"/ws/src/routes/blog/+page.ts", "A"}}, true, true},
+		{"param matcher removed", []GitChange{{"/ws/src/params/id.ts", "D"}}, true, true},
+	} {
+		relevant, sync := w.classifyGitChanges(tt.changes)
+		if relevant != tt.relevant || sync != tt.syn {
+			t.Errorf("%s: classifyGitChanges() = %v, %v; want %v, %v", tt.name, relevant, sync, tt.relevant, tt.syn)
+		}
+	}
+}
+
+// FakeGitDiffer adds GitDiffer to FakeGitBranchWatcher.
+type FakeGitDiffer struct {
+	*FakeGitBranchWatcher
+	changes []GitChange
+}
+
+func (f *FakeGitDiffer) Changes() ([]GitChange, bool) { return f.changes, true }
+
+func TestWatcher_GitChange_SkipsIrrelevant(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		gitWatcher := &FakeGitDiffer{FakeGitBranchWatcher: NewFakeGitBranchWatcher()}
+		var restarts, syncs int
+		callbacks := WatcherCallbacks{OnRestart: func() { restarts++ }, OnSvelteSync: func() { syncs++ }}
+		config := WatcherConfig{WorkspacePath: "/ws", RecursiveDirs: []string{"src"}}
+		w := NewWatcher(config, callbacks, NewFakeFSWatcher(), gitWatcher)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		gitWatcher.changes = []GitChange{{"/ws/CHANGELOG.md", "M"}}
+		gitWatcher.branchCh <- struct{}{}
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+		if restarts != 0 || syncs != 0 {
+			t.Fatalf("restarts, syncs = %d, %d after a docs-only commit; want 0, 0", restarts, syncs)
+		}
+
+		gitWatcher.changes = []GitChange{{"/ws/src/routes/about/+page.ts", "A"}}
+		gitWatcher.headCh <- struct{}{}
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+		if restarts != 1 || syncs != 1 {
+			t.Errorf("restarts, syncs = %d, %d after adding a route; want 1, 1", restarts, syncs)
+		}
+	})
+}
+
+func TestRealGitBranchWatcher_Changes(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	// The workspace is reached through a symlink, as /tmp is on macOS.
+	root := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(t.TempDir(), root); err != nil {
+		t.Fatalf("Symlink failed: %v", err)
+	}
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	writeFiles(t, root, map[string]string{"src/app.ts": "1", "README.md": "1"})
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+
+	resetWatcherCount()
+	r, err := NewRealGitBranchWatcher(root, kexec.New())
+	if err != nil {
+		t.Fatalf("NewRealGitBranchWatcher failed: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+
+	writeFiles(t, root, map[string]string{"src/app.ts": "2", "src/routes/+page.ts": ""})
+	git("add", ".")
+	git("commit", "-q", "-m", "second")
+
+	want := []GitChange{
+		{Path: filepath.Join(root, "src", "app.ts"), Status: "M"},
+		{Path: filepath.Join(root, "src", "routes", "+page.ts"), Status: "A"},
+	}
+	if got, ok := r.Changes(); !ok || !slices.Equal(got, want) {
+		t.Errorf("Changes() = %v, %v; want %v, true", got, ok, want)
+	}
+	if got, ok := r.Changes(); !ok || len(got) != 0 {
+		t.Errorf("second Changes() = %v, %v; want none, true", got, ok)
+	}
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tylergannon/svelte-check-server/pkg/types"
+)
+
+// =============================================================================
+// Git State
+// =============================================================================
+
+// GitState is the branch and commit checked out in the workspace.
+type GitState = types.GitState
+
+// GitStateReporter is implemented by GitBranchWatchers that can tell the
+// branch and commit checked out.
+type GitStateReporter interface {
+	GitState() (GitState, bool)
+}
+
+// shortSHALength is the length git abbreviates commits to by default.
+const shortSHALength = 7
+
+// GitState reads HEAD and the ref it points to. ok is false outside a git
+// repository and on an unborn branch.
+func (r *RealGitBranchWatcher) GitState() (GitState, bool) {
+	if r.gitDir == "" {
+		return GitState{}, false
+	}
+	ref, sha := readHead(r.gitDir, r.commonDir)
+	if sha == "" {
+		return GitState{}, false
+	}
+	if len(sha) > shortSHALength {
+		sha = sha[:shortSHALength]
+	}
+	return GitState{Branch: strings.TrimPrefix(ref, "refs/heads/"), Commit: sha}, true
+}
+
+// maxGitStates bounds the checkouts a Watcher remembers for dating results.
+const maxGitStates = 16
+
+// gitStateHistory records when the checked-out branch and commit changed.
+type gitStateHistory struct {
+	mu      sync.Mutex
+	entries []gitStateEntry
+}
+
+type gitStateEntry struct {
+	at    int64 // Unix milliseconds, as result timestamps
+	state GitState
+}
+
+// record adds state unless it is already the latest.
+func (h *gitStateHistory) record(at time.Time, state GitState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n := len(h.entries); n > 0 && h.entries[n-1].state == state {
+		return
+	}
+	h.entries = append(h.entries, gitStateEntry{at: at.UnixMilli(), state: state})
+	if len(h.entries) > maxGitStates {
+		h.entries = h.entries[len(h.entries)-maxGitStates:]
+	}
+}
+
+// at returns the state in effect at t, or false if t precedes the history.
+func (h *gitStateHistory) at(t time.Time) (GitState, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if h.entries[i].at <= t.UnixMilli() {
+			return h.entries[i].state, true
+		}
+	}
+	return GitState{}, false
+}
+
+// recordGitState notes the branch and commit now checked out, if the git
+// watcher reports them.
+func (w *Watcher) recordGitState() {
+	if state, ok := w.GitState(); ok {
+		w.gitStates.record(time.Now(), state)
+	}
+}
+
+// GitState returns the branch and commit checked out in the workspace. ok is
+// false if the git watcher cannot tell.
+func (w *Watcher) GitState() (GitState, bool) {
+	if gr, ok := w.gitBranchWatcher.(GitStateReporter); ok {
+		return gr.GitState()
+	}
+	return GitState{}, false
+}
+
+// GitStateAt returns the branch and commit that were checked out at t, as
+// far as the Watcher has seen them change since it was created.
+func (w *Watcher) GitStateAt(t time.Time) (GitState, bool) {
+	return w.gitStates.at(t)
+}
+
+// markGit sets the branch and commit that were checked out when result
+// completed.
+func (s *Server) markGit(result *SvelteWatchCheckComplete) {
+	if s.watcher == nil || result.Timestamp == 0 {
+		return
+	}
+	if state, ok := s.watcher.GitStateAt(time.UnixMilli(result.Timestamp)); ok {
+		result.Git = &state
+	}
+}
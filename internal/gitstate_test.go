@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRealGitBranchWatcher_GitState(t *testing.T) {
+	const sha = "1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d"
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".git/HEAD":                     "ref: refs/heads/feature/login\n",
+		".git/refs/heads/feature/login": sha + "\n",
+	})
+
+	resetWatcherCount()
+	r, err := NewRealGitBranchWatcher(root, NewFakeExecutor(root+"\n", ""))
+	if err != nil {
+		t.Fatalf("NewRealGitBranchWatcher failed: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+
+	if got, ok := r.GitState(); !ok || got != (GitState{Branch: "feature/login", Commit: "1a2b3c4"}) {
+		t.Errorf("GitState() = %v, %v; want feature/login@1a2b3c4", got, ok)
+	}
+
+	writeFiles(t, root, map[string]string{".git/HEAD": sha + "\n"})
+	if got, ok := r.GitState(); !ok || got.String() != "1a2b3c4 (detached)" {
+		t.Errorf("GitState() = %v, %v; want detached at 1a2b3c4", got, ok)
+	}
+
+	writeFiles(t, root, map[string]string{".git/HEAD": "ref: refs/heads/unborn\n"})
+	if got, ok := r.GitState(); ok {
+		t.Errorf("GitState() = %v on an unborn branch, want not ok", got)
+	}
+}
+
+// FakeGitStateReporter adds GitStateReporter to FakeGitBranchWatcher.
+type FakeGitStateReporter struct {
+	*FakeGitBranchWatcher
+	state GitState
+}
+
+func (f *FakeGitStateReporter) GitState() (GitState, bool) { return f.state, true }
+
+func TestServer_MarkGit(t *testing.T) {
+	git := &FakeGitStateReporter{FakeGitBranchWatcher: NewFakeGitBranchWatcher(), state: GitState{Branch: "main", Commit: "aaaaaaa"}}
+	w := NewWatcher(WatcherConfig{WorkspacePath: "/ws"}, WatcherCallbacks{}, NewFakeFSWatcher(), git)
+	s := NewServer(testSocketPath(t), NewRunner("/ws", WithExecutor(NewFakeExecutor("", ""))))
+	s.SetWatcher(w)
+
+	before := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	git.state = GitState{Branch: "feature", Commit: "bbbbbbb"}
+	w.gitChanged("Git HEAD changed (branch switch)")
+	after := time.Now()
+	defer w.Close()
+
+	for _, tt := range []struct {
+		at   time.Time
+		want string
+	}{
+		{before.Add(-time.Hour), ""},
+		{before, "main@aaaaaaa"},
+		{after, "feature@bbbbbbb"},
+	} {
+		result := SvelteWatchCheckComplete{Timestamp: tt.at.UnixMilli()}
+		s.markGit(&result)
+		got := ""
+		if result.Git != nil {
+			got = result.Git.String()
+		}
+		if got != tt.want {
+			t.Errorf("Git of a result at %v = %q, want %q", tt.at, got, tt.want)
+		}
+	}
+
+	if status := s.Status(); status.Git == nil || *status.Git != git.state {
+		t.Errorf("Status().Git = %v, want %v", status.Git, git.state)
+	}
+	if out := FormatStatus(s.Status()); !strings.Contains(out, "Git:        feature@bbbbbbb\n") {
+		t.Errorf("FormatStatus() = %q, want the git state", out)
+	}
+}
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// =============================================================================
+// Result History
+// =============================================================================
+
+// HistoryLimits bounds the completed results a Runner retains.
+type HistoryLimits struct {
+	// Size is how many results are kept.
+	Size int
+
+	// MaxDiagnostics caps the diagnostics held across all kept results, so a
+	// run of checks with thousands of errors cannot hold Size copies of them.
+	// The oldest results are dropped first; the latest is always kept.
+	MaxDiagnostics int
+}
+
+// DefaultHistoryLimits supplies each HistoryLimits field left zero.
+var DefaultHistoryLimits = HistoryLimits{
+	Size:           20,
+	MaxDiagnostics: 20_000,
+}
+
+// withDefaults returns a copy of the limits with zero fields resolved.
+func (l HistoryLimits) withDefaults() HistoryLimits {
+	l.Size = cmp.Or(l.Size, DefaultHistoryLimits.Size)
+	l.MaxDiagnostics = cmp.Or(l.MaxDiagnostics, DefaultHistoryLimits.MaxDiagnostics)
+	return l
+}
+
+// HistoryEntry is one completed check result.
+type HistoryEntry struct {
+	// Seq numbers completed checks from 1. It keeps increasing as old
+	// entries are dropped, so callers can ask for entries after one they saw.
+	Seq         int                      `json:"seq"`
+	CompletedAt time.Time                `json:"completedAt"`
+	Result      SvelteWatchCheckComplete `json:"result"`
+}
+
+// resultHistory is a bounded ring of completed results, oldest first.
+// It is not safe for concurrent use.
+type resultHistory struct {
+	limits      HistoryLimits
+	entries     []HistoryEntry
+	seq         int
+	diagnostics int // total diagnostics across entries
+}
+
+// add appends a result, dropping the oldest entries beyond the limits.
+func (h *resultHistory) add(result SvelteWatchCheckComplete) {
+	h.seq++
+	h.entries = append(h.entries, HistoryEntry{
+		Seq:         h.seq,
+		CompletedAt: time.UnixMilli(eventTime(result.Timestamp)),
+		Result:      result,
+	})
+	h.diagnostics += len(result.Diagnostics)
+
+	drop := 0
+	for len(h.entries)-drop > 1 &&
+		(len(h.entries)-drop > h.limits.Size || h.diagnostics > h.limits.MaxDiagnostics) {
+		h.diagnostics -= len(h.entries[drop].Result.Diagnostics)
+		drop++
+	}
+	if drop > 0 {
+		// Copy rather than reslice so dropped results can be collected.
+		h.entries = slices.Clone(h.entries[drop:])
+	}
+}
+
+// since returns the entries with Seq greater than seq, oldest first.
+func (h *resultHistory) since(seq int) []HistoryEntry {
+	i, _ := slices.BinarySearchFunc(h.entries, seq+1, func(e HistoryEntry, seq int) int {
+		return e.Seq - seq
+	})
+	return slices.Clone(h.entries[i:])
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+)
+
+// seqs returns the Seq of each entry.
+func seqs(entries []HistoryEntry) []int {
+	var s []int
+	for _, e := range entries {
+		s = append(s, e.Seq)
+	}
+	return s
+}
+
+// TestResultHistory_Size tests that the oldest results are dropped beyond Size.
+func TestResultHistory_Size(t *testing.T) {
+	h := resultHistory{limits: HistoryLimits{Size: 3, MaxDiagnostics: 100}}
+	for i := range 5 {
+		h.add(SvelteWatchCheckComplete{Timestamp: int64(1000 + i), ErrorCount: i})
+	}
+
+	got := h.since(0)
+	if want := []int{3, 4, 5}; !slices.Equal(seqs(got), want) {
+		t.Fatalf("seqs = %v, want %v", seqs(got), want)
+	}
+	if got[0].Result.ErrorCount != 2 {
+		t.Errorf("oldest ErrorCount = %d, want 2", got[0].Result.ErrorCount)
+	}
+	if got[2].CompletedAt.UnixMilli() != 1004 {
+		t.Errorf("newest CompletedAt = %v, want 1004ms", got[2].CompletedAt.UnixMilli())
+	}
+}
+
+// TestResultHistory_MaxDiagnostics tests that results are dropped to stay
+// under the diagnostics cap, but the latest is always kept.
+func TestResultHistory_MaxDiagnostics(t *testing.T) {
+	h := resultHistory{limits: HistoryLimits{Size: 10, MaxDiagnostics: 5}}
+	h.add(SvelteWatchCheckComplete{Diagnostics: make([]Diagnostic, 2)})
+	h.add(SvelteWatchCheckComplete{Diagnostics: make([]Diagnostic, 2)})
+	h.add(SvelteWatchCheckComplete{Diagnostics: make([]Diagnostic, 3)})
+
+	if got, want := seqs(h.since(0)), []int{2, 3}; !slices.Equal(got, want) {
+		t.Errorf("seqs = %v, want %v", got, want)
+	}
+
+	h.add(SvelteWatchCheckComplete{Diagnostics: make([]Diagnostic, 8)})
+	if got, want := seqs(h.since(0)), []int{4}; !slices.Equal(got, want) {
+		t.Errorf("seqs after oversized result = %v, want %v", got, want)
+	}
+}
+
+// TestResultHistory_Since tests fetching entries after a known Seq.
+func TestResultHistory_Since(t *testing.T) {
+	h := resultHistory{limits: HistoryLimits{Size: 3, MaxDiagnostics: 100}}
+	for range 5 {
+		h.add(SvelteWatchCheckComplete{})
+	}
+
+	for _, tt := range []struct {
+		seq  int
+		want []int
+	}{
+		{0, []int{3, 4, 5}},
+		{3, []int{4, 5}},
+		{5, nil},
+	} {
+		if got := seqs(h.since(tt.seq)); !slices.Equal(got, tt.want) {
+			t.Errorf("since(%d) = %v, want %v", tt.seq, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryLimits_WithDefaults(t *testing.T) {
+	for _, tc := range []struct {
+		limits, want HistoryLimits
+	}{
+		{HistoryLimits{}, DefaultHistoryLimits},
+		{HistoryLimits{Size: 5}, HistoryLimits{Size: 5, MaxDiagnostics: DefaultHistoryLimits.MaxDiagnostics}},
+		{HistoryLimits{MaxDiagnostics: 50}, HistoryLimits{Size: DefaultHistoryLimits.Size, MaxDiagnostics: 50}},
+		{HistoryLimits{Size: 5, MaxDiagnostics: 50}, HistoryLimits{Size: 5, MaxDiagnostics: 50}},
+	} {
+		if got := tc.limits.withDefaults(); got != tc.want {
+			t.Errorf("%+v.withDefaults() = %+v, want %+v", tc.limits, got, tc.want)
+		}
+	}
+}
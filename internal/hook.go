@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// Pre-commit Hook
+// =============================================================================
+
+// hookExtensions are the extensions of files a check can report on. A commit
+// staging none of them, e.g. one touching only docs, is not checked.
+var hookExtensions = map[string]bool{
+	".svelte": true,
+	".ts":     true,
+	".mts":    true,
+	".cts":    true,
+	".tsx":    true,
+	".js":     true,
+	".mjs":    true,
+	".cjs":    true,
+	".jsx":    true,
+}
+
+// stagedFiles returns the workspace-relative paths of the files added,
+// copied, modified, or renamed in the git index.
+func stagedFiles(executor kexec.Interface, workspace string) ([]string, error) {
+	cmd := executor.Command("git", "diff", "--cached", "--name-only", "--relative", "--diff-filter=ACMR", "-z")
+	cmd.SetDir(workspace)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff --cached: %w", err)
+	}
+	var files []string
+	for name := range bytes.SplitSeq(out, []byte{0}) {
+		if len(name) > 0 {
+			files = append(files, string(name))
+		}
+	}
+	return files, nil
+}
+
+// workspaceFiles makes paths, relative to dir as a hook receives them,
+// relative to workspace, as diagnostics' filenames are.
+func workspaceFiles(workspace, dir string, paths []string) []string {
+	files := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		if rel, err := filepath.Rel(workspace, p); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+	}
+	return files
+}
+
+// checkable reports whether any of files can have diagnostics.
+func checkable(files []string) bool {
+	for _, f := range files {
+		if hookExtensions[filepath.Ext(f)] {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyFiles removes the diagnostics outside files from result, narrowing its
+// counts alike. Failures, which belong to no file, are kept.
+func onlyFiles(result SvelteWatchCheckComplete, files []string) SvelteWatchCheckComplete {
+	keep := make(map[string]bool, len(files))
+	for _, f := range files {
+		keep[f] = true
+	}
+	result, _ = filterResult(result, result, func(diags []Diagnostic) []bool {
+		removed := make([]bool, len(diags))
+		for i, d := range diags {
+			removed[i] = !keep[d.Filename]
+		}
+		return removed
+	})
+	return result
+}
+
+// FormatQuickfix formats result's diagnostics one per line as
+// "file:line:col: type: message", which editors' quickfix lists and most
+// CI log parsers read, followed by its failures and a summary line.
+func FormatQuickfix(result SvelteWatchCheckComplete) string {
+	var sb strings.Builder
+	for _, d := range result.Diagnostics {
+		fmt.Fprintf(&sb, "%s:%d:%d: %s: %s\n", d.Filename, d.Start.Line+1, d.Start.Character+1,
+			strings.ToLower(d.Type), strings.ReplaceAll(d.Message, "\n", " "))
+	}
+	for _, f := range result.Failures {
+		fmt.Fprintf(&sb, "svelte-check failure: %s\n", f)
+	}
+	fmt.Fprintf(&sb, "svelte-check: %d errors, %d warnings\n", result.ErrorCount, result.WarningCount)
+	return sb.String()
+}
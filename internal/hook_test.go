@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestStagedFiles(t *testing.T) {
+	executor := NewFakeExecutor("src/App.svelte\x00src/lib/a b.ts\x00", "")
+	files, err := stagedFiles(executor, "/workspace")
+	if err != nil {
+		t.Fatalf("stagedFiles failed: %v", err)
+	}
+	if want := []string{"src/App.svelte", "src/lib/a b.ts"}; !slices.Equal(files, want) {
+		t.Errorf("stagedFiles() = %q, want %q", files, want)
+	}
+	if got := executor.commandLine(); got != "git diff --cached --name-only --relative --diff-filter=ACMR -z" {
+		t.Errorf("command = %q", got)
+	}
+}
+
+func TestWorkspaceFiles(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	got := workspaceFiles(filepath.Join(root, "apps/web"), root, []string{"apps/web/src/App.svelte", filepath.Join(root, "apps/web/src/a.ts")})
+	if want := []string{"src/App.svelte", "src/a.ts"}; !slices.Equal(got, want) {
+		t.Errorf("workspaceFiles() = %q, want %q", got, want)
+	}
+	if checkable([]string{"README.md", "package.json"}) || !checkable([]string{"README.md", "src/a.mts"}) {
+		t.Error("checkable() misjudged source files")
+	}
+}
+
+// TestOnlyFiles tests that a hook counts only the diagnostics of its files.
+func TestOnlyFiles(t *testing.T) {
+	result := SvelteWatchCheckComplete{
+		ErrorCount: 2, WarningCount: 1, FilesWithProblems: 2,
+		Diagnostics: []Diagnostic{
+			{Type: "ERROR", Filename: "src/a.ts", Message: "Staged error"},
+			{Type: "ERROR", Filename: "src/b.ts", Message: "Unstaged error"},
+			{Type: "WARNING", Filename: "src/a.ts", Message: "Staged warning"},
+		},
+		Failures: []string{"language server crashed"},
+	}
+	got := onlyFiles(result, []string{"src/a.ts"})
+	if got.ErrorCount != 1 || got.WarningCount != 1 || got.FilesWithProblems != 1 || len(got.Diagnostics) != 2 || len(got.Failures) != 1 {
+		t.Errorf("onlyFiles() = %+v, want src/a.ts and the failure", got)
+	}
+
+	want := "src/a.ts:3:5: error: Type 'string' is not assignable\nsvelte-check failure: boom\nsvelte-check: 1 errors, 0 warnings\n"
+	out := FormatQuickfix(SvelteWatchCheckComplete{
+		ErrorCount:  1,
+		Diagnostics: []Diagnostic{{Type: "ERROR", Filename: "src/a.ts", Start: Position{Line: 2, Character: 4}, Message: "Type 'string'\nis not assignable"}},
+		Failures:    []string{"boom"},
+	})
+	if out != want {
+		t.Errorf("FormatQuickfix() = %q, want %q", out, want)
+	}
+}
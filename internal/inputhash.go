@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// Input Hashes
+// =============================================================================
+
+// InputHash returns a digest of the files a check of dir reads: the paths,
+// relative to dir, of the files under it that git does not ignore, tracked
+// or not, and the git blob hashes of their contents in the working tree. It
+// is the same on any machine with the same files, so a build cache such as
+// turborepo's or nx's can record a passing check under it and skip the
+// next. Files outside dir, such as a monorepo's shared packages or root
+// tsconfig, are not covered; a cache must key on those itself.
+func InputHash(executor kexec.Interface, dir string) (string, error) {
+	cmd := executor.Command("git", "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	cmd.SetDir(dir)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git ls-files: %w", err)
+	}
+	var files []string
+	for name := range bytes.SplitSeq(out, []byte{0}) {
+		file := string(name)
+		if file == "" {
+			continue
+		}
+		if strings.Contains(file, "\n") {
+			// hash-object reads one path per line.
+			return "", fmt.Errorf("cannot hash %q: its name contains a newline", file)
+		}
+		// Tracked files deleted in the working tree are listed, as are
+		// submodules.
+		if info, err := os.Stat(filepath.Join(dir, file)); err != nil || info.IsDir() {
+			continue
+		}
+		files = append(files, file)
+	}
+	slices.Sort(files)
+	files = slices.Compact(files) // a conflicted file is listed per stage
+
+	var blobs []string
+	if len(files) > 0 {
+		cmd = executor.Command("git", "hash-object", "--stdin-paths")
+		cmd.SetDir(dir)
+		cmd.SetStdin(strings.NewReader(strings.Join(files, "\n") + "\n"))
+		out, err = cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("git hash-object: %w", err)
+		}
+		blobs = strings.Fields(string(out))
+		if len(blobs) != len(files) {
+			return "", fmt.Errorf("git hash-object: %d hashes for %d files", len(blobs), len(files))
+		}
+	}
+
+	h := sha256.New()
+	for i, file := range files {
+		fmt.Fprintf(h, "%s\x00%s\n", file, blobs[i])
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// inputDir returns the directory whose inputs a check of the named project
+// reads: the project's, or the workspace's when project is empty or unknown.
+func inputDir(workspace string, projects []ProjectConfig, project string) string {
+	for _, p := range projects {
+		if project != "" && p.Name == project {
+			return filepath.Join(workspace, p.Dir)
+		}
+	}
+	return workspace
+}
+
+// withInputHash adds hash to output, a check result printed in format: as
+// an "inputHash" field of the JSON object, or as a final line.
+func withInputHash(output, format, hash string) string {
+	if format == "json" {
+		trimmed := strings.TrimRight(output, "\n")
+		if body, ok := strings.CutSuffix(trimmed, "}"); ok {
+			if !strings.HasSuffix(strings.TrimSpace(body), "{") {
+				body += ","
+			}
+			return fmt.Sprintf("%s%q:%q}\n", body, "inputHash", hash)
+		}
+		return output
+	}
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		output += "\n"
+	}
+	return output + "Input hash: " + hash + "\n"
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestInputHash tests that the hash covers the files git does not ignore,
+// tracked or not, and nothing else.
+func TestInputHash(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	write(".gitignore", ".svelte-kit/\n")
+	write("src/app.ts", "export {}\n")
+	hash := func() string {
+		t.Helper()
+		h, err := InputHash(NewExecutor(), dir)
+		if err != nil {
+			t.Fatalf("InputHash failed: %v", err)
+		}
+		return h
+	}
+
+	first := hash()
+	if first != hash() {
+		t.Error("InputHash is not deterministic")
+	}
+	write(".svelte-kit/types.d.ts", "generated")
+	if hash() != first {
+		t.Error("an ignored file changed the hash")
+	}
+	write("src/app.ts", "export const x = 1\n")
+	if hash() == first {
+		t.Error("a changed file did not change the hash")
+	}
+	write("src/app.ts", "export {}\n")
+	write("src/new.ts", "")
+	if hash() == first {
+		t.Error("a new file did not change the hash")
+	}
+
+	// hash-object cannot be given a name containing a newline.
+	write("src/odd\nname.ts", "")
+	if _, err := InputHash(NewExecutor(), dir); err == nil {
+		t.Error("InputHash with a newline in a file name succeeded")
+	}
+}
+
+func TestWithInputHash(t *testing.T) {
+	for _, tc := range []struct {
+		output, format, want string
+	}{
+		{`{"errorCount":0}` + "\n", "json", `{"errorCount":0,"inputHash":"sha256:ab"}` + "\n"},
+		{"{}", "json", `{"inputHash":"sha256:ab"}` + "\n"},
+		{"0 errors", "human", "0 errors\nInput hash: sha256:ab\n"},
+	} {
+		if got := withInputHash(tc.output, tc.format, "sha256:ab"); got != tc.want {
+			t.Errorf("withInputHash(%q, %s) = %q, want %q", tc.output, tc.format, got, tc.want)
+		}
+	}
+}
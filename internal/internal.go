@@ -2,23 +2,30 @@
 package internal
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"maps"
+	"math"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
-	"github.com/tylergannon/go-signal"
+	"github.com/tylergannon/svelte-check-server/pkg/types"
 	kexec "k8s.io/utils/exec"
 )
 
@@ -26,39 +33,69 @@ import (
 // Watcher Limit
 // =============================================================================
 
-// MaxWatchers is the global limit on the number of filesystem watchers.
-// If this limit is exceeded, creating new watchers will fail with ErrTooManyWatchers.
-// This prevents misconfiguration from exhausting OS resources.
-const MaxWatchers = 100
+// DefaultMaxWatchers is the default limit on the number of filesystem
+// watchers open at once. If it is exceeded, creating new watchers fails with
+// ErrTooManyWatchers. This prevents misconfiguration from exhausting OS
+// resources.
+const DefaultMaxWatchers = 100
 
-// globalWatcherCount tracks the number of active filesystem watchers.
-var globalWatcherCount atomic.Int32
-
-// ErrTooManyWatchers is returned when attempting to create a watcher would exceed MaxWatchers.
+// ErrTooManyWatchers is returned when attempting to create a watcher would
+// exceed its WatcherLimit.
 var ErrTooManyWatchers = errors.New("too many filesystem watchers: limit exceeded")
 
-// WatcherCount returns the current number of active watchers.
-func WatcherCount() int32 {
-	return globalWatcherCount.Load()
+// WatcherLimit caps the number of filesystem watchers open at once within
+// one scope, such as a daemon, so that several daemons in one process do
+// not share a budget. It is safe for concurrent use.
+type WatcherLimit struct {
+	max   int32
+	count atomic.Int32
+}
+
+// NewWatcherLimit creates a WatcherLimit of max watchers, or
+// DefaultMaxWatchers if max is not positive.
+func NewWatcherLimit(max int) *WatcherLimit {
+	if max <= 0 {
+		max = DefaultMaxWatchers
+	}
+	return &WatcherLimit{max: int32(min(max, math.MaxInt32))}
+}
+
+// defaultWatcherLimit covers the watchers created without a WatcherLimit.
+var defaultWatcherLimit = NewWatcherLimit(DefaultMaxWatchers)
+
+// Count returns the number of open watchers.
+func (l *WatcherLimit) Count() int {
+	return int(l.count.Load())
 }
 
-// acquireWatcher attempts to increment the watcher count.
+// Max returns the limit.
+func (l *WatcherLimit) Max() int {
+	return int(l.max)
+}
+
+// acquire attempts to increment the watcher count.
 // Returns an error if the limit would be exceeded.
-func acquireWatcher() error {
+func (l *WatcherLimit) acquire() error {
 	for {
-		current := globalWatcherCount.Load()
-		if current >= MaxWatchers {
-			return ErrTooManyWatchers
+		current := l.count.Load()
+		if current >= l.max {
+			return fmt.Errorf("%w (%d in use)", ErrTooManyWatchers, current)
 		}
-		if globalWatcherCount.CompareAndSwap(current, current+1) {
+		if l.count.CompareAndSwap(current, current+1) {
 			return nil
 		}
 	}
 }
 
-// releaseWatcher decrements the watcher count.
-func releaseWatcher() {
-	globalWatcherCount.Add(-1)
+// release decrements the watcher count.
+func (l *WatcherLimit) release() {
+	l.count.Add(-1)
+}
+
+// WatcherCount returns the current number of active watchers created without
+// a WatcherLimit.
+func WatcherCount() int32 {
+	return int32(defaultWatcherLimit.Count())
 }
 
 // =============================================================================
@@ -81,6 +118,41 @@ func SocketPathForWorkspace(workspacePath string) (string, error) {
 	return filepath.Join(os.TempDir(), slug+"-svelte-check.sock"), nil
 }
 
+// workspaceMarkers are files at the root of a workspace.
+var workspaceMarkers = append([]string{ConfigFileName, "package.json"}, svelteConfigFiles...)
+
+// FindWorkspace returns the workspace that dir belongs to, so commands run
+// in a subdirectory reach the daemon started at the root: the nearest of dir
+// and its ancestors with a server socket, else the nearest with a config
+// file, svelte.config, or package.json, else dir itself. A socket anywhere
+// up the tree wins over a nearer marker, so packages of a monorepo served
+// from its root find that server.
+func FindWorkspace(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	ancestors := []string{dir}
+	for d := dir; filepath.Dir(d) != d; {
+		d = filepath.Dir(d)
+		ancestors = append(ancestors, d)
+	}
+
+	for _, d := range ancestors {
+		if socketPath, err := SocketPathForWorkspace(d); err == nil && SocketExists(socketPath) {
+			return d, nil
+		}
+	}
+	for _, d := range ancestors {
+		for _, marker := range workspaceMarkers {
+			if _, err := os.Stat(filepath.Join(d, marker)); err == nil {
+				return d, nil
+			}
+		}
+	}
+	return dir, nil
+}
+
 // SocketExists checks if a socket file exists at the given path.
 func SocketExists(socketPath string) bool {
 	_, err := os.Stat(socketPath)
@@ -91,136 +163,942 @@ func SocketExists(socketPath string) bool {
 // Runner
 // =============================================================================
 
+// RunnerConfig holds Runner configuration.
+type RunnerConfig struct {
+	WorkspacePath  string
+	TsconfigPath   string
+	PackageManager PackageManager // detected from lockfiles when empty
+
+	// Checker selects the watch-mode tool: svelte-check (the default) or tsc.
+	Checker CheckerKind
+
+	// Command, when set, replaces the svelte-check invocation entirely.
+	// The watch command must produce --output machine-verbose for parsing.
+	Command []string
+
+	// ExtraArgs are appended to every svelte-check invocation, watch or one-shot.
+	ExtraArgs []string
+
+	// RestartPolicy governs automatic restarts after a crash.
+	// The zero value means DefaultRestartPolicy.
+	RestartPolicy RestartPolicy
+
+	// Resources configures memory and CPU sampling of the svelte-check process.
+	// The zero value disables monitoring.
+	Resources ResourceLimits
+
+	// Env controls the environment of spawned processes. The zero value
+	// inherits the daemon's environment.
+	Env EnvConfig
+
+	// StopPolicy sets the signals and grace periods used to stop the process.
+	// The zero value means DefaultStopPolicy.
+	StopPolicy StopPolicy
+
+	// History bounds the completed results retained for History.
+	// Zero fields mean those of DefaultHistoryLimits.
+	History HistoryLimits
+
+	// StateFile, when set, is where each completed result is saved. A result
+	// saved by a previous daemon is served by PeekLatest until the first
+	// check completes.
+	StateFile string
+
+	// Audit, if set, records completed checks, crashes, and the restarts
+	// the checker makes itself.
+	Audit *AuditLog
+
+	// Tracer, if set, records a span for each check cycle; see
+	// Runner.TraceCycle.
+	Tracer *Tracer
+
+	// CrashDir, when set, is where a report is written for each panic in
+	// the Runner's goroutines; see PanicReport.
+	CrashDir string
+}
+
+// withDefaults returns a copy of the config with unset fields resolved.
+func (c RunnerConfig) withDefaults() RunnerConfig {
+	if c.PackageManager == "" {
+		c.PackageManager = DetectPackageManager(c.WorkspacePath)
+	}
+	if c.RestartPolicy == (RestartPolicy{}) {
+		c.RestartPolicy = DefaultRestartPolicy
+	}
+	if c.Checker == "" {
+		c.Checker = CheckerSvelteCheck
+	}
+	if c.StopPolicy == (StopPolicy{}) {
+		c.StopPolicy = DefaultStopPolicy
+	}
+	c.History = c.History.withDefaults()
+	return c
+}
+
+// interpreter returns the output parser for the configured checker.
+func (c RunnerConfig) interpreter() func(context.Context, io.Reader, chan<- SvelteCheckEvent) error {
+	if c.Checker == CheckerTsc {
+		return InterpretTscOutput
+	}
+	return InterpretOutput
+}
+
+// command returns the program and arguments for the configured checker.
+// In watch mode, machine-readable output is requested for the interpreter.
+func (c RunnerConfig) command(watch bool) (string, []string) {
+	if c.Checker == CheckerTsc {
+		args := []string{"--noEmit", "--pretty", "false"}
+		if watch {
+			args = append(args, "--watch", "--preserveWatchOutput")
+		}
+		if c.TsconfigPath != "" {
+			args = append(args, "--project", c.TsconfigPath)
+		}
+		return c.PackageManager.Command("tsc", args...)
+	}
+
+	if len(c.Command) > 0 {
+		args := c.Command[1:]
+		if !watch {
+			args = oneShotArgs(args)
+		}
+		return c.Command[0], append(slices.Clone(args), c.ExtraArgs...)
+	}
+
+	var args []string
+	if watch {
+		args = append(args, "--watch", "--output", "machine-verbose")
+	}
+	if c.TsconfigPath != "" {
+		args = append(args, "--tsconfig", c.TsconfigPath)
+	}
+	args = append(args, c.ExtraArgs...)
+	return c.PackageManager.Command("svelte-check", args...)
+}
+
+// oneShotArgs strips watch-mode flags from a custom command so it can be used
+// for a single human-readable run.
+func oneShotArgs(args []string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--watch":
+		case args[i] == "--output":
+			i++ // skip the value too
+		case strings.HasPrefix(args[i], "--output="):
+		default:
+			out = append(out, args[i])
+		}
+	}
+	return out
+}
+
+// RestartPolicy controls automatic restarts when svelte-check exits unexpectedly.
+// Delays grow exponentially from InitialBackoff up to MaxBackoff. After
+// MaxRetries consecutive crashes without a completed check, the Runner gives up.
+type RestartPolicy struct {
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	MaxRetries     int // negative disables automatic restarts
+}
+
+// DefaultRestartPolicy is used when RunnerConfig.RestartPolicy is the zero value.
+var DefaultRestartPolicy = RestartPolicy{
+	InitialBackoff: 1 * time.Second,
+	MaxBackoff:     30 * time.Second,
+	MaxRetries:     5,
+}
+
+// backoff returns the delay before restart attempt n (1-based).
+func (p RestartPolicy) backoff(n int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < n && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxBackoff)
+}
+
+// RunnerState describes the lifecycle state of the svelte-check process.
+type RunnerState string
+
+const (
+	RunnerStateStopped  RunnerState = "stopped"  // not started, or stopped intentionally
+	RunnerStateStarting RunnerState = "starting" // process started, no check cycle seen yet
+	RunnerStateChecking RunnerState = "checking" // check cycle in progress
+	RunnerStateReady    RunnerState = "ready"    // latest result is current
+	RunnerStateDegraded RunnerState = "degraded" // process exited unexpectedly; restart pending
+	RunnerStateFailed   RunnerState = "failed"   // gave up restarting after MaxRetries crashes
+)
+
+// RunnerStatus is a snapshot of the Runner's health, served by GET /status.
+type RunnerStatus struct {
+	State              RunnerState     `json:"state"`
+	PackageManager     PackageManager  `json:"packageManager"`
+	AutoRestarts       int             `json:"autoRestarts"`
+	ConsecutiveCrashes int             `json:"consecutiveCrashes"`
+	LastExit           string          `json:"lastExit,omitempty"`
+	LastExitAt         time.Time       `json:"lastExitAt,omitzero"`
+	NextRestartAt      time.Time       `json:"nextRestartAt,omitzero"`
+	LastCrash          *CrashReport    `json:"lastCrash,omitempty"`
+	Panics             int             `json:"panics,omitempty"`    // panics recovered in the checker's goroutines
+	LastPanic          *PanicReport    `json:"lastPanic,omitempty"` // the most recent of them
+	Resources          *ResourceUsage  `json:"resources,omitempty"` // latest sample of the running process
+	MemoryLimitBytes   int64           `json:"memoryLimitBytes,omitempty"`
+	MemoryRestarts     int             `json:"memoryRestarts"`
+	Checkers           []CheckerStatus `json:"checkers,omitempty"` // per-checker detail for a CheckerSet
+	Timings            *CheckTimings   `json:"timings,omitempty"`  // nil before the first completed check
+	LastCycle          *CycleTimeline  `json:"lastCycle,omitempty"`
+}
+
 // Runner manages a svelte-check --watch process.
 type Runner struct {
 	workspacePath string
 	tsconfigPath  string
+	config        RunnerConfig
 	executor      kexec.Interface
-	cmd           kexec.Cmd
+	logger        *slog.Logger
+
+	// restartMu serializes Restart and Stop. It is acquired before mu.
+	restartMu sync.Mutex
+
+	mu   sync.Mutex
+	ctx  context.Context // context passed to Start, reused for automatic restarts
+	cmd  kexec.Cmd
+	done chan struct{} // closed when cmd has exited and its events are handled
+
+	// cancelInterpret stops the current process's interpreter.
+	cancelInterpret context.CancelFunc
+
+	// ready is closed when the current process reports its first check
+	// starting; output captures that process's output for startup errors.
+	ready  chan struct{}
+	output *outputCapture
+
+	// generation is incremented whenever a process is started or stopped on
+	// purpose. A Wait goroutine whose generation is stale knows the exit was
+	// intentional and does not count it as a crash.
+	generation   int
+	state        RunnerState
+	restartTimer *time.Timer
+	autoRestarts int
+	crashes      int // consecutive crashes since the last completed check
+	lastExit     string
+	lastExitAt   time.Time
+	lastCrash    *CrashReport
+	nextRestart  time.Time
+	panics       int
+	lastPanic    *PanicReport
+
+	// pendingRestart is the Restart that new calls join: one that has not
+	// yet started its new process. Nil when none is.
+	pendingRestart *restartCall
+
+	// cycle is the span of the check cycle in progress, from its trigger to
+	// its result; nil when none is or tracing is off.
+	cycle *Span
+
+	// Resource monitoring. listProcesses is replaced in tests.
+	listProcesses  processLister
+	resources      *ResourceUsage
+	memoryRestarts int
+
+	timer   checkTimer
+	history resultHistory
+
+	// trigger is the cause of the next process start, set by Restart;
+	// timeline is the cycle in progress and lastCycle the last completed.
+	trigger   CycleTrigger
+	timeline  CycleTimeline
+	lastCycle *CycleTimeline
+
+	// store persists completed results; persisted is the result loaded from
+	// it at creation, nil if there was none.
+	store     resultStore
+	persisted *SvelteWatchCheckComplete
+
+	// events fans out the current process's events to Subscribe callers.
+	events eventHub
 
 	// Holds the latest completed check result.
 	// Readers block while a check is in progress.
-	latest *signal.Signal[SvelteWatchCheckComplete]
+	latest *valueSignal[SvelteWatchCheckComplete]
 }
 
 // NewRunner creates a new Runner for the given workspace.
-func NewRunner(workspacePath, tsconfigPath string, executor kexec.Interface) *Runner {
-	return &Runner{
-		workspacePath: workspacePath,
-		tsconfigPath:  tsconfigPath,
-		executor:      executor,
-		latest:        signal.New[SvelteWatchCheckComplete](),
+// The package manager is detected from the workspace's lockfiles.
+func NewRunner(workspacePath string, opts ...Option) *Runner {
+	return NewRunnerWithConfig(RunnerConfig{WorkspacePath: workspacePath}, opts...)
+}
+
+// NewRunnerWithConfig creates a new Runner from a RunnerConfig, with opts
+// applied over it.
+func NewRunnerWithConfig(config RunnerConfig, opts ...Option) *Runner {
+	o := newOptions(opts)
+	if o.tsconfig != "" {
+		config.TsconfigPath = o.tsconfig
+	}
+	config.ExtraArgs = append(slices.Clip(config.ExtraArgs), o.checkerArgs...)
+	if o.executor == nil {
+		o.executor = NewExecutor()
 	}
+	config = config.withDefaults()
+	r := &Runner{
+		workspacePath: config.WorkspacePath,
+		tsconfigPath:  config.TsconfigPath,
+		config:        config,
+		executor:      o.executor,
+		state:         RunnerStateStopped,
+		listProcesses: listProcesses,
+		history:       resultHistory{limits: config.History},
+		store:         resultStore{path: config.StateFile, workspace: config.WorkspacePath},
+		latest:        newValueSignal[SvelteWatchCheckComplete](),
+	}
+	r.logger = o.logger.With("subsystem", "runner", "checker", r.Name(), "workspace", config.WorkspacePath)
+	if result, ok := r.store.load(); ok {
+		r.persisted = &result
+	}
+	return r
+}
+
+// Name returns the checker this Runner supervises, e.g. "svelte-check".
+func (r *Runner) Name() string {
+	return string(r.config.Checker)
+}
+
+// PackageManager returns the package manager used to invoke svelte-check.
+func (r *Runner) PackageManager() PackageManager {
+	return r.config.PackageManager
 }
 
 // Start begins the svelte-check --watch process.
 func (r *Runner) Start(ctx context.Context) error {
-	args := []string{"run", "svelte-check", "--watch", "--output", "machine-verbose"}
-	if r.tsconfigPath != "" {
-		args = append(args, "--tsconfig", r.tsconfigPath)
-	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	r.cmd = r.executor.CommandContext(ctx, "bun", args...)
-	r.cmd.SetDir(r.workspacePath)
+	r.ctx = ctx
+	return r.startLocked()
+}
 
-	stdout, err := r.cmd.StdoutPipe()
-	if err != nil {
-		return err
-	}
+// startLocked starts a new svelte-check process. r.mu must be held.
+func (r *Runner) startLocked() error {
+	name, args := r.config.command(true)
 
-	stderr, err := r.cmd.StderrPipe()
-	if err != nil {
+	cmd := r.executor.CommandContext(r.ctx, name, args...)
+	cmd.SetDir(r.workspacePath)
+	r.config.Env.apply(cmd)
+	setProcessGroup(cmd, r.config.StopPolicy)
+
+	// Attach writers rather than pipes: Wait then returns only after all output
+	// has been copied, so crash reports see the process's final lines, and
+	// stderr is drained concurrently instead of after stdout closes.
+	capture, combined := newOutputCapture()
+	cmd.SetStdout(capture.Stdout())
+	cmd.SetStderr(capture.Stderr())
+
+	if err := cmd.Start(); err != nil {
+		capture.Close()
 		return err
 	}
 
-	if err := r.cmd.Start(); err != nil {
-		return err
-	}
+	r.cmd = cmd
+	r.output = capture
+	r.timeline, r.trigger = newTimeline(r.trigger, r.generation == 0), CycleTrigger{}
+	r.generation++
+	generation := r.generation
+	r.state = RunnerStateStarting
+	r.nextRestart = time.Time{}
+	r.resources = nil
+
+	// done is closed once the process has exited and all of its output has
+	// been handled, so Restart never overlaps two processes or event streams.
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	ready := make(chan struct{})
+	r.done, r.ready = done, ready
 
 	// Wait for the process in a goroutine. This ensures ProcessState is populated
 	// when the process exits, which is required for kexec's Stop() to work correctly.
-	go func() {
-		_ = r.cmd.Wait()
-	}()
+	// It also lets us notice when svelte-check dies on its own.
+	wg.Go(func() { r.waitForExit(cmd, generation, capture) })
+
+	if pcmd, ok := cmd.(ProcessCmd); ok && r.config.Resources.Interval > 0 {
+		go r.monitorResources(pcmd.Pid(), generation, done)
+	}
 
-	// Combine stdout and stderr into a single reader for the interpreter
-	combined := io.MultiReader(stdout, stderr)
+	// Stopping the process also stops its interpreter, rather than leaving
+	// it to notice the output closing.
+	interpretCtx, cancelInterpret := context.WithCancel(r.ctx)
+	r.cancelInterpret = cancelInterpret
 	events := make(chan SvelteCheckEvent)
 
 	go func() {
-		if err := InterpretOutput(combined, events); err != nil {
-			log.Printf("Interpreter error: %v", err)
+		defer close(events)
+		defer cancelInterpret()
+		defer r.recoverPanic("interpreter", generation)
+		if err := r.config.interpreter()(interpretCtx, combined, events); err != nil && interpretCtx.Err() == nil {
+			// Nothing reads the checker's output any more, so its results
+			// would go stale; restart it as if it had crashed.
+			r.logger.Error("interpreter failed", "error", err)
+			r.mu.Lock()
+			if generation == r.generation && r.ctx.Err() == nil {
+				r.crashLocked("interpreter failed: " + err.Error())
+			}
+			r.mu.Unlock()
 		}
-		close(events)
+		// Keep draining so the child never blocks writing output.
+		_, _ = io.Copy(io.Discard, combined)
 	}()
 
-	go r.handleEvents(events)
+	wg.Go(func() { r.handleEvents(events, generation, ready) })
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
 
 	return nil
 }
 
-// Stop terminates the svelte-check process.
+// waitForExit waits for the process to exit. If nothing asked it to stop, the
+// exit is treated as a crash: a CrashReport is recorded from the captured
+// output and an automatic restart is scheduled.
+func (r *Runner) waitForExit(cmd kexec.Cmd, generation int, capture *outputCapture) {
+	defer r.recoverPanic("exit watcher", generation)
+	err := cmd.Wait()
+	capture.Close()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if generation != r.generation || r.ctx.Err() != nil {
+		return // stopped or replaced on purpose
+	}
+
+	r.crashes++
+	r.resources = nil
+	r.lastExit = describeExit(err)
+	r.lastExitAt = time.Now()
+	stderr, output := capture.Tails()
+	r.lastCrash = &CrashReport{
+		At:     r.lastExitAt,
+		Exit:   r.lastExit,
+		Stderr: stderr,
+		Output: output,
+	}
+	r.logger.Error("svelte-check exited unexpectedly", "exit", r.lastExit, "stderr", stderr)
+	r.config.Audit.Record("crash", map[string]any{"checker": r.Name(), "workspace": r.config.WorkspacePath, "exit": r.lastExit})
+	r.scheduleRestartLocked()
+}
+
+// crashLocked stops the current process, which is still running but no
+// longer usable, and restarts it as if it had crashed with the given exit
+// reason. r.mu must be held.
+func (r *Runner) crashLocked(reason string) {
+	r.stopLocked()
+	r.crashes++
+	r.resources = nil
+	r.lastExit = reason
+	r.lastExitAt = time.Now()
+	r.scheduleRestartLocked()
+}
+
+// scheduleRestartLocked schedules an automatic restart after r.crashes
+// consecutive crashes, with backoff, or gives up once RestartPolicy allows
+// no more. r.mu must be held.
+func (r *Runner) scheduleRestartLocked() {
+	generation := r.generation
+	policy := r.config.RestartPolicy
+	if policy.MaxRetries < 0 || r.crashes > policy.MaxRetries {
+		r.state = RunnerStateFailed
+		r.logger.Error("svelte-check keeps crashing, giving up on automatic restarts", "crashes", r.crashes)
+		return
+	}
+
+	delay := policy.backoff(r.crashes)
+	r.state = RunnerStateDegraded
+	r.nextRestart = time.Now().Add(delay)
+	r.logger.Warn("restarting svelte-check", "delay", delay, "attempt", r.crashes, "maxRetries", policy.MaxRetries)
+
+	r.restartTimer = time.AfterFunc(delay, func() {
+		defer r.recoverPanic("restart timer", generation)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if generation != r.generation || r.ctx.Err() != nil {
+			return // someone restarted or stopped us in the meantime
+		}
+		r.autoRestarts++
+		r.config.Audit.Record("restart", map[string]any{"reason": "crash", "checker": r.Name(), "workspace": r.config.WorkspacePath})
+		_, span := startSpan(r.traceCycleLocked(context.Background(), "crash"), "restart")
+		err := r.startLocked()
+		span.End(err)
+		if err != nil {
+			r.state = RunnerStateFailed
+			r.lastExit = fmt.Sprintf("restart failed: %v", err)
+			r.logger.Error("failed to restart svelte-check", "error", err)
+		}
+	})
+}
+
+// monitorResources samples the process tree rooted at pid every
+// Resources.Interval until the process exits, and restarts svelte-check if it
+// grows past Resources.MaxRSSBytes.
+func (r *Runner) monitorResources(pid, generation int, done <-chan struct{}) {
+	defer r.recoverPanic("resource monitor", generation)
+	limits := r.config.Resources
+	sampler := &resourceSampler{list: r.listProcesses}
+
+	ticker := time.NewTicker(limits.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		usage, err := sampler.sample(pid)
+		if err != nil {
+			r.logger.Warn("resource sampling failed", "error", err)
+			continue
+		}
+
+		r.mu.Lock()
+		if generation != r.generation {
+			r.mu.Unlock()
+			return
+		}
+		r.resources = &usage
+		exceeded := limits.MaxRSSBytes > 0 && usage.RSSBytes > limits.MaxRSSBytes
+		if exceeded {
+			r.memoryRestarts++
+		}
+		ctx := r.ctx
+		r.mu.Unlock()
+
+		if exceeded {
+			r.logger.Warn("svelte-check exceeded its memory limit, restarting",
+				"rssMiB", usage.RSSBytes>>20, "limitMiB", limits.MaxRSSBytes>>20)
+			r.config.Audit.Record("restart", map[string]any{"reason": "memory", "checker": r.Name(), "workspace": r.config.WorkspacePath, "rssBytes": usage.RSSBytes})
+			if err := r.Restart(ctx); err != nil {
+				r.logger.Error("failed to restart svelte-check", "error", err)
+			}
+			return
+		}
+	}
+}
+
+// describeExit renders a process exit error for logs and status output.
+func describeExit(err error) string {
+	if err == nil {
+		return "exit status 0"
+	}
+	if exitErr, ok := err.(kexec.ExitError); ok {
+		return fmt.Sprintf("exit status %d", exitErr.ExitStatus())
+	}
+	return err.Error()
+}
+
+// Stop terminates the svelte-check process. It does not wait for the process
+// to exit.
 func (r *Runner) Stop() {
+	r.restartMu.Lock()
+	defer r.restartMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stopLocked()
+	r.state = RunnerStateStopped
+	r.resources = nil
+	r.cycle.End(errors.New("stopped"))
+	r.cycle = nil
+}
+
+// stopLocked stops the current process and cancels any pending automatic
+// restart. r.mu must be held.
+func (r *Runner) stopLocked() {
+	r.generation++
+	if r.restartTimer != nil {
+		r.restartTimer.Stop()
+		r.restartTimer = nil
+	}
+	r.nextRestart = time.Time{}
+	if r.cancelInterpret != nil {
+		r.cancelInterpret()
+	}
 	if r.cmd != nil {
 		r.cmd.Stop()
 	}
 }
 
-// Restart stops and starts the svelte-check process.
+// restartCall is one stop/start cycle that concurrent Restart calls share.
+type restartCall struct {
+	done chan struct{} // closed when the cycle has finished
+	err  error
+}
+
+// Restart stops the svelte-check process, waits for it to exit, and starts a
+// new one. Restarts are single-flight: a call made while another restart has
+// not yet started its new process joins that restart, since the new process
+// will see whatever changed, and returns its result. Calls made later run one
+// more cycle after it, which further calls join in turn. Stop waits for an
+// in-flight Restart, so at most one process runs at a time. A CycleTrigger
+// in ctx (see ContextWithTrigger) begins the new cycle's timeline; of those
+// joining one restart, the earliest does.
+//
+// The new process runs under the context given to Start, as the old one did,
+// so it outlives the caller. ctx only carries the trigger and trace, and
+// bounds how long a call waits for a restart it joins.
 func (r *Runner) Restart(ctx context.Context) error {
-	r.Stop()
-	time.Sleep(100 * time.Millisecond)
+	r.mu.Lock()
+	if t, ok := triggerFrom(ctx); ok && r.trigger.Source == "" {
+		r.trigger = t
+	}
+	if call := r.pendingRestart; call != nil {
+		r.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	call := &restartCall{done: make(chan struct{})}
+	r.pendingRestart = call
+	r.mu.Unlock()
+
+	ctx, span := startSpan(r.TraceCycle(ctx, "restart"), "restart")
+	call.err = r.restart(ctx, call)
+	span.End(call.err)
+	close(call.done)
+	return call.err
+}
+
+// restart runs the stop/start cycle for call.
+func (r *Runner) restart(ctx context.Context, call *restartCall) error {
+	r.restartMu.Lock()
+	defer r.restartMu.Unlock()
+
+	r.mu.Lock()
+	r.stopLocked()
+	r.state = RunnerStateStopped
+	r.resources = nil
+	done := r.done
+	r.mu.Unlock()
+
+	if done != nil {
+		<-done
+	}
 
 	// Invalidate so readers block until the new check completes
 	r.latest.Invalidate()
 
-	return r.Start(ctx)
+	// From here on, a new request may come too late for the new process to
+	// see its change, so it must start a cycle of its own.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pendingRestart == call {
+		r.pendingRestart = nil
+	}
+
+	if r.ctx == nil {
+		// Never started: the restart starts it.
+		r.ctx = ctx
+	}
+	if err := r.ctx.Err(); err != nil {
+		return err // shut down while the old process stopped
+	}
+	return r.startLocked()
 }
 
 // GetLatestEvent blocks until a check is complete and returns the result.
-// If a check is in progress, this blocks until it completes.
-func (r *Runner) GetLatestEvent() SvelteWatchCheckComplete {
-	return r.latest.Get()
+// If a check is in progress, this blocks until it completes or ctx is done,
+// in which case it returns the context's error.
+func (r *Runner) GetLatestEvent(ctx context.Context) (SvelteWatchCheckComplete, error) {
+	return r.latest.Wait(ctx)
+}
+
+// PeekLatest returns the most recent completed result without blocking. While
+// a check is in progress or the process is down, the previous result is
+// returned marked stale. Before the first check completes, it returns the
+// result persisted by a previous daemon, if any, also marked stale.
+func (r *Runner) PeekLatest() (SvelteWatchCheckComplete, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(r.history.entries)
+	if n == 0 {
+		if r.persisted == nil {
+			return SvelteWatchCheckComplete{}, false
+		}
+		return *r.persisted, true
+	}
+	result := r.history.entries[n-1].Result
+	if r.state != RunnerStateReady {
+		result.Stale = true
+		result.StaleReason = fmt.Sprintf("%s is %s", r.Name(), r.state)
+	}
+	return result, true
+}
+
+// NotifyDependenciesChanged sends a DependenciesChanged event to subscribers.
+func (r *Runner) NotifyDependenciesChanged(lockfile string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events.publish(DependenciesChanged{Timestamp: time.Now().UnixMilli(), Lockfile: lockfile})
+}
+
+// Subscribe returns a channel receiving every event of the current
+// svelte-check process, across restarts, and a function that unsubscribes.
+// Events arrive after the Runner has applied them, so Status and
+// GetLatestEvent already reflect an event when it is received. A subscriber
+// that falls SubscriberBuffer events behind is unsubscribed and its channel
+// closed; it may subscribe again.
+func (r *Runner) Subscribe() (<-chan SvelteCheckEvent, func()) {
+	return r.events.subscribe()
+}
+
+// History returns the retained completed results, oldest first. It does not
+// block while a check is in progress.
+func (r *Runner) History() []HistoryEntry {
+	return r.HistorySince(0)
+}
+
+// HistorySince returns the retained results with Seq greater than seq,
+// oldest first.
+func (r *Runner) HistorySince(seq int) []HistoryEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.history.since(seq)
+}
+
+// Status returns a snapshot of the Runner's health.
+func (r *Runner) Status() RunnerStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return RunnerStatus{
+		State:              r.state,
+		PackageManager:     r.config.PackageManager,
+		AutoRestarts:       r.autoRestarts,
+		ConsecutiveCrashes: r.crashes,
+		LastExit:           r.lastExit,
+		LastExitAt:         r.lastExitAt,
+		NextRestartAt:      r.nextRestart,
+		LastCrash:          r.lastCrash,
+		Panics:             r.panics,
+		LastPanic:          r.lastPanic,
+		Resources:          r.resources,
+		MemoryLimitBytes:   r.config.Resources.MaxRSSBytes,
+		MemoryRestarts:     r.memoryRestarts,
+		Timings:            r.timer.summary(),
+		LastCycle:          r.lastCycle,
+	}
+}
+
+// LastCrash returns the report for the most recent unexpected exit, or nil if
+// svelte-check has not crashed.
+func (r *Runner) LastCrash() *CrashReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastCrash
+}
+
+// setState updates the lifecycle state from the event stream of the process
+// with the given generation. Events from a replaced process are ignored.
+func (r *Runner) setState(generation int, state RunnerState, resetCrashes bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if generation != r.generation {
+		return
+	}
+	r.state = state
+	if resetCrashes {
+		r.crashes = 0
+	}
+}
+
+// recordTiming feeds a START or COMPLETED event to the check timer and the
+// cycle's timeline, ignoring events from a replaced process.
+func (r *Runner) recordTiming(generation int, event SvelteCheckEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if generation != r.generation {
+		return
+	}
+	switch e := event.(type) {
+	case SvelteWatchCheckStart:
+		r.timer.start(e.Timestamp)
+		r.timeline.start(e.Timestamp)
+	case SvelteWatchCheckComplete:
+		r.timer.complete(e.Timestamp)
+		if r.timeline.complete(e) {
+			cycle := r.timeline
+			r.lastCycle = &cycle
+		}
+	}
+}
+
+// recordResult diffs a completed result against the previous one, or against
+// the persisted one before the first. It adds the result to the history only
+// if generation is current, and reports whether it did.
+func (r *Runner) recordResult(generation int, result SvelteWatchCheckComplete) (SvelteWatchCheckComplete, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if generation != r.generation {
+		return result, false
+	}
+	if n := len(r.history.entries); n > 0 {
+		result = withDiff(result, r.history.entries[n-1].Result)
+	} else if r.persisted != nil {
+		result = withDiff(result, *r.persisted)
+	}
+	r.history.add(result)
+	return result, true
+}
+
+// publish sends event to subscribers if generation is current.
+func (r *Runner) publish(generation int, event SvelteCheckEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if generation != r.generation {
+		return
+	}
+	r.events.publish(event)
 }
 
 // handleEvents processes events from the interpreter and updates the Signal.
-func (r *Runner) handleEvents(events <-chan SvelteCheckEvent) {
+// ready is closed on the first check event.
+func (r *Runner) handleEvents(events <-chan SvelteCheckEvent, generation int, ready chan struct{}) {
+	defer r.recoverPanic("event handler", generation)
+	var failures []string // FAILURE messages of the current cycle
+	var check int64       // the current cycle's start timestamp, identifying it in logs
+	var span *Span        // the current cycle's svelte-check span
+	defer func() { span.End(errors.New("interrupted")) }()
 	for event := range events {
+		select {
+		case <-ready:
+		default:
+			if _, ok := event.(SvelteWatchFailure); !ok {
+				close(ready)
+			}
+		}
+
 		switch e := event.(type) {
 		case SvelteWatchCheckStart:
+			failures = nil
 			r.latest.Invalidate()
-			log.Println("svelte-check started")
+			r.setState(generation, RunnerStateChecking, false)
+			r.recordTiming(generation, e)
+			check = e.Timestamp
+			r.logger.Info("svelte-check started", "check", check)
+			r.config.Audit.Record("check-start", map[string]any{"checker": r.Name(), "workspace": r.config.WorkspacePath, "check": check})
+			span.End(errors.New("interrupted"))
+			_, span = startSpan(r.TraceCycle(context.Background(), "watch"), r.Name(), "check", check)
 		case SvelteWatchCheckComplete:
+			e.Failures, failures = failures, nil
+			r.recordTiming(generation, e)
+			e, current := r.recordResult(generation, e)
+			event = e
 			r.latest.Set(e)
-			log.Printf("svelte-check completed: %d errors, %d warnings", e.ErrorCount, e.WarningCount)
+			r.setState(generation, RunnerStateReady, true)
+			r.logger.Info("svelte-check completed", "check", check, "errors", e.ErrorCount, "warnings", e.WarningCount, "files", e.FileCount)
+			span.SetAttributes("errors", e.ErrorCount, "warnings", e.WarningCount, "files", e.FileCount)
+			span.End(nil)
+			span = nil
+			if current {
+				r.endCycle(e)
+				r.store.save(e)
+				r.config.Audit.recordCheck(r.Name(), r.config.WorkspacePath, e)
+			}
 		case SvelteWatchFailure:
-			log.Printf("svelte-check failure: %s", e.Message)
+			failures = append(failures, e.Message)
+			r.logger.Error("svelte-check failure", "check", check, "message", e.Message)
+			r.config.Audit.Record("failure", map[string]any{"checker": r.Name(), "workspace": r.config.WorkspacePath, "check": check, "message": e.Message})
 		}
+		r.publish(generation, event)
 	}
 }
 
-// RunSvelteKitSync runs `bun run svelte-kit sync` to regenerate types.
-// This should be called when route files are created, deleted, or renamed.
-func RunSvelteKitSync(ctx context.Context, workspacePath string, executor kexec.Interface) error {
-	cmd := executor.CommandContext(ctx, "bun", "run", "svelte-kit", "sync")
-	cmd.SetDir(workspacePath)
+// StartupError reports a checker that failed to become ready: its process
+// exited, or no check started before the context was done.
+type StartupError struct {
+	Name   string
+	Reason string   // e.g. "exited before starting a check (exit status 1)"
+	Stderr []string // tail of stderr
+	Output []string // tail of combined stdout and stderr
+}
+
+func (e *StartupError) Error() string {
+	return fmt.Sprintf("%s %s", e.Name, e.Reason)
+}
+
+// WaitReady waits until the current process starts its first check. It
+// returns a *StartupError with the captured output if the process exits first
+// or ctx is done first, so a missing dependency or a bad tsconfig fails fast
+// instead of leaving /check blocked.
+func (r *Runner) WaitReady(ctx context.Context) error {
+	r.mu.Lock()
+	ready, done, output := r.ready, r.done, r.output
+	r.mu.Unlock()
+
+	if ready == nil {
+		return &StartupError{Name: r.Name(), Reason: "was not started"}
+	}
+
+	var reason string
+	select {
+	case <-ready:
+		return nil
+	case <-done:
+		select {
+		case <-ready:
+			return nil // exited after starting a check; crash handling takes over
+		default:
+		}
+		r.mu.Lock()
+		reason = fmt.Sprintf("exited before starting a check (%s)", cmp.Or(r.lastExit, "stopped"))
+		r.mu.Unlock()
+	case <-ctx.Done():
+		reason = fmt.Sprintf("did not start a check: %v", context.Cause(ctx))
+	}
 
-	output, err := cmd.CombinedOutput()
+	stderr, combined := output.Tails()
+	return &StartupError{Name: r.Name(), Reason: reason, Stderr: stderr, Output: combined}
+}
+
+// RunSvelteKitSync runs `svelte-kit sync` through the package manager to regenerate types.
+// This should be called when route files are created, deleted, or renamed.
+func RunSvelteKitSync(ctx context.Context, workspacePath string, pm PackageManager, executor kexec.Interface) error {
+	output, err := svelteKitSync(ctx, workspacePath, pm, executor)
 	if err != nil {
 		return fmt.Errorf("svelte-kit sync failed: %w\n%s", err, string(output))
 	}
 	return nil
 }
 
+// svelteKitSync runs `svelte-kit sync` and returns its combined output.
+func svelteKitSync(ctx context.Context, workspacePath string, pm PackageManager, executor kexec.Interface) ([]byte, error) {
+	name, args := pm.Command("svelte-kit", "sync")
+	cmd := executor.CommandContext(ctx, name, args...)
+	cmd.SetDir(workspacePath)
+	return cmd.CombinedOutput()
+}
+
 // RunOnce runs svelte-check once (non-watch mode) and returns the exit code.
-func RunOnce(ctx context.Context, workspacePath, tsconfigPath string, executor kexec.Interface) (output string, exitCode int) {
-	args := []string{"run", "svelte-check"}
-	if tsconfigPath != "" {
-		args = append(args, "--tsconfig", tsconfigPath)
-	}
+func RunOnce(ctx context.Context, config RunnerConfig, executor kexec.Interface) (output string, exitCode int) {
+	config = config.withDefaults()
+	name, args := config.command(false)
 
-	cmd := executor.CommandContext(ctx, "bun", args...)
-	cmd.SetDir(workspacePath)
+	cmd := executor.CommandContext(ctx, name, args...)
+	cmd.SetDir(config.WorkspacePath)
+	config.Env.apply(cmd)
 
 	out, err := cmd.CombinedOutput()
 	output = string(out)
@@ -238,57 +1116,366 @@ func RunOnce(ctx context.Context, workspacePath, tsconfigPath string, executor k
 // Server
 // =============================================================================
 
+// Status is the response body of GET /status.
+type Status struct {
+	Runner   RunnerStatus    `json:"runner"`             // the first (or only) project
+	Projects []ProjectStatus `json:"projects,omitempty"` // all projects, when configured
+	Sync     []SyncResult    `json:"sync,omitempty"`     // latest svelte-kit sync per directory
+	Watcher  *WatcherStatus  `json:"watcher,omitempty"`
+	Git      *GitState       `json:"git,omitempty"` // the branch and commit checked out
+
+	// Subsystems reports the health of the daemon's supervised loops.
+	Subsystems []SubsystemStatus `json:"subsystems,omitempty"`
+
+	// Suppressed counts, per ignore rule, the diagnostics of the latest
+	// results it hides.
+	Suppressed []SuppressionStats `json:"suppressed,omitempty"`
+}
+
+// dependencyChange records the latest lockfile change.
+type dependencyChange struct {
+	mu       sync.Mutex
+	at       time.Time
+	lockfile string
+}
+
 // Server is an HTTP server over UDS that exposes svelte-check state.
 type Server struct {
-	socketPath string
-	runner     *Runner
-	httpServer *http.Server
-	mu         sync.Mutex
-	shutdownCh chan struct{}
+	socketPath    string
+	runner        Checker   // the first project's checker
+	projects      []Project // nil when serving a single unnamed runner
+	syncs         *SyncTracker
+	watcher       *Watcher
+	policy        CheckPolicy
+	baseline      *baselineFile
+	ignore        *IgnoreRules
+	audit         *AuditLog
+	tracer        *Tracer
+	supervisor    *Supervisor
+	notifications *Notifications
+	hooks         *ExecHooks
+	fileStreams   fileStreams // GET /events/files streams, for PUT to update
+	deps          dependencyChange
+	listener      net.Listener // serves the socket in place of creating it; see SetListener
+	httpServer    *http.Server
+	mu            sync.Mutex
+	shutdownCh    chan struct{}
+	closing       chan struct{} // closed on shutdown, ending event streams
+	stopOnDone    func() bool   // unregisters closing the server when Start's ctx is done
+	tcpAddr       string        // also serve on this TCP address; see ListenTCP
+	token         string        // required of TCP clients
+	logger        *slog.Logger  // for errors the HTTP server cannot report to a client
+	startedAt     time.Time
 }
 
-// NewServer creates a new Server.
-func NewServer(socketPath string, runner *Runner) *Server {
+// NewServer creates a new Server for a single checker.
+func NewServer(socketPath string, runner Checker, opts ...Option) *Server {
 	return &Server{
 		socketPath: socketPath,
 		runner:     runner,
+		logger:     newOptions(opts).logger.With("subsystem", "server"),
+		policy:     DefaultCheckPolicy,
 		shutdownCh: make(chan struct{}),
+		closing:    make(chan struct{}),
 	}
 }
 
-// Start begins listening on the Unix socket.
-func (s *Server) Start() error {
-	_ = os.Remove(s.socketPath)
+// NewProjectServer creates a Server for several named projects. GET /check
+// merges their results unless ?project= selects one.
+func NewProjectServer(socketPath string, projects []Project, opts ...Option) *Server {
+	s := NewServer(socketPath, projects[0].Runner, opts...)
+	s.projects = projects
+	return s
+}
 
-	listener, err := net.Listen("unix", s.socketPath)
-	if err != nil {
-		return err
+// SetSyncTracker reports the tracker's svelte-kit sync results in /status and
+// marks /check results stale while a relevant sync has failed. Call it before
+// Start.
+func (s *Server) SetSyncTracker(t *SyncTracker) {
+	s.syncs = t
+}
+
+// SetBaselineFile excludes the diagnostics listed in the baseline file at path
+// from /check results, unless requested with ?baseline=false. The file is
+// reread when it changes and may be missing. Call it before Start.
+func (s *Server) SetBaselineFile(path string) {
+	s.baseline = &baselineFile{path: path}
+}
+
+// SetIgnoreRules removes the diagnostics matched by rules from /check results
+// and reports per-rule counts in /status. Call it before Start.
+func (s *Server) SetIgnoreRules(rules *IgnoreRules) {
+	s.ignore = rules
+}
+
+// SetAuditLog records restarts requested through POST /restart in a. Call it
+// before Start.
+func (s *Server) SetAuditLog(a *AuditLog) {
+	s.audit = a
+}
+
+// DependenciesChanged records that lockfile changed: results checked before
+// now are marked stale, and subscribers of every checker are notified.
+func (s *Server) DependenciesChanged(lockfile string) {
+	s.deps.mu.Lock()
+	s.deps.at, s.deps.lockfile = time.Now(), lockfile
+	s.deps.mu.Unlock()
+
+	checkers := []Checker{s.runner}
+	if len(s.projects) > 0 {
+		checkers = checkers[:0]
+		for _, p := range s.projects {
+			checkers = append(checkers, p.Runner)
+		}
+	}
+	for _, c := range checkers {
+		if n, ok := c.(DependencyNotifier); ok {
+			n.NotifyDependenciesChanged(lockfile)
+		}
+	}
+}
+
+// SetCheckPolicy sets which results fail GET /check and the exit codes the
+// check command uses for them. Call it before Start.
+func (s *Server) SetCheckPolicy(p CheckPolicy) {
+	s.policy = p
+}
+
+// SetWatcher reports the watcher's restart counters in /status. Call it
+// before Start.
+func (s *Server) SetWatcher(w *Watcher) {
+	s.watcher = w
+}
+
+// markStale flags result when the latest svelte-kit sync failed in the
+// requested project's directory, or in any directory for a merged result:
+// svelte-check may be reporting against outdated generated types. It is
+// also flagged while a watcher is down, as changes may have been missed.
+func (s *Server) markStale(r *http.Request, result *SvelteWatchCheckComplete) {
+	s.deps.mu.Lock()
+	changedAt, lockfile := s.deps.at, s.deps.lockfile
+	s.deps.mu.Unlock()
+	if !changedAt.IsZero() && result.Timestamp < changedAt.UnixMilli() {
+		addStaleReason(result, lockfile+" changed since this check")
+	}
+	for _, reason := range s.supervisor.Down() {
+		addStaleReason(result, reason)
+	}
+
+	if s.syncs == nil {
+		return
+	}
+	var dirs []string
+	if name := r.URL.Query().Get("project"); name != "" {
+		p, _ := findProject(s.projects, name)
+		dirs = []string{filepath.Clean(p.Dir)}
+	} else if len(s.projects) == 0 {
+		dirs = []string{"."}
+	}
+	if failed := s.syncs.Failed(dirs...); len(failed) > 0 {
+		addStaleReason(result, "svelte-kit sync failed in "+strings.Join(failed, ", "))
+	}
+}
+
+// addStaleReason marks result stale, appending reason to any existing one.
+func addStaleReason(result *SvelteWatchCheckComplete, reason string) {
+	if result.Stale && result.StaleReason != "" {
+		reason = result.StaleReason + "; " + reason
+	}
+	result.Stale = true
+	result.StaleReason = reason
+}
+
+// markFreshness sets when result was checked, its age, and whether the
+// requested checkers, or any checker for a merged result, are checking again.
+func (s *Server) markFreshness(r *http.Request, result *SvelteWatchCheckComplete) {
+	if result.Timestamp != 0 {
+		result.CheckedAt = time.UnixMilli(result.Timestamp)
+		result.AgeSeconds = ageSeconds(result.CheckedAt)
+	}
+
+	if name := r.URL.Query().Get("project"); name != "" || len(s.projects) == 0 {
+		if runner, err := s.runnerFor(r); err == nil {
+			result.InProgress = checking(runner.Status())
+		}
+		return
+	}
+	for _, p := range s.projects {
+		result.InProgress = result.InProgress || checking(p.Runner.Status())
+	}
+}
+
+// checking reports whether a check is running in the runner or, for a
+// CheckerSet, in any of its checkers.
+func checking(status RunnerStatus) bool {
+	if status.State == RunnerStateChecking || status.State == RunnerStateStarting {
+		return true
+	}
+	return slices.ContainsFunc(status.Checkers, func(c CheckerStatus) bool {
+		return checking(c.Status)
+	})
+}
+
+// runnerFor returns the checker selected by the request's ?project= parameter,
+// or the first checker if none is given.
+func (s *Server) runnerFor(r *http.Request) (Checker, error) {
+	name := r.URL.Query().Get("project")
+	if name == "" {
+		return s.runner, nil
+	}
+	p, err := findProject(s.projects, name)
+	return p.Runner, err
+}
+
+// latestResult blocks until the requested project, or every project, has a
+// completed check and returns the result. It gives up when the request's
+// context is done, e.g. because the client disconnected.
+func (s *Server) latestResult(r *http.Request) (SvelteWatchCheckComplete, error) {
+	ctx := r.Context()
+	if name := r.URL.Query().Get("project"); name != "" || len(s.projects) == 0 {
+		runner, err := s.runnerFor(r)
+		if err != nil {
+			return SvelteWatchCheckComplete{}, err
+		}
+		return runner.GetLatestEvent(ctx)
+	}
+
+	results := make([]SvelteWatchCheckComplete, len(s.projects))
+	for i, p := range s.projects {
+		result, err := p.Runner.GetLatestEvent(ctx)
+		if err != nil {
+			return SvelteWatchCheckComplete{}, err
+		}
+		results[i] = p.qualify(result)
+	}
+	return MergeResults(results), nil
+}
+
+// peekResult returns the requested project's, or every project's, most recent
+// result without waiting for a check in progress. It reports ok == false if
+// any checker has no result yet; the caller then waits as usual.
+func (s *Server) peekResult(project string) (result SvelteWatchCheckComplete, ok bool, err error) {
+	peek := func(c Checker) (SvelteWatchCheckComplete, bool) {
+		if p, isPeeker := c.(ResultPeeker); isPeeker {
+			return p.PeekLatest()
+		}
+		return SvelteWatchCheckComplete{}, false
+	}
+
+	if project != "" || len(s.projects) == 0 {
+		runner := s.runner
+		if project != "" {
+			p, err := findProject(s.projects, project)
+			if err != nil {
+				return result, false, err
+			}
+			runner = p.Runner
+		}
+		result, ok = peek(runner)
+		return result, ok, nil
+	}
+
+	results := make([]SvelteWatchCheckComplete, len(s.projects))
+	for i, p := range s.projects {
+		pr, ok := peek(p.Runner)
+		if !ok {
+			return result, false, nil
+		}
+		results[i] = p.qualify(pr)
+	}
+	return MergeResults(results), true, nil
+}
+
+// Start begins listening on the Unix socket. Requests' contexts derive from
+// ctx, and when ctx is done the server closes, ending the requests it is
+// still serving; Stop shuts it down gracefully instead.
+func (s *Server) Start(ctx context.Context) error {
+	s.startedAt = time.Now()
+
+	listener := s.listener
+	if listener == nil {
+		_ = os.Remove(s.socketPath)
+		var err error
+		if listener, err = net.Listen("unix", s.socketPath); err != nil {
+			return err
+		}
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /check", s.handleCheck)
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /last-crash", s.handleLastCrash)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.HandleFunc("POST /restart", s.handleRestart)
 	mux.HandleFunc("POST /stop", s.handleStop)
+	mux.HandleFunc("GET /config/watch-dirs", s.handleWatchDirs)
+	mux.HandleFunc("PUT /config/watch-dirs", s.handleWatchDirs)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	mux.HandleFunc("GET /events/files", s.handleFileEvents)
+	mux.HandleFunc("PUT /events/files/{id}", s.handleSetFiles)
+	mux.HandleFunc("GET /version", s.handleVersion)
+	mux.HandleFunc("GET /statusline", s.handleStatusline)
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.HandleFunc("GET /debug/info", s.handleDebugInfo)
+
+	var tcpListener net.Listener
+	if s.tcpAddr != "" {
+		var err error
+		if tcpListener, err = net.Listen("tcp", s.tcpAddr); err != nil {
+			_ = listener.Close()
+			return err
+		}
+		s.tcpAddr = tcpListener.Addr().String() // resolves port 0
+	}
 
-	s.httpServer = &http.Server{Handler: mux}
+	s.httpServer = &http.Server{
+		Handler:     s.traceRequests(s.authorize(mux)),
+		ErrorLog:    slog.NewLogLogger(s.logger.Handler(), slog.LevelError),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	s.httpServer.RegisterOnShutdown(func() { close(s.closing) })
+	httpServer := s.httpServer
+	s.stopOnDone = context.AfterFunc(ctx, func() {
+		_ = httpServer.Close()
+		s.removeSocket()
+	})
 
-	go func() { _ = s.httpServer.Serve(listener) }()
+	go func() { _ = httpServer.Serve(listener) }()
+	if s.notifications != nil || s.hooks != nil {
+		go s.notifyResults(ctx)
+	}
+	if tcpListener != nil {
+		go func() { _ = httpServer.Serve(tcpListener) }()
+	}
 
 	return nil
 }
 
-// Stop gracefully shuts down the server and removes the socket file.
+// Stop gracefully shuts down the server and removes the socket file, unless
+// it was passed a listener.
 func (s *Server) Stop(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	var err error
+	if s.stopOnDone != nil {
+		s.stopOnDone()
+	}
 	if s.httpServer != nil {
 		err = s.httpServer.Shutdown(ctx)
 	}
-	_ = os.Remove(s.socketPath)
+	s.removeSocket()
 	return err
 }
 
+// removeSocket removes the socket file the server created. One it was
+// passed a listener for belongs to whoever created it.
+func (s *Server) removeSocket() {
+	if s.listener == nil {
+		_ = os.Remove(s.socketPath)
+	}
+}
+
 // SocketPath returns the path to the Unix socket.
 func (s *Server) SocketPath() string {
 	return s.socketPath
@@ -299,8 +1486,37 @@ func (s *Server) ShutdownCh() <-chan struct{} {
 	return s.shutdownCh
 }
 
+// handleCheck serves the latest result, waiting for a check in progress.
+// With ?stale=true it instead serves the most recent result at once, even one
+// saved before the server restarted, with "stale" set when it may be outdated.
+// ?severity=, ?glob=, and ?code= narrow it (see DiagnosticFilter).
 func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
-	event := s.runner.GetLatestEvent()
+	filter, err := parseDiagnosticFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var event SvelteWatchCheckComplete
+	var ok bool
+	if stale, _ := strconv.ParseBool(r.URL.Query().Get("stale")); stale {
+		event, ok, err = s.peekResult(r.URL.Query().Get("project"))
+	}
+	if err == nil && !ok {
+		event, err = s.latestResult(r)
+	}
+	if r.Context().Err() != nil {
+		// The client is gone, or the server is closing; never let an
+		// unanswered wait read as a passing check.
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	s.decorate(r, &event)
+	event = filter.Apply(event, s.qualifier(r))
 
 	// Check for format query parameter: ?format=json or ?format=human (default)
 	format := r.URL.Query().Get("format")
@@ -308,20 +1524,153 @@ func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
 		format = "human"
 	}
 
-	if event.ErrorCount > 0 {
-		w.WriteHeader(http.StatusInternalServerError)
+	verdict := s.policy.Verdict(event)
+	if verdict != "" {
+		w.Header().Set(HeaderCheckVerdict, string(verdict))
 	}
+	w.Header().Set(HeaderCheckExitCode, strconv.Itoa(s.policy.ExitCode(verdict)))
 
 	switch format {
 	case "json":
+		// Clients polling for an unchanged result are spared its transfer.
+		if etag := resultETag(event); etag != "" {
+			w.Header().Set("ETag", etag)
+			if etagMatches(r.Header.Get("If-None-Match"), etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(s.policy.StatusCode(verdict))
 		_ = json.NewEncoder(w).Encode(event)
 	default:
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(s.policy.StatusCode(verdict))
 		_, _ = w.Write([]byte(FormatHuman(event)))
 	}
 }
 
+// decorate applies the request's suppressions to a result and marks how
+// stale and fresh it is and what was checked out, as it is served.
+func (s *Server) decorate(r *http.Request, event *SvelteWatchCheckComplete) {
+	s.suppress(r, event)
+	s.markStale(r, event)
+	s.markFreshness(r, event)
+	s.markGit(event)
+	event.SchemaVersion = types.SchemaVersion
+}
+
+// suppress removes diagnostics matched by ignore rules and, unless the request
+// has ?baseline=false, baselined diagnostics from a /check result. A result
+// for a single project is matched with workspace-relative filenames.
+func (s *Server) suppress(r *http.Request, event *SvelteWatchCheckComplete) {
+	qualify := s.qualifier(r)
+	*event = s.ignore.Apply(*event, qualify)
+
+	if s.baseline == nil {
+		return
+	}
+	if apply, err := strconv.ParseBool(r.URL.Query().Get("baseline")); err == nil && !apply {
+		return
+	}
+	*event = s.baseline.get().Apply(*event, qualify)
+}
+
+// qualifier returns the function that makes the filenames of a result served
+// for r workspace-relative: a project's qualify for ?project=, else identity.
+func (s *Server) qualifier(r *http.Request) func(SvelteWatchCheckComplete) SvelteWatchCheckComplete {
+	if name := r.URL.Query().Get("project"); name != "" {
+		if p, err := findProject(s.projects, name); err == nil {
+			return p.qualify
+		}
+	}
+	return func(result SvelteWatchCheckComplete) SvelteWatchCheckComplete { return result }
+}
+
+// Status returns a snapshot of the daemon's health.
+func (s *Server) Status() Status {
+	status := Status{
+		Runner: s.runner.Status(),
+	}
+	for _, p := range s.projects {
+		status.Projects = append(status.Projects, ProjectStatus{
+			Name:   p.Name,
+			Dir:    p.Dir,
+			Runner: p.Runner.Status(),
+		})
+	}
+	if s.syncs != nil {
+		status.Sync = s.syncs.Results()
+	}
+	if s.watcher != nil {
+		ws := s.watcher.Status()
+		status.Watcher = &ws
+		if git, ok := s.watcher.GitState(); ok {
+			status.Git = &git
+		}
+	}
+	status.Subsystems = s.supervisor.Status()
+	if s.ignore != nil {
+		result, _, _ := s.peekResult("")
+		status.Suppressed = s.ignore.Stats(result)
+	}
+	return status
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(s.Status())
+}
+
+func (s *Server) handleLastCrash(w http.ResponseWriter, r *http.Request) {
+	runner, err := s.runnerFor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	report := runner.LastCrash()
+	if report == nil {
+		http.Error(w, "svelte-check has not crashed", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// handleRestart restarts the checker selected by ?project=, or every
+// project's checker, and responds once the new processes have started.
+// Requests that overlap a restart in progress share it.
+func (s *Server) handleRestart(w http.ResponseWriter, r *http.Request) {
+	checkers := []Checker{s.runner}
+	if name := r.URL.Query().Get("project"); name != "" {
+		runner, err := s.runnerFor(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		checkers = []Checker{runner}
+	} else if len(s.projects) > 0 {
+		checkers = checkers[:0]
+		for _, p := range s.projects {
+			checkers = append(checkers, p.Runner)
+		}
+	}
+
+	s.audit.Record("restart", map[string]any{"reason": "request", "project": r.URL.Query().Get("project")})
+
+	var errs []error
+	for _, c := range checkers {
+		if err := c.Restart(r.Context()); err != nil {
+			errs = append(errs, fmt.Errorf("restart %s: %w", c.Name(), err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
 func (s *Server) handleStop(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	go func() { close(s.shutdownCh) }()
@@ -333,36 +1682,140 @@ func (s *Server) handleStop(w http.ResponseWriter, _ *http.Request) {
 
 // Client communicates with the svelte-check server.
 type Client struct {
+	workspace  string
 	socketPath string
 	httpClient *http.Client
+	cache      checkCache // see CheckTyped
+
+	strictVersion bool // see WithStrictVersion
+}
+
+// DefaultDialTimeout bounds connecting to the server's socket.
+const DefaultDialTimeout = time.Second
+
+// clientOptions are the settings of a Client.
+type clientOptions struct {
+	dialTimeout     time.Duration
+	responseTimeout time.Duration
+	socketPath      string // overrides the workspace's socket
+	tcpAddr         string // connects over TCP instead of a socket
+	token           string // sent as a bearer token over TCP
+	strictVersion   bool   // see WithStrictVersion
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*clientOptions)
+
+// WithDialTimeout bounds connecting to the server (default
+// DefaultDialTimeout), so a missing or wedged server is noticed quickly.
+func WithDialTimeout(d time.Duration) ClientOption {
+	return func(o *clientOptions) { o.dialTimeout = d }
+}
+
+// WithResponseTimeout bounds how long a request waits for the server to
+// start responding, 0 for no limit (the default). Requests are otherwise
+// bounded only by their context, since GET /check legitimately blocks for
+// as long as a check takes.
+func WithResponseTimeout(d time.Duration) ClientOption {
+	return func(o *clientOptions) { o.responseTimeout = d }
 }
 
-// NewClient creates a new Client for the given workspace.
-func NewClient(workspacePath string) (*Client, error) {
-	socketPath, err := SocketPathForWorkspace(workspacePath)
+// NewClient creates a new Client for the workspace that workspacePath belongs
+// to (see FindWorkspace).
+func NewClient(workspacePath string, opts ...ClientOption) (*Client, error) {
+	o := clientOptions{dialTimeout: DefaultDialTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	workspace, err := FindWorkspace(workspacePath)
 	if err != nil {
 		return nil, err
 	}
+	socketPath := o.socketPath
+	if socketPath == "" && o.tcpAddr == "" {
+		if socketPath, err = SocketPathForWorkspace(workspace); err != nil {
+			return nil, err
+		}
+	}
+	network, address := "unix", socketPath
+	if o.tcpAddr != "" {
+		network, address = "tcp", o.tcpAddr
+	}
 
-	httpClient := &http.Client{
-		Transport: &http.Transport{
-			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
-				var d net.Dialer
-				return d.DialContext(ctx, "unix", socketPath)
-			},
+	var transport http.RoundTripper = &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: o.dialTimeout}
+			return d.DialContext(ctx, network, address)
 		},
-		Timeout: 5 * time.Second,
+		ResponseHeaderTimeout: o.responseTimeout,
+	}
+	if o.token != "" {
+		transport = tokenTransport{base: transport, token: o.token}
 	}
+	httpClient := &http.Client{Transport: transport}
 
 	return &Client{
-		socketPath: socketPath,
-		httpClient: httpClient,
+		workspace:     workspace,
+		socketPath:    socketPath,
+		httpClient:    httpClient,
+		strictVersion: o.strictVersion,
 	}, nil
 }
 
-// IsServerRunning checks if the server is running.
+// serverProbeTimeout bounds how long Probe waits for the server to answer.
+const serverProbeTimeout = time.Second
+
+// Errors returned by Probe when no server answers.
+var (
+	ErrNotRunning    = errors.New("server is not running")
+	ErrStaleSocket   = errors.New("server is not running; removed its stale socket")
+	ErrNotResponding = errors.New("server is not responding")
+)
+
+// Probe checks that a server answers GET /status on the socket within
+// serverProbeTimeout. A socket that refuses connections was left behind by
+// a server that exited without removing it; Probe removes it and returns
+// ErrStaleSocket, so the next start or check does not trip over it. A
+// server that accepts the connection but does not answer in time is left
+// alone and reported as ErrNotResponding. Over TCP, a refused connection
+// is ErrNotRunning.
+func (c *Client) Probe(ctx context.Context) error {
+	if c.socketPath != "" && !SocketExists(c.socketPath) {
+		return ErrNotRunning
+	}
+	ctx, cancel := context.WithTimeout(ctx, serverProbeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://unix/status", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED) && c.socketPath == "":
+		return ErrNotRunning
+	case errors.Is(err, syscall.ECONNREFUSED):
+		if err := os.Remove(c.socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w (removing %s: %v)", ErrNotRunning, c.socketPath, err)
+		}
+		return ErrStaleSocket
+	case errors.Is(err, os.ErrNotExist):
+		return ErrNotRunning
+	case err != nil && ctx.Err() != nil:
+		return ErrNotResponding
+	case err != nil:
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errUnauthorized
+	}
+	return nil
+}
+
+// IsServerRunning reports whether a server answers on the socket. See Probe.
 func (c *Client) IsServerRunning() bool {
-	return SocketExists(c.socketPath)
+	return c.Probe(context.Background()) == nil
 }
 
 // Check retrieves the latest check result from the server.
@@ -370,37 +1823,177 @@ func (c *Client) IsServerRunning() bool {
 // format can be "human" or "json".
 // Returns the output, whether there were errors, and any error communicating with server.
 func (c *Client) Check(ctx context.Context, format string) (output string, hasErrors bool, err error) {
-	url := "http://unix/check"
-	if format != "" && format != "human" {
-		url = fmt.Sprintf("http://unix/check?format=%s", format)
+	return c.CheckProject(ctx, "", format)
+}
+
+// CheckProject is like Check but returns the result of a single named project.
+// An empty project returns the merged result of all projects.
+func (c *Client) CheckProject(ctx context.Context, project, format string) (output string, hasErrors bool, err error) {
+	resp, err := c.CheckWith(ctx, CheckOptions{Project: project, Format: format})
+	return resp.Output, resp.ExitCode != 0, err
+}
+
+// CheckOptions selects what Client.CheckWith requests from GET /check.
+type CheckOptions struct {
+	Project string // a single project; empty for all projects merged
+	Format  string // "human" (the default) or "json"
+
+	// AllowStale returns the most recent result at once instead of waiting
+	// for a check in progress, including one saved before the server
+	// restarted. Such results are marked stale.
+	AllowStale bool
+
+	// IgnoreBaseline includes diagnostics listed in the baseline file.
+	IgnoreBaseline bool
+
+	// Filter narrows the result to the diagnostics it keeps.
+	Filter DiagnosticFilter
+}
+
+// CheckResponse is a /check result as judged by the daemon's CheckPolicy.
+type CheckResponse struct {
+	Output   string
+	Verdict  Severity // the failing severity; empty if the check passed
+	ExitCode int      // the exit code the check command uses; 0 if the check passed
+}
+
+// CheckWith is like Check with the given options.
+func (c *Client) CheckWith(ctx context.Context, opts CheckOptions) (CheckResponse, error) {
+	reply, err := c.check(ctx, opts, "")
+	return reply.CheckResponse, err
+}
+
+// checkReply is a /check response with what conditional requests need.
+type checkReply struct {
+	CheckResponse
+	etag        string
+	notModified bool // the result still has the If-None-Match ETag; Output is empty
+}
+
+// checkQuery returns the GET /check query for opts.
+func checkQuery(opts CheckOptions) url.Values {
+	query := url.Values{}
+	if opts.Format != "" && opts.Format != "human" {
+		query.Set("format", opts.Format)
+	}
+	if opts.Project != "" {
+		query.Set("project", opts.Project)
 	}
+	if opts.AllowStale {
+		query.Set("stale", "true")
+	}
+	if opts.IgnoreBaseline {
+		query.Set("baseline", "false")
+	}
+	opts.Filter.values(query)
+	return query
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// check requests GET /check with opts, sending ifNoneMatch, if set, as
+// If-None-Match.
+func (c *Client) check(ctx context.Context, opts CheckOptions, ifNoneMatch string) (checkReply, error) {
+	query := checkQuery(opts)
+	u := "http://unix/check"
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
 	if err != nil {
-		return "", false, err
+		return checkReply{}, err
+	}
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", false, err
+		return checkReply{}, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", false, err
+		return checkReply{}, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound:
+		return checkReply{}, errors.New(strings.TrimSpace(string(body)))
+	}
+
+	reply := checkReply{
+		CheckResponse: CheckResponse{
+			Output:  string(body),
+			Verdict: Severity(resp.Header.Get(HeaderCheckVerdict)),
+		},
+		etag:        resp.Header.Get("ETag"),
+		notModified: resp.StatusCode == http.StatusNotModified,
+	}
+	reply.ExitCode, err = strconv.Atoi(resp.Header.Get(HeaderCheckExitCode))
+	if err != nil {
+		// A daemon without a CheckPolicy fails a check with 500.
+		reply.ExitCode = 0
+		if resp.StatusCode != http.StatusOK && !reply.notModified {
+			reply.ExitCode = 1
+		}
 	}
+	return reply, nil
+}
 
-	output = string(body)
-	hasErrors = resp.StatusCode == http.StatusInternalServerError
-	return output, hasErrors, nil
+// Workspace returns the workspace this client was resolved to.
+func (c *Client) Workspace() string {
+	return c.workspace
 }
 
-// SocketPath returns the socket path for this client.
+// SocketPath returns the socket path for this client, or "" over TCP.
 func (c *Client) SocketPath() string {
 	return c.socketPath
 }
 
+// Status retrieves the daemon's health from GET /status.
+func (c *Client) Status(ctx context.Context) (Status, error) {
+	var status Status
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://unix/status", nil)
+	if err != nil {
+		return status, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return status, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return status, fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	return status, err
+}
+
+// Restart asks the server to restart the named project's checker, or every
+// checker when project is empty, and waits until they have started.
+func (c *Client) Restart(ctx context.Context, project string) error {
+	u := "http://unix/restart"
+	if project != "" {
+		u += "?" + url.Values{"project": {project}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
 // Stop requests the server to shut down gracefully via HTTP.
 func (c *Client) Stop(ctx context.Context) error {
 	req, err := http.NewRequestWithContext(ctx, "POST", "http://unix/stop", nil)
@@ -431,11 +2024,72 @@ type FSWatcher interface {
 	Close() error
 }
 
+// PathFilter is implemented by FSWatchers that can skip ignored directories
+// when adding recursive watches.
+type PathFilter interface {
+	SetIgnore(ignored func(path string) bool)
+}
+
+// SubtreeWatcher is implemented by FSWatchers that can watch a newly created
+// directory and its contents by themselves. Other FSWatchers are rescanned
+// in full on every Create event.
+type SubtreeWatcher interface {
+	// AddCreated watches path if it is a directory below a recursive watch,
+	// or one that was added itself. Other paths are ignored.
+	AddCreated(path string) error
+
+	// RemoveDeleted drops the watches of path and everything below it after
+	// it was removed or renamed.
+	RemoveDeleted(path string)
+}
+
+// WatchCounter is implemented by FSWatchers that know how many paths they
+// hold OS watches for.
+type WatchCounter interface {
+	WatchCount() int
+}
+
+// DefaultSkipDirs are directories never descended into by recursive watches,
+// wherever they occur below the watched directory. Watching node_modules
+// alone can exhaust the OS watch limit.
+var DefaultSkipDirs = []string{"node_modules", ".git", ".svelte-kit"}
+
+// DefaultRootSkipDirs are the directories build output is written to, never
+// descended into at a project's root. Elsewhere, e.g. src/routes/build, they
+// are watched like any other directory.
+var DefaultRootSkipDirs = []string{"build", "dist"}
+
+// skippedDir reports whether recursive watches skip the directory path. Each
+// entry of skipDirs is either a name, skipped at any depth, or an absolute
+// path, skipped only there.
+func skippedDir(skipDirs []string, path string) bool {
+	return slices.Contains(skipDirs, filepath.Base(path)) || slices.Contains(skipDirs, path)
+}
+
 // RealFSWatcher wraps fsnotify.Watcher to implement FSWatcher.
 type RealFSWatcher struct {
-	watcher *fsnotify.Watcher
-	paths   []watchedPath // track paths for Rescan
-	mu      sync.Mutex
+	watcher  *fsnotify.Watcher
+	limit    *WatcherLimit
+	addWatch func(path string) error // watcher.Add; replaced in tests
+	paths    []watchedPath           // added paths, kept for Rescan even while deleted
+	watched  map[string]bool         // paths with a live watch
+	ignored  func(path string) bool
+	skipDirs []string
+	follow   bool // descend into symlinked directories
+	mu       sync.Mutex
+
+	// Directories that could not be watched because the OS ran out of
+	// watches, and those polled by fallback instead.
+	exhausted error
+	unwatched map[string]bool
+	polled    map[string]bool
+
+	// With a poll fallback, events and errors of both watchers are merged.
+	fallback   *PollingFSWatcher
+	events     chan fsnotify.Event
+	errors     chan error
+	done       chan struct{}
+	forwarding sync.WaitGroup
 }
 
 type watchedPath struct {
@@ -444,63 +2098,425 @@ type watchedPath struct {
 }
 
 // NewRealFSWatcher creates a new RealFSWatcher.
-// Returns ErrTooManyWatchers if the global watcher limit would be exceeded.
+// Returns ErrTooManyWatchers if the default watcher limit would be exceeded.
 func NewRealFSWatcher() (*RealFSWatcher, error) {
-	if err := acquireWatcher(); err != nil {
+	return NewRealFSWatcherWithLimit(defaultWatcherLimit)
+}
+
+// NewRealFSWatcherWithLimit creates a new RealFSWatcher counted against limit.
+// Returns ErrTooManyWatchers if limit would be exceeded.
+func NewRealFSWatcherWithLimit(limit *WatcherLimit) (*RealFSWatcher, error) {
+	if err := limit.acquire(); err != nil {
 		return nil, err
 	}
 
 	w, err := fsnotify.NewWatcher()
 	if err != nil {
-		releaseWatcher()
+		limit.release()
 		return nil, err
 	}
-	return &RealFSWatcher{watcher: w}, nil
+	return &RealFSWatcher{
+		watcher:   w,
+		limit:     limit,
+		addWatch:  w.Add,
+		watched:   make(map[string]bool),
+		skipDirs:  DefaultSkipDirs,
+		unwatched: make(map[string]bool),
+		polled:    make(map[string]bool),
+	}, nil
+}
+
+// SetSkipDirs replaces DefaultSkipDirs for recursive watches added
+// afterwards, with names or absolute paths as skippedDir takes them. An empty
+// list descends into every directory.
+func (r *RealFSWatcher) SetSkipDirs(names []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skipDirs = names
+	if r.fallback != nil {
+		r.fallback.SetSkipDirs(names)
+	}
+}
+
+// SetFollowSymlinks makes recursive watches descend into symlinked
+// directories, reporting their events under the symlink's path. Each real
+// directory is watched once per walk, so symlink cycles end and a directory
+// reachable under two names is watched under the first one found.
+func (r *RealFSWatcher) SetFollowSymlinks(follow bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.follow = follow
+}
+
+// SetPollFallback polls directories that cannot be watched because the OS
+// ran out of watches, every interval, instead of leaving them unwatched.
+// Call it before Events and Errors.
+func (r *RealFSWatcher) SetPollFallback(interval time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fallback != nil {
+		return
+	}
+
+	r.fallback = NewPollingFSWatcher(interval)
+	r.fallback.SetSkipDirs(r.skipDirs)
+	if r.ignored != nil {
+		r.fallback.SetIgnore(r.ignored)
+	}
+
+	r.events = make(chan fsnotify.Event)
+	r.errors = make(chan error)
+	r.done = make(chan struct{})
+	r.forwarding.Add(4)
+	go forward(&r.forwarding, r.watcher.Events, r.events, r.done)
+	go forward(&r.forwarding, r.fallback.Events(), r.events, r.done)
+	go forward(&r.forwarding, r.watcher.Errors, r.errors, r.done)
+	go forward(&r.forwarding, r.fallback.Errors(), r.errors, r.done)
+}
+
+// forward sends values from one channel to another until from is closed or
+// done is.
+func forward[T any](wg *sync.WaitGroup, from <-chan T, to chan<- T, done <-chan struct{}) {
+	defer wg.Done()
+	for {
+		select {
+		case v, ok := <-from:
+			if !ok {
+				return
+			}
+			select {
+			case to <- v:
+			case <-done:
+				return
+			}
+		case <-done:
+			return
+		}
+	}
 }
 
 func (r *RealFSWatcher) Events() <-chan fsnotify.Event {
+	if r.events != nil {
+		return r.events
+	}
 	return r.watcher.Events
 }
 
 func (r *RealFSWatcher) Errors() <-chan error {
+	if r.errors != nil {
+		return r.errors
+	}
 	return r.watcher.Errors
 }
 
-func (r *RealFSWatcher) Add(path string, recursive bool) error {
+func (r *RealFSWatcher) Add(path string, recursive bool) error {
+	r.mu.Lock()
+	if wp := (watchedPath{path: path, recursive: recursive}); !slices.Contains(r.paths, wp) {
+		r.paths = append(r.paths, wp)
+	}
+	r.mu.Unlock()
+
+	if recursive {
+		return r.addRecursive(path)
+	}
+	_, err := r.watch(path, false)
+	return err
+}
+
+// SetIgnore skips directories for which ignored reports true, and everything
+// below them, in recursive watches added afterwards.
+func (r *RealFSWatcher) SetIgnore(ignored func(path string) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ignored = ignored
+	if r.fallback != nil {
+		r.fallback.SetIgnore(ignored)
+	}
+}
+
+// watch adds a watch for path. If the OS has run out of watches, the first
+// time this happens is logged with remediation, and path is polled instead
+// when there is a poll fallback or else recorded as unwatched; no error is
+// returned then. polled reports whether path is polled.
+func (r *RealFSWatcher) watch(path string, recursive bool) (polled bool, err error) {
+	r.mu.Lock()
+	if r.polled[path] {
+		r.mu.Unlock()
+		return true, nil
+	}
+	if r.watched[path] {
+		r.mu.Unlock()
+		return false, nil
+	}
+	r.mu.Unlock()
+
+	err = r.addWatch(path)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		r.watched[path] = true
+		delete(r.unwatched, path)
+		return false, nil
+	}
+	if !isWatchExhausted(err) {
+		return false, err
+	}
+
+	if r.exhausted == nil {
+		r.exhausted = err
+		logger("fswatch").Warn(watchExhaustionWarning(err, r.fallback != nil))
+	}
+	if r.fallback != nil && r.fallback.Add(path, recursive) == nil {
+		delete(r.unwatched, path)
+		r.polled[path] = true
+		return true, nil
+	}
+	r.unwatched[path] = true
+	return false, nil
+}
+
+// Degradation reports directories that are not watched because the OS ran
+// out of watches, or nil if there are none.
+func (r *RealFSWatcher) Degradation() *WatchDegradation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.unwatched) == 0 && len(r.polled) == 0 {
+		return nil
+	}
+	d := &WatchDegradation{
+		Reason:    r.exhausted.Error(),
+		Unwatched: len(r.unwatched),
+		Polled:    len(r.polled),
+	}
+	if errors.Is(r.exhausted, syscall.ENOSPC) {
+		d.Limit = inotifyWatchLimit()
+	}
+	return d
+}
+
+func (r *RealFSWatcher) addRecursive(dir string) error {
+	r.mu.Lock()
+	ignored, skipDirs, follow := r.ignored, r.skipDirs, r.follow
+	r.mu.Unlock()
+
+	if !follow {
+		return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			return r.addWalked(dir, path, ignored, skipDirs)
+		})
+	}
+
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	visited := make(map[string]bool) // real paths walked, to break cycles
+
+	// walk watches the real directory target under the name top.
+	var walk func(top, target string) error
+	walk = func(top, target string) error {
+		return filepath.WalkDir(target, func(realPath string, d os.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			rel, _ := filepath.Rel(target, realPath)
+			path := filepath.Join(top, rel)
+
+			if d.Type()&os.ModeSymlink != 0 {
+				linked, err := filepath.EvalSymlinks(realPath)
+				if err != nil {
+					return nil
+				}
+				if info, err := os.Stat(linked); err != nil || !info.IsDir() || visited[linked] {
+					return nil
+				}
+				if skippedDir(skipDirs, path) || (ignored != nil && ignored(path)) {
+					return nil
+				}
+				return walk(path, linked)
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			if visited[realPath] {
+				return filepath.SkipDir
+			}
+			visited[realPath] = true
+			return r.addWalked(dir, path, ignored, skipDirs)
+		})
+	}
+	return walk(dir, real)
+}
+
+// addWalked watches a directory found walking the recursive watch of dir,
+// returning filepath.SkipDir if its contents are not to be walked.
+func (r *RealFSWatcher) addWalked(dir, path string, ignored func(string) bool, skipDirs []string) error {
+	if path != dir && skippedDir(skipDirs, path) {
+		return filepath.SkipDir
+	}
+	if ignored != nil && ignored(path) {
+		return filepath.SkipDir
+	}
+	polled, err := r.watch(path, true)
+	if err != nil {
+		logger("fswatch").Warn("could not watch directory", "path", path, "error", err)
+	}
+	if polled {
+		return filepath.SkipDir
+	}
+	return nil
+}
+
+// AddCreated watches a directory created below a recursive watch, and
+// everything in it, without walking the rest of the tree. Directories
+// skipped by a full walk, or below a polled directory, are left alone.
+func (r *RealFSWatcher) AddCreated(path string) error {
+	r.mu.Lock()
+	stat := os.Lstat
+	if r.follow {
+		stat = os.Stat
+	}
+	r.mu.Unlock()
+	info, err := stat(path)
+	if err != nil || !info.IsDir() {
+		return nil
+	}
+
+	r.mu.Lock()
+	under, root, recursive := false, false, false
+	for _, wp := range r.paths {
+		switch {
+		case wp.path == path:
+			root, recursive = true, recursive || wp.recursive
+		case wp.recursive && isWithin(wp.path, path):
+			under = true
+		}
+	}
+	skip := !root && (!under || skippedDir(r.skipDirs, path) || (r.ignored != nil && r.ignored(path)))
+	for dir := range r.polled {
+		skip = skip || isWithin(dir, path)
+	}
+	if !skip {
+		// The directory is new: any registrations at or below it belong to
+		// an earlier directory of the same name, whose watches went with it.
+		r.forgetLocked(path)
+	}
+	r.mu.Unlock()
+
+	switch {
+	case skip:
+		return nil
+	case root && !recursive:
+		_, err := r.watch(path, false)
+		return err
+	}
+	return r.addRecursive(path)
+}
+
+// RemoveDeleted drops the watches of a removed or renamed path and of
+// everything below it. The OS drops watches of deleted directories itself,
+// but a renamed directory keeps its watch and would report events under its
+// old name; its new name is watched by AddCreated.
+func (r *RealFSWatcher) RemoveDeleted(path string) {
+	r.mu.Lock()
+	removed := r.forgetLocked(path)
+	for p := range r.unwatched {
+		if p == path || isWithin(path, p) {
+			delete(r.unwatched, p)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, p := range removed {
+		// Fails for watches the OS already dropped, which is fine.
+		_ = r.watcher.Remove(p)
+	}
+}
+
+// Remove stops watching a path added with Add, and with recursive everything
+// below it. Watches other added paths still need are kept.
+func (r *RealFSWatcher) Remove(path string, recursive bool) error {
+	r.mu.Lock()
+	i := slices.Index(r.paths, watchedPath{path: path, recursive: recursive})
+	if i < 0 {
+		r.mu.Unlock()
+		return fmt.Errorf("%s is not watched", path)
+	}
+	r.paths = slices.Delete(r.paths, i, i+1)
+
+	covered := func(p string) bool {
+		return p == path || (recursive && isWithin(path, p))
+	}
+	needed := func(p string) bool {
+		return slices.ContainsFunc(r.paths, func(wp watchedPath) bool {
+			return wp.path == p || (wp.recursive && isWithin(wp.path, p))
+		})
+	}
+	var removed []string
+	for p := range r.watched {
+		if covered(p) && !needed(p) {
+			delete(r.watched, p)
+			removed = append(removed, p)
+		}
+	}
+	for p := range r.unwatched {
+		if covered(p) && !needed(p) {
+			delete(r.unwatched, p)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, p := range removed {
+		_ = r.watcher.Remove(p)
+	}
+	return nil
+}
+
+// forgetLocked unregisters the watches of path and everything below it and
+// returns their paths. r.mu must be held.
+func (r *RealFSWatcher) forgetLocked(path string) []string {
+	var forgotten []string
+	for p := range r.watched {
+		if p == path || isWithin(path, p) {
+			delete(r.watched, p)
+			forgotten = append(forgotten, p)
+		}
+	}
+	return forgotten
+}
+
+// WatchCount returns the number of paths with a live watch.
+func (r *RealFSWatcher) WatchCount() int {
 	r.mu.Lock()
-	r.paths = append(r.paths, watchedPath{path: path, recursive: recursive})
-	r.mu.Unlock()
-
-	if recursive {
-		return r.addRecursive(path)
-	}
-	return r.watcher.Add(path)
+	defer r.mu.Unlock()
+	return len(r.watched)
 }
 
-func (r *RealFSWatcher) addRecursive(dir string) error {
-	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-		if d.IsDir() {
-			if err := r.watcher.Add(path); err != nil {
-				log.Printf("Warning: could not watch %s: %v", path, err)
-			}
-		}
-		return nil
-	})
+// isWithin reports whether path is strictly below dir.
+func isWithin(dir, path string) bool {
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
 }
 
+// Rescan walks every recursive watch again, re-adding all watches in case
+// some were lost.
 func (r *RealFSWatcher) Rescan() error {
 	r.mu.Lock()
 	paths := make([]watchedPath, len(r.paths))
 	copy(paths, r.paths)
+	for _, wp := range paths {
+		if wp.recursive {
+			r.forgetLocked(wp.path)
+		}
+	}
 	r.mu.Unlock()
 
 	for _, wp := range paths {
 		if wp.recursive {
 			if err := r.addRecursive(wp.path); err != nil {
-				log.Printf("Warning: rescan failed for %s: %v", wp.path, err)
+				logger("fswatch").Warn("rescan failed", "path", wp.path, "error", err)
 			}
 		}
 	}
@@ -508,8 +2524,16 @@ func (r *RealFSWatcher) Rescan() error {
 }
 
 func (r *RealFSWatcher) Close() error {
-	releaseWatcher()
-	return r.watcher.Close()
+	r.limit.release()
+	err := r.watcher.Close()
+	if r.fallback != nil {
+		close(r.done)
+		_ = r.fallback.Close()
+		r.forwarding.Wait()
+		close(r.events)
+		close(r.errors)
+	}
+	return err
 }
 
 // GitBranchWatcher watches for git branch changes and emits events on channels.
@@ -520,27 +2544,70 @@ type GitBranchWatcher interface {
 	Close() error
 }
 
+// GitOperationWatcher is implemented by GitBranchWatchers that detect git
+// operations rewriting the working tree, such as rebases and merges, during
+// which the Watcher defers restarts.
+type GitOperationWatcher interface {
+	// OperationChanged emits when an operation may have started or ended.
+	OperationChanged() <-chan struct{}
+	// Operation returns the operation in progress, or "" if there is none.
+	Operation() string
+}
+
+// gitOperationMarkers are the files and directories git keeps in the git
+// directory while an operation is in progress, and the operation's name.
+// index.lock is not one: a lock left behind by a crashed git would hold
+// restarts until someone deleted it.
+var gitOperationMarkers = map[string]string{
+	"rebase-merge":     "rebase",
+	"rebase-apply":     "rebase",
+	"MERGE_HEAD":       "merge",
+	"CHERRY_PICK_HEAD": "cherry-pick",
+}
+
+// gitOperation returns the operation in progress in gitDir, or "".
+func gitOperation(gitDir string) string {
+	for _, name := range slices.Sorted(maps.Keys(gitOperationMarkers)) {
+		if _, err := os.Stat(filepath.Join(gitDir, name)); err == nil {
+			return gitOperationMarkers[name]
+		}
+	}
+	return ""
+}
+
 // RealGitBranchWatcher implements GitBranchWatcher using fsnotify.
 type RealGitBranchWatcher struct {
 	workspacePath string
 	executor      kexec.Interface
+	mu            sync.Mutex // guards watcher, which Reopen replaces
 	watcher       *fsnotify.Watcher
+	limit         *WatcherLimit
 	headCh        chan struct{}
 	branchCh      chan struct{}
+	operationCh   chan struct{}
 	gitRoot       string
-	gitDir        string
+	gitDir        string // HEAD's directory; per-worktree
+	commonDir     string // refs' directory; shared by all worktrees
+	commit        string // HEAD's commit as of the last Changes
+	submodules    []*gitSubmodule
 }
 
 // NewRealGitBranchWatcher creates a new RealGitBranchWatcher for the given workspace.
-// Returns ErrTooManyWatchers if the global watcher limit would be exceeded.
+// Returns ErrTooManyWatchers if the default watcher limit would be exceeded.
 func NewRealGitBranchWatcher(workspacePath string, executor kexec.Interface) (*RealGitBranchWatcher, error) {
-	if err := acquireWatcher(); err != nil {
+	return NewRealGitBranchWatcherWithLimit(workspacePath, executor, defaultWatcherLimit)
+}
+
+// NewRealGitBranchWatcherWithLimit creates a new RealGitBranchWatcher counted
+// against limit. Returns ErrTooManyWatchers if limit would be exceeded.
+func NewRealGitBranchWatcherWithLimit(workspacePath string, executor kexec.Interface, limit *WatcherLimit) (*RealGitBranchWatcher, error) {
+	if err := limit.acquire(); err != nil {
 		return nil, err
 	}
 
 	w, err := fsnotify.NewWatcher()
 	if err != nil {
-		releaseWatcher()
+		limit.release()
 		return nil, err
 	}
 
@@ -548,16 +2615,65 @@ func NewRealGitBranchWatcher(workspacePath string, executor kexec.Interface) (*R
 		workspacePath: workspacePath,
 		executor:      executor,
 		watcher:       w,
+		limit:         limit,
 		headCh:        make(chan struct{}, 1),
 		branchCh:      make(chan struct{}, 1),
+		operationCh:   make(chan struct{}, 1),
 	}
 	r.gitRoot = r.findGitRoot()
 	if r.gitRoot != "" {
-		r.gitDir = filepath.Join(r.gitRoot, ".git")
+		r.gitDir, r.commonDir, err = resolveGitDir(r.gitRoot)
+		if err != nil {
+			logger("git").Warn("could not resolve git directory", "error", err)
+		}
+		r.commit = r.headCommit(r.gitRoot)
+		r.submodules = r.findSubmodules()
 	}
 	return r, nil
 }
 
+// resolveGitDir returns the git directory of the work tree at gitRoot, which
+// holds HEAD, and the common directory, which holds refs. They are both
+// gitRoot/.git unless .git is a file pointing elsewhere ("gitdir: <path>"),
+// as in linked worktrees, submodules, and repositories created with
+// --separate-git-dir; a linked worktree's git directory names the main
+// repository's in its commondir file.
+func resolveGitDir(gitRoot string) (gitDir, commonDir string, err error) {
+	dotGit := filepath.Join(gitRoot, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return "", "", err
+	}
+	gitDir = dotGit
+	if !info.IsDir() {
+		content, err := os.ReadFile(dotGit)
+		if err != nil {
+			return "", "", err
+		}
+		line := strings.TrimSpace(string(content))
+		if !strings.HasPrefix(line, "gitdir: ") {
+			return "", "", fmt.Errorf("%s: no gitdir line", dotGit)
+		}
+		gitDir = relativeTo(gitRoot, strings.TrimPrefix(line, "gitdir: "))
+	}
+
+	commonDir = gitDir
+	if content, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = relativeTo(gitDir, strings.TrimSpace(string(content)))
+	}
+	return gitDir, commonDir, nil
+}
+
+// relativeTo resolves path, as git writes it in .git and commondir files,
+// against dir unless it is absolute.
+func relativeTo(dir, path string) string {
+	path = filepath.FromSlash(path)
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, path)
+}
+
 func (r *RealGitBranchWatcher) findGitRoot() string {
 	cmd := r.executor.Command("git", "rev-parse", "--show-toplevel")
 	cmd.SetDir(r.workspacePath)
@@ -576,7 +2692,22 @@ func (r *RealGitBranchWatcher) BranchChanged() <-chan struct{} {
 	return r.branchCh
 }
 
+func (r *RealGitBranchWatcher) OperationChanged() <-chan struct{} {
+	return r.operationCh
+}
+
+// Operation returns the rebase, merge, or cherry-pick in progress, or "".
+func (r *RealGitBranchWatcher) Operation() string {
+	if r.gitDir == "" {
+		return ""
+	}
+	return gitOperation(r.gitDir)
+}
+
 // Start begins watching git files. This blocks until the context is cancelled.
+//
+// Git replaces HEAD, refs, and packed-refs by renaming lock files over them,
+// so their directories are watched rather than the files themselves.
 func (r *RealGitBranchWatcher) Start(ctx context.Context) {
 	if r.gitDir == "" {
 		// Not a git repo, just block until context is cancelled
@@ -585,42 +2716,61 @@ func (r *RealGitBranchWatcher) Start(ctx context.Context) {
 	}
 
 	headPath := filepath.Join(r.gitDir, "HEAD")
-	if err := r.watcher.Add(headPath); err != nil {
-		log.Printf("Warning: could not watch .git/HEAD: %v", err)
+	if err := r.notifier().Add(r.gitDir); err != nil {
+		logger("git").Warn("could not watch .git/HEAD", "error", err)
 	} else {
-		log.Printf("Watching %s for branch switches", headPath)
+		logger("git").Info("watching for branch switches", "path", headPath)
 	}
 
-	// Watch current branch ref
-	currentBranchRefPath := r.currentBranchRefPath()
-	if currentBranchRefPath != "" {
-		if err := r.watcher.Add(currentBranchRefPath); err != nil {
-			log.Printf("Warning: could not watch branch ref: %v", err)
-		} else {
-			log.Printf("Watching %s for branch updates", currentBranchRefPath)
+	// Branches packed by git gc or git pack-refs live in packed-refs, which
+	// is shared by all worktrees.
+	packedRefsPath := filepath.Join(r.commonDir, "packed-refs")
+	if r.commonDir != r.gitDir {
+		if err := r.notifier().Add(r.commonDir); err != nil {
+			logger("git").Warn("could not watch packed-refs", "error", err)
 		}
 	}
 
+	// Watch current branch ref
+	currentBranchRefPath := r.currentBranchRefPath()
+	r.watchBranchRef(currentBranchRefPath)
+	branchSHA := r.branchSHA()
+	r.watchSubmodules()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 
-		case event, ok := <-r.watcher.Events:
+		case event, ok := <-r.notifier().Events:
 			if !ok {
 				return
 			}
+			logger("git").Debug("git event", "path", event.Name, "op", event.Op.String())
+
+			if filepath.Dir(event.Name) == r.gitDir && gitOperationMarkers[filepath.Base(event.Name)] != "" {
+				// Non-blocking send
+				select {
+				case r.operationCh <- struct{}{}:
+				default:
+				}
+				continue
+			}
+
+			if s := r.submoduleFor(event.Name); s != nil {
+				r.submoduleChanged(s)
+				continue
+			}
 
 			if event.Name == headPath {
-				log.Println("Git HEAD changed (branch switch)")
+				logger("git").Info("git HEAD changed (branch switch)")
 				// Update watch for new branch ref
 				newBranchRefPath := r.currentBranchRefPath()
 				if newBranchRefPath != "" && newBranchRefPath != currentBranchRefPath {
-					if err := r.watcher.Add(newBranchRefPath); err == nil {
-						log.Printf("Now watching %s for branch updates", newBranchRefPath)
-						currentBranchRefPath = newBranchRefPath
-					}
+					r.watchBranchRef(newBranchRefPath)
+					currentBranchRefPath = newBranchRefPath
 				}
+				branchSHA = r.branchSHA()
 				// Non-blocking send
 				select {
 				case r.headCh <- struct{}{}:
@@ -629,9 +2779,16 @@ func (r *RealGitBranchWatcher) Start(ctx context.Context) {
 				continue
 			}
 
-			// Check if this is a branch ref update (any file in .git/refs/heads/)
-			if strings.HasPrefix(event.Name, filepath.Join(r.gitDir, "refs", "heads")) {
-				log.Println("Branch ref updated (commit/pull/merge/rebase)")
+			// A branch ref update (commit/pull/merge/rebase) rewrites the
+			// loose ref or packed-refs. Packing a loose ref removes it but
+			// keeps the commit, which is not an update.
+			if event.Name == currentBranchRefPath || event.Name == packedRefsPath {
+				sha := r.branchSHA()
+				if sha == branchSHA {
+					continue
+				}
+				branchSHA = sha
+				logger("git").Info("branch ref updated (commit/pull/merge/rebase)")
 				// Non-blocking send
 				select {
 				case r.branchCh <- struct{}{}:
@@ -640,13 +2797,74 @@ func (r *RealGitBranchWatcher) Start(ctx context.Context) {
 				continue
 			}
 
-		case err, ok := <-r.watcher.Errors:
+		case err, ok := <-r.notifier().Errors:
 			if !ok {
 				return
 			}
-			log.Printf("Git watcher error: %v", err)
+			logger("git").Error("git watcher error", "error", err)
+		}
+	}
+}
+
+// watchBranchRef watches the directory of the loose ref file at path, which
+// may not exist while the branch is packed.
+func (r *RealGitBranchWatcher) watchBranchRef(path string) {
+	if path == "" {
+		return
+	}
+	if err := r.notifier().Add(filepath.Dir(path)); err != nil {
+		logger("git").Warn("could not watch branch ref", "error", err)
+		return
+	}
+	logger("git").Info("watching for branch updates", "path", path)
+}
+
+// branchSHA returns the commit the current branch points to, read from its
+// loose ref file or, once packed, from packed-refs. It is empty for a
+// detached HEAD.
+func (r *RealGitBranchWatcher) branchSHA() string {
+	ref, sha := readHead(r.gitDir, r.commonDir)
+	if ref == "" {
+		return ""
+	}
+	return sha
+}
+
+// readHead returns the ref HEAD in gitDir points to, "" if it is detached,
+// and the commit it resolves to through refs in commonDir.
+func readHead(gitDir, commonDir string) (ref, sha string) {
+	content, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", ""
+	}
+	ref = parseGitHeadRef(string(content))
+	if ref == "" {
+		return "", strings.TrimSpace(string(content))
+	}
+	if loose, err := os.ReadFile(filepath.Join(commonDir, ref)); err == nil {
+		return ref, strings.TrimSpace(string(loose))
+	}
+	packed, err := os.ReadFile(filepath.Join(commonDir, "packed-refs"))
+	if err != nil {
+		return ref, ""
+	}
+	return ref, parsePackedRef(string(packed), ref)
+}
+
+// parsePackedRef returns the object ref names in the content of a
+// packed-refs file, or "" if it is not listed.
+func parsePackedRef(content, ref string) string {
+	for line := range strings.Lines(content) {
+		// Skip the header and the peeled objects of annotated tags.
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "^") {
+			continue
+		}
+		sha, name, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if ok && name == ref {
+			return sha
 		}
 	}
+	return ""
 }
 
 func (r *RealGitBranchWatcher) currentBranchRefPath() string {
@@ -663,7 +2881,7 @@ func (r *RealGitBranchWatcher) currentBranchRefPath() string {
 	if ref == "" {
 		return ""
 	}
-	return filepath.Join(r.gitDir, ref)
+	return filepath.Join(r.commonDir, ref)
 }
 
 // parseGitHeadRef parses the content of a .git/HEAD file and returns the ref path.
@@ -677,8 +2895,30 @@ func parseGitHeadRef(content string) string {
 }
 
 func (r *RealGitBranchWatcher) Close() error {
-	releaseWatcher()
-	return r.watcher.Close()
+	r.limit.release()
+	return r.notifier().Close()
+}
+
+// notifier returns the current fsnotify watcher.
+func (r *RealGitBranchWatcher) notifier() *fsnotify.Watcher {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.watcher
+}
+
+// Reopen replaces the fsnotify watcher with a new one, so that Start can be
+// called again after its loop has ended unexpectedly.
+func (r *RealGitBranchWatcher) Reopen() error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	old := r.watcher
+	r.watcher = w
+	r.mu.Unlock()
+	_ = old.Close()
+	return nil
 }
 
 // WatcherConfig holds watcher configuration.
@@ -686,23 +2926,124 @@ type WatcherConfig struct {
 	WorkspacePath    string
 	RecursiveDirs    []string
 	NonRecursiveDirs []string
+
+	// RestartCooldown is the minimum time between restarts. Restarts requested
+	// sooner (e.g. during an interactive rebase) are coalesced into one at the
+	// end of the cooldown. Zero disables throttling.
+	RestartCooldown time.Duration
+
+	// RestartOnLockfile restarts svelte-check LockfileGrace after the last
+	// change to a package manager lockfile, once an install has settled.
+	RestartOnLockfile bool
+	LockfileGrace     time.Duration
+
+	// Ignore lists globs, relative to the workspace, of paths whose events
+	// are dropped and whose directories are not watched, e.g. "coverage/**"
+	// or "**/*.stories.ts". "**" matches any number of directories.
+	Ignore []string
+
+	// RouteDirs are the workspace-relative routes directories (kit.files.routes)
+	// of the projects. Route files elsewhere do not trigger svelte-kit sync.
+	// Empty means route files anywhere count.
+	RouteDirs []string
+
+	// SyncOn lists globs, relative to the workspace, of further files whose
+	// changes require svelte-kit sync, such as param matchers and app.d.ts.
+	SyncOn []string
+
+	// RestartOn lists globs, relative to the workspace, of files whose
+	// changes restart svelte-check, such as ambient type declarations that
+	// its own watcher may miss. Their directories are watched as needed.
+	RestartOn []string
+
+	// Limit, if set, is the WatcherLimit of the daemon's watchers, whose
+	// usage Status reports.
+	Limit *WatcherLimit
+
+	// Audit, if set, records every event and the restarts it leads to.
+	Audit *AuditLog
+
+	// Rules run further actions when matching files change. Their
+	// directories are watched as needed.
+	Rules []EventRule
+
+	// AutoWatch lists globs, relative to the workspace, of directories
+	// such as packages/*/src to watch recursively, including those created
+	// while the Watcher runs.
+	AutoWatch []string
+}
+
+// WatcherStatus reports the watcher's restart activity in GET /status.
+type WatcherStatus struct {
+	Restarts           int `json:"restarts"`
+	RestartsSuppressed int `json:"restartsSuppressed"` // coalesced by the cooldown
+
+	// Watches is the number of paths with a live OS watch, for FSWatchers
+	// that hold one per directory.
+	Watches int `json:"watches,omitempty"`
+
+	// Watchers is the number of filesystem watchers the daemon holds open,
+	// of at most MaxWatchers (--max-watchers).
+	Watchers    int `json:"watchers,omitempty"`
+	MaxWatchers int `json:"maxWatchers,omitempty"`
+
+	// What the watcher saw since the daemon started: filesystem events, by
+	// workspace-relative directory, those dropped by ignore globs, events
+	// lost to OS queue overflows, and what the events triggered. Rescans
+	// counts full rescans and created paths checked for new directories.
+	Events          int            `json:"events"`
+	EventsByDir     map[string]int `json:"eventsByDir,omitempty"`
+	Dropped         int            `json:"dropped"`
+	Overflows       int            `json:"overflows"`
+	Errors          int            `json:"errors"`
+	Rescans         int            `json:"rescans"`
+	SyncTriggers    int            `json:"syncTriggers"`
+	RestartTriggers int            `json:"restartTriggers"`
+	RuleTriggers    map[string]int `json:"ruleTriggers,omitempty"` // by rule name
+	LastEvent       *WatchEvent    `json:"lastEvent,omitempty"`
+
+	// Degraded is set when some directories could not be watched because
+	// the OS ran out of watches.
+	Degraded *WatchDegradation `json:"degraded,omitempty"`
 }
 
 // WatcherCallbacks holds the callback functions for the watcher.
 type WatcherCallbacks struct {
 	OnRestart    func() // Called when svelte-check should restart
 	OnSvelteSync func() // Called when svelte-kit sync should run
+
+	// OnDependenciesChanged, if set, is called with the workspace-relative
+	// path of a package manager lockfile whenever it changes.
+	OnDependenciesChanged func(lockfile string)
+
+	// OnSourceChange, if set, is called when any watched file is written,
+	// created, removed, or renamed. Checkers without a watch mode use it.
+	OnSourceChange func()
 }
 
 // Watcher watches files and triggers callbacks on changes.
 type Watcher struct {
 	config           WatcherConfig
+	fsMu             sync.Mutex // guards fsWatcher, which ReplaceFSWatcher swaps
 	fsWatcher        FSWatcher
 	callbacks        WatcherCallbacks
 	gitBranchWatcher GitBranchWatcher // can be nil if not a git repo
+	logger           *slog.Logger
 
 	restartDebouncer *Debouncer
+	restartThrottle  *Throttle
 	syncDebouncer    *Debouncer
+	configDebouncer  *Debouncer // syncs, then restarts
+	lockDebouncer    *Debouncer // nil without RestartOnLockfile
+	sourceDebouncer  *Debouncer // nil without OnSourceChange
+	rules            []*watchRule
+
+	gitOperation string // the git operation restarts are held for, if any
+	gitStates    gitStateHistory
+
+	dirsMu sync.Mutex // guards config.RecursiveDirs and NonRecursiveDirs
+
+	stats watcherStats
 }
 
 // svelteKitRouteFiles lists all SvelteKit route files that need svelte-kit sync
@@ -727,86 +3068,573 @@ func isRouteFile(filename string) bool {
 	return svelteKitRouteFiles[filepath.Base(filename)]
 }
 
+// projectConfigPatterns match configuration files that change how svelte-check
+// compiles the project, so editing one requires a sync and a restart. The
+// .env files define the variables svelte-kit sync types $env/static/* with.
+var projectConfigPatterns = []string{"svelte.config.*", "vite.config.*", "tsconfig*.json", "package.json", ".env", ".env.*"}
+
+// isProjectConfigFile reports whether filename is a project configuration
+// file. Vite's temporary vite.config.*.timestamp-*.mjs bundles are not.
+func isProjectConfigFile(filename string) bool {
+	base := filepath.Base(filename)
+	if strings.Contains(base, ".timestamp-") {
+		return false
+	}
+	for _, pattern := range projectConfigPatterns {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isLockfile reports whether filename is a package manager lockfile.
+func isLockfile(filename string) bool {
+	base := filepath.Base(filename)
+	for _, l := range packageManagerLockfiles {
+		if l.file == base && strings.Contains(base, "lock") {
+			return true
+		}
+	}
+	return false
+}
+
+// isProjectRoot reports whether dir is the workspace root or one of the
+// non-recursively watched directories, which by default are the project
+// roots.
+func (w *Watcher) isProjectRoot(dir string) bool {
+	if dir == filepath.Clean(w.config.WorkspacePath) {
+		return true
+	}
+	for _, d := range w.WatchDirs().NonRecursive {
+		if dir == filepath.Join(w.config.WorkspacePath, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateGlobs reports the first malformed glob.
+func ValidateGlobs(globs []string) error {
+	for _, g := range globs {
+		if _, err := filepath.Match(g, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %w", g, err)
+		}
+	}
+	return nil
+}
+
+// isRouteDirEvent reports whether event creates, removes, or renames a
+// directory below a routes directory, which changes the generated route
+// types even before it holds a route file. Removed paths cannot be
+// inspected, so a name without an extension, or a "[param]" or "(group)"
+// segment, is taken to be a directory. Without RouteDirs, src/routes is
+// assumed.
+func (w *Watcher) isRouteDirEvent(event fsnotify.Event) bool {
+	roots := w.config.RouteDirs
+	if len(roots) == 0 {
+		roots = []string{DefaultKitPaths.Routes}
+	}
+	under := slices.ContainsFunc(roots, func(dir string) bool {
+		return isWithin(filepath.Join(w.config.WorkspacePath, dir), event.Name)
+	})
+	if !under {
+		return false
+	}
+
+	switch {
+	case event.Has(fsnotify.Create):
+		info, err := os.Stat(event.Name)
+		return err == nil && info.IsDir()
+	case event.Has(fsnotify.Remove | fsnotify.Rename):
+		name := filepath.Base(event.Name)
+		return filepath.Ext(name) == "" ||
+			strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]") ||
+			strings.HasPrefix(name, "(") && strings.HasSuffix(name, ")")
+	}
+	return false
+}
+
+// isRouteFile reports whether path is a SvelteKit route file inside one of
+// the configured routes directories.
+func (w *Watcher) isRouteFile(path string) bool {
+	if !isRouteFile(path) {
+		return false
+	}
+	if len(w.config.RouteDirs) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(w.config.RouteDirs, func(dir string) bool {
+		return isWithin(filepath.Join(w.config.WorkspacePath, dir), path)
+	})
+}
+
+// ignored reports whether path, absolute or relative to the workspace,
+// matches one of the ignore globs.
+func (w *Watcher) ignored(p string) bool {
+	return w.matchesGlob(w.config.Ignore, p)
+}
+
+// matchesGlob reports whether path, absolute or relative to the workspace,
+// matches one of globs.
+func (w *Watcher) matchesGlob(globs []string, p string) bool {
+	if len(globs) == 0 {
+		return false
+	}
+	if filepath.IsAbs(p) {
+		rel, err := filepath.Rel(w.config.WorkspacePath, p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return false
+		}
+		p = rel
+	}
+	parts := strings.Split(filepath.ToSlash(p), "/")
+	for _, g := range globs {
+		if matchDoubleStar(strings.Split(g, "/"), parts) {
+			return true
+		}
+	}
+	return false
+}
+
+// globDir returns the directory, relative to the workspace, that holds all
+// paths matching glob, and whether they may be below it rather than in it.
+func globDir(glob string) (dir string, recursive bool) {
+	parts := strings.Split(glob, "/")
+	i := 0
+	for i < len(parts)-1 && !strings.ContainsAny(parts[i], "*?[") {
+		i++
+	}
+	return filepath.Join(".", filepath.FromSlash(strings.Join(parts[:i], "/"))), i < len(parts)-1
+}
+
+// globDirs returns the existing directories that must be watched for
+// changes matching the RestartOn globs or a rule's and are not covered by
+// dirs.
+func (w *Watcher) globDirs(dirs WatchDirs) []watchedPath {
+	globs := slices.Clone(w.config.RestartOn)
+	for _, r := range w.config.Rules {
+		globs = append(globs, r.Globs...)
+	}
+	var paths []watchedPath
+	for _, g := range globs {
+		dir, recursive := globDir(g)
+		covered := slices.ContainsFunc(dirs.Recursive, func(d string) bool {
+			return dir == filepath.Clean(d) || isWithin(filepath.Clean(d), dir)
+		}) || (!recursive && slices.ContainsFunc(dirs.NonRecursive, func(d string) bool {
+			return dir == filepath.Clean(d)
+		}))
+		wp := watchedPath{path: filepath.Join(w.config.WorkspacePath, dir), recursive: recursive}
+		if covered || slices.Contains(paths, wp) {
+			continue
+		}
+		if info, err := os.Stat(wp.path); err != nil || !info.IsDir() {
+			continue
+		}
+		paths = append(paths, wp)
+	}
+	return paths
+}
+
 // NewWatcher creates a new Watcher with the given configuration.
 // gitBranchWatcher can be nil if not watching a git repository.
-func NewWatcher(config WatcherConfig, callbacks WatcherCallbacks, fsWatcher FSWatcher, gitBranchWatcher GitBranchWatcher) *Watcher {
-	const debounceInterval = 250 * time.Millisecond
-	return &Watcher{
+func NewWatcher(config WatcherConfig, callbacks WatcherCallbacks, fsWatcher FSWatcher, gitBranchWatcher GitBranchWatcher, opts ...Option) *Watcher {
+	o := newOptions(opts)
+	debounceInterval := o.debounce
+	var w *Watcher
+	onRestart := callbacks.OnRestart
+	if onRestart != nil {
+		onRestart = func() {
+			w.stats.restarting()
+			config.Audit.Record("restart", map[string]any{"reason": "watcher"})
+			callbacks.OnRestart()
+		}
+	}
+	restartThrottle := NewThrottle(config.RestartCooldown, onRestart)
+	w = &Watcher{
 		config:           config,
 		fsWatcher:        fsWatcher,
 		callbacks:        callbacks,
 		gitBranchWatcher: gitBranchWatcher,
-		restartDebouncer: NewDebouncer(debounceInterval, callbacks.OnRestart),
+		logger:           o.logger.With("subsystem", "watcher"),
+		restartDebouncer: NewDebouncer(debounceInterval, restartThrottle.Trigger),
+		restartThrottle:  restartThrottle,
 		syncDebouncer:    NewDebouncer(debounceInterval, callbacks.OnSvelteSync),
+		configDebouncer: NewDebouncer(debounceInterval, func() {
+			if callbacks.OnSvelteSync != nil {
+				callbacks.OnSvelteSync()
+			}
+			restartThrottle.Trigger()
+		}),
+	}
+	if callbacks.OnSourceChange != nil {
+		w.sourceDebouncer = NewDebouncer(debounceInterval, callbacks.OnSourceChange)
 	}
+	if config.RestartOnLockfile {
+		w.lockDebouncer = NewDebouncer(max(config.LockfileGrace, debounceInterval), restartThrottle.Trigger)
+	}
+	w.addRules()
+	w.recordGitState()
+	return w
 }
 
 // Start begins watching files. This blocks until the context is cancelled.
 func (w *Watcher) Start(ctx context.Context) {
-	for _, dir := range w.config.NonRecursiveDirs {
+	fsw := w.fs()
+	if pf, ok := fsw.(PathFilter); ok && len(w.config.Ignore) > 0 {
+		pf.SetIgnore(w.ignored)
+	}
+
+	dirs := w.WatchDirs()
+	for _, dir := range dirs.NonRecursive {
 		absDir := filepath.Join(w.config.WorkspacePath, dir)
-		if err := w.fsWatcher.Add(absDir, false); err != nil {
-			log.Printf("Warning: could not watch %s: %v", absDir, err)
+		if err := fsw.Add(absDir, false); err != nil {
+			w.logger.Warn("could not watch directory", "path", absDir, "error", err)
 		}
 	}
 
-	for _, dir := range w.config.RecursiveDirs {
+	for _, dir := range dirs.Recursive {
 		absDir := filepath.Join(w.config.WorkspacePath, dir)
-		if err := w.fsWatcher.Add(absDir, true); err != nil {
-			log.Printf("Warning: could not watch %s recursively: %v", absDir, err)
+		if err := fsw.Add(absDir, true); err != nil {
+			w.logger.Warn("could not watch directory recursively", "path", absDir, "error", err)
+		}
+	}
+
+	for _, wp := range w.globDirs(dirs) {
+		if err := fsw.Add(wp.path, wp.recursive); err != nil {
+			w.logger.Warn("could not watch directory", "path", wp.path, "error", err)
 		}
 	}
+	w.autoWatch(".")
 
 	// Get git channels (may be nil if no git watcher)
-	var headCh, branchCh <-chan struct{}
+	var headCh, branchCh, operationCh <-chan struct{}
+	var operations GitOperationWatcher
 	if w.gitBranchWatcher != nil {
 		headCh = w.gitBranchWatcher.HeadChanged()
 		branchCh = w.gitBranchWatcher.BranchChanged()
+		if ow, ok := w.gitBranchWatcher.(GitOperationWatcher); ok {
+			operations, operationCh = ow, ow.OperationChanged()
+			w.gitOperationChanged(operations)
+		}
 	}
 
+	// Filesystem events are collected for eventBatchWindow before acting
+	// on them.
+	var batch []fsnotify.Event
+	var batchTimer <-chan time.Time
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 
 		case <-headCh:
-			log.Println("Git HEAD changed (branch switch), restarting svelte-check...")
-			w.restartDebouncer.Trigger()
+			w.gitChanged("Git HEAD changed (branch switch)")
 
 		case <-branchCh:
-			log.Println("Branch ref updated (commit/pull/merge/rebase), restarting svelte-check...")
-			w.restartDebouncer.Trigger()
+			w.gitChanged("Branch ref updated (commit/pull/merge/rebase)")
 
-		case event, ok := <-w.fsWatcher.Events():
+		case <-operationCh:
+			w.gitOperationChanged(operations)
+
+		case event, ok := <-fsw.Events():
 			if !ok {
 				return
 			}
-
-			// Check if this is a SvelteKit route file change
-			if isRouteFile(event.Name) {
-				if event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
-					log.Printf("Route file changed: %s, running svelte-kit sync...", filepath.Base(event.Name))
-					w.syncDebouncer.Trigger()
-				}
+			batch = append(batch, event)
+			if batchTimer == nil {
+				batchTimer = time.After(eventBatchWindow)
 			}
 
-			// Handle new directories - rescan to pick up new subdirectories
-			if event.Has(fsnotify.Create) {
-				_ = w.fsWatcher.Rescan()
-			}
+		case <-batchTimer:
+			w.handleEvents(batch)
+			batch, batchTimer = nil, nil
 
-		case err, ok := <-w.fsWatcher.Errors():
+		case err, ok := <-fsw.Errors():
 			if !ok {
 				return
 			}
-			log.Printf("Watcher error: %v", err)
+			w.stats.error(err)
+			w.logger.Error("watcher error", "error", err)
+		}
+	}
+}
+
+// eventBatchWindow is how long the watcher collects filesystem events before
+// acting on them, so that a branch switch or rm -rf touching thousands of
+// files triggers each sync, restart, and rescan once.
+const eventBatchWindow = 20 * time.Millisecond
+
+// eventTrigger collects the files in a batch of events that call for the
+// same action.
+type eventTrigger struct {
+	first string
+	n     int
+}
+
+func (t *eventTrigger) add(path string) {
+	if t.n == 0 {
+		t.first = filepath.Base(path)
+	}
+	t.n++
+}
+
+// String returns e.g. "+page.ts", or "+page.ts and 11 more".
+func (t eventTrigger) String() string {
+	if t.n > 1 {
+		return fmt.Sprintf("%s and %d more", t.first, t.n-1)
+	}
+	return t.first
+}
+
+// handleEvents acts on a batch of filesystem events in one pass. Each sync,
+// restart, and rescan they call for is triggered once, and directories
+// created or removed below another one in the same batch are not handled
+// separately.
+func (w *Watcher) handleEvents(events []fsnotify.Event) {
+	const changed = fsnotify.Write | fsnotify.Create | fsnotify.Remove | fsnotify.Rename
+	var config, restartOn, routeFiles, routeDirs, syncOn eventTrigger
+	var lockfiles, created, removed []string
+	source := false
+	rules := make([]eventTrigger, len(w.rules))
+
+	for _, event := range events {
+		dropped := w.ignored(event.Name)
+		w.stats.event(w.config.WorkspacePath, event, dropped)
+		w.config.Audit.Record("event", map[string]any{"path": w.relPath(event.Name), "op": event.Op.String(), "ignored": dropped})
+		w.logger.Debug("filesystem event", "path", w.relPath(event.Name), "op", event.Op.String(), "ignored", dropped)
+		if dropped {
+			continue
+		}
+
+		// Config edits change compiler options: sync, then restart.
+		if isProjectConfigFile(event.Name) && w.isProjectRoot(filepath.Dir(event.Name)) && event.Has(changed) {
+			config.add(event.Name)
+		} else if w.matchesGlob(w.config.RestartOn, event.Name) && event.Has(changed) {
+			restartOn.add(event.Name)
+		}
+
+		if isLockfile(event.Name) && w.isProjectRoot(filepath.Dir(event.Name)) && event.Has(changed) &&
+			!slices.Contains(lockfiles, event.Name) {
+			lockfiles = append(lockfiles, event.Name)
+		}
+
+		// Check if this is a SvelteKit route file change
+		if w.isRouteFile(event.Name) {
+			if event.Has(fsnotify.Create | fsnotify.Remove | fsnotify.Rename) {
+				routeFiles.add(event.Name)
+			}
+		} else if w.isRouteDirEvent(event) {
+			routeDirs.add(event.Name)
+		} else if w.matchesGlob(w.config.SyncOn, event.Name) && event.Has(changed) {
+			syncOn.add(event.Name)
+		}
+
+		w.matchRules(event, rules)
+
+		source = source || event.Has(changed)
+		if event.Has(fsnotify.Remove | fsnotify.Rename) {
+			removed = append(removed, event.Name)
+		}
+		if event.Has(fsnotify.Create) {
+			created = append(created, event.Name)
+		}
+	}
+
+	if config.n > 0 {
+		w.logger.Info("config file changed, running svelte-kit sync and restarting svelte-check", "trigger", config.String())
+		w.stats.syncTriggered()
+		w.stats.restartTriggered(TriggerFS)
+		w.configDebouncer.Trigger()
+	}
+	if restartOn.n > 0 {
+		w.logger.Info("restarting svelte-check", "trigger", restartOn.String())
+		w.stats.restartTriggered(TriggerFS)
+		w.restartDebouncer.Trigger()
+	}
+	for _, name := range lockfiles {
+		w.lockfileChanged(name)
+	}
+	for _, t := range []struct {
+		trigger eventTrigger
+		msg     string
+	}{
+		{routeFiles, "route file changed, running svelte-kit sync"},
+		{routeDirs, "route directory changed, running svelte-kit sync"},
+		{syncOn, "running svelte-kit sync"},
+	} {
+		if t.trigger.n > 0 {
+			w.logger.Info(t.msg, "trigger", t.trigger.String())
+			w.stats.syncTriggered()
+			w.syncDebouncer.Trigger()
+		}
+	}
+
+	w.triggerRules(rules)
+
+	if w.sourceDebouncer != nil && source {
+		w.sourceDebouncer.Trigger()
+	}
+
+	// Drop the watches of removed directories, then watch new ones: just
+	// the created subtrees if the FSWatcher supports it, otherwise rescan
+	// everything once. New directories matching an AutoWatch pattern become
+	// watch roots.
+	sw, subtrees := w.fs().(SubtreeWatcher)
+	if subtrees {
+		for _, path := range outermostPaths(removed) {
+			sw.RemoveDeleted(path)
+		}
+	}
+	if len(created) == 0 {
+		return
+	}
+	created = outermostPaths(created)
+	if subtrees {
+		for _, path := range created {
+			w.stats.rescan()
+			if err := sw.AddCreated(path); err != nil {
+				w.logger.Warn("could not watch directory", "path", path, "error", err)
+			}
+		}
+	} else {
+		w.stats.rescan()
+		_ = w.fs().Rescan()
+	}
+	for _, path := range created {
+		w.autoWatch(w.relPath(path))
+	}
+}
+
+// outermostPaths returns paths without duplicates or those below another of
+// them, in their original order.
+func outermostPaths(paths []string) []string {
+	all := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		all[p] = true
+	}
+	var outer []string
+	seen := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		below := false
+		for dir := filepath.Dir(p); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+			if all[dir] {
+				below = true
+				break
+			}
 		}
+		if !below {
+			outer = append(outer, p)
+		}
+	}
+	return outer
+}
+
+// relPath returns path relative to the workspace, or as is if it cannot be.
+func (w *Watcher) relPath(path string) string {
+	rel, err := filepath.Rel(w.config.WorkspacePath, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
+// lockfileChanged reports a dependency change and schedules a restart.
+func (w *Watcher) lockfileChanged(name string) {
+	rel := w.relPath(name)
+	w.logger.Info("lockfile changed", "path", rel)
+	if w.callbacks.OnDependenciesChanged != nil {
+		w.callbacks.OnDependenciesChanged(rel)
+	}
+	if w.lockDebouncer != nil {
+		w.stats.restartTriggered(TriggerFS)
+		w.lockDebouncer.Trigger()
+	}
+}
+
+// gitOperationChanged holds restarts while a git operation is in progress and
+// releases them, restarting once if any were triggered, when it completes.
+func (w *Watcher) gitOperationChanged(ow GitOperationWatcher) {
+	op := ow.Operation()
+	if op == w.gitOperation {
+		return
+	}
+	switch {
+	case op == "":
+		w.logger.Info("git operation completed, resuming restarts", "operation", w.gitOperation)
+		w.config.Audit.Record("git-operation", map[string]any{"operation": w.gitOperation, "state": "completed"})
+		w.restartThrottle.Release()
+	case w.gitOperation == "":
+		w.logger.Info("git operation in progress, deferring restarts until it completes", "operation", op)
+		w.config.Audit.Record("git-operation", map[string]any{"operation": op, "state": "started"})
+		w.restartThrottle.Hold()
+	}
+	w.gitOperation = op
+}
+
+// Status returns the watcher's counters, watch count, and any degraded
+// watching.
+func (w *Watcher) Status() WatcherStatus {
+	restarts, suppressed := w.restartThrottle.Counts()
+	status := WatcherStatus{Restarts: restarts, RestartsSuppressed: suppressed}
+	if wc, ok := w.fs().(WatchCounter); ok {
+		status.Watches = wc.WatchCount()
+	}
+	if l := w.config.Limit; l != nil {
+		status.Watchers, status.MaxWatchers = l.Count(), l.Max()
 	}
+	if dr, ok := w.fs().(DegradationReporter); ok {
+		status.Degraded = dr.Degradation()
+	}
+	w.stats.fill(&status)
+	return status
+}
+
+// LastTrigger returns the trigger of the restart in progress, or of the last
+// one, for OnRestart to pass to Runner.Restart with ContextWithTrigger.
+func (w *Watcher) LastTrigger() CycleTrigger {
+	w.stats.mu.Lock()
+	defer w.stats.mu.Unlock()
+	return w.stats.lastTrigger
 }
 
 // Close stops the watcher.
 func (w *Watcher) Close() error {
 	w.restartDebouncer.Stop()
+	w.restartThrottle.Stop()
 	w.syncDebouncer.Stop()
-	return w.fsWatcher.Close()
+	w.configDebouncer.Stop()
+	if w.lockDebouncer != nil {
+		w.lockDebouncer.Stop()
+	}
+	if w.sourceDebouncer != nil {
+		w.sourceDebouncer.Stop()
+	}
+	for _, r := range w.rules {
+		r.debouncer.Stop()
+	}
+	return w.fs().Close()
+}
+
+// fs returns the current filesystem watcher.
+func (w *Watcher) fs() FSWatcher {
+	w.fsMu.Lock()
+	defer w.fsMu.Unlock()
+	return w.fsWatcher
+}
+
+// ReplaceFSWatcher closes the filesystem watcher and uses fsWatcher instead,
+// so that Start can be called again after its loop has ended unexpectedly.
+// Start watches the configured directories again.
+func (w *Watcher) ReplaceFSWatcher(fsWatcher FSWatcher) {
+	w.fsMu.Lock()
+	old := w.fsWatcher
+	w.fsWatcher = fsWatcher
+	w.fsMu.Unlock()
+	_ = old.Close()
 }
@@ -2,87 +2,51 @@ package internal
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/tylergannon/svelte-check-server/pkg/types"
 )
 
 // =============================================================================
 // Diagnostic Types
 // =============================================================================
 
-// Position represents a location in a file.
-type Position struct {
-	Line      int `json:"line"`
-	Character int `json:"character"`
-}
-
-// Diagnostic represents a single error or warning from svelte-check.
-// The Timestamp field is extracted from the machine-verbose output prefix
-// and added to the struct for clean JSONL output.
-type Diagnostic struct {
-	Timestamp int64    `json:"timestamp"`
-	Type      string   `json:"type"` // "ERROR" or "WARNING"
-	Filename  string   `json:"filename"`
-	Start     Position `json:"start"`
-	End       Position `json:"end"`
-	Message   string   `json:"message"`
-	Code      any      `json:"code"`             // int for TS errors, string for Svelte warnings
-	Source    string   `json:"source,omitempty"` // "js", "ts", "svelte", "css", or empty
-}
-
-// =============================================================================
-// Events
-// =============================================================================
-
-// SvelteCheckEvent represents an event from the svelte-check output stream.
-type SvelteCheckEvent interface {
-	implementsSvelteCheckEvent()
-}
-
-// SvelteWatchCheckStart is emitted when svelte-check begins a new check cycle.
-type SvelteWatchCheckStart struct {
-	Timestamp int64  `json:"timestamp"`
-	Workspace string `json:"workspace"`
-}
-
-func (SvelteWatchCheckStart) implementsSvelteCheckEvent() {}
-
-// SvelteWatchCheckComplete is emitted when svelte-check finishes a check cycle.
-type SvelteWatchCheckComplete struct {
-	Timestamp         int64        `json:"timestamp"`
-	Diagnostics       []Diagnostic `json:"diagnostics"`
-	FileCount         int          `json:"fileCount"`
-	ErrorCount        int          `json:"errorCount"`
-	WarningCount      int          `json:"warningCount"`
-	FilesWithProblems int          `json:"filesWithProblems"`
-}
-
-func (SvelteWatchCheckComplete) implementsSvelteCheckEvent() {}
+// The diagnostic and event types are defined in pkg/types, whose JSON is a
+// stable schema for other programs.
+type (
+	Position   = types.Position
+	Diagnostic = types.Diagnostic
 
-// SvelteWatchFailure is emitted when svelte-check encounters a runtime error.
-type SvelteWatchFailure struct {
-	Timestamp int64  `json:"timestamp"`
-	Message   string `json:"message"`
-}
+	// SvelteCheckEvent represents an event from the svelte-check output
+	// stream, or DependenciesChanged.
+	SvelteCheckEvent = types.Event
 
-func (SvelteWatchFailure) implementsSvelteCheckEvent() {}
+	SvelteWatchCheckStart    = types.SvelteWatchCheckStart
+	SvelteWatchCheckComplete = types.SvelteWatchCheckComplete
+	SvelteWatchFailure       = types.SvelteWatchFailure
+)
 
 // =============================================================================
 // Interpreter
 // =============================================================================
 
 // InterpretOutput reads svelte-check --output machine-verbose output and sends events to the channel.
-// It blocks until the reader is closed or returns an error.
+// It blocks until the reader is closed, ctx is done, or the reader returns an error.
 // The channel is NOT closed when the function returns - caller owns the channel.
-func InterpretOutput(r io.Reader, events chan<- SvelteCheckEvent) error {
+func InterpretOutput(ctx context.Context, r io.Reader, events chan<- SvelteCheckEvent) error {
+	log := logger("interpreter")
 	scanner := bufio.NewScanner(r)
 	var diagnostics []Diagnostic
 
 	for scanner.Scan() {
 		line := scanner.Text()
+		log.Debug("output line", "line", line)
 
 		// Skip empty lines and comments (for test fixtures)
 		if line == "" || strings.HasPrefix(line, "#") {
@@ -99,9 +63,11 @@ func InterpretOutput(r io.Reader, events chan<- SvelteCheckEvent) error {
 		if after, ok0 := strings.CutPrefix(rest, "START "); ok0 {
 			workspace := strings.Trim(after, `"`)
 			diagnostics = nil // Reset for new cycle
-			events <- SvelteWatchCheckStart{
+			if err := sendEvent(ctx, events, SvelteWatchCheckStart{
 				Timestamp: timestamp,
 				Workspace: workspace,
+			}); err != nil {
+				return err
 			}
 			continue
 		}
@@ -109,13 +75,15 @@ func InterpretOutput(r io.Reader, events chan<- SvelteCheckEvent) error {
 		// Check for COMPLETED event: 1770310077701 COMPLETED 159 FILES 9 ERRORS 7 WARNINGS 4 FILES_WITH_PROBLEMS
 		if strings.HasPrefix(rest, "COMPLETED ") {
 			fileCount, errorCount, warningCount, filesWithProblems := parseCompletedLine(rest)
-			events <- SvelteWatchCheckComplete{
+			if err := sendEvent(ctx, events, SvelteWatchCheckComplete{
 				Timestamp:         timestamp,
 				Diagnostics:       diagnostics,
 				FileCount:         fileCount,
 				ErrorCount:        errorCount,
 				WarningCount:      warningCount,
 				FilesWithProblems: filesWithProblems,
+			}); err != nil {
+				return err
 			}
 			diagnostics = nil // Reset for next cycle
 			continue
@@ -124,9 +92,11 @@ func InterpretOutput(r io.Reader, events chan<- SvelteCheckEvent) error {
 		// Check for FAILURE event: 1770310077701 FAILURE "Connection closed"
 		if after, ok0 := strings.CutPrefix(rest, "FAILURE "); ok0 {
 			message := strings.Trim(after, `"`)
-			events <- SvelteWatchFailure{
+			if err := sendEvent(ctx, events, SvelteWatchFailure{
 				Timestamp: timestamp,
 				Message:   message,
+			}); err != nil {
+				return err
 			}
 			continue
 		}
@@ -144,6 +114,17 @@ func InterpretOutput(r io.Reader, events chan<- SvelteCheckEvent) error {
 	return scanner.Err()
 }
 
+// sendEvent sends e on events, or returns ctx's cause if ctx is done first, so
+// an interpreter whose reader has gone away can be abandoned.
+func sendEvent(ctx context.Context, events chan<- SvelteCheckEvent, e SvelteCheckEvent) error {
+	select {
+	case events <- e:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
 // parseTimestampPrefix extracts the timestamp and remaining content from a line.
 // Returns (timestamp, rest, ok).
 func parseTimestampPrefix(line string) (int64, string, bool) {
@@ -180,27 +161,82 @@ func parseCompletedLine(rest string) (fileCount, errorCount, warningCount, files
 
 // FormatHuman formats a SvelteWatchCheckComplete as human-readable output.
 func FormatHuman(event SvelteWatchCheckComplete) string {
-	if len(event.Diagnostics) == 0 {
-		return fmt.Sprintf("svelte-check found no issues (%d files checked)\n", event.FileCount)
-	}
+	return FormatHumanLinked(event, nil)
+}
 
+// FormatHumanLinked is like FormatHuman, with each diagnostic's location
+// linked by links.
+func FormatHumanLinked(event SvelteWatchCheckComplete, links *Linker) string {
 	var sb strings.Builder
+	if event.Stale {
+		sb.WriteString(fmt.Sprintf("Warning: results may be stale (%s)\n", event.StaleReason))
+	}
+
+	if len(event.Diagnostics) == 0 && len(event.Failures) == 0 {
+		sb.WriteString(fmt.Sprintf("svelte-check found no issues (%d files checked)\n", event.FileCount))
+		writeBaselined(&sb, event)
+		writeDelta(&sb, event)
+		writeFreshness(&sb, event)
+		return sb.String()
+	}
 
 	for _, d := range event.Diagnostics {
 		// Format: filename:line:char - TYPE: message
-		typeStr := d.Type
-		sb.WriteString(fmt.Sprintf("%s:%d:%d - %s: %s\n",
-			d.Filename,
-			d.Start.Line+1, // Convert 0-based to 1-based
-			d.Start.Character+1,
-			typeStr,
+		line, char := d.Start.Line+1, d.Start.Character+1 // Convert 0-based to 1-based
+		location := fmt.Sprintf("%s:%d:%d", d.Filename, line, char)
+		sb.WriteString(fmt.Sprintf("%s - %s: %s\n",
+			links.Link(d.Filename, line, char, location),
+			d.Type,
 			d.Message,
 		))
 	}
 
+	for _, f := range event.Failures {
+		sb.WriteString(fmt.Sprintf("svelte-check failure: %s\n", f))
+	}
+
 	// Summary line
 	sb.WriteString(fmt.Sprintf("\nsvelte-check: %d errors, %d warnings (%d files checked)\n",
 		event.ErrorCount, event.WarningCount, event.FileCount))
+	writeBaselined(&sb, event)
+	writeDelta(&sb, event)
+	writeFreshness(&sb, event)
 
 	return sb.String()
 }
+
+// writeBaselined writes how many diagnostics ignore rules and the baseline
+// hid, if any.
+func writeBaselined(sb *strings.Builder, event SvelteWatchCheckComplete) {
+	if event.Suppressed > 0 {
+		fmt.Fprintf(sb, "%d diagnostics suppressed by ignore rules\n", event.Suppressed)
+	}
+	if event.Baselined > 0 {
+		fmt.Fprintf(sb, "%d baselined diagnostics not shown\n", event.Baselined)
+	}
+}
+
+// writeDelta writes how many diagnostics are new or fixed since the previous
+// check, if any.
+func writeDelta(sb *strings.Builder, event SvelteWatchCheckComplete) {
+	if len(event.Introduced) == 0 && len(event.Resolved) == 0 {
+		return
+	}
+	fmt.Fprintf(sb, "Since the previous check: %d new, %d fixed\n", len(event.Introduced), len(event.Resolved))
+}
+
+// writeFreshness writes when the result was checked, if the server set it.
+func writeFreshness(sb *strings.Builder, event SvelteWatchCheckComplete) {
+	if event.CheckedAt.IsZero() {
+		return
+	}
+	age := time.Duration(event.AgeSeconds * float64(time.Second)).Round(time.Second)
+	fmt.Fprintf(sb, "Checked at %s (%v ago)", event.CheckedAt.Local().Format(time.TimeOnly), age)
+	if event.Git != nil {
+		fmt.Fprintf(sb, " on %s", event.Git)
+	}
+	if event.InProgress {
+		sb.WriteString("; a newer check is in progress")
+	}
+	sb.WriteString("\n")
+}
@@ -1,8 +1,10 @@
 package internal
 
 import (
+	"context"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestInterpretOutput tests the svelte-check --output machine-verbose interpreter.
@@ -14,7 +16,7 @@ func TestInterpretOutput_EmitsStartEvent(t *testing.T) {
 	events := make(chan SvelteCheckEvent, 10)
 
 	go func() {
-		if err := InterpretOutput(strings.NewReader(input), events); err != nil {
+		if err := InterpretOutput(context.Background(), strings.NewReader(input), events); err != nil {
 			t.Errorf("InterpretOutput error: %v", err)
 		}
 		close(events)
@@ -41,7 +43,7 @@ func TestInterpretOutput_EmitsCompleteEvent(t *testing.T) {
 	events := make(chan SvelteCheckEvent, 10)
 
 	go func() {
-		if err := InterpretOutput(strings.NewReader(input), events); err != nil {
+		if err := InterpretOutput(context.Background(), strings.NewReader(input), events); err != nil {
 			t.Errorf("InterpretOutput error: %v", err)
 		}
 		close(events)
@@ -97,7 +99,7 @@ func TestInterpretOutput_CountsErrorsAndWarnings(t *testing.T) {
 	events := make(chan SvelteCheckEvent, 10)
 
 	go func() {
-		if err := InterpretOutput(strings.NewReader(input), events); err != nil {
+		if err := InterpretOutput(context.Background(), strings.NewReader(input), events); err != nil {
 			t.Errorf("InterpretOutput error: %v", err)
 		}
 		close(events)
@@ -130,7 +132,7 @@ func TestInterpretOutput_MultipleCycles(t *testing.T) {
 	events := make(chan SvelteCheckEvent, 10)
 
 	go func() {
-		if err := InterpretOutput(strings.NewReader(input), events); err != nil {
+		if err := InterpretOutput(context.Background(), strings.NewReader(input), events); err != nil {
 			t.Errorf("InterpretOutput error: %v", err)
 		}
 		close(events)
@@ -166,7 +168,7 @@ func TestInterpretOutput_ZeroErrorsAndWarnings(t *testing.T) {
 	events := make(chan SvelteCheckEvent, 10)
 
 	go func() {
-		if err := InterpretOutput(strings.NewReader(input), events); err != nil {
+		if err := InterpretOutput(context.Background(), strings.NewReader(input), events); err != nil {
 			t.Errorf("InterpretOutput error: %v", err)
 		}
 		close(events)
@@ -195,7 +197,7 @@ func TestInterpretOutput_ParsesDiagnosticTimestamp(t *testing.T) {
 	events := make(chan SvelteCheckEvent, 10)
 
 	go func() {
-		if err := InterpretOutput(strings.NewReader(input), events); err != nil {
+		if err := InterpretOutput(context.Background(), strings.NewReader(input), events); err != nil {
 			t.Errorf("InterpretOutput error: %v", err)
 		}
 		close(events)
@@ -220,7 +222,7 @@ func TestInterpretOutput_ParsesNumericAndStringCodes(t *testing.T) {
 	events := make(chan SvelteCheckEvent, 10)
 
 	go func() {
-		if err := InterpretOutput(strings.NewReader(input), events); err != nil {
+		if err := InterpretOutput(context.Background(), strings.NewReader(input), events); err != nil {
 			t.Errorf("InterpretOutput error: %v", err)
 		}
 		close(events)
@@ -256,7 +258,7 @@ func TestInterpretOutput_EmitsFailureEvent(t *testing.T) {
 	events := make(chan SvelteCheckEvent, 10)
 
 	go func() {
-		if err := InterpretOutput(strings.NewReader(input), events); err != nil {
+		if err := InterpretOutput(context.Background(), strings.NewReader(input), events); err != nil {
 			t.Errorf("InterpretOutput error: %v", err)
 		}
 		close(events)
@@ -287,7 +289,7 @@ func TestInterpretOutput_SkipsCommentsAndEmptyLines(t *testing.T) {
 	events := make(chan SvelteCheckEvent, 10)
 
 	go func() {
-		if err := InterpretOutput(strings.NewReader(input), events); err != nil {
+		if err := InterpretOutput(context.Background(), strings.NewReader(input), events); err != nil {
 			t.Errorf("InterpretOutput error: %v", err)
 		}
 		close(events)
@@ -322,6 +324,26 @@ func TestFormatHuman_NoIssues(t *testing.T) {
 	}
 }
 
+// TestFormatHuman_Freshness tests the line reporting when the result was checked.
+func TestFormatHuman_Freshness(t *testing.T) {
+	checkedAt := time.Date(2026, 2, 5, 12, 0, 1, 0, time.Local)
+	event := SvelteWatchCheckComplete{
+		FileCount:  100,
+		CheckedAt:  checkedAt,
+		AgeSeconds: 3.4,
+		InProgress: true,
+	}
+
+	output := FormatHuman(event)
+	if want := "Checked at 12:00:01 (3s ago); a newer check is in progress\n"; !strings.HasSuffix(output, want) {
+		t.Errorf("Output should end with %q, got: %q", want, output)
+	}
+
+	if output := FormatHuman(SvelteWatchCheckComplete{FileCount: 100}); strings.Contains(output, "Checked at") {
+		t.Errorf("Output without CheckedAt should not report freshness, got: %q", output)
+	}
+}
+
 func TestFormatHuman_WithDiagnostics(t *testing.T) {
 	event := SvelteWatchCheckComplete{
 		FileCount:    100,
@@ -0,0 +1,33 @@
+package internal
+
+// =============================================================================
+// Event Journal
+// =============================================================================
+
+// journalKinds are the audit entry kinds a journal keeps: each checker's
+// events, and the restarts, crashes, and syncs between them.
+var journalKinds = map[string]bool{
+	"start":       true, // the daemon started
+	"stop":        true,
+	"check-start": true,
+	"check":       true, // a completed check, with its counts
+	"failure":     true,
+	"restart":     true,
+	"crash":       true,
+	"panic":       true,
+	"sync":        true,
+}
+
+// OpenJournal opens an event journal at path: an append-only JSONL record
+// of every check's start, summary, and failures, and the restarts and syncs
+// around them, for postmortems and analytics that should not depend on the
+// HTTP API. It is an AuditLog without the watcher's entries, which make up
+// most of an audit log; Tee it to the audit log, if any, so both are kept.
+func OpenJournal(path string, maxSize int64) (*AuditLog, error) {
+	j, err := OpenAuditLog(path, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	j.kinds = journalKinds
+	return j, nil
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestJournal tests that a journal teed to an audit log keeps the checker's
+// events and restarts, but not watcher events, and that the audit log keeps
+// everything.
+func TestJournal(t *testing.T) {
+	dir := t.TempDir()
+	auditPath, journalPath := filepath.Join(dir, "audit.jsonl"), filepath.Join(dir, "journal.jsonl")
+	audit, err := OpenAuditLog(auditPath, 0)
+	if err != nil {
+		t.Fatalf("OpenAuditLog failed: %v", err)
+	}
+	journal, err := OpenJournal(journalPath, 0)
+	if err != nil {
+		t.Fatalf("OpenJournal failed: %v", err)
+	}
+	log := audit.Tee(journal)
+
+	output := "1770255834000 START \"/workspace\"\n" +
+		"1770255834001 FAILURE \"Connection closed\"\n" +
+		"1770255834500 COMPLETED 10 FILES 1 ERRORS 0 WARNINGS 1 FILES_WITH_PROBLEMS\n"
+	r := NewRunnerWithConfig(RunnerConfig{WorkspacePath: "/workspace", Audit: log}, WithExecutor(NewFakeExecutor(output, "")))
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if _, err := r.GetLatestEvent(context.Background()); err != nil {
+		t.Fatalf("GetLatestEvent failed: %v", err)
+	}
+	r.Stop()
+	log.Record("event", map[string]any{"path": "src/app.ts"})
+	log.Record("restart", map[string]any{"reason": "watcher"})
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	kinds := func(path string) string {
+		var got []string
+		for _, e := range readAuditLog(t, path) {
+			got = append(got, e["kind"].(string))
+		}
+		return strings.Join(got, " ")
+	}
+	if got, want := kinds(journalPath), "check-start failure check restart"; got != want {
+		t.Errorf("journal kinds = %q, want %q", got, want)
+	}
+	if got, want := kinds(auditPath), "check-start failure check event restart"; got != want {
+		t.Errorf("audit kinds = %q, want %q", got, want)
+	}
+
+	check := readAuditLog(t, journalPath)[2]
+	if check["check"] != float64(1770255834500) || check["errors"] != float64(1) || check["filesWithProblems"] != float64(1) || check["failures"] != float64(1) {
+		t.Errorf("check entry = %v, want the summary", check)
+	}
+}
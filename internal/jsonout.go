@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// =============================================================================
+// JSON Output
+// =============================================================================
+
+// Codes of a --json error, stable across releases so scripts can branch on
+// them instead of on messages.
+const (
+	errCodeNotRunning      = "not_running"      // no server serves the workspace
+	errCodeStartupFailed   = "startup_failed"   // start could not start the server
+	errCodeTimeout         = "timeout"          // a wait or request timed out
+	errCodeVersionMismatch = "version_mismatch" // the server is older than the client (--strict-version)
+	errCodeFailed          = "failed"           // any other failure
+)
+
+// jsonError is what a command prints with --json when it fails.
+type jsonError struct {
+	Error jsonErrorDetail `json:"error"`
+}
+
+type jsonErrorDetail struct {
+	Code    string   `json:"code"`
+	Message string   `json:"message"`
+	Output  []string `json:"output,omitempty"` // the checker's output, for startup_failed
+}
+
+// cliOutput prints a command's results and errors as human text or, with
+// --json, as one JSON document on stdout. Logs still go to stderr.
+type cliOutput struct {
+	json bool
+	w    io.Writer
+}
+
+// registerJSON adds the --json flag to fs.
+func registerJSON(fs *flag.FlagSet) *cliOutput {
+	out := &cliOutput{w: os.Stdout}
+	fs.BoolVar(&out.json, "json", false, "Print machine-readable JSON")
+	return out
+}
+
+// result prints v as JSON with --json, or calls human.
+func (o *cliOutput) result(v any, human func()) {
+	if !o.json {
+		human()
+		return
+	}
+	enc := json.NewEncoder(o.w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// fail reports a failure and exits with exitCode.
+func (o *cliOutput) fail(code string, exitCode int, format string, args ...any) {
+	o.report(code, format, args...)
+	os.Exit(exitCode)
+}
+
+// report reports a failure: with --json as a jsonError with the given code,
+// otherwise as a log message.
+func (o *cliOutput) report(code string, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if !o.json {
+		log.Print(msg)
+		return
+	}
+	o.result(jsonError{Error: jsonErrorDetail{Code: code, Message: msg}}, nil)
+}
+
+// startupFailed reports err from starting the server, with the checker's
+// output if it exited.
+func (o *cliOutput) startupFailed(err error) {
+	if !o.json {
+		logStartupError(err)
+		os.Exit(1)
+	}
+	detail := jsonErrorDetail{Code: errCodeStartupFailed, Message: err.Error()}
+	var startupErr *StartupError
+	if errors.As(err, &startupErr) {
+		detail.Output = startupErr.Output
+	}
+	o.result(jsonError{Error: detail}, nil)
+	os.Exit(1)
+}
+
+// notRunning reports that no server serves the workspace.
+func (o *cliOutput) notRunning() {
+	if !o.json {
+		fmt.Fprintln(o.w, "Server is not running")
+		os.Exit(1)
+	}
+	o.fail(errCodeNotRunning, 1, "server is not running")
+}
+
+// startInfo is what start prints with --json once the server is serving.
+type startInfo struct {
+	Workspace string `json:"workspace"`
+	Socket    string `json:"socket"`
+	TCPAddr   string `json:"tcpAddr,omitempty"`
+	PID       int    `json:"pid"`
+}
+
+// stopResult is what stop prints with --json.
+type stopResult struct {
+	WasRunning bool `json:"wasRunning"`
+}
+
+// restartResult is what restart prints with --json.
+type restartResult struct {
+	Restarted bool   `json:"restarted"`
+	Project   string `json:"project,omitempty"` // empty for all projects
+}
+
+// baselineResult is what baseline write prints with --json.
+type baselineResult struct {
+	Path        string `json:"path"`
+	Diagnostics int    `json:"diagnostics"`
+}
+
+// serviceResult is what service install and uninstall print with --json.
+type serviceResult struct {
+	Files []string `json:"files"` // written or removed
+}
+
+// directResult is what check prints with --json when no server is running
+// and svelte-check was run directly: its exit code and text output, per
+// project when several are configured.
+type directResult struct {
+	Direct   bool        `json:"direct"` // always true, to tell it apart from a check result
+	ExitCode int         `json:"exitCode"`
+	Runs     []directRun `json:"runs"`
+
+	// InputHash is the digest of the checked files, with --hash-inputs.
+	InputHash string `json:"inputHash,omitempty"`
+}
+
+type directRun struct {
+	Project  string `json:"project,omitempty"`
+	ExitCode int    `json:"exitCode"`
+	Output   string `json:"output"`
+}
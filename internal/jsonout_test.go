@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// TestCLIOutput_Result tests that result prints JSON with --json and calls
+// the human printer otherwise.
+func TestCLIOutput_Result(t *testing.T) {
+	var buf bytes.Buffer
+	out := &cliOutput{json: true, w: &buf}
+	out.result(stopResult{WasRunning: true}, func() { t.Error("human printer called with --json") })
+	if got, want := buf.String(), "{\n  \"wasRunning\": true\n}\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	called := false
+	out.json = false
+	out.result(stopResult{}, func() { called = true })
+	if !called || buf.Len() != 0 {
+		t.Errorf("without --json: human called = %v, output = %q; want the human printer only", called, buf.String())
+	}
+}
+
+// TestRunProjectsOnce_JSON tests that direct runs are reported as one JSON
+// document with each project's exit code and output.
+func TestRunProjectsOnce_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	out := &cliOutput{json: true, w: &buf}
+	executor := NewFakeExecutor("", "")
+	projects := []ProjectConfig{{Name: "app", Dir: "apps/web"}, {Name: "docs", Dir: "apps/docs"}}
+
+	code := runProjectsOnce(context.Background(), RunnerConfig{WorkspacePath: "/workspace"}, projects, "docs", executor, "", out)
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0", code)
+	}
+
+	var result directResult
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	if !result.Direct || len(result.Runs) != 1 || result.Runs[0].Project != "docs" {
+		t.Errorf("result = %+v, want one direct run of docs", result)
+	}
+}
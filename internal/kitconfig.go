@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// SvelteKit Config
+// =============================================================================
+
+// KitPaths are the directories a SvelteKit project's svelte.config sets
+// under kit.files and kit.outDir, relative to the project directory.
+type KitPaths struct {
+	Routes string `json:"routes"`
+	Lib    string `json:"lib"`
+	Params string `json:"params"`
+	OutDir string `json:"outDir"`
+}
+
+// DefaultKitPaths are SvelteKit's defaults.
+var DefaultKitPaths = KitPaths{Routes: "src/routes", Lib: "src/lib", Params: "src/params", OutDir: ".svelte-kit"}
+
+// kitConfigTimeout bounds how long evaluating svelte.config may take.
+const kitConfigTimeout = 10 * time.Second
+
+// kitConfigScript prints the kit paths of the config module given as its
+// argument as JSON.
+const kitConfigScript = `const { pathToFileURL } = await import("node:url");
+const config = (await import(pathToFileURL(process.argv[1]).href)).default ?? {};
+const kit = config.kit ?? {};
+console.log(JSON.stringify({ routes: kit.files?.routes, lib: kit.files?.lib, params: kit.files?.params, outDir: kit.outDir }));`
+
+// LoadKitPaths reads the kit paths of the project in dir. The config is
+// evaluated with node so computed values are honored; if that fails, e.g.
+// for a TypeScript config or missing dependencies, string literals are read
+// from its source instead. Unset paths, and all of them without a config,
+// are the defaults.
+func LoadKitPaths(ctx context.Context, dir string, executor kexec.Interface) KitPaths {
+	var configFile string
+	for _, name := range svelteConfigFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			configFile = filepath.Join(dir, name)
+			break
+		}
+	}
+	if configFile == "" {
+		return DefaultKitPaths
+	}
+
+	paths, err := evalKitPaths(ctx, configFile, executor)
+	if err != nil {
+		source, readErr := os.ReadFile(configFile)
+		if readErr != nil {
+			logger("kitconfig").Warn("could not read svelte config", "path", configFile, "error", readErr)
+			return DefaultKitPaths
+		}
+		paths = parseKitPaths(string(source))
+	}
+	return paths.withDefaults()
+}
+
+// evalKitPaths imports configFile with node and returns the paths it sets.
+func evalKitPaths(ctx context.Context, configFile string, executor kexec.Interface) (KitPaths, error) {
+	ctx, cancel := context.WithTimeout(ctx, kitConfigTimeout)
+	defer cancel()
+
+	cmd := executor.CommandContext(ctx, "node", "--input-type=module", "-e", kitConfigScript, configFile)
+	cmd.SetDir(filepath.Dir(configFile))
+	out, err := cmd.Output()
+	if err != nil {
+		return KitPaths{}, err
+	}
+	var paths KitPaths
+	if err := json.Unmarshal(out, &paths); err != nil {
+		return KitPaths{}, err
+	}
+	return paths, nil
+}
+
+// kitPathPatterns find kit paths given as string literals. A key must
+// follow whitespace, "{", or ",", so "$lib:" aliases do not match.
+var kitPathPatterns = map[string]*regexp.Regexp{
+	"routes": regexp.MustCompile(`(?:^|[\s{,])routes\s*:\s*['"` + "`" + `]([^'"` + "`" + `]+)`),
+	"lib":    regexp.MustCompile(`(?:^|[\s{,])lib\s*:\s*['"` + "`" + `]([^'"` + "`" + `]+)`),
+	"params": regexp.MustCompile(`(?:^|[\s{,])params\s*:\s*['"` + "`" + `]([^'"` + "`" + `]+)`),
+	"outDir": regexp.MustCompile(`(?:^|[\s{,])outDir\s*:\s*['"` + "`" + `]([^'"` + "`" + `]+)`),
+}
+
+// parseKitPaths reads kit paths given as string literals in a config's
+// source.
+func parseKitPaths(source string) KitPaths {
+	find := func(key string) string {
+		if m := kitPathPatterns[key].FindStringSubmatch(source); m != nil {
+			return m[1]
+		}
+		return ""
+	}
+	return KitPaths{Routes: find("routes"), Lib: find("lib"), Params: find("params"), OutDir: find("outDir")}
+}
+
+// withDefaults fills unset paths from DefaultKitPaths and cleans the others.
+func (k KitPaths) withDefaults() KitPaths {
+	clean := func(p, def string) string {
+		if p == "" {
+			return def
+		}
+		return filepath.Clean(filepath.FromSlash(p))
+	}
+	return KitPaths{
+		Routes: clean(k.Routes, DefaultKitPaths.Routes),
+		Lib:    clean(k.Lib, DefaultKitPaths.Lib),
+		Params: clean(k.Params, DefaultKitPaths.Params),
+		OutDir: clean(k.OutDir, DefaultKitPaths.OutDir),
+	}
+}
+
+// syncGlobs returns globs, relative to the project, of files outside routes
+// that feed svelte-kit sync: param matchers, the App namespace in
+// src/app.d.ts, and server hooks.
+func (k KitPaths) syncGlobs() []string {
+	return []string{filepath.ToSlash(k.Params) + "/*", "src/app.d.ts", "src/hooks.server.*"}
+}
+
+// watchDirs returns the directories to watch recursively: src, plus the
+// routes and lib directories when they are outside it.
+func (k KitPaths) watchDirs() []string {
+	dirs := []string{"src"}
+	for _, dir := range []string{k.Routes, k.Lib} {
+		covered := slices.ContainsFunc(dirs, func(d string) bool {
+			return dir == d || strings.HasPrefix(dir, d+string(filepath.Separator))
+		})
+		if !covered && !strings.HasPrefix(dir, "..") {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseKitPaths(t *testing.T) {
+	source := `import adapter from '@sveltejs/adapter-node';
+
+/** @type {import('@sveltejs/kit').Config} */
+export default {
+	kit: {
+		adapter: adapter(),
+		alias: { '$lib': 'app/lib', $components: "app/components" },
+		outDir: "generated",
+		files: {
+			routes: 'app/routes',
+			lib: ` + "`app/lib`" + `,
+			params: "app/params"
+		}
+	}
+};
+`
+	want := KitPaths{Routes: "app/routes", Lib: "app/lib", Params: "app/params", OutDir: "generated"}
+	if got := parseKitPaths(source); got != want {
+		t.Errorf("parseKitPaths() = %+v, want %+v", got, want)
+	}
+
+	if got := parseKitPaths(`export default { kit: { alias: { $lib: "x" } } }`); got != (KitPaths{}) {
+		t.Errorf("parseKitPaths() of aliases only = %+v, want nothing", got)
+	}
+}
+
+func TestLoadKitPaths(t *testing.T) {
+	t.Run("no config", func(t *testing.T) {
+		executor := NewFakeExecutor(`{"routes":"x"}`, "")
+		if got := LoadKitPaths(context.Background(), t.TempDir(), executor); got != DefaultKitPaths {
+			t.Errorf("LoadKitPaths() = %+v, want the defaults", got)
+		}
+		if got := executor.commandLine(); got != "" {
+			t.Errorf("ran %q without a config", got)
+		}
+	})
+
+	t.Run("evaluated", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"svelte.config.js": "export default {}"})
+		executor := NewFakeExecutor(`{"routes":"./app/routes","outDir":"gen"}`+"\n", "")
+
+		want := KitPaths{Routes: filepath.Join("app", "routes"), Lib: "src/lib", Params: "src/params", OutDir: "gen"}
+		if got := LoadKitPaths(context.Background(), dir, executor); got != want {
+			t.Errorf("LoadKitPaths() = %+v, want %+v", got, want)
+		}
+		if got := executor.commandLine(); !strings.HasPrefix(got, "node --input-type=module -e") ||
+			!strings.HasSuffix(got, filepath.Join(dir, "svelte.config.js")) {
+			t.Errorf("command = %q", got)
+		}
+	})
+
+	t.Run("source fallback", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"svelte.config.ts": `export default { kit: { files: { lib: "shared" } } } satisfies Config`})
+		executor := NewFakeExecutor("", "")
+		executor.setCmd(&FakeCmd{waitErr: errors.New("ERR_UNKNOWN_FILE_EXTENSION")})
+
+		want := KitPaths{Routes: "src/routes", Lib: "shared", Params: "src/params", OutDir: ".svelte-kit"}
+		if got := LoadKitPaths(context.Background(), dir, executor); got != want {
+			t.Errorf("LoadKitPaths() = %+v, want %+v", got, want)
+		}
+	})
+}
+
+func TestDefaultWatchDirs_KitPaths(t *testing.T) {
+	projects := []ProjectConfig{{Name: "web", Dir: "apps/web"}, {Name: "docs", Dir: "apps/docs"}}
+	kits := map[string]KitPaths{
+		filepath.Join("apps", "web"): {Routes: filepath.Join("app", "routes"), Lib: filepath.Join("src", "lib"), OutDir: "gen"},
+	}
+
+	_, recursive := defaultWatchDirs(projects, kits)
+	want := []string{"./apps/web/src", "./apps/web/app/routes", "./apps/docs/src"}
+	if !slices.Equal(recursive, want) {
+		t.Errorf("recursive = %v, want %v", recursive, want)
+	}
+
+	wantRoutes := []string{filepath.Join("apps", "web", "app", "routes"), filepath.Join("apps", "docs", "src", "routes")}
+	if got := routeDirs(projects, kits); !slices.Equal(got, wantRoutes) {
+		t.Errorf("routeDirs() = %v, want %v", got, wantRoutes)
+	}
+
+	wantSkip := append(slices.Clone(DefaultSkipDirs),
+		filepath.Join("/ws", "apps", "web", "build"), filepath.Join("/ws", "apps", "web", "dist"), filepath.Join("/ws", "apps", "web", "gen"))
+	if got := withRootSkipDirs("/ws", DefaultSkipDirs, kits); !slices.Equal(got, wantSkip) {
+		t.Errorf("withRootSkipDirs() = %v, want %v", got, wantSkip)
+	}
+}
+
+func TestWatcher_IsRouteFile_RouteDirs(t *testing.T) {
+	w := NewWatcher(WatcherConfig{WorkspacePath: "/ws", RouteDirs: []string{"app/routes"}}, WatcherCallbacks{}, NewFakeFSWatcher(), nil)
+
+	if !w.isRouteFile("/ws/app/routes/blog/+page.ts") {
+		t.Error("route file in the routes directory not detected")
+	}
+	if w.isRouteFile("/ws/src/routes/blog/+page.ts") {
+		t.Error("route file outside the routes directory detected")
+	}
+}
+
+func TestSyncGlobs(t *testing.T) {
+	projects := []ProjectConfig{{Name: "web", Dir: "apps/web"}, {Name: "docs", Dir: "apps/docs"}}
+	kits := map[string]KitPaths{
+		filepath.Join("apps", "web"): {Params: filepath.Join("app", "matchers")},
+	}
+
+	want := []string{
+		"apps/web/app/matchers/*", "apps/web/src/app.d.ts", "apps/web/src/hooks.server.*",
+		"apps/docs/src/params/*", "apps/docs/src/app.d.ts", "apps/docs/src/hooks.server.*",
+	}
+	if got := syncGlobs(projects, kits); !slices.Equal(got, want) {
+		t.Errorf("syncGlobs() = %v, want %v", got, want)
+	}
+}
+
+func TestRestartGlobs(t *testing.T) {
+	projects := []ProjectConfig{{Name: "web", Dir: "apps/web"}}
+	want := []string{"apps/web/*.d.ts", "apps/web/types/**/*.d.ts"}
+	if got := restartGlobs(projects); !slices.Equal(got, want) {
+		t.Errorf("restartGlobs() = %v, want %v", got, want)
+	}
+	if got, want := restartGlobs(nil), []string{"*.d.ts", "types/**/*.d.ts"}; !slices.Equal(got, want) {
+		t.Errorf("restartGlobs(nil) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// goroutines returns the stacks of the running goroutines by ID.
+func goroutines() map[string]string {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	stacks := make(map[string]string)
+	for stack := range strings.SplitSeq(string(buf), "\n\n") {
+		id, _, _ := strings.Cut(strings.TrimPrefix(stack, "goroutine "), " ")
+		stacks[id] = stack
+	}
+	return stacks
+}
+
+// checkLeaks fails the test if goroutines running this package's code, started
+// while it ran, are still running shortly after it and its deferred calls
+// have finished. Call it first, so that it runs after other cleanups.
+func checkLeaks(t *testing.T) {
+	t.Helper()
+	before := goroutines()
+	t.Cleanup(func() {
+		var leaked []string
+		for deadline := time.Now().Add(2 * time.Second); ; {
+			leaked = nil
+			for id, stack := range goroutines() {
+				if _, ok := before[id]; ok || strings.Contains(stack, "testing.tRunner") {
+					continue
+				}
+				if strings.Contains(stack, "svelte-check-server/internal.") {
+					leaked = append(leaked, stack)
+				}
+			}
+			if len(leaked) == 0 || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if len(leaked) > 0 {
+			t.Errorf("%d goroutines leaked:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+		}
+	})
+}
+
+// TestRunner_Stop_NoLeaks tests that a Runner leaves no goroutines behind
+// once stopped, including those of abandoned waits for a result.
+func TestRunner_Stop_NoLeaks(t *testing.T) {
+	checkLeaks(t)
+
+	// The check never completes.
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(`1770255832071 START "/workspace"
+`, "")))
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := r.GetLatestEvent(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetLatestEvent() error = %v, want DeadlineExceeded", err)
+	}
+	r.Stop()
+}
+
+// TestServer_Start_ContextDone tests that the server closes, ending its
+// event streams, when the context given to Start is done.
+func TestServer_Start_ContextDone(t *testing.T) {
+	checkLeaks(t)
+	socketPath := testSocketPath(t)
+
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(checkOutput("1770255832071", 0), "")))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewServer(socketPath, r)
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	client := unixHTTPClient(socketPath)
+	defer client.CloseIdleConnections()
+	resp, err := client.Get("http://unix/events")
+	if err != nil {
+		t.Fatalf("GET /events failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body := bufio.NewReader(resp.Body)
+	if line, err := body.ReadString('\n'); err != nil || line != "event: result\n" {
+		t.Fatalf("first line = %q, %v; want a result event", line, err)
+	}
+
+	cancel()
+	ended := make(chan struct{})
+	go func() {
+		defer close(ended)
+		for {
+			if _, err := body.ReadString('\n'); err != nil {
+				return
+			}
+		}
+	}()
+	select {
+	case <-ended:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the event stream did not end when the context was done")
+	}
+	if _, err := client.Get("http://unix/status"); err == nil {
+		t.Error("the server still answers after its context is done")
+	}
+}
+
+// TestInterpretOutput_ContextDone tests that an interpreter nobody reads
+// from returns once its context is done.
+func TestInterpretOutput_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- InterpretOutput(ctx, strings.NewReader(`1770255832071 START "/workspace"
+`), make(chan SvelteCheckEvent))
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("InterpretOutput() = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("InterpretOutput did not return when its context was done")
+	}
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// =============================================================================
+// Terminal Hyperlinks
+// =============================================================================
+
+// EnvLinkScheme sets the default of --link-scheme.
+const EnvLinkScheme = "SVELTE_CHECK_LINK_SCHEME"
+
+// Link schemes of note. Any other scheme is taken to be an editor's, opening
+// <scheme>://file/<path>:<line>:<column> as vscode:// does, e.g. windsurf.
+const (
+	LinkSchemeFile   = "file"   // file://<host>/<path>, opened by the terminal
+	LinkSchemeVSCode = "vscode" // vscode://file/<path>:<line>:<column>
+	LinkSchemeCursor = "cursor" // cursor://file/<path>:<line>:<column>
+	LinkSchemeNone   = "none"   // no links
+)
+
+// schemePattern matches a URL scheme, as RFC 3986 defines it.
+var schemePattern = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
+
+// Linker wraps the locations of diagnostics in OSC 8 hyperlinks, which
+// modern terminals make clickable. A nil *Linker leaves them plain.
+type Linker struct {
+	scheme    string
+	workspace string // diagnostics' filenames are relative to it
+	host      string // of file:// links
+}
+
+// NewLinker returns a Linker of scheme for diagnostics of workspace, or nil
+// for LinkSchemeNone.
+func NewLinker(scheme, workspace string) (*Linker, error) {
+	if scheme == LinkSchemeNone {
+		return nil, nil
+	}
+	if !schemePattern.MatchString(scheme) {
+		return nil, fmt.Errorf("invalid link scheme %q: want file, vscode, cursor, none, or another editor's URL scheme", scheme)
+	}
+	l := &Linker{scheme: scheme, workspace: workspace}
+	if scheme == LinkSchemeFile {
+		// Terminals only open file:// links on the host they name.
+		l.host, _ = os.Hostname()
+	}
+	return l, nil
+}
+
+// Link returns text as a hyperlink to a 1-based line and column of filename.
+func (l *Linker) Link(filename string, line, column int, text string) string {
+	if l == nil {
+		return text
+	}
+	path := filename
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(l.workspace, path)
+	}
+	escaped := (&url.URL{Path: filepath.ToSlash(path)}).EscapedPath()
+	uri := "file://" + l.host + escaped
+	if l.scheme != LinkSchemeFile {
+		uri = fmt.Sprintf("%s://file%s:%d:%d", l.scheme, escaped, line, column)
+	}
+	return "\x1b]8;;" + uri + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// linkFlags holds the --link-scheme flag of commands that print results in
+// human form.
+type linkFlags struct {
+	scheme string
+}
+
+// registerLinks adds --link-scheme to fs.
+func registerLinks(fs *flag.FlagSet) *linkFlags {
+	f := &linkFlags{}
+	fs.StringVar(&f.scheme, "link-scheme", os.Getenv(EnvLinkScheme), "Link diagnostics to file, vscode, cursor, or none (default: file on a terminal)")
+	return f
+}
+
+// linker returns the Linker for workspace, or nil when stdout is not a
+// terminal, whose escape sequences would garble output piped elsewhere.
+func (f *linkFlags) linker(workspace string) (*Linker, error) {
+	scheme := f.scheme
+	if scheme == "" {
+		scheme = LinkSchemeFile
+	}
+	l, err := NewLinker(scheme, workspace)
+	if err != nil || !isTerminal(os.Stdout) {
+		return nil, err
+	}
+	return l, nil
+}
+
+// isTerminal reports whether f is a terminal that is not "dumb".
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb"
+}
@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLinker_Link(t *testing.T) {
+	for _, tc := range []struct {
+		scheme, filename, want string
+	}{
+		{LinkSchemeVSCode, "src/my page.svelte", "vscode://file/ws/src/my%20page.svelte:3:7"},
+		{LinkSchemeCursor, "/abs/a.ts", "cursor://file/abs/a.ts:3:7"},
+		{"windsurf", "a.ts", "windsurf://file/ws/a.ts:3:7"},
+		{LinkSchemeFile, "a.ts", "file://host/ws/a.ts"},
+	} {
+		l, err := NewLinker(tc.scheme, "/ws")
+		if err != nil {
+			t.Fatalf("NewLinker(%s) failed: %v", tc.scheme, err)
+		}
+		if tc.scheme == LinkSchemeFile {
+			l.host = "host"
+		}
+		want := "\x1b]8;;" + tc.want + "\x1b\\a.ts:3:7\x1b]8;;\x1b\\"
+		if got := l.Link(tc.filename, 3, 7, "a.ts:3:7"); got != want {
+			t.Errorf("Link(%s) with %s = %q, want %q", tc.filename, tc.scheme, got, want)
+		}
+	}
+
+	if l, err := NewLinker(LinkSchemeNone, "/ws"); l != nil || err != nil {
+		t.Errorf("NewLinker(none) = %v, %v; want nil", l, err)
+	}
+	if _, err := NewLinker("Not a scheme", "/ws"); err == nil {
+		t.Error("NewLinker with an invalid scheme succeeded")
+	}
+	var none *Linker
+	if got := none.Link("a.ts", 1, 1, "a.ts:1:1"); got != "a.ts:1:1" {
+		t.Errorf("nil Link = %q, want the text", got)
+	}
+}
+
+func TestFormatHumanLinked(t *testing.T) {
+	event := SvelteWatchCheckComplete{
+		ErrorCount: 1,
+		Diagnostics: []Diagnostic{{
+			Type: "ERROR", Filename: "src/App.svelte", Message: "oops",
+			Start: Position{Line: 4, Character: 2},
+		}},
+	}
+	l, _ := NewLinker(LinkSchemeVSCode, "/ws")
+	output := FormatHumanLinked(event, l)
+	want := "\x1b]8;;vscode://file/ws/src/App.svelte:5:3\x1b\\src/App.svelte:5:3\x1b]8;;\x1b\\ - ERROR: oops\n"
+	if !strings.HasPrefix(output, want) {
+		t.Errorf("FormatHumanLinked() = %q, want prefix %q", output, want)
+	}
+	if plain := FormatHuman(event); !strings.HasPrefix(plain, "src/App.svelte:5:3 - ERROR: oops\n") {
+		t.Errorf("FormatHuman() = %q", plain)
+	}
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// =============================================================================
+// Logging
+// =============================================================================
+
+// Log formats accepted by --log-format.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// DebugScopes are the subsystems --debug accepts: each logs at debug level
+// when named, whatever --log-level is.
+var DebugScopes = []string{
+	"audit", "baseline", "daemon", "executor", "fswatch", "git", "hooks",
+	"interpreter", "kitconfig", "notify", "runner", "server", "service",
+	"state", "supervisor", "sync", "tracing", "watcher",
+}
+
+// logger returns the default logger with a subsystem attribute, for code
+// without a Runner's or Watcher's own logger. It reads the default on each
+// call, so it follows --log-level and --log-format once they are applied.
+func logger(subsystem string) *slog.Logger {
+	return slog.Default().With("subsystem", subsystem)
+}
+
+// NewLogHandler returns a handler writing to w at level ("debug", "info",
+// "warn", or "error") in format (LogFormatText or LogFormatJSON). Loggers
+// whose subsystem is one of debug, each one of DebugScopes, also write debug
+// entries.
+func NewLogHandler(w io.Writer, level, format string, debug ...string) (slog.Handler, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: want debug, info, warn, or error", level)
+	}
+	for _, scope := range debug {
+		if !slices.Contains(DebugScopes, scope) {
+			return nil, fmt.Errorf("invalid debug scope %q: want one of %s", scope, strings.Join(DebugScopes, ", "))
+		}
+	}
+	opts := &slog.HandlerOptions{Level: l}
+	if len(debug) > 0 {
+		opts.Level = slog.LevelDebug
+	}
+	var h slog.Handler
+	switch format {
+	case LogFormatText:
+		h = slog.NewTextHandler(w, opts)
+	case LogFormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q: want text or json", format)
+	}
+	if len(debug) == 0 {
+		return h, nil
+	}
+	return &scopedHandler{Handler: h, level: l, scopes: debug}, nil
+}
+
+// scopedHandler filters entries at level, except that loggers whose
+// subsystem attribute is one of scopes pass debug entries too. The handler
+// it wraps is at debug level.
+type scopedHandler struct {
+	slog.Handler
+	level  slog.Level
+	scopes []string
+	debug  bool // this logger's subsystem is in scopes
+}
+
+func (h *scopedHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level || h.debug && h.Handler.Enabled(ctx, level)
+}
+
+func (h *scopedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.Handler = h.Handler.WithAttrs(attrs)
+	for _, a := range attrs {
+		if a.Key == "subsystem" {
+			c.debug = slices.Contains(h.scopes, a.Value.String())
+		}
+	}
+	return &c
+}
+
+func (h *scopedHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.Handler = h.Handler.WithGroup(name)
+	return &c
+}
+
+// logFlags holds the --log-level, --log-format, and --debug flags of a
+// command that runs a daemon.
+type logFlags struct {
+	level  string
+	format string
+	debug  string
+}
+
+// registerLogFlags registers --log-level, --log-format, and --debug on fs.
+func registerLogFlags(fs *flag.FlagSet) *logFlags {
+	l := &logFlags{}
+	fs.StringVar(&l.level, "log-level", "info", "Log level: debug, info, warn, or error")
+	fs.StringVar(&l.format, "log-format", LogFormatText, "Log format: text or json")
+	fs.StringVar(&l.debug, "debug", "", "Comma-separated subsystems to log at debug level, e.g. watcher,git")
+	return l
+}
+
+// apply makes a handler writing to w the default logger, which the
+// standard log package then writes through as well.
+func (l *logFlags) apply(w io.Writer) error {
+	var scopes []string
+	for scope := range strings.SplitSeq(l.debug, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	h, err := NewLogHandler(w, l.level, l.format, scopes...)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestNewLogHandler tests that the handler filters by level and writes the
+// chosen format.
+func TestNewLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := NewLogHandler(&buf, "warn", LogFormatJSON)
+	if err != nil {
+		t.Fatalf("NewLogHandler failed: %v", err)
+	}
+	l := slog.New(h).With("subsystem", "runner")
+	l.Info("svelte-check started")
+	l.Warn("restarting svelte-check", "attempt", 2)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("logged %q, want only the warning", buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if entry["level"] != "WARN" || entry["subsystem"] != "runner" || entry["attempt"] != float64(2) {
+		t.Errorf("entry = %v, want a WARN with subsystem and attempt", entry)
+	}
+
+	for _, tt := range []struct{ level, format string }{
+		{"verbose", LogFormatText},
+		{"info", "xml"},
+	} {
+		if _, err := NewLogHandler(&buf, tt.level, tt.format); err == nil {
+			t.Errorf("NewLogHandler(%q, %q) succeeded, want an error", tt.level, tt.format)
+		}
+	}
+}
+
+// TestNewLogHandler_DebugScopes tests that --debug enables debug entries of
+// the named subsystems only.
+func TestNewLogHandler_DebugScopes(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := NewLogHandler(&buf, "info", LogFormatText, "watcher", "interpreter")
+	if err != nil {
+		t.Fatalf("NewLogHandler failed: %v", err)
+	}
+	l := slog.New(h)
+	l.With("subsystem", "watcher").With("checker", "svelte-check").Debug("filesystem event")
+	l.With("subsystem", "interpreter").Debug("output line")
+	l.With("subsystem", "runner").Debug("hidden")
+	l.With("subsystem", "runner").Info("svelte-check started")
+	l.Debug("hidden")
+
+	out := buf.String()
+	for _, want := range []string{"filesystem event", "output line", "svelte-check started"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("logged %q, want %q", out, want)
+		}
+	}
+	if strings.Contains(out, "hidden") {
+		t.Errorf("logged %q, want no debug entries outside the scopes", out)
+	}
+
+	if _, err := NewLogHandler(&buf, "info", LogFormatText, "watchr"); err == nil || !strings.Contains(err.Error(), "watcher") {
+		t.Errorf("NewLogHandler with an unknown scope = %v, want an error listing the scopes", err)
+	}
+}
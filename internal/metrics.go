@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// =============================================================================
+// Metrics
+// =============================================================================
+
+// metricsPrefix namespaces every metric served by GET /metrics.
+const metricsPrefix = "svelte_check_server_"
+
+// metricSample is one labeled value of a metric.
+type metricSample struct {
+	labels string // e.g. `project="web"`; empty for none
+	value  float64
+}
+
+// metricsWriter renders metrics in the Prometheus text exposition format.
+type metricsWriter struct {
+	w io.Writer
+}
+
+// family writes a metric's HELP and TYPE lines followed by its samples.
+// Metrics without samples are omitted.
+func (m metricsWriter) family(name, kind, help string, samples []metricSample) {
+	if len(samples) == 0 {
+		return
+	}
+	fmt.Fprintf(m.w, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricsPrefix, name, help, metricsPrefix, name, kind)
+	m.samples(name, samples)
+}
+
+// samples writes the sample lines of a metric without HELP and TYPE, as for
+// the _sum and _count series of a summary.
+func (m metricsWriter) samples(name string, samples []metricSample) {
+	name = metricsPrefix + name
+	for _, s := range samples {
+		if s.labels != "" {
+			fmt.Fprintf(m.w, "%s{%s} %g\n", name, s.labels, s.value)
+		} else {
+			fmt.Fprintf(m.w, "%s %g\n", name, s.value)
+		}
+	}
+}
+
+// summary writes check timings as a Prometheus summary: p50 and p95 quantiles
+// over recent cycles plus the all-time sum and count.
+func (m metricsWriter) summary(name, help string, runners []labeledRunner) {
+	var quantiles, sums, counts []metricSample
+	for _, r := range runners {
+		t := r.status.Timings
+		if t == nil {
+			continue
+		}
+		quantiles = append(quantiles,
+			metricSample{labels: joinLabels(r.labels, `quantile="0.5"`), value: float64(t.P50Ms) / 1000},
+			metricSample{labels: joinLabels(r.labels, `quantile="0.95"`), value: float64(t.P95Ms) / 1000})
+		sums = append(sums, metricSample{labels: r.labels, value: float64(t.TotalMs) / 1000})
+		counts = append(counts, metricSample{labels: r.labels, value: float64(t.Count)})
+	}
+	if len(quantiles) == 0 {
+		return
+	}
+	m.family(name, "summary", help, quantiles)
+	m.samples(name+"_sum", sums)
+	m.samples(name+"_count", counts)
+}
+
+// labeledRunner is a RunnerStatus with the labels that identify its project.
+type labeledRunner struct {
+	labels string
+	status RunnerStatus
+}
+
+// joinLabels joins two label lists with a comma, skipping empty ones.
+func joinLabels(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return a + "," + b
+}
+
+// writeMetrics renders a Status as Prometheus metrics. With several projects,
+// each runner's samples carry a project label.
+func writeMetrics(w io.Writer, status Status) {
+	runners := []labeledRunner{{status: status.Runner}}
+	if len(status.Projects) > 0 {
+		runners = runners[:0]
+		for _, p := range status.Projects {
+			runners = append(runners, labeledRunner{labels: fmt.Sprintf("project=%q", p.Name), status: p.Runner})
+		}
+	}
+
+	// collect builds one sample per runner for which value reports ok.
+	collect := func(value func(RunnerStatus) (float64, bool)) []metricSample {
+		var samples []metricSample
+		for _, r := range runners {
+			if v, ok := value(r.status); ok {
+				samples = append(samples, metricSample{labels: r.labels, value: v})
+			}
+		}
+		return samples
+	}
+
+	m := metricsWriter{w: w}
+
+	var states []metricSample
+	for _, r := range runners {
+		states = append(states, metricSample{labels: joinLabels(r.labels, fmt.Sprintf("state=%q", r.status.State)), value: 1})
+	}
+	m.family("runner_state", "gauge", "Current runner state (always 1).", states)
+
+	m.family("auto_restarts_total", "counter", "Automatic restarts after svelte-check crashed.",
+		collect(func(s RunnerStatus) (float64, bool) { return float64(s.AutoRestarts), true }))
+	m.family("consecutive_crashes", "gauge", "Crashes since the last completed check.",
+		collect(func(s RunnerStatus) (float64, bool) { return float64(s.ConsecutiveCrashes), true }))
+	m.family("panics_total", "counter", "Panics recovered in the runner's goroutines.",
+		collect(func(s RunnerStatus) (float64, bool) { return float64(s.Panics), true }))
+	m.family("memory_restarts_total", "counter", "Restarts triggered by the memory ceiling.",
+		collect(func(s RunnerStatus) (float64, bool) { return float64(s.MemoryRestarts), true }))
+	m.family("memory_limit_bytes", "gauge", "Memory ceiling for the svelte-check process tree.",
+		collect(func(s RunnerStatus) (float64, bool) { return float64(s.MemoryLimitBytes), s.MemoryLimitBytes > 0 }))
+
+	m.summary("check_duration_seconds", "Wall time of svelte-check cycles, START to COMPLETED.", runners)
+	m.family("last_check_duration_seconds", "gauge", "Wall time of the most recent check cycle.",
+		collect(func(s RunnerStatus) (float64, bool) {
+			if s.Timings == nil {
+				return 0, false
+			}
+			return float64(s.Timings.LastMs) / 1000, true
+		}))
+	m.family("max_check_duration_seconds", "gauge", "Longest check cycle since the daemon started.",
+		collect(func(s RunnerStatus) (float64, bool) {
+			if s.Timings == nil {
+				return 0, false
+			}
+			return float64(s.Timings.MaxMs) / 1000, true
+		}))
+
+	m.family("child_rss_bytes", "gauge", "Resident memory of the svelte-check process tree.",
+		collect(func(s RunnerStatus) (float64, bool) {
+			if s.Resources == nil {
+				return 0, false
+			}
+			return float64(s.Resources.RSSBytes), true
+		}))
+	m.family("child_cpu_percent", "gauge", "CPU usage of the svelte-check process tree (100 = one core).",
+		collect(func(s RunnerStatus) (float64, bool) {
+			if s.Resources == nil {
+				return 0, false
+			}
+			return s.Resources.CPUPercent, true
+		}))
+	m.family("child_processes", "gauge", "Processes in the svelte-check process tree.",
+		collect(func(s RunnerStatus) (float64, bool) {
+			if s.Resources == nil {
+				return 0, false
+			}
+			return float64(s.Resources.Processes), true
+		}))
+
+	if ws := status.Watcher; ws != nil {
+		counter := func(name, help string, v int) {
+			m.family(name, "counter", help, []metricSample{{value: float64(v)}})
+		}
+		counter("watcher_events_total", "Filesystem events received by the watcher.", ws.Events)
+		counter("watcher_dropped_events_total", "Filesystem events dropped by ignore globs.", ws.Dropped)
+		counter("watcher_overflows_total", "OS event queue overflows, each losing events.", ws.Overflows)
+		counter("watcher_errors_total", "Errors reported by the filesystem watcher.", ws.Errors)
+		counter("watcher_rescans_total", "Rescans for new directories.", ws.Rescans)
+		counter("watcher_sync_triggers_total", "svelte-kit sync runs requested by file changes.", ws.SyncTriggers)
+		counter("watcher_restart_triggers_total", "Restarts requested by file and git changes, before debouncing.", ws.RestartTriggers)
+		counter("watcher_restarts_total", "Restarts performed by the watcher.", ws.Restarts)
+		counter("watcher_restarts_suppressed_total", "Restarts coalesced by the restart cooldown.", ws.RestartsSuppressed)
+		if ws.Watches > 0 {
+			m.family("watcher_watches", "gauge", "Paths with a live OS watch.", []metricSample{{value: float64(ws.Watches)}})
+		}
+	}
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, s.Status())
+}
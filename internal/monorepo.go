@@ -0,0 +1,245 @@
+package internal
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// =============================================================================
+// Monorepo
+// =============================================================================
+
+// packageJSON holds the fields of package.json used for workspace discovery.
+type packageJSON struct {
+	Name            string            `json:"name"`
+	Workspaces      json.RawMessage   `json:"workspaces"` // []string or {"packages": []string}
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+}
+
+func readPackageJSON(dir string) (packageJSON, error) {
+	var pkg packageJSON
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return pkg, err
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return pkg, fmt.Errorf("parse %s: %w", filepath.Join(dir, "package.json"), err)
+	}
+	return pkg, nil
+}
+
+// workspacePatterns returns the package globs from package.json "workspaces".
+func (p packageJSON) workspacePatterns() []string {
+	if len(p.Workspaces) == 0 {
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(p.Workspaces, &list); err == nil {
+		return list
+	}
+	var obj struct {
+		Packages []string `json:"packages"`
+	}
+	_ = json.Unmarshal(p.Workspaces, &obj)
+	return obj.Packages
+}
+
+// isSvelteKit reports whether the package depends on @sveltejs/kit.
+func (p packageJSON) isSvelteKit() bool {
+	_, dep := p.Dependencies["@sveltejs/kit"]
+	_, dev := p.DevDependencies["@sveltejs/kit"]
+	return dep || dev
+}
+
+// isSvelteKitDir reports whether the package in dir depends on @sveltejs/kit,
+// i.e. whether `svelte-kit sync` applies to it.
+func isSvelteKitDir(dir string) bool {
+	pkg, err := readPackageJSON(dir)
+	return err == nil && pkg.isSvelteKit()
+}
+
+// svelteConfigFiles mark a package as a Svelte project even without a
+// @sveltejs/kit dependency (e.g. a Svelte component library).
+var svelteConfigFiles = []string{"svelte.config.js", "svelte.config.mjs", "svelte.config.ts"}
+
+func hasSvelteConfig(dir string) bool {
+	for _, name := range svelteConfigFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePnpmWorkspace extracts the "packages" globs from pnpm-workspace.yaml.
+// Only the simple block-list form pnpm documents is supported:
+//
+//	packages:
+//	  - "apps/*"
+//	  - "!**/test/**"
+func parsePnpmWorkspace(content string) []string {
+	var patterns []string
+	inPackages := false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		// A new top-level key ends the packages list.
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "-") {
+			inPackages = strings.TrimSpace(strings.TrimSuffix(trimmed, ":")) == "packages"
+			continue
+		}
+
+		if inPackages {
+			if item, ok := strings.CutPrefix(trimmed, "-"); ok {
+				patterns = append(patterns, strings.Trim(strings.TrimSpace(item), `"'`))
+			}
+		}
+	}
+	return patterns
+}
+
+// ErrNoWorkspaces is returned by DiscoverWorkspacePackages when the root
+// declares no package workspaces.
+var ErrNoWorkspaces = errors.New("no pnpm-workspace.yaml or package.json workspaces found")
+
+// DiscoverWorkspacePackages finds the Svelte packages of a monorepo rooted at
+// root, as listed by pnpm-workspace.yaml or the "workspaces" field of
+// package.json. Each becomes a ProjectConfig named after the package, with
+// Dir relative to root. Packages are returned sorted by directory.
+func DiscoverWorkspacePackages(root string) ([]ProjectConfig, error) {
+	var patterns []string
+	if data, err := os.ReadFile(filepath.Join(root, "pnpm-workspace.yaml")); err == nil {
+		patterns = parsePnpmWorkspace(string(data))
+	} else if pkg, err := readPackageJSON(root); err == nil {
+		patterns = pkg.workspacePatterns()
+	}
+	if len(patterns) == 0 {
+		return nil, ErrNoWorkspaces
+	}
+
+	var include, exclude []string
+	for _, p := range patterns {
+		if neg, ok := strings.CutPrefix(p, "!"); ok {
+			exclude = append(exclude, filepath.Clean(neg))
+		} else {
+			include = append(include, filepath.Clean(p))
+		}
+	}
+
+	dirs := make(map[string]bool)
+	for _, pattern := range include {
+		matches, err := expandWorkspaceGlob(root, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			dirs[m] = true
+		}
+	}
+
+	var projects []ProjectConfig
+	for _, dir := range slices.Sorted(maps.Keys(dirs)) {
+		if matchesAny(dir, exclude) {
+			continue
+		}
+		abs := filepath.Join(root, dir)
+		pkg, err := readPackageJSON(abs)
+		if err != nil {
+			continue // not a package
+		}
+		if !pkg.isSvelteKit() && !hasSvelteConfig(abs) {
+			continue
+		}
+		projects = append(projects, ProjectConfig{
+			Name: cmp.Or(pkg.Name, filepath.ToSlash(dir)),
+			Dir:  dir,
+		})
+	}
+	return projects, ValidateProjects(projects)
+}
+
+// expandWorkspaceGlob returns the directories under root matching a workspace
+// glob. A trailing "/**" matches every directory below the prefix, skipping
+// node_modules; other patterns use filepath.Glob syntax.
+func expandWorkspaceGlob(root, pattern string) ([]string, error) {
+	if prefix, ok := strings.CutSuffix(pattern, string(filepath.Separator)+"**"); ok {
+		base := filepath.Join(root, prefix)
+		var dirs []string
+		err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if d.Name() == "node_modules" || strings.HasPrefix(d.Name(), ".") && path != base {
+				return filepath.SkipDir
+			}
+			rel, _ := filepath.Rel(root, path)
+			dirs = append(dirs, rel)
+			return nil
+		})
+		return dirs, err
+	}
+
+	matches, err := filepath.Glob(filepath.Join(root, pattern))
+	if err != nil {
+		return nil, fmt.Errorf("invalid workspace pattern %q: %w", pattern, err)
+	}
+	var dirs []string
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.IsDir() {
+			rel, _ := filepath.Rel(root, m)
+			dirs = append(dirs, rel)
+		}
+	}
+	return dirs, nil
+}
+
+// matchesAny reports whether dir matches one of the exclusion globs. "**"
+// segments match any number of directories.
+func matchesAny(dir string, patterns []string) bool {
+	for _, p := range patterns {
+		if matchDoubleStar(strings.Split(p, string(filepath.Separator)), strings.Split(dir, string(filepath.Separator))) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchDoubleStar(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchDoubleStar(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if ok, _ := filepath.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+	return matchDoubleStar(pattern[1:], parts[1:])
+}
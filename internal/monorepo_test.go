@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFiles creates files (and their directories) under root.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+}
+
+func TestParsePnpmWorkspace(t *testing.T) {
+	content := `# comment
+packages:
+  - "apps/*"
+  - 'packages/**' # trailing comment
+  - "!**/test/**"
+catalog:
+  svelte: ^5.0.0
+`
+	got := parsePnpmWorkspace(content)
+	want := []string{"apps/*", "packages/**", "!**/test/**"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePnpmWorkspace() = %q, want %q", got, want)
+	}
+}
+
+// TestDiscoverWorkspacePackages_Pnpm tests discovery from pnpm-workspace.yaml,
+// including recursive globs, exclusions, and skipping non-Svelte packages.
+func TestDiscoverWorkspacePackages_Pnpm(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"pnpm-workspace.yaml":               "packages:\n  - apps/*\n  - packages/**\n  - '!**/test/**'\n",
+		"apps/web/package.json":             `{"name": "@acme/web", "devDependencies": {"@sveltejs/kit": "^2.0.0"}}`,
+		"apps/api/package.json":             `{"name": "@acme/api", "dependencies": {"fastify": "^4.0.0"}}`,
+		"packages/ui/package.json":          `{"name": "@acme/ui"}`,
+		"packages/ui/svelte.config.js":      "export default {}",
+		"packages/ui/test/package.json":     `{"name": "ui-test", "devDependencies": {"@sveltejs/kit": "^2.0.0"}}`,
+		"packages/nameless/package.json":    `{"devDependencies": {"@sveltejs/kit": "^2.0.0"}}`,
+		"packages/ui/node_modules/x/a.json": `{}`,
+	})
+
+	got, err := DiscoverWorkspacePackages(root)
+	if err != nil {
+		t.Fatalf("DiscoverWorkspacePackages failed: %v", err)
+	}
+	want := []ProjectConfig{
+		{Name: "@acme/web", Dir: filepath.Join("apps", "web")},
+		{Name: "packages/nameless", Dir: filepath.Join("packages", "nameless")},
+		{Name: "@acme/ui", Dir: filepath.Join("packages", "ui")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiscoverWorkspacePackages() = %+v, want %+v", got, want)
+	}
+}
+
+// TestDiscoverWorkspacePackages_PackageJSON tests both forms of the
+// package.json "workspaces" field.
+func TestDiscoverWorkspacePackages_PackageJSON(t *testing.T) {
+	for _, workspaces := range []string{`["sites/*"]`, `{"packages": ["sites/*"]}`} {
+		root := t.TempDir()
+		writeFiles(t, root, map[string]string{
+			"package.json":            `{"name": "root", "workspaces": ` + workspaces + `}`,
+			"sites/docs/package.json": `{"name": "docs", "dependencies": {"@sveltejs/kit": "^2.0.0"}}`,
+		})
+
+		got, err := DiscoverWorkspacePackages(root)
+		if err != nil {
+			t.Fatalf("DiscoverWorkspacePackages failed: %v", err)
+		}
+		want := []ProjectConfig{{Name: "docs", Dir: filepath.Join("sites", "docs")}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("workspaces %s: got %+v, want %+v", workspaces, got, want)
+		}
+	}
+}
+
+func TestDiscoverWorkspacePackages_NoWorkspaces(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"package.json": `{"name": "app"}`})
+
+	if _, err := DiscoverWorkspacePackages(root); !errors.Is(err, ErrNoWorkspaces) {
+		t.Errorf("error = %v, want ErrNoWorkspaces", err)
+	}
+}
+
+func TestIsSvelteKitDir(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"kit/package.json": `{"devDependencies": {"@sveltejs/kit": "^2.0.0"}}`,
+		"lib/package.json": `{"devDependencies": {"svelte": "^5.0.0"}}`,
+	})
+
+	if !isSvelteKitDir(filepath.Join(root, "kit")) {
+		t.Error("expected kit to be a SvelteKit project")
+	}
+	if isSvelteKitDir(filepath.Join(root, "lib")) {
+		t.Error("expected lib not to be a SvelteKit project")
+	}
+	if isSvelteKitDir(filepath.Join(root, "missing")) {
+		t.Error("expected a directory without package.json not to be a SvelteKit project")
+	}
+}
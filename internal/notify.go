@@ -0,0 +1,477 @@
+package internal
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// =============================================================================
+// Notifications
+// =============================================================================
+
+// Transitions a Notification reports.
+const (
+	TransitionBroken            = "broken"             // errors appeared in a clean workspace
+	TransitionClean             = "clean"              // the last errors were fixed
+	TransitionThresholdExceeded = "threshold-exceeded" // errors reached NotifyPolicy.ErrorThreshold
+	TransitionBelowThreshold    = "below-threshold"    // errors fell below it again, but not to 0
+)
+
+// notifyTimeout bounds each notifier's delivery.
+const notifyTimeout = 10 * time.Second
+
+// Notification is sent when the workspace's health changes.
+type Notification struct {
+	Transition     string                   `json:"transition"`
+	Errors         int                      `json:"errors"`
+	Warnings       int                      `json:"warnings"`
+	PreviousErrors int                      `json:"previousErrors"` // as of the previous notification
+	Threshold      int                      `json:"threshold,omitempty"`
+	At             time.Time                `json:"at"`
+	Result         SvelteWatchCheckComplete `json:"result"`
+}
+
+// Notifier delivers notifications, e.g. to a webhook.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotifyPolicy decides which results are worth a notification. Only
+// changes of health are: from clean to broken and back, and across
+// ErrorThreshold, rather than every one of the day's check cycles.
+type NotifyPolicy struct {
+	// ErrorThreshold, if positive, is an error count whose crossing in
+	// either direction is a change of health too.
+	ErrorThreshold int
+
+	// QuietHours holds back notifications during part of the day.
+	QuietHours QuietHours
+
+	// MinInterval holds back notifications sent sooner than this after the
+	// previous one.
+	MinInterval time.Duration
+}
+
+// health is a result's state as far as NotifyPolicy is concerned.
+type health int
+
+const (
+	healthClean health = iota
+	healthBroken
+	healthOverThreshold
+)
+
+func (p NotifyPolicy) health(result SvelteWatchCheckComplete) health {
+	switch {
+	case p.ErrorThreshold > 0 && result.ErrorCount >= p.ErrorThreshold:
+		return healthOverThreshold
+	case result.ErrorCount > 0:
+		return healthBroken
+	}
+	return healthClean
+}
+
+// transition names the change from one health to another.
+func transition(from, to health) string {
+	switch {
+	case to == healthClean:
+		return TransitionClean
+	case to == healthOverThreshold:
+		return TransitionThresholdExceeded
+	case from == healthOverThreshold:
+		return TransitionBelowThreshold
+	}
+	return TransitionBroken
+}
+
+// Notifications sends results to notifiers as NotifyPolicy allows. Each
+// result is compared with the health last notified, so a change held back
+// by quiet hours or the rate limit is sent with the first result after
+// them, unless it has been undone by then.
+type Notifications struct {
+	policy    NotifyPolicy
+	notifiers []Notifier
+	now       func() time.Time
+
+	mu         sync.Mutex
+	seen       bool // a result has been observed; the first sets the health
+	health     health
+	errors     int // as of the previous notification
+	lastSentAt time.Time
+}
+
+// NewNotifications returns Notifications sending to notifiers.
+func NewNotifications(policy NotifyPolicy, notifiers ...Notifier) *Notifications {
+	return &Notifications{policy: policy, notifiers: notifiers, now: time.Now}
+}
+
+// Observe notifies every notifier of result if it changes the workspace's
+// health and the policy does not hold it back.
+func (n *Notifications) Observe(ctx context.Context, result SvelteWatchCheckComplete) {
+	note, ok := n.decide(result)
+	if !ok {
+		return
+	}
+	log := logger("notify")
+	log.Info("notifying", "transition", note.Transition, "errors", note.Errors)
+	for i, notifier := range n.notifiers {
+		ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+		if err := notifier.Notify(ctx, note); err != nil {
+			log.Warn("notification failed", "notifier", i, "error", err)
+		}
+		cancel()
+	}
+}
+
+// decide returns the notification result warrants, if any.
+func (n *Notifications) decide(result SvelteWatchCheckComplete) (Notification, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	h := n.policy.health(result)
+	if !n.seen {
+		// The first result after starting is no change.
+		n.seen, n.health, n.errors = true, h, result.ErrorCount
+		return Notification{}, false
+	}
+	if h == n.health {
+		return Notification{}, false
+	}
+	now := n.now()
+	if n.policy.QuietHours.Contains(now) {
+		return Notification{}, false
+	}
+	if n.policy.MinInterval > 0 && !n.lastSentAt.IsZero() && now.Sub(n.lastSentAt) < n.policy.MinInterval {
+		return Notification{}, false
+	}
+
+	note := Notification{
+		Transition:     transition(n.health, h),
+		Errors:         result.ErrorCount,
+		Warnings:       result.WarningCount,
+		PreviousErrors: n.errors,
+		Threshold:      n.policy.ErrorThreshold,
+		At:             now,
+		Result:         result,
+	}
+	n.health, n.errors, n.lastSentAt = h, result.ErrorCount, now
+	return note, true
+}
+
+// QuietHours is a daily span of local time, which may run past midnight,
+// e.g. 22:00 to 08:00. The zero value is no span.
+type QuietHours struct {
+	Start, End time.Duration // since midnight
+}
+
+// ParseQuietHours parses a span such as "22:00-08:00".
+func ParseQuietHours(s string) (QuietHours, error) {
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return QuietHours{}, fmt.Errorf("invalid quiet hours %q: want HH:MM-HH:MM", s)
+	}
+	var q QuietHours
+	for _, part := range []struct {
+		s string
+		d *time.Duration
+	}{{start, &q.Start}, {end, &q.End}} {
+		t, err := time.Parse("15:04", strings.TrimSpace(part.s))
+		if err != nil {
+			return QuietHours{}, fmt.Errorf("invalid quiet hours %q: want HH:MM-HH:MM", s)
+		}
+		*part.d = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	return q, nil
+}
+
+// Contains reports whether t falls within the span.
+func (q QuietHours) Contains(t time.Time) bool {
+	if q.Start == q.End {
+		return false
+	}
+	since := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if q.Start < q.End {
+		return since >= q.Start && since < q.End
+	}
+	return since >= q.Start || since < q.End
+}
+
+// notifyResults passes each new result, as /check would serve it, to
+// s.notifications and s.hooks until ctx is done or the server shuts down.
+func (s *Server) notifyResults(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/check", nil)
+
+	for result := range s.pollResults(ctx, r) {
+		if s.notifications != nil {
+			s.notifications.Observe(ctx, result)
+		}
+		s.hooks.Observe(ctx, result)
+	}
+}
+
+// SetNotifications sends changes of the workspace's health to n. Call it
+// before Start.
+func (s *Server) SetNotifications(n *Notifications) {
+	s.notifications = n
+}
+
+// =============================================================================
+// Webhook Notifier
+// =============================================================================
+
+// WebhookNotifier POSTs each Notification as JSON to a URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client // http.DefaultClient if nil
+}
+
+// Notify posts n, failing on a non-2xx response.
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, w.Client, w.URL, body)
+}
+
+// postJSON POSTs body to target, failing on a non-2xx response. Errors name
+// only its host: webhook URLs carry their secret in the path.
+func postJSON(ctx context.Context, client *http.Client, target string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", withoutURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("POST to %s: %w", req.URL.Host, withoutURL(err))
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST to %s: %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
+
+// withoutURL unwraps a *url.Error, whose message includes the whole URL.
+func withoutURL(err error) error {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		return uerr.Err
+	}
+	return err
+}
+
+// =============================================================================
+// Chat Notifiers
+// =============================================================================
+
+// Chat services a ChatNotifier posts to.
+const (
+	ChatSlack   = "slack"
+	ChatDiscord = "discord"
+)
+
+// maxTopFiles bounds the files NotifyMessage.TopFiles lists.
+const maxTopFiles = 5
+
+// discordMaxContent is the most characters Discord accepts in a message.
+const discordMaxContent = 2000
+
+// DefaultNotifyTemplate formats a NotifyMessage, e.g.
+//
+//	🔴 svelte-check broken on main: 3 errors (+3), 1 warnings
+//	• src/routes/+page.svelte: 2
+//	• src/lib/api.ts: 1
+const DefaultNotifyTemplate = `{{.Icon}} svelte-check {{.Transition}}{{with .Branch}} on {{.}}{{end}}: ` +
+	`{{.Errors}} errors ({{signed .Delta}}), {{.Warnings}} warnings
+{{- range .TopFiles}}
+• {{.File}}: {{.Errors}}
+{{- end}}`
+
+// NotifyMessage is what a chat message template formats: the Notification
+// and what is worth reading at a glance.
+type NotifyMessage struct {
+	Notification
+	Icon     string       // a colored circle: red when broken, green when clean
+	Branch   string       // empty for a detached HEAD or outside git
+	Delta    int          // Errors - PreviousErrors
+	TopFiles []FileErrors // the files with the most errors, at most maxTopFiles
+}
+
+// FileErrors is a file's error count.
+type FileErrors struct {
+	File   string
+	Errors int
+}
+
+// newNotifyMessage returns n's NotifyMessage.
+func newNotifyMessage(n Notification) NotifyMessage {
+	m := NotifyMessage{Notification: n, Delta: n.Errors - n.PreviousErrors}
+	m.Icon = map[string]string{
+		TransitionBroken:            "🔴",
+		TransitionClean:             "🟢",
+		TransitionThresholdExceeded: "🔥",
+		TransitionBelowThreshold:    "🟠",
+	}[n.Transition]
+	if n.Result.Git != nil {
+		m.Branch = n.Result.Git.Branch
+	}
+
+	counts := make(map[string]int)
+	for _, d := range n.Result.Diagnostics {
+		if d.Type == "ERROR" {
+			counts[d.Filename]++
+		}
+	}
+	for file, errors := range counts {
+		m.TopFiles = append(m.TopFiles, FileErrors{File: file, Errors: errors})
+	}
+	slices.SortFunc(m.TopFiles, func(a, b FileErrors) int {
+		if a.Errors != b.Errors {
+			return b.Errors - a.Errors
+		}
+		return strings.Compare(a.File, b.File)
+	})
+	if len(m.TopFiles) > maxTopFiles {
+		m.TopFiles = m.TopFiles[:maxTopFiles]
+	}
+	return m
+}
+
+// ParseNotifyTemplate parses a chat message template, which formats a
+// NotifyMessage and may call signed to format an int with its sign.
+func ParseNotifyTemplate(text string) (*template.Template, error) {
+	return template.New("notify").Funcs(template.FuncMap{
+		"signed": func(n int) string { return fmt.Sprintf("%+d", n) },
+	}).Parse(text)
+}
+
+// ChatNotifier posts each Notification as a message to a Slack or Discord
+// incoming webhook.
+type ChatNotifier struct {
+	URL      string
+	Service  string             // ChatSlack or ChatDiscord
+	Template *template.Template // from ParseNotifyTemplate
+	Client   *http.Client       // http.DefaultClient if nil
+}
+
+// Notify posts n's message, failing on a non-2xx response.
+func (c *ChatNotifier) Notify(ctx context.Context, n Notification) error {
+	var sb strings.Builder
+	if err := c.Template.Execute(&sb, newNotifyMessage(n)); err != nil {
+		return err
+	}
+	text := sb.String()
+
+	var payload any = map[string]string{"text": text}
+	if c.Service == ChatDiscord {
+		if utf8.RuneCountInString(text) > discordMaxContent {
+			text = string([]rune(text)[:discordMaxContent-1]) + "…"
+		}
+		payload = map[string]string{"content": text}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, c.Client, c.URL, body)
+}
+
+// =============================================================================
+// Notify Config
+// =============================================================================
+
+// NotifyConfig is the "notify" object of the config file, e.g.
+// {"webhooks": ["https://..."], "slack": ["https://hooks.slack.com/..."],
+// "errorThreshold": 50, "quietHours": "22:00-08:00", "minInterval": "15m"}.
+type NotifyConfig struct {
+	Webhooks       []string `json:"webhooks,omitempty"`
+	Slack          []string `json:"slack,omitempty"`    // incoming webhook URLs
+	Discord        []string `json:"discord,omitempty"`  // webhook URLs
+	Template       string   `json:"template,omitempty"` // of Slack and Discord messages; see NotifyMessage
+	ErrorThreshold int      `json:"errorThreshold,omitempty"`
+	QuietHours     string   `json:"quietHours,omitempty"`
+	MinInterval    string   `json:"minInterval,omitempty"`
+}
+
+// Policy returns the NotifyPolicy c configures.
+func (c NotifyConfig) Policy() (NotifyPolicy, error) {
+	p := NotifyPolicy{ErrorThreshold: c.ErrorThreshold}
+	if c.ErrorThreshold < 0 {
+		return p, errors.New("errorThreshold must not be negative")
+	}
+	var err error
+	if c.QuietHours != "" {
+		if p.QuietHours, err = ParseQuietHours(c.QuietHours); err != nil {
+			return p, err
+		}
+	}
+	if c.MinInterval != "" {
+		if p.MinInterval, err = time.ParseDuration(c.MinInterval); err != nil {
+			return p, fmt.Errorf("invalid minInterval: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// Notifiers returns the notifiers c configures, failing if its template
+// does not parse.
+func (c NotifyConfig) Notifiers() ([]Notifier, error) {
+	tmpl, err := ParseNotifyTemplate(cmp.Or(c.Template, DefaultNotifyTemplate))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	var notifiers []Notifier
+	for _, url := range c.Webhooks {
+		notifiers = append(notifiers, &WebhookNotifier{URL: url})
+	}
+	for _, url := range c.Slack {
+		notifiers = append(notifiers, &ChatNotifier{URL: url, Service: ChatSlack, Template: tmpl})
+	}
+	for _, url := range c.Discord {
+		notifiers = append(notifiers, &ChatNotifier{URL: url, Service: ChatDiscord, Template: tmpl})
+	}
+	return notifiers, nil
+}
+
+// Notifications returns the Notifications c configures, or nil if it
+// configures no notifiers.
+func (c NotifyConfig) Notifications() (*Notifications, error) {
+	policy, err := c.Policy()
+	if err != nil {
+		return nil, err
+	}
+	notifiers, err := c.Notifiers()
+	if err != nil {
+		return nil, err
+	}
+	if len(notifiers) == 0 {
+		return nil, nil
+	}
+	return NewNotifications(policy, notifiers...), nil
+}
@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// observe feeds results with the given error counts to n, one minute apart
+// from start, and returns the transitions notified.
+func observe(n *Notifications, start time.Time, errors ...int) []string {
+	var got []string
+	for i, count := range errors {
+		n.now = func() time.Time { return start.Add(time.Duration(i) * time.Minute) }
+		if note, ok := n.decide(SvelteWatchCheckComplete{ErrorCount: count}); ok {
+			got = append(got, note.Transition)
+		}
+	}
+	return got
+}
+
+func TestNotifications_Transitions(t *testing.T) {
+	n := NewNotifications(NotifyPolicy{ErrorThreshold: 50})
+	got := observe(n, time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local), 0, 0, 3, 5, 60, 70, 20, 0, 0)
+	want := "broken threshold-exceeded below-threshold clean"
+	if strings.Join(got, " ") != want {
+		t.Errorf("transitions = %q, want %q", strings.Join(got, " "), want)
+	}
+
+	// The first result only sets the health.
+	n = NewNotifications(NotifyPolicy{})
+	if got := observe(n, time.Now(), 4, 4); len(got) != 0 {
+		t.Errorf("transitions = %q, want none", got)
+	}
+}
+
+// TestNotifications_HeldBack tests that a change held back by quiet hours or
+// the rate limit is sent afterwards, unless it has been undone.
+func TestNotifications_HeldBack(t *testing.T) {
+	quiet := QuietHours{Start: 22 * time.Hour, End: 8 * time.Hour}
+	n := NewNotifications(NotifyPolicy{QuietHours: quiet})
+	// 23:57 to 00:01: broken and fixed again within quiet hours.
+	if got := observe(n, time.Date(2026, 3, 2, 23, 57, 0, 0, time.Local), 0, 2, 0, 2); len(got) != 0 {
+		t.Errorf("transitions during quiet hours = %q, want none", got)
+	}
+	// 07:59 and 08:00: still broken once quiet hours end.
+	if got := observe(n, time.Date(2026, 3, 3, 7, 59, 0, 0, time.Local), 2, 2); strings.Join(got, " ") != "broken" {
+		t.Errorf("transitions after quiet hours = %q, want broken", got)
+	}
+
+	n = NewNotifications(NotifyPolicy{MinInterval: 5 * time.Minute})
+	got := observe(n, time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local), 0, 1, 0, 1, 1, 1, 1, 0, 1)
+	// broken at 12:01 is sent; clean at 12:02 is held back and undone at
+	// 12:03; clean at 12:07, six minutes after broken, is sent.
+	if strings.Join(got, " ") != "broken clean" {
+		t.Errorf("transitions = %q, want broken clean", got)
+	}
+	if n.lastSentAt.Minute() != 7 {
+		t.Errorf("last sent at %v, want 12:07", n.lastSentAt)
+	}
+}
+
+func TestParseQuietHours(t *testing.T) {
+	q, err := ParseQuietHours("22:00-08:30")
+	if err != nil {
+		t.Fatalf("ParseQuietHours failed: %v", err)
+	}
+	for hour, want := range map[int]bool{21: false, 22: true, 3: true, 8: true, 9: false} {
+		if got := q.Contains(time.Date(2026, 3, 2, hour, 15, 0, 0, time.Local)); got != want {
+			t.Errorf("Contains(%02d:15) = %v, want %v", hour, got, want)
+		}
+	}
+	if q, _ := ParseQuietHours("12:00-13:00"); !q.Contains(time.Date(2026, 3, 2, 12, 30, 0, 0, time.Local)) {
+		t.Error("12:00-13:00 does not contain 12:30")
+	}
+	for _, bad := range []string{"22:00", "late-early", "25:00-08:00"} {
+		if _, err := ParseQuietHours(bad); err == nil {
+			t.Errorf("ParseQuietHours(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+// TestServer_Notifications tests that the server posts a change of health
+// to a webhook, and nothing for the first result.
+func TestServer_Notifications(t *testing.T) {
+	posted := make(chan Notification, 4)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("decoding notification: %v", err)
+		}
+		posted <- n
+	}))
+	defer hook.Close()
+
+	socketPath := testSocketPath(t)
+	executor := NewFakeExecutor(checkOutput("1770255834000", 0), "")
+	r := NewRunner("/workspace", WithExecutor(executor))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	notifications, err := NotifyConfig{Webhooks: []string{hook.URL}}.Notifications()
+	if err != nil {
+		t.Fatalf("Notifications failed: %v", err)
+	}
+	s := NewServer(socketPath, r)
+	s.SetNotifications(notifications)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	// Let the server observe the first, clean, result.
+	time.Sleep(3 * eventsPollInterval)
+	executor.setCmd(newFakeCmd(checkOutput("1770255835000", 2)))
+	if err := r.Restart(context.Background()); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+
+	select {
+	case n := <-posted:
+		if n.Transition != TransitionBroken || n.Errors != 2 || n.PreviousErrors != 0 || n.Result.Timestamp != 1770255835000 {
+			t.Errorf("notification = %+v, want broken with 2 errors", n)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no notification within 2s")
+	}
+	select {
+	case n := <-posted:
+		t.Errorf("unexpected notification %+v", n)
+	default:
+	}
+}
+
+func TestNotifyConfig_Policy(t *testing.T) {
+	p, err := NotifyConfig{ErrorThreshold: 10, QuietHours: "22:00-07:00", MinInterval: "15m"}.Policy()
+	if err != nil {
+		t.Fatalf("Policy failed: %v", err)
+	}
+	if p.ErrorThreshold != 10 || p.QuietHours.Start != 22*time.Hour || p.MinInterval != 15*time.Minute {
+		t.Errorf("Policy() = %+v", p)
+	}
+	for _, c := range []NotifyConfig{{ErrorThreshold: -1}, {QuietHours: "soon"}, {MinInterval: "often"}} {
+		if _, err := c.Policy(); err == nil {
+			t.Errorf("%+v.Policy() succeeded, want an error", c)
+		}
+	}
+	if n, err := (NotifyConfig{}).Notifications(); n != nil || err != nil {
+		t.Errorf("Notifications() without notifiers = %v, %v; want nil", n, err)
+	}
+}
+
+func TestChatNotifier(t *testing.T) {
+	posted := make(chan map[string]string, 2)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding message: %v", err)
+		}
+		posted <- body
+	}))
+	defer hook.Close()
+
+	notifiers, err := NotifyConfig{Slack: []string{hook.URL}, Discord: []string{hook.URL}}.Notifiers()
+	if err != nil {
+		t.Fatalf("Notifiers failed: %v", err)
+	}
+	n := Notification{
+		Transition: TransitionBroken, Errors: 3, Warnings: 1, PreviousErrors: 0,
+		Result: SvelteWatchCheckComplete{
+			Git: &GitState{Branch: "main"},
+			Diagnostics: []Diagnostic{
+				{Type: "ERROR", Filename: "src/lib/api.ts"},
+				{Type: "ERROR", Filename: "src/routes/+page.svelte"},
+				{Type: "WARNING", Filename: "src/app.css"},
+				{Type: "ERROR", Filename: "src/routes/+page.svelte"},
+			},
+		},
+	}
+	want := "🔴 svelte-check broken on main: 3 errors (+3), 1 warnings\n• src/routes/+page.svelte: 2\n• src/lib/api.ts: 1"
+	for i, key := range []string{"text", "content"} {
+		if err := notifiers[i].Notify(context.Background(), n); err != nil {
+			t.Fatalf("Notify failed: %v", err)
+		}
+		if got := <-posted; got[key] != want {
+			t.Errorf("%s = %q, want %q", key, got[key], want)
+		}
+	}
+
+	if _, err := (NotifyConfig{Slack: []string{hook.URL}, Template: "{{.Nope"}).Notifications(); err == nil {
+		t.Error("Notifications() with an invalid template succeeded")
+	}
+}
+
+func TestPostJSON_RedactsURL(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	addr := strings.TrimPrefix(hook.URL, "http://")
+
+	const secret = "/services/T000/B000/s3cr3t"
+	if err := postJSON(context.Background(), nil, hook.URL+secret, nil); err == nil || strings.Contains(err.Error(), secret) {
+		t.Errorf("postJSON() with a 403 = %v, want an error without the path", err)
+	} else if !strings.Contains(err.Error(), addr) {
+		t.Errorf("postJSON() with a 403 = %v, want the host named", err)
+	}
+
+	hook.Close()
+	if err := postJSON(context.Background(), nil, hook.URL+secret, nil); err == nil || strings.Contains(err.Error(), secret) {
+		t.Errorf("postJSON() to a closed server = %v, want an error without the path", err)
+	}
+	if err := postJSON(context.Background(), nil, "http://host\x7f"+secret, nil); err == nil || strings.Contains(err.Error(), secret) {
+		t.Errorf("postJSON() to an invalid URL = %v, want an error without the path", err)
+	}
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"log/slog"
+	"time"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// Constructor Options
+// =============================================================================
+
+// DefaultDebounce is how long the Watcher waits for changes to settle before
+// restarting or syncing.
+const DefaultDebounce = 250 * time.Millisecond
+
+// Option configures NewRunner, NewRunnerWithConfig, NewServer, and
+// NewWatcher. A constructor ignores the options that do not apply to it, so
+// one set can be passed to each.
+type Option func(*options)
+
+type options struct {
+	tsconfig    string
+	checkerArgs []string
+	executor    kexec.Interface
+	debounce    time.Duration
+	logger      *slog.Logger
+}
+
+// newOptions applies opts over the defaults.
+func newOptions(opts []Option) options {
+	o := options{debounce: DefaultDebounce, logger: slog.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithTsconfig sets the tsconfig svelte-check is given, overriding
+// RunnerConfig.TsconfigPath.
+func WithTsconfig(path string) Option {
+	return func(o *options) { o.tsconfig = path }
+}
+
+// WithCheckerArgs appends args to every checker invocation, after
+// RunnerConfig.ExtraArgs.
+func WithCheckerArgs(args ...string) Option {
+	return func(o *options) { o.checkerArgs = append(o.checkerArgs, args...) }
+}
+
+// WithExecutor sets what runs the checker; the default is NewExecutor.
+func WithExecutor(executor kexec.Interface) Option {
+	return func(o *options) { o.executor = executor }
+}
+
+// WithDebounce sets how long the Watcher waits for changes to settle; the
+// default is DefaultDebounce. A lockfile change still waits at least
+// WatcherConfig.LockfileGrace.
+func WithDebounce(d time.Duration) Option {
+	return func(o *options) { o.debounce = d }
+}
+
+// WithLogger sets the logger of the Runner and Watcher and the one the
+// Server reports HTTP errors to; the default is slog.Default(). Each adds
+// its subsystem and, for a Runner, its checker and workspace as attributes.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// TestNewRunnerWithConfig_Options tests that options apply over the config.
+func TestNewRunnerWithConfig_Options(t *testing.T) {
+	executor := NewFakeExecutor(checkOutput("1770255834000", 0), "")
+	var logs bytes.Buffer
+	r := NewRunnerWithConfig(RunnerConfig{
+		WorkspacePath:  "/workspace",
+		TsconfigPath:   "tsconfig.json",
+		PackageManager: PackageManagerBun,
+		ExtraArgs:      []string{"--ignore", "dist/**"},
+	}, WithExecutor(executor), WithTsconfig("tsconfig.app.json"), WithCheckerArgs("--threshold", "error"), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	want := "bun run svelte-check --watch --output machine-verbose --tsconfig tsconfig.app.json --ignore dist/** --threshold error"
+	if got := executor.commandLine(); got != want {
+		t.Errorf("command = %q, want %q", got, want)
+	}
+	if _, err := r.GetLatestEvent(context.Background()); err != nil {
+		t.Fatalf("GetLatestEvent failed: %v", err)
+	}
+	r.Stop()
+	for _, want := range []string{`msg="svelte-check started"`, "subsystem=runner", "checker=svelte-check", "workspace=/workspace", "check=1770255834000"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs = %q, want %s", logs.String(), want)
+		}
+	}
+}
+
+// TestWatcher_WithDebounce tests that WithDebounce replaces the default
+// debounce interval.
+func TestWatcher_WithDebounce(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		gitWatcher := NewFakeGitBranchWatcher()
+		var restarts atomic.Int32
+		callbacks := WatcherCallbacks{OnRestart: func() { restarts.Add(1) }}
+		w := NewWatcher(WatcherConfig{WorkspacePath: "/fake/workspace"}, callbacks, NewFakeFSWatcher(), gitWatcher, WithDebounce(time.Second))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go w.Start(ctx)
+		synctest.Wait()
+
+		gitWatcher.headCh <- struct{}{}
+		time.Sleep(DefaultDebounce + 50*time.Millisecond)
+		synctest.Wait()
+		if restarts.Load() != 0 {
+			t.Fatalf("OnRestart called after %v, want it to wait for the 1s debounce", DefaultDebounce)
+		}
+
+		time.Sleep(time.Second)
+		synctest.Wait()
+		if n := restarts.Load(); n != 1 {
+			t.Fatalf("OnRestart called %d times, want 1", n)
+		}
+	})
+}
+
+// TestWatcher_LastTrigger tests that a restart's trigger records when the
+// change was received and when the debounce passed.
+func TestWatcher_LastTrigger(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		gitWatcher := NewFakeGitBranchWatcher()
+		var w *Watcher
+		var trigger CycleTrigger
+		callbacks := WatcherCallbacks{OnRestart: func() { trigger = w.LastTrigger() }}
+		w = NewWatcher(WatcherConfig{WorkspacePath: "/fake/workspace"}, callbacks, NewFakeFSWatcher(), gitWatcher, WithDebounce(time.Second))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go w.Start(ctx)
+		synctest.Wait()
+
+		gitWatcher.headCh <- struct{}{}
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+		if trigger.Source != TriggerGit || trigger.DebouncedAt.Sub(trigger.ReceivedAt) != time.Second {
+			t.Errorf("LastTrigger() = %+v, want a git trigger debounced for 1s", trigger)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// =============================================================================
+// Package Manager
+// =============================================================================
+
+// PackageManager identifies the JavaScript package manager used to invoke
+// svelte-check and svelte-kit from the workspace's node_modules.
+type PackageManager string
+
+const (
+	PackageManagerBun  PackageManager = "bun"
+	PackageManagerNpm  PackageManager = "npm"
+	PackageManagerPnpm PackageManager = "pnpm"
+	PackageManagerYarn PackageManager = "yarn"
+	PackageManagerDeno PackageManager = "deno"
+)
+
+// DefaultPackageManager is used when no lockfile is found in the workspace.
+const DefaultPackageManager = PackageManagerBun
+
+// packageManagerLockfiles maps lockfiles to the package manager that owns them,
+// in detection order. The first lockfile found in the workspace wins.
+var packageManagerLockfiles = []struct {
+	file string
+	pm   PackageManager
+}{
+	{"bun.lockb", PackageManagerBun},
+	{"bun.lock", PackageManagerBun},
+	{"pnpm-lock.yaml", PackageManagerPnpm},
+	{"yarn.lock", PackageManagerYarn},
+	{"package-lock.json", PackageManagerNpm},
+	{"deno.json", PackageManagerDeno},
+	{"deno.jsonc", PackageManagerDeno},
+	{"deno.lock", PackageManagerDeno},
+}
+
+// denoPackageSpecifiers maps binary names to the npm: specifier deno needs to
+// run them, since deno has no node_modules/.bin lookup of its own.
+var denoPackageSpecifiers = map[string]string{
+	"svelte-check": "npm:svelte-check",
+	"svelte-kit":   "npm:@sveltejs/kit/svelte-kit",
+}
+
+// DetectPackageManager returns the package manager for the workspace based on
+// which lockfile is present. Returns DefaultPackageManager if none is found.
+func DetectPackageManager(workspacePath string) PackageManager {
+	for _, lf := range packageManagerLockfiles {
+		if _, err := os.Stat(filepath.Join(workspacePath, lf.file)); err == nil {
+			return lf.pm
+		}
+	}
+	return DefaultPackageManager
+}
+
+// ParsePackageManager validates a package manager name from the command line.
+func ParsePackageManager(name string) (PackageManager, error) {
+	switch pm := PackageManager(name); pm {
+	case PackageManagerBun, PackageManagerNpm, PackageManagerPnpm, PackageManagerYarn, PackageManagerDeno:
+		return pm, nil
+	default:
+		return "", fmt.Errorf("unknown package manager %q (want bun, npm, pnpm, yarn, or deno)", name)
+	}
+}
+
+// Command returns the program and arguments that run the given package binary
+// (e.g. "svelte-check") with args through this package manager.
+func (pm PackageManager) Command(bin string, args ...string) (string, []string) {
+	var prefix []string
+	name := string(pm)
+
+	switch pm {
+	case PackageManagerNpm:
+		// Without --no-install, npx downloads a missing bin from the
+		// registry instead of failing as the others do.
+		name = "npx"
+		prefix = []string{"--no-install", bin}
+	case PackageManagerPnpm:
+		prefix = []string{"exec", bin}
+	case PackageManagerYarn:
+		prefix = []string{bin}
+	case PackageManagerDeno:
+		spec, ok := denoPackageSpecifiers[bin]
+		if !ok {
+			spec = "npm:" + bin
+		}
+		prefix = []string{"run", "-A", spec}
+	default:
+		name = string(PackageManagerBun)
+		prefix = []string{"run", bin}
+	}
+
+	return name, append(prefix, args...)
+}
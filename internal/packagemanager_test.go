@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDetectPackageManager(t *testing.T) {
+	tests := []struct {
+		name      string
+		lockfiles []string
+		want      PackageManager
+	}{
+		{"no lockfile defaults to bun", nil, PackageManagerBun},
+		{"bun binary lockfile", []string{"bun.lockb"}, PackageManagerBun},
+		{"bun text lockfile", []string{"bun.lock"}, PackageManagerBun},
+		{"pnpm", []string{"pnpm-lock.yaml"}, PackageManagerPnpm},
+		{"yarn", []string{"yarn.lock"}, PackageManagerYarn},
+		{"npm", []string{"package-lock.json"}, PackageManagerNpm},
+		{"deno", []string{"deno.json"}, PackageManagerDeno},
+		{"bun wins over npm", []string{"package-lock.json", "bun.lockb"}, PackageManagerBun},
+		{"pnpm wins over yarn", []string{"yarn.lock", "pnpm-lock.yaml"}, PackageManagerPnpm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range tt.lockfiles {
+				if err := os.WriteFile(filepath.Join(dir, f), nil, 0o644); err != nil {
+					t.Fatalf("WriteFile failed: %v", err)
+				}
+			}
+
+			if got := DetectPackageManager(dir); got != tt.want {
+				t.Errorf("DetectPackageManager() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePackageManager(t *testing.T) {
+	for _, name := range []string{"bun", "npm", "pnpm", "yarn", "deno"} {
+		t.Run(name, func(t *testing.T) {
+			pm, err := ParsePackageManager(name)
+			if err != nil {
+				t.Fatalf("ParsePackageManager(%q) failed: %v", name, err)
+			}
+			if string(pm) != name {
+				t.Errorf("ParsePackageManager(%q) = %q", name, pm)
+			}
+		})
+	}
+
+	if _, err := ParsePackageManager("cargo"); err == nil {
+		t.Error("ParsePackageManager(\"cargo\") should fail")
+	}
+}
+
+func TestPackageManager_Command(t *testing.T) {
+	tests := []struct {
+		pm       PackageManager
+		bin      string
+		args     []string
+		wantName string
+		wantArgs []string
+	}{
+		{PackageManagerBun, "svelte-check", []string{"--watch"}, "bun", []string{"run", "svelte-check", "--watch"}},
+		{PackageManagerNpm, "svelte-check", []string{"--watch"}, "npx", []string{"--no-install", "svelte-check", "--watch"}},
+		{PackageManagerPnpm, "svelte-check", []string{"--watch"}, "pnpm", []string{"exec", "svelte-check", "--watch"}},
+		{PackageManagerYarn, "svelte-check", []string{"--watch"}, "yarn", []string{"svelte-check", "--watch"}},
+		{PackageManagerDeno, "svelte-check", []string{"--watch"}, "deno", []string{"run", "-A", "npm:svelte-check", "--watch"}},
+		{PackageManagerDeno, "svelte-kit", []string{"sync"}, "deno", []string{"run", "-A", "npm:@sveltejs/kit/svelte-kit", "sync"}},
+		{PackageManagerPnpm, "svelte-kit", []string{"sync"}, "pnpm", []string{"exec", "svelte-kit", "sync"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.pm)+" "+tt.bin, func(t *testing.T) {
+			name, args := tt.pm.Command(tt.bin, tt.args...)
+			if name != tt.wantName {
+				t.Errorf("name = %q, want %q", name, tt.wantName)
+			}
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("args = %q, want %q", args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// Panic Reports
+// =============================================================================
+
+// CrashDirSuffix is appended to the socket path to name the directory the
+// daemon writes panic reports to.
+const CrashDirSuffix = ".crashes"
+
+// maxPanicReports is how many report files are kept in the crash directory;
+// older ones are removed as new ones are written.
+const maxPanicReports = 20
+
+// PanicReport describes a panic in one of a Runner's goroutines: where it
+// happened, its stack, what the checker printed last, and the config it ran
+// with. A panic is handled like a crash of svelte-check: the process is
+// stopped and restarted with backoff, rather than left running without
+// anything reading its output.
+type PanicReport struct {
+	At        time.Time      `json:"at"`
+	Goroutine string         `json:"goroutine"` // e.g. "interpreter" or "event handler"
+	Panic     string         `json:"panic"`
+	Stack     string         `json:"stack"`
+	Output    []string       `json:"output,omitempty"` // last lines of the checker's output
+	Config    map[string]any `json:"config"`
+	File      string         `json:"file,omitempty"` // the written report; "" if none was
+}
+
+// recoverPanic recovers a panic in the goroutine it is deferred in, named
+// goroutine, which belongs to the process of the given generation. The
+// panic is reported in Status and written to RunnerConfig.CrashDir, and if
+// that process is still the current one, it is stopped and restarted as if
+// it had crashed.
+func (r *Runner) recoverPanic(goroutine string, generation int) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	report := &PanicReport{
+		At:        time.Now(),
+		Goroutine: goroutine,
+		Panic:     fmt.Sprint(v),
+		Stack:     string(debug.Stack()),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := generation == r.generation && r.ctx != nil && r.ctx.Err() == nil
+	if current && r.output != nil {
+		_, report.Output = r.output.Tails()
+	}
+	report.Config = r.configSnapshot()
+	if r.config.CrashDir != "" {
+		file, err := writePanicReport(r.config.CrashDir, r.Name(), report)
+		if err != nil {
+			r.logger.Error("could not write panic report", "dir", r.config.CrashDir, "error", err)
+		}
+		report.File = file
+	}
+	r.panics++
+	r.lastPanic = report
+	r.logger.Error("goroutine panicked", "goroutine", goroutine, "panic", report.Panic, "report", report.File)
+	r.config.Audit.Record("panic", map[string]any{"checker": r.Name(), "workspace": r.config.WorkspacePath, "goroutine": goroutine, "panic": report.Panic, "report": report.File})
+
+	if !current {
+		return
+	}
+	r.crashLocked(fmt.Sprintf("%s panicked: %s", goroutine, report.Panic))
+}
+
+// configSnapshot returns the parts of the config a panic report records.
+// The environment is left out, as it may hold secrets.
+func (r *Runner) configSnapshot() map[string]any {
+	name, args := r.config.command(true)
+	return map[string]any{
+		"checker":        r.Name(),
+		"workspace":      r.config.WorkspacePath,
+		"command":        strings.Join(append([]string{name}, args...), " "),
+		"packageManager": r.config.PackageManager,
+		"tsconfig":       r.config.TsconfigPath,
+		"restartPolicy":  r.config.RestartPolicy,
+		"version":        CurrentVersion().Version,
+	}
+}
+
+// writePanicReport writes report to a new file in dir, removing the oldest
+// reports beyond maxPanicReports, and returns its path.
+func writePanicReport(dir, checker string, report *PanicReport) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%s-%s.json", report.At.UTC().Format("20060102T150405.000000000Z"), checker,
+		strings.ReplaceAll(report.Goroutine, " ", "-"))
+	path := filepath.Join(dir, name)
+	report.File = path
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return "", err
+	}
+
+	// Names start with the time, so they sort oldest first.
+	if old, err := filepath.Glob(filepath.Join(dir, "*.json")); err == nil && len(old) > maxPanicReports {
+		slices.Sort(old)
+		for _, f := range old[:len(old)-maxPanicReports] {
+			_ = os.Remove(f)
+		}
+	}
+	return path, nil
+}
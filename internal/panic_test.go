@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRunner_RecoverPanic tests that a panic in a Runner goroutine is
+// written to the crash directory, reported in Status, and handled like a
+// crash.
+func TestRunner_RecoverPanic(t *testing.T) {
+	dir := t.TempDir()
+	executor := NewFakeExecutor("", "")
+	executor.newCmd = func() *FakeCmd { return newFakeCmd(checkOutput("1770255834000", 0)) }
+	r := NewRunnerWithConfig(RunnerConfig{
+		WorkspacePath: "/workspace",
+		CrashDir:      dir,
+		RestartPolicy: RestartPolicy{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxRetries: 3},
+	}, WithExecutor(executor))
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer r.Stop()
+	if _, err := r.GetLatestEvent(context.Background()); err != nil {
+		t.Fatalf("GetLatestEvent failed: %v", err)
+	}
+
+	r.mu.Lock()
+	generation := r.generation
+	r.mu.Unlock()
+	func() {
+		defer r.recoverPanic("event handler", generation)
+		panic("boom")
+	}()
+
+	status := r.Status()
+	p := status.LastPanic
+	if status.Panics != 1 || p == nil {
+		t.Fatalf("Status() = %+v, want one panic", status)
+	}
+	if p.Goroutine != "event handler" || p.Panic != "boom" || !strings.Contains(p.Stack, "TestRunner_RecoverPanic") {
+		t.Errorf("report = %+v, want the panic and its stack", p)
+	}
+	if p.Config["workspace"] != "/workspace" || !strings.Contains(p.Config["command"].(string), "svelte-check") {
+		t.Errorf("config = %v, want the runner's", p.Config)
+	}
+	if len(p.Output) == 0 {
+		t.Error("report has no output")
+	}
+	if status.ConsecutiveCrashes != 1 || !strings.Contains(status.LastExit, "event handler panicked: boom") {
+		t.Errorf("Status() = %+v, want the panic counted as a crash", status)
+	}
+
+	data, err := os.ReadFile(p.File)
+	if err != nil {
+		t.Fatalf("reading the report: %v", err)
+	}
+	var written PanicReport
+	if err := json.Unmarshal(data, &written); err != nil || written.Panic != "boom" {
+		t.Errorf("written report = %+v, %v; want the panic", written, err)
+	}
+
+	// The checker is restarted after the backoff.
+	for deadline := time.Now().Add(2 * time.Second); r.Status().AutoRestarts == 0; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the checker was not restarted after the panic")
+		}
+	}
+}
+
+// TestWritePanicReport_Prunes tests that only the newest reports are kept.
+func TestWritePanicReport_Prunes(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var last string
+	for i := range maxPanicReports + 5 {
+		var err error
+		last, err = writePanicReport(dir, "svelte-check", &PanicReport{At: start.Add(time.Duration(i) * time.Second), Goroutine: "interpreter"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != maxPanicReports {
+		t.Errorf("%d reports kept, want %d", len(entries), maxPanicReports)
+	}
+	if _, err := os.Stat(last); err != nil {
+		t.Errorf("the newest report was removed: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// =============================================================================
+// Check Policy
+// =============================================================================
+
+// Severity is a kind of problem a check result can fail on.
+type Severity string
+
+const (
+	SeverityFailure Severity = "failure" // svelte-check itself reported a FAILURE
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// severities lists every Severity from most to least severe.
+var severities = []Severity{SeverityFailure, SeverityError, SeverityWarning}
+
+// ParseSeverity validates a severity name.
+func ParseSeverity(s string) (Severity, error) {
+	sev := Severity(strings.ToLower(strings.TrimSpace(s)))
+	if !slices.Contains(severities, sev) {
+		return "", fmt.Errorf("unknown severity %q (want failure, error, or warning)", s)
+	}
+	return sev, nil
+}
+
+// Response headers carrying a /check result's verdict, so clients need not
+// know the daemon's policy.
+const (
+	HeaderCheckVerdict  = "X-Check-Verdict"   // the failing Severity, absent if the check passed
+	HeaderCheckExitCode = "X-Check-Exit-Code" // the exit code the check command should use
+)
+
+// CheckPolicy decides which results fail /check and how: the HTTP status of
+// GET /check and the exit code of the check command.
+type CheckPolicy struct {
+	// FailOn lists the severities that fail a result. Errors and warnings
+	// are answered with 500 Internal Server Error, a FAILURE with
+	// FailureStatus.
+	FailOn []Severity
+
+	// FailureStatus is the HTTP status for a FAILURE: 502 Bad Gateway, since
+	// svelte-check rather than the code is broken, or 500 for clients that
+	// only know that one.
+	FailureStatus int
+
+	// ExitCodes maps each severity to the check command's exit code.
+	// Severities not listed exit 1.
+	ExitCodes map[Severity]int
+}
+
+// DefaultCheckPolicy fails on svelte-check failures and on errors, but not on
+// warnings. A failure exits 2, so scripts can tell a broken checker from
+// type errors.
+var DefaultCheckPolicy = CheckPolicy{
+	FailOn:        []Severity{SeverityFailure, SeverityError},
+	FailureStatus: http.StatusBadGateway,
+	ExitCodes:     map[Severity]int{SeverityFailure: 2, SeverityError: 1, SeverityWarning: 1},
+}
+
+// Validate reports an invalid policy.
+func (p CheckPolicy) Validate() error {
+	for _, sev := range p.FailOn {
+		if !slices.Contains(severities, sev) {
+			return fmt.Errorf("unknown severity %q", sev)
+		}
+	}
+	if p.FailureStatus != http.StatusBadGateway && p.FailureStatus != http.StatusInternalServerError {
+		return fmt.Errorf("failure status must be 500 or 502, got %d", p.FailureStatus)
+	}
+	for sev, code := range p.ExitCodes {
+		if code < 1 || code > 125 {
+			return fmt.Errorf("exit code for %s must be 1-125, got %d", sev, code)
+		}
+	}
+	return nil
+}
+
+// Verdict returns the most severe problem in result that the policy fails
+// on, or "" if the result passes.
+func (p CheckPolicy) Verdict(result SvelteWatchCheckComplete) Severity {
+	present := map[Severity]bool{
+		SeverityFailure: len(result.Failures) > 0,
+		SeverityError:   result.ErrorCount > 0,
+		SeverityWarning: result.WarningCount > 0,
+	}
+	for _, sev := range severities {
+		if present[sev] && slices.Contains(p.FailOn, sev) {
+			return sev
+		}
+	}
+	return ""
+}
+
+// StatusCode returns the HTTP status for a verdict.
+func (p CheckPolicy) StatusCode(verdict Severity) int {
+	switch verdict {
+	case "":
+		return http.StatusOK
+	case SeverityFailure:
+		return cmp.Or(p.FailureStatus, http.StatusBadGateway)
+	}
+	return http.StatusInternalServerError
+}
+
+// ExitCode returns the check command's exit code for a verdict.
+func (p CheckPolicy) ExitCode(verdict Severity) int {
+	if verdict == "" {
+		return 0
+	}
+	if code, ok := p.ExitCodes[verdict]; ok {
+		return code
+	}
+	return 1
+}
+
+// ParseSeverities parses severity names, as given to --fail-on.
+func ParseSeverities(names []string) ([]Severity, error) {
+	sevs := make([]Severity, 0, len(names))
+	for _, name := range names {
+		sev, err := ParseSeverity(name)
+		if err != nil {
+			return nil, err
+		}
+		sevs = append(sevs, sev)
+	}
+	return sevs, nil
+}
+
+// ParseExitCodes parses severity=code pairs, as given to --exit-codes, e.g.
+// "error=1,warning=3".
+func ParseExitCodes(pairs []string) (map[Severity]int, error) {
+	codes := make(map[Severity]int, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid exit code %q (want SEVERITY=CODE)", pair)
+		}
+		sev, err := ParseSeverity(name)
+		if err != nil {
+			return nil, err
+		}
+		code, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || code < 1 || code > 125 {
+			return nil, fmt.Errorf("invalid exit code %q for %s (want 1-125)", value, sev)
+		}
+		codes[sev] = code
+	}
+	return codes, nil
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCheckPolicy_Verdict(t *testing.T) {
+	warnings := SvelteWatchCheckComplete{WarningCount: 2}
+	errs := SvelteWatchCheckComplete{ErrorCount: 1, WarningCount: 2}
+	failed := SvelteWatchCheckComplete{ErrorCount: 1, Failures: []string{"Connection closed"}}
+
+	tests := []struct {
+		name     string
+		policy   CheckPolicy
+		result   SvelteWatchCheckComplete
+		verdict  Severity
+		status   int
+		exitCode int
+	}{
+		{"clean", DefaultCheckPolicy, SvelteWatchCheckComplete{}, "", http.StatusOK, 0},
+		{"warnings pass by default", DefaultCheckPolicy, warnings, "", http.StatusOK, 0},
+		{"errors", DefaultCheckPolicy, errs, SeverityError, http.StatusInternalServerError, 1},
+		{"failure beats errors", DefaultCheckPolicy, failed, SeverityFailure, http.StatusBadGateway, 2},
+		{
+			"fail on warnings",
+			CheckPolicy{FailOn: []Severity{SeverityWarning}, ExitCodes: map[Severity]int{SeverityWarning: 3}},
+			errs, SeverityWarning, http.StatusInternalServerError, 3,
+		},
+		{
+			"failure as 500",
+			CheckPolicy{FailOn: []Severity{SeverityFailure}, FailureStatus: http.StatusInternalServerError},
+			failed, SeverityFailure, http.StatusInternalServerError, 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := tt.policy.Verdict(tt.result)
+			if verdict != tt.verdict {
+				t.Errorf("Verdict = %q, want %q", verdict, tt.verdict)
+			}
+			if got := tt.policy.StatusCode(verdict); got != tt.status {
+				t.Errorf("StatusCode = %d, want %d", got, tt.status)
+			}
+			if got := tt.policy.ExitCode(verdict); got != tt.exitCode {
+				t.Errorf("ExitCode = %d, want %d", got, tt.exitCode)
+			}
+		})
+	}
+}
+
+func TestParseExitCodes(t *testing.T) {
+	codes, err := ParseExitCodes([]string{"error=1", "Warning=3"})
+	if err != nil {
+		t.Fatalf("ParseExitCodes failed: %v", err)
+	}
+	if codes[SeverityError] != 1 || codes[SeverityWarning] != 3 || len(codes) != 2 {
+		t.Errorf("codes = %v, want error=1 warning=3", codes)
+	}
+
+	for _, bad := range []string{"error", "fatal=2", "error=0", "error=x", "failure=200"} {
+		if _, err := ParseExitCodes([]string{bad}); err == nil {
+			t.Errorf("ParseExitCodes(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestCheckPolicy_Validate(t *testing.T) {
+	if err := DefaultCheckPolicy.Validate(); err != nil {
+		t.Errorf("DefaultCheckPolicy.Validate() = %v", err)
+	}
+	p := DefaultCheckPolicy
+	p.FailureStatus = http.StatusServiceUnavailable
+	if err := p.Validate(); err == nil {
+		t.Error("Validate accepted failure status 503")
+	}
+}
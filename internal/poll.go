@@ -0,0 +1,259 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// =============================================================================
+// Polling Watcher
+// =============================================================================
+
+// Watch backends selectable with --watch-backend.
+const (
+	WatchBackendNotify   = "notify"   // fsnotify (inotify, FSEvents, ...)
+	WatchBackendPoll     = "poll"     // PollingFSWatcher
+	WatchBackendWatchman = "watchman" // WatchmanFSWatcher
+)
+
+// DefaultPollInterval is how often PollingFSWatcher scans by default.
+const DefaultPollInterval = time.Second
+
+// PollingFSWatcher implements FSWatcher by periodically scanning the watched
+// paths and comparing modification times and sizes. It works where fsnotify
+// events are not delivered, such as Docker for Mac bind mounts and network
+// filesystems, at the cost of latency and CPU.
+type PollingFSWatcher struct {
+	interval time.Duration
+	events   chan fsnotify.Event
+	errors   chan error
+	done     chan struct{}
+	stopped  chan struct{}
+	closing  sync.Once
+
+	mu       sync.Mutex
+	paths    []watchedPath
+	ignored  func(path string) bool
+	skipDirs []string
+	files    map[string]fileStamp // the last scan
+}
+
+// fileStamp is what a scan records about one path.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	dir     bool
+}
+
+// NewPollingFSWatcher starts a PollingFSWatcher scanning every interval.
+func NewPollingFSWatcher(interval time.Duration) *PollingFSWatcher {
+	p := &PollingFSWatcher{
+		interval: interval,
+		events:   make(chan fsnotify.Event),
+		errors:   make(chan error),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		skipDirs: DefaultSkipDirs,
+		files:    make(map[string]fileStamp),
+	}
+	go p.run()
+	return p
+}
+
+func (p *PollingFSWatcher) Events() <-chan fsnotify.Event {
+	return p.events
+}
+
+func (p *PollingFSWatcher) Errors() <-chan error {
+	return p.errors
+}
+
+// Add watches path: a file, a directory's entries, or with recursive every
+// directory and file below it. Existing files do not produce events.
+func (p *PollingFSWatcher) Add(path string, recursive bool) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	wp := watchedPath{path: path, recursive: recursive}
+	p.paths = append(p.paths, wp)
+	p.scanLocked(wp, p.files)
+	return nil
+}
+
+// Remove stops scanning a path added with Add. Files other added paths
+// still cover stay, and nothing removed produces events.
+func (p *PollingFSWatcher) Remove(path string, recursive bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i := slices.Index(p.paths, watchedPath{path: path, recursive: recursive})
+	if i < 0 {
+		return fmt.Errorf("%s is not watched", path)
+	}
+	p.paths = slices.Delete(p.paths, i, i+1)
+
+	for f := range p.files {
+		covered := slices.ContainsFunc(p.paths, func(wp watchedPath) bool {
+			return f == wp.path || filepath.Dir(f) == wp.path || (wp.recursive && isWithin(wp.path, f))
+		})
+		if !covered {
+			delete(p.files, f)
+		}
+	}
+	return nil
+}
+
+// Rescan is a no-op: every scan already picks up new directories.
+func (p *PollingFSWatcher) Rescan() error {
+	return nil
+}
+
+// SetIgnore skips paths for which ignored reports true, and everything below
+// ignored directories.
+func (p *PollingFSWatcher) SetIgnore(ignored func(path string) bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ignored = ignored
+}
+
+// SetSkipDirs replaces DefaultSkipDirs, as for RealFSWatcher.
+func (p *PollingFSWatcher) SetSkipDirs(names []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.skipDirs = names
+}
+
+// Close stops scanning and closes the Events channel.
+func (p *PollingFSWatcher) Close() error {
+	p.closing.Do(func() {
+		close(p.done)
+		<-p.stopped
+		close(p.events)
+	})
+	return nil
+}
+
+func (p *PollingFSWatcher) run() {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+		for _, event := range p.poll() {
+			select {
+			case p.events <- event:
+			case <-p.done:
+				return
+			}
+		}
+	}
+}
+
+// poll scans every watched path and returns the changes since the last scan,
+// ordered by path.
+func (p *PollingFSWatcher) poll() []fsnotify.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	files := make(map[string]fileStamp, len(p.files))
+	for _, wp := range p.paths {
+		p.scanLocked(wp, files)
+	}
+
+	var events []fsnotify.Event
+	for path, stamp := range files {
+		old, existed := p.files[path]
+		switch {
+		case !existed:
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+		case !stamp.dir && (!stamp.modTime.Equal(old.modTime) || stamp.size != old.size):
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Write})
+		}
+	}
+	for path := range p.files {
+		if _, exists := files[path]; !exists {
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Remove})
+		}
+	}
+	p.files = files
+
+	slices.SortFunc(events, func(a, b fsnotify.Event) int { return strings.Compare(a.Name, b.Name) })
+	return events
+}
+
+// scanLocked records wp's files in files. p.mu must be held.
+func (p *PollingFSWatcher) scanLocked(wp watchedPath, files map[string]fileStamp) {
+	info, err := os.Stat(wp.path)
+	if err != nil {
+		return
+	}
+	files[wp.path] = stampOf(info)
+	if !info.IsDir() {
+		return
+	}
+
+	if !wp.recursive {
+		entries, err := os.ReadDir(wp.path)
+		if err != nil {
+			p.reportLocked(err)
+			return
+		}
+		for _, e := range entries {
+			path := filepath.Join(wp.path, e.Name())
+			if p.ignored != nil && p.ignored(path) {
+				continue
+			}
+			if info, err := e.Info(); err == nil {
+				files[path] = stampOf(info)
+			}
+		}
+		return
+	}
+
+	_ = filepath.WalkDir(wp.path, func(path string, d os.DirEntry, err error) error {
+		if err != nil || path == wp.path {
+			return nil
+		}
+		if p.ignored != nil && p.ignored(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() && skippedDir(p.skipDirs, path) {
+			return filepath.SkipDir
+		}
+		if info, err := d.Info(); err == nil {
+			files[path] = stampOf(info)
+		}
+		return nil
+	})
+}
+
+// reportLocked sends err to Errors without blocking a scan.
+func (p *PollingFSWatcher) reportLocked(err error) {
+	select {
+	case p.errors <- fmt.Errorf("poll: %w", err):
+	default:
+	}
+}
+
+func stampOf(info os.FileInfo) fileStamp {
+	return fileStamp{modTime: info.ModTime(), size: info.Size(), dir: info.IsDir()}
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestPollingFSWatcher_Poll_ReportsChanges(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"src/routes/+page.svelte":   "<h1>hi</h1>",
+		"src/routes/+layout.svelte": "<slot />",
+	})
+	page := filepath.Join(root, "src", "routes", "+page.svelte")
+	layout := filepath.Join(root, "src", "routes", "+layout.svelte")
+
+	// An hour-long interval keeps the background scan out of the way.
+	p := NewPollingFSWatcher(time.Hour)
+	defer func() { _ = p.Close() }()
+	if err := p.Add(root, true); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	if events := p.poll(); len(events) != 0 {
+		t.Fatalf("poll() after Add = %v, want no events", events)
+	}
+
+	writeFiles(t, root, map[string]string{
+		"src/lib/util.ts":         "export {}",
+		"src/routes/+page.svelte": "<h1>hello</h1>",
+	})
+	added := filepath.Join(root, "src", "lib", "util.ts")
+	if err := os.Remove(layout); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+
+	want := []fsnotify.Event{
+		{Name: filepath.Join(root, "src", "lib"), Op: fsnotify.Create},
+		{Name: added, Op: fsnotify.Create},
+		{Name: layout, Op: fsnotify.Remove},
+		{Name: page, Op: fsnotify.Write},
+	}
+	if got := p.poll(); !slices.Equal(got, want) {
+		t.Errorf("poll() = %v, want %v", got, want)
+	}
+	if events := p.poll(); len(events) != 0 {
+		t.Errorf("second poll() = %v, want no events", events)
+	}
+}
+
+func TestPollingFSWatcher_NonRecursive(t *testing.T) {
+	root := t.TempDir()
+
+	p := NewPollingFSWatcher(time.Hour)
+	defer func() { _ = p.Close() }()
+	if err := p.Add(root, false); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	writeFiles(t, root, map[string]string{"package.json": "{}", "src/app.html": ""})
+
+	want := []fsnotify.Event{
+		{Name: filepath.Join(root, "package.json"), Op: fsnotify.Create},
+		{Name: filepath.Join(root, "src"), Op: fsnotify.Create},
+	}
+	if got := p.poll(); !slices.Equal(got, want) {
+		t.Errorf("poll() = %v, want %v", got, want)
+	}
+}
+
+func TestPollingFSWatcher_SkipsDirectories(t *testing.T) {
+	root := t.TempDir()
+
+	p := NewPollingFSWatcher(time.Hour)
+	defer func() { _ = p.Close() }()
+	w := NewWatcher(WatcherConfig{WorkspacePath: root, Ignore: []string{"coverage/**"}}, WatcherCallbacks{}, p, nil)
+	p.SetIgnore(w.ignored)
+	if err := p.Add(root, true); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	writeFiles(t, root, map[string]string{
+		"node_modules/pkg/index.js": "",
+		"coverage/lcov.info":        "",
+		"src/app.d.ts":              "",
+	})
+
+	want := []fsnotify.Event{
+		{Name: filepath.Join(root, "src"), Op: fsnotify.Create},
+		{Name: filepath.Join(root, "src", "app.d.ts"), Op: fsnotify.Create},
+	}
+	if got := p.poll(); !slices.Equal(got, want) {
+		t.Errorf("poll() = %v, want %v", got, want)
+	}
+}
+
+func TestPollingFSWatcher_DeliversEventsAndCloses(t *testing.T) {
+	root := t.TempDir()
+
+	p := NewPollingFSWatcher(10 * time.Millisecond)
+	if err := p.Add(root, true); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	writeFiles(t, root, map[string]string{"+page.svelte": ""})
+	path := filepath.Join(root, "+page.svelte")
+
+	select {
+	case event := <-p.Events():
+		if event.Name != path || !event.Has(fsnotify.Create) {
+			t.Errorf("event = %v, want CREATE %s", event, path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event within 5s")
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	for range p.Events() {
+		// Drain anything sent before Close; the channel must be closed.
+	}
+}
+
+func TestPollingFSWatcher_Add_MissingPath(t *testing.T) {
+	p := NewPollingFSWatcher(time.Hour)
+	defer func() { _ = p.Close() }()
+
+	if err := p.Add(filepath.Join(t.TempDir(), "missing"), true); err == nil {
+		t.Error("Add of a missing path succeeded, want an error")
+	}
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// =============================================================================
+// Projects
+// =============================================================================
+
+// ProjectConfig describes one svelte-check target within a workspace, as set
+// in the "projects" list of ConfigFileName.
+type ProjectConfig struct {
+	Name     string `json:"name"`
+	Tsconfig string `json:"tsconfig,omitempty"` // relative to Dir
+	Dir      string `json:"dir,omitempty"`      // relative to the workspace; default "."
+}
+
+// Project is a named Checker served by the daemon. Diagnostics from a project
+// in a subdirectory are reported relative to the workspace in merged results.
+type Project struct {
+	Name   string
+	Dir    string // relative to the workspace; "" or "." for the root
+	Runner Checker
+}
+
+// ProjectStatus is the health of one project in GET /status.
+type ProjectStatus struct {
+	Name   string       `json:"name"`
+	Dir    string       `json:"dir,omitempty"`
+	Runner RunnerStatus `json:"runner"`
+}
+
+// ErrUnknownProject is returned when a request names a project that is not configured.
+var ErrUnknownProject = errors.New("unknown project")
+
+// ValidateProjects checks that project names are present and unique.
+func ValidateProjects(projects []ProjectConfig) error {
+	seen := make(map[string]bool)
+	for i, p := range projects {
+		if p.Name == "" {
+			return fmt.Errorf("project %d has no name", i+1)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate project name %q", p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
+
+// ProjectRunnerConfigs derives one RunnerConfig per project from base, which
+// carries the workspace-wide settings (package manager, command, args, ...).
+func ProjectRunnerConfigs(base RunnerConfig, projects []ProjectConfig) []RunnerConfig {
+	configs := make([]RunnerConfig, len(projects))
+	for i, p := range projects {
+		c := base
+		c.WorkspacePath = filepath.Join(base.WorkspacePath, p.Dir)
+		c.TsconfigPath = p.Tsconfig
+		configs[i] = c
+	}
+	return configs
+}
+
+// findProject returns the project with the given name.
+func findProject(projects []Project, name string) (Project, error) {
+	for _, p := range projects {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Project{}, fmt.Errorf("%w %q", ErrUnknownProject, name)
+}
+
+// qualify returns a copy of result with filenames made relative to the
+// workspace and each diagnostic tagged with the project name.
+func (p Project) qualify(result SvelteWatchCheckComplete) SvelteWatchCheckComplete {
+	dir := filepath.ToSlash(filepath.Clean(p.Dir))
+	return mapDiagnostics(result, func(d *Diagnostic) {
+		d.Project = p.Name
+		if dir != "." && !path.IsAbs(d.Filename) {
+			d.Filename = path.Join(dir, d.Filename)
+		}
+	})
+}
+
+// MergeResults combines check results from several projects into one. Counts
+// are summed, diagnostics concatenated in order, and the timestamp is that of
+// the most recent result. The merged result is stale if any input is, with
+// their distinct reasons joined.
+func MergeResults(results []SvelteWatchCheckComplete) SvelteWatchCheckComplete {
+	var merged SvelteWatchCheckComplete
+	var reasons []string
+	for _, r := range results {
+		merged.Timestamp = max(merged.Timestamp, r.Timestamp)
+		merged.Diagnostics = append(merged.Diagnostics, r.Diagnostics...)
+		merged.FileCount += r.FileCount
+		merged.ErrorCount += r.ErrorCount
+		merged.WarningCount += r.WarningCount
+		merged.FilesWithProblems += r.FilesWithProblems
+		merged.Failures = append(merged.Failures, r.Failures...)
+		merged.Introduced = append(merged.Introduced, r.Introduced...)
+		merged.Resolved = append(merged.Resolved, r.Resolved...)
+		merged.Baselined += r.Baselined
+		merged.Suppressed += r.Suppressed
+		if r.Stale {
+			merged.Stale = true
+			if r.StaleReason != "" && !slices.Contains(reasons, r.StaleReason) {
+				reasons = append(reasons, r.StaleReason)
+			}
+		}
+	}
+	merged.StaleReason = strings.Join(reasons, "; ")
+	return merged
+}
@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestMergeResults tests that counts are summed and diagnostics concatenated.
+func TestMergeResults(t *testing.T) {
+	merged := MergeResults([]SvelteWatchCheckComplete{
+		{Timestamp: 10, FileCount: 100, ErrorCount: 1, WarningCount: 2, FilesWithProblems: 2,
+			Diagnostics: []Diagnostic{{Filename: "src/a.ts"}, {Filename: "src/b.ts"}}},
+		{Timestamp: 20, FileCount: 5, ErrorCount: 3, FilesWithProblems: 1,
+			Diagnostics: []Diagnostic{{Filename: "vite.config.ts"}}},
+	})
+
+	if merged.Timestamp != 20 {
+		t.Errorf("Timestamp = %d, want 20", merged.Timestamp)
+	}
+	if merged.FileCount != 105 || merged.ErrorCount != 4 || merged.WarningCount != 2 || merged.FilesWithProblems != 3 {
+		t.Errorf("counts = %d files, %d errors, %d warnings, %d with problems; want 105, 4, 2, 3",
+			merged.FileCount, merged.ErrorCount, merged.WarningCount, merged.FilesWithProblems)
+	}
+	if len(merged.Diagnostics) != 3 || merged.Diagnostics[2].Filename != "vite.config.ts" {
+		t.Errorf("Diagnostics = %+v, want all three in order", merged.Diagnostics)
+	}
+}
+
+// TestMergeResults_Stale tests that staleness and distinct reasons carry over.
+func TestMergeResults_Stale(t *testing.T) {
+	merged := MergeResults([]SvelteWatchCheckComplete{
+		{Stale: true, StaleReason: "svelte-check is checking"},
+		{},
+		{Stale: true, StaleReason: "svelte-check is checking"},
+		{Stale: true, StaleReason: "tsc is degraded"},
+	})
+	if !merged.Stale || merged.StaleReason != "svelte-check is checking; tsc is degraded" {
+		t.Errorf("Stale = %v (%q), want stale with both reasons", merged.Stale, merged.StaleReason)
+	}
+
+	if merged := MergeResults([]SvelteWatchCheckComplete{{}, {}}); merged.Stale {
+		t.Errorf("Stale = true for fresh results")
+	}
+}
+
+// TestProject_Qualify tests that diagnostics are tagged and made workspace-relative.
+func TestProject_Qualify(t *testing.T) {
+	result := SvelteWatchCheckComplete{
+		Diagnostics: []Diagnostic{{Filename: "src/a.ts"}, {Filename: "/abs/b.ts"}},
+	}
+
+	got := Project{Name: "web", Dir: "apps/web"}.qualify(result)
+	if got.Diagnostics[0].Filename != "apps/web/src/a.ts" {
+		t.Errorf("Filename = %q, want %q", got.Diagnostics[0].Filename, "apps/web/src/a.ts")
+	}
+	if got.Diagnostics[1].Filename != "/abs/b.ts" {
+		t.Errorf("absolute Filename = %q, want it unchanged", got.Diagnostics[1].Filename)
+	}
+	if got.Diagnostics[0].Project != "web" {
+		t.Errorf("Project = %q, want %q", got.Diagnostics[0].Project, "web")
+	}
+	if result.Diagnostics[0].Filename != "src/a.ts" || result.Diagnostics[0].Project != "" {
+		t.Error("qualify modified the runner's result")
+	}
+
+	root := Project{Name: "node"}.qualify(result)
+	if root.Diagnostics[0].Filename != "src/a.ts" {
+		t.Errorf("root project Filename = %q, want it unchanged", root.Diagnostics[0].Filename)
+	}
+}
+
+// TestValidateProjects tests rejection of missing and duplicate names.
+func TestValidateProjects(t *testing.T) {
+	if err := ValidateProjects([]ProjectConfig{{Name: "app"}, {Name: "node"}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateProjects([]ProjectConfig{{Name: "app"}, {Tsconfig: "x.json"}}); err == nil {
+		t.Error("expected error for missing name")
+	}
+	if err := ValidateProjects([]ProjectConfig{{Name: "app"}, {Name: "app"}}); err == nil {
+		t.Error("expected error for duplicate name")
+	}
+}
+
+// TestProjectRunnerConfigs tests that each project gets its own directory and
+// tsconfig while sharing the workspace-wide settings.
+func TestProjectRunnerConfigs(t *testing.T) {
+	base := RunnerConfig{
+		WorkspacePath:  "/repo",
+		TsconfigPath:   "ignored.json",
+		PackageManager: PackageManagerPnpm,
+		ExtraArgs:      []string{"--fail-on-warnings"},
+	}
+	configs := ProjectRunnerConfigs(base, []ProjectConfig{
+		{Name: "app", Tsconfig: "tsconfig.json"},
+		{Name: "docs", Tsconfig: "tsconfig.app.json", Dir: "docs"},
+	})
+
+	if len(configs) != 2 {
+		t.Fatalf("got %d configs, want 2", len(configs))
+	}
+	if configs[0].WorkspacePath != "/repo" || configs[0].TsconfigPath != "tsconfig.json" {
+		t.Errorf("configs[0] = %q, %q", configs[0].WorkspacePath, configs[0].TsconfigPath)
+	}
+	if configs[1].WorkspacePath != "/repo/docs" || configs[1].TsconfigPath != "tsconfig.app.json" {
+		t.Errorf("configs[1] = %q, %q", configs[1].WorkspacePath, configs[1].TsconfigPath)
+	}
+	for _, c := range configs {
+		if c.PackageManager != PackageManagerPnpm || len(c.ExtraArgs) != 1 {
+			t.Errorf("shared settings not copied: %+v", c)
+		}
+	}
+}
+
+// TestFindProject tests lookup by name.
+func TestFindProject(t *testing.T) {
+	projects := []Project{{Name: "app"}, {Name: "node"}}
+	if p, err := findProject(projects, "node"); err != nil || p.Name != "node" {
+		t.Errorf("findProject(node) = %+v, %v", p, err)
+	}
+	if _, err := findProject(projects, "nope"); !errors.Is(err, ErrUnknownProject) {
+		t.Errorf("findProject(nope) error = %v, want ErrUnknownProject", err)
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// =============================================================================
+// Remote Access
+// =============================================================================
+
+// Environment variables that point the CLI's client commands at a daemon
+// other than the workspace's local one, e.g. inside a devcontainer.
+const (
+	EnvServerAddr   = "SVELTE_CHECK_SERVER_ADDR"   // host:port of a daemon started with --listen
+	EnvServerToken  = "SVELTE_CHECK_SERVER_TOKEN"  // its token; also read by start --listen
+	EnvServerSocket = "SVELTE_CHECK_SERVER_SOCKET" // a socket forwarded from elsewhere, e.g. by ssh -L
+)
+
+// errUnauthorized is returned by Probe when a TCP server rejects the token.
+var errUnauthorized = errors.New("server rejected the token")
+
+// ListenTCP makes Start also serve on addr, e.g. "0.0.0.0:7420" in a
+// container, for clients that cannot reach the socket. TCP requests must
+// carry token as "Authorization: Bearer <token>"; socket requests are
+// trusted as before. Call it before Start.
+func (s *Server) ListenTCP(addr, token string) error {
+	if token == "" {
+		return fmt.Errorf("listening on %s requires a token (set %s)", addr, EnvServerToken)
+	}
+	s.tcpAddr, s.token = addr, token
+	return nil
+}
+
+// TCPAddr returns the address served over TCP, or "" if none.
+func (s *Server) TCPAddr() string {
+	return s.tcpAddr
+}
+
+// authorize rejects requests that arrived over TCP without the server's
+// token.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && addr.Network() == "tcp" {
+			token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WithTCP connects to a daemon started with --listen at addr instead of the
+// workspace's socket, authenticating with token.
+func WithTCP(addr, token string) ClientOption {
+	return func(o *clientOptions) { o.tcpAddr, o.token = addr, token }
+}
+
+// WithSocket connects to the socket at path instead of the workspace's,
+// e.g. one forwarded from a remote machine with
+// ssh -L /tmp/remote.sock:<remote socket> host.
+func WithSocket(path string) ClientOption {
+	return func(o *clientOptions) { o.socketPath = path }
+}
+
+// clientOptionsFromEnv returns the options selected by EnvServerAddr,
+// EnvServerToken, and EnvServerSocket.
+func clientOptionsFromEnv() []ClientOption {
+	if addr := os.Getenv(EnvServerAddr); addr != "" {
+		return []ClientOption{WithTCP(addr, os.Getenv(EnvServerToken))}
+	}
+	if path := os.Getenv(EnvServerSocket); path != "" {
+		return []ClientOption{WithSocket(path)}
+	}
+	return nil
+}
+
+// tokenTransport adds a bearer token to every request.
+type tokenTransport struct {
+	base  http.RoundTripper
+	token string
+}
+
+func (t tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
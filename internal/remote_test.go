@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestServer_ListenTCP tests that TCP clients need the token while socket
+// clients do not.
+func TestServer_ListenTCP(t *testing.T) {
+	socketPath := testSocketPath(t)
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(checkOutput("1770255834000", 0), "")))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	s := NewServer(socketPath, r)
+	if err := s.ListenTCP("127.0.0.1:0", ""); err == nil {
+		t.Error("ListenTCP without a token succeeded")
+	}
+	if err := s.ListenTCP("127.0.0.1:0", "secret"); err != nil {
+		t.Fatalf("ListenTCP failed: %v", err)
+	}
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() { _ = s.Stop(context.Background()) }()
+	ctx := context.Background()
+	workspace := t.TempDir()
+
+	c, err := NewClient(workspace, WithTCP(s.TCPAddr(), "secret"))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if err := c.Probe(ctx); err != nil {
+		t.Errorf("Probe over TCP = %v, want nil", err)
+	}
+	if resp, err := c.CheckWith(ctx, CheckOptions{}); err != nil || resp.ExitCode != 0 {
+		t.Errorf("CheckWith over TCP = %+v, %v; want a passing result", resp, err)
+	}
+
+	c, err = NewClient(workspace, WithTCP(s.TCPAddr(), "wrong"))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if err := c.Probe(ctx); !errors.Is(err, errUnauthorized) {
+		t.Errorf("Probe with the wrong token = %v, want errUnauthorized", err)
+	}
+	if _, err := c.CheckWith(ctx, CheckOptions{}); err == nil {
+		t.Error("CheckWith with the wrong token succeeded")
+	}
+
+	// A forwarded socket needs no token.
+	c, err = NewClient(workspace, WithSocket(socketPath))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if resp, err := c.CheckWith(ctx, CheckOptions{}); err != nil || resp.ExitCode != 0 {
+		t.Errorf("CheckWith over the socket = %+v, %v; want a passing result", resp, err)
+	}
+}
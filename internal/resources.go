@@ -0,0 +1,282 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// Resource Monitoring
+// =============================================================================
+
+// ResourceLimits configures periodic sampling of the svelte-check process tree.
+type ResourceLimits struct {
+	// Interval between samples. Zero disables monitoring.
+	Interval time.Duration
+
+	// MaxRSSBytes restarts svelte-check when the resident memory of its process
+	// tree exceeds this many bytes. Zero means no ceiling.
+	MaxRSSBytes int64
+}
+
+// ResourceUsage is one sample of the svelte-check process tree. svelte-check
+// runs under a package manager and spawns tsserver-like workers, so the
+// figures cover the child and all of its descendants.
+type ResourceUsage struct {
+	PID        int       `json:"pid"`
+	Processes  int       `json:"processes"`
+	RSSBytes   int64     `json:"rssBytes"`
+	CPUPercent float64   `json:"cpuPercent"` // since the previous sample; 100 is one full core
+	SampledAt  time.Time `json:"sampledAt"`
+}
+
+// processInfo is one row of the system process table.
+type processInfo struct {
+	pid      int
+	ppid     int
+	rssBytes int64
+	cpuTime  time.Duration // user + system
+}
+
+// processLister returns a snapshot of the system process table.
+type processLister func() ([]processInfo, error)
+
+// listProcesses reads the process table from /proc where available and falls
+// back to ps(1) elsewhere (e.g. macOS).
+func listProcesses() ([]processInfo, error) {
+	if _, err := os.Stat("/proc/self/stat"); err == nil {
+		return listProcessesProc("/proc")
+	}
+	return listProcessesPS()
+}
+
+// clockTicks is the kernel's USER_HZ, the unit of utime/stime in /proc/<pid>/stat.
+// It is 100 on every mainstream Linux architecture.
+const clockTicks = 100
+
+func listProcessesProc(procRoot string) ([]processInfo, error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := int64(os.Getpagesize())
+	var procs []processInfo
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(procRoot, entry.Name(), "stat"))
+		if err != nil {
+			continue // process exited while we were scanning
+		}
+		info, err := parseProcStat(string(data), pageSize)
+		if err != nil {
+			continue
+		}
+		procs = append(procs, info)
+	}
+	return procs, nil
+}
+
+// parseProcStat parses the content of /proc/<pid>/stat.
+func parseProcStat(content string, pageSize int64) (processInfo, error) {
+	// The command name is in parentheses and may itself contain spaces or
+	// parentheses, so split around the last ')'.
+	open := strings.IndexByte(content, '(')
+	end := strings.LastIndexByte(content, ')')
+	if open < 0 || end < open {
+		return processInfo{}, fmt.Errorf("malformed stat line")
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(content[:open]))
+	if err != nil {
+		return processInfo{}, err
+	}
+
+	// Fields after the name, starting at field 3 (state).
+	fields := strings.Fields(content[end+1:])
+	if len(fields) < 22 {
+		return processInfo{}, fmt.Errorf("short stat line for pid %d", pid)
+	}
+	ppid, err1 := strconv.Atoi(fields[1])
+	utime, err2 := strconv.ParseInt(fields[11], 10, 64)
+	stime, err3 := strconv.ParseInt(fields[12], 10, 64)
+	rss, err4 := strconv.ParseInt(fields[21], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return processInfo{}, fmt.Errorf("malformed stat line for pid %d", pid)
+	}
+
+	return processInfo{
+		pid:      pid,
+		ppid:     ppid,
+		rssBytes: rss * pageSize,
+		cpuTime:  time.Duration(utime+stime) * time.Second / clockTicks,
+	}, nil
+}
+
+func listProcessesPS() ([]processInfo, error) {
+	out, err := exec.Command("ps", "-A", "-o", "pid=,ppid=,rss=,time=").Output()
+	if err != nil {
+		return nil, fmt.Errorf("ps: %w", err)
+	}
+	return parsePSOutput(out)
+}
+
+// parsePSOutput parses `ps -o pid=,ppid=,rss=,time=` output, where rss is in
+// kilobytes and time is [[dd-]hh:]mm:ss[.ff].
+func parsePSOutput(out []byte) ([]processInfo, error) {
+	var procs []processInfo
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		rss, err3 := strconv.ParseInt(fields[2], 10, 64)
+		cpu, err4 := parsePSTime(fields[3])
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			continue
+		}
+		procs = append(procs, processInfo{pid: pid, ppid: ppid, rssBytes: rss * 1024, cpuTime: cpu})
+	}
+	return procs, scanner.Err()
+}
+
+// parsePSTime parses a ps cumulative CPU time such as "1-02:03:04", "02:03:04"
+// or "3:04.56".
+func parsePSTime(s string) (time.Duration, error) {
+	var days int
+	if d, rest, ok := strings.Cut(s, "-"); ok {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return 0, err
+		}
+		days, s = n, rest
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid cpu time %q", s)
+	}
+
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, err
+	}
+	total := time.Duration(seconds * float64(time.Second))
+	for i, unit := range []time.Duration{time.Minute, time.Hour} {
+		idx := len(parts) - 2 - i
+		if idx < 0 {
+			break
+		}
+		n, err := strconv.Atoi(parts[idx])
+		if err != nil {
+			return 0, err
+		}
+		total += time.Duration(n) * unit
+	}
+	return total + time.Duration(days)*24*time.Hour, nil
+}
+
+// resourceSampler samples one process tree, remembering the previous sample
+// so CPU usage can be reported as a rate.
+type resourceSampler struct {
+	list processLister
+
+	lastCPU time.Duration
+	lastAt  time.Time
+}
+
+// sample measures the process tree rooted at pid.
+func (s *resourceSampler) sample(pid int) (ResourceUsage, error) {
+	procs, err := s.list()
+	if err != nil {
+		return ResourceUsage{}, err
+	}
+	now := time.Now()
+
+	tree := processTree(procs, pid)
+	if tree == nil {
+		return ResourceUsage{}, fmt.Errorf("process %d not found", pid)
+	}
+
+	usage := ResourceUsage{PID: pid, SampledAt: now}
+	var cpu time.Duration
+	for _, p := range tree {
+		usage.Processes++
+		usage.RSSBytes += p.rssBytes
+		cpu += p.cpuTime
+	}
+
+	// Descendants that exit take their CPU time with them; clamp rather than
+	// report a negative rate.
+	if !s.lastAt.IsZero() && cpu >= s.lastCPU {
+		if elapsed := now.Sub(s.lastAt); elapsed > 0 {
+			usage.CPUPercent = 100 * float64(cpu-s.lastCPU) / float64(elapsed)
+		}
+	}
+	s.lastCPU, s.lastAt = cpu, now
+	return usage, nil
+}
+
+// processTree returns the process pid and its descendants among procs,
+// parents before their children, or nil if pid is not among them.
+func processTree(procs []processInfo, pid int) []processInfo {
+	children := make(map[int][]processInfo)
+	var root *processInfo
+	for i, p := range procs {
+		children[p.ppid] = append(children[p.ppid], p)
+		if p.pid == pid {
+			root = &procs[i]
+		}
+	}
+	if root == nil {
+		return nil
+	}
+
+	tree := []processInfo{*root}
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i].pid]...)
+	}
+	return tree
+}
+
+// ParseByteSize parses a memory size such as "512MB", "4G" or "1073741824".
+// Units are binary: KB/K, MB/M, GB/G and TB/T are powers of 1024.
+func ParseByteSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	str = strings.TrimSuffix(strings.TrimSuffix(str, "IB"), "B")
+
+	mult := int64(1)
+	if str != "" {
+		switch str[len(str)-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		case 'T':
+			mult = 1 << 40
+		}
+		if mult > 1 {
+			str = str[:len(str)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
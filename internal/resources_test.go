@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// TestParseProcStat tests parsing /proc/<pid>/stat, including a command name
+// containing spaces and parentheses.
+func TestParseProcStat(t *testing.T) {
+	content := "4242 (node (svelte) check) S 4200 4242 4200 0 -1 4194304 1000 0 0 0 " +
+		"250 50 0 0 20 0 11 0 123456 1234567890 2048 18446744073709551615\n"
+
+	info, err := parseProcStat(content, 4096)
+	if err != nil {
+		t.Fatalf("parseProcStat: %v", err)
+	}
+	if info.pid != 4242 || info.ppid != 4200 {
+		t.Errorf("pid/ppid = %d/%d, want 4242/4200", info.pid, info.ppid)
+	}
+	if info.rssBytes != 2048*4096 {
+		t.Errorf("rssBytes = %d, want %d", info.rssBytes, 2048*4096)
+	}
+	if info.cpuTime != 3*time.Second {
+		t.Errorf("cpuTime = %v, want 3s", info.cpuTime)
+	}
+
+	if _, err := parseProcStat("garbage", 4096); err == nil {
+		t.Error("expected error for malformed stat line")
+	}
+}
+
+// TestParsePSOutput tests parsing ps output in the format used on macOS.
+func TestParsePSOutput(t *testing.T) {
+	out := []byte(`    1     0   1024   1-00:00:01
+  500     1  20480      0:02.50
+  501   500 102400   01:00:00
+bogus line
+`)
+	procs, err := parsePSOutput(out)
+	if err != nil {
+		t.Fatalf("parsePSOutput: %v", err)
+	}
+	if len(procs) != 3 {
+		t.Fatalf("got %d processes, want 3", len(procs))
+	}
+
+	want := []processInfo{
+		{pid: 1, ppid: 0, rssBytes: 1024 * 1024, cpuTime: 24*time.Hour + time.Second},
+		{pid: 500, ppid: 1, rssBytes: 20480 * 1024, cpuTime: 2500 * time.Millisecond},
+		{pid: 501, ppid: 500, rssBytes: 102400 * 1024, cpuTime: time.Hour},
+	}
+	for i := range want {
+		if procs[i] != want[i] {
+			t.Errorf("procs[%d] = %+v, want %+v", i, procs[i], want[i])
+		}
+	}
+}
+
+// TestResourceSampler_SumsProcessTree tests that descendants are included,
+// unrelated processes are not, and CPU is reported as a rate between samples.
+func TestResourceSampler_SumsProcessTree(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		procs := []processInfo{
+			{pid: 1, ppid: 0, rssBytes: 999, cpuTime: time.Hour},
+			{pid: 10, ppid: 1, rssBytes: 100, cpuTime: time.Second},
+			{pid: 11, ppid: 10, rssBytes: 200, cpuTime: time.Second},
+			{pid: 12, ppid: 11, rssBytes: 300, cpuTime: time.Second},
+			{pid: 20, ppid: 1, rssBytes: 400, cpuTime: time.Second},
+		}
+		s := &resourceSampler{list: func() ([]processInfo, error) { return procs, nil }}
+
+		usage, err := s.sample(10)
+		if err != nil {
+			t.Fatalf("sample: %v", err)
+		}
+		if usage.Processes != 3 || usage.RSSBytes != 600 {
+			t.Errorf("Processes = %d, RSSBytes = %d; want 3, 600", usage.Processes, usage.RSSBytes)
+		}
+		if usage.CPUPercent != 0 {
+			t.Errorf("first sample CPUPercent = %v, want 0", usage.CPUPercent)
+		}
+
+		// One and a half cores busy for 10s.
+		time.Sleep(10 * time.Second)
+		procs[2].cpuTime += 15 * time.Second
+
+		usage, err = s.sample(10)
+		if err != nil {
+			t.Fatalf("sample: %v", err)
+		}
+		if usage.CPUPercent != 150 {
+			t.Errorf("CPUPercent = %v, want 150", usage.CPUPercent)
+		}
+
+		if _, err := s.sample(99); err == nil {
+			t.Error("expected error for missing process")
+		}
+	})
+}
+
+// TestParseByteSize tests size parsing with binary units.
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"1024", 1024},
+		{"512B", 512},
+		{"64k", 64 << 10},
+		{"512MB", 512 << 20},
+		{"1.5G", 3 << 29},
+		{"4GiB", 4 << 30},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.in)
+		if err != nil {
+			t.Errorf("ParseByteSize(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "GB", "-1G", "lots"} {
+		if _, err := ParseByteSize(bad); err == nil {
+			t.Errorf("ParseByteSize(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package internal
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// Event Rules
+// =============================================================================
+
+// Built-in actions an EventRule can name in Then.
+const (
+	RuleActionSync    = "sync"    // run svelte-kit sync
+	RuleActionRestart = "restart" // restart the checkers, subject to the cooldown
+)
+
+// EventRule runs an action when watched files change, so that tools such
+// as GraphQL codegen or paraglide can run alongside the checkers. A batch of
+// events matching the rule triggers it once, after Debounce.
+type EventRule struct {
+	Name     string
+	Globs    []string      // relative to the workspace
+	Ops      fsnotify.Op   // 0 means Write, Create, Remove, or Rename
+	Debounce time.Duration // 0 means DefaultDebounce
+
+	// Run, if set, is called first. If it fails, Then is skipped.
+	Run func() error
+
+	// Then names built-in actions run afterwards, in order.
+	Then []string
+}
+
+// ops returns the operations that trigger the rule.
+func (r EventRule) ops() fsnotify.Op {
+	if r.Ops == 0 {
+		return fsnotify.Write | fsnotify.Create | fsnotify.Remove | fsnotify.Rename
+	}
+	return r.Ops
+}
+
+// ValidateEventRules checks that rules have distinct names, valid globs, and
+// something to do.
+func ValidateEventRules(rules []EventRule) error {
+	seen := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		switch {
+		case r.Name == "":
+			return errors.New("rule without a name")
+		case seen[r.Name]:
+			return fmt.Errorf("duplicate rule %q", r.Name)
+		case len(r.Globs) == 0:
+			return fmt.Errorf("rule %q: no globs", r.Name)
+		case r.Run == nil && len(r.Then) == 0:
+			return fmt.Errorf("rule %q: nothing to run", r.Name)
+		}
+		seen[r.Name] = true
+		if err := ValidateGlobs(r.Globs); err != nil {
+			return fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		for _, action := range r.Then {
+			if action != RuleActionSync && action != RuleActionRestart {
+				return fmt.Errorf("rule %q: unknown action %q (want %q or %q)", r.Name, action, RuleActionSync, RuleActionRestart)
+			}
+		}
+	}
+	return nil
+}
+
+// watchRule is an EventRule registered with a Watcher.
+type watchRule struct {
+	EventRule
+	debouncer *Debouncer
+	mu        sync.Mutex // serializes runs
+}
+
+// addRules registers the configured rules, each with its own debouncer.
+func (w *Watcher) addRules() {
+	for _, rule := range w.config.Rules {
+		r := &watchRule{EventRule: rule}
+		r.debouncer = NewDebouncer(cmp.Or(rule.Debounce, DefaultDebounce), func() { w.runRule(r) })
+		w.rules = append(w.rules, r)
+	}
+}
+
+// runRule runs r's command, then its built-in actions.
+func (w *Watcher) runRule(r *watchRule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Run != nil {
+		w.logger.Info("running rule", "rule", r.Name)
+		if err := r.Run(); err != nil {
+			w.logger.Error("rule failed", "rule", r.Name, "error", err)
+			w.config.Audit.Record("rule", map[string]any{"name": r.Name, "ok": false, "error": err.Error()})
+			return
+		}
+	}
+	w.config.Audit.Record("rule", map[string]any{"name": r.Name, "ok": true})
+	for _, action := range r.Then {
+		switch action {
+		case RuleActionSync:
+			if w.callbacks.OnSvelteSync != nil {
+				w.callbacks.OnSvelteSync()
+			}
+		case RuleActionRestart:
+			w.stats.restartTriggered(TriggerFS)
+			w.restartThrottle.Trigger()
+		}
+	}
+}
+
+// matchRules adds event to the triggers of the rules it matches.
+func (w *Watcher) matchRules(event fsnotify.Event, triggers []eventTrigger) {
+	for i, r := range w.rules {
+		if event.Has(r.ops()) && w.matchesGlob(r.Globs, event.Name) {
+			triggers[i].add(event.Name)
+		}
+	}
+}
+
+// triggerRules triggers the rules with matching events.
+func (w *Watcher) triggerRules(triggers []eventTrigger) {
+	for i, r := range w.rules {
+		if triggers[i].n > 0 {
+			w.logger.Info("triggering rule", "rule", r.Name, "trigger", triggers[i].String())
+			w.stats.ruleTriggered(r.Name)
+			r.debouncer.Trigger()
+		}
+	}
+}
+
+// =============================================================================
+// Rule Config
+// =============================================================================
+
+// RuleConfig is an event rule in the config file, e.g.
+// {"name": "codegen", "globs": ["schema.graphql"], "run": "npm run codegen"}.
+type RuleConfig struct {
+	Name  string   `json:"name"`
+	Globs []string `json:"globs"`
+
+	// Ops limits the rule to "create", "write", "remove", "rename", or
+	// "chmod" events. Empty means all but chmod.
+	Ops []string `json:"ops,omitempty"`
+
+	// Run is a shell command run in the workspace, with the checkers'
+	// environment.
+	Run string `json:"run,omitempty"`
+
+	// Then lists built-in actions run afterwards: "sync", "restart".
+	Then []string `json:"then,omitempty"`
+
+	// Debounce is how long after the last matching event to run, as a Go
+	// duration ("1s"). Defaults to 250ms.
+	Debounce string `json:"debounce,omitempty"`
+}
+
+// ruleOps maps RuleConfig.Ops names to fsnotify operations.
+var ruleOps = map[string]fsnotify.Op{
+	"create": fsnotify.Create,
+	"write":  fsnotify.Write,
+	"remove": fsnotify.Remove,
+	"rename": fsnotify.Rename,
+	"chmod":  fsnotify.Chmod,
+}
+
+// EventRules converts configured rules, whose commands run in workspace with
+// env until ctx is cancelled.
+func EventRules(ctx context.Context, configs []RuleConfig, workspace string, env EnvConfig, executor kexec.Interface) ([]EventRule, error) {
+	rules := make([]EventRule, 0, len(configs))
+	for _, c := range configs {
+		rule := EventRule{Name: c.Name, Globs: c.Globs, Then: c.Then}
+		for _, name := range c.Ops {
+			op, ok := ruleOps[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf("rule %q: unknown op %q", c.Name, name)
+			}
+			rule.Ops |= op
+		}
+		if c.Debounce != "" {
+			d, err := time.ParseDuration(c.Debounce)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: invalid debounce: %w", c.Name, err)
+			}
+			rule.Debounce = d
+		}
+		if c.Run != "" {
+			command := c.Run
+			rule.Run = func() error {
+				cmd := executor.CommandContext(ctx, "sh", "-c", command)
+				cmd.SetDir(workspace)
+				env.apply(cmd)
+				if out, err := cmd.CombinedOutput(); err != nil {
+					return fmt.Errorf("%s: %w\n%s", command, err, strings.TrimSpace(string(out)))
+				}
+				return nil
+			}
+		}
+		rules = append(rules, rule)
+	}
+	if err := ValidateEventRules(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
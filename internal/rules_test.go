@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestEventRules(t *testing.T) {
+	executor := NewFakeExecutor("", "")
+	rules, err := EventRules(context.Background(), []RuleConfig{
+		{Name: "codegen", Globs: []string{"schema.graphql"}, Ops: []string{"write", "Create"}, Run: "npm run codegen", Then: []string{"restart"}, Debounce: "1s"},
+		{Name: "i18n", Globs: []string{"messages/*.json"}, Then: []string{"sync"}},
+	}, "/ws", EnvConfig{}, executor)
+	if err != nil {
+		t.Fatalf("EventRules failed: %v", err)
+	}
+	codegen := rules[0]
+	if codegen.Ops != fsnotify.Write|fsnotify.Create || codegen.Debounce != time.Second || codegen.Run == nil {
+		t.Errorf("codegen = %+v, want write|create, 1s, and a command", codegen)
+	}
+	if rules[1].Run != nil || rules[1].ops() != fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename {
+		t.Errorf("i18n = %+v, want no command and the default ops", rules[1])
+	}
+
+	if err := codegen.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if executor.name != "sh" || !slices.Equal(executor.args, []string{"-c", "npm run codegen"}) || executor.cmd.dir != "/ws" {
+		t.Errorf("ran %s %v in %s, want sh -c 'npm run codegen' in /ws", executor.name, executor.args, executor.cmd.dir)
+	}
+
+	for _, tt := range []struct {
+		rule RuleConfig
+		want string
+	}{
+		{RuleConfig{Globs: []string{"*.graphql"}, Then: []string{"restart"}}, "without a name"},
+		{RuleConfig{Name: "x", Then: []string{"restart"}}, "no globs"},
+		{RuleConfig{Name: "x", Globs: []string{"*.graphql"}}, "nothing to run"},
+		{RuleConfig{Name: "x", Globs: []string{"*.graphql"}, Then: []string{"deploy"}}, `unknown action "deploy"`},
+		{RuleConfig{Name: "x", Globs: []string{"*.graphql"}, Run: "true", Ops: []string{"touch"}}, `unknown op "touch"`},
+		{RuleConfig{Name: "x", Globs: []string{"[.graphql"}, Run: "true"}, "invalid glob"},
+		{RuleConfig{Name: "x", Globs: []string{"*.graphql"}, Run: "true", Debounce: "soon"}, "invalid debounce"},
+	} {
+		_, err := EventRules(context.Background(), []RuleConfig{tt.rule}, "/ws", EnvConfig{}, executor)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("EventRules(%+v) = %v, want an error containing %q", tt.rule, err, tt.want)
+		}
+	}
+	_, err = EventRules(context.Background(), []RuleConfig{
+		{Name: "x", Globs: []string{"a"}, Run: "true"},
+		{Name: "x", Globs: []string{"b"}, Run: "true"},
+	}, "/ws", EnvConfig{}, executor)
+	if err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("EventRules with a duplicate name = %v, want an error", err)
+	}
+}
+
+func TestWatcher_Rules(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"messages/en.json": "{}", "schema.graphql": ""})
+
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := NewFakeFSWatcher()
+		var runs, syncs, restarts int
+		callbacks := WatcherCallbacks{OnRestart: func() { restarts++ }, OnSvelteSync: func() { syncs++ }}
+		config := WatcherConfig{
+			WorkspacePath:    root,
+			NonRecursiveDirs: []string{"."},
+			Rules: []EventRule{
+				{Name: "codegen", Globs: []string{"schema.graphql"}, Run: func() error { runs++; return nil }, Then: []string{RuleActionRestart}},
+				{Name: "i18n", Globs: []string{"messages/*.json"}, Ops: fsnotify.Write, Then: []string{RuleActionSync}},
+				{Name: "broken", Globs: []string{"schema.graphql"}, Run: func() error { return errors.New("exit status 1") }, Then: []string{RuleActionSync}},
+			},
+		}
+		w := NewWatcher(config, callbacks, fsWatcher, nil)
+		defer w.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go w.Start(ctx)
+		synctest.Wait()
+
+		// messages is watched for the i18n rule.
+		if want := []addedPath{{root, false}, {filepath.Join(root, "messages"), false}}; !slices.Equal(fsWatcher.addedPaths, want) {
+			t.Errorf("added paths = %v, want %v", fsWatcher.addedPaths, want)
+		}
+
+		fsWatcher.events <- fsnotify.Event{Name: filepath.Join(root, "schema.graphql"), Op: fsnotify.Write}
+		fsWatcher.events <- fsnotify.Event{Name: filepath.Join(root, "schema.graphql"), Op: fsnotify.Write}
+		fsWatcher.events <- fsnotify.Event{Name: filepath.Join(root, "messages", "en.json"), Op: fsnotify.Write}
+		fsWatcher.events <- fsnotify.Event{Name: filepath.Join(root, "messages", "de.json"), Op: fsnotify.Remove}
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+
+		// The failing rule's sync is skipped.
+		if runs != 1 || restarts != 1 || syncs != 1 {
+			t.Errorf("runs, restarts, syncs = %d, %d, %d; want 1, 1, 1", runs, restarts, syncs)
+		}
+		want := map[string]int{"codegen": 1, "i18n": 1, "broken": 1}
+		if got := w.Status().RuleTriggers; !maps.Equal(got, want) {
+			t.Errorf("RuleTriggers = %v, want %v", got, want)
+		}
+	})
+}
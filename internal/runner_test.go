@@ -3,7 +3,12 @@ package internal
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"testing/synctest"
 	"time"
@@ -12,29 +17,137 @@ import (
 )
 
 // FakeCmd implements kexec.Cmd for testing.
+// Like a real svelte-check --watch process, Wait blocks until the command is
+// stopped, or until Exit simulates the process dying on its own.
 type FakeCmd struct {
 	dir        string
+	env        []string   // set by SetEnv; nil means inherit
+	pgroup     bool       // set by SetProcessGroupCreation
+	stopPolicy StopPolicy // set by SetStopPolicy
 	stdout     io.ReadCloser
 	stderr     io.ReadCloser
 	started    bool
 	stopped    bool
 	startError error
+	pid        int           // reported by Pid, for resource monitoring
+	stopDelay  time.Duration // how long the process takes to exit after Stop
+
+	// onStart and onExit, if set, are called when the process starts and exits.
+	onStart func()
+	onExit  func()
+
+	mu       sync.Mutex
+	exited   chan struct{}
+	waitErr  error
+	stdoutW  io.Writer
+	stderrW  io.Writer
+	copyDone chan struct{}
+}
+
+// exitCh lazily creates the channel closed when the process exits.
+func (c *FakeCmd) exitCh() chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.exited == nil {
+		c.exited = make(chan struct{})
+	}
+	return c.exited
+}
+
+// Exit simulates the process exiting with err. Safe to call more than once.
+func (c *FakeCmd) Exit(err error) {
+	ch := c.exitCh()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-ch:
+	default:
+		c.waitErr = err
+		if c.onExit != nil {
+			c.onExit()
+		}
+		close(ch)
+	}
+}
+
+// Wait blocks until the process exits and, as with os/exec, until output sent
+// to writers from SetStdout/SetStderr has been fully copied.
+func (c *FakeCmd) Wait() error {
+	<-c.exitCh()
+	c.mu.Lock()
+	copyDone, err := c.copyDone, c.waitErr
+	c.mu.Unlock()
+	if copyDone != nil {
+		<-copyDone
+	}
+	return err
 }
 
-func (c *FakeCmd) SetDir(dir string)                                    { c.dir = dir }
-func (c *FakeCmd) SetStdin(in io.Reader)                                {}
-func (c *FakeCmd) SetStdout(out io.Writer)                              {}
-func (c *FakeCmd) SetStderr(out io.Writer)                              {}
-func (c *FakeCmd) SetEnv(env []string)                                  {}
-func (c *FakeCmd) StdoutPipe() (io.ReadCloser, error)                   { return c.stdout, nil }
-func (c *FakeCmd) StderrPipe() (io.ReadCloser, error)                   { return c.stderr, nil }
-func (c *FakeCmd) Start() error                                         { c.started = true; return c.startError }
-func (c *FakeCmd) Wait() error                                          { return nil }
-func (c *FakeCmd) Run() error                                           { return nil }
-func (c *FakeCmd) CombinedOutput() ([]byte, error)                      { return nil, nil }
-func (c *FakeCmd) Output() ([]byte, error)                              { return nil, nil }
-func (c *FakeCmd) Stop()                                                { c.stopped = true }
-func (c *FakeCmd) SetProcessGroupCreation(_ bool)                       {}
+func (c *FakeCmd) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started = true
+	if c.startError != nil {
+		return c.startError
+	}
+	if c.onStart != nil {
+		c.onStart()
+	}
+
+	// Emulate os/exec copying canned output into writers set via SetStdout/SetStderr.
+	if c.stdoutW != nil || c.stderrW != nil {
+		done := make(chan struct{})
+		c.copyDone = done
+		go func() {
+			defer close(done)
+			if c.stdoutW != nil && c.stdout != nil {
+				_, _ = io.Copy(c.stdoutW, c.stdout)
+			}
+			if c.stderrW != nil && c.stderr != nil {
+				_, _ = io.Copy(c.stderrW, c.stderr)
+			}
+		}()
+	}
+	return nil
+}
+
+func (c *FakeCmd) Stop() {
+	c.mu.Lock()
+	c.stopped = true
+	delay := c.stopDelay
+	c.mu.Unlock()
+	if delay > 0 {
+		time.AfterFunc(delay, func() { c.Exit(nil) })
+		return
+	}
+	c.Exit(nil)
+}
+
+// isStarted reports whether Start was called, safely across goroutines.
+func (c *FakeCmd) isStarted() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.started
+}
+
+// isStopped reports whether Stop was called, safely across goroutines.
+func (c *FakeCmd) isStopped() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopped
+}
+
+func (c *FakeCmd) Pid() int                           { return c.pid }
+func (c *FakeCmd) SetDir(dir string)                  { c.dir = dir }
+func (c *FakeCmd) SetStdin(in io.Reader)              {}
+func (c *FakeCmd) SetStdout(out io.Writer)            { c.stdoutW = out }
+func (c *FakeCmd) SetStderr(out io.Writer)            { c.stderrW = out }
+func (c *FakeCmd) SetEnv(env []string)                { c.env = env }
+func (c *FakeCmd) StdoutPipe() (io.ReadCloser, error) { return c.stdout, nil }
+func (c *FakeCmd) StderrPipe() (io.ReadCloser, error) { return c.stderr, nil }
+
+func (c *FakeCmd) SetProcessGroupCreation(create bool)                  { c.pgroup = create }
+func (c *FakeCmd) SetStopPolicy(policy StopPolicy)                      { c.stopPolicy = policy }
 func (c *FakeCmd) SetProcessGroupPgid(_ bool)                           {}
 func (c *FakeCmd) SetProcessGroupPdeathsig(_ bool)                      {}
 func (c *FakeCmd) GetProcessGroupProcess() (*int, error)                { return nil, nil }
@@ -43,9 +156,48 @@ func (c *FakeCmd) SetTerminateGracePeriodWithContext(_ context.Context) {}
 func (c *FakeCmd) SetTerminateGracePeriodWithTimer(_ *time.Timer)       {}
 func (c *FakeCmd) SetTerminateGracePeriodWithoutKilling()               {}
 
+// Output returns the canned stdout and the error set with Exit, as a one-shot
+// command would.
+func (c *FakeCmd) Output() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started = true
+	if c.stdout == nil {
+		return nil, c.waitErr
+	}
+	out, err := io.ReadAll(c.stdout)
+	if err != nil {
+		return nil, err
+	}
+	return out, c.waitErr
+}
+
+// Run returns the error set with Exit, as a one-shot command would.
+func (c *FakeCmd) Run() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started = true
+	return c.waitErr
+}
+
+// CombinedOutput behaves like Output; stderr is not merged in.
+func (c *FakeCmd) CombinedOutput() ([]byte, error) {
+	return c.Output()
+}
+
 // FakeExecutor implements kexec.Interface for testing.
 type FakeExecutor struct {
+	mu  sync.Mutex
 	cmd *FakeCmd
+
+	// name, args, and ctx record the most recent command requested; ctx is
+	// nil for one made with Command.
+	name string
+	args []string
+	ctx  context.Context
+
+	// newCmd, if set, creates a fresh command for every invocation.
+	newCmd func() *FakeCmd
 }
 
 func NewFakeExecutor(stdout, stderr string) *FakeExecutor {
@@ -58,10 +210,34 @@ func NewFakeExecutor(stdout, stderr string) *FakeExecutor {
 }
 
 func (e *FakeExecutor) Command(cmd string, args ...string) kexec.Cmd {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.name, e.args, e.ctx = cmd, args, nil
+	if e.newCmd != nil {
+		e.cmd = e.newCmd()
+	}
 	return e.cmd
 }
 
 func (e *FakeExecutor) CommandContext(ctx context.Context, cmd string, args ...string) kexec.Cmd {
+	c := e.Command(cmd, args...)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ctx = ctx
+	return c
+}
+
+// setCmd replaces the command returned for subsequent invocations.
+func (e *FakeExecutor) setCmd(c *FakeCmd) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cmd = c
+}
+
+// currentCmd returns the command handed out for the next invocation.
+func (e *FakeExecutor) currentCmd() *FakeCmd {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	return e.cmd
 }
 
@@ -69,10 +245,27 @@ func (e *FakeExecutor) LookPath(file string) (string, error) {
 	return file, nil
 }
 
+// latestEvent returns c's latest result, failing the test if it cannot be had.
+func latestEvent(t *testing.T, c Checker) SvelteWatchCheckComplete {
+	t.Helper()
+	result, err := c.GetLatestEvent(context.Background())
+	if err != nil {
+		t.Fatalf("GetLatestEvent failed: %v", err)
+	}
+	return result
+}
+
+// commandLine returns the most recent command and its arguments joined by spaces.
+func (e *FakeExecutor) commandLine() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return strings.Join(append([]string{e.name}, e.args...), " ")
+}
+
 // TestNewRunner tests the NewRunner constructor.
 func TestNewRunner(t *testing.T) {
 	executor := NewFakeExecutor("", "")
-	r := NewRunner("/workspace", "/workspace/tsconfig.json", executor)
+	r := NewRunner("/workspace", WithTsconfig("/workspace/tsconfig.json"), WithExecutor(executor))
 
 	if r.workspacePath != "/workspace" {
 		t.Errorf("workspacePath = %q, want /workspace", r.workspacePath)
@@ -92,7 +285,7 @@ func TestRunner_Start(t *testing.T) {
 1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
 `
 	executor := NewFakeExecutor(output, "")
-	r := NewRunner("/workspace", "", executor)
+	r := NewRunner("/workspace", WithExecutor(executor))
 
 	ctx := context.Background()
 	err := r.Start(ctx)
@@ -108,10 +301,191 @@ func TestRunner_Start(t *testing.T) {
 	}
 }
 
+// TestRunner_Start_UsesPackageManager tests that the configured package manager builds the command.
+func TestRunner_Start_UsesPackageManager(t *testing.T) {
+	executor := NewFakeExecutor("", "")
+	r := NewRunnerWithConfig(RunnerConfig{
+		WorkspacePath:  "/workspace",
+		TsconfigPath:   "tsconfig.app.json",
+		PackageManager: PackageManagerPnpm,
+	}, WithExecutor(executor))
+
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	want := "pnpm exec svelte-check --watch --output machine-verbose --tsconfig tsconfig.app.json"
+	if got := executor.commandLine(); got != want {
+		t.Errorf("command = %q, want %q", got, want)
+	}
+}
+
+// TestRunOnce_UsesPackageManager tests that RunOnce runs without --watch through the package manager.
+func TestRunOnce_UsesPackageManager(t *testing.T) {
+	executor := NewFakeExecutor("", "")
+	_, exitCode := RunOnce(context.Background(), RunnerConfig{
+		WorkspacePath:  "/workspace",
+		PackageManager: PackageManagerNpm,
+	}, executor)
+
+	if exitCode != 0 {
+		t.Errorf("exitCode = %d, want 0", exitCode)
+	}
+	want := "npx --no-install svelte-check"
+	if got := executor.commandLine(); got != want {
+		t.Errorf("command = %q, want %q", got, want)
+	}
+	if executor.cmd.dir != "/workspace" {
+		t.Errorf("Command dir = %q, want /workspace", executor.cmd.dir)
+	}
+}
+
+// TestRunner_Start_CustomCommand tests that a custom command replaces the invocation entirely.
+func TestRunner_Start_CustomCommand(t *testing.T) {
+	executor := NewFakeExecutor("", "")
+	r := NewRunnerWithConfig(RunnerConfig{
+		WorkspacePath: "/workspace",
+		TsconfigPath:  "ignored.json",
+		Command:       []string{"./scripts/check.sh", "--watch", "--output", "machine-verbose"},
+	}, WithExecutor(executor))
+
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	want := "./scripts/check.sh --watch --output machine-verbose"
+	if got := executor.commandLine(); got != want {
+		t.Errorf("command = %q, want %q", got, want)
+	}
+}
+
+// TestRunOnce_CustomCommand_StripsWatchFlags tests that one-shot runs drop watch-only flags.
+func TestRunOnce_CustomCommand_StripsWatchFlags(t *testing.T) {
+	executor := NewFakeExecutor("", "")
+	_, _ = RunOnce(context.Background(), RunnerConfig{
+		WorkspacePath: "/workspace",
+		Command:       []string{"npx", "svelte-check", "--watch", "--output", "machine-verbose", "--threshold", "error"},
+	}, executor)
+
+	want := "npx svelte-check --threshold error"
+	if got := executor.commandLine(); got != want {
+		t.Errorf("command = %q, want %q", got, want)
+	}
+}
+
+// TestRunner_Start_ExtraArgs tests that extra arguments are appended to the watch invocation.
+func TestRunner_Start_ExtraArgs(t *testing.T) {
+	executor := NewFakeExecutor("", "")
+	r := NewRunnerWithConfig(RunnerConfig{
+		WorkspacePath:  "/workspace",
+		PackageManager: PackageManagerBun,
+		ExtraArgs:      []string{"--ignore", "dist/**", "--diagnostic-sources", "js,svelte"},
+	}, WithExecutor(executor))
+
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	want := "bun run svelte-check --watch --output machine-verbose --ignore dist/** --diagnostic-sources js,svelte"
+	if got := executor.commandLine(); got != want {
+		t.Errorf("command = %q, want %q", got, want)
+	}
+}
+
+// TestRunOnce_ExtraArgs tests that extra arguments are appended to one-shot runs, including custom commands.
+func TestRunOnce_ExtraArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		config RunnerConfig
+		want   string
+	}{
+		{
+			name: "default command",
+			config: RunnerConfig{
+				WorkspacePath:  "/workspace",
+				PackageManager: PackageManagerPnpm,
+				ExtraArgs:      []string{"--compiler-warnings", "css_unused_selector:ignore"},
+			},
+			want: "pnpm exec svelte-check --compiler-warnings css_unused_selector:ignore",
+		},
+		{
+			name: "custom command",
+			config: RunnerConfig{
+				WorkspacePath: "/workspace",
+				Command:       []string{"npx", "svelte-check", "--watch"},
+				ExtraArgs:     []string{"--ignore", "dist/**"},
+			},
+			want: "npx svelte-check --ignore dist/**",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewFakeExecutor("", "")
+			_, _ = RunOnce(context.Background(), tt.config, executor)
+
+			if got := executor.commandLine(); got != tt.want {
+				t.Errorf("command = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRunner_Start_Env tests that the configured environment reaches the
+// process, and that a zero EnvConfig leaves it inheriting the daemon's.
+func TestRunner_Start_Env(t *testing.T) {
+	executor := NewFakeExecutor("", "")
+	r := NewRunnerWithConfig(RunnerConfig{WorkspacePath: "/workspace"}, WithExecutor(executor))
+	_ = r.Start(context.Background())
+	r.Stop()
+	if env := executor.currentCmd().env; env != nil {
+		t.Errorf("env = %q, want nil (inherit)", env)
+	}
+
+	executor = NewFakeExecutor("", "")
+	r = NewRunnerWithConfig(RunnerConfig{
+		WorkspacePath: "/workspace",
+		Env:           EnvConfig{Set: map[string]string{"NODE_OPTIONS": "--max-old-space-size=8192"}},
+	}, WithExecutor(executor))
+	_ = r.Start(context.Background())
+	r.Stop()
+	if env := executor.currentCmd().env; !slices.Contains(env, "NODE_OPTIONS=--max-old-space-size=8192") {
+		t.Errorf("env = %q, want NODE_OPTIONS set", env)
+	}
+}
+
+// TestRunner_Start_ProcessGroup tests that svelte-check runs in its own
+// process group so Stop reaches the processes it spawns.
+func TestRunner_Start_ProcessGroup(t *testing.T) {
+	executor := NewFakeExecutor("", "")
+	r := NewRunner("/workspace", WithExecutor(executor))
+	_ = r.Start(context.Background())
+	r.Stop()
+	if !executor.currentCmd().pgroup {
+		t.Error("expected the process to be started in a new process group")
+	}
+	if got := executor.currentCmd().stopPolicy; got != DefaultStopPolicy {
+		t.Errorf("stop policy = %+v, want DefaultStopPolicy", got)
+	}
+}
+
+// TestRunSvelteKitSync_UsesPackageManager tests the sync invocation for each package manager.
+func TestRunSvelteKitSync_UsesPackageManager(t *testing.T) {
+	executor := NewFakeExecutor("", "")
+	if err := RunSvelteKitSync(context.Background(), "/workspace", PackageManagerYarn, executor); err != nil {
+		t.Fatalf("RunSvelteKitSync failed: %v", err)
+	}
+
+	want := "yarn svelte-kit sync"
+	if got := executor.commandLine(); got != want {
+		t.Errorf("command = %q, want %q", got, want)
+	}
+}
+
 // TestRunner_Stop tests stopping the runner.
 func TestRunner_Stop(t *testing.T) {
 	executor := NewFakeExecutor("", "")
-	r := NewRunner("/workspace", "", executor)
+	r := NewRunner("/workspace", WithExecutor(executor))
 
 	ctx := context.Background()
 	_ = r.Start(ctx)
@@ -126,7 +500,7 @@ func TestRunner_Stop(t *testing.T) {
 // TestRunner_Stop_NilCmd tests stopping when cmd is nil.
 func TestRunner_Stop_NilCmd(t *testing.T) {
 	executor := NewFakeExecutor("", "")
-	r := NewRunner("/workspace", "", executor)
+	r := NewRunner("/workspace", WithExecutor(executor))
 
 	// Should not panic when cmd is nil
 	r.Stop()
@@ -141,16 +515,17 @@ func TestRunner_GetLatestEvent_BlocksUntilComplete(t *testing.T) {
 1770255834342 COMPLETED 100 FILES 1 ERRORS 0 WARNINGS 1 FILES_WITH_PROBLEMS
 `
 		executor := NewFakeExecutor(output, "")
-		r := NewRunner("/workspace", "", executor)
+		r := NewRunner("/workspace", WithExecutor(executor))
 
 		ctx := context.Background()
 		_ = r.Start(ctx)
+		defer r.Stop()
 
 		// Give the interpreter time to process
 		time.Sleep(10 * time.Millisecond)
 		synctest.Wait()
 
-		result := r.GetLatestEvent()
+		result := latestEvent(t, r)
 
 		if result.ErrorCount != 1 {
 			t.Errorf("ErrorCount = %d, want 1", result.ErrorCount)
@@ -158,6 +533,28 @@ func TestRunner_GetLatestEvent_BlocksUntilComplete(t *testing.T) {
 	})
 }
 
+// TestRunner_GetLatestEvent_Canceled tests that a wait for a check in
+// progress ends when the context is done.
+func TestRunner_GetLatestEvent_Canceled(t *testing.T) {
+	executor := NewFakeExecutor(`1770255832071 START "/workspace"
+`, "")
+	r := NewRunner("/workspace", WithExecutor(executor))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := r.GetLatestEvent(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetLatestEvent error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.GetLatestEvent(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetLatestEvent error = %v, want %v", err, context.Canceled)
+	}
+}
+
 // TestRunner_GetLatestEvent_ReturnsValueMultipleTimes tests read-then-write-back.
 func TestRunner_GetLatestEvent_ReturnsValueMultipleTimes(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
@@ -165,18 +562,19 @@ func TestRunner_GetLatestEvent_ReturnsValueMultipleTimes(t *testing.T) {
 1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
 `
 		executor := NewFakeExecutor(output, "")
-		r := NewRunner("/workspace", "", executor)
+		r := NewRunner("/workspace", WithExecutor(executor))
 
 		ctx := context.Background()
 		_ = r.Start(ctx)
+		defer r.Stop()
 
 		time.Sleep(10 * time.Millisecond)
 		synctest.Wait()
 
 		// Call GetLatestEvent multiple times - should return same value each time
-		result1 := r.GetLatestEvent()
-		result2 := r.GetLatestEvent()
-		result3 := r.GetLatestEvent()
+		result1 := latestEvent(t, r)
+		result2 := latestEvent(t, r)
+		result3 := latestEvent(t, r)
 
 		// Since structs with slices can't be compared with ==, compare key fields
 		if result1.ErrorCount != result2.ErrorCount || result2.ErrorCount != result3.ErrorCount {
@@ -188,6 +586,138 @@ func TestRunner_GetLatestEvent_ReturnsValueMultipleTimes(t *testing.T) {
 	})
 }
 
+// TestRunner_Status_Timings tests that check durations are measured from the
+// START and COMPLETED timestamps.
+func TestRunner_Status_Timings(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		output := `1770255832071 START "/workspace"
+1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+1770255844663 START "/workspace"
+1770255844689 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+`
+		executor := NewFakeExecutor(output, "")
+		r := NewRunner("/workspace", WithExecutor(executor))
+		_ = r.Start(context.Background())
+		defer r.Stop()
+
+		time.Sleep(10 * time.Millisecond)
+		synctest.Wait()
+
+		got := r.Status().Timings
+		if got == nil {
+			t.Fatal("Timings = nil, want two cycles")
+		}
+		want := CheckTimings{Count: 2, LastMs: 26, P50Ms: 26, P95Ms: 2271, MaxMs: 2271, TotalMs: 2297}
+		if *got != want {
+			t.Errorf("Timings = %+v, want %+v", *got, want)
+		}
+	})
+}
+
+// TestRunner_History tests that every completed result is retained in order.
+func TestRunner_History(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		output := `1770255832071 START "/workspace"
+1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+1770255844663 START "/workspace"
+1770255844689 {"type":"ERROR","filename":"src/a.ts","start":{"line":0,"character":0},"end":{"line":0,"character":1},"message":"New error","code":2322}
+1770255844689 COMPLETED 100 FILES 1 ERRORS 0 WARNINGS 1 FILES_WITH_PROBLEMS
+`
+		executor := NewFakeExecutor(output, "")
+		r := NewRunner("/workspace", WithExecutor(executor))
+		_ = r.Start(context.Background())
+		defer r.Stop()
+
+		time.Sleep(10 * time.Millisecond)
+		synctest.Wait()
+
+		history := r.History()
+		if len(history) != 2 {
+			t.Fatalf("len(History()) = %d, want 2", len(history))
+		}
+		if history[0].Seq != 1 || history[0].Result.ErrorCount != 0 {
+			t.Errorf("History()[0] = seq %d, %d errors; want seq 1, 0 errors", history[0].Seq, history[0].Result.ErrorCount)
+		}
+		if history[1].Seq != 2 || history[1].Result.ErrorCount != 1 {
+			t.Errorf("History()[1] = seq %d, %d errors; want seq 2, 1 error", history[1].Seq, history[1].Result.ErrorCount)
+		}
+		if got := r.HistorySince(1); len(got) != 1 || got[0].Seq != 2 {
+			t.Errorf("HistorySince(1) = %+v, want only seq 2", got)
+		}
+	})
+}
+
+// TestRunner_PeekLatest_Persisted tests that a result saved by a previous
+// Runner is served, marked stale, until the first check completes, and that
+// the new result is saved in its place.
+func TestRunner_PeekLatest_Persisted(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "x.sock"+StateFileSuffix)
+	resultStore{path: stateFile, workspace: "/workspace"}.save(SvelteWatchCheckComplete{ErrorCount: 3})
+
+	synctest.Test(t, func(t *testing.T) {
+		executor := NewFakeExecutor(`1770255832071 START "/workspace"
+`, "")
+		r := NewRunnerWithConfig(RunnerConfig{WorkspacePath: "/workspace", StateFile: stateFile}, WithExecutor(executor))
+		_ = r.Start(context.Background())
+		defer r.Stop()
+
+		time.Sleep(10 * time.Millisecond)
+		synctest.Wait()
+
+		got, ok := r.PeekLatest()
+		if !ok || got.ErrorCount != 3 || !got.Stale || got.StaleReason != staleReasonPersisted {
+			t.Errorf("PeekLatest() = %+v, %v; want the persisted result, marked stale", got, ok)
+		}
+	})
+
+	synctest.Test(t, func(t *testing.T) {
+		executor := NewFakeExecutor(`1770255832071 START "/workspace"
+1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+`, "")
+		r := NewRunnerWithConfig(RunnerConfig{WorkspacePath: "/workspace", StateFile: stateFile}, WithExecutor(executor))
+		_ = r.Start(context.Background())
+		defer r.Stop()
+
+		time.Sleep(10 * time.Millisecond)
+		synctest.Wait()
+
+		got, ok := r.PeekLatest()
+		if !ok || got.ErrorCount != 0 || got.Stale {
+			t.Errorf("PeekLatest() = %+v, %v; want the fresh result", got, ok)
+		}
+	})
+
+	saved, ok := resultStore{path: stateFile, workspace: "/workspace"}.load()
+	if !ok || saved.ErrorCount != 0 || saved.FileCount != 100 {
+		t.Errorf("state file holds %+v, want the latest result", saved)
+	}
+}
+
+// TestRunner_PeekLatest_CheckInProgress tests that the previous result is
+// returned, marked stale, while a new check runs.
+func TestRunner_PeekLatest_CheckInProgress(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		executor := NewFakeExecutor(`1770255832071 START "/workspace"
+1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+1770255844663 START "/workspace"
+`, "")
+		r := NewRunner("/workspace", WithExecutor(executor))
+		if _, ok := r.PeekLatest(); ok {
+			t.Error("PeekLatest() before any check: ok = true, want false")
+		}
+		_ = r.Start(context.Background())
+		defer r.Stop()
+
+		time.Sleep(10 * time.Millisecond)
+		synctest.Wait()
+
+		got, ok := r.PeekLatest()
+		if !ok || got.FileCount != 100 || !got.Stale || got.StaleReason != "svelte-check is checking" {
+			t.Errorf("PeekLatest() = %+v, %v; want the previous result, stale while checking", got, ok)
+		}
+	})
+}
+
 // TestRunner_HandleEvents_StartDrainsChannel tests that start event drains the channel.
 func TestRunner_HandleEvents_StartDrainsChannel(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
@@ -199,16 +729,17 @@ func TestRunner_HandleEvents_StartDrainsChannel(t *testing.T) {
 1770255844689 COMPLETED 100 FILES 1 ERRORS 0 WARNINGS 1 FILES_WITH_PROBLEMS
 `
 		executor := NewFakeExecutor(output, "")
-		r := NewRunner("/workspace", "", executor)
+		r := NewRunner("/workspace", WithExecutor(executor))
 
 		ctx := context.Background()
 		_ = r.Start(ctx)
+		defer r.Stop()
 
 		time.Sleep(10 * time.Millisecond)
 		synctest.Wait()
 
 		// Should get the second (latest) result
-		result := r.GetLatestEvent()
+		result := latestEvent(t, r)
 
 		if result.ErrorCount != 1 {
 			t.Errorf("ErrorCount = %d, want 1 (should be latest result)", result.ErrorCount)
@@ -223,16 +754,17 @@ func TestRunner_Restart(t *testing.T) {
 1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
 `
 		executor := NewFakeExecutor(output, "")
-		r := NewRunner("/workspace", "", executor)
+		r := NewRunner("/workspace", WithExecutor(executor))
 
 		ctx := context.Background()
 		_ = r.Start(ctx)
+		defer r.Stop()
 
 		time.Sleep(10 * time.Millisecond)
 		synctest.Wait()
 
 		// Pre-fill channel with a value
-		_ = r.GetLatestEvent()
+		_ = latestEvent(t, r)
 
 		// Reset the executor for the restart
 		executor.cmd = &FakeCmd{
@@ -245,18 +777,176 @@ func TestRunner_Restart(t *testing.T) {
 			t.Fatalf("Restart failed: %v", err)
 		}
 
-		// Give time for restart
-		time.Sleep(200 * time.Millisecond)
 		synctest.Wait()
 
 		// Should be able to get a result after restart
-		result := r.GetLatestEvent()
+		result := latestEvent(t, r)
 		if result.ErrorCount != 0 {
 			t.Errorf("ErrorCount = %d after restart, want 0", result.ErrorCount)
 		}
 	})
 }
 
+// TestRunner_Restart_KeepsStartContext tests that a restarted process runs
+// under the context the Runner was started with, not the restart's, so that
+// it outlives the caller and still ends with the Runner.
+func TestRunner_Restart_KeepsStartContext(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		executor := NewFakeExecutor("", "")
+		executor.newCmd = func() *FakeCmd {
+			return newFakeCmd(`1770255832071 START "/workspace"
+1770255834342 COMPLETED 1 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+`)
+		}
+		r := NewRunner("/workspace", WithExecutor(executor))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		_ = r.Start(ctx)
+		defer r.Stop()
+		synctest.Wait()
+
+		restartCtx, cancelRestart := context.WithCancel(context.Background())
+		if err := r.Restart(restartCtx); err != nil {
+			t.Fatalf("Restart failed: %v", err)
+		}
+		cancelRestart()
+		synctest.Wait()
+
+		executor.mu.Lock()
+		processCtx := executor.ctx
+		executor.mu.Unlock()
+		if err := processCtx.Err(); err != nil {
+			t.Errorf("the restarted process's context ended with the restart's: %v", err)
+		}
+		cancel()
+		if processCtx.Err() == nil {
+			t.Error("the restarted process's context outlived the Runner's")
+		}
+	})
+}
+
+// TestRunner_Restart_WaitsForPreviousProcess tests that concurrent restarts
+// never leave two svelte-check processes running at once.
+func TestRunner_Restart_WaitsForPreviousProcess(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var mu sync.Mutex
+		var cmds []*FakeCmd
+		running, maxRunning := 0, 0
+
+		executor := NewFakeExecutor("", "")
+		executor.newCmd = func() *FakeCmd {
+			c := newFakeCmd(`1770255832071 START "/workspace"
+1770255834342 COMPLETED 1 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+`)
+			c.onStart = func() {
+				mu.Lock()
+				defer mu.Unlock()
+				running++
+				maxRunning = max(maxRunning, running)
+			}
+			c.onExit = func() {
+				mu.Lock()
+				defer mu.Unlock()
+				running--
+			}
+			mu.Lock()
+			cmds = append(cmds, c)
+			mu.Unlock()
+			return c
+		}
+		r := NewRunner("/workspace", WithExecutor(executor))
+
+		ctx := context.Background()
+		_ = r.Start(ctx)
+		defer r.Stop()
+
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Go(func() {
+				if err := r.Restart(ctx); err != nil {
+					t.Errorf("Restart failed: %v", err)
+				}
+			})
+		}
+		wg.Wait()
+		synctest.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(cmds) < 2 {
+			t.Errorf("started %d processes, want the restarts to start at least one", len(cmds))
+		}
+		if maxRunning != 1 {
+			t.Errorf("max concurrent processes = %d, want 1", maxRunning)
+		}
+		if running != 1 {
+			t.Errorf("running processes = %d, want 1", running)
+		}
+		if got := r.Status().State; got != RunnerStateReady {
+			t.Errorf("State = %q, want %q", got, RunnerStateReady)
+		}
+	})
+}
+
+// TestRunner_Restart_SingleFlight tests that restarts requested while another
+// is stopping the old process share its cycle, and that a restart requested
+// afterwards runs a cycle of its own.
+func TestRunner_Restart_SingleFlight(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var mu sync.Mutex
+		var cmds []*FakeCmd
+
+		executor := NewFakeExecutor("", "")
+		executor.newCmd = func() *FakeCmd {
+			c := newFakeCmd(`1770255832071 START "/workspace"
+1770255834342 COMPLETED 1 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+`)
+			c.stopDelay = time.Second
+			mu.Lock()
+			cmds = append(cmds, c)
+			mu.Unlock()
+			return c
+		}
+		started := func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(cmds)
+		}
+		r := NewRunner("/workspace", WithExecutor(executor))
+
+		ctx := context.Background()
+		_ = r.Start(ctx)
+		defer func() {
+			r.Stop()
+			time.Sleep(time.Second) // let the last process exit
+		}()
+		synctest.Wait()
+
+		var wg sync.WaitGroup
+		restart := func() {
+			if err := r.Restart(ctx); err != nil {
+				t.Errorf("Restart failed: %v", err)
+			}
+		}
+		wg.Go(restart)
+		synctest.Wait() // the first restart is waiting for the old process to exit
+		for range 4 {
+			wg.Go(restart)
+		}
+		wg.Wait()
+
+		if got := started(); got != 2 {
+			t.Errorf("started %d processes, want 2 (one restart for five requests)", got)
+		}
+
+		restart()
+		if got := started(); got != 3 {
+			t.Errorf("started %d processes, want 3 after a later restart", got)
+		}
+	})
+}
+
 // TestRunner_HandleEvents_CompleteDrainsOldValue tests that new complete replaces old.
 func TestRunner_HandleEvents_CompleteDrainsOldValue(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
@@ -268,19 +958,371 @@ func TestRunner_HandleEvents_CompleteDrainsOldValue(t *testing.T) {
 1770255844689 COMPLETED 100 FILES 1 ERRORS 0 WARNINGS 1 FILES_WITH_PROBLEMS
 `
 		executor := NewFakeExecutor(output, "")
-		r := NewRunner("/workspace", "", executor)
+		r := NewRunner("/workspace", WithExecutor(executor))
 
 		ctx := context.Background()
 		_ = r.Start(ctx)
+		defer r.Stop()
 
 		time.Sleep(10 * time.Millisecond)
 		synctest.Wait()
 
 		// Should have the latest result (1 error), not the first (0 errors)
-		result := r.GetLatestEvent()
+		result := latestEvent(t, r)
 
 		if result.ErrorCount != 1 {
 			t.Errorf("ErrorCount = %d, want 1 (latest result)", result.ErrorCount)
 		}
 	})
 }
+
+// newFakeCmd returns a FakeCmd that emits output on stdout.
+func newFakeCmd(output string) *FakeCmd {
+	return &FakeCmd{
+		stdout: io.NopCloser(bytes.NewBufferString(output)),
+		stderr: io.NopCloser(bytes.NewBufferString("")),
+	}
+}
+
+// TestRestartPolicy_Backoff tests exponential growth and the cap.
+func TestRestartPolicy_Backoff(t *testing.T) {
+	p := RestartPolicy{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, MaxRetries: 5}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 1 * time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{20, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+// TestRunner_Crash_AutoRestartsWithBackoff tests that an unexpected exit marks the
+// runner degraded and restarts it after the backoff delay.
+func TestRunner_Crash_AutoRestartsWithBackoff(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		output := `1770255832071 START "/workspace"
+1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+`
+		executor := NewFakeExecutor(output, "")
+		r := NewRunner("/workspace", WithExecutor(executor))
+
+		ctx := context.Background()
+		_ = r.Start(ctx)
+		defer r.Stop()
+		synctest.Wait()
+
+		if got := r.Status().State; got != RunnerStateReady {
+			t.Fatalf("State = %q, want %q", got, RunnerStateReady)
+		}
+
+		crashed := executor.currentCmd()
+		next := newFakeCmd(output)
+		executor.setCmd(next)
+		crashed.Exit(errors.New("signal: killed"))
+		synctest.Wait()
+
+		status := r.Status()
+		if status.State != RunnerStateDegraded {
+			t.Fatalf("State = %q after crash, want %q", status.State, RunnerStateDegraded)
+		}
+		if status.LastExit != "signal: killed" {
+			t.Errorf("LastExit = %q, want %q", status.LastExit, "signal: killed")
+		}
+		if status.ConsecutiveCrashes != 1 {
+			t.Errorf("ConsecutiveCrashes = %d, want 1", status.ConsecutiveCrashes)
+		}
+		if next.isStarted() {
+			t.Fatal("restarted before backoff elapsed")
+		}
+
+		time.Sleep(DefaultRestartPolicy.InitialBackoff)
+		synctest.Wait()
+
+		if !next.isStarted() {
+			t.Fatal("not restarted after backoff")
+		}
+		status = r.Status()
+		if status.AutoRestarts != 1 {
+			t.Errorf("AutoRestarts = %d, want 1", status.AutoRestarts)
+		}
+		// The new process completed a check, which resets the crash counter.
+		if status.State != RunnerStateReady || status.ConsecutiveCrashes != 0 {
+			t.Errorf("State = %q, ConsecutiveCrashes = %d; want ready with 0 crashes", status.State, status.ConsecutiveCrashes)
+		}
+	})
+}
+
+// TestRunner_Crash_GivesUpAfterMaxRetries tests that repeated crashes end in the failed state.
+func TestRunner_Crash_GivesUpAfterMaxRetries(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		executor := NewFakeExecutor("", "")
+		r := NewRunnerWithConfig(RunnerConfig{
+			WorkspacePath: "/workspace",
+			RestartPolicy: RestartPolicy{InitialBackoff: time.Second, MaxBackoff: time.Second, MaxRetries: 2},
+		}, WithExecutor(executor))
+
+		ctx := context.Background()
+		_ = r.Start(ctx)
+		defer r.Stop()
+
+		// Every process (including restarts) dies without completing a check.
+		for range 3 {
+			crashed := executor.currentCmd()
+			executor.setCmd(newFakeCmd(""))
+			crashed.Exit(errors.New("boom"))
+			synctest.Wait()
+			time.Sleep(time.Second)
+			synctest.Wait()
+		}
+
+		status := r.Status()
+		if status.State != RunnerStateFailed {
+			t.Fatalf("State = %q, want %q", status.State, RunnerStateFailed)
+		}
+		if status.AutoRestarts != 2 {
+			t.Errorf("AutoRestarts = %d, want 2", status.AutoRestarts)
+		}
+	})
+}
+
+// TestRunner_Stop_IsNotACrash tests that an intentional stop does not schedule a restart.
+func TestRunner_Stop_IsNotACrash(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		executor := NewFakeExecutor("", "")
+		r := NewRunner("/workspace", WithExecutor(executor))
+
+		_ = r.Start(context.Background())
+		r.Stop()
+		synctest.Wait()
+
+		status := r.Status()
+		if status.State != RunnerStateStopped {
+			t.Errorf("State = %q, want %q", status.State, RunnerStateStopped)
+		}
+		if status.ConsecutiveCrashes != 0 {
+			t.Errorf("ConsecutiveCrashes = %d, want 0", status.ConsecutiveCrashes)
+		}
+	})
+}
+
+// TestRunner_Crash_RecordsCrashReport tests that a crash captures stderr and the final output.
+func TestRunner_Crash_RecordsCrashReport(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		output := `1770255832071 START "/workspace"
+Loading svelte-check in workspace
+`
+		executor := NewFakeExecutor(output, "FATAL ERROR: Reached heap limit\nAllocation failed")
+		r := NewRunner("/workspace", WithExecutor(executor))
+
+		_ = r.Start(context.Background())
+		defer r.Stop()
+		synctest.Wait()
+
+		if r.LastCrash() != nil {
+			t.Fatal("LastCrash should be nil before any crash")
+		}
+
+		crashed := executor.currentCmd()
+		executor.setCmd(newFakeCmd(""))
+		crashed.Exit(errors.New("signal: aborted"))
+		synctest.Wait()
+
+		report := r.LastCrash()
+		if report == nil {
+			t.Fatal("LastCrash is nil after crash")
+		}
+		if report.Exit != "signal: aborted" {
+			t.Errorf("Exit = %q, want %q", report.Exit, "signal: aborted")
+		}
+		wantStderr := []string{"FATAL ERROR: Reached heap limit", "Allocation failed"}
+		if !slices.Equal(report.Stderr, wantStderr) {
+			t.Errorf("Stderr = %q, want %q", report.Stderr, wantStderr)
+		}
+		if len(report.Output) != 4 || report.Output[1] != "Loading svelte-check in workspace" {
+			t.Errorf("Output = %q, want stdout then stderr lines", report.Output)
+		}
+		if r.Status().LastCrash != report {
+			t.Error("Status().LastCrash should match LastCrash()")
+		}
+	})
+}
+
+// TestRunner_WaitReady tests that readiness follows the first START event.
+func TestRunner_WaitReady(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		executor := NewFakeExecutor(`1770255832071 START "/workspace"
+`, "")
+		r := NewRunner("/workspace", WithExecutor(executor))
+
+		if err := r.WaitReady(context.Background()); err == nil {
+			t.Error("WaitReady before Start should fail")
+		}
+
+		_ = r.Start(context.Background())
+		defer r.Stop()
+
+		if err := r.WaitReady(context.Background()); err != nil {
+			t.Errorf("WaitReady failed: %v", err)
+		}
+	})
+}
+
+// TestRunner_WaitReady_ExitBeforeStart tests the fast path for a process
+// that dies before its first check, e.g. a missing dependency.
+func TestRunner_WaitReady_ExitBeforeStart(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		executor := &FakeExecutor{cmd: &FakeCmd{
+			stdout: io.NopCloser(bytes.NewBufferString("")),
+			stderr: io.NopCloser(bytes.NewBufferString("Error: Cannot find module 'svelte-check'\n")),
+		}}
+		r := NewRunner("/workspace", WithExecutor(executor))
+		_ = r.Start(context.Background())
+		defer r.Stop()
+
+		crashed := executor.currentCmd()
+		executor.setCmd(newFakeCmd(""))
+		crashed.Exit(errors.New("exit status 1"))
+
+		err := r.WaitReady(context.Background())
+		var startupErr *StartupError
+		if !errors.As(err, &startupErr) {
+			t.Fatalf("WaitReady() = %v, want *StartupError", err)
+		}
+		if !strings.Contains(startupErr.Reason, "exit status 1") {
+			t.Errorf("Reason = %q, want the exit status", startupErr.Reason)
+		}
+		if !slices.Contains(startupErr.Stderr, "Error: Cannot find module 'svelte-check'") {
+			t.Errorf("Stderr = %q, want the captured error", startupErr.Stderr)
+		}
+	})
+}
+
+// TestRunner_WaitReady_Timeout tests that a process that never starts a check
+// fails once the context is done.
+func TestRunner_WaitReady_Timeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		executor := NewFakeExecutor("Loading svelte-check in workspace: /workspace\n", "")
+		r := NewRunner("/workspace", WithExecutor(executor))
+		_ = r.Start(context.Background())
+		defer r.Stop()
+
+		ctx, cancel := context.WithTimeoutCause(context.Background(), time.Minute, errors.New("timed out after 1m0s"))
+		defer cancel()
+
+		err := r.WaitReady(ctx)
+		var startupErr *StartupError
+		if !errors.As(err, &startupErr) {
+			t.Fatalf("WaitReady() = %v, want *StartupError", err)
+		}
+		if startupErr.Error() != "svelte-check did not start a check: timed out after 1m0s" {
+			t.Errorf("Error() = %q", startupErr.Error())
+		}
+		if len(startupErr.Output) != 1 {
+			t.Errorf("Output = %q, want the loading line", startupErr.Output)
+		}
+	})
+}
+
+// TestRunner_Resources_RestartsAboveMemoryCeiling tests that samples appear in
+// Status and that exceeding MaxRSSBytes restarts svelte-check.
+func TestRunner_Resources_RestartsAboveMemoryCeiling(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		executor := NewFakeExecutor("", "")
+		executor.currentCmd().pid = 100
+		r := NewRunnerWithConfig(RunnerConfig{
+			WorkspacePath: "/workspace",
+			Resources:     ResourceLimits{Interval: time.Second, MaxRSSBytes: 1000},
+		}, WithExecutor(executor))
+
+		var mu sync.Mutex
+		rss := map[int]int64{100: 500, 101: 500}
+		r.listProcesses = func() ([]processInfo, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var procs []processInfo
+			for pid, bytes := range rss {
+				procs = append(procs, processInfo{pid: pid, ppid: 1, rssBytes: bytes})
+			}
+			return procs, nil
+		}
+
+		_ = r.Start(context.Background())
+		defer r.Stop()
+
+		time.Sleep(time.Second)
+		synctest.Wait()
+
+		status := r.Status()
+		if status.Resources == nil || status.Resources.PID != 100 || status.Resources.RSSBytes != 500 {
+			t.Fatalf("Resources = %+v, want pid 100 at 500 bytes", status.Resources)
+		}
+		if status.MemoryLimitBytes != 1000 {
+			t.Errorf("MemoryLimitBytes = %d, want 1000", status.MemoryLimitBytes)
+		}
+
+		next := newFakeCmd("")
+		next.pid = 101
+		executor.setCmd(next)
+		mu.Lock()
+		rss[100] = 2000
+		mu.Unlock()
+
+		time.Sleep(time.Second)
+		synctest.Wait()
+		// The new process is sampled one interval after the restart.
+		time.Sleep(time.Second)
+		synctest.Wait()
+
+		if !next.isStarted() {
+			t.Fatal("not restarted after exceeding the memory ceiling")
+		}
+		status = r.Status()
+		if status.MemoryRestarts != 1 {
+			t.Errorf("MemoryRestarts = %d, want 1", status.MemoryRestarts)
+		}
+		if status.ConsecutiveCrashes != 0 {
+			t.Errorf("ConsecutiveCrashes = %d, want 0 (memory restart is not a crash)", status.ConsecutiveCrashes)
+		}
+		if status.Resources == nil || status.Resources.PID != 101 {
+			t.Errorf("Resources = %+v, want a sample of the new process", status.Resources)
+		}
+	})
+}
+
+// TestRunner_Status_LastCycle tests that a restart's trigger begins the
+// timeline of the cycle it starts.
+func TestRunner_Status_LastCycle(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		executor := NewFakeExecutor(checkOutput("1770255834000", 0), "")
+		r := NewRunner("/workspace", WithExecutor(executor))
+		_ = r.Start(context.Background())
+		defer r.Stop()
+		synctest.Wait()
+
+		if c := r.Status().LastCycle; c == nil || c.Trigger != TriggerStart || c.ProcessStartedAt.IsZero() {
+			t.Fatalf("LastCycle = %+v, want the first process's cycle", c)
+		}
+
+		executor.setCmd(newFakeCmd(checkOutput("1770255835000", 0)))
+		trigger := CycleTrigger{Source: TriggerGit, ReceivedAt: time.Now().Add(-time.Second), DebouncedAt: time.Now()}
+		if err := r.Restart(ContextWithTrigger(context.Background(), trigger)); err != nil {
+			t.Fatalf("Restart failed: %v", err)
+		}
+		synctest.Wait()
+
+		c := r.Status().LastCycle
+		if c == nil || c.Trigger != TriggerGit || !c.TriggeredAt.Equal(trigger.ReceivedAt) || c.DebounceMs != 1000 {
+			t.Errorf("LastCycle = %+v, want the git trigger's cycle", c)
+		}
+	})
+}
@@ -2,6 +2,8 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -34,7 +36,7 @@ func testSocketPath(t *testing.T) string {
 // TestNewServer tests the NewServer constructor.
 func TestNewServer(t *testing.T) {
 	executor := NewFakeExecutor("", "")
-	r := NewRunner("/workspace", "", executor)
+	r := NewRunner("/workspace", WithExecutor(executor))
 	s := NewServer("/tmp/test.sock", r)
 
 	if s.socketPath != "/tmp/test.sock" {
@@ -51,7 +53,7 @@ func TestNewServer(t *testing.T) {
 // TestServer_SocketPath tests the SocketPath getter.
 func TestServer_SocketPath(t *testing.T) {
 	executor := NewFakeExecutor("", "")
-	r := NewRunner("/workspace", "", executor)
+	r := NewRunner("/workspace", WithExecutor(executor))
 	s := NewServer("/tmp/test.sock", r)
 
 	if s.SocketPath() != "/tmp/test.sock" {
@@ -68,7 +70,7 @@ func TestServer_StartAndStop(t *testing.T) {
 1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
 `
 	executor := NewFakeExecutor(output, "")
-	r := NewRunner("/workspace", "", executor)
+	r := NewRunner("/workspace", WithExecutor(executor))
 
 	ctx := context.Background()
 	_ = r.Start(ctx)
@@ -78,7 +80,7 @@ func TestServer_StartAndStop(t *testing.T) {
 
 	s := NewServer(socketPath, r)
 
-	err := s.Start()
+	err := s.Start(context.Background())
 	if err != nil {
 		t.Fatalf("Start failed: %v", err)
 	}
@@ -113,7 +115,7 @@ func TestServer_HandleCheck_WithErrors(t *testing.T) {
 1770255834342 COMPLETED 100 FILES 1 ERRORS 1 WARNINGS 2 FILES_WITH_PROBLEMS
 `
 	executor := NewFakeExecutor(output, "")
-	r := NewRunner("/workspace", "", executor)
+	r := NewRunner("/workspace", WithExecutor(executor))
 
 	ctx := context.Background()
 	_ = r.Start(ctx)
@@ -121,7 +123,7 @@ func TestServer_HandleCheck_WithErrors(t *testing.T) {
 	time.Sleep(50 * time.Millisecond)
 
 	s := NewServer(socketPath, r)
-	err := s.Start()
+	err := s.Start(context.Background())
 	if err != nil {
 		t.Fatalf("Start failed: %v", err)
 	}
@@ -165,7 +167,7 @@ func TestServer_HandleCheck_NoErrors(t *testing.T) {
 1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
 `
 	executor := NewFakeExecutor(output, "")
-	r := NewRunner("/workspace", "", executor)
+	r := NewRunner("/workspace", WithExecutor(executor))
 
 	ctx := context.Background()
 	_ = r.Start(ctx)
@@ -173,7 +175,7 @@ func TestServer_HandleCheck_NoErrors(t *testing.T) {
 	time.Sleep(50 * time.Millisecond)
 
 	s := NewServer(socketPath, r)
-	err := s.Start()
+	err := s.Start(context.Background())
 	if err != nil {
 		t.Fatalf("Start failed: %v", err)
 	}
@@ -202,6 +204,65 @@ func TestServer_HandleCheck_NoErrors(t *testing.T) {
 	}
 }
 
+// TestServer_HandleCheck_Policy tests that a FAILURE answers 502 with the
+// policy's verdict and exit code, and that a custom policy is honored.
+func TestServer_HandleCheck_Policy(t *testing.T) {
+	socketPath := testSocketPath(t)
+
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(`1770255832071 START "/workspace"
+1770255834000 FAILURE "Connection closed"
+1770255834342 COMPLETED 100 FILES 0 ERRORS 1 WARNINGS 1 FILES_WITH_PROBLEMS
+`, "")))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	resp, err := unixHTTPClient(socketPath).Get("http://unix/check")
+	if err != nil {
+		t.Fatalf("GET /check failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("Status code = %d, want %d", resp.StatusCode, http.StatusBadGateway)
+	}
+	if got := resp.Header.Get(HeaderCheckVerdict); got != "failure" {
+		t.Errorf("%s = %q, want failure", HeaderCheckVerdict, got)
+	}
+	if got := resp.Header.Get(HeaderCheckExitCode); got != "2" {
+		t.Errorf("%s = %q, want 2", HeaderCheckExitCode, got)
+	}
+	if !strings.Contains(string(body), "Connection closed") {
+		t.Errorf("Body should report the failure, got: %s", body)
+	}
+
+	// Failing on warnings only, with a custom exit code.
+	warnSocket := testSocketPath(t)
+	ws := NewServer(warnSocket, r)
+	ws.SetCheckPolicy(CheckPolicy{FailOn: []Severity{SeverityWarning}, ExitCodes: map[Severity]int{SeverityWarning: 3}})
+	if err := ws.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = ws.Stop(context.Background())
+	}()
+	c := &Client{socketPath: warnSocket, httpClient: unixHTTPClient(warnSocket)}
+	got, err := c.CheckWith(context.Background(), CheckOptions{})
+	if err != nil {
+		t.Fatalf("CheckWith failed: %v", err)
+	}
+	if got.Verdict != SeverityWarning || got.ExitCode != 3 {
+		t.Errorf("CheckWith = verdict %q exit %d, want warning exit 3", got.Verdict, got.ExitCode)
+	}
+}
+
 // TestServer_HandleStop tests the POST /stop endpoint.
 func TestServer_HandleStop(t *testing.T) {
 	socketPath := testSocketPath(t)
@@ -210,7 +271,7 @@ func TestServer_HandleStop(t *testing.T) {
 1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
 `
 	executor := NewFakeExecutor(output, "")
-	r := NewRunner("/workspace", "", executor)
+	r := NewRunner("/workspace", WithExecutor(executor))
 
 	ctx := context.Background()
 	_ = r.Start(ctx)
@@ -218,7 +279,7 @@ func TestServer_HandleStop(t *testing.T) {
 	time.Sleep(50 * time.Millisecond)
 
 	s := NewServer(socketPath, r)
-	err := s.Start()
+	err := s.Start(context.Background())
 	if err != nil {
 		t.Fatalf("Start failed: %v", err)
 	}
@@ -257,7 +318,7 @@ func TestServer_HandleStop(t *testing.T) {
 // TestServer_ShutdownCh tests the ShutdownCh getter.
 func TestServer_ShutdownCh(t *testing.T) {
 	executor := NewFakeExecutor("", "")
-	r := NewRunner("/workspace", "", executor)
+	r := NewRunner("/workspace", WithExecutor(executor))
 	s := NewServer("/tmp/test.sock", r)
 
 	ch := s.ShutdownCh()
@@ -279,7 +340,7 @@ func TestServer_RemovesExistingSocket(t *testing.T) {
 1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
 `
 	executor := NewFakeExecutor(output, "")
-	r := NewRunner("/workspace", "", executor)
+	r := NewRunner("/workspace", WithExecutor(executor))
 
 	ctx := context.Background()
 	_ = r.Start(ctx)
@@ -288,7 +349,7 @@ func TestServer_RemovesExistingSocket(t *testing.T) {
 
 	s := NewServer(socketPath, r)
 
-	err := s.Start()
+	err := s.Start(context.Background())
 	if err != nil {
 		t.Fatalf("Start failed: %v", err)
 	}
@@ -332,7 +393,7 @@ func TestSocketExists(t *testing.T) {
 1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
 `
 	executor := NewFakeExecutor(output, "")
-	r := NewRunner("/workspace", "", executor)
+	r := NewRunner("/workspace", WithExecutor(executor))
 
 	ctx := context.Background()
 	_ = r.Start(ctx)
@@ -340,7 +401,7 @@ func TestSocketExists(t *testing.T) {
 	time.Sleep(50 * time.Millisecond)
 
 	s := NewServer(socketPath, r)
-	err := s.Start()
+	err := s.Start(context.Background())
 	if err != nil {
 		t.Fatalf("Start failed: %v", err)
 	}
@@ -371,6 +432,57 @@ func TestClient_IsServerRunning(t *testing.T) {
 	}
 }
 
+// TestClient_Probe tests that Probe tells a live server from a stale socket
+// and an unresponsive one.
+func TestClient_Probe(t *testing.T) {
+	socketPath := testSocketPath(t)
+	c := &Client{socketPath: socketPath, httpClient: unixHTTPClient(socketPath)}
+	ctx := context.Background()
+
+	if err := c.Probe(ctx); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Probe without a socket = %v, want ErrNotRunning", err)
+	}
+
+	// A server that crashed leaves its socket behind.
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = l.Close()
+	if err := c.Probe(ctx); !errors.Is(err, ErrStaleSocket) {
+		t.Errorf("Probe with a stale socket = %v, want ErrStaleSocket", err)
+	}
+	if SocketExists(socketPath) {
+		t.Error("stale socket not removed")
+	}
+
+	// A hung server accepts connections but never answers.
+	l, err = net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Probe(ctx); !errors.Is(err, ErrNotResponding) {
+		t.Errorf("Probe with a hung server = %v, want ErrNotResponding", err)
+	}
+	if !SocketExists(socketPath) {
+		t.Error("socket of a hung server removed")
+	}
+	_ = l.Close()
+
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(checkOutput("1770255834000", 0), "")))
+	_ = r.Start(ctx)
+	defer r.Stop()
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() { _ = s.Stop(ctx) }()
+	if err := c.Probe(ctx); err != nil || !c.IsServerRunning() {
+		t.Errorf("Probe with a running server = %v, want nil", err)
+	}
+}
+
 // TestClient_Shutdown tests the Shutdown method.
 func TestClient_Shutdown(t *testing.T) {
 	socketPath := testSocketPath(t)
@@ -379,7 +491,7 @@ func TestClient_Shutdown(t *testing.T) {
 1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
 `
 	executor := NewFakeExecutor(output, "")
-	r := NewRunner("/workspace", "", executor)
+	r := NewRunner("/workspace", WithExecutor(executor))
 
 	ctx := context.Background()
 	_ = r.Start(ctx)
@@ -387,7 +499,7 @@ func TestClient_Shutdown(t *testing.T) {
 	time.Sleep(50 * time.Millisecond)
 
 	s := NewServer(socketPath, r)
-	err := s.Start()
+	err := s.Start(context.Background())
 	if err != nil {
 		t.Fatalf("Start failed: %v", err)
 	}
@@ -423,3 +535,462 @@ func TestClient_Shutdown(t *testing.T) {
 
 	_ = s.Stop(context.Background())
 }
+
+// unixHTTPClient returns an HTTP client that dials the given Unix socket.
+func unixHTTPClient(socketPath string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+		Timeout: 5 * time.Second,
+	}
+}
+
+// TestServer_HandleStatus tests GET /status reports the runner state.
+func TestServer_HandleStatus(t *testing.T) {
+	socketPath := testSocketPath(t)
+
+	output := `1770255832071 START "/workspace"
+1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+`
+	executor := NewFakeExecutor(output, "")
+	r := NewRunner("/workspace", WithExecutor(executor))
+
+	ctx := context.Background()
+	_ = r.Start(ctx)
+	defer r.Stop()
+
+	time.Sleep(50 * time.Millisecond)
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	resp, err := unixHTTPClient(socketPath).Get("http://unix/status")
+	if err != nil {
+		t.Fatalf("GET /status failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Status code = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("decode /status: %v", err)
+	}
+	if status.Runner.State != RunnerStateReady {
+		t.Errorf("Runner.State = %q, want %q", status.Runner.State, RunnerStateReady)
+	}
+	if status.Runner.PackageManager != PackageManagerBun {
+		t.Errorf("Runner.PackageManager = %q, want %q", status.Runner.PackageManager, PackageManagerBun)
+	}
+}
+
+// TestServer_SyncFailure tests that a failed svelte-kit sync appears in
+// /status and marks /check results stale.
+func TestServer_SyncFailure(t *testing.T) {
+	socketPath := testSocketPath(t)
+
+	output := `1770255832071 START "/workspace"
+1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+`
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(output, "")))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	syncExecutor := NewFakeExecutor("Error: invalid svelte.config.js\n", "")
+	syncExecutor.currentCmd().waitErr = errors.New("exit status 1")
+	syncs := NewSyncTracker("/workspace", PackageManagerBun, syncExecutor)
+	_ = syncs.Run(context.Background(), ".")
+
+	time.Sleep(50 * time.Millisecond)
+
+	s := NewServer(socketPath, r)
+	s.SetSyncTracker(syncs)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	client := unixHTTPClient(socketPath)
+
+	resp, err := client.Get("http://unix/status")
+	if err != nil {
+		t.Fatalf("GET /status failed: %v", err)
+	}
+	var status Status
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatalf("decode /status: %v", err)
+	}
+	if len(status.Sync) != 1 || status.Sync[0].OK || status.Sync[0].Output != "Error: invalid svelte.config.js" {
+		t.Errorf("Sync = %+v, want the failed sync with its output", status.Sync)
+	}
+
+	resp, err = client.Get("http://unix/check?format=json")
+	if err != nil {
+		t.Fatalf("GET /check failed: %v", err)
+	}
+	var result SvelteWatchCheckComplete
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatalf("decode /check: %v", err)
+	}
+	if !result.Stale || result.StaleReason != "svelte-kit sync failed in ." {
+		t.Errorf("Stale = %v (%q), want stale after a failed sync", result.Stale, result.StaleReason)
+	}
+}
+
+// TestServer_HandleCheck_Stale tests that ?stale=true serves a result
+// persisted by a previous server instead of waiting for the first check.
+func TestServer_HandleCheck_Stale(t *testing.T) {
+	socketPath := testSocketPath(t)
+	stateFile := socketPath + StateFileSuffix
+	t.Cleanup(func() { _ = os.Remove(stateFile) })
+	resultStore{path: stateFile, workspace: "/workspace"}.save(SvelteWatchCheckComplete{
+		FileCount:   100,
+		ErrorCount:  1,
+		Diagnostics: []Diagnostic{{Type: "ERROR", Filename: "src/a.ts", Message: "Test error"}},
+	})
+
+	// The check never completes, so a plain /check would block.
+	executor := NewFakeExecutor(`1770255832071 START "/workspace"
+`, "")
+	r := NewRunnerWithConfig(RunnerConfig{WorkspacePath: "/workspace", StateFile: stateFile}, WithExecutor(executor))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	resp, err := unixHTTPClient(socketPath).Get("http://unix/check?stale=true&format=json")
+	if err != nil {
+		t.Fatalf("GET /check?stale=true failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d for a result with errors", resp.StatusCode, http.StatusInternalServerError)
+	}
+	var result SvelteWatchCheckComplete
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("decode /check: %v", err)
+	}
+	if result.ErrorCount != 1 || !result.Stale || result.StaleReason != staleReasonPersisted {
+		t.Errorf("result = %+v, want the persisted result marked stale", result)
+	}
+}
+
+// TestServer_HandleCheck_Freshness tests that results carry when they were
+// checked and whether a newer check is running.
+func TestServer_HandleCheck_Freshness(t *testing.T) {
+	socketPath := testSocketPath(t)
+
+	// The second check never completes.
+	executor := NewFakeExecutor(`1770255832071 START "/workspace"
+1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+1770255844663 START "/workspace"
+`, "")
+	r := NewRunner("/workspace", WithExecutor(executor))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+	time.Sleep(50 * time.Millisecond)
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	resp, err := unixHTTPClient(socketPath).Get("http://unix/check?stale=true&format=json")
+	if err != nil {
+		t.Fatalf("GET /check failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var result SvelteWatchCheckComplete
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("decode /check: %v", err)
+	}
+	if want := time.UnixMilli(1770255834342); !result.CheckedAt.Equal(want) {
+		t.Errorf("CheckedAt = %v, want %v", result.CheckedAt, want)
+	}
+	if result.AgeSeconds <= 0 {
+		t.Errorf("AgeSeconds = %v, want the time since CheckedAt", result.AgeSeconds)
+	}
+	if !result.InProgress || !result.Stale {
+		t.Errorf("InProgress = %v, Stale = %v; want both while the next check runs", result.InProgress, result.Stale)
+	}
+}
+
+// TestServer_HandleRestart tests that POST /restart starts a new process and
+// rejects unknown projects.
+func TestServer_HandleRestart(t *testing.T) {
+	socketPath := testSocketPath(t)
+
+	executor := NewFakeExecutor("", "")
+	executor.newCmd = func() *FakeCmd { return newFakeCmd("") }
+	r := NewRunner("/workspace", WithExecutor(executor))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+	first := executor.currentCmd()
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	c := &Client{socketPath: socketPath, httpClient: unixHTTPClient(socketPath)}
+	if err := c.Restart(context.Background(), ""); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if !first.isStopped() {
+		t.Error("the original process was not stopped")
+	}
+	if executor.currentCmd() == first || !executor.currentCmd().isStarted() {
+		t.Error("no new process was started")
+	}
+
+	if err := c.Restart(context.Background(), "missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Restart(missing) error = %v, want a 404", err)
+	}
+}
+
+// TestServer_HandleCheck_ClientGone tests that a /check waiting for a check
+// in progress ends when its client disconnects, so shutdown is not held up.
+func TestServer_HandleCheck_ClientGone(t *testing.T) {
+	socketPath := testSocketPath(t)
+
+	// The check never completes.
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(`1770255832071 START "/workspace"
+`, "")))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	client := unixHTTPClient(socketPath)
+	client.Timeout = 50 * time.Millisecond
+	if resp, err := client.Get("http://unix/check"); err == nil {
+		_ = resp.Body.Close()
+		t.Fatal("GET /check returned, want it to wait for the check")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Errorf("Stop failed: %v (handler still waiting after the client left)", err)
+	}
+}
+
+// TestServer_HandleLastCrash_NotFound tests GET /last-crash before any crash.
+func TestServer_HandleLastCrash_NotFound(t *testing.T) {
+	socketPath := testSocketPath(t)
+
+	executor := NewFakeExecutor("", "")
+	r := NewRunner("/workspace", WithExecutor(executor))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	resp, err := unixHTTPClient(socketPath).Get("http://unix/last-crash")
+	if err != nil {
+		t.Fatalf("GET /last-crash failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Status code = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+// TestServer_HandleMetrics tests GET /metrics serves Prometheus text.
+func TestServer_HandleMetrics(t *testing.T) {
+	socketPath := testSocketPath(t)
+
+	executor := NewFakeExecutor("", "")
+	r := NewRunner("/workspace", WithExecutor(executor))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	resp, err := unixHTTPClient(socketPath).Get("http://unix/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read /metrics: %v", err)
+	}
+	for _, want := range []string{
+		"# TYPE svelte_check_server_auto_restarts_total counter\n",
+		"svelte_check_server_memory_restarts_total 0\n",
+		`svelte_check_server_runner_state{state="starting"} 1` + "\n",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("/metrics missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+// TestWriteMetrics_Timings tests the check duration summary and gauges.
+func TestWriteMetrics_Timings(t *testing.T) {
+	var sb strings.Builder
+	writeMetrics(&sb, Status{Runner: RunnerStatus{
+		State:   RunnerStateReady,
+		Timings: &CheckTimings{Count: 4, LastMs: 1500, P50Ms: 1200, P95Ms: 2500, MaxMs: 2500, TotalMs: 6000},
+	}})
+
+	for _, want := range []string{
+		"# TYPE svelte_check_server_check_duration_seconds summary\n",
+		`svelte_check_server_check_duration_seconds{quantile="0.5"} 1.2` + "\n",
+		`svelte_check_server_check_duration_seconds{quantile="0.95"} 2.5` + "\n",
+		"svelte_check_server_check_duration_seconds_sum 6\n",
+		"svelte_check_server_check_duration_seconds_count 4\n",
+		"svelte_check_server_last_check_duration_seconds 1.5\n",
+		"svelte_check_server_max_check_duration_seconds 2.5\n",
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("metrics missing %q in:\n%s", want, sb.String())
+		}
+	}
+}
+
+// TestWriteMetrics_Watcher tests the watcher counters.
+func TestWriteMetrics_Watcher(t *testing.T) {
+	var sb strings.Builder
+	writeMetrics(&sb, Status{Runner: RunnerStatus{State: RunnerStateReady}, Watcher: &WatcherStatus{
+		Restarts: 2, Events: 40, Dropped: 5, Overflows: 1, Rescans: 3, SyncTriggers: 4, RestartTriggers: 6, Watches: 120,
+	}})
+
+	for _, want := range []string{
+		"# TYPE svelte_check_server_watcher_events_total counter\n",
+		"svelte_check_server_watcher_events_total 40\n",
+		"svelte_check_server_watcher_dropped_events_total 5\n",
+		"svelte_check_server_watcher_overflows_total 1\n",
+		"svelte_check_server_watcher_rescans_total 3\n",
+		"svelte_check_server_watcher_sync_triggers_total 4\n",
+		"svelte_check_server_watcher_restart_triggers_total 6\n",
+		"svelte_check_server_watcher_restarts_total 2\n",
+		"svelte_check_server_watcher_watches 120\n",
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("metrics missing %q in:\n%s", want, sb.String())
+		}
+	}
+}
+
+// TestServer_HandleCheck_Projects tests merged results and ?project= filtering.
+func TestServer_HandleCheck_Projects(t *testing.T) {
+	socketPath := testSocketPath(t)
+
+	appOutput := `1770255832071 START "/workspace"
+1770255834342 {"type":"ERROR","filename":"src/a.ts","start":{"line":0,"character":0},"end":{"line":0,"character":1},"message":"App error","code":2322}
+1770255834342 COMPLETED 100 FILES 1 ERRORS 0 WARNINGS 1 FILES_WITH_PROBLEMS
+`
+	nodeOutput := `1770255832071 START "/workspace"
+1770255834342 COMPLETED 2 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+`
+	app := NewRunner("/workspace/apps/web", WithExecutor(NewFakeExecutor(appOutput, "")))
+	node := NewRunner("/workspace", WithTsconfig("tsconfig.node.json"), WithExecutor(NewFakeExecutor(nodeOutput, "")))
+	_ = app.Start(context.Background())
+	defer app.Stop()
+	_ = node.Start(context.Background())
+	defer node.Stop()
+
+	time.Sleep(50 * time.Millisecond)
+
+	s := NewProjectServer(socketPath, []Project{
+		{Name: "web", Dir: "apps/web", Runner: app},
+		{Name: "node", Runner: node},
+	})
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	client := unixHTTPClient(socketPath)
+	get := func(path string) (int, SvelteWatchCheckComplete) {
+		t.Helper()
+		resp, err := client.Get("http://unix" + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var result SvelteWatchCheckComplete
+		if resp.StatusCode != http.StatusNotFound {
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatalf("decode %s: %v", path, err)
+			}
+		}
+		return resp.StatusCode, result
+	}
+
+	code, merged := get("/check?format=json")
+	if code != http.StatusInternalServerError {
+		t.Errorf("merged status = %d, want %d", code, http.StatusInternalServerError)
+	}
+	if merged.FileCount != 102 || merged.ErrorCount != 1 {
+		t.Errorf("merged = %d files, %d errors; want 102, 1", merged.FileCount, merged.ErrorCount)
+	}
+	if len(merged.Diagnostics) != 1 || merged.Diagnostics[0].Filename != "apps/web/src/a.ts" || merged.Diagnostics[0].Project != "web" {
+		t.Errorf("merged diagnostics = %+v, want qualified web diagnostic", merged.Diagnostics)
+	}
+
+	code, only := get("/check?format=json&project=node")
+	if code != http.StatusOK || only.FileCount != 2 {
+		t.Errorf("project=node: status %d, %d files; want 200, 2", code, only.FileCount)
+	}
+
+	if code, _ := get("/check?project=nope"); code != http.StatusNotFound {
+		t.Errorf("unknown project status = %d, want %d", code, http.StatusNotFound)
+	}
+
+	if status := s.Status(); len(status.Projects) != 2 || status.Projects[0].Name != "web" {
+		t.Errorf("Status().Projects = %+v, want web and node", status.Projects)
+	}
+}
@@ -0,0 +1,319 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// Socket Activation
+// =============================================================================
+
+// activationFD is the first file descriptor systemd passes to a
+// socket-activated service.
+const activationFD = 3
+
+// ActivationListener returns the socket systemd passed this process under
+// socket activation, or nil if it passed none. The LISTEN_* variables are
+// unset so that checkers started later do not see them.
+func ActivationListener() (net.Listener, error) {
+	pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	fds, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	for _, name := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		_ = os.Unsetenv(name)
+	}
+	if pid != os.Getpid() || fds < 1 {
+		return nil, nil
+	}
+	f := os.NewFile(activationFD, "systemd socket")
+	defer func() { _ = f.Close() }()
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("socket from systemd: %w", err)
+	}
+	return l, nil
+}
+
+// SetListener serves on l, e.g. a socket passed by systemd, in place of
+// creating the socket; the socket file is left in place on Stop. Call it
+// before Start.
+func (s *Server) SetListener(l net.Listener) {
+	s.listener = l
+}
+
+// =============================================================================
+// Service Units
+// =============================================================================
+
+// ServiceConfig describes the daemon of a service unit.
+type ServiceConfig struct {
+	Workspace string // absolute path of the workspace
+	Binary    string // absolute path of svelte-check-server
+	Socket    string // the workspace's socket, which systemd listens on
+	Path      string // PATH of the daemon, to find node and the package manager
+}
+
+// ServiceFile is a file a service manager loads.
+type ServiceFile struct {
+	Path    string
+	Content string
+}
+
+// serviceName returns the name of the units of workspace: its path with
+// slashes replaced by dashes, as for its socket, and other characters unit
+// names cannot hold replaced by underscores.
+func serviceName(workspace string) string {
+	slug := strings.ReplaceAll(strings.TrimPrefix(filepath.Clean(workspace), "/"), "/", "-")
+	slug = strings.Map(func(r rune) rune {
+		if r < 0x80 && (r == '-' || r == '_' || r == '.' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, slug)
+	return "svelte-check-server-" + slug
+}
+
+// SystemdUnits returns a service and a socket unit in dir for c. systemd
+// listens on the workspace's socket and starts the daemon on the first
+// connection, and again after it is stopped.
+func SystemdUnits(c ServiceConfig, dir string) []ServiceFile {
+	name := serviceName(c.Workspace)
+	service := fmt.Sprintf(`# Written by svelte-check-server service install.
+[Unit]
+Description=svelte-check-server for %[1]s
+Requires=%[2]s.socket
+
+[Service]
+ExecStart=%[3]s start -w %[4]s
+WorkingDirectory=%[5]s
+Environment=%[6]s
+Restart=on-failure
+RestartSec=5
+`, c.Workspace, name, systemdQuote(c.Binary), systemdQuote(c.Workspace), systemdEscape(c.Workspace), systemdQuote("PATH="+c.Path))
+	socket := fmt.Sprintf(`# Written by svelte-check-server service install.
+[Unit]
+Description=svelte-check-server socket for %[1]s
+
+[Socket]
+ListenStream=%[2]s
+SocketMode=0600
+
+[Install]
+WantedBy=sockets.target
+`, c.Workspace, systemdEscape(c.Socket))
+	return []ServiceFile{
+		{Path: filepath.Join(dir, name+".service"), Content: service},
+		{Path: filepath.Join(dir, name+".socket"), Content: socket},
+	}
+}
+
+// systemdEscape escapes the specifiers of a unit file value.
+func systemdEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// systemdQuote quotes s as a single word of a command line or assignment.
+func systemdQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", "$$").Replace(systemdEscape(s))
+	return `"` + s + `"`
+}
+
+// launchdLabel returns the label of workspace's launchd agent.
+func launchdLabel(workspace string) string {
+	return "com.github.tylergannon." + serviceName(workspace)
+}
+
+// LaunchdPlist returns a launchd agent in dir for c, which starts the daemon
+// at login and again if it fails, logging to logDir. launchd cannot pass a
+// socket to a program that does not link against launchd, so the daemon
+// creates its own.
+func LaunchdPlist(c ServiceConfig, dir, logDir string) ServiceFile {
+	label := launchdLabel(c.Workspace)
+	content := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<!-- Written by svelte-check-server service install. -->
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%[1]s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%[2]s</string>
+		<string>start</string>
+		<string>-w</string>
+		<string>%[3]s</string>
+	</array>
+	<key>WorkingDirectory</key>
+	<string>%[3]s</string>
+	<key>EnvironmentVariables</key>
+	<dict>
+		<key>PATH</key>
+		<string>%[4]s</string>
+	</dict>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>StandardErrorPath</key>
+	<string>%[5]s</string>
+</dict>
+</plist>
+`, xmlEscape(label), xmlEscape(c.Binary), xmlEscape(c.Workspace), xmlEscape(c.Path),
+		xmlEscape(filepath.Join(logDir, serviceName(c.Workspace)+".log")))
+	return ServiceFile{Path: filepath.Join(dir, label+".plist"), Content: content}
+}
+
+// xmlEscape escapes s for XML character data.
+func xmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// =============================================================================
+// Service Managers
+// =============================================================================
+
+// Services installs and uninstalls daemons as services of the user's
+// session: systemd user units on Linux, launchd agents on macOS.
+type Services struct {
+	goos     string
+	home     string
+	uid      int
+	executor kexec.Interface
+}
+
+// NewServices returns the Services of the current user and OS.
+func NewServices(executor kexec.Interface) (*Services, error) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		return nil, fmt.Errorf("services are not supported on %s", runtime.GOOS)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Services{goos: runtime.GOOS, home: home, uid: os.Getuid(), executor: executor}, nil
+}
+
+// Install writes the units of c, replacing any it has, and loads them:
+// systemd then listens on the workspace's socket, and launchd starts the
+// daemon. It returns the files written. A daemon already serving the
+// workspace outside the service manager must be stopped first.
+func (s *Services) Install(ctx context.Context, c ServiceConfig) ([]string, error) {
+	files := s.files(c)
+	if _, err := os.Stat(files[0].Path); err == nil {
+		s.unload(ctx, c.Workspace)
+	}
+	if err := removeStaleSocket(c.Socket); err != nil {
+		return nil, fmt.Errorf("%w; stop it first", err)
+	}
+
+	var paths []string
+	for _, f := range files {
+		if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+			return paths, err
+		}
+		if err := os.WriteFile(f.Path, []byte(f.Content), 0o644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, f.Path)
+	}
+
+	name := serviceName(c.Workspace)
+	switch s.goos {
+	case "darwin":
+		if err := os.MkdirAll(s.logDir(), 0o755); err != nil {
+			return paths, err
+		}
+		return paths, s.run(ctx, "launchctl", "bootstrap", s.domain(), files[0].Path)
+	default:
+		if err := s.run(ctx, "systemctl", "--user", "daemon-reload"); err != nil {
+			return paths, err
+		}
+		return paths, s.run(ctx, "systemctl", "--user", "enable", "--now", name+".socket")
+	}
+}
+
+// Uninstall stops the daemon of workspace and removes its units, returning
+// the files removed.
+func (s *Services) Uninstall(ctx context.Context, workspace string) ([]string, error) {
+	files := s.files(ServiceConfig{Workspace: workspace})
+	s.unload(ctx, workspace)
+
+	var paths []string
+	for _, f := range files {
+		if err := os.Remove(f.Path); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return paths, err
+		}
+		paths = append(paths, f.Path)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no service installed for %s", workspace)
+	}
+	if s.goos == "linux" {
+		return paths, s.run(ctx, "systemctl", "--user", "daemon-reload")
+	}
+	return paths, nil
+}
+
+// files returns the units of c: the plist first on macOS, the service
+// first on Linux.
+func (s *Services) files(c ServiceConfig) []ServiceFile {
+	if s.goos == "darwin" {
+		return []ServiceFile{LaunchdPlist(c, filepath.Join(s.home, "Library", "LaunchAgents"), s.logDir())}
+	}
+	return SystemdUnits(c, filepath.Join(s.home, ".config", "systemd", "user"))
+}
+
+// logDir is where launchd agents log.
+func (s *Services) logDir() string {
+	return filepath.Join(s.home, "Library", "Logs", "svelte-check-server")
+}
+
+// domain is the launchd domain of the user's agents.
+func (s *Services) domain() string {
+	return "gui/" + strconv.Itoa(s.uid)
+}
+
+// unload stops the daemon of workspace and unloads its units. Failures are
+// logged, since the units may not be loaded.
+func (s *Services) unload(ctx context.Context, workspace string) {
+	log := logger("service")
+	name := serviceName(workspace)
+	var commands [][]string
+	if s.goos == "darwin" {
+		commands = [][]string{{"launchctl", "bootout", s.domain() + "/" + launchdLabel(workspace)}}
+	} else {
+		commands = [][]string{
+			{"systemctl", "--user", "disable", "--now", name + ".socket"},
+			{"systemctl", "--user", "stop", name + ".service"},
+		}
+	}
+	for _, c := range commands {
+		if err := s.run(ctx, c[0], c[1:]...); err != nil {
+			log.Debug("unloading service", "error", err)
+		}
+	}
+}
+
+// run runs a service manager command, returning its output in the error if
+// it fails.
+func (s *Services) run(ctx context.Context, name string, args ...string) error {
+	out, err := s.executor.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
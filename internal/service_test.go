@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSystemdUnits(t *testing.T) {
+	files := SystemdUnits(ServiceConfig{
+		Workspace: "/home/me/100% app",
+		Binary:    "/usr/local/bin/svelte-check-server",
+		Socket:    "/tmp/home-me-100% app-svelte-check.sock",
+		Path:      "/usr/bin:$HOME/bin",
+	}, "/units")
+
+	if files[0].Path != "/units/svelte-check-server-home-me-100__app.service" || files[1].Path != "/units/svelte-check-server-home-me-100__app.socket" {
+		t.Errorf("paths = %s, %s", files[0].Path, files[1].Path)
+	}
+	for _, want := range []string{
+		`ExecStart="/usr/local/bin/svelte-check-server" start -w "/home/me/100%% app"`,
+		`WorkingDirectory=/home/me/100%% app`,
+		`Environment="PATH=/usr/bin:$$HOME/bin"`,
+		`Requires=svelte-check-server-home-me-100__app.socket`,
+	} {
+		if !strings.Contains(files[0].Content, want) {
+			t.Errorf("service lacks %q:\n%s", want, files[0].Content)
+		}
+	}
+	if want := "ListenStream=/tmp/home-me-100%% app-svelte-check.sock\n"; !strings.Contains(files[1].Content, want) {
+		t.Errorf("socket lacks %q:\n%s", want, files[1].Content)
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	f := LaunchdPlist(ServiceConfig{
+		Workspace: "/Users/me/a&b",
+		Binary:    "/opt/homebrew/bin/svelte-check-server",
+		Path:      "/opt/homebrew/bin:/usr/bin",
+	}, "/agents", "/logs")
+
+	if f.Path != "/agents/com.github.tylergannon.svelte-check-server-Users-me-a_b.plist" {
+		t.Errorf("path = %s", f.Path)
+	}
+	for _, want := range []string{
+		"<string>com.github.tylergannon.svelte-check-server-Users-me-a_b</string>",
+		"<string>/Users/me/a&amp;b</string>",
+		"<string>/logs/svelte-check-server-Users-me-a_b.log</string>",
+	} {
+		if !strings.Contains(f.Content, want) {
+			t.Errorf("plist lacks %q:\n%s", want, f.Content)
+		}
+	}
+}
+
+// TestServices tests that install writes and loads the units, and uninstall
+// removes them.
+func TestServices(t *testing.T) {
+	for _, tc := range []struct {
+		goos    string
+		file    string
+		install string
+	}{
+		{"linux", ".config/systemd/user/svelte-check-server-ws.socket", "systemctl --user enable --now svelte-check-server-ws.socket"},
+		{"darwin", "Library/LaunchAgents/com.github.tylergannon.svelte-check-server-ws.plist", "launchctl bootstrap gui/501 "},
+	} {
+		t.Run(tc.goos, func(t *testing.T) {
+			home := t.TempDir()
+			executor := NewFakeExecutor("", "")
+			executor.newCmd = func() *FakeCmd { return &FakeCmd{} }
+			s := &Services{goos: tc.goos, home: home, uid: 501, executor: executor}
+			c := ServiceConfig{Workspace: "/ws", Binary: "/bin/scs", Socket: filepath.Join(home, "ws.sock")}
+			ctx := context.Background()
+
+			files, err := s.Install(ctx, c)
+			if err != nil {
+				t.Fatalf("Install failed: %v", err)
+			}
+			if !strings.HasPrefix(executor.commandLine(), tc.install) {
+				t.Errorf("Install ran %q, want %q", executor.commandLine(), tc.install)
+			}
+			path := filepath.Join(home, tc.file)
+			if _, err := os.Stat(path); err != nil || !strings.Contains(strings.Join(files, " "), path) {
+				t.Errorf("Install wrote %v, want %s: %v", files, path, err)
+			}
+
+			if _, err := s.Uninstall(ctx, "/ws"); err != nil {
+				t.Fatalf("Uninstall failed: %v", err)
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("Uninstall left %s", path)
+			}
+			if _, err := s.Uninstall(ctx, "/ws"); err == nil {
+				t.Error("Uninstall without a service succeeded")
+			}
+		})
+	}
+}
+
+// TestServer_SetListener tests that a server passed a listener serves on
+// it and leaves the socket to its owner.
+func TestServer_SetListener(t *testing.T) {
+	socketPath := testSocketPath(t)
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false) // as for one from systemd
+	executor := NewFakeExecutor(checkOutput("1770255834000", 0), "")
+	s := NewServer(socketPath, NewRunner("/workspace", WithExecutor(executor)))
+	s.SetListener(l)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	c := &Client{socketPath: socketPath, httpClient: unixHTTPClient(socketPath)}
+	if _, err := c.Status(context.Background()); err != nil {
+		t.Errorf("Status() = %v", err)
+	}
+	_ = s.Stop(context.Background())
+	if !SocketExists(socketPath) {
+		t.Error("Stop removed a socket it did not create")
+	}
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"context"
+	"sync"
+)
+
+// =============================================================================
+// Signals
+// =============================================================================
+
+// valueSignal holds a value that readers wait for until it is set. Unlike a
+// sync.Cond, a wait ends when its context is done without leaving a
+// goroutine behind, so abandoned /check requests do not leak.
+type valueSignal[T any] struct {
+	mu    sync.Mutex
+	value T
+	valid bool
+	set   chan struct{} // closed when a value is set; replaced by Invalidate
+}
+
+func newValueSignal[T any]() *valueSignal[T] {
+	return &valueSignal[T]{set: make(chan struct{})}
+}
+
+// Set stores value and wakes every waiting Wait.
+func (s *valueSignal[T]) Set(value T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.value = value
+	if !s.valid {
+		s.valid = true
+		close(s.set)
+	}
+}
+
+// Invalidate clears the value, so that Wait blocks until the next Set.
+func (s *valueSignal[T]) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var zero T
+	s.value = zero
+	if s.valid {
+		s.valid = false
+		s.set = make(chan struct{})
+	}
+}
+
+// Wait returns the value once it is set, or ctx's cause if ctx is done
+// first.
+func (s *valueSignal[T]) Wait(ctx context.Context) (T, error) {
+	var zero T
+	for {
+		if err := context.Cause(ctx); err != nil {
+			return zero, err
+		}
+		s.mu.Lock()
+		value, valid, set := s.value, s.valid, s.set
+		s.mu.Unlock()
+		if valid {
+			return value, nil
+		}
+		select {
+		case <-set:
+		case <-ctx.Done():
+		}
+	}
+}
@@ -156,3 +156,41 @@ func TestSocketPathForWorkspace_DeepNesting(t *testing.T) {
 		t.Errorf("Deep nesting slug incorrect: got %q, want %q", filename, expected)
 	}
 }
+
+// TestFindWorkspace tests that a subdirectory resolves to the workspace of a
+// running server above it, or else to its nearest project root.
+func TestFindWorkspace(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"package.json":                    "{}",
+		"packages/ui/package.json":        "{}",
+		"packages/ui/src/routes/+page.ts": "",
+	})
+	routes := filepath.Join(root, "packages", "ui", "src", "routes")
+
+	got, err := FindWorkspace(routes)
+	if err != nil {
+		t.Fatalf("FindWorkspace failed: %v", err)
+	}
+	if want := filepath.Join(root, "packages", "ui"); got != want {
+		t.Errorf("FindWorkspace() without a server = %q, want the nearest package %q", got, want)
+	}
+
+	// A server at the root wins over the nearer package.
+	socketPath, err := SocketPathForWorkspace(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(socketPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Remove(socketPath) })
+
+	c, err := NewClient(routes)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if c.Workspace() != root || c.SocketPath() != socketPath {
+		t.Errorf("NewClient() resolved to %q (%s), want the server's workspace %q", c.Workspace(), c.SocketPath(), root)
+	}
+}
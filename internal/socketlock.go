@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// =============================================================================
+// Socket Lock
+// =============================================================================
+
+// socketLockPath returns the lock file that guards socketPath.
+func socketLockPath(socketPath string) string {
+	return socketPath + ".lock"
+}
+
+// claimSocket makes socketPath free for a new daemon and returns the lock
+// that keeps it so; closing the lock releases it. The lock is an flock on a
+// file next to the socket holding the daemon's PID, so it is released even
+// if the daemon crashes, and two starts cannot both claim the socket. The
+// file is left in place when the daemon stops: removing it would let a start
+// lock the removed file while another locks a new one.
+//
+// A socket that refuses connections was left by a daemon that exited
+// without removing it; it is removed and the reclaim logged. A socket that
+// accepts connections belongs to a running daemon, perhaps one that
+// predates the lock.
+func claimSocket(socketPath string) (*os.File, error) {
+	lock, err := lockSocket(socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := removeStaleSocket(socketPath); err != nil {
+		_ = lock.Close()
+		return nil, err
+	}
+	return lock, nil
+}
+
+// lockSocket takes the lock of claimSocket without touching the socket,
+// for a daemon passed a listener on it by systemd, which accepts
+// connections on its behalf while it is not running.
+func lockSocket(socketPath string) (*os.File, error) {
+	lock, err := os.OpenFile(socketLockPath(socketPath), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		pid, _ := os.ReadFile(lock.Name())
+		_ = lock.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("server already running (pid %s holds %s)", strings.TrimSpace(string(pid)), lock.Name())
+		}
+		return nil, fmt.Errorf("locking %s: %w", lock.Name(), err)
+	}
+
+	if err := lock.Truncate(0); err == nil {
+		_, _ = lock.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return lock, nil
+}
+
+// removeStaleSocket removes socketPath if no server accepts connections on
+// it, and fails if one does.
+func removeStaleSocket(socketPath string) error {
+	if !SocketExists(socketPath) {
+		return nil
+	}
+	conn, err := net.DialTimeout("unix", socketPath, serverProbeTimeout)
+	switch {
+	case err == nil:
+		_ = conn.Close()
+		return fmt.Errorf("server already running (socket exists at %s)", socketPath)
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, os.ErrNotExist):
+		if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing stale socket: %w", err)
+		}
+		logger("daemon").Info("reclaimed stale socket left by a server that exited", "socket", socketPath)
+		return nil
+	default:
+		return fmt.Errorf("probing existing socket %s: %w", socketPath, err)
+	}
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestClaimSocket tests that a socket left by a crashed server is reclaimed,
+// while a live server's socket and a held lock are respected.
+func TestClaimSocket(t *testing.T) {
+	socketPath := testSocketPath(t)
+	t.Cleanup(func() { _ = os.Remove(socketLockPath(socketPath)) })
+
+	// A listener closed without unlinking leaves the socket file behind, as
+	// a crash would.
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = l.Close()
+	if !SocketExists(socketPath) {
+		t.Fatal("socket was removed on close")
+	}
+
+	lock, err := claimSocket(socketPath)
+	if err != nil {
+		t.Fatalf("claimSocket with a stale socket failed: %v", err)
+	}
+	if SocketExists(socketPath) {
+		t.Error("the stale socket was not removed")
+	}
+
+	if _, err := claimSocket(socketPath); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("claimSocket while locked = %v, want already running", err)
+	}
+	_ = lock.Close()
+
+	live, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = live.Close() }()
+	if _, err := claimSocket(socketPath); err == nil || !strings.Contains(err.Error(), "socket exists") {
+		t.Errorf("claimSocket with a live server = %v, want socket exists", err)
+	}
+	if !SocketExists(socketPath) {
+		t.Error("a live server's socket was removed")
+	}
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// Persisted Results
+// =============================================================================
+
+// StateFileSuffix is appended to the socket path to name the file the latest
+// result is persisted to, e.g. /tmp/<slug>-svelte-check.sock.state.json.
+const StateFileSuffix = ".state.json"
+
+// ResultPeeker is implemented by checkers that can return their most recent
+// result without waiting for a check in progress. See Runner.PeekLatest.
+type ResultPeeker interface {
+	PeekLatest() (result SvelteWatchCheckComplete, ok bool)
+}
+
+// staleReasonPersisted marks a result loaded from a previous daemon's state file.
+const staleReasonPersisted = "result from before the server restarted"
+
+// persistedResult is the content of a state file.
+type persistedResult struct {
+	Workspace string                   `json:"workspace"`
+	SavedAt   time.Time                `json:"savedAt"`
+	Result    SvelteWatchCheckComplete `json:"result"`
+}
+
+// resultStore saves a checker's latest result so the next daemon can serve it
+// before its first check completes. The zero value persists nothing.
+type resultStore struct {
+	path      string
+	workspace string
+}
+
+// load returns the persisted result, marked stale. A missing file, or one
+// written for a different workspace, yields ok == false.
+func (s resultStore) load() (result SvelteWatchCheckComplete, ok bool) {
+	if s.path == "" {
+		return result, false
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger("state").Warn("could not read persisted result", "path", s.path, "error", err)
+		}
+		return result, false
+	}
+
+	var state persistedResult
+	if err := json.Unmarshal(data, &state); err != nil {
+		logger("state").Warn("ignoring persisted result", "path", s.path, "error", err)
+		return result, false
+	}
+	if state.Workspace != s.workspace {
+		return result, false
+	}
+	state.Result.Stale = true
+	state.Result.StaleReason = staleReasonPersisted
+	return state.Result, true
+}
+
+// save writes result to the state file, replacing it atomically so a reader
+// never sees a partial file. Failures are logged, not returned: persistence
+// only speeds up the next start.
+func (s resultStore) save(result SvelteWatchCheckComplete) {
+	if s.path == "" {
+		return
+	}
+	if err := s.write(result); err != nil {
+		logger("state").Warn("could not save result", "path", s.path, "error", err)
+	}
+}
+
+func (s resultStore) write(result SvelteWatchCheckComplete) error {
+	data, err := json.Marshal(persistedResult{
+		Workspace: s.workspace,
+		SavedAt:   time.Now(),
+		Result:    result,
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
+
+// unsafeStateKey matches characters not kept in state file names.
+var unsafeStateKey = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// QualifyStateFile derives the state file of one project or checker from
+// path, e.g. x.sock.state.json with key "tsc" becomes x.sock.tsc.state.json.
+// An empty path stays empty.
+func QualifyStateFile(path, key string) string {
+	if path == "" || key == "" {
+		return path
+	}
+	key = unsafeStateKey.ReplaceAllString(key, "_")
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(path, StateFileSuffix), key, StateFileSuffix)
+}
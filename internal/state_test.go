@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestResultStore_SaveLoad tests that a saved result loads back marked stale.
+func TestResultStore_SaveLoad(t *testing.T) {
+	store := resultStore{path: filepath.Join(t.TempDir(), "x.sock"+StateFileSuffix), workspace: "/workspace"}
+	store.save(SvelteWatchCheckComplete{
+		Timestamp:   1770255834342,
+		ErrorCount:  1,
+		Diagnostics: []Diagnostic{{Type: "ERROR", Filename: "src/a.ts", Message: "Test error"}},
+	})
+
+	got, ok := store.load()
+	if !ok {
+		t.Fatal("load() ok = false, want the saved result")
+	}
+	if got.ErrorCount != 1 || len(got.Diagnostics) != 1 || got.Diagnostics[0].Filename != "src/a.ts" {
+		t.Errorf("load() = %+v, want the saved result", got)
+	}
+	if !got.Stale || got.StaleReason != staleReasonPersisted {
+		t.Errorf("Stale = %v (%q), want stale with %q", got.Stale, got.StaleReason, staleReasonPersisted)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(store.path))
+	if len(entries) != 1 {
+		t.Errorf("state dir has %d files, want only the state file", len(entries))
+	}
+}
+
+// TestResultStore_Load_Ignored tests the cases where nothing is loaded.
+func TestResultStore_Load_Ignored(t *testing.T) {
+	dir := t.TempDir()
+
+	other := resultStore{path: filepath.Join(dir, "other.json"), workspace: "/elsewhere"}
+	other.save(SvelteWatchCheckComplete{ErrorCount: 1})
+	corrupt := filepath.Join(dir, "corrupt.json")
+	if err := os.WriteFile(corrupt, []byte("{"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	for name, store := range map[string]resultStore{
+		"disabled":            {},
+		"missing":             {path: filepath.Join(dir, "missing.json"), workspace: "/workspace"},
+		"corrupt":             {path: corrupt, workspace: "/workspace"},
+		"different workspace": {path: other.path, workspace: "/workspace"},
+	} {
+		if got, ok := store.load(); ok {
+			t.Errorf("%s: load() = %+v, want nothing", name, got)
+		}
+	}
+}
+
+// TestQualifyStateFile tests deriving per-project and per-checker state files.
+func TestQualifyStateFile(t *testing.T) {
+	tests := []struct {
+		path, key, want string
+	}{
+		{"/tmp/x.sock.state.json", "tsc", "/tmp/x.sock.tsc.state.json"},
+		{"/tmp/x.sock.state.json", "@acme/web", "/tmp/x.sock._acme_web.state.json"},
+		{"/tmp/x.sock.state.json", "", "/tmp/x.sock.state.json"},
+		{"", "tsc", ""},
+	}
+	for _, tt := range tests {
+		if got := QualifyStateFile(tt.path, tt.key); got != tt.want {
+			t.Errorf("QualifyStateFile(%q, %q) = %q, want %q", tt.path, tt.key, got, tt.want)
+		}
+	}
+}
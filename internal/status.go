@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// Status Formatting
+// =============================================================================
+
+// FormatStatus formats a Status for the status command.
+func FormatStatus(status Status) string {
+	var sb strings.Builder
+	writeRunnerStatus(&sb, "", status.Runner)
+	if status.Git != nil {
+		fmt.Fprintf(&sb, "Git:        %s\n", status.Git)
+	}
+
+	if w := status.Watcher; w != nil {
+		fmt.Fprintf(&sb, "Watcher:    %d restarts on changes (%d suppressed by cooldown)\n", w.Restarts, w.RestartsSuppressed)
+		fmt.Fprintf(&sb, "Events:     %d received (%d dropped by ignore globs, %d lost to overflows, %d errors)\n",
+			w.Events, w.Dropped, w.Overflows, w.Errors)
+		fmt.Fprintf(&sb, "Triggered:  %d syncs, %d restarts, %d rescans\n", w.SyncTriggers, w.RestartTriggers, w.Rescans)
+		if len(w.RuleTriggers) > 0 {
+			var rules []string
+			for _, name := range slices.Sorted(maps.Keys(w.RuleTriggers)) {
+				rules = append(rules, fmt.Sprintf("%s %d", name, w.RuleTriggers[name]))
+			}
+			fmt.Fprintf(&sb, "Rules:      %s\n", strings.Join(rules, ", "))
+		}
+		if busiest := busiestDirs(w.EventsByDir, 3); len(busiest) > 0 {
+			fmt.Fprintf(&sb, "Busiest:    %s\n", strings.Join(busiest, ", "))
+		}
+		if e := w.LastEvent; e != nil {
+			fmt.Fprintf(&sb, "Last event: %s %s at %s\n", e.Op, e.Path, e.At.Format(time.TimeOnly))
+		}
+		if w.Watches > 0 {
+			fmt.Fprintf(&sb, "Watching:   %d paths\n", w.Watches)
+		}
+		if w.MaxWatchers > 0 {
+			fmt.Fprintf(&sb, "Watchers:   %d of %d in use\n", w.Watchers, w.MaxWatchers)
+		}
+		if d := w.Degraded; d != nil {
+			fmt.Fprintf(&sb, "Watching:   DEGRADED, %d directories unwatched, %d polled (%s", d.Unwatched, d.Polled, d.Reason)
+			if d.Limit > 0 {
+				fmt.Fprintf(&sb, "; fs.inotify.max_user_watches = %d", d.Limit)
+			}
+			sb.WriteString(")\n")
+		}
+	}
+	for _, s := range status.Subsystems {
+		if s.State == SubsystemRunning {
+			continue
+		}
+		fmt.Fprintf(&sb, "Subsystem:  %s %s after %d restarts (%s at %s)\n",
+			s.Name, strings.ToUpper(s.State), s.Restarts, s.LastFailure, s.LastFailureAt.Format(time.TimeOnly))
+	}
+	for _, s := range status.Suppressed {
+		if s.Count == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "Suppressed: %d by ignore rule %s\n", s.Count, s.Rule)
+	}
+	for _, r := range status.Sync {
+		if r.OK {
+			fmt.Fprintf(&sb, "Sync:       %s ok in %v at %s\n", r.Dir, msDuration(r.DurationMs), r.At.Format(time.TimeOnly))
+		} else {
+			fmt.Fprintf(&sb, "Sync:       %s FAILED (%s) at %s\n", r.Dir, r.Error, r.At.Format(time.TimeOnly))
+		}
+	}
+
+	if len(status.Projects) > 0 {
+		sb.WriteString("\nProjects:\n")
+		for _, p := range status.Projects {
+			fmt.Fprintf(&sb, "  %s\n", p.Name)
+			writeRunnerStatus(&sb, "    ", p.Runner)
+		}
+	}
+	return sb.String()
+}
+
+// writeRunnerStatus writes the lines describing one runner, each prefixed
+// with indent.
+func writeRunnerStatus(sb *strings.Builder, indent string, s RunnerStatus) {
+	line := func(label, format string, args ...any) {
+		fmt.Fprintf(sb, "%s%-11s %s\n", indent, label+":", fmt.Sprintf(format, args...))
+	}
+
+	line("State", "%s (%s)", s.State, s.PackageManager)
+	if t := s.Timings; t != nil {
+		line("Checks", "%d completed; last %v, p50 %v, p95 %v, max %v",
+			t.Count, msDuration(t.LastMs), msDuration(t.P50Ms), msDuration(t.P95Ms), msDuration(t.MaxMs))
+	} else {
+		line("Checks", "none completed yet")
+	}
+	if c := s.LastCycle; c != nil {
+		line("Last cycle", "%s", formatTimeline(c))
+	}
+	line("Restarts", "%d after crashes, %d for memory", s.AutoRestarts, s.MemoryRestarts)
+	if r := s.Resources; r != nil {
+		line("Resources", "%d MiB in %d processes, %.1f%% CPU", r.RSSBytes>>20, r.Processes, r.CPUPercent)
+	}
+	if s.LastExit != "" {
+		line("Last exit", "%s at %s", s.LastExit, s.LastExitAt.Format(time.DateTime))
+	}
+	if p := s.LastPanic; p != nil {
+		line("Panics", "%d, last in the %s at %s: %s", s.Panics, p.Goroutine, p.At.Format(time.DateTime), p.Panic)
+		if p.File != "" {
+			line("Panic report", "%s", p.File)
+		}
+	}
+	for _, c := range s.Checkers {
+		fmt.Fprintf(sb, "%s  %s:\n", indent, c.Name)
+		writeRunnerStatus(sb, indent+"    ", c.Status)
+	}
+}
+
+// msDuration converts milliseconds to a Duration, which prints as e.g. 1.234s.
+func msDuration(ms int64) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
+
+// busiestDirs returns the n directories with the most events as "dir (count)",
+// busiest first.
+func busiestDirs(byDir map[string]int, n int) []string {
+	dirs := slices.Collect(maps.Keys(byDir))
+	slices.SortFunc(dirs, func(a, b string) int {
+		return cmp.Or(cmp.Compare(byDir[b], byDir[a]), strings.Compare(a, b))
+	})
+	var busiest []string
+	for _, dir := range dirs[:min(n, len(dirs))] {
+		busiest = append(busiest, fmt.Sprintf("%s (%d)", dir, byDir[dir]))
+	}
+	return busiest
+}
+
+// formatTimeline describes where a cycle's time went, e.g. "fs trigger:
+// debounce 300ms, restart 120ms, startup 2.1s, check 4.5s (first diagnostic
+// after 1.2s)".
+func formatTimeline(c *CycleTimeline) string {
+	var stages []string
+	if !c.TriggeredAt.IsZero() {
+		stages = append(stages, "debounce "+msDuration(c.DebounceMs).String())
+	}
+	if !c.DebouncedAt.IsZero() && !c.ProcessStartedAt.IsZero() {
+		stages = append(stages, "restart "+msDuration(c.RestartMs).String())
+	}
+	if !c.ProcessStartedAt.IsZero() {
+		stages = append(stages, "startup "+msDuration(c.StartupMs).String())
+	}
+	stages = append(stages, "check "+msDuration(c.CheckMs).String())
+	out := c.Trigger + " trigger: " + strings.Join(stages, ", ")
+	if !c.FirstDiagnosticAt.IsZero() {
+		out += fmt.Sprintf(" (first diagnostic after %v)", msDuration(c.FirstDiagnosticMs))
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFormatStatus tests the status command's human output.
+func TestFormatStatus(t *testing.T) {
+	out := FormatStatus(Status{Runner: RunnerStatus{
+		State:          RunnerStateReady,
+		PackageManager: "bun",
+		AutoRestarts:   1,
+		Timings:        &CheckTimings{Count: 3, LastMs: 1500, P50Ms: 1200, P95Ms: 2500, MaxMs: 2500, TotalMs: 5200},
+		LastCycle: &CycleTimeline{
+			Trigger:     TriggerFS,
+			TriggeredAt: time.Unix(1, 0), DebouncedAt: time.Unix(2, 0), ProcessStartedAt: time.Unix(3, 0), FirstDiagnosticAt: time.Unix(4, 0),
+			DebounceMs: 300, RestartMs: 120, StartupMs: 2100, FirstDiagnosticMs: 1200, CheckMs: 1500,
+		},
+	}})
+
+	for _, want := range []string{
+		"State:      ready (bun)\n",
+		"Checks:     3 completed; last 1.5s, p50 1.2s, p95 2.5s, max 2.5s\n",
+		"Restarts:   1 after crashes, 0 for memory\n",
+		"Last cycle: fs trigger: debounce 300ms, restart 120ms, startup 2.1s, check 1.5s (first diagnostic after 1.2s)\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("FormatStatus missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestFormatStatus_DegradedWatching(t *testing.T) {
+	out := FormatStatus(Status{Watcher: &WatcherStatus{
+		Degraded: &WatchDegradation{Reason: "no space left on device", Limit: 8192, Unwatched: 12, Polled: 1},
+	}})
+
+	want := "Watching:   DEGRADED, 12 directories unwatched, 1 polled (no space left on device; fs.inotify.max_user_watches = 8192)\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("FormatStatus missing %q in:\n%s", want, out)
+	}
+}
+
+func TestFormatStatus_WatcherStats(t *testing.T) {
+	out := FormatStatus(Status{Watcher: &WatcherStatus{
+		Events: 42, Dropped: 3, Overflows: 1, Errors: 1, Rescans: 2, SyncTriggers: 1, RestartTriggers: 4,
+		EventsByDir: map[string]int{"src/lib": 5, "src/routes": 30, "static": 1, "src": 5},
+		LastEvent:   &WatchEvent{Path: "src/routes/+page.svelte", Op: "WRITE", At: time.Date(2026, 1, 2, 15, 4, 5, 0, time.Local)},
+		Watchers:    2, MaxWatchers: 100,
+	}})
+
+	for _, want := range []string{
+		"Events:     42 received (3 dropped by ignore globs, 1 lost to overflows, 1 errors)\n",
+		"Triggered:  1 syncs, 4 restarts, 2 rescans\n",
+		"Busiest:    src/routes (30), src (5), src/lib (5)\n",
+		"Last event: WRITE src/routes/+page.svelte at 15:04:05\n",
+		"Watchers:   2 of 100 in use\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("FormatStatus missing %q in:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// Statuslines
+// =============================================================================
+
+// Styles of a statusline.
+const (
+	StatuslineEmoji = "emoji" // ✖3 ⚠12 2m
+	StatuslinePlain = "plain" // E3 W12 2m
+)
+
+// statuslineBudget is how long the statusline command waits for the server,
+// since a shell prompt waits for it in turn.
+const statuslineBudget = 100 * time.Millisecond
+
+// FormatStatusline returns a one-line summary of result for a shell prompt
+// or tmux status bar: its errors, counting failures, and warnings, or a
+// check mark if it has neither, then its age, and an ellipsis while a newer
+// check is running.
+// ok is false when there is no result yet.
+func FormatStatusline(result SvelteWatchCheckComplete, ok bool, style string) string {
+	plain := style == StatuslinePlain
+	if !ok {
+		if plain {
+			return "pending"
+		}
+		return "…"
+	}
+
+	var parts []string
+	errorCount := result.ErrorCount + len(result.Failures)
+	if errorCount > 0 {
+		parts = append(parts, fmt.Sprintf("%s%d", pick(plain, "E", "✖"), errorCount))
+	}
+	if result.WarningCount > 0 {
+		parts = append(parts, fmt.Sprintf("%s%d", pick(plain, "W", "⚠"), result.WarningCount))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, pick(plain, "ok", "✔"))
+	}
+	parts = append(parts, shortAge(time.Duration(result.AgeSeconds*float64(time.Second))))
+	line := strings.Join(parts, " ")
+	if result.InProgress {
+		line += pick(plain, "...", "…")
+	}
+	return line
+}
+
+// pick returns ifPlain for the plain style, else fancy.
+func pick(plain bool, ifPlain, fancy string) string {
+	if plain {
+		return ifPlain
+	}
+	return fancy
+}
+
+// shortAge formats d in its largest whole unit, e.g. "45s", "3m", or "2d".
+func shortAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", max(0, int(d.Seconds())))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// handleStatusline serves GET /statusline?style=emoji|plain, the
+// FormatStatusline of the latest result as text. It never waits for a check
+// in progress. Like /check, ?project= selects a single project, and ignored
+// and baselined diagnostics are not counted.
+func (s *Server) handleStatusline(w http.ResponseWriter, r *http.Request) {
+	style := r.URL.Query().Get("style")
+	if style == "" {
+		style = StatuslineEmoji
+	}
+	if style != StatuslineEmoji && style != StatuslinePlain {
+		http.Error(w, fmt.Sprintf("unknown style %q: want emoji or plain", style), http.StatusBadRequest)
+		return
+	}
+	result, ok, err := s.peekResult(r.URL.Query().Get("project"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if ok {
+		s.suppress(r, &result)
+		s.markFreshness(r, &result)
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, FormatStatusline(result, ok, style))
+}
+
+// Statusline returns the server's statusline for the named project, or for
+// all projects merged when project is empty, in style.
+func (c *Client) Statusline(ctx context.Context, style, project string) (string, error) {
+	q := url.Values{"style": {style}}
+	if project != "" {
+		q.Set("project", project)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://unix/statusline?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
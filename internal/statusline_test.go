@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatStatusline(t *testing.T) {
+	for _, tc := range []struct {
+		result SvelteWatchCheckComplete
+		ok     bool
+		style  string
+		want   string
+	}{
+		{ok: false, style: StatuslineEmoji, want: "…"},
+		{ok: false, style: StatuslinePlain, want: "pending"},
+		{SvelteWatchCheckComplete{ErrorCount: 3, WarningCount: 12, AgeSeconds: 150}, true, StatuslineEmoji, "✖3 ⚠12 2m"},
+		{SvelteWatchCheckComplete{ErrorCount: 3, WarningCount: 12, AgeSeconds: 150}, true, StatuslinePlain, "E3 W12 2m"},
+		{SvelteWatchCheckComplete{WarningCount: 1, AgeSeconds: 7200}, true, StatuslineEmoji, "⚠1 2h"},
+		{SvelteWatchCheckComplete{AgeSeconds: 4.2, InProgress: true}, true, StatuslineEmoji, "✔ 4s…"},
+		{SvelteWatchCheckComplete{Failures: []string{"crashed"}, AgeSeconds: 3 * 86400}, true, StatuslinePlain, "E1 3d"},
+	} {
+		if got := FormatStatusline(tc.result, tc.ok, tc.style); got != tc.want {
+			t.Errorf("FormatStatusline(%+v, %v, %s) = %q, want %q", tc.result, tc.ok, tc.style, got, tc.want)
+		}
+	}
+}
+
+// TestServer_Statusline tests that the statusline does not wait for the
+// first result.
+func TestServer_Statusline(t *testing.T) {
+	socketPath := testSocketPath(t)
+	executor := NewFakeExecutor(checkOutput("1770255834000", 2), "")
+	r := NewRunner("/workspace", WithExecutor(executor))
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+	c := &Client{socketPath: socketPath, httpClient: unixHTTPClient(socketPath)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), statuslineBudget)
+	defer cancel()
+	if line, err := c.Statusline(ctx, StatuslinePlain, ""); err != nil || line != "pending" {
+		t.Errorf("Statusline() before a result = %q, %v; want pending", line, err)
+	}
+
+	_ = r.Start(context.Background())
+	defer r.Stop()
+	if _, err := r.GetLatestEvent(context.Background()); err != nil {
+		t.Fatalf("GetLatestEvent failed: %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	// The fake result completed long ago.
+	if line, err := c.Statusline(ctx, StatuslineEmoji, ""); err != nil || !strings.HasPrefix(line, "✖2 ") || !strings.HasSuffix(line, "d") {
+		t.Errorf("Statusline() = %q, %v; want ✖2 and an age in days", line, err)
+	}
+	if _, err := c.Statusline(ctx, "fancy", ""); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Statusline() with an unknown style = %v, want a 400", err)
+	}
+}
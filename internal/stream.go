@@ -0,0 +1,577 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tylergannon/svelte-check-server/pkg/types"
+)
+
+// =============================================================================
+// Result Streams
+// =============================================================================
+
+// eventsPollInterval is how often GET /events looks for a new result.
+const eventsPollInterval = 250 * time.Millisecond
+
+// handleEvents serves GET /events, a text/event-stream of "result" events
+// each carrying a completed result as GET /check?format=json serves it,
+// starting with the current one. Like /check, ?project= selects a single
+// project.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("project") != "" {
+		if _, err := s.runnerFor(r); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
+
+	ctx, cancel := s.startStream(w, r)
+	defer cancel()
+	for event := range s.pollResults(ctx, r) {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		if _, err := fmt.Fprintf(w, "event: result\nid: %d\ndata: %s\n\n", event.Timestamp, data); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// startStream writes the headers of an event stream and returns the
+// context it is served in, which ends when the client leaves or the server
+// shuts down.
+func (s *Server) startStream(w http.ResponseWriter, r *http.Request) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(r.Context())
+	go func() {
+		select {
+		case <-s.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+	return ctx, cancel
+}
+
+// pollResults delivers each new result for r, as /check would serve it,
+// starting with the current one, until ctx is done.
+func (s *Server) pollResults(ctx context.Context, r *http.Request) <-chan SvelteWatchCheckComplete {
+	r = r.WithContext(ctx)
+	results := make(chan SvelteWatchCheckComplete)
+	go func() {
+		defer close(results)
+		ticker := time.NewTicker(eventsPollInterval)
+		defer ticker.Stop()
+		var last int64 = -1
+		for {
+			// Waits while a check is in progress.
+			event, err := s.latestResult(r)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil && event.Timestamp != last {
+				last = event.Timestamp
+				s.decorate(r, &event)
+				select {
+				case results <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return results
+}
+
+// Reconnection delays of Client.Subscribe.
+const (
+	subscribeMinBackoff = 500 * time.Millisecond
+	subscribeMaxBackoff = 10 * time.Second
+)
+
+// Subscribe streams completed results of the named project, or of all
+// projects merged when project is empty, from GET /events. The current
+// result is delivered first, then each new one. If the connection is lost,
+// Subscribe reconnects with exponential backoff, without delivering a
+// result twice. The channel is closed when ctx is done; an error is
+// returned only if the first connection fails.
+func (c *Client) Subscribe(ctx context.Context, project string) (<-chan SvelteWatchCheckComplete, error) {
+	body, err := c.openEvents(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(chan SvelteWatchCheckComplete)
+	go func() {
+		defer close(results)
+		var last int64 = -1
+		backoff := subscribeMinBackoff
+		for {
+			if body != nil {
+				backoff = subscribeMinBackoff
+				readEvents(ctx, body, func(result SvelteWatchCheckComplete) bool {
+					if result.Timestamp == last {
+						return true
+					}
+					last = result.Timestamp
+					select {
+					case results <- result:
+						return true
+					case <-ctx.Done():
+						return false
+					}
+				})
+				_ = body.Close()
+				body = nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, subscribeMaxBackoff)
+			body, _ = c.openEvents(ctx, project)
+		}
+	}()
+	return results, nil
+}
+
+// openEvents requests GET /events and returns the stream.
+func (c *Client) openEvents(ctx context.Context, project string) (io.ReadCloser, error) {
+	u := "http://unix/events"
+	if project != "" {
+		u += "?" + url.Values{"project": {project}}.Encode()
+	}
+	return c.openStream(ctx, u)
+}
+
+// openStream requests the event stream at u and returns it.
+func (c *Client) openStream(ctx context.Context, u string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp.Body, nil
+}
+
+// readEvents calls deliver with the result of each "result" event in an
+// event stream until it ends, ctx is done, or deliver returns false.
+// Results types.Decode rejects, such as those of a newer schema, are
+// skipped.
+func readEvents(ctx context.Context, r io.Reader, deliver func(SvelteWatchCheckComplete) bool) {
+	readStream(ctx, r, func(name string, data []byte) bool {
+		if name != "result" {
+			return true
+		}
+		result, err := types.Decode(data)
+		return err != nil || deliver(result)
+	})
+}
+
+// readStream calls deliver with the name and data of each event in an event
+// stream until it ends, ctx is done, or deliver returns false.
+func readStream(ctx context.Context, r io.Reader, deliver func(name string, data []byte) bool) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+	var name string
+	var data bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if name != "" && !deliver(name, data.Bytes()) {
+				return
+			}
+			name = ""
+			data.Reset()
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// WaitForClean waits until a result of the named project, or of all
+// projects merged when project is empty, passes the daemon's CheckPolicy,
+// and returns it. The current result counts. If ctx is done first, it
+// returns the last result seen and ctx's error.
+func (c *Client) WaitForClean(ctx context.Context, project string) (SvelteWatchCheckComplete, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results, err := c.Subscribe(ctx, project)
+	if err != nil {
+		return SvelteWatchCheckComplete{}, err
+	}
+
+	var last SvelteWatchCheckComplete
+	for last = range results {
+		// Only the daemon knows its policy, so ask it for the verdict.
+		resp, err := c.CheckTyped(ctx, CheckOptions{Project: project, AllowStale: true})
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return last, err
+		}
+		if resp.Verdict != "" {
+			continue
+		}
+		return resp.Result, nil
+	}
+	return last, ctx.Err()
+}
+
+// =============================================================================
+// File Subscriptions
+// =============================================================================
+
+// FileDiagnostics is pushed to a GET /events/files subscriber when the
+// diagnostics of one of its files change.
+type FileDiagnostics = types.FileDiagnostics
+
+// fileSet is the body of PUT /events/files/{id}.
+type fileSet struct {
+	Files []string `json:"files"`
+}
+
+// handleFileEvents serves GET /events/files?file=..., a text/event-stream
+// for editors that need the diagnostics of only the files they have open.
+// It begins with a "subscribed" event whose data, {"id": ...}, names the
+// stream for PUT /events/files/{id}. Then, starting with the current
+// result, a "diagnostics" event carries a file's FileDiagnostics whenever
+// they change, and a "cleared" event when none are left. Files are
+// workspace-relative, as in results merged across projects.
+func (s *Server) handleFileEvents(w http.ResponseWriter, r *http.Request) {
+	files, err := streamFiles(r.URL.Query()["file"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := w.(http.Flusher); !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	id, updates, unsubscribe := s.fileStreams.add()
+	defer unsubscribe()
+
+	ctx, cancel := s.startStream(w, r)
+	defer cancel()
+	send := func(name string, v any) bool {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+			return false
+		}
+		w.(http.Flusher).Flush()
+		return true
+	}
+	push := func(changes []FileDiagnostics) bool {
+		for _, c := range changes {
+			name := "diagnostics"
+			if c.Cleared {
+				name = "cleared"
+			}
+			if !send(name, c) {
+				return false
+			}
+		}
+		return true
+	}
+	if !send("subscribed", map[string]string{"id": id}) {
+		return
+	}
+
+	// Whatever the request's query, files are matched in the merged result.
+	all, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/check", nil)
+	results := s.pollResults(ctx, all)
+	sent := make(sentFiles)
+	sent.set(files)
+	var last *SvelteWatchCheckComplete
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				return
+			}
+			last = &result
+			if !push(sent.changes(result)) {
+				return
+			}
+		case files := <-updates:
+			sent.set(files)
+			// Files just added are compared with the result already seen.
+			if last != nil && !push(sent.changes(*last)) {
+				return
+			}
+		}
+	}
+}
+
+// handleSetFiles serves PUT /events/files/{id}, which replaces the files of
+// a GET /events/files stream with those of a {"files": [...]} body. The
+// stream then pushes the current diagnostics of the files added.
+func (s *Server) handleSetFiles(w http.ResponseWriter, r *http.Request) {
+	var body fileSet
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid files: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	files, err := streamFiles(body.Files)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.fileStreams.update(r.PathValue("id"), files) {
+		http.Error(w, "no such stream", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// streamFiles cleans the files a stream is asked for, which must be
+// workspace-relative.
+func streamFiles(files []string) ([]string, error) {
+	cleaned := make([]string, 0, len(files))
+	for _, f := range files {
+		if path.IsAbs(f) || filepath.IsAbs(f) {
+			return nil, fmt.Errorf("file %q is not workspace-relative", f)
+		}
+		cleaned = append(cleaned, path.Clean(filepath.ToSlash(f)))
+	}
+	return cleaned, nil
+}
+
+// fileStreams routes PUT /events/files/{id} to its stream.
+type fileStreams struct {
+	mu      sync.Mutex
+	next    int
+	streams map[string]chan []string
+}
+
+// add registers a stream, returning its ID, the channel its new files
+// arrive on, and the function that unregisters it.
+func (f *fileStreams) add() (string, <-chan []string, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.streams == nil {
+		f.streams = make(map[string]chan []string)
+	}
+	f.next++
+	id := strconv.Itoa(f.next)
+	ch := make(chan []string, 1)
+	f.streams[id] = ch
+	return id, ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.streams, id)
+	}
+}
+
+// update sends files to the stream id, replacing any it has yet to read.
+// It reports false if there is no such stream.
+func (f *fileStreams) update(id string, files []string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch, ok := f.streams[id]
+	if !ok {
+		return false
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- files
+	return true
+}
+
+// sentFiles is what a stream has sent of each of its files: the version and
+// diagnostics of the last notification.
+type sentFiles map[string]*FileDiagnostics
+
+// set replaces the files, keeping what was sent of those remaining.
+func (s sentFiles) set(files []string) {
+	keep := make(map[string]bool, len(files))
+	for _, f := range files {
+		keep[f] = true
+		if s[f] == nil {
+			s[f] = &FileDiagnostics{File: f}
+		}
+	}
+	for f := range s {
+		if !keep[f] {
+			delete(s, f)
+		}
+	}
+}
+
+// changes returns a notification, in order of file, for each file whose
+// diagnostics in result differ from those last sent, and records it as
+// sent. A file not yet notified counts as having none.
+func (s sentFiles) changes(result SvelteWatchCheckComplete) []FileDiagnostics {
+	byFile := make(map[string][]Diagnostic)
+	for _, d := range result.Diagnostics {
+		if s[d.Filename] != nil {
+			byFile[d.Filename] = append(byFile[d.Filename], d)
+		}
+	}
+	var changes []FileDiagnostics
+	for f, sent := range s {
+		diags := byFile[f]
+		if sameDiagnostics(sent.Diagnostics, diags) {
+			continue
+		}
+		*sent = FileDiagnostics{
+			File:        f,
+			Version:     sent.Version + 1,
+			Cleared:     len(diags) == 0,
+			Diagnostics: append([]Diagnostic{}, diags...),
+			Timestamp:   result.Timestamp,
+		}
+		changes = append(changes, *sent)
+	}
+	slices.SortFunc(changes, func(a, b FileDiagnostics) int { return strings.Compare(a.File, b.File) })
+	return changes
+}
+
+// sameDiagnostics reports whether a and b are the same diagnostics, though
+// reported by different check cycles.
+func sameDiagnostics(a, b []Diagnostic) bool {
+	return slices.EqualFunc(a, b, func(x, y Diagnostic) bool {
+		x.Timestamp, y.Timestamp = 0, 0
+		return x == y
+	})
+}
+
+// FileSubscription is an editor's subscription to the diagnostics of some
+// files, from Client.SubscribeFiles.
+type FileSubscription struct {
+	c       *Client
+	id      string
+	updates chan FileDiagnostics
+}
+
+// SubscribeFiles subscribes to the diagnostics of files, which may be
+// absolute or relative to the workspace. The current diagnostics of each
+// file that has any are delivered first, then each change, including
+// Cleared when a file's last diagnostic is fixed. The channel is closed
+// when ctx is done or the connection is lost; subscribing again starts
+// every file's Version over.
+func (c *Client) SubscribeFiles(ctx context.Context, files []string) (*FileSubscription, error) {
+	q := url.Values{"file": workspaceFiles(c.Workspace(), c.Workspace(), files)}
+	body, err := c.openStream(ctx, "http://unix/events/files?"+q.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &FileSubscription{c: c, updates: make(chan FileDiagnostics)}
+	ids := make(chan string, 1)
+	go func() {
+		defer close(sub.updates)
+		defer close(ids)
+		defer func() { _ = body.Close() }()
+		readStream(ctx, body, func(name string, data []byte) bool {
+			var msg struct {
+				ID string `json:"id"`
+				FileDiagnostics
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				return true
+			}
+			if name == "subscribed" {
+				ids <- msg.ID
+				return true
+			}
+			select {
+			case sub.updates <- msg.FileDiagnostics:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	var ok bool
+	if sub.id, ok = <-ids; !ok {
+		return nil, cmp.Or(ctx.Err(), errors.New("the stream ended before subscribing"))
+	}
+	return sub, nil
+}
+
+// Updates delivers the subscription's notifications.
+func (s *FileSubscription) Updates() <-chan FileDiagnostics {
+	return s.updates
+}
+
+// SetFiles replaces the subscription's files, e.g. as the editor opens and
+// closes them. The current diagnostics of the files added are delivered
+// next.
+func (s *FileSubscription) SetFiles(ctx context.Context, files []string) error {
+	body, err := json.Marshal(fileSet{Files: workspaceFiles(s.c.Workspace(), s.c.Workspace(), files)})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, "http://unix/events/files/"+url.PathEscape(s.id), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp, err := s.c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusNoContent {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
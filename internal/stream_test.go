@@ -0,0 +1,230 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// checkOutput is svelte-check output of one cycle completing at ts.
+func checkOutput(ts string, errors int) string {
+	return fmt.Sprintf("%s START \"/workspace\"\n%s COMPLETED 10 FILES %d ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS\n", ts, ts, errors)
+}
+
+// nextResult returns the next result from results, failing after a second.
+func nextResult(t *testing.T, results <-chan SvelteWatchCheckComplete) SvelteWatchCheckComplete {
+	t.Helper()
+	select {
+	case result, ok := <-results:
+		if !ok {
+			t.Fatal("results closed")
+		}
+		return result
+	case <-time.After(time.Second):
+		t.Fatal("no result within 1s")
+	}
+	return SvelteWatchCheckComplete{}
+}
+
+// TestClient_Subscribe tests that results stream as checks complete, across
+// a restart of the server.
+func TestClient_Subscribe(t *testing.T) {
+	socketPath := testSocketPath(t)
+	executor := NewFakeExecutor(checkOutput("1770255834000", 1), "")
+	r := NewRunner("/workspace", WithExecutor(executor))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &Client{socketPath: socketPath, httpClient: unixHTTPClient(socketPath)}
+
+	if _, err := c.Subscribe(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Subscribe to an unknown project = %v, want a 404", err)
+	}
+
+	results, err := c.Subscribe(ctx, "")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if got := nextResult(t, results); got.Timestamp != 1770255834000 || got.ErrorCount != 1 {
+		t.Errorf("first result = %+v, want the current one", got)
+	}
+
+	executor.setCmd(newFakeCmd(checkOutput("1770255835000", 0)))
+	if err := r.Restart(context.Background()); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if got := nextResult(t, results); got.Timestamp != 1770255835000 || got.ErrorCount != 0 {
+		t.Errorf("second result = %+v, want the new one", got)
+	}
+
+	// The subscription survives the server restarting, without repeating
+	// the result it already delivered.
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	s = NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() { _ = s.Stop(context.Background()) }()
+	executor.setCmd(newFakeCmd(checkOutput("1770255836000", 0)))
+	if err := r.Restart(context.Background()); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if got := nextResult(t, results); got.Timestamp != 1770255836000 {
+		t.Errorf("result after reconnecting = %+v, want the newest", got)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-results:
+		if ok {
+			t.Error("received a result after cancelling")
+		}
+	case <-time.After(time.Second):
+		t.Error("results not closed after cancelling")
+	}
+}
+
+// TestClient_WaitForClean tests that WaitForClean skips failing results and
+// returns the first passing one.
+func TestClient_WaitForClean(t *testing.T) {
+	socketPath := testSocketPath(t)
+	executor := NewFakeExecutor(checkOutput("1770255834000", 2), "")
+	r := NewRunner("/workspace", WithExecutor(executor))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() { _ = s.Stop(context.Background()) }()
+	c := &Client{socketPath: socketPath, httpClient: unixHTTPClient(socketPath)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	result, err := c.WaitForClean(ctx, "")
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) || result.ErrorCount != 2 {
+		t.Errorf("WaitForClean() = %+v, %v; want the failing result and a deadline error", result, err)
+	}
+
+	done := make(chan SvelteWatchCheckComplete)
+	go func() {
+		result, err := c.WaitForClean(context.Background(), "")
+		if err != nil {
+			t.Errorf("WaitForClean failed: %v", err)
+		}
+		done <- result
+	}()
+	executor.setCmd(newFakeCmd(checkOutput("1770255835000", 0)))
+	if err := r.Restart(context.Background()); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	select {
+	case result := <-done:
+		if result.Timestamp != 1770255835000 || result.ErrorCount != 0 {
+			t.Errorf("WaitForClean() = %+v, want the passing result", result)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("WaitForClean did not return a passing result")
+	}
+}
+
+// fileOutput is svelte-check output of one cycle completing at ts with an
+// error in each of files.
+func fileOutput(ts string, files ...string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s START \"/workspace\"\n", ts)
+	for _, f := range files {
+		fmt.Fprintf(&sb, `%s {"type":"ERROR","filename":%q,"start":{"line":0,"character":0},"end":{"line":0,"character":1},"message":"Broken","code":2322}`+"\n", ts, f)
+	}
+	fmt.Fprintf(&sb, "%s COMPLETED 10 FILES %d ERRORS 0 WARNINGS %d FILES_WITH_PROBLEMS\n", ts, len(files), len(files))
+	return sb.String()
+}
+
+// TestClient_SubscribeFiles tests that a file subscription is sent only the
+// changes to its files' diagnostics, and the current ones of files added.
+func TestClient_SubscribeFiles(t *testing.T) {
+	socketPath := testSocketPath(t)
+	executor := NewFakeExecutor(fileOutput("1770255834000", "src/a.ts", "src/b.ts", "src/c.ts"), "")
+	r := NewRunner("/workspace", WithExecutor(executor))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() { _ = s.Stop(context.Background()) }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &Client{workspace: "/workspace", socketPath: socketPath, httpClient: unixHTTPClient(socketPath)}
+
+	sub, err := c.SubscribeFiles(ctx, []string{"src/a.ts", "/workspace/src/b.ts", "src/clean.ts"})
+	if err != nil {
+		t.Fatalf("SubscribeFiles failed: %v", err)
+	}
+	next := func() string {
+		t.Helper()
+		select {
+		case d, ok := <-sub.Updates():
+			if !ok {
+				t.Fatal("updates closed")
+			}
+			return fmt.Sprintf("%s v%d %d cleared=%v", d.File, d.Version, len(d.Diagnostics), d.Cleared)
+		case <-time.After(time.Second):
+			t.Fatal("no update within 1s")
+		}
+		return ""
+	}
+	for _, want := range []string{"src/a.ts v1 1 cleared=false", "src/b.ts v1 1 cleared=false"} {
+		if got := next(); got != want {
+			t.Errorf("update = %q, want %q", got, want)
+		}
+	}
+
+	// a.ts is unchanged and c.ts is not subscribed to.
+	executor.setCmd(newFakeCmd(fileOutput("1770255835000", "src/a.ts")))
+	if err := r.Restart(context.Background()); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if got, want := next(), "src/b.ts v2 0 cleared=true"; got != want {
+		t.Errorf("update = %q, want %q", got, want)
+	}
+
+	executor.setCmd(newFakeCmd(fileOutput("1770255836000", "src/a.ts", "src/c.ts")))
+	if err := r.Restart(context.Background()); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	time.Sleep(3 * eventsPollInterval)
+	if err := sub.SetFiles(ctx, []string{"src/c.ts"}); err != nil {
+		t.Fatalf("SetFiles failed: %v", err)
+	}
+	if got, want := next(), "src/c.ts v1 1 cleared=false"; got != want {
+		t.Errorf("update after SetFiles = %q, want %q", got, want)
+	}
+	select {
+	case d := <-sub.Updates():
+		t.Errorf("unexpected update %+v", d)
+	case <-time.After(3 * eventsPollInterval):
+	}
+
+	if err := (&FileSubscription{c: c, id: "missing"}).SetFiles(ctx, nil); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("SetFiles on an unknown stream = %v, want a 404", err)
+	}
+	if _, err := streamFiles([]string{"/etc/passwd"}); err == nil {
+		t.Error("streamFiles accepted an absolute path")
+	}
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// =============================================================================
+// Git Submodules
+// =============================================================================
+
+// gitSubmodule is a checked-out submodule inside the workspace, whose files
+// are checked like the workspace's own.
+type gitSubmodule struct {
+	path      string // work tree, under the workspace path
+	gitDir    string
+	commonDir string
+
+	refPath string // the loose ref HEAD points to, or "" if detached
+	sha     string // HEAD's commit as last seen by Start
+	commit  string // HEAD's commit as of the last Changes
+}
+
+// findSubmodules returns the initialized submodules inside the workspace.
+// Submodules of submodules are not included.
+func (r *RealGitBranchWatcher) findSubmodules() []*gitSubmodule {
+	cmd := r.executor.Command("git", "ls-files", "--stage", "-z")
+	cmd.SetDir(r.workspacePath)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var submodules []*gitSubmodule
+	for _, rel := range parseGitLinks(string(out)) {
+		path := filepath.Join(r.workspacePath, rel)
+		// An uninitialized submodule is an empty directory without .git.
+		gitDir, commonDir, err := resolveGitDir(path)
+		if err != nil {
+			continue
+		}
+		submodules = append(submodules, &gitSubmodule{
+			path:      path,
+			gitDir:    gitDir,
+			commonDir: commonDir,
+			commit:    r.headCommit(path),
+		})
+	}
+	return submodules
+}
+
+// parseGitLinks returns the paths of the submodules, recorded as gitlinks of
+// mode 160000, in the output of git ls-files --stage -z.
+func parseGitLinks(out string) []string {
+	var paths []string
+	for entry := range strings.SplitSeq(out, "\x00") {
+		info, path, ok := strings.Cut(entry, "\t")
+		if ok && strings.HasPrefix(info, "160000 ") {
+			paths = append(paths, filepath.FromSlash(path))
+		}
+	}
+	return paths
+}
+
+// watchSubmodules watches the HEAD and current branch of each submodule, so
+// that checking out another commit in it, e.g. by git submodule update,
+// counts as a branch switch.
+func (r *RealGitBranchWatcher) watchSubmodules() {
+	for _, s := range r.submodules {
+		if err := r.notifier().Add(s.gitDir); err != nil {
+			logger("git").Warn("could not watch submodule", "submodule", s.path, "error", err)
+			continue
+		}
+		if s.commonDir != s.gitDir {
+			if err := r.notifier().Add(s.commonDir); err != nil {
+				logger("git").Warn("could not watch packed-refs of submodule", "submodule", s.path, "error", err)
+			}
+		}
+		r.watchSubmoduleRef(s)
+		logger("git").Info("watching submodule for checkouts", "submodule", s.path)
+	}
+}
+
+// watchSubmoduleRef records the commit s is at and watches the directory of
+// the branch it is on, if any.
+func (r *RealGitBranchWatcher) watchSubmoduleRef(s *gitSubmodule) {
+	ref, sha := readHead(s.gitDir, s.commonDir)
+	s.sha = sha
+	refPath := ""
+	if ref != "" {
+		refPath = filepath.Join(s.commonDir, ref)
+	}
+	if refPath != "" && refPath != s.refPath {
+		if err := r.notifier().Add(filepath.Dir(refPath)); err != nil {
+			logger("git").Warn("could not watch branch ref of submodule", "submodule", s.path, "error", err)
+		}
+	}
+	s.refPath = refPath
+}
+
+// submoduleFor returns the submodule whose HEAD, branch ref, or packed-refs
+// path is, or nil.
+func (r *RealGitBranchWatcher) submoduleFor(path string) *gitSubmodule {
+	for _, s := range r.submodules {
+		if path == filepath.Join(s.gitDir, "HEAD") || path == filepath.Join(s.commonDir, "packed-refs") ||
+			(s.refPath != "" && path == s.refPath) {
+			return s
+		}
+	}
+	return nil
+}
+
+// submoduleChanged emits on headCh when s has moved to another commit.
+func (r *RealGitBranchWatcher) submoduleChanged(s *gitSubmodule) {
+	prev := s.sha
+	r.watchSubmoduleRef(s)
+	if s.sha == prev {
+		return
+	}
+	logger("git").Info("submodule checked out another commit", "submodule", s.path)
+	// Non-blocking send
+	select {
+	case r.headCh <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	kexec "k8s.io/utils/exec"
+)
+
+func TestParseGitLinks(t *testing.T) {
+	out := "100644 aaaa 0\tpackage.json\x00160000 bbbb 0\tpackages/ui\x00100644 cccc 0\tsrc/app.ts\x00"
+	if got, want := parseGitLinks(out), []string{filepath.Join("packages", "ui")}; !slices.Equal(got, want) {
+		t.Errorf("parseGitLinks() = %v, want %v", got, want)
+	}
+}
+
+func TestRealGitBranchWatcher_Submodule(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root, lib := t.TempDir(), t.TempDir()
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{
+			"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "protocol.file.allow=always",
+		}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git(lib, "init", "-q")
+	writeFiles(t, lib, map[string]string{"src/index.ts": "1"})
+	git(lib, "add", ".")
+	git(lib, "commit", "-q", "-m", "initial")
+
+	git(root, "init", "-q")
+	writeFiles(t, root, map[string]string{"src/app.ts": ""})
+	git(root, "submodule", "add", "-q", lib, "ui")
+	git(root, "add", ".")
+	git(root, "commit", "-q", "-m", "initial")
+
+	resetWatcherCount()
+	r, err := NewRealGitBranchWatcher(root, kexec.New())
+	if err != nil {
+		t.Fatalf("NewRealGitBranchWatcher failed: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+	if len(r.submodules) != 1 || r.submodules[0].path != filepath.Join(root, "ui") {
+		t.Fatalf("submodules = %v, want ui", r.submodules)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Start(ctx)
+	time.Sleep(100 * time.Millisecond)
+
+	// Check out a newer commit of the library in the submodule, as
+	// git submodule update does after a pull.
+	writeFiles(t, lib, map[string]string{"src/index.ts": "2"})
+	git(lib, "commit", "-q", "-am", "second")
+	sub := filepath.Join(root, "ui")
+	git(sub, "fetch", "-q")
+	git(sub, "checkout", "-q", "--detach", "origin/HEAD")
+
+	select {
+	case <-r.HeadChanged():
+	case <-time.After(5 * time.Second):
+		t.Fatal("HeadChanged did not fire for a submodule checkout")
+	}
+	want := []GitChange{{Path: filepath.Join(sub, "src", "index.ts"), Status: "M"}}
+	if got, ok := r.Changes(); !ok || !slices.Equal(got, want) {
+		t.Errorf("Changes() = %v, %v; want %v, true", got, ok, want)
+	}
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"sync"
+
+	"github.com/tylergannon/svelte-check-server/pkg/types"
+)
+
+// =============================================================================
+// Event Subscriptions
+// =============================================================================
+
+// DependenciesChanged is sent to subscribers when a package manager lockfile
+// changes.
+type DependenciesChanged = types.DependenciesChanged
+
+// SubscriberBuffer is how many events a subscriber may fall behind before it
+// is dropped.
+const SubscriberBuffer = 256
+
+// eventHub fans events out to subscribers. A subscriber that falls
+// SubscriberBuffer events behind is unsubscribed and its channel closed
+// rather than slowing the publisher or silently missing events; it can
+// subscribe again and re-read current state.
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan SvelteCheckEvent]struct{}
+}
+
+// subscribe registers a new subscriber. The returned function unsubscribes
+// and closes the channel; it may be called more than once.
+func (h *eventHub) subscribe() (<-chan SvelteCheckEvent, func()) {
+	ch := make(chan SvelteCheckEvent, SubscriberBuffer)
+
+	h.mu.Lock()
+	if h.subs == nil {
+		h.subs = make(map[chan SvelteCheckEvent]struct{})
+	}
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.removeLocked(ch)
+	}
+}
+
+// publish delivers event to every subscriber without blocking.
+func (h *eventHub) publish(event SvelteCheckEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs {
+		select {
+		case ch <- event:
+		default:
+			logger("server").Warn("dropping event subscriber that fell behind", "buffer", SubscriberBuffer)
+			h.removeLocked(ch)
+		}
+	}
+}
+
+// removeLocked unsubscribes ch and closes it, if still subscribed.
+// h.mu must be held.
+func (h *eventHub) removeLocked(ch chan SvelteCheckEvent) {
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// len returns the number of subscribers.
+func (h *eventHub) len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// backlogs returns how many events each subscriber has yet to read.
+func (h *eventHub) backlogs() []int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	backlogs := make([]int, 0, len(h.subs))
+	for ch := range h.subs {
+		backlogs = append(backlogs, len(ch))
+	}
+	return backlogs
+}
@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// TestEventHub_FanOut tests that every subscriber receives every event.
+func TestEventHub_FanOut(t *testing.T) {
+	var hub eventHub
+	a, unsubA := hub.subscribe()
+	defer unsubA()
+	b, unsubB := hub.subscribe()
+	defer unsubB()
+
+	hub.publish(SvelteWatchCheckStart{Timestamp: 1})
+	hub.publish(SvelteWatchCheckComplete{Timestamp: 2})
+
+	for name, ch := range map[string]<-chan SvelteCheckEvent{"a": a, "b": b} {
+		if e, ok := (<-ch).(SvelteWatchCheckStart); !ok || e.Timestamp != 1 {
+			t.Errorf("%s: first event = %+v, want START", name, e)
+		}
+		if e, ok := (<-ch).(SvelteWatchCheckComplete); !ok || e.Timestamp != 2 {
+			t.Errorf("%s: second event = %+v, want COMPLETED", name, e)
+		}
+	}
+}
+
+// TestEventHub_SlowSubscriberDropped tests that a subscriber that falls a full
+// buffer behind is closed without affecting the others.
+func TestEventHub_SlowSubscriberDropped(t *testing.T) {
+	var hub eventHub
+	slow, unsubSlow := hub.subscribe()
+	defer unsubSlow()
+	fast, unsubFast := hub.subscribe()
+	defer unsubFast()
+
+	for i := range SubscriberBuffer + 1 {
+		hub.publish(SvelteWatchFailure{Timestamp: int64(i)})
+		<-fast
+	}
+
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != SubscriberBuffer {
+		t.Errorf("slow subscriber received %d events before closing, want %d", n, SubscriberBuffer)
+	}
+	if got := hub.len(); got != 1 {
+		t.Errorf("subscribers = %d, want 1", got)
+	}
+}
+
+// TestEventHub_Unsubscribe tests that unsubscribing closes the channel and
+// may be repeated.
+func TestEventHub_Unsubscribe(t *testing.T) {
+	var hub eventHub
+	ch, unsubscribe := hub.subscribe()
+	unsubscribe()
+	unsubscribe()
+
+	if _, ok := <-ch; ok {
+		t.Error("channel still open after unsubscribe")
+	}
+	hub.publish(SvelteWatchCheckStart{}) // must not panic on the closed channel
+	if got := hub.len(); got != 0 {
+		t.Errorf("subscribers = %d, want 0", got)
+	}
+}
+
+// TestRunner_Subscribe tests that subscribers see the process's events after
+// the Runner has applied them, across a restart.
+func TestRunner_Subscribe(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		executor := NewFakeExecutor("", "")
+		executor.newCmd = func() *FakeCmd {
+			return newFakeCmd(`1770255832071 START "/workspace"
+1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+`)
+		}
+		r := NewRunner("/workspace", WithExecutor(executor))
+		events, unsubscribe := r.Subscribe()
+		defer unsubscribe()
+
+		ctx := context.Background()
+		_ = r.Start(ctx)
+		defer r.Stop()
+		time.Sleep(10 * time.Millisecond)
+		synctest.Wait()
+		if err := r.Restart(ctx); err != nil {
+			t.Fatalf("Restart failed: %v", err)
+		}
+		synctest.Wait()
+
+		var kinds []string
+		for range 4 {
+			switch (<-events).(type) {
+			case SvelteWatchCheckStart:
+				kinds = append(kinds, "START")
+			case SvelteWatchCheckComplete:
+				kinds = append(kinds, "COMPLETED")
+				if r.Status().State != RunnerStateReady {
+					t.Errorf("State = %q when COMPLETED received, want %q", r.Status().State, RunnerStateReady)
+				}
+			}
+		}
+		if want := []string{"START", "COMPLETED", "START", "COMPLETED"}; !slices.Equal(kinds, want) {
+			t.Errorf("events = %v, want %v", kinds, want)
+		}
+	})
+}
+
+// TestServer_DependenciesChanged tests that a lockfile change marks earlier
+// results stale and is sent to subscribers.
+func TestServer_DependenciesChanged(t *testing.T) {
+	socketPath := testSocketPath(t)
+
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(`1770255832071 START "/workspace"
+1770255834342 COMPLETED 100 FILES 0 ERRORS 0 WARNINGS 0 FILES_WITH_PROBLEMS
+`, "")))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	if _, err := r.GetLatestEvent(context.Background()); err != nil {
+		t.Fatalf("GetLatestEvent failed: %v", err)
+	}
+	events, unsubscribe := r.Subscribe()
+	defer unsubscribe()
+
+	s.DependenciesChanged("bun.lock")
+
+	timeout := time.After(time.Second)
+	for received := false; !received; {
+		select {
+		case e := <-events:
+			if dc, ok := e.(DependenciesChanged); ok {
+				if dc.Lockfile != "bun.lock" {
+					t.Errorf("Lockfile = %q, want bun.lock", dc.Lockfile)
+				}
+				received = true
+			}
+		case <-timeout:
+			t.Fatal("no DependenciesChanged event received")
+		}
+	}
+
+	resp, err := unixHTTPClient(socketPath).Get("http://unix/check?format=json")
+	if err != nil {
+		t.Fatalf("GET /check failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var result SvelteWatchCheckComplete
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !result.Stale || result.StaleReason != "bun.lock changed since this check" {
+		t.Errorf("Stale, StaleReason = %v, %q; want stale because bun.lock changed", result.Stale, result.StaleReason)
+	}
+}
@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// Supervisor
+// =============================================================================
+
+// Subsystem states reported in /status.
+const (
+	SubsystemRunning    = "running"
+	SubsystemRestarting = "restarting" // its loop ended; waiting to start it again
+	SubsystemFailed     = "failed"     // gave up after RestartPolicy.MaxRetries failures
+)
+
+// supervisorStableAfter is how long a subsystem must run before its next
+// failure counts as the first again.
+const supervisorStableAfter = time.Minute
+
+// SubsystemStatus is a supervised subsystem's health, served by GET /status.
+type SubsystemStatus struct {
+	Name          string    `json:"name"`
+	State         string    `json:"state"`
+	Restarts      int       `json:"restarts"`
+	LastFailure   string    `json:"lastFailure,omitempty"`
+	LastFailureAt time.Time `json:"lastFailureAt,omitzero"`
+}
+
+// Supervisor runs the daemon's long-lived loops, such as the file and git
+// watchers, and starts a loop again when it ends or panics while the daemon
+// is still running, with backoff as for crashes of svelte-check. Until it is
+// running again, /status reports it and /check marks results stale, since
+// changes may have been missed.
+type Supervisor struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	policy RestartPolicy
+	wg     sync.WaitGroup
+
+	mu         sync.Mutex
+	subsystems []*SubsystemStatus
+}
+
+// NewSupervisor returns a Supervisor whose loops run until ctx is done or
+// Stop is called. The zero RestartPolicy means DefaultRestartPolicy.
+func NewSupervisor(ctx context.Context, policy RestartPolicy) *Supervisor {
+	if policy == (RestartPolicy{}) {
+		policy = DefaultRestartPolicy
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &Supervisor{ctx: ctx, cancel: cancel, policy: policy}
+}
+
+// Go runs run, a loop that returns only once its context is done, and runs
+// it again whenever it returns earlier or panics. reopen, if not nil, is
+// called before each new run to replace what the loop depends on, e.g. a
+// closed fsnotify watcher.
+func (s *Supervisor) Go(name string, run func(context.Context), reopen func() error) {
+	st := &SubsystemStatus{Name: name, State: SubsystemRunning}
+	s.mu.Lock()
+	s.subsystems = append(s.subsystems, st)
+	s.mu.Unlock()
+	s.wg.Go(func() { s.supervise(st, run, reopen) })
+}
+
+func (s *Supervisor) supervise(st *SubsystemStatus, run func(context.Context), reopen func() error) {
+	log := logger("supervisor").With("name", st.Name)
+	for failures := 0; ; {
+		started := time.Now()
+		err := runRecovered(s.ctx, run)
+		if s.ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) >= supervisorStableAfter {
+			failures = 0
+		}
+
+		for {
+			failures++
+			s.update(st, func() {
+				st.LastFailure, st.LastFailureAt = err.Error(), time.Now()
+				st.State = SubsystemRestarting
+			})
+			if s.policy.MaxRetries < 0 || failures > s.policy.MaxRetries {
+				s.update(st, func() { st.State = SubsystemFailed })
+				log.Error("subsystem keeps failing, giving up", "failures", failures, "error", err)
+				return
+			}
+			delay := s.policy.backoff(failures)
+			log.Error("subsystem stopped unexpectedly, restarting", "error", err, "delay", delay)
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if reopen == nil {
+				break
+			}
+			if err = reopen(); err == nil {
+				break
+			}
+			err = fmt.Errorf("reopening: %w", err)
+		}
+
+		s.update(st, func() {
+			st.State = SubsystemRunning
+			st.Restarts++
+		})
+		log.Info("subsystem restarted")
+	}
+}
+
+// runRecovered runs run, returning why it ended.
+func runRecovered(ctx context.Context, run func(context.Context)) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic: %v", v)
+			logger("supervisor").Error("subsystem panicked", "panic", v, "stack", string(debug.Stack()))
+		}
+	}()
+	run(ctx)
+	return errors.New("exited unexpectedly")
+}
+
+func (s *Supervisor) update(st *SubsystemStatus, f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f()
+}
+
+// Status returns the status of each subsystem, in the order they were
+// started. A nil *Supervisor has none.
+func (s *Supervisor) Status() []SubsystemStatus {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]SubsystemStatus, len(s.subsystems))
+	for i, st := range s.subsystems {
+		statuses[i] = *st
+	}
+	return statuses
+}
+
+// Down returns a description of each subsystem that is not running, e.g.
+// "file watcher is restarting".
+func (s *Supervisor) Down() []string {
+	var down []string
+	for _, st := range s.Status() {
+		if st.State != SubsystemRunning {
+			down = append(down, st.Name+" is "+st.State)
+		}
+	}
+	return down
+}
+
+// SetSupervisor reports sv's subsystems in /status, and marks results stale
+// while any is down. Call it before Start.
+func (s *Server) SetSupervisor(sv *Supervisor) {
+	s.supervisor = sv
+}
+
+// Stop ends every loop and waits for them to return.
+func (s *Supervisor) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fastRestarts = RestartPolicy{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxRetries: 2}
+
+// waitForSubsystem polls sv until its only subsystem satisfies done.
+func waitForSubsystem(t *testing.T, sv *Supervisor, what string, done func(SubsystemStatus) bool) SubsystemStatus {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		st := sv.Status()[0]
+		if done(st) {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("subsystem = %+v, want %s", st, what)
+		}
+	}
+}
+
+// TestSupervisor_Restarts tests that a loop that panics or returns early is
+// reopened and run again, and that it is not once the supervisor stops.
+func TestSupervisor_Restarts(t *testing.T) {
+	sv := NewSupervisor(context.Background(), fastRestarts)
+	var runs, reopens atomic.Int32
+	sv.Go("file watcher", func(ctx context.Context) {
+		switch runs.Add(1) {
+		case 1:
+			panic("boom")
+		case 2:
+			return
+		}
+		<-ctx.Done()
+	}, func() error {
+		reopens.Add(1)
+		return nil
+	})
+
+	st := waitForSubsystem(t, sv, "two restarts", func(st SubsystemStatus) bool { return st.Restarts == 2 })
+	if st.State != SubsystemRunning || st.LastFailure != "exited unexpectedly" || st.LastFailureAt.IsZero() {
+		t.Errorf("subsystem = %+v, want running after the early return", st)
+	}
+	if got := reopens.Load(); got != 2 {
+		t.Errorf("reopened %d times, want 2", got)
+	}
+	if down := sv.Down(); len(down) != 0 {
+		t.Errorf("Down() = %v, want none", down)
+	}
+
+	sv.Stop()
+	if got := runs.Load(); got != 3 {
+		t.Errorf("ran %d times, want 3", got)
+	}
+}
+
+// TestSupervisor_GivesUp tests that a loop that keeps failing is reported
+// down once the policy allows no more restarts.
+func TestSupervisor_GivesUp(t *testing.T) {
+	sv := NewSupervisor(context.Background(), fastRestarts)
+	defer sv.Stop()
+	sv.Go("git watcher", func(context.Context) {}, nil)
+
+	st := waitForSubsystem(t, sv, "failed", func(st SubsystemStatus) bool { return st.State == SubsystemFailed })
+	if st.Restarts != fastRestarts.MaxRetries {
+		t.Errorf("Restarts = %d, want %d", st.Restarts, fastRestarts.MaxRetries)
+	}
+	if down := sv.Down(); len(down) != 1 || down[0] != "git watcher is failed" {
+		t.Errorf("Down() = %v, want the git watcher", down)
+	}
+}
+
+// TestServer_SubsystemDown tests that results are marked stale, and the
+// subsystem reported in /status, while a supervised loop is down.
+func TestServer_SubsystemDown(t *testing.T) {
+	socketPath := testSocketPath(t)
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(checkOutput("1770255832071", 0), "")))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	sv := NewSupervisor(context.Background(), RestartPolicy{MaxRetries: -1})
+	defer sv.Stop()
+	sv.Go("file watcher", func(context.Context) {}, nil)
+	waitForSubsystem(t, sv, "failed", func(st SubsystemStatus) bool { return st.State == SubsystemFailed })
+
+	s := NewServer(socketPath, r)
+	s.SetSupervisor(sv)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	client := unixHTTPClient(socketPath)
+	resp, err := client.Get("http://unix/check?format=json")
+	if err != nil {
+		t.Fatalf("GET /check failed: %v", err)
+	}
+	var result SvelteWatchCheckComplete
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatalf("decoding /check: %v", err)
+	}
+	if !result.Stale || result.StaleReason != "file watcher is failed" {
+		t.Errorf("result stale = %v (%q), want the file watcher's failure", result.Stale, result.StaleReason)
+	}
+
+	resp, err = client.Get("http://unix/status")
+	if err != nil {
+		t.Fatalf("GET /status failed: %v", err)
+	}
+	var status Status
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatalf("decoding /status: %v", err)
+	}
+	if len(status.Subsystems) != 1 || status.Subsystems[0].State != SubsystemFailed {
+		t.Errorf("Subsystems = %+v, want the failed file watcher", status.Subsystems)
+	}
+	if out := FormatStatus(status); !strings.Contains(out, "file watcher FAILED") {
+		t.Errorf("FormatStatus() = %q, want the failed subsystem", out)
+	}
+}
+
+// TestRunner_InterpreterFailure tests that the checker is restarted when
+// its interpreter fails, rather than left running with nothing reading it.
+func TestRunner_InterpreterFailure(t *testing.T) {
+	executor := NewFakeExecutor("", "")
+	executor.newCmd = func() *FakeCmd { return newFakeCmd(strings.Repeat("x", 128<<10) + "\n") }
+	r := NewRunnerWithConfig(RunnerConfig{WorkspacePath: "/workspace", RestartPolicy: fastRestarts}, WithExecutor(executor))
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer r.Stop()
+
+	for deadline := time.Now().Add(2 * time.Second); r.Status().AutoRestarts == 0; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Status() = %+v, want the checker restarted", r.Status())
+		}
+	}
+	if status := r.Status(); !strings.Contains(status.LastExit, "interpreter failed") {
+		t.Errorf("LastExit = %q, want the interpreter's failure", status.LastExit)
+	}
+}
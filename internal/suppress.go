@@ -0,0 +1,214 @@
+package internal
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// =============================================================================
+// Suppression
+// =============================================================================
+
+// IgnoreRule suppresses the diagnostics it matches. Every field that is set
+// must match; at least one must be set.
+type IgnoreRule struct {
+	// Code matches the diagnostic code, e.g. "css_unused_selector" or "2322".
+	Code string `json:"code,omitempty"`
+
+	// Glob matches the workspace-relative filename; "**" matches any number
+	// of directories, e.g. "src/legacy/**".
+	Glob string `json:"glob,omitempty"`
+
+	// Message is a regular expression matched against the message.
+	Message string `json:"message,omitempty"`
+}
+
+// String describes the rule for status output.
+func (r IgnoreRule) String() string {
+	var parts []string
+	if r.Code != "" {
+		parts = append(parts, "code "+r.Code)
+	}
+	if r.Glob != "" {
+		parts = append(parts, "in "+r.Glob)
+	}
+	if r.Message != "" {
+		parts = append(parts, fmt.Sprintf("message /%s/", r.Message))
+	}
+	return strings.Join(parts, " ")
+}
+
+// DefaultExcludedGlobs match generated and third-party output that
+// svelte-check occasionally reports into. Their diagnostics are never
+// actionable, so they are suppressed unless the config sets
+// "excludeGenerated": false.
+var DefaultExcludedGlobs = []string{"**/.svelte-kit/**", "**/node_modules/**"}
+
+// DefaultExcludedRootGlobs are excluded like DefaultExcludedGlobs, but
+// relative to each project's root, where builds write their output. A build
+// directory elsewhere, e.g. src/routes/build, holds sources.
+var DefaultExcludedRootGlobs = []string{"build/**"}
+
+// excludeRules returns an IgnoreRule for each glob.
+func excludeRules(globs []string) []IgnoreRule {
+	rules := make([]IgnoreRule, len(globs))
+	for i, g := range globs {
+		rules[i] = IgnoreRule{Glob: g}
+	}
+	return rules
+}
+
+// SuppressionRules returns the ignore rules a config asks for: the default
+// exclusions unless disabled, then its excludeDiagnostics globs and its
+// ignore rules.
+func SuppressionRules(cfg Config) []IgnoreRule {
+	var rules []IgnoreRule
+	if cfg.ExcludeGenerated == nil || *cfg.ExcludeGenerated {
+		rules = append(rules, excludeRules(DefaultExcludedGlobs)...)
+		for _, dir := range projectDirs(cfg.Projects) {
+			for _, g := range DefaultExcludedRootGlobs {
+				rules = append(rules, IgnoreRule{Glob: path.Join(filepath.ToSlash(dir), g)})
+			}
+		}
+	}
+	rules = append(rules, excludeRules(cfg.ExcludeDiagnostics)...)
+	return append(rules, cfg.Ignore...)
+}
+
+// SuppressionStats reports how many diagnostics of the latest results one
+// ignore rule hides.
+type SuppressionStats struct {
+	Rule  IgnoreRule `json:"rule"`
+	Count int        `json:"count"`
+}
+
+// IgnoreRules is a validated set of IgnoreRule.
+type IgnoreRules struct {
+	rules   []IgnoreRule
+	message []*regexp.Regexp // compiled Message of each rule; nil if unset
+}
+
+// CompileIgnoreRules validates rules.
+func CompileIgnoreRules(rules []IgnoreRule) (*IgnoreRules, error) {
+	compiled := &IgnoreRules{rules: rules, message: make([]*regexp.Regexp, len(rules))}
+	for i, r := range rules {
+		if r.Code == "" && r.Glob == "" && r.Message == "" {
+			return nil, fmt.Errorf("ignore rule %d: set at least one of code, glob, or message", i+1)
+		}
+		if r.Glob != "" {
+			if _, err := path.Match(r.Glob, ""); err != nil {
+				return nil, fmt.Errorf("ignore rule %d: invalid glob %q: %w", i+1, r.Glob, err)
+			}
+		}
+		if r.Message != "" {
+			re, err := regexp.Compile(r.Message)
+			if err != nil {
+				return nil, fmt.Errorf("ignore rule %d: invalid message pattern: %w", i+1, err)
+			}
+			compiled.message[i] = re
+		}
+	}
+	return compiled, nil
+}
+
+// match returns the index of the first rule matching d, or -1.
+func (rs *IgnoreRules) match(d Diagnostic) int {
+	for i, r := range rs.rules {
+		if r.Code != "" && r.Code != fmt.Sprint(d.Code) {
+			continue
+		}
+		if r.Glob != "" && !matchDoubleStar(strings.Split(r.Glob, "/"), strings.Split(d.Filename, "/")) {
+			continue
+		}
+		if re := rs.message[i]; re != nil && !re.MatchString(d.Message) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// Apply removes the diagnostics matched by any rule from result, reducing its
+// counts and adding to Suppressed. Matching uses qualify(result), as for
+// Baseline.Apply.
+func (rs *IgnoreRules) Apply(result SvelteWatchCheckComplete, qualify func(SvelteWatchCheckComplete) SvelteWatchCheckComplete) SvelteWatchCheckComplete {
+	if rs == nil || len(rs.rules) == 0 {
+		return result
+	}
+	result, n := filterResult(result, qualify(result), func(diags []Diagnostic) []bool {
+		matched := make([]bool, len(diags))
+		for i, d := range diags {
+			matched[i] = rs.match(d) >= 0
+		}
+		return matched
+	})
+	result.Suppressed += n
+	return result
+}
+
+// Stats counts the diagnostics of a workspace-relative result hidden by each
+// rule.
+func (rs *IgnoreRules) Stats(result SvelteWatchCheckComplete) []SuppressionStats {
+	if rs == nil {
+		return nil
+	}
+	stats := make([]SuppressionStats, len(rs.rules))
+	for i, r := range rs.rules {
+		stats[i].Rule = r
+	}
+	for _, d := range result.Diagnostics {
+		if i := rs.match(d); i >= 0 {
+			stats[i].Count++
+		}
+	}
+	return stats
+}
+
+// filterResult removes the diagnostics of result whose counterparts in
+// qualified are matched, from Diagnostics, Introduced, and Resolved alike.
+// qualified must hold the same diagnostics in the same order, possibly with
+// rewritten filenames. Error, warning, and file counts are reduced by what was
+// removed from Diagnostics, and the number removed from it is returned.
+func filterResult(result, qualified SvelteWatchCheckComplete, match func([]Diagnostic) []bool) (SvelteWatchCheckComplete, int) {
+	kept, removed := partition(result.Diagnostics, match(qualified.Diagnostics))
+	result.Diagnostics = kept
+
+	problemFiles := make(map[string]bool)
+	for _, d := range kept {
+		problemFiles[d.Filename] = true
+	}
+	clearedFiles := make(map[string]bool)
+	for _, d := range removed {
+		switch d.Type {
+		case "ERROR":
+			result.ErrorCount--
+		case "WARNING":
+			result.WarningCount--
+		}
+		if !problemFiles[d.Filename] {
+			clearedFiles[d.Filename] = true
+		}
+	}
+	result.ErrorCount = max(result.ErrorCount, 0)
+	result.WarningCount = max(result.WarningCount, 0)
+	result.FilesWithProblems = max(result.FilesWithProblems-len(clearedFiles), 0)
+
+	result.Introduced, _ = partition(result.Introduced, match(qualified.Introduced))
+	result.Resolved, _ = partition(result.Resolved, match(qualified.Resolved))
+	return result, len(removed)
+}
+
+// partition splits diags by the matching mask.
+func partition(diags []Diagnostic, matched []bool) (kept, removed []Diagnostic) {
+	for i, d := range diags {
+		if matched[i] {
+			removed = append(removed, d)
+		} else {
+			kept = append(kept, d)
+		}
+	}
+	return kept, removed
+}
@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestCompileIgnoreRules_Invalid(t *testing.T) {
+	for _, rules := range [][]IgnoreRule{
+		{{}},
+		{{Glob: "src/[legacy"}},
+		{{Message: "unterminated ("}},
+	} {
+		if _, err := CompileIgnoreRules(rules); err == nil {
+			t.Errorf("CompileIgnoreRules(%+v) succeeded, want error", rules)
+		}
+	}
+}
+
+func TestIgnoreRules_Apply(t *testing.T) {
+	rules, err := CompileIgnoreRules([]IgnoreRule{
+		{Code: "css_unused_selector", Glob: "src/legacy/**"},
+		{Message: `^Unused export '\w+'$`},
+	})
+	if err != nil {
+		t.Fatalf("CompileIgnoreRules failed: %v", err)
+	}
+
+	unused := Diagnostic{Type: "WARNING", Filename: "src/legacy/old/A.svelte", Code: "css_unused_selector", Message: "Unused CSS selector"}
+	result := SvelteWatchCheckComplete{
+		Diagnostics: []Diagnostic{
+			unused,
+			// Same code outside the glob: kept.
+			{Type: "WARNING", Filename: "src/B.svelte", Code: "css_unused_selector", Message: "Unused CSS selector"},
+			diagAt("src/c.ts", 3, 6133, "Unused export 'foo'"),
+		},
+		ErrorCount:        1,
+		WarningCount:      2,
+		FilesWithProblems: 3,
+	}
+
+	got := rules.Apply(result, identity)
+	if len(got.Diagnostics) != 1 || got.Diagnostics[0].Filename != "src/B.svelte" {
+		t.Errorf("Diagnostics = %+v, want only src/B.svelte", got.Diagnostics)
+	}
+	if got.Suppressed != 2 || got.ErrorCount != 0 || got.WarningCount != 1 || got.FilesWithProblems != 1 {
+		t.Errorf("Suppressed, ErrorCount, WarningCount, FilesWithProblems = %d, %d, %d, %d; want 2, 0, 1, 1",
+			got.Suppressed, got.ErrorCount, got.WarningCount, got.FilesWithProblems)
+	}
+
+	stats := rules.Stats(result)
+	if len(stats) != 2 || stats[0].Count != 1 || stats[1].Count != 1 {
+		t.Errorf("Stats = %+v, want one diagnostic per rule", stats)
+	}
+}
+
+// TestServer_Status_Suppressed tests that /status reports what each ignore
+// rule hides.
+func TestServer_Status_Suppressed(t *testing.T) {
+	socketPath := testSocketPath(t)
+
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(`1770255832071 START "/workspace"
+1770255834342 {"type":"WARNING","filename":"src/legacy/A.svelte","start":{"line":0,"character":0},"end":{"line":0,"character":1},"message":"Unused CSS selector","code":"css_unused_selector"}
+1770255834342 COMPLETED 100 FILES 0 ERRORS 1 WARNINGS 1 FILES_WITH_PROBLEMS
+`, "")))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	rules, err := CompileIgnoreRules([]IgnoreRule{{Glob: "src/legacy/**"}})
+	if err != nil {
+		t.Fatalf("CompileIgnoreRules failed: %v", err)
+	}
+	s := NewServer(socketPath, r)
+	s.SetIgnoreRules(rules)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	if _, err := r.GetLatestEvent(context.Background()); err != nil {
+		t.Fatalf("GetLatestEvent failed: %v", err)
+	}
+
+	resp, err := unixHTTPClient(socketPath).Get("http://unix/status")
+	if err != nil {
+		t.Fatalf("GET /status failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(status.Suppressed) != 1 || status.Suppressed[0].Count != 1 {
+		t.Errorf("Suppressed = %+v, want the rule hiding 1 diagnostic", status.Suppressed)
+	}
+}
+
+func TestSuppressionRules_ExcludeGenerated(t *testing.T) {
+	rules, err := CompileIgnoreRules(SuppressionRules(Config{ExcludeDiagnostics: []string{"dist/**"}}))
+	if err != nil {
+		t.Fatalf("CompileIgnoreRules failed: %v", err)
+	}
+	for _, file := range []string{
+		".svelte-kit/types/src/routes/$types.d.ts",
+		"apps/web/.svelte-kit/ambient.d.ts",
+		"/home/me/app/node_modules/pkg/index.d.ts",
+		"build/server/index.js",
+		"dist/index.js",
+	} {
+		if rules.match(Diagnostic{Filename: file}) < 0 {
+			t.Errorf("%s not suppressed", file)
+		}
+	}
+	for _, file := range []string{"src/lib/build.ts", "src/routes/build/+page.svelte"} {
+		if rules.match(Diagnostic{Filename: file}) >= 0 {
+			t.Errorf("%s suppressed, want kept", file)
+		}
+	}
+
+	// Only each project's own build directory is excluded.
+	projects := Config{Projects: []ProjectConfig{{Name: "web", Dir: "apps/web"}}}
+	rules, err = CompileIgnoreRules(SuppressionRules(projects))
+	if err != nil {
+		t.Fatalf("CompileIgnoreRules failed: %v", err)
+	}
+	if rules.match(Diagnostic{Filename: "apps/web/build/server/index.js"}) < 0 {
+		t.Error("apps/web/build/server/index.js not suppressed")
+	}
+	for _, file := range []string{"build/index.js", "apps/web/src/routes/build/+page.svelte"} {
+		if rules.match(Diagnostic{Filename: file}) >= 0 {
+			t.Errorf("%s suppressed, want kept", file)
+		}
+	}
+
+	if got := SuppressionRules(Config{ExcludeGenerated: new(bool)}); len(got) != 0 {
+		t.Errorf(`SuppressionRules with "excludeGenerated": false = %+v, want none`, got)
+	}
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// svelte-kit sync Tracking
+// =============================================================================
+
+// SyncResult is the outcome of the latest svelte-kit sync in one directory.
+type SyncResult struct {
+	Dir        string    `json:"dir"` // relative to the workspace, "." for the root
+	At         time.Time `json:"at"`
+	DurationMs int64     `json:"durationMs"`
+	OK         bool      `json:"ok"`
+	Error      string    `json:"error,omitempty"`
+	Output     string    `json:"output,omitempty"` // combined output, on failure
+}
+
+// SyncTracker runs svelte-kit sync and remembers the latest result for each
+// directory, so failures reach /status instead of only the daemon's log.
+type SyncTracker struct {
+	workspacePath string
+	pm            PackageManager
+	executor      kexec.Interface
+	audit         *AuditLog
+	hooks         *ExecHooks
+
+	mu      sync.Mutex
+	results map[string]SyncResult
+}
+
+// NewSyncTracker creates a SyncTracker for the workspace.
+func NewSyncTracker(workspacePath string, pm PackageManager, executor kexec.Interface) *SyncTracker {
+	return &SyncTracker{
+		workspacePath: workspacePath,
+		pm:            pm,
+		executor:      executor,
+		results:       make(map[string]SyncResult),
+	}
+}
+
+// SetAuditLog records every sync in a.
+func (t *SyncTracker) SetAuditLog(a *AuditLog) {
+	t.audit = a
+}
+
+// Run runs svelte-kit sync in dir (relative to the workspace) and records
+// the result.
+func (t *SyncTracker) Run(ctx context.Context, dir string) error {
+	dir = filepath.Clean(dir)
+	ctx, span := startSpan(ctx, "svelte-kit sync", "dir", dir)
+	start := time.Now()
+	output, err := svelteKitSync(ctx, filepath.Join(t.workspacePath, dir), t.pm, t.executor)
+	span.End(err)
+
+	result := SyncResult{
+		Dir:        dir,
+		At:         start,
+		DurationMs: time.Since(start).Milliseconds(),
+		OK:         err == nil,
+	}
+	if err != nil {
+		result.Error = describeExit(err)
+		result.Output = strings.TrimSpace(string(output))
+		err = fmt.Errorf("svelte-kit sync failed: %w\n%s", err, output)
+	}
+
+	t.mu.Lock()
+	t.results[dir] = result
+	t.mu.Unlock()
+	t.audit.Record("sync", map[string]any{"dir": dir, "ok": result.OK, "durationMs": result.DurationMs, "error": result.Error})
+	if !result.OK {
+		t.hooks.SyncFailed(ctx, result)
+	}
+	return err
+}
+
+// Results returns the latest result for each directory, sorted by directory.
+func (t *SyncTracker) Results() []SyncResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	results := make([]SyncResult, 0, len(t.results))
+	for _, r := range t.results {
+		results = append(results, r)
+	}
+	slices.SortFunc(results, func(a, b SyncResult) int { return cmp.Compare(a.Dir, b.Dir) })
+	return results
+}
+
+// Failed returns the directories among dirs whose latest sync failed. With
+// no dirs, it considers every directory.
+func (t *SyncTracker) Failed(dirs ...string) []string {
+	var failed []string
+	for _, r := range t.Results() {
+		if !r.OK && (len(dirs) == 0 || slices.Contains(dirs, r.Dir)) {
+			failed = append(failed, r.Dir)
+		}
+	}
+	return failed
+}
+
+// SyncAll runs svelte-kit sync in each SvelteKit project directory. Failures
+// are logged: svelte-check still runs, just with stale types.
+func (t *SyncTracker) SyncAll(ctx context.Context, dirs []string) {
+	for _, dir := range dirs {
+		if !isSvelteKitDir(filepath.Join(t.workspacePath, dir)) {
+			continue
+		}
+		logger("sync").Info("running svelte-kit sync", "dir", dir)
+		if err := t.Run(ctx, dir); err != nil {
+			logger("sync").Error("svelte-kit sync failed", "dir", dir, "error", err)
+		} else {
+			logger("sync").Info("svelte-kit sync completed", "dir", dir)
+		}
+	}
+}
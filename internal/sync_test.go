@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestSyncTracker tests that the latest result per directory is recorded,
+// including output on failure, and that a later success clears the failure.
+func TestSyncTracker(t *testing.T) {
+	executor := &FakeExecutor{}
+	outcomes := []struct {
+		output string
+		err    error
+	}{
+		{"", nil},
+		{"Error: svelte.config.js: Unexpected token\n", errors.New("exit status 1")},
+		{"", nil},
+	}
+	executor.newCmd = func() *FakeCmd {
+		o := outcomes[0]
+		outcomes = outcomes[1:]
+		return &FakeCmd{stdout: io.NopCloser(bytes.NewBufferString(o.output)), waitErr: o.err}
+	}
+	tracker := NewSyncTracker("/workspace", PackageManagerPnpm, executor)
+
+	if err := tracker.Run(context.Background(), "."); err != nil {
+		t.Fatalf("Run(.) failed: %v", err)
+	}
+	if err := tracker.Run(context.Background(), "apps/web/"); err == nil {
+		t.Fatal("expected Run(apps/web) to fail")
+	}
+	if got := executor.currentCmd().dir; got != filepath.Join("/workspace", "apps/web") {
+		t.Errorf("sync ran in %q", got)
+	}
+
+	results := tracker.Results()
+	if len(results) != 2 || results[0].Dir != "." || results[1].Dir != "apps/web" {
+		t.Fatalf("Results() = %+v, want . and apps/web", results)
+	}
+	if !results[0].OK || results[1].OK {
+		t.Errorf("OK = %v, %v; want true, false", results[0].OK, results[1].OK)
+	}
+	if results[1].Error != "exit status 1" || results[1].Output != "Error: svelte.config.js: Unexpected token" {
+		t.Errorf("failed result = %+v", results[1])
+	}
+
+	if got := tracker.Failed(); !reflect.DeepEqual(got, []string{"apps/web"}) {
+		t.Errorf("Failed() = %q, want [apps/web]", got)
+	}
+	if got := tracker.Failed("."); got != nil {
+		t.Errorf("Failed(.) = %q, want none", got)
+	}
+
+	if err := tracker.Run(context.Background(), "apps/web"); err != nil {
+		t.Fatalf("Run(apps/web) failed: %v", err)
+	}
+	if got := tracker.Failed(); got != nil {
+		t.Errorf("Failed() after a successful sync = %q, want none", got)
+	}
+}
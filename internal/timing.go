@@ -0,0 +1,207 @@
+package internal
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// =============================================================================
+// Check Timings
+// =============================================================================
+
+// CheckTimings summarizes how long check cycles take, from the START event to
+// the COMPLETED event. Percentiles cover the most recent timingWindow cycles;
+// Count, TotalMs, and MaxMs cover every cycle since the runner was created.
+type CheckTimings struct {
+	Count   int   `json:"count"`
+	LastMs  int64 `json:"lastMs"`
+	P50Ms   int64 `json:"p50Ms"`
+	P95Ms   int64 `json:"p95Ms"`
+	MaxMs   int64 `json:"maxMs"`
+	TotalMs int64 `json:"totalMs"`
+}
+
+// timingWindow is how many recent cycles the percentiles are computed over.
+const timingWindow = 100
+
+// checkTimer measures check cycles from interpreter event timestamps.
+// It is not safe for concurrent use.
+type checkTimer struct {
+	startedAt int64   // timestamp (ms) of the pending START, 0 if none
+	recent    []int64 // ring of the last timingWindow durations (ms)
+	next      int     // ring position of the next duration
+	count     int
+	last      int64
+	max       int64
+	total     int64
+}
+
+// start records the beginning of a cycle. A zero timestamp means now.
+func (t *checkTimer) start(timestamp int64) {
+	t.startedAt = eventTime(timestamp)
+}
+
+// complete records the end of the pending cycle, if any.
+func (t *checkTimer) complete(timestamp int64) {
+	if t.startedAt == 0 {
+		return
+	}
+	d := max(eventTime(timestamp)-t.startedAt, 0)
+	t.startedAt = 0
+
+	if len(t.recent) < timingWindow {
+		t.recent = append(t.recent, d)
+	} else {
+		t.recent[t.next] = d
+	}
+	t.next = (t.next + 1) % timingWindow
+	t.count++
+	t.last = d
+	t.max = max(t.max, d)
+	t.total += d
+}
+
+// summary returns the aggregates, or nil before the first completed cycle.
+func (t *checkTimer) summary() *CheckTimings {
+	if t.count == 0 {
+		return nil
+	}
+	sorted := slices.Sorted(slices.Values(t.recent))
+	return &CheckTimings{
+		Count:   t.count,
+		LastMs:  t.last,
+		P50Ms:   percentile(sorted, 0.50),
+		P95Ms:   percentile(sorted, 0.95),
+		MaxMs:   t.max,
+		TotalMs: t.total,
+	}
+}
+
+// percentile returns the nearest-rank percentile p (0..1) of sorted values.
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(float64(len(sorted))*p+0.999999) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
+
+// eventTime returns an event timestamp in milliseconds, using the current
+// time for events that carry none.
+func eventTime(timestamp int64) int64 {
+	if timestamp == 0 {
+		return time.Now().UnixMilli()
+	}
+	return timestamp
+}
+
+// =============================================================================
+// Cycle Timelines
+// =============================================================================
+
+// Cycle triggers: what caused a check cycle.
+const (
+	TriggerFS      = "fs"      // a file change the watcher acted on
+	TriggerGit     = "git"     // a branch switch, commit, or other git change
+	TriggerStart   = "start"   // the checker's first process
+	TriggerRestart = "restart" // any other restart, e.g. POST /restart or after a crash
+	TriggerWatch   = "watch"   // svelte-check rechecked on its own
+)
+
+// CycleTrigger is the change behind a restart: its source, when the watcher
+// received it, and when the debounce interval passed without another.
+type CycleTrigger struct {
+	Source      string
+	ReceivedAt  time.Time
+	DebouncedAt time.Time
+}
+
+type cycleTriggerKey struct{}
+
+// ContextWithTrigger returns ctx carrying t, which Runner.Restart records as
+// the cause of the cycle it starts.
+func ContextWithTrigger(ctx context.Context, t CycleTrigger) context.Context {
+	return context.WithValue(ctx, cycleTriggerKey{}, t)
+}
+
+func triggerFrom(ctx context.Context) (CycleTrigger, bool) {
+	t, ok := ctx.Value(cycleTriggerKey{}).(CycleTrigger)
+	return t, ok
+}
+
+// CycleTimeline is when each stage of one check cycle happened, so that a
+// slow cycle can be blamed on the debounce, the process (re)start, or
+// svelte-check itself. Stages a cycle skipped are zero: a cycle svelte-check
+// started on its own has no trigger, debounce, or process start.
+type CycleTimeline struct {
+	Trigger           string    `json:"trigger"`
+	TriggeredAt       time.Time `json:"triggeredAt,omitzero"`      // the watcher received the change
+	DebouncedAt       time.Time `json:"debouncedAt,omitzero"`      // the debounce interval passed
+	ProcessStartedAt  time.Time `json:"processStartedAt,omitzero"` // the new process started
+	StartedAt         time.Time `json:"startedAt,omitzero"`        // the START event
+	FirstDiagnosticAt time.Time `json:"firstDiagnosticAt,omitzero"`
+	CompletedAt       time.Time `json:"completedAt,omitzero"` // the COMPLETED event
+
+	DebounceMs        int64 `json:"debounceMs,omitempty"`        // trigger to debounce
+	RestartMs         int64 `json:"restartMs,omitempty"`         // debounce to process start, stopping the old one
+	StartupMs         int64 `json:"startupMs,omitempty"`         // process start to START
+	FirstDiagnosticMs int64 `json:"firstDiagnosticMs,omitempty"` // START to the first diagnostic
+	CheckMs           int64 `json:"checkMs"`                     // START to COMPLETED
+	TotalMs           int64 `json:"totalMs"`                     // the first stage to COMPLETED
+}
+
+// newTimeline begins the timeline of a cycle caused by t, whose process
+// starts now.
+func newTimeline(t CycleTrigger, first bool) CycleTimeline {
+	c := CycleTimeline{Trigger: t.Source, TriggeredAt: t.ReceivedAt, DebouncedAt: t.DebouncedAt, ProcessStartedAt: time.Now()}
+	if c.Trigger == "" {
+		c.Trigger = TriggerRestart
+		if first {
+			c.Trigger = TriggerStart
+		}
+	}
+	return c
+}
+
+// start records the START event. A second START on the same timeline is a
+// cycle svelte-check started on its own, which begins a timeline of its own.
+func (c *CycleTimeline) start(timestamp int64) {
+	if !c.StartedAt.IsZero() {
+		*c = CycleTimeline{Trigger: TriggerWatch}
+	}
+	c.StartedAt = time.UnixMilli(eventTime(timestamp))
+}
+
+// complete records the COMPLETED event and works out the durations. It
+// reports false, recording nothing, if the cycle's START was not seen.
+func (c *CycleTimeline) complete(result SvelteWatchCheckComplete) bool {
+	if c.StartedAt.IsZero() || !c.CompletedAt.IsZero() {
+		return false
+	}
+	c.CompletedAt = time.UnixMilli(eventTime(result.Timestamp))
+	for _, d := range result.Diagnostics {
+		if at := time.UnixMilli(d.Timestamp); d.Timestamp != 0 && (c.FirstDiagnosticAt.IsZero() || at.Before(c.FirstDiagnosticAt)) {
+			c.FirstDiagnosticAt = at
+		}
+	}
+
+	c.DebounceMs = msBetween(c.TriggeredAt, c.DebouncedAt)
+	c.RestartMs = msBetween(c.DebouncedAt, c.ProcessStartedAt)
+	c.StartupMs = msBetween(c.ProcessStartedAt, c.StartedAt)
+	c.FirstDiagnosticMs = msBetween(c.StartedAt, c.FirstDiagnosticAt)
+	c.CheckMs = msBetween(c.StartedAt, c.CompletedAt)
+	for _, first := range []time.Time{c.TriggeredAt, c.DebouncedAt, c.ProcessStartedAt, c.StartedAt} {
+		if !first.IsZero() {
+			c.TotalMs = msBetween(first, c.CompletedAt)
+			break
+		}
+	}
+	return true
+}
+
+// msBetween returns the milliseconds from one stage to another, or 0 if
+// either is missing.
+func msBetween(from, to time.Time) int64 {
+	if from.IsZero() || to.IsZero() {
+		return 0
+	}
+	return max(to.Sub(from).Milliseconds(), 0)
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCheckTimer_Summary tests the aggregates over a handful of cycles.
+func TestCheckTimer_Summary(t *testing.T) {
+	var timer checkTimer
+	if got := timer.summary(); got != nil {
+		t.Fatalf("summary before any cycle = %+v, want nil", got)
+	}
+
+	for i, d := range []int64{300, 100, 500, 200, 400} {
+		start := int64(1_000_000 * (i + 1))
+		timer.start(start)
+		timer.complete(start + d)
+	}
+
+	got := timer.summary()
+	want := CheckTimings{Count: 5, LastMs: 400, P50Ms: 300, P95Ms: 500, MaxMs: 500, TotalMs: 1500}
+	if *got != want {
+		t.Errorf("summary = %+v, want %+v", *got, want)
+	}
+}
+
+// TestCheckTimer_CompleteWithoutStart tests that an unmatched COMPLETED is ignored.
+func TestCheckTimer_CompleteWithoutStart(t *testing.T) {
+	var timer checkTimer
+	timer.complete(5000)
+	if got := timer.summary(); got != nil {
+		t.Errorf("summary = %+v, want nil", got)
+	}
+}
+
+// TestCheckTimer_Window tests that percentiles cover only recent cycles while
+// the maximum and count cover all of them.
+func TestCheckTimer_Window(t *testing.T) {
+	var timer checkTimer
+	timer.start(1)
+	timer.complete(10_001) // one slow cycle, later pushed out of the window
+	for i := range timingWindow {
+		start := int64(100_000 * (i + 1))
+		timer.start(start)
+		timer.complete(start + 50)
+	}
+
+	got := timer.summary()
+	if got.Count != timingWindow+1 {
+		t.Errorf("Count = %d, want %d", got.Count, timingWindow+1)
+	}
+	if got.MaxMs != 10_000 {
+		t.Errorf("MaxMs = %d, want 10000", got.MaxMs)
+	}
+	if got.P95Ms != 50 {
+		t.Errorf("P95Ms = %d, want 50", got.P95Ms)
+	}
+}
+
+// TestCycleTimeline tests the durations between a restarted cycle's stages,
+// and that a cycle svelte-check starts on its own has only its own.
+func TestCycleTimeline(t *testing.T) {
+	at := time.UnixMilli(1770255830000)
+	c := CycleTimeline{
+		Trigger:          TriggerFS,
+		TriggeredAt:      at,
+		DebouncedAt:      at.Add(300 * time.Millisecond),
+		ProcessStartedAt: at.Add(420 * time.Millisecond),
+	}
+	c.start(1770255832420)
+	result := SvelteWatchCheckComplete{
+		Timestamp:   1770255836920,
+		Diagnostics: []Diagnostic{{Timestamp: 1770255834000}, {Timestamp: 1770255833620}},
+	}
+	if !c.complete(result) {
+		t.Fatal("complete() = false, want true")
+	}
+	got := [...]int64{c.DebounceMs, c.RestartMs, c.StartupMs, c.FirstDiagnosticMs, c.CheckMs, c.TotalMs}
+	if want := [...]int64{300, 120, 2000, 1200, 4500, 6920}; got != want {
+		t.Errorf("durations = %v, want %v", got, want)
+	}
+	if c.complete(result) {
+		t.Error("complete() twice = true, want false")
+	}
+
+	c.start(1770255840000)
+	c.complete(SvelteWatchCheckComplete{Timestamp: 1770255840500})
+	if c.Trigger != TriggerWatch || !c.ProcessStartedAt.IsZero() || c.CheckMs != 500 || c.TotalMs != 500 {
+		t.Errorf("timeline = %+v, want a 500ms watch cycle", c)
+	}
+}
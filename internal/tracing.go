@@ -0,0 +1,593 @@
+package internal
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// Tracing
+// =============================================================================
+
+// DefaultServiceName is the service.name of exported spans when
+// OTEL_SERVICE_NAME is not set.
+const DefaultServiceName = "svelte-check-server"
+
+const (
+	traceExportInterval = 5 * time.Second
+	traceExportTimeout  = 10 * time.Second
+	traceBatchSize      = 512  // spans that trigger an export before the interval
+	traceQueueLimit     = 4096 // spans kept while the collector is unreachable
+)
+
+// Span kinds, as numbered by OTLP.
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+)
+
+// TracerConfig says where a Tracer exports spans.
+type TracerConfig struct {
+	// Endpoint is the OTLP/HTTP traces URL, e.g.
+	// http://localhost:4318/v1/traces. Spans are sent as JSON.
+	Endpoint string
+
+	// Headers are added to every export request, e.g. for authentication.
+	Headers map[string]string
+
+	// Service is the service.name resource attribute; "" for
+	// DefaultServiceName.
+	Service string
+}
+
+// TracerConfigFromEnv returns the config given by the standard OpenTelemetry
+// variables: OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, or
+// OTEL_EXPORTER_OTLP_ENDPOINT with /v1/traces appended, the matching
+// _HEADERS variable, and OTEL_SERVICE_NAME. endpoint, if not empty,
+// overrides the endpoint variables. An empty Endpoint means tracing is not
+// configured.
+func TracerConfigFromEnv(endpoint string) TracerConfig {
+	headers := os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")
+	switch {
+	case endpoint != "":
+	case os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "":
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+		headers = cmp.Or(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS"), headers)
+	case os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "":
+		endpoint = strings.TrimSuffix(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "/") + "/v1/traces"
+	}
+	return TracerConfig{
+		Endpoint: endpoint,
+		Headers:  parseOTLPHeaders(headers),
+		Service:  os.Getenv("OTEL_SERVICE_NAME"),
+	}
+}
+
+// parseOTLPHeaders parses "key1=value1,key2=value2", with URL-encoded
+// values as the OpenTelemetry spec allows.
+func parseOTLPHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+	for pair := range strings.SplitSeq(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(value); err == nil {
+			value = unescaped
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// Tracer records spans and exports them in batches over OTLP/HTTP, so the
+// time between a change and its result can be followed in any
+// OpenTelemetry backend. A nil *Tracer records nothing, and neither do the
+// nil *Spans it returns.
+type Tracer struct {
+	config TracerConfig
+	client *http.Client
+
+	mu     sync.Mutex
+	queue  []*Span
+	failed bool // the last export failed; logged once until one succeeds
+
+	wake    chan struct{} // a batch is ready
+	closing chan struct{}
+	done    chan struct{} // closed once the final export has been made
+	once    sync.Once
+}
+
+// NewTracer returns a Tracer exporting to config.Endpoint. Close it to
+// export the remaining spans.
+func NewTracer(config TracerConfig) *Tracer {
+	config.Service = cmp.Or(config.Service, DefaultServiceName)
+	t := &Tracer{
+		config:  config,
+		client:  &http.Client{Timeout: traceExportTimeout},
+		wake:    make(chan struct{}, 1),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// Close exports the spans ended so far and stops the Tracer, giving up when
+// ctx is done.
+func (t *Tracer) Close(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.once.Do(func() { close(t.closing) })
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(traceExportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.wake:
+		case <-ticker.C:
+		case <-t.closing:
+			t.export()
+			return
+		}
+		t.export()
+	}
+}
+
+// Start starts a span named name as a child of the span in ctx, or of the
+// remote parent from a traceparent header, or as the root of a new trace.
+// attrs are alternating keys and values, as for log/slog.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...any) (context.Context, *Span) {
+	return t.start(ctx, name, spanKindInternal, attrs)
+}
+
+func (t *Tracer) start(ctx context.Context, name string, kind int, attrs []any) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	s := &Span{tracer: t, name: name, kind: kind, start: time.Now(), attrs: attrs}
+	if parent := SpanFromContext(ctx); parent != nil {
+		s.traceID, s.parentID = parent.traceID, parent.spanID
+	} else {
+		_, _ = rand.Read(s.traceID[:])
+	}
+	_, _ = rand.Read(s.spanID[:])
+	return ContextWithSpan(ctx, s), s
+}
+
+// startSpan starts a child of the span in ctx with that span's Tracer. It
+// records nothing when ctx carries no span, so code that is only traced as
+// part of something larger, e.g. a sync within a check cycle, needs no
+// Tracer of its own.
+func startSpan(ctx context.Context, name string, attrs ...any) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name, attrs...)
+}
+
+// queued returns how many ended spans wait to be exported.
+func (t *Tracer) queued() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.queue)
+}
+
+// enqueue queues an ended span for export.
+func (t *Tracer) enqueue(s *Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.queue) >= traceQueueLimit {
+		t.queue = t.queue[1:]
+	}
+	t.queue = append(t.queue, s)
+	if len(t.queue) >= traceBatchSize {
+		select {
+		case t.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// export sends the queued spans. On failure they are kept for the next
+// attempt, up to traceQueueLimit.
+func (t *Tracer) export() {
+	t.mu.Lock()
+	spans := t.queue
+	t.queue = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+
+	err := t.post(spans)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil {
+		if !t.failed {
+			logger("tracing").Warn("could not export spans", "endpoint", t.config.Endpoint, "error", err)
+		}
+		t.failed = true
+		t.queue = append(spans, t.queue...)
+		if n := len(t.queue) - traceQueueLimit; n > 0 {
+			t.queue = t.queue[n:]
+		}
+		return
+	}
+	t.failed = false
+}
+
+func (t *Tracer) post(spans []*Span) error {
+	body, err := json.Marshal(t.encode(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.config.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Span is an operation within a trace. Its methods do nothing on a nil
+// *Span.
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte // zero for a root span
+	remote   bool    // a parent from a traceparent header; never exported
+	name     string
+	kind     int
+	start    time.Time
+
+	mu    sync.Mutex
+	attrs []any
+	end   time.Time
+	err   error
+	ended bool
+}
+
+// SetName renames the span, e.g. once the route of a request is known.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+}
+
+// SetAttributes adds attrs, alternating keys and values, to the span.
+func (s *Span) SetAttributes(attrs ...any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// End ends the span, marking it failed if err is not nil, and queues it for
+// export. Later calls do nothing.
+func (s *Span) End(err error) {
+	if s == nil || s.remote {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended, s.end, s.err = true, time.Now(), err
+	s.mu.Unlock()
+	s.tracer.enqueue(s)
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpan returns ctx carrying s as the parent of spans started from
+// it.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanContextKey{}, s)
+}
+
+// SpanFromContext returns the span ctx carries, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanContextKey{}).(*Span)
+	return s
+}
+
+// remoteParent returns ctx carrying the parent named by a W3C traceparent
+// header, e.g. from an instrumented client, or ctx if header is not valid.
+func (t *Tracer) remoteParent(ctx context.Context, header string) context.Context {
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+	s := &Span{tracer: t, remote: true}
+	if _, err := hex.Decode(s.traceID[:], []byte(parts[1])); err != nil {
+		return ctx
+	}
+	if _, err := hex.Decode(s.spanID[:], []byte(parts[2])); err != nil {
+		return ctx
+	}
+	if s.traceID == [16]byte{} || s.spanID == [8]byte{} {
+		return ctx
+	}
+	return ContextWithSpan(ctx, s)
+}
+
+// =============================================================================
+// Check Cycles
+// =============================================================================
+
+// TraceCycle returns ctx carrying the span of the check cycle in progress,
+// starting one for reason, e.g. "restart", if none is. The cycle runs from
+// whatever triggered it to the next completed check, so that spans started
+// from the returned context, such as a restart or a svelte-kit sync, show
+// where the time between a change and its result goes. A cycle started
+// while ctx carries a span, e.g. that of a POST /restart, is its child.
+func (r *Runner) TraceCycle(ctx context.Context, reason string) context.Context {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.traceCycleLocked(ctx, reason)
+}
+
+func (r *Runner) traceCycleLocked(ctx context.Context, reason string) context.Context {
+	if r.config.Tracer == nil {
+		return ctx
+	}
+	if r.cycle == nil {
+		_, r.cycle = r.config.Tracer.Start(ctx, "check cycle",
+			"checker", r.Name(), "workspace", r.config.WorkspacePath, "reason", reason)
+	}
+	return ContextWithSpan(ctx, r.cycle)
+}
+
+// endCycle ends the check cycle in progress with its result.
+func (r *Runner) endCycle(result SvelteWatchCheckComplete) {
+	r.mu.Lock()
+	cycle := r.cycle
+	r.cycle = nil
+	r.mu.Unlock()
+	cycle.SetAttributes("errors", result.ErrorCount, "warnings", result.WarningCount)
+	cycle.End(nil)
+}
+
+// traceCycle is Runner.TraceCycle for any Checker, returning ctx unchanged
+// for checkers that are not traced.
+func traceCycle(ctx context.Context, c Checker, reason string) context.Context {
+	if r, ok := c.(interface {
+		TraceCycle(context.Context, string) context.Context
+	}); ok {
+		return r.TraceCycle(ctx, reason)
+	}
+	return ctx
+}
+
+// =============================================================================
+// HTTP Requests
+// =============================================================================
+
+// SetTracer records a span for every request the server handles. Call it
+// before Start.
+func (s *Server) SetTracer(t *Tracer) {
+	s.tracer = t
+}
+
+// traceRequests wraps next so that each request is a server span, named
+// after the route it matched and a child of the caller's span when the
+// request has a traceparent header.
+func (s *Server) traceRequests(next http.Handler) http.Handler {
+	if s.tracer == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := s.tracer.remoteParent(r.Context(), r.Header.Get("Traceparent"))
+		ctx, span := s.tracer.start(ctx, r.Method+" "+r.URL.Path, spanKindServer,
+			[]any{"http.request.method", r.Method, "url.path", r.URL.Path})
+		rec := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		r = r.WithContext(ctx)
+		next.ServeHTTP(rec, r)
+
+		if r.Pattern != "" {
+			span.SetName(r.Pattern)
+		}
+		span.SetAttributes("http.response.status_code", rec.status)
+		var err error
+		if rec.status >= http.StatusInternalServerError {
+			err = errors.New(http.StatusText(rec.status))
+		}
+		span.End(err)
+	})
+}
+
+// statusWriter records the status code written through it.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Flush keeps GET /events streaming through the wrapper.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// =============================================================================
+// OTLP Encoding
+// =============================================================================
+
+// The OTLP/HTTP JSON encoding of an export request, limited to what the
+// Tracer sends.
+type (
+	otlpTraces struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            *otlpStatus    `json:"status,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"` // 2 for error
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"` // int64 as a decimal string
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+)
+
+func (t *Tracer) encode(spans []*Span) otlpTraces {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		encoded = append(encoded, s.encode())
+	}
+	version := CurrentVersion().Version
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: otlpAttributes([]any{
+			"service.name", t.config.Service,
+			"service.version", version,
+		})},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: DefaultServiceName, Version: version},
+			Spans: encoded,
+		}},
+	}}}
+}
+
+func (s *Span) encode() otlpSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Attributes:        otlpAttributes(s.attrs),
+	}
+	if s.parentID != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	if s.err != nil {
+		span.Status = &otlpStatus{Code: 2, Message: s.err.Error()}
+	}
+	return span
+}
+
+// otlpAttributes encodes alternating keys and values. A key without a
+// value is dropped.
+func otlpAttributes(attrs []any) []otlpKeyValue {
+	var kvs []otlpKeyValue
+	for i := 0; i+1 < len(attrs); i += 2 {
+		key, ok := attrs[i].(string)
+		if !ok {
+			continue
+		}
+		kvs = append(kvs, otlpKeyValue{Key: key, Value: otlpValueOf(attrs[i+1])})
+	}
+	return kvs
+}
+
+func otlpValueOf(v any) otlpValue {
+	intValue := func(n int64) otlpValue {
+		s := strconv.FormatInt(n, 10)
+		return otlpValue{IntValue: &s}
+	}
+	switch v := v.(type) {
+	case string:
+		return otlpValue{StringValue: &v}
+	case int:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case float64:
+		return otlpValue{DoubleValue: &v}
+	case bool:
+		return otlpValue{BoolValue: &v}
+	default:
+		s := fmt.Sprint(v)
+		return otlpValue{StringValue: &s}
+	}
+}
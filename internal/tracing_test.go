@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fakeCollector is an OTLP/HTTP endpoint that keeps the spans posted to it.
+type fakeCollector struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	spans   []otlpSpan
+	headers http.Header
+}
+
+func newFakeCollector(t *testing.T) *fakeCollector {
+	c := &fakeCollector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var traces otlpTraces
+		if err := json.NewDecoder(r.Body).Decode(&traces); err != nil {
+			t.Errorf("decoding export request: %v", err)
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.headers = r.Header
+		for _, rs := range traces.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				c.spans = append(c.spans, ss.Spans...)
+			}
+		}
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+// find returns the exported span that satisfies match, failing if there is
+// none.
+func (c *fakeCollector) find(t *testing.T, what string, match func(otlpSpan) bool) otlpSpan {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.spans {
+		if match(s) {
+			return s
+		}
+	}
+	t.Fatalf("no %s among %+v", what, c.spans)
+	return otlpSpan{}
+}
+
+// span returns the exported span named name.
+func (c *fakeCollector) span(t *testing.T, name string) otlpSpan {
+	t.Helper()
+	return c.find(t, "span named "+name, func(s otlpSpan) bool { return s.Name == name })
+}
+
+// TestRunner_TraceCycle tests that a restart and the check that follows it
+// are exported as children of one check cycle span.
+func TestRunner_TraceCycle(t *testing.T) {
+	collector := newFakeCollector(t)
+	tracer := NewTracer(TracerConfig{Endpoint: collector.URL, Headers: map[string]string{"Authorization": "Bearer secret"}})
+
+	executor := NewFakeExecutor("", "")
+	executor.newCmd = func() *FakeCmd { return newFakeCmd(checkOutput("1770255834000", 1)) }
+	r := NewRunnerWithConfig(RunnerConfig{WorkspacePath: "/workspace", Tracer: tracer}, WithExecutor(executor))
+	ctx := context.Background()
+	if err := r.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if _, err := r.GetLatestEvent(ctx); err != nil {
+		t.Fatalf("GetLatestEvent failed: %v", err)
+	}
+	if err := r.Restart(ctx); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if _, err := r.GetLatestEvent(ctx); err != nil {
+		t.Fatalf("GetLatestEvent failed: %v", err)
+	}
+	r.Stop()
+	if err := tracer.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	restart := collector.span(t, "restart")
+	cycle := collector.find(t, "parent of the restart", func(s otlpSpan) bool { return s.SpanID == restart.ParentSpanID })
+	if cycle.Name != "check cycle" || cycle.ParentSpanID != "" || cycle.Status != nil {
+		t.Errorf("cycle = %+v, want a root check cycle span without an error", cycle)
+	}
+	if got := attribute(cycle, "reason"); got != "restart" {
+		t.Errorf("cycle reason = %q, want restart", got)
+	}
+	if got := attribute(cycle, "errors"); got != "1" {
+		t.Errorf("cycle errors = %q, want 1", got)
+	}
+	check := collector.find(t, "check in the cycle", func(s otlpSpan) bool {
+		return s.Name == "svelte-check" && s.ParentSpanID == cycle.SpanID
+	})
+	if check.TraceID != cycle.TraceID {
+		t.Errorf("check span = %+v, want the cycle's trace", check)
+	}
+	if got := collector.headers.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the configured header", got)
+	}
+}
+
+// attribute returns the value of s's attribute key as a string.
+func attribute(s otlpSpan, key string) string {
+	for _, kv := range s.Attributes {
+		if kv.Key != key {
+			continue
+		}
+		switch {
+		case kv.Value.StringValue != nil:
+			return *kv.Value.StringValue
+		case kv.Value.IntValue != nil:
+			return *kv.Value.IntValue
+		}
+	}
+	return ""
+}
+
+// TestServer_TraceRequests tests that requests are server spans named after
+// their route and parented by the caller's traceparent.
+func TestServer_TraceRequests(t *testing.T) {
+	collector := newFakeCollector(t)
+	tracer := NewTracer(TracerConfig{Endpoint: collector.URL})
+	socketPath := testSocketPath(t)
+
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(checkOutput("1770255832071", 0), "")))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	s := NewServer(socketPath, r)
+	s.SetTracer(tracer)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	req, _ := http.NewRequest(http.MethodGet, "http://unix/status", nil)
+	req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := unixHTTPClient(socketPath).Do(req)
+	if err != nil {
+		t.Fatalf("GET /status failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if err := tracer.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	span := collector.span(t, "GET /status")
+	if span.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || span.ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("span = %+v, want the traceparent's trace and parent", span)
+	}
+	if span.Kind != spanKindServer || attribute(span, "http.response.status_code") != "200" {
+		t.Errorf("span = %+v, want a server span with status 200", span)
+	}
+}
+
+func TestTracerConfigFromEnv(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318/")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=a%20b, x-team=web")
+	t.Setenv("OTEL_SERVICE_NAME", "")
+
+	c := TracerConfigFromEnv("")
+	if c.Endpoint != "http://collector:4318/v1/traces" {
+		t.Errorf("Endpoint = %q, want /v1/traces appended", c.Endpoint)
+	}
+	if c.Headers["api-key"] != "a b" || c.Headers["x-team"] != "web" {
+		t.Errorf("Headers = %v", c.Headers)
+	}
+	if c := TracerConfigFromEnv("http://other/v1/traces"); c.Endpoint != "http://other/v1/traces" {
+		t.Errorf("Endpoint = %q, want the flag's", c.Endpoint)
+	}
+}
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// tsc Interpreter
+// =============================================================================
+
+// tscDiagnosticRe matches `tsc --pretty false` diagnostics:
+//
+//	src/lib/a.ts(3,7): error TS2322: Type 'string' is not assignable to type 'number'.
+var tscDiagnosticRe = regexp.MustCompile(`^(.+)\((\d+),(\d+)\): (error|warning) TS(\d+): (.*)$`)
+
+// tscFoundRe matches the summary printed at the end of each watch cycle:
+//
+//	[12:00:01 PM] Found 2 errors. Watching for file changes.
+var tscFoundRe = regexp.MustCompile(`Found (\d+) errors?\b`)
+
+// InterpretTscOutput reads `tsc --watch --pretty false --preserveWatchOutput`
+// output and sends the same events InterpretOutput does for svelte-check, so a
+// Runner can supervise tsc unchanged. tsc does not report file counts, so
+// FileCount is always zero.
+// Like InterpretOutput, it stops when ctx is done.
+// The channel is NOT closed when the function returns - caller owns the channel.
+func InterpretTscOutput(ctx context.Context, r io.Reader, events chan<- SvelteCheckEvent) error {
+	log := logger("interpreter")
+	scanner := bufio.NewScanner(r)
+	var diagnostics []Diagnostic
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		log.Debug("output line", "line", line)
+		now := time.Now().UnixMilli()
+
+		switch {
+		case strings.Contains(line, "Starting compilation in watch mode") ||
+			strings.Contains(line, "Starting incremental compilation"):
+			diagnostics = nil
+			if err := sendEvent(ctx, events, SvelteWatchCheckStart{Timestamp: now}); err != nil {
+				return err
+			}
+
+		case tscFoundRe.MatchString(line):
+			if err := sendEvent(ctx, events, tscComplete(now, diagnostics)); err != nil {
+				return err
+			}
+			diagnostics = nil
+
+		case tscDiagnosticRe.MatchString(line):
+			m := tscDiagnosticRe.FindStringSubmatch(line)
+			lineNo, _ := strconv.Atoi(m[2])
+			col, _ := strconv.Atoi(m[3])
+			code, _ := strconv.Atoi(m[5])
+			pos := Position{Line: lineNo - 1, Character: col - 1}
+			diagnostics = append(diagnostics, Diagnostic{
+				Timestamp: now,
+				Type:      strings.ToUpper(m[4]),
+				Filename:  m[1],
+				Start:     pos,
+				End:       pos,
+				Message:   m[6],
+				Code:      code,
+				Source:    "ts",
+			})
+
+		case len(diagnostics) > 0 && strings.HasPrefix(line, "  "):
+			// Continuation of a multi-line message (e.g. an elaboration chain).
+			d := &diagnostics[len(diagnostics)-1]
+			d.Message += "\n" + strings.TrimSpace(line)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// tscComplete builds a completion event from the diagnostics of one cycle.
+func tscComplete(timestamp int64, diagnostics []Diagnostic) SvelteWatchCheckComplete {
+	event := SvelteWatchCheckComplete{Timestamp: timestamp, Diagnostics: diagnostics}
+	files := make(map[string]bool)
+	for _, d := range diagnostics {
+		files[d.Filename] = true
+		if d.Type == "ERROR" {
+			event.ErrorCount++
+		} else {
+			event.WarningCount++
+		}
+	}
+	event.FilesWithProblems = len(files)
+	return event
+}
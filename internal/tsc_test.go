@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestInterpretTscOutput(t *testing.T) {
+	output := `[12:00:00 PM] Starting compilation in watch mode...
+
+src/lib/a.ts(3,7): error TS2322: Type 'string' is not assignable to type 'number'.
+src/lib/b.ts(10,1): error TS2345: Argument of type 'X' is not assignable to parameter of type 'Y'.
+  Property 'id' is missing in type 'X' but required in type 'Y'.
+src/lib/a.ts(4,2): error TS7006: Parameter 'x' implicitly has an 'any' type.
+[12:00:01 PM] Found 3 errors. Watching for file changes.
+
+[12:00:05 PM] File change detected. Starting incremental compilation...
+
+[12:00:06 PM] Found 0 errors. Watching for file changes.
+`
+	ch := make(chan SvelteCheckEvent, 10)
+	if err := InterpretTscOutput(context.Background(), strings.NewReader(output), ch); err != nil {
+		t.Fatalf("InterpretTscOutput error: %v", err)
+	}
+	close(ch)
+
+	var events []SvelteCheckEvent
+	for e := range ch {
+		events = append(events, e)
+	}
+
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4: %#v", len(events), events)
+	}
+	if _, ok := events[0].(SvelteWatchCheckStart); !ok {
+		t.Errorf("events[0] = %T, want SvelteWatchCheckStart", events[0])
+	}
+
+	first, ok := events[1].(SvelteWatchCheckComplete)
+	if !ok {
+		t.Fatalf("events[1] = %T, want SvelteWatchCheckComplete", events[1])
+	}
+	if first.ErrorCount != 3 || first.FilesWithProblems != 2 || len(first.Diagnostics) != 3 {
+		t.Errorf("first = %d errors, %d files with problems, %d diagnostics; want 3, 2, 3",
+			first.ErrorCount, first.FilesWithProblems, len(first.Diagnostics))
+	}
+	d := first.Diagnostics[0]
+	if d.Filename != "src/lib/a.ts" || d.Start != (Position{Line: 2, Character: 6}) || d.Code != 2322 || d.Type != "ERROR" || d.Source != "ts" {
+		t.Errorf("Diagnostics[0] = %+v", d)
+	}
+	if !strings.HasSuffix(first.Diagnostics[1].Message, "\nProperty 'id' is missing in type 'X' but required in type 'Y'.") {
+		t.Errorf("continuation line not appended: %q", first.Diagnostics[1].Message)
+	}
+
+	second, ok := events[3].(SvelteWatchCheckComplete)
+	if !ok || second.ErrorCount != 0 || len(second.Diagnostics) != 0 {
+		t.Errorf("events[3] = %#v, want a clean completion", events[3])
+	}
+}
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/tylergannon/svelte-check-server/pkg/types"
+)
+
+// =============================================================================
+// Version Handshake
+// =============================================================================
+
+// APILevel is the level of the HTTP API the daemon serves. It is raised
+// whenever an endpoint or query parameter is added, so a client can tell a
+// daemon that predates a feature it relies on from a bad request.
+const APILevel = 4
+
+// VersionInfo is what GET /version serves: the daemon's build and the levels
+// of its API and result schema.
+type VersionInfo struct {
+	Version       string `json:"version"`       // module version, or "devel"
+	APILevel      int    `json:"apiLevel"`      // see APILevel
+	SchemaVersion int    `json:"schemaVersion"` // see types.SchemaVersion
+	GoVersion     string `json:"goVersion"`
+}
+
+// CurrentVersion returns the VersionInfo of this binary.
+func CurrentVersion() VersionInfo {
+	return VersionInfo{
+		Version:       buildVersion(),
+		APILevel:      APILevel,
+		SchemaVersion: types.SchemaVersion,
+		GoVersion:     runtime.Version(),
+	}
+}
+
+// buildVersion returns the module version the binary was built from, such
+// as "v0.4.0" when installed with go install, or "devel".
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" || info.Main.Version == "(devel)" {
+		return "devel"
+	}
+	return info.Main.Version
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(CurrentVersion())
+}
+
+// VersionMismatchError is returned by Client.CheckVersion when the daemon is
+// older than the client, so requests for newer features would fail.
+type VersionMismatchError struct {
+	Daemon VersionInfo // zero if the daemon predates GET /version
+	Client VersionInfo
+}
+
+func (e *VersionMismatchError) Error() string {
+	const restart = "restart it with 'svelte-check-server stop' and 'svelte-check-server start'"
+	if e.Daemon.APILevel == 0 {
+		return fmt.Sprintf("the server is older than this client (%s) and has no /version; %s",
+			e.Client.Version, restart)
+	}
+	return fmt.Sprintf("the server is %s (API level %d), older than this client %s (API level %d); %s",
+		e.Daemon.Version, e.Daemon.APILevel, e.Client.Version, e.Client.APILevel, restart)
+}
+
+// WithStrictVersion makes connecting through pkg/client fail with a
+// VersionMismatchError when the daemon is older than the client.
+func WithStrictVersion() ClientOption {
+	return func(o *clientOptions) { o.strictVersion = true }
+}
+
+// StrictVersion reports whether the client was created WithStrictVersion.
+func (c *Client) StrictVersion() bool {
+	return c.strictVersion
+}
+
+// Version returns the daemon's VersionInfo from GET /version. A daemon that
+// predates it answers 404, reported as a zero VersionInfo.
+func (c *Client) Version(ctx context.Context) (VersionInfo, error) {
+	var info VersionInfo
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://unix/version", nil)
+	if err != nil {
+		return info, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return info, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		err = json.NewDecoder(resp.Body).Decode(&info)
+		return info, err
+	case http.StatusNotFound:
+		return info, nil
+	default:
+		return info, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+}
+
+// CheckVersion returns the daemon's VersionInfo and, if its API is older
+// than the client's, a VersionMismatchError. A newer daemon is fine, as the
+// API only grows.
+func (c *Client) CheckVersion(ctx context.Context) (VersionInfo, error) {
+	info, err := c.Version(ctx)
+	if err != nil {
+		return info, err
+	}
+	if info.APILevel < APILevel {
+		return info, &VersionMismatchError{Daemon: info, Client: CurrentVersion()}
+	}
+	return info, nil
+}
+
+// versionCheck is the --strict-version flag of the commands that talk to a
+// running server.
+type versionCheck struct {
+	strict bool
+}
+
+// registerVersionCheck adds --strict-version to fs.
+func registerVersionCheck(fs *flag.FlagSet) *versionCheck {
+	v := &versionCheck{}
+	fs.BoolVar(&v.strict, "strict-version", false, "Fail instead of warning when the server is older than this client")
+	return v
+}
+
+// check warns when the server behind c is older than this binary, or fails
+// with --strict-version. Other errors are left to the command's own request.
+func (v *versionCheck) check(c *Client, out *cliOutput) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	_, err := c.CheckVersion(ctx)
+	var mismatch *VersionMismatchError
+	if !errors.As(err, &mismatch) {
+		return
+	}
+	if v.strict {
+		out.fail(errCodeVersionMismatch, 1, "%v", err)
+	}
+	log.Printf("Warning: %v", err)
+}
@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestClient_CheckVersion tests the handshake against the current server and
+// one that predates GET /version.
+func TestClient_CheckVersion(t *testing.T) {
+	socketPath := testSocketPath(t)
+	r := NewRunner("/workspace", WithExecutor(NewFakeExecutor(checkOutput("1770255832071", 0), "")))
+	s := NewServer(socketPath, r)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	c := &Client{socketPath: socketPath, httpClient: unixHTTPClient(socketPath)}
+	info, err := c.CheckVersion(context.Background())
+	if err != nil {
+		t.Fatalf("CheckVersion failed: %v", err)
+	}
+	if info != CurrentVersion() {
+		t.Errorf("Version() = %+v, want %+v", info, CurrentVersion())
+	}
+
+	old := serveHandler(t, http.NotFoundHandler())
+	_, err = old.CheckVersion(context.Background())
+	var mismatch *VersionMismatchError
+	if !errors.As(err, &mismatch) || !strings.Contains(err.Error(), "restart it") {
+		t.Errorf("CheckVersion() against an old server = %v, want a VersionMismatchError", err)
+	}
+}
+
+// serveHandler serves handler on a new socket and returns a client for it.
+func serveHandler(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	socketPath := testSocketPath(t)
+	s := &http.Server{Handler: handler}
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = s.Serve(l) }()
+	t.Cleanup(func() { _ = s.Close() })
+	return &Client{socketPath: socketPath, httpClient: unixHTTPClient(socketPath)}
+}
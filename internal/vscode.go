@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// =============================================================================
+// VS Code Tasks
+// =============================================================================
+
+// Lines that begin and end each result of watch --format vscode, matched by
+// the background patterns of the problemMatcher in the README.
+const (
+	VSCodeBegin = "svelte-check-server: check started"
+	VSCodeEnd   = "svelte-check-server: check completed"
+)
+
+// FormatVSCode formats result for a VS Code task's problemMatcher: VSCodeBegin,
+// then each diagnostic on one line as
+// "file:line:col:endLine:endCol: severity code: message", with the code left
+// out if it has none, then VSCodeEnd and a summary. Failures, which belong to
+// no file, are printed between them on lines the matcher does not match.
+func FormatVSCode(result SvelteWatchCheckComplete) string {
+	var sb strings.Builder
+	sb.WriteString(VSCodeBegin + "\n")
+	for _, d := range result.Diagnostics {
+		severity := strings.ToLower(d.Type)
+		if d.Code != nil {
+			severity += " " + strings.ReplaceAll(fmt.Sprint(d.Code), " ", "_")
+		}
+		fmt.Fprintf(&sb, "%s:%d:%d:%d:%d: %s: %s\n", d.Filename,
+			d.Start.Line+1, d.Start.Character+1, d.End.Line+1, d.End.Character+1,
+			severity, strings.Join(strings.Fields(d.Message), " "))
+	}
+	for _, f := range result.Failures {
+		fmt.Fprintf(&sb, "svelte-check-server: failure: %s\n", strings.Join(strings.Fields(f), " "))
+	}
+	fmt.Fprintf(&sb, "%s: %d errors, %d warnings (%d files checked)\n", VSCodeEnd,
+		result.ErrorCount, result.WarningCount, result.FileCount)
+	return sb.String()
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// vscodePattern is the problemMatcher regexp documented in the README.
+var vscodePattern = regexp.MustCompile(`^(.+):(\d+):(\d+):(\d+):(\d+): (error|warning)(?: (\S+))?: (.*)$`)
+
+func TestFormatVSCode(t *testing.T) {
+	result := SvelteWatchCheckComplete{
+		FileCount:    10,
+		ErrorCount:   1,
+		WarningCount: 2,
+		Diagnostics: []Diagnostic{
+			{Type: "ERROR", Filename: "src/a:b.ts", Start: Position{Line: 4, Character: 2}, End: Position{Line: 4, Character: 9},
+				Message: "Type 'string' is not assignable\n  to type 'number'.", Code: 2322},
+			{Type: "WARNING", Filename: "src/App.svelte", Message: "Unused CSS selector", Code: "css_unused_selector"},
+			{Type: "WARNING", Filename: "src/c.svelte", Message: "No code"},
+		},
+		Failures: []string{"svelte-check crashed"},
+	}
+	want := strings.Join([]string{
+		VSCodeBegin,
+		"src/a:b.ts:5:3:5:10: error 2322: Type 'string' is not assignable to type 'number'.",
+		"src/App.svelte:1:1:1:1: warning css_unused_selector: Unused CSS selector",
+		"src/c.svelte:1:1:1:1: warning: No code",
+		"svelte-check-server: failure: svelte-check crashed",
+		VSCodeEnd + ": 1 errors, 2 warnings (10 files checked)",
+	}, "\n") + "\n"
+	got := FormatVSCode(result)
+	if got != want {
+		t.Fatalf("FormatVSCode() =\n%s\nwant:\n%s", got, want)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	wantMatches := [][]string{
+		{"src/a:b.ts", "5", "3", "5", "10", "error", "2322", "Type 'string' is not assignable to type 'number'."},
+		{"src/App.svelte", "1", "1", "1", "1", "warning", "css_unused_selector", "Unused CSS selector"},
+		{"src/c.svelte", "1", "1", "1", "1", "warning", "", "No code"},
+	}
+	for i, line := range lines {
+		m := vscodePattern.FindStringSubmatch(line)
+		if i == 0 || i > len(wantMatches) {
+			if m != nil {
+				t.Errorf("the problemMatcher matches %q", line)
+			}
+			continue
+		}
+		if m == nil || strings.Join(m[1:], "|") != strings.Join(wantMatches[i-1], "|") {
+			t.Errorf("the problemMatcher matches %q as %q, want %q", line, m, wantMatches[i-1])
+		}
+	}
+}
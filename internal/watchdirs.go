@@ -0,0 +1,219 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// =============================================================================
+// Runtime Watch Directories
+// =============================================================================
+
+// PathRemover is implemented by FSWatchers that can stop watching a path
+// added with Add, which lets a running Watcher drop watch directories.
+type PathRemover interface {
+	Remove(path string, recursive bool) error
+}
+
+// WatchDirs are the directories a Watcher watches, relative to the
+// workspace.
+type WatchDirs struct {
+	Recursive    []string `json:"recursive"`
+	NonRecursive []string `json:"nonRecursive"`
+}
+
+// ErrInvalidWatchDirs is returned by SetWatchDirs for directories that are
+// outside the workspace or do not exist.
+var ErrInvalidWatchDirs = errors.New("invalid watch directory")
+
+// WatchDirs returns the directories currently watched.
+func (w *Watcher) WatchDirs() WatchDirs {
+	w.dirsMu.Lock()
+	defer w.dirsMu.Unlock()
+	return WatchDirs{
+		Recursive:    slices.Clone(w.config.RecursiveDirs),
+		NonRecursive: slices.Clone(w.config.NonRecursiveDirs),
+	}
+}
+
+// SetWatchDirs replaces the watched directories of a running Watcher:
+// directories no longer listed stop being watched and new ones are added.
+// It returns the directories watched afterwards, which on error include
+// the changes made before it.
+func (w *Watcher) SetWatchDirs(dirs WatchDirs) (WatchDirs, error) {
+	recursive, err := w.cleanWatchDirs(dirs.Recursive)
+	if err != nil {
+		return w.WatchDirs(), err
+	}
+	nonRecursive, err := w.cleanWatchDirs(dirs.NonRecursive)
+	if err != nil {
+		return w.WatchDirs(), err
+	}
+
+	w.dirsMu.Lock()
+	defer w.dirsMu.Unlock()
+	current := func() WatchDirs {
+		return WatchDirs{
+			Recursive:    slices.Clone(w.config.RecursiveDirs),
+			NonRecursive: slices.Clone(w.config.NonRecursiveDirs),
+		}
+	}
+
+	remover, canRemove := w.fs().(PathRemover)
+	if !canRemove && (!containsAll(recursive, w.config.RecursiveDirs) || !containsAll(nonRecursive, w.config.NonRecursiveDirs)) {
+		return current(), errors.New("the watch backend cannot stop watching directories")
+	}
+
+	var errs []error
+	update := func(current *[]string, want []string, rec bool) {
+		for _, dir := range slices.Clone(*current) {
+			if slices.Contains(want, dir) {
+				continue
+			}
+			if err := remover.Remove(filepath.Join(w.config.WorkspacePath, dir), rec); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			*current = slices.DeleteFunc(*current, func(d string) bool { return d == dir })
+		}
+		for _, dir := range want {
+			if slices.Contains(*current, dir) {
+				continue
+			}
+			if err := w.fs().Add(filepath.Join(w.config.WorkspacePath, dir), rec); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			*current = append(*current, dir)
+		}
+	}
+	update(&w.config.RecursiveDirs, recursive, true)
+	update(&w.config.NonRecursiveDirs, nonRecursive, false)
+
+	return current(), errors.Join(errs...)
+}
+
+// cleanWatchDirs validates workspace-relative directories and returns them
+// cleaned and without duplicates.
+func (w *Watcher) cleanWatchDirs(dirs []string) ([]string, error) {
+	var cleaned []string
+	for _, dir := range dirs {
+		rel := filepath.Clean(filepath.FromSlash(dir))
+		if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%w %q: must be relative to the workspace and inside it", ErrInvalidWatchDirs, dir)
+		}
+		info, err := os.Stat(filepath.Join(w.config.WorkspacePath, rel))
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidWatchDirs, dir, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%w %q: not a directory", ErrInvalidWatchDirs, dir)
+		}
+		if !slices.Contains(cleaned, rel) {
+			cleaned = append(cleaned, rel)
+		}
+	}
+	return cleaned, nil
+}
+
+// apply returns dirs with the given directories added and those in remove
+// dropped from both lists. A directory added to one list leaves the other.
+func (d WatchDirs) apply(recursive, nonRecursive, remove []string) WatchDirs {
+	clean := func(dirs []string) []string {
+		var out []string
+		for _, dir := range dirs {
+			out = append(out, filepath.Clean(filepath.FromSlash(dir)))
+		}
+		return out
+	}
+	drop := slices.Concat(clean(remove), clean(recursive), clean(nonRecursive))
+	keep := func(dirs []string) []string {
+		return slices.DeleteFunc(clean(dirs), func(dir string) bool { return slices.Contains(drop, dir) })
+	}
+	return WatchDirs{
+		Recursive:    append(keep(d.Recursive), clean(recursive)...),
+		NonRecursive: append(keep(d.NonRecursive), clean(nonRecursive)...),
+	}
+}
+
+// containsAll reports whether all of subset are in set.
+func containsAll(set, subset []string) bool {
+	for _, s := range subset {
+		if !slices.Contains(set, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleWatchDirs serves GET and PUT /config/watch-dirs.
+func (s *Server) handleWatchDirs(w http.ResponseWriter, r *http.Request) {
+	if s.watcher == nil {
+		http.Error(w, "the server has no watcher", http.StatusNotFound)
+		return
+	}
+
+	dirs := s.watcher.WatchDirs()
+	if r.Method == http.MethodPut {
+		var want WatchDirs
+		if err := json.NewDecoder(r.Body).Decode(&want); err != nil {
+			http.Error(w, "invalid watch directories: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		var err error
+		dirs, err = s.watcher.SetWatchDirs(want)
+		switch {
+		case errors.Is(err, ErrInvalidWatchDirs):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(dirs)
+}
+
+// WatchDirs returns the directories the server watches.
+func (c *Client) WatchDirs(ctx context.Context) (WatchDirs, error) {
+	return c.watchDirs(ctx, http.MethodGet, nil)
+}
+
+// SetWatchDirs replaces the directories the server watches and returns
+// those watched afterwards.
+func (c *Client) SetWatchDirs(ctx context.Context, dirs WatchDirs) (WatchDirs, error) {
+	body, err := json.Marshal(dirs)
+	if err != nil {
+		return WatchDirs{}, err
+	}
+	return c.watchDirs(ctx, http.MethodPut, body)
+}
+
+func (c *Client) watchDirs(ctx context.Context, method string, body []byte) (WatchDirs, error) {
+	req, err := http.NewRequestWithContext(ctx, method, "http://unix/config/watch-dirs", bytes.NewReader(body))
+	if err != nil {
+		return WatchDirs{}, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return WatchDirs{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return WatchDirs{}, fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var dirs WatchDirs
+	err = json.NewDecoder(resp.Body).Decode(&dirs)
+	return dirs, err
+}
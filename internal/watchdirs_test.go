@@ -0,0 +1,217 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// FakePathRemover adds PathRemover to FakeFSWatcher.
+type FakePathRemover struct {
+	*FakeFSWatcher
+	removed []addedPath
+}
+
+func (f *FakePathRemover) Remove(path string, recursive bool) error {
+	f.removed = append(f.removed, addedPath{path: path, recursive: recursive})
+	return nil
+}
+
+func TestWatcher_SetWatchDirs(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"src/app.ts": "", "packages/ui/src/index.ts": "", "README.md": ""})
+	fw := &FakePathRemover{FakeFSWatcher: NewFakeFSWatcher()}
+	config := WatcherConfig{WorkspacePath: root, RecursiveDirs: []string{"src"}, NonRecursiveDirs: []string{"."}}
+	w := NewWatcher(config, WatcherCallbacks{}, fw, nil)
+
+	dirs, err := w.SetWatchDirs(WatchDirs{Recursive: []string{"packages/ui/src/", "packages/ui/src"}, NonRecursive: []string{"."}})
+	if err != nil {
+		t.Fatalf("SetWatchDirs failed: %v", err)
+	}
+	ui := filepath.Join("packages", "ui", "src")
+	if !slices.Equal(dirs.Recursive, []string{ui}) || !slices.Equal(dirs.NonRecursive, []string{"."}) {
+		t.Errorf("SetWatchDirs() = %+v, want packages/ui/src recursive and . non-recursive", dirs)
+	}
+	if want := []addedPath{{filepath.Join(root, "src"), true}}; !slices.Equal(fw.removed, want) {
+		t.Errorf("removed = %v, want %v", fw.removed, want)
+	}
+	if want := []addedPath{{filepath.Join(root, ui), true}}; !slices.Equal(fw.addedPaths, want) {
+		t.Errorf("added = %v, want %v", fw.addedPaths, want)
+	}
+	if got := w.WatchDirs(); !slices.Equal(got.Recursive, dirs.Recursive) {
+		t.Errorf("WatchDirs() = %+v, want %+v", got, dirs)
+	}
+
+	for _, bad := range []string{"../outside", filepath.Join(root, "src"), "missing", "README.md"} {
+		if _, err := w.SetWatchDirs(WatchDirs{Recursive: []string{bad}}); !errors.Is(err, ErrInvalidWatchDirs) {
+			t.Errorf("SetWatchDirs(%s) error = %v, want ErrInvalidWatchDirs", bad, err)
+		}
+	}
+}
+
+func TestWatcher_SetWatchDirs_RemovalUnsupported(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"src/app.ts": "", "lib/util.ts": ""})
+	fw := NewFakeFSWatcher()
+	w := NewWatcher(WatcherConfig{WorkspacePath: root, RecursiveDirs: []string{"src"}}, WatcherCallbacks{}, fw, nil)
+
+	if _, err := w.SetWatchDirs(WatchDirs{Recursive: []string{"lib"}}); err == nil {
+		t.Error("SetWatchDirs dropping src succeeded without a PathRemover, want an error")
+	}
+	dirs, err := w.SetWatchDirs(WatchDirs{Recursive: []string{"src", "lib"}})
+	if err != nil || !slices.Equal(dirs.Recursive, []string{"src", "lib"}) {
+		t.Errorf("SetWatchDirs() = %+v, %v; want src and lib", dirs, err)
+	}
+}
+
+func TestWatchDirs_Apply(t *testing.T) {
+	dirs := WatchDirs{Recursive: []string{"src", "lib"}, NonRecursive: []string{"."}}
+	got := dirs.apply([]string{"packages/ui/"}, []string{"lib"}, []string{"src"})
+	want := WatchDirs{Recursive: []string{filepath.Join("packages", "ui")}, NonRecursive: []string{".", "lib"}}
+	if !slices.Equal(got.Recursive, want.Recursive) || !slices.Equal(got.NonRecursive, want.NonRecursive) {
+		t.Errorf("apply() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRealFSWatcher_Remove(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"src/lib/util.ts": "", "packages/ui/index.ts": ""})
+
+	fw, err := NewRealFSWatcherWithLimit(NewWatcherLimit(0))
+	if err != nil {
+		t.Fatalf("NewRealFSWatcherWithLimit failed: %v", err)
+	}
+	defer func() { _ = fw.Close() }()
+
+	src := filepath.Join(root, "src")
+	for _, add := range []addedPath{{root, false}, {src, true}, {filepath.Join(root, "packages"), true}} {
+		if err := fw.Add(add.path, add.recursive); err != nil {
+			t.Fatalf("Add(%s) failed: %v", add.path, err)
+		}
+	}
+
+	if err := fw.Remove(filepath.Join(root, "packages"), true); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	watched := fw.watcher.WatchList()
+	for _, dir := range watched {
+		if strings.Contains(dir, "packages") {
+			t.Errorf("watching %s after removing packages", dir)
+		}
+	}
+	for _, dir := range []string{root, src, filepath.Join(src, "lib")} {
+		if !slices.Contains(watched, dir) {
+			t.Errorf("WatchList() = %v, want %s still watched", watched, dir)
+		}
+	}
+
+	// The non-recursive root watch outlives the recursive ones below it.
+	if err := fw.Remove(src, true); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if watched := fw.watcher.WatchList(); !slices.Equal(watched, []string{root}) {
+		t.Errorf("WatchList() = %v, want only %s", watched, root)
+	}
+	if err := fw.Remove(src, true); err == nil {
+		t.Error("Remove of an unwatched path succeeded, want an error")
+	}
+}
+
+func TestPollingFSWatcher_Remove(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"package.json": "{}", "src/app.ts": ""})
+
+	p := NewPollingFSWatcher(time.Hour)
+	defer func() { _ = p.Close() }()
+	if err := p.Add(root, false); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := p.Add(filepath.Join(root, "src"), true); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	if err := p.Remove(filepath.Join(root, "src"), true); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	writeFiles(t, root, map[string]string{"src/app.ts": "changed", "src/new.ts": ""})
+	if events := p.poll(); len(events) != 0 {
+		t.Errorf("poll() after Remove = %v, want no events from src", events)
+	}
+
+	writeFiles(t, root, map[string]string{"package.json": `{"name":"app"}`})
+	if events := p.poll(); len(events) != 1 || events[0].Name != filepath.Join(root, "package.json") {
+		t.Errorf("poll() = %v, want package.json still watched", events)
+	}
+}
+
+func TestWatchmanFSWatcher_Remove(t *testing.T) {
+	root := t.TempDir()
+	fake := newFakeWatchman(t)
+
+	w, err := NewWatchmanFSWatcher(fake.sockPath)
+	if err != nil {
+		t.Fatalf("NewWatchmanFSWatcher failed: %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	if err := w.Add(root, true); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	name := fake.command(1)[2]
+	if err := w.Remove(root, true); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if got := fake.command(2); got[0] != "unsubscribe" || got[1] != root || got[2] != name {
+		t.Errorf("command = %v, want unsubscribe %s %v", got, root, name)
+	}
+	if err := w.Remove(root, true); err == nil {
+		t.Error("second Remove succeeded, want an error")
+	}
+}
+
+func TestServer_WatchDirs(t *testing.T) {
+	socketPath := testSocketPath(t)
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"src/app.ts": "", "lib/util.ts": ""})
+
+	executor := NewFakeExecutor("", "")
+	executor.newCmd = func() *FakeCmd { return newFakeCmd("") }
+	r := NewRunner(root, WithExecutor(executor))
+	_ = r.Start(context.Background())
+	defer r.Stop()
+
+	s := NewServer(socketPath, r)
+	fw := &FakePathRemover{FakeFSWatcher: NewFakeFSWatcher()}
+	s.SetWatcher(NewWatcher(WatcherConfig{WorkspacePath: root, RecursiveDirs: []string{"src"}}, WatcherCallbacks{}, fw, nil))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() {
+		_ = s.Stop(context.Background())
+	}()
+
+	c := &Client{socketPath: socketPath, httpClient: unixHTTPClient(socketPath)}
+	ctx := context.Background()
+	dirs, err := c.WatchDirs(ctx)
+	if err != nil || !slices.Equal(dirs.Recursive, []string{"src"}) {
+		t.Fatalf("WatchDirs() = %+v, %v; want src", dirs, err)
+	}
+
+	dirs, err = c.SetWatchDirs(ctx, WatchDirs{Recursive: []string{"lib"}})
+	if err != nil || !slices.Equal(dirs.Recursive, []string{"lib"}) {
+		t.Errorf("SetWatchDirs() = %+v, %v; want lib", dirs, err)
+	}
+	data, _ := json.Marshal(dirs)
+	if !strings.Contains(string(data), `"recursive":["lib"]`) {
+		t.Errorf("JSON = %s, want recursive lib", data)
+	}
+
+	if _, err := c.SetWatchDirs(ctx, WatchDirs{Recursive: []string{"../elsewhere"}}); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("SetWatchDirs(../elsewhere) error = %v, want a 400", err)
+	}
+}
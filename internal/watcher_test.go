@@ -3,7 +3,13 @@ package internal
 import (
 	"context"
 	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/synctest"
 	"time"
@@ -11,9 +17,9 @@ import (
 	"github.com/fsnotify/fsnotify"
 )
 
-// resetWatcherCount resets the global watcher count for testing.
+// resetWatcherCount resets the default watcher count for testing.
 func resetWatcherCount() {
-	globalWatcherCount.Store(0)
+	defaultWatcherLimit.count.Store(0)
 }
 
 // FakeFSWatcher implements FSWatcher for testing.
@@ -76,6 +82,16 @@ func (f *FakeGitBranchWatcher) Close() error {
 	return nil
 }
 
+// FakeGitOperationWatcher adds GitOperationWatcher to FakeGitBranchWatcher.
+type FakeGitOperationWatcher struct {
+	*FakeGitBranchWatcher
+	operationCh chan struct{}
+	operation   string
+}
+
+func (f *FakeGitOperationWatcher) OperationChanged() <-chan struct{} { return f.operationCh }
+func (f *FakeGitOperationWatcher) Operation() string                 { return f.operation }
+
 func TestWatcher_HeadChange_TriggersRestart(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		fsWatcher := NewFakeFSWatcher()
@@ -118,6 +134,56 @@ func TestWatcher_HeadChange_TriggersRestart(t *testing.T) {
 	})
 }
 
+// TestWatcher_RestartCooldown tests that a burst of git changes spread over
+// more than the debounce interval (as in an interactive rebase) restarts
+// once immediately and once after the cooldown, and that Status counts the
+// coalesced restarts.
+func TestWatcher_RestartCooldown(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := NewFakeFSWatcher()
+		gitWatcher := NewFakeGitBranchWatcher()
+
+		var restarts atomic.Int32
+		callbacks := WatcherCallbacks{
+			OnRestart:    func() { restarts.Add(1) },
+			OnSvelteSync: func() {},
+		}
+		config := WatcherConfig{
+			WorkspacePath:   "/fake/workspace",
+			RestartCooldown: 5 * time.Second,
+		}
+
+		w := NewWatcher(config, callbacks, fsWatcher, gitWatcher)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		// Four rebase steps, each past the 250ms debounce.
+		for range 4 {
+			gitWatcher.branchCh <- struct{}{}
+			time.Sleep(time.Second)
+			synctest.Wait()
+		}
+		if got := restarts.Load(); got != 1 {
+			t.Fatalf("restarts during cooldown = %d, want 1", got)
+		}
+
+		time.Sleep(5 * time.Second)
+		synctest.Wait()
+		if got := restarts.Load(); got != 2 {
+			t.Fatalf("restarts after cooldown = %d, want 2", got)
+		}
+
+		if status := w.Status(); status.Restarts != 2 || status.RestartsSuppressed != 2 {
+			t.Errorf("Status() = %+v, want 2 restarts and 2 suppressed", status)
+		}
+		_ = w.Close()
+	})
+}
+
 func TestWatcher_BranchChange_TriggersRestart(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		fsWatcher := NewFakeFSWatcher()
@@ -401,6 +467,7 @@ func TestWatcher_CreateEvent_TriggersRescan(t *testing.T) {
 			Name: "/fake/workspace/src/newdir",
 			Op:   fsnotify.Create,
 		}
+		time.Sleep(eventBatchWindow)
 		synctest.Wait()
 
 		if fsWatcher.rescanCount != 1 {
@@ -601,33 +668,37 @@ func TestWatcher_AddsPaths(t *testing.T) {
 }
 
 func TestWatcherLimit_AcquireAndRelease(t *testing.T) {
-	resetWatcherCount()
-	defer resetWatcherCount()
+	limit := NewWatcherLimit(0)
+	if limit.Max() != DefaultMaxWatchers {
+		t.Errorf("Max() = %d, want DefaultMaxWatchers", limit.Max())
+	}
 
-	if err := acquireWatcher(); err != nil {
-		t.Fatalf("acquireWatcher failed: %v", err)
+	if err := limit.acquire(); err != nil {
+		t.Fatalf("acquire failed: %v", err)
 	}
 
-	if count := WatcherCount(); count != 1 {
-		t.Fatalf("WatcherCount = %d, want 1", count)
+	if count := limit.Count(); count != 1 {
+		t.Fatalf("Count = %d, want 1", count)
 	}
 
-	releaseWatcher()
+	limit.release()
 
-	if count := WatcherCount(); count != 0 {
-		t.Fatalf("WatcherCount = %d, want 0", count)
+	if count := limit.Count(); count != 0 {
+		t.Fatalf("Count = %d, want 0", count)
 	}
 }
 
 func TestWatcherLimit_ExceedsMax(t *testing.T) {
-	resetWatcherCount()
-	defer resetWatcherCount()
-
-	globalWatcherCount.Store(MaxWatchers)
+	limit := NewWatcherLimit(2)
+	for range 2 {
+		if err := limit.acquire(); err != nil {
+			t.Fatalf("acquire failed: %v", err)
+		}
+	}
 
-	err := acquireWatcher()
+	err := limit.acquire()
 	if err == nil {
-		t.Fatal("acquireWatcher should have failed when at MaxWatchers")
+		t.Fatal("acquire should have failed when at the limit")
 	}
 
 	if !errors.Is(err, ErrTooManyWatchers) {
@@ -636,8 +707,7 @@ func TestWatcherLimit_ExceedsMax(t *testing.T) {
 }
 
 func TestWatcherLimit_ConcurrentAcquire(t *testing.T) {
-	resetWatcherCount()
-	defer resetWatcherCount()
+	limit := NewWatcherLimit(DefaultMaxWatchers)
 
 	const numGoroutines = 50
 	var wg sync.WaitGroup
@@ -646,16 +716,46 @@ func TestWatcherLimit_ConcurrentAcquire(t *testing.T) {
 	for range numGoroutines {
 		go func() {
 			defer wg.Done()
-			if err := acquireWatcher(); err != nil {
-				t.Errorf("acquireWatcher failed: %v", err)
+			if err := limit.acquire(); err != nil {
+				t.Errorf("acquire failed: %v", err)
 			}
 		}()
 	}
 
 	wg.Wait()
 
-	if count := WatcherCount(); count != numGoroutines {
-		t.Fatalf("WatcherCount = %d, want %d", count, numGoroutines)
+	if count := limit.Count(); count != numGoroutines {
+		t.Fatalf("Count = %d, want %d", count, numGoroutines)
+	}
+}
+
+func TestWatcherLimit_PerScope(t *testing.T) {
+	resetWatcherCount()
+	defer resetWatcherCount()
+
+	first, second := NewWatcherLimit(1), NewWatcherLimit(1)
+	w1, err := NewRealFSWatcherWithLimit(first)
+	if err != nil {
+		t.Fatalf("NewRealFSWatcherWithLimit failed: %v", err)
+	}
+
+	// Each limit has its own budget, separate from the default one.
+	w2, err := NewRealFSWatcherWithLimit(second)
+	if err != nil {
+		t.Fatalf("NewRealFSWatcherWithLimit with a second limit failed: %v", err)
+	}
+	defer func() { _ = w2.Close() }()
+	if WatcherCount() != 0 {
+		t.Errorf("WatcherCount = %d, want the default limit untouched", WatcherCount())
+	}
+
+	if _, err := NewRealGitBranchWatcherWithLimit(t.TempDir(), NewFakeExecutor("", ""), first); !errors.Is(err, ErrTooManyWatchers) {
+		t.Fatalf("NewRealGitBranchWatcherWithLimit over the limit: err = %v, want ErrTooManyWatchers", err)
+	}
+
+	_ = w1.Close()
+	if first.Count() != 0 {
+		t.Errorf("Count = %d after Close, want 0", first.Count())
 	}
 }
 
@@ -684,3 +784,888 @@ func TestWatcherLimit_RealFSWatcher_IncrementsCount(t *testing.T) {
 		t.Fatalf("WatcherCount = %d after closing watcher, want 0", count)
 	}
 }
+
+func TestWatcher_SourceChange_TriggersOnSourceChange(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := NewFakeFSWatcher()
+
+		var calls int
+		callbacks := WatcherCallbacks{
+			OnRestart:      func() {},
+			OnSvelteSync:   func() {},
+			OnSourceChange: func() { calls++ },
+		}
+
+		w := NewWatcher(WatcherConfig{WorkspacePath: "/fake/workspace"}, callbacks, fsWatcher, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		for _, name := range []string{"a.ts", "b.svelte", "c.ts"} {
+			fsWatcher.events <- fsnotify.Event{Name: "/fake/workspace/src/" + name, Op: fsnotify.Write}
+		}
+		fsWatcher.events <- fsnotify.Event{Name: "/fake/workspace/src/a.ts", Op: fsnotify.Chmod}
+		synctest.Wait()
+
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+
+		if calls != 1 {
+			t.Errorf("OnSourceChange called %d times, want 1 (debounced)", calls)
+		}
+	})
+}
+
+func TestWatcher_Ignore_DropsEvents(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := NewFakeFSWatcher()
+
+		var calls int
+		callbacks := WatcherCallbacks{
+			OnRestart:      func() {},
+			OnSvelteSync:   func() {},
+			OnSourceChange: func() { calls++ },
+		}
+
+		config := WatcherConfig{
+			WorkspacePath: "/fake/workspace",
+			Ignore:        []string{"coverage/**", "**/*.stories.ts"},
+		}
+		w := NewWatcher(config, callbacks, fsWatcher, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		fsWatcher.events <- fsnotify.Event{Name: "/fake/workspace/coverage/lcov-report", Op: fsnotify.Create}
+		fsWatcher.events <- fsnotify.Event{Name: "/fake/workspace/src/lib/Button.stories.ts", Op: fsnotify.Write}
+		synctest.Wait()
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+
+		if calls != 0 || fsWatcher.rescanCount != 0 {
+			t.Errorf("ignored events: OnSourceChange called %d times, Rescan %d times; want 0, 0", calls, fsWatcher.rescanCount)
+		}
+
+		fsWatcher.events <- fsnotify.Event{Name: "/fake/workspace/src/lib/Button.ts", Op: fsnotify.Write}
+		synctest.Wait()
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+
+		if calls != 1 {
+			t.Errorf("OnSourceChange called %d times, want 1", calls)
+		}
+	})
+}
+
+func TestRealFSWatcher_Ignore_SkipsDirectories(t *testing.T) {
+	resetWatcherCount()
+	defer resetWatcherCount()
+
+	root := t.TempDir()
+	for _, dir := range []string{"src/lib", "coverage/lcov-report"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+	}
+
+	fw, err := NewRealFSWatcher()
+	if err != nil {
+		t.Fatalf("NewRealFSWatcher failed: %v", err)
+	}
+	defer func() { _ = fw.Close() }()
+
+	w := NewWatcher(WatcherConfig{WorkspacePath: root, Ignore: []string{"coverage/**"}}, WatcherCallbacks{}, fw, nil)
+	fw.SetIgnore(w.ignored)
+	if err := fw.Add(root, true); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	watched := fw.watcher.WatchList()
+	for _, dir := range watched {
+		if strings.Contains(dir, "coverage") {
+			t.Errorf("watching %s, want coverage skipped", dir)
+		}
+	}
+	if !slices.Contains(watched, filepath.Join(root, "src", "lib")) {
+		t.Errorf("WatchList() = %v, want src/lib watched", watched)
+	}
+}
+
+func TestRealFSWatcher_SkipDirs(t *testing.T) {
+	resetWatcherCount()
+	defer resetWatcherCount()
+
+	root := t.TempDir()
+	for _, dir := range []string{"src/lib", "node_modules/pkg", "src/.svelte-kit/types", "build"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+	}
+	// Only the project's own build directory is skipped, not a route's.
+	writeFiles(t, root, map[string]string{"src/routes/build/+page.svelte": ""})
+
+	fw, err := NewRealFSWatcher()
+	if err != nil {
+		t.Fatalf("NewRealFSWatcher failed: %v", err)
+	}
+	defer func() { _ = fw.Close() }()
+
+	fw.SetSkipDirs(withRootSkipDirs(root, DefaultSkipDirs, map[string]KitPaths{".": DefaultKitPaths}))
+	if err := fw.Add(root, true); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	// A skipped name given as the watched directory itself is still watched.
+	if err := fw.Add(filepath.Join(root, "build"), true); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	watched := fw.watcher.WatchList()
+	slices.Sort(watched)
+	want := []string{
+		root, filepath.Join(root, "build"), filepath.Join(root, "src"), filepath.Join(root, "src", "lib"),
+		filepath.Join(root, "src", "routes"), filepath.Join(root, "src", "routes", "build"),
+	}
+	slices.Sort(want)
+	if !slices.Equal(watched, want) {
+		t.Errorf("WatchList() = %v, want %v", watched, want)
+	}
+
+	fw.SetSkipDirs(nil)
+	_ = fw.Rescan()
+	if !slices.Contains(fw.watcher.WatchList(), filepath.Join(root, "node_modules", "pkg")) {
+		t.Error("node_modules not watched after SetSkipDirs(nil)")
+	}
+}
+
+func TestWatcher_ConfigChange_SyncsAndRestarts(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := NewFakeFSWatcher()
+
+		var calls []string
+		callbacks := WatcherCallbacks{
+			OnRestart:    func() { calls = append(calls, "restart") },
+			OnSvelteSync: func() { calls = append(calls, "sync") },
+		}
+
+		config := WatcherConfig{
+			WorkspacePath:    "/fake/workspace",
+			NonRecursiveDirs: []string{"apps/web"},
+		}
+		w := NewWatcher(config, callbacks, fsWatcher, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		// Not project configuration: below a project root, or Vite's temporary bundle.
+		for _, name := range []string{
+			"/fake/workspace/src/lib/package.json",
+			"/fake/workspace/vite.config.ts.timestamp-1770255832071-abc.mjs",
+		} {
+			fsWatcher.events <- fsnotify.Event{Name: name, Op: fsnotify.Create}
+		}
+		synctest.Wait()
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+		if len(calls) != 0 {
+			t.Fatalf("calls = %v, want none", calls)
+		}
+
+		for _, name := range []string{
+			"/fake/workspace/svelte.config.js",
+			"/fake/workspace/apps/web/vite.config.ts",
+			"/fake/workspace/tsconfig.node.json",
+			"/fake/workspace/package.json",
+		} {
+			fsWatcher.events <- fsnotify.Event{Name: name, Op: fsnotify.Write}
+		}
+		synctest.Wait()
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+
+		if !slices.Equal(calls, []string{"sync", "restart"}) {
+			t.Errorf("calls = %v, want one sync then one restart", calls)
+		}
+	})
+}
+
+func TestWatcher_EnvFileChange_SyncsAndRestarts(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := NewFakeFSWatcher()
+
+		var calls []string
+		callbacks := WatcherCallbacks{
+			OnRestart:    func() { calls = append(calls, "restart") },
+			OnSvelteSync: func() { calls = append(calls, "sync") },
+		}
+
+		config := WatcherConfig{
+			WorkspacePath:    "/fake/workspace",
+			NonRecursiveDirs: []string{"apps/web"},
+		}
+		w := NewWatcher(config, callbacks, fsWatcher, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		fsWatcher.events <- fsnotify.Event{Name: "/fake/workspace/src/.env", Op: fsnotify.Write}
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+		if len(calls) != 0 {
+			t.Fatalf("calls = %v after a .env below a project root, want none", calls)
+		}
+
+		fsWatcher.events <- fsnotify.Event{Name: "/fake/workspace/.env", Op: fsnotify.Write}
+		fsWatcher.events <- fsnotify.Event{Name: "/fake/workspace/apps/web/.env.local", Op: fsnotify.Create}
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+
+		if !slices.Equal(calls, []string{"sync", "restart"}) {
+			t.Errorf("calls = %v, want one sync then one restart", calls)
+		}
+	})
+}
+
+func TestWatcher_LockfileChange(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := NewFakeFSWatcher()
+
+		var (
+			mu       sync.Mutex
+			changed  []string
+			restarts atomic.Int32
+		)
+		callbacks := WatcherCallbacks{
+			OnRestart:    func() { restarts.Add(1) },
+			OnSvelteSync: func() {},
+			OnDependenciesChanged: func(lockfile string) {
+				mu.Lock()
+				defer mu.Unlock()
+				changed = append(changed, lockfile)
+			},
+		}
+
+		config := WatcherConfig{
+			WorkspacePath:     "/fake/workspace",
+			RestartOnLockfile: true,
+			LockfileGrace:     3 * time.Second,
+		}
+		w := NewWatcher(config, callbacks, fsWatcher, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		// An install rewrites the lockfile a few times.
+		for range 3 {
+			fsWatcher.events <- fsnotify.Event{Name: "/fake/workspace/bun.lock", Op: fsnotify.Write}
+			time.Sleep(time.Second)
+		}
+		synctest.Wait()
+
+		mu.Lock()
+		got := slices.Clone(changed)
+		mu.Unlock()
+		if !slices.Equal(got, []string{"bun.lock", "bun.lock", "bun.lock"}) {
+			t.Errorf("OnDependenciesChanged calls = %v, want bun.lock three times", got)
+		}
+		if n := restarts.Load(); n != 0 {
+			t.Fatalf("restarted %d times during the install, want 0", n)
+		}
+
+		time.Sleep(3 * time.Second)
+		synctest.Wait()
+		if n := restarts.Load(); n != 1 {
+			t.Errorf("restarted %d times after the grace period, want 1", n)
+		}
+	})
+}
+
+// countingAdds records the paths fw adds watches for.
+func countingAdds(fw *RealFSWatcher) *[]string {
+	var added []string
+	add := fw.addWatch
+	fw.addWatch = func(path string) error {
+		added = append(added, path)
+		return add(path)
+	}
+	return &added
+}
+
+func TestRealFSWatcher_AddCreated_WalksOnlyNewSubtree(t *testing.T) {
+	resetWatcherCount()
+	defer resetWatcherCount()
+
+	root := t.TempDir()
+	for _, dir := range []string{"src/lib", "src/routes", "static"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+	}
+
+	fw, err := NewRealFSWatcher()
+	if err != nil {
+		t.Fatalf("NewRealFSWatcher failed: %v", err)
+	}
+	defer func() { _ = fw.Close() }()
+	added := countingAdds(fw)
+
+	if err := fw.Add(filepath.Join(root, "src"), true); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if len(*added) != 3 {
+		t.Fatalf("Add watched %v, want src, src/lib, src/routes", *added)
+	}
+
+	// Adding the same tree again does not add any watch twice.
+	*added = nil
+	if err := fw.Add(filepath.Join(root, "src"), true); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if len(*added) != 0 {
+		t.Errorf("second Add watched %v, want nothing", *added)
+	}
+
+	created := filepath.Join(root, "src", "routes", "blog")
+	for _, dir := range []string{"src/routes/blog/[slug]", "src/routes/node_modules", "static/img"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+	}
+	writeFiles(t, root, map[string]string{"src/lib/util.ts": ""})
+
+	*added = nil
+	for _, path := range []string{
+		created,
+		filepath.Join(root, "src", "lib", "util.ts"),         // a file
+		filepath.Join(root, "src", "routes", "node_modules"), // a skipped directory
+		filepath.Join(root, "static", "img"),                 // outside any recursive watch
+	} {
+		if err := fw.AddCreated(path); err != nil {
+			t.Errorf("AddCreated(%s) failed: %v", path, err)
+		}
+	}
+	want := []string{created, filepath.Join(created, "[slug]")}
+	if !slices.Equal(*added, want) {
+		t.Errorf("AddCreated watched %v, want %v", *added, want)
+	}
+
+	// A directory deleted and created again is watched again.
+	if err := os.RemoveAll(created); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	if err := os.Mkdir(created, 0o755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	*added = nil
+	if err := fw.AddCreated(created); err != nil {
+		t.Fatalf("AddCreated failed: %v", err)
+	}
+	if !slices.Equal(*added, []string{created}) {
+		t.Errorf("AddCreated of a recreated directory watched %v, want %v", *added, []string{created})
+	}
+}
+
+// FakeSubtreeWatcher is a FakeFSWatcher that implements SubtreeWatcher.
+type FakeSubtreeWatcher struct {
+	*FakeFSWatcher
+	created []string
+	deleted []string
+}
+
+func (f *FakeSubtreeWatcher) AddCreated(path string) error {
+	f.created = append(f.created, path)
+	return nil
+}
+
+func (f *FakeSubtreeWatcher) RemoveDeleted(path string) {
+	f.deleted = append(f.deleted, path)
+}
+
+func TestWatcher_CreateEvent_AddsCreatedSubtree(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := &FakeSubtreeWatcher{FakeFSWatcher: NewFakeFSWatcher()}
+		w := NewWatcher(WatcherConfig{WorkspacePath: "/fake/workspace"}, WatcherCallbacks{}, fsWatcher, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		fsWatcher.events <- fsnotify.Event{Name: "/fake/workspace/src/newdir", Op: fsnotify.Create}
+		time.Sleep(eventBatchWindow)
+		synctest.Wait()
+
+		if !slices.Equal(fsWatcher.created, []string{"/fake/workspace/src/newdir"}) || fsWatcher.rescanCount != 0 {
+			t.Errorf("AddCreated called with %v, Rescan %d times; want the new directory and no rescan",
+				fsWatcher.created, fsWatcher.rescanCount)
+		}
+	})
+}
+
+func TestWatcher_RemoveAndRenameEvents_RemoveWatches(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := &FakeSubtreeWatcher{FakeFSWatcher: NewFakeFSWatcher()}
+		w := NewWatcher(WatcherConfig{WorkspacePath: "/fake/workspace"}, WatcherCallbacks{}, fsWatcher, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		fsWatcher.events <- fsnotify.Event{Name: "/fake/workspace/src/old", Op: fsnotify.Remove}
+		fsWatcher.events <- fsnotify.Event{Name: "/fake/workspace/src/moved", Op: fsnotify.Rename}
+		fsWatcher.events <- fsnotify.Event{Name: "/fake/workspace/src/app.html", Op: fsnotify.Write}
+		time.Sleep(eventBatchWindow)
+		synctest.Wait()
+
+		want := []string{"/fake/workspace/src/old", "/fake/workspace/src/moved"}
+		if !slices.Equal(fsWatcher.deleted, want) {
+			t.Errorf("RemoveDeleted called with %v, want %v", fsWatcher.deleted, want)
+		}
+	})
+}
+
+func TestWatcher_BatchesEvents(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := &FakeSubtreeWatcher{FakeFSWatcher: NewFakeFSWatcher()}
+		var syncs, restarts int
+		callbacks := WatcherCallbacks{OnRestart: func() { restarts++ }, OnSvelteSync: func() { syncs++ }}
+		w := NewWatcher(WatcherConfig{WorkspacePath: "/ws"}, callbacks, fsWatcher, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		// rm -rf of a feature directory, then a branch switch recreating it.
+		for _, name := range []string{"a/+page.svelte", "a/+page.ts", "a/b/+page.svelte", "a/b", "a"} {
+			fsWatcher.events <- fsnotify.Event{Name: "/ws/src/routes/" + name, Op: fsnotify.Remove}
+		}
+		for _, name := range []string{"a", "a/b", "a/b/+page.svelte", "a/+page.svelte"} {
+			fsWatcher.events <- fsnotify.Event{Name: "/ws/src/routes/" + name, Op: fsnotify.Create}
+		}
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+
+		if want := []string{"/ws/src/routes/a"}; !slices.Equal(fsWatcher.deleted, want) || !slices.Equal(fsWatcher.created, want) {
+			t.Errorf("RemoveDeleted %v, AddCreated %v; want %v for both", fsWatcher.deleted, fsWatcher.created, want)
+		}
+		status := w.Status()
+		if status.Events != 9 || status.SyncTriggers != 2 || status.Rescans != 1 || syncs != 1 || restarts != 0 {
+			t.Errorf("events %d, sync triggers %d, rescans %d, syncs %d, restarts %d; want 9, 2, 1, 1, 0",
+				status.Events, status.SyncTriggers, status.Rescans, syncs, restarts)
+		}
+	})
+}
+
+func TestOutermostPaths(t *testing.T) {
+	paths := []string{"/ws/a/b/c", "/ws/x", "/ws/a", "/ws/x", "/ws/a-b", "/ws/a/b"}
+	if got, want := outermostPaths(paths), []string{"/ws/x", "/ws/a", "/ws/a-b"}; !slices.Equal(got, want) {
+		t.Errorf("outermostPaths() = %v, want %v", got, want)
+	}
+}
+
+func TestRealFSWatcher_RemoveDeleted(t *testing.T) {
+	resetWatcherCount()
+	defer resetWatcherCount()
+
+	root := t.TempDir()
+	for _, dir := range []string{"src/routes/blog/[slug]", "src/lib"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+	}
+
+	fw, err := NewRealFSWatcher()
+	if err != nil {
+		t.Fatalf("NewRealFSWatcher failed: %v", err)
+	}
+	defer func() { _ = fw.Close() }()
+
+	src := filepath.Join(root, "src")
+	if err := fw.Add(src, true); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if n := fw.WatchCount(); n != 5 {
+		t.Fatalf("WatchCount() = %d, want 5", n)
+	}
+
+	// A renamed directory is dropped under its old name and watched under
+	// its new one.
+	blog, posts := filepath.Join(src, "routes", "blog"), filepath.Join(src, "routes", "posts")
+	if err := os.Rename(blog, posts); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	fw.RemoveDeleted(blog)
+	if err := fw.AddCreated(posts); err != nil {
+		t.Fatalf("AddCreated failed: %v", err)
+	}
+	watched := fw.watcher.WatchList()
+	if slices.Contains(watched, blog) || !slices.Contains(watched, filepath.Join(posts, "[slug]")) {
+		t.Errorf("WatchList() = %v, want blog replaced by posts", watched)
+	}
+	if n := fw.WatchCount(); n != 5 {
+		t.Errorf("WatchCount() after rename = %d, want 5", n)
+	}
+
+	// A deleted directory is no longer counted.
+	if err := os.RemoveAll(posts); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	fw.RemoveDeleted(posts)
+	if n := fw.WatchCount(); n != 3 {
+		t.Errorf("WatchCount() after delete = %d, want 3", n)
+	}
+
+	// So is an added root, which is watched again when recreated.
+	if err := os.RemoveAll(src); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	fw.RemoveDeleted(src)
+	if n := fw.WatchCount(); n != 0 {
+		t.Errorf("WatchCount() after deleting the root = %d, want 0", n)
+	}
+	if err := os.MkdirAll(filepath.Join(src, "lib"), 0o755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if err := fw.AddCreated(src); err != nil {
+		t.Fatalf("AddCreated failed: %v", err)
+	}
+	if n := fw.WatchCount(); n != 2 {
+		t.Errorf("WatchCount() after recreating the root = %d, want 2", n)
+	}
+
+	w := NewWatcher(WatcherConfig{WorkspacePath: root}, WatcherCallbacks{}, fw, nil)
+	if status := w.Status(); status.Watches != 2 {
+		t.Errorf("Watcher.Status().Watches = %d, want 2", status.Watches)
+	}
+}
+
+func TestRealFSWatcher_FollowSymlinks(t *testing.T) {
+	resetWatcherCount()
+	defer resetWatcherCount()
+
+	root := t.TempDir()
+	app := filepath.Join(root, "apps", "web")
+	shared := filepath.Join(root, "packages", "shared")
+	for _, dir := range []string{filepath.Join(app, "src", "lib"), filepath.Join(shared, "utils")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+	}
+	link := filepath.Join(app, "src", "lib", "shared")
+	if err := os.Symlink(shared, link); err != nil {
+		t.Fatalf("Symlink failed: %v", err)
+	}
+	// A link back to an ancestor must not loop.
+	if err := os.Symlink(filepath.Join(app, "src"), filepath.Join(shared, "utils", "loop")); err != nil {
+		t.Fatalf("Symlink failed: %v", err)
+	}
+
+	newWatcher := func(follow bool) *RealFSWatcher {
+		fw, err := NewRealFSWatcher()
+		if err != nil {
+			t.Fatalf("NewRealFSWatcher failed: %v", err)
+		}
+		t.Cleanup(func() { _ = fw.Close() })
+		fw.SetFollowSymlinks(follow)
+		if err := fw.Add(filepath.Join(app, "src"), true); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		return fw
+	}
+
+	if watched := newWatcher(false).watcher.WatchList(); slices.Contains(watched, link) {
+		t.Errorf("WatchList() = %v, want the symlink not followed by default", watched)
+	}
+
+	fw := newWatcher(true)
+	watched := fw.watcher.WatchList()
+	slices.Sort(watched)
+	want := []string{filepath.Join(app, "src"), filepath.Join(app, "src", "lib"), link, filepath.Join(link, "utils")}
+	if !slices.Equal(watched, want) {
+		t.Errorf("WatchList() = %v, want %v", watched, want)
+	}
+
+	writeFiles(t, shared, map[string]string{"utils/format.ts": ""})
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-fw.Events():
+			if event.Name == filepath.Join(link, "utils", "format.ts") {
+				return
+			}
+		case <-deadline:
+			t.Fatal("no event under the symlink's path within 5s")
+		}
+	}
+}
+
+func TestWatcher_Stats(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := NewFakeFSWatcher()
+		gitWatcher := NewFakeGitBranchWatcher()
+		config := WatcherConfig{WorkspacePath: "/fake/workspace", Ignore: []string{"coverage/**"}}
+		w := NewWatcher(config, WatcherCallbacks{OnRestart: func() {}, OnSvelteSync: func() {}}, fsWatcher, gitWatcher)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		for _, event := range []fsnotify.Event{
+			{Name: "/fake/workspace/src/routes/+page.ts", Op: fsnotify.Create},
+			{Name: "/fake/workspace/src/routes/+page.svelte", Op: fsnotify.Write},
+			{Name: "/fake/workspace/package.json", Op: fsnotify.Write},
+			{Name: "/fake/workspace/coverage/lcov.info", Op: fsnotify.Write},
+		} {
+			fsWatcher.events <- event
+		}
+		gitWatcher.headCh <- struct{}{}
+		fsWatcher.errors <- fsnotify.ErrEventOverflow
+		time.Sleep(eventBatchWindow)
+		synctest.Wait()
+
+		status := w.Status()
+		if status.Events != 4 || status.Dropped != 1 || status.Overflows != 1 || status.Errors != 1 {
+			t.Errorf("events %d, dropped %d, overflows %d, errors %d; want 4, 1, 1, 1",
+				status.Events, status.Dropped, status.Overflows, status.Errors)
+		}
+		if status.SyncTriggers != 2 || status.RestartTriggers != 2 || status.Rescans != 1 {
+			t.Errorf("syncs %d, restarts %d, rescans %d; want 2, 2, 1",
+				status.SyncTriggers, status.RestartTriggers, status.Rescans)
+		}
+		if want := map[string]int{"src/routes": 2, ".": 1}; !maps.Equal(status.EventsByDir, want) {
+			t.Errorf("EventsByDir = %v, want %v", status.EventsByDir, want)
+		}
+		if e := status.LastEvent; e == nil || e.Path != "coverage/lcov.info" || e.Op != "WRITE" {
+			t.Errorf("LastEvent = %+v, want WRITE coverage/lcov.info", e)
+		}
+	})
+}
+
+func TestWatcher_IsRouteDirEvent(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"src/routes/blog/[slug]", "src/lib/server"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+	}
+	writeFiles(t, root, map[string]string{"src/routes/blog/util.ts": ""})
+	w := NewWatcher(WatcherConfig{WorkspacePath: root}, WatcherCallbacks{}, NewFakeFSWatcher(), nil)
+
+	for _, tt := range []struct {
+		path string
+		op   fsnotify.Op
+		want bool
+	}{
+		{"src/routes/blog/[slug]", fsnotify.Create, true},
+		{"src/routes/blog/util.ts", fsnotify.Create, false},
+		{"src/routes/blog/[slug]", fsnotify.Write, false},
+		{"src/routes/(app)", fsnotify.Remove, true},
+		{"src/routes/[...rest]", fsnotify.Remove, true},
+		{"src/routes/about", fsnotify.Rename, true},
+		{"src/routes/blog/notes.md", fsnotify.Remove, false},
+		{"src/lib/server", fsnotify.Create, false},
+		{"src/routes", fsnotify.Remove, false},
+	} {
+		event := fsnotify.Event{Name: filepath.Join(root, tt.path), Op: tt.op}
+		if got := w.isRouteDirEvent(event); got != tt.want {
+			t.Errorf("isRouteDirEvent(%s %s) = %v, want %v", tt.op, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWatcher_RouteDirCreated_TriggersSync(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "src", "routes", "blog")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := NewFakeFSWatcher()
+		var syncs int
+		callbacks := WatcherCallbacks{OnRestart: func() {}, OnSvelteSync: func() { syncs++ }}
+		w := NewWatcher(WatcherConfig{WorkspacePath: root}, callbacks, fsWatcher, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		fsWatcher.events <- fsnotify.Event{Name: dir, Op: fsnotify.Create}
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+
+		if syncs != 1 {
+			t.Errorf("OnSvelteSync called %d times, want 1", syncs)
+		}
+	})
+}
+
+func TestWatcher_SyncOn_TriggersSync(t *testing.T) {
+	root := t.TempDir()
+
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := NewFakeFSWatcher()
+		var syncs, restarts int
+		callbacks := WatcherCallbacks{OnRestart: func() { restarts++ }, OnSvelteSync: func() { syncs++ }}
+		config := WatcherConfig{WorkspacePath: root, SyncOn: []string{"src/params/*", "src/app.d.ts"}}
+		w := NewWatcher(config, callbacks, fsWatcher, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		fsWatcher.events <- fsnotify.Event{Name: filepath.Join(root, "src", "params", "slug.ts"), Op: fsnotify.Create}
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+		fsWatcher.events <- fsnotify.Event{Name: filepath.Join(root, "src", "app.d.ts"), Op: fsnotify.Write}
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+		fsWatcher.events <- fsnotify.Event{Name: filepath.Join(root, "src", "lib", "util.ts"), Op: fsnotify.Write}
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+
+		if syncs != 2 {
+			t.Errorf("OnSvelteSync called %d times, want 2", syncs)
+		}
+		if restarts != 0 {
+			t.Errorf("OnRestart called %d times, want 0", restarts)
+		}
+	})
+}
+
+func TestGlobDir(t *testing.T) {
+	for _, tt := range []struct {
+		glob      string
+		dir       string
+		recursive bool
+	}{
+		{"*.d.ts", ".", false},
+		{"ambient.d.ts", ".", false},
+		{"types/*.d.ts", "types", false},
+		{"types/**/*.d.ts", "types", true},
+		{"apps/web/*.d.ts", filepath.Join("apps", "web"), false},
+		{"**/*.d.ts", ".", true},
+	} {
+		dir, recursive := globDir(tt.glob)
+		if dir != tt.dir || recursive != tt.recursive {
+			t.Errorf("globDir(%q) = %q, %v; want %q, %v", tt.glob, dir, recursive, tt.dir, tt.recursive)
+		}
+	}
+}
+
+func TestWatcher_RestartOn_TriggersRestart(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"types/globals.d.ts": "", "src/app.ts": ""})
+
+	synctest.Test(t, func(t *testing.T) {
+		fsWatcher := NewFakeFSWatcher()
+		var syncs, restarts int
+		callbacks := WatcherCallbacks{OnRestart: func() { restarts++ }, OnSvelteSync: func() { syncs++ }}
+		config := WatcherConfig{
+			WorkspacePath:    root,
+			NonRecursiveDirs: []string{"."},
+			RecursiveDirs:    []string{"src"},
+			RestartOn:        []string{"*.d.ts", "types/**/*.d.ts", "src/**/*.d.ts", "missing/*.d.ts"},
+		}
+		w := NewWatcher(config, callbacks, fsWatcher, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		// Only types needs a watch of its own.
+		want := []addedPath{{root, false}, {filepath.Join(root, "src"), true}, {filepath.Join(root, "types"), true}}
+		if !slices.Equal(fsWatcher.addedPaths, want) {
+			t.Errorf("added paths = %v, want %v", fsWatcher.addedPaths, want)
+		}
+
+		fsWatcher.events <- fsnotify.Event{Name: filepath.Join(root, "types", "globals.d.ts"), Op: fsnotify.Write}
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+		fsWatcher.events <- fsnotify.Event{Name: filepath.Join(root, "types", "README.md"), Op: fsnotify.Write}
+		time.Sleep(300 * time.Millisecond)
+		synctest.Wait()
+
+		if restarts != 1 || syncs != 0 {
+			t.Errorf("restarts, syncs = %d, %d; want 1, 0", restarts, syncs)
+		}
+	})
+}
+
+func TestWatcher_GitOperation_DefersRestarts(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		gitWatcher := &FakeGitOperationWatcher{
+			FakeGitBranchWatcher: NewFakeGitBranchWatcher(),
+			operationCh:          make(chan struct{}),
+		}
+		var restarts int
+		callbacks := WatcherCallbacks{OnRestart: func() { restarts++ }, OnSvelteSync: func() {}}
+		w := NewWatcher(WatcherConfig{WorkspacePath: "/fake/workspace"}, callbacks, NewFakeFSWatcher(), gitWatcher)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Start(ctx)
+		synctest.Wait()
+
+		gitWatcher.operation = "rebase"
+		gitWatcher.operationCh <- struct{}{}
+		for range 3 {
+			gitWatcher.headCh <- struct{}{}
+			gitWatcher.branchCh <- struct{}{}
+			time.Sleep(time.Second)
+		}
+		synctest.Wait()
+		if restarts != 0 {
+			t.Fatalf("OnRestart called %d times during the rebase, want 0", restarts)
+		}
+
+		gitWatcher.operation = ""
+		gitWatcher.operationCh <- struct{}{}
+		synctest.Wait()
+		if restarts != 1 {
+			t.Errorf("OnRestart called %d times after the rebase, want 1", restarts)
+		}
+	})
+}
+
+func TestWatcher_Status_ReportsWatcherLimit(t *testing.T) {
+	limit := NewWatcherLimit(5)
+	fw, err := NewRealFSWatcherWithLimit(limit)
+	if err != nil {
+		t.Fatalf("NewRealFSWatcherWithLimit failed: %v", err)
+	}
+	w := NewWatcher(WatcherConfig{WorkspacePath: t.TempDir(), Limit: limit}, WatcherCallbacks{}, fw, nil)
+	defer func() { _ = w.Close() }()
+
+	if status := w.Status(); status.Watchers != 1 || status.MaxWatchers != 5 {
+		t.Errorf("Status() watchers = %d of %d, want 1 of 5", status.Watchers, status.MaxWatchers)
+	}
+}
@@ -0,0 +1,393 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// Watchman Watcher
+// =============================================================================
+
+// WatchmanSocket returns the path of the Watchman daemon's socket: the
+// WATCHMAN_SOCK environment variable, or what `watchman get-sockname` reports,
+// which starts the daemon if it is not running.
+func WatchmanSocket(executor kexec.Interface) (string, error) {
+	if sock := os.Getenv("WATCHMAN_SOCK"); sock != "" {
+		return sock, nil
+	}
+
+	out, err := executor.Command("watchman", "--no-pretty", "get-sockname").Output()
+	if err != nil {
+		return "", fmt.Errorf("watchman get-sockname: %w", err)
+	}
+	var resp struct {
+		Sockname string `json:"sockname"`
+		Error    string `json:"error"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return "", fmt.Errorf("watchman get-sockname: %w", err)
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("watchman get-sockname: %s", resp.Error)
+	}
+	if resp.Sockname == "" {
+		return "", errors.New("watchman get-sockname: no socket reported")
+	}
+	return resp.Sockname, nil
+}
+
+// WatchmanFSWatcher implements FSWatcher with subscriptions to a running
+// Watchman daemon. Watchman watches each project root once, so very large
+// repositories do not need an inotify watch per directory, and it survives
+// event queue overflows by recrawling on its own.
+type WatchmanFSWatcher struct {
+	conn      net.Conn
+	responses chan watchmanPDU
+	events    chan fsnotify.Event
+	errors    chan error
+	ready     chan struct{} // signalled when queue grows
+	done      chan struct{}
+	stopped   chan struct{} // closed when the connection is no longer read
+	delivered chan struct{} // closed when events are no longer sent
+	closing   sync.Once
+
+	cmdMu sync.Mutex // serializes commands; responses arrive in order
+
+	mu       sync.Mutex
+	subs     map[string]string // subscription name -> directory names are relative to
+	added    map[watchedPath]watchmanSub
+	lastSub  int // numbers subscription names
+	skipDirs []string
+	queue    []fsnotify.Event
+}
+
+// watchmanSub identifies the subscription of an added path.
+type watchmanSub struct {
+	name string
+	root string // the watch root it was made on
+}
+
+// watchmanPDU is a response or unilateral message from Watchman, reduced to
+// the fields used here.
+type watchmanPDU struct {
+	Error         string `json:"error"`
+	Unilateral    bool   `json:"unilateral"`
+	Log           string `json:"log"`
+	Subscription  string `json:"subscription"`
+	Canceled      bool   `json:"canceled"`
+	FreshInstance bool   `json:"is_fresh_instance"`
+
+	Files []watchmanFile `json:"files"`
+
+	// watch-project
+	Watch        string `json:"watch"`
+	RelativePath string `json:"relative_path"`
+}
+
+// isUnilateral reports whether pdu was sent unprompted rather than in
+// response to a command.
+func (pdu watchmanPDU) isUnilateral() bool {
+	return pdu.Unilateral || pdu.Log != "" || pdu.Subscription != ""
+}
+
+// watchmanFile is one entry of a subscription's files, with the fields
+// requested by Add.
+type watchmanFile struct {
+	Name   string `json:"name"`
+	Exists bool   `json:"exists"`
+	New    bool   `json:"new"`
+	Type   string `json:"type"` // "f", "d", "l", ...
+}
+
+// NewWatchmanFSWatcher connects to the Watchman daemon listening on sockPath.
+func NewWatchmanFSWatcher(sockPath string) (*WatchmanFSWatcher, error) {
+	conn, err := net.Dial("unix", sockPath)
+	if err != nil {
+		return nil, fmt.Errorf("connect to watchman: %w", err)
+	}
+
+	w := &WatchmanFSWatcher{
+		conn:      conn,
+		responses: make(chan watchmanPDU, 1),
+		events:    make(chan fsnotify.Event),
+		errors:    make(chan error),
+		ready:     make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+		delivered: make(chan struct{}),
+		subs:      make(map[string]string),
+		added:     make(map[watchedPath]watchmanSub),
+		skipDirs:  DefaultSkipDirs,
+	}
+	go w.read()
+	go w.deliver()
+	return w, nil
+}
+
+func (w *WatchmanFSWatcher) Events() <-chan fsnotify.Event {
+	return w.events
+}
+
+func (w *WatchmanFSWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Add subscribes to changes of path: a file, a directory's entries, or with
+// recursive everything below it except skipped directories, which Watchman
+// filters out itself.
+func (w *WatchmanFSWatcher) Add(path string, recursive bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	dir, expr := path, []any{"true"}
+	switch {
+	case !info.IsDir():
+		dir, expr = filepath.Dir(path), []any{"name", filepath.Base(path)}
+	case !recursive:
+		expr = []any{"dirname", "", []any{"depth", "eq", 0}}
+	default:
+		w.mu.Lock()
+		skipped := []any{"anyof"}
+		for _, name := range w.skipDirs {
+			pattern := "**/" + name + "/**"
+			if filepath.IsAbs(name) {
+				// Watchman matches paths relative to the subscribed one.
+				if !isWithin(path, name) {
+					continue
+				}
+				rel, _ := filepath.Rel(path, name)
+				pattern = filepath.ToSlash(rel) + "/**"
+			}
+			skipped = append(skipped, []any{"match", pattern, "wholename", map[string]bool{"includedotfiles": true}})
+		}
+		if len(skipped) > 1 {
+			expr = []any{"not", skipped}
+		}
+		w.mu.Unlock()
+	}
+
+	watch, err := w.command("watch-project", dir)
+	if err != nil {
+		return err
+	}
+
+	query := map[string]any{
+		"expression":              expr,
+		"fields":                  []string{"name", "exists", "new", "type"},
+		"empty_on_fresh_instance": true,
+	}
+	if watch.RelativePath != "" {
+		query["relative_root"] = watch.RelativePath
+	}
+
+	w.mu.Lock()
+	w.lastSub++
+	name := fmt.Sprintf("svelte-check-server-%d-%d", os.Getpid(), w.lastSub)
+	w.subs[name] = dir
+	w.mu.Unlock()
+
+	if _, err := w.command("subscribe", watch.Watch, name, query); err != nil {
+		w.mu.Lock()
+		delete(w.subs, name)
+		w.mu.Unlock()
+		return err
+	}
+	w.mu.Lock()
+	w.added[watchedPath{path: path, recursive: recursive}] = watchmanSub{name: name, root: watch.Watch}
+	w.mu.Unlock()
+	return nil
+}
+
+// Remove unsubscribes from the changes of a path added with Add.
+func (w *WatchmanFSWatcher) Remove(path string, recursive bool) error {
+	wp := watchedPath{path: path, recursive: recursive}
+	w.mu.Lock()
+	sub, ok := w.added[wp]
+	w.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%s is not watched", path)
+	}
+
+	if _, err := w.command("unsubscribe", sub.root, sub.name); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	delete(w.added, wp)
+	delete(w.subs, sub.name)
+	w.mu.Unlock()
+	return nil
+}
+
+// Rescan is a no-op: Watchman picks up new directories itself.
+func (w *WatchmanFSWatcher) Rescan() error {
+	return nil
+}
+
+// SetSkipDirs replaces DefaultSkipDirs for subsequent recursive Adds, as for
+// RealFSWatcher.
+func (w *WatchmanFSWatcher) SetSkipDirs(names []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.skipDirs = names
+}
+
+// Close disconnects from Watchman, which ends the subscriptions, and closes
+// the Events channel.
+func (w *WatchmanFSWatcher) Close() error {
+	var err error
+	w.closing.Do(func() {
+		close(w.done)
+		err = w.conn.Close()
+		<-w.stopped
+		<-w.delivered
+		close(w.events)
+	})
+	return err
+}
+
+// command sends a command and waits for its response.
+func (w *WatchmanFSWatcher) command(args ...any) (watchmanPDU, error) {
+	w.cmdMu.Lock()
+	defer w.cmdMu.Unlock()
+
+	data, err := json.Marshal(args)
+	if err != nil {
+		return watchmanPDU{}, err
+	}
+	if _, err := w.conn.Write(append(data, '\n')); err != nil {
+		return watchmanPDU{}, fmt.Errorf("watchman %s: %w", args[0], err)
+	}
+
+	select {
+	case pdu := <-w.responses:
+		if pdu.Error != "" {
+			return pdu, fmt.Errorf("watchman %s: %s", args[0], pdu.Error)
+		}
+		return pdu, nil
+	case <-w.stopped:
+		return watchmanPDU{}, fmt.Errorf("watchman %s: connection closed", args[0])
+	}
+}
+
+// read dispatches messages from the connection: responses to command,
+// subscription updates to the event queue.
+func (w *WatchmanFSWatcher) read() {
+	defer close(w.stopped)
+
+	dec := json.NewDecoder(w.conn)
+	for {
+		var pdu watchmanPDU
+		if err := dec.Decode(&pdu); err != nil {
+			select {
+			case <-w.done:
+			default:
+				w.report(fmt.Errorf("watchman connection lost: %w", err))
+			}
+			return
+		}
+
+		if !pdu.isUnilateral() {
+			select {
+			case w.responses <- pdu:
+			case <-w.done:
+				return
+			}
+			continue
+		}
+		if pdu.Canceled {
+			w.report(fmt.Errorf("watchman canceled subscription %s", pdu.Subscription))
+			continue
+		}
+		w.enqueue(w.translate(pdu))
+	}
+}
+
+// translate converts a subscription update to events. Fresh instances, sent
+// after Watchman restarts or recrawls, carry no files because of
+// empty_on_fresh_instance and produce none.
+func (w *WatchmanFSWatcher) translate(pdu watchmanPDU) []fsnotify.Event {
+	w.mu.Lock()
+	dir, ok := w.subs[pdu.Subscription]
+	w.mu.Unlock()
+	if !ok || pdu.FreshInstance {
+		return nil
+	}
+
+	var events []fsnotify.Event
+	for _, f := range pdu.Files {
+		event := fsnotify.Event{Name: filepath.Join(dir, filepath.FromSlash(f.Name))}
+		switch {
+		case !f.Exists:
+			event.Op = fsnotify.Remove
+		case f.New:
+			event.Op = fsnotify.Create
+		case f.Type == "d":
+			continue // entries changed; they are reported themselves
+		default:
+			event.Op = fsnotify.Write
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// enqueue hands events to deliver without blocking read, which must keep
+// reading so command responses are not held up behind unread events.
+func (w *WatchmanFSWatcher) enqueue(events []fsnotify.Event) {
+	if len(events) == 0 {
+		return
+	}
+	w.mu.Lock()
+	w.queue = append(w.queue, events...)
+	w.mu.Unlock()
+
+	select {
+	case w.ready <- struct{}{}:
+	default:
+	}
+}
+
+// deliver sends queued events to Events in order.
+func (w *WatchmanFSWatcher) deliver() {
+	defer close(w.delivered)
+
+	for {
+		select {
+		case <-w.ready:
+		case <-w.done:
+			return
+		}
+
+		w.mu.Lock()
+		events := w.queue
+		w.queue = nil
+		w.mu.Unlock()
+
+		for _, event := range events {
+			select {
+			case w.events <- event:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// report sends err to Errors without blocking.
+func (w *WatchmanFSWatcher) report(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
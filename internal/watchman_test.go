@@ -0,0 +1,272 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fakeWatchman serves the subset of the Watchman JSON protocol used by
+// WatchmanFSWatcher. Every directory is its own watch root unless root is
+// set, in which case watch-project reports paths relative to it.
+type fakeWatchman struct {
+	t        *testing.T
+	sockPath string
+	root     string
+	fail     string // a command to answer with an error
+
+	mu       sync.Mutex
+	conn     net.Conn
+	commands [][]any
+}
+
+func newFakeWatchman(t *testing.T) *fakeWatchman {
+	t.Helper()
+	f := &fakeWatchman{t: t, sockPath: testSocketPath(t)}
+	ln, err := net.Listen("unix", f.sockPath)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.conn = conn
+		f.mu.Unlock()
+		f.serve(conn)
+	}()
+	return f
+}
+
+func (f *fakeWatchman) serve(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var cmd []any
+		if err := json.Unmarshal(scanner.Bytes(), &cmd); err != nil {
+			f.t.Errorf("bad command %q: %v", scanner.Text(), err)
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, cmd)
+		f.mu.Unlock()
+
+		name := cmd[0].(string)
+		resp := map[string]any{"version": "2024.01.01.00"}
+		switch {
+		case name == f.fail:
+			resp["error"] = "unable to resolve root"
+		case name == "watch-project":
+			dir := cmd[1].(string)
+			if f.root == "" {
+				resp["watch"] = dir
+			} else {
+				rel, _ := filepath.Rel(f.root, dir)
+				resp["watch"], resp["relative_path"] = f.root, rel
+			}
+		case name == "subscribe":
+			resp["subscribe"] = cmd[2]
+		}
+		f.send(resp)
+	}
+}
+
+// send writes a PDU to the client.
+func (f *fakeWatchman) send(pdu map[string]any) {
+	data, _ := json.Marshal(pdu)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, _ = f.conn.Write(append(data, '\n'))
+}
+
+// command returns the i-th command received.
+func (f *fakeWatchman) command(i int) []any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i >= len(f.commands) {
+		f.t.Fatalf("got %d commands, want at least %d", len(f.commands), i+1)
+	}
+	return f.commands[i]
+}
+
+func receiveEvents(t *testing.T, w FSWatcher, n int) []fsnotify.Event {
+	t.Helper()
+	var events []fsnotify.Event
+	for len(events) < n {
+		select {
+		case event := <-w.Events():
+			events = append(events, event)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d events %v, want %d", len(events), events, n)
+		}
+	}
+	return events
+}
+
+func TestWatchmanFSWatcher_Recursive(t *testing.T) {
+	root := t.TempDir()
+	fake := newFakeWatchman(t)
+
+	w, err := NewWatchmanFSWatcher(fake.sockPath)
+	if err != nil {
+		t.Fatalf("NewWatchmanFSWatcher failed: %v", err)
+	}
+	defer func() { _ = w.Close() }()
+	w.SetSkipDirs([]string{"node_modules", filepath.Join(root, "build"), filepath.Join(t.TempDir(), "build")})
+
+	if err := w.Add(root, true); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	if got := fake.command(0); got[0] != "watch-project" || got[1] != root {
+		t.Errorf("first command = %v, want watch-project %s", got, root)
+	}
+	subscribe := fake.command(1)
+	if subscribe[0] != "subscribe" || subscribe[1] != root {
+		t.Fatalf("second command = %v, want subscribe %s", subscribe, root)
+	}
+	query, _ := json.Marshal(subscribe[3])
+	if !strings.Contains(string(query), `["not",["anyof",["match","**/node_modules/**","wholename"`) {
+		t.Errorf("query = %s, want node_modules excluded", query)
+	}
+	// Skipped paths are matched relative to the root, and those outside it
+	// are left out.
+	if !strings.Contains(string(query), `["match","build/**","wholename"`) || strings.Count(string(query), `"match"`) != 2 {
+		t.Errorf("query = %s, want only the root's build excluded", query)
+	}
+	if strings.Contains(string(query), "relative_root") {
+		t.Errorf("query = %s, want no relative_root for a watch root", query)
+	}
+	name := subscribe[2]
+
+	// The initial result of a subscription produces no events.
+	fake.send(map[string]any{"subscription": name, "unilateral": true, "is_fresh_instance": true})
+	fake.send(map[string]any{"subscription": name, "unilateral": true, "files": []map[string]any{
+		{"name": "src/lib/util.ts", "exists": true, "new": true, "type": "f"},
+		{"name": "src/routes/+page.svelte", "exists": true, "new": false, "type": "f"},
+		{"name": "src/routes", "exists": true, "new": false, "type": "d"},
+		{"name": "src/old.ts", "exists": false, "new": false, "type": "f"},
+	}})
+
+	want := []fsnotify.Event{
+		{Name: filepath.Join(root, "src", "lib", "util.ts"), Op: fsnotify.Create},
+		{Name: filepath.Join(root, "src", "routes", "+page.svelte"), Op: fsnotify.Write},
+		{Name: filepath.Join(root, "src", "old.ts"), Op: fsnotify.Remove},
+	}
+	if got := receiveEvents(t, w, len(want)); !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestWatchmanFSWatcher_RelativeRoot(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"apps/web/package.json": "{}"})
+	fake := newFakeWatchman(t)
+	fake.root = root
+
+	w, err := NewWatchmanFSWatcher(fake.sockPath)
+	if err != nil {
+		t.Fatalf("NewWatchmanFSWatcher failed: %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	web := filepath.Join(root, "apps", "web")
+	if err := w.Add(web, false); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := w.Add(filepath.Join(web, "package.json"), false); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	dirQuery, _ := json.Marshal(fake.command(1)[3])
+	if !strings.Contains(string(dirQuery), `"expression":["dirname","",["depth","eq",0]]`) ||
+		!strings.Contains(string(dirQuery), `"relative_root":"apps/web"`) {
+		t.Errorf("directory query = %s, want its entries relative to apps/web", dirQuery)
+	}
+	fileQuery, _ := json.Marshal(fake.command(3)[3])
+	if !strings.Contains(string(fileQuery), `"expression":["name","package.json"]`) {
+		t.Errorf("file query = %s, want package.json by name", fileQuery)
+	}
+
+	fake.send(map[string]any{"subscription": fake.command(3)[2], "unilateral": true, "files": []map[string]any{
+		{"name": "package.json", "exists": true, "new": false, "type": "f"},
+	}})
+	want := fsnotify.Event{Name: filepath.Join(web, "package.json"), Op: fsnotify.Write}
+	if got := receiveEvents(t, w, 1)[0]; got != want {
+		t.Errorf("event = %v, want %v", got, want)
+	}
+}
+
+func TestWatchmanFSWatcher_Add_Error(t *testing.T) {
+	fake := newFakeWatchman(t)
+	fake.fail = "watch-project"
+
+	w, err := NewWatchmanFSWatcher(fake.sockPath)
+	if err != nil {
+		t.Fatalf("NewWatchmanFSWatcher failed: %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	err = w.Add(t.TempDir(), true)
+	if err == nil || !strings.Contains(err.Error(), "unable to resolve root") {
+		t.Errorf("Add error = %v, want the watchman error", err)
+	}
+}
+
+func TestWatchmanFSWatcher_Close(t *testing.T) {
+	fake := newFakeWatchman(t)
+
+	w, err := NewWatchmanFSWatcher(fake.sockPath)
+	if err != nil {
+		t.Fatalf("NewWatchmanFSWatcher failed: %v", err)
+	}
+	_ = w.Close()
+
+	if _, ok := <-w.Events(); ok {
+		t.Error("Events not closed after Close")
+	}
+	if err := w.Add(t.TempDir(), true); err == nil {
+		t.Error("Add after Close succeeded, want an error")
+	}
+}
+
+func TestWatchmanSocket(t *testing.T) {
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv("WATCHMAN_SOCK", "/tmp/watchman.sock")
+		sock, err := WatchmanSocket(NewFakeExecutor("", ""))
+		if err != nil || sock != "/tmp/watchman.sock" {
+			t.Errorf("WatchmanSocket() = %q, %v; want /tmp/watchman.sock", sock, err)
+		}
+	})
+
+	t.Run("get-sockname", func(t *testing.T) {
+		t.Setenv("WATCHMAN_SOCK", "")
+		executor := NewFakeExecutor(`{"version":"2024.01.01.00","sockname":"/run/watchman/sock"}`, "")
+		sock, err := WatchmanSocket(executor)
+		if err != nil || sock != "/run/watchman/sock" {
+			t.Errorf("WatchmanSocket() = %q, %v; want /run/watchman/sock", sock, err)
+		}
+		if got := executor.commandLine(); got != "watchman --no-pretty get-sockname" {
+			t.Errorf("command = %q", got)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Setenv("WATCHMAN_SOCK", "")
+		_, err := WatchmanSocket(NewFakeExecutor(`{"error":"no state dir"}`, ""))
+		if err == nil || !strings.Contains(err.Error(), "no state dir") {
+			t.Errorf("WatchmanSocket() error = %v, want the watchman error", err)
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"errors"
+	"maps"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// =============================================================================
+// Watcher Statistics
+// =============================================================================
+
+// WatchEvent describes a filesystem event in GET /status.
+type WatchEvent struct {
+	Path string    `json:"path"` // relative to the workspace
+	Op   string    `json:"op"`   // e.g. "CREATE", "WRITE|CHMOD"
+	At   time.Time `json:"at"`
+}
+
+// watcherStats counts what a Watcher saw, so "it didn't notice my change"
+// can be diagnosed from /status.
+type watcherStats struct {
+	mu              sync.Mutex
+	events          int
+	byDir           map[string]int // workspace-relative directory -> events
+	dropped         int
+	overflows       int
+	errors          int
+	rescans         int
+	syncTriggers    int
+	restartTriggers int
+	ruleTriggers    map[string]int
+	lastEvent       *WatchEvent
+
+	// pending is the first restart trigger since the last restart, and
+	// lastTrigger the trigger of the last restart.
+	pending     CycleTrigger
+	lastTrigger CycleTrigger
+}
+
+// event records an event received from the FSWatcher; dropped events
+// matched an ignore glob and were not acted on.
+func (s *watcherStats) event(workspace string, event fsnotify.Event, dropped bool) {
+	rel, err := filepath.Rel(workspace, event.Name)
+	if err != nil {
+		rel = event.Name
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events++
+	s.lastEvent = &WatchEvent{Path: filepath.ToSlash(rel), Op: event.Op.String(), At: time.Now()}
+	if dropped {
+		s.dropped++
+		return
+	}
+	if s.byDir == nil {
+		s.byDir = make(map[string]int)
+	}
+	s.byDir[filepath.ToSlash(filepath.Dir(rel))]++
+}
+
+// error records an error reported by the FSWatcher.
+func (s *watcherStats) error(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors++
+	if errors.Is(err, fsnotify.ErrEventOverflow) {
+		s.overflows++
+	}
+}
+
+func (s *watcherStats) rescan() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rescans++
+}
+
+func (s *watcherStats) syncTriggered() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncTriggers++
+}
+
+// restartTriggered records a restart triggered by source, TriggerFS or
+// TriggerGit.
+func (s *watcherStats) restartTriggered(source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.restartTriggers++
+	if s.pending.Source == "" {
+		s.pending = CycleTrigger{Source: source, ReceivedAt: time.Now()}
+	}
+}
+
+// restarting records that the debounced restart is starting.
+func (s *watcherStats) restarting() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastTrigger, s.pending = s.pending, CycleTrigger{}
+	s.lastTrigger.DebouncedAt = time.Now()
+}
+
+func (s *watcherStats) ruleTriggered(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ruleTriggers == nil {
+		s.ruleTriggers = make(map[string]int)
+	}
+	s.ruleTriggers[name]++
+}
+
+// fill copies the counters into status.
+func (s *watcherStats) fill(status *WatcherStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status.Events = s.events
+	status.Dropped = s.dropped
+	status.Overflows = s.overflows
+	status.Errors = s.errors
+	status.Rescans = s.rescans
+	status.SyncTriggers = s.syncTriggers
+	status.RestartTriggers = s.restartTriggers
+	if len(s.byDir) > 0 {
+		status.EventsByDir = make(map[string]int, len(s.byDir))
+		for dir, n := range s.byDir {
+			status.EventsByDir[dir] = n
+		}
+	}
+	if len(s.ruleTriggers) > 0 {
+		status.RuleTriggers = maps.Clone(s.ruleTriggers)
+	}
+	if s.lastEvent != nil {
+		e := *s.lastEvent
+		status.LastEvent = &e
+	}
+}
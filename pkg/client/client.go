@@ -0,0 +1,233 @@
+// Package client talks to a running svelte-check-server daemon over its Unix
+// socket, for editor helpers, CI wrappers, and other Go tools.
+//
+// The package follows semantic versioning: its exported identifiers and the
+// JSON fields of the types it returns are only removed or changed
+// incompatibly in a new major version. Everything else in this module is
+// internal to the daemon.
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tylergannon/svelte-check-server/internal"
+	"github.com/tylergannon/svelte-check-server/pkg/types"
+)
+
+// ErrNotRunning is returned by Connect when no daemon serves the workspace.
+var ErrNotRunning = errors.New("svelte-check-server is not running")
+
+// CheckOptions selects what Check requests.
+type CheckOptions = internal.CheckOptions
+
+// CheckResponse is a check result as judged by the daemon's policy: the
+// formatted output, the failing severity (empty if the check passed), and
+// the exit code the check command would use.
+type CheckResponse = internal.CheckResponse
+
+// TypedCheckResponse is a check result decoded for Go callers, as judged by
+// the daemon's policy like CheckResponse.
+type TypedCheckResponse = internal.TypedCheckResponse
+
+// Severity is a kind of problem a check result can fail on.
+type Severity = internal.Severity
+
+// The severities a CheckResponse can fail on.
+const (
+	SeverityFailure = internal.SeverityFailure
+	SeverityError   = internal.SeverityError
+	SeverityWarning = internal.SeverityWarning
+)
+
+// Status is the daemon's health as reported by GET /status.
+type Status = internal.Status
+
+// CheckResult is a completed check: its counts, diagnostics, and when and
+// on which commit it ran.
+type CheckResult = types.SvelteWatchCheckComplete
+
+// Diagnostic is one error or warning of a CheckResult.
+type Diagnostic = types.Diagnostic
+
+// FileDiagnostics is a change to the diagnostics of one file of a
+// FileSubscription, versioned per file from 1; Cleared is set when none are
+// left.
+type FileDiagnostics = types.FileDiagnostics
+
+// FileSubscription delivers the diagnostics of the files an editor has
+// open; see SubscribeFiles.
+type FileSubscription = internal.FileSubscription
+
+// Filter selects the diagnostics Diagnostics returns: Severity
+// SeverityError for errors only, a workspace-relative Glob such as
+// "src/routes/**", and Codes such as "2322".
+type Filter = internal.DiagnosticFilter
+
+// Summary is the counts of the diagnostics Diagnostics returns.
+type Summary = internal.Summary
+
+// Client is a connection to the daemon of one workspace. It is safe for
+// concurrent use.
+type Client struct {
+	c *internal.Client
+}
+
+// Option configures a Client.
+type Option = internal.ClientOption
+
+// WithDialTimeout bounds connecting to the daemon (default 1s).
+func WithDialTimeout(d time.Duration) Option {
+	return internal.WithDialTimeout(d)
+}
+
+// WithResponseTimeout bounds how long each request waits for the daemon to
+// start responding (default: no limit). Requests are otherwise bounded only
+// by their context, since Check waits for a check in progress.
+func WithResponseTimeout(d time.Duration) Option {
+	return internal.WithResponseTimeout(d)
+}
+
+// WithTCP connects to a daemon started with --listen at addr, such as one
+// inside a devcontainer, authenticating with its token.
+func WithTCP(addr, token string) Option {
+	return internal.WithTCP(addr, token)
+}
+
+// WithSocket connects to the socket at path instead of the workspace's, such
+// as one forwarded from a remote machine with ssh -L.
+func WithSocket(path string) Option {
+	return internal.WithSocket(path)
+}
+
+// WithStrictVersion makes Connect fail with a *VersionMismatchError when the
+// daemon is older than this package, e.g. one started before an upgrade,
+// instead of failing later on requests it does not understand.
+func WithStrictVersion() Option {
+	return internal.WithStrictVersion()
+}
+
+// VersionInfo is a daemon's build and API level, as returned by Version.
+type VersionInfo = internal.VersionInfo
+
+// VersionMismatchError reports a daemon older than this package; its
+// message tells the user to restart it.
+type VersionMismatchError = internal.VersionMismatchError
+
+// versionTimeout bounds the version handshake of WithStrictVersion.
+const versionTimeout = 5 * time.Second
+
+// Connect returns a Client for the daemon serving workspace, or
+// ErrNotRunning if there is none. workspace may be a subdirectory of the
+// directory the daemon was started in.
+func Connect(workspace string, opts ...Option) (*Client, error) {
+	c, err := internal.NewClient(workspace, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !c.IsServerRunning() {
+		return nil, ErrNotRunning
+	}
+	if c.StrictVersion() {
+		ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+		defer cancel()
+		if _, err := c.CheckVersion(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return &Client{c: c}, nil
+}
+
+// Workspace returns the directory the daemon was started in.
+func (c *Client) Workspace() string {
+	return c.c.Workspace()
+}
+
+// SocketPath returns the path of the daemon's socket.
+func (c *Client) SocketPath() string {
+	return c.c.SocketPath()
+}
+
+// Check returns the latest check result, waiting for a check in progress
+// unless opts.AllowStale is set.
+func (c *Client) Check(ctx context.Context, opts CheckOptions) (CheckResponse, error) {
+	return c.c.CheckWith(ctx, opts)
+}
+
+// CheckTyped is like Check but returns the result itself, with its counts,
+// diagnostics, and timestamps, in place of formatted output.
+func (c *Client) CheckTyped(ctx context.Context, opts CheckOptions) (TypedCheckResponse, error) {
+	return c.c.CheckTyped(ctx, opts)
+}
+
+// Diagnostics returns the diagnostics of the latest result that pass f, and
+// their counts, waiting for a check in progress.
+func (c *Client) Diagnostics(ctx context.Context, f Filter) ([]Diagnostic, Summary, error) {
+	return c.c.Diagnostics(ctx, "", f)
+}
+
+// Subscribe delivers the current result and then each new one as a check
+// cycle completes, reconnecting with backoff if the connection to the
+// daemon is lost. The channel is closed when ctx is done. An error is
+// returned only if the daemon cannot be reached at first.
+func (c *Client) Subscribe(ctx context.Context) (<-chan CheckResult, error) {
+	return c.c.Subscribe(ctx, "")
+}
+
+// SubscribeProject is like Subscribe for a single named project.
+func (c *Client) SubscribeProject(ctx context.Context, project string) (<-chan CheckResult, error) {
+	return c.c.Subscribe(ctx, project)
+}
+
+// SubscribeFiles pushes the diagnostics of files, absolute or relative to
+// the workspace, as they change: first those of each file that has any,
+// then a FileDiagnostics for each change. FileSubscription.SetFiles
+// replaces the files. The channel is closed when ctx is done or the
+// connection to the daemon is lost.
+func (c *Client) SubscribeFiles(ctx context.Context, files []string) (*FileSubscription, error) {
+	return c.c.SubscribeFiles(ctx, files)
+}
+
+// WaitForClean waits until a result passes the daemon's policy (see
+// CheckResponse) and returns it. The current result counts. If ctx is done
+// first, it returns the last result seen and ctx's error.
+func (c *Client) WaitForClean(ctx context.Context) (CheckResult, error) {
+	return c.c.WaitForClean(ctx, "")
+}
+
+// Version returns the daemon's build and API level. It is zero for a daemon
+// that predates GET /version.
+func (c *Client) Version(ctx context.Context) (VersionInfo, error) {
+	return c.c.Version(ctx)
+}
+
+// CheckVersion is like Version but also returns a *VersionMismatchError if
+// the daemon is older than this package, for callers that would rather warn
+// than fail as WithStrictVersion does.
+func (c *Client) CheckVersion(ctx context.Context) (VersionInfo, error) {
+	return c.c.CheckVersion(ctx)
+}
+
+// Status returns the daemon's health: checker state, restarts, watcher
+// counters, and the latest svelte-kit syncs.
+func (c *Client) Status(ctx context.Context) (Status, error) {
+	return c.c.Status(ctx)
+}
+
+// Healthz returns nil if the daemon is healthy, or an error naming each
+// checker or subsystem that has stopped or given up restarting.
+func (c *Client) Healthz(ctx context.Context) error {
+	return c.c.Healthz(ctx)
+}
+
+// Restart restarts the named project's checker, or every checker when
+// project is empty, and waits until they have started.
+func (c *Client) Restart(ctx context.Context, project string) error {
+	return c.c.Restart(ctx, project)
+}
+
+// Stop asks the daemon to shut down.
+func (c *Client) Stop(ctx context.Context) error {
+	return c.c.Stop(ctx)
+}
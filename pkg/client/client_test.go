@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tylergannon/svelte-check-server/internal"
+)
+
+// serve answers requests to the daemon of a new workspace with handler.
+func serve(t *testing.T, handler http.Handler) (workspace string) {
+	t.Helper()
+	// The socket is named after the workspace, so keep its path short.
+	workspace, err := os.MkdirTemp("", "scs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(workspace) })
+
+	if _, err := Connect(workspace); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("Connect without a daemon = %v, want ErrNotRunning", err)
+	}
+
+	socketPath, err := internal.SocketPathForWorkspace(workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: handler}
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return workspace
+}
+
+func TestClient(t *testing.T) {
+	var stopped bool
+	var restarted []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /check", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(internal.HeaderCheckVerdict, "error")
+		w.Header().Set(internal.HeaderCheckExitCode, "1")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("project=" + r.URL.Query().Get("project")))
+	})
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(Status{Runner: internal.RunnerStatus{State: "ready"}})
+	})
+	mux.HandleFunc("POST /restart", func(w http.ResponseWriter, r *http.Request) {
+		if project := r.URL.Query().Get("project"); project != "app" {
+			http.Error(w, "unknown project "+project, http.StatusNotFound)
+			return
+		}
+		restarted = append(restarted, "app")
+	})
+	mux.HandleFunc("POST /stop", func(w http.ResponseWriter, _ *http.Request) { stopped = true })
+	workspace := serve(t, mux)
+
+	c, err := Connect(workspace)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	ctx := context.Background()
+
+	resp, err := c.Check(ctx, CheckOptions{Project: "app"})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if resp.Output != "project=app" || resp.Verdict != SeverityError || resp.ExitCode != 1 {
+		t.Errorf("Check() = %+v, want app's output failing on errors with exit code 1", resp)
+	}
+
+	status, err := c.Status(ctx)
+	if err != nil || status.Runner.State != "ready" {
+		t.Errorf("Status() = %+v, %v; want ready", status, err)
+	}
+
+	if err := c.Restart(ctx, "app"); err != nil || len(restarted) != 1 {
+		t.Errorf("Restart(app) = %v, restarted %q; want app restarted", err, restarted)
+	}
+	if err := c.Restart(ctx, "docs"); err == nil || !strings.Contains(err.Error(), "unknown project docs") {
+		t.Errorf("Restart(docs) error = %v, want the server's message", err)
+	}
+
+	if err := c.Stop(ctx); err != nil || !stopped {
+		t.Errorf("Stop() = %v, stopped %v; want the daemon stopped", err, stopped)
+	}
+}
+
+func TestClient_CheckTyped(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /check", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") != "json" {
+			http.Error(w, "want format=json", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set(internal.HeaderCheckVerdict, "error")
+		w.Header().Set(internal.HeaderCheckExitCode, "1")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"schemaVersion":2,"timestamp":1700000000000,"fileCount":3,"errorCount":1,` +
+			`"diagnostics":[{"type":"ERROR","filename":"src/a.ts","message":"Type mismatch"}]}`))
+	})
+	c, err := Connect(serve(t, mux))
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	resp, err := c.CheckTyped(context.Background(), CheckOptions{Format: "human"})
+	if err != nil {
+		t.Fatalf("CheckTyped failed: %v", err)
+	}
+	if resp.Verdict != SeverityError || resp.ExitCode != 1 {
+		t.Errorf("CheckTyped() verdict = %q, exit code %d; want error, 1", resp.Verdict, resp.ExitCode)
+	}
+	result := resp.Result
+	if result.FileCount != 3 || result.ErrorCount != 1 || len(result.Diagnostics) != 1 || result.Diagnostics[0].Message != "Type mismatch" {
+		t.Errorf("CheckTyped() result = %+v, want 1 error in 3 files", result)
+	}
+}
+
+func TestConnect_StrictVersion(t *testing.T) {
+	// A daemon that predates GET /version.
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, _ *http.Request) {})
+	workspace := serve(t, mux)
+
+	if _, err := Connect(workspace); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	_, err := Connect(workspace, WithStrictVersion())
+	var mismatch *VersionMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Errorf("Connect(WithStrictVersion) = %v, want a VersionMismatchError", err)
+	}
+}
@@ -0,0 +1,212 @@
+package scstest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/tylergannon/svelte-check-server/internal"
+	"github.com/tylergannon/svelte-check-server/pkg/client"
+	"github.com/tylergannon/svelte-check-server/pkg/types"
+)
+
+// =============================================================================
+// Fake Client
+// =============================================================================
+
+// FakeClient has the methods of client.Client but answers from the results
+// and status the test sets, without a daemon or socket. Like FakeServer, it
+// applies the default check policy, and it ignores project names.
+type FakeClient struct {
+	workspace string
+	results   *results
+
+	mu       sync.Mutex
+	status   client.Status
+	err      error
+	restarts []string
+	stopped  bool
+}
+
+// NewFakeClient returns a FakeClient for workspace. Until SetResult is
+// called, checks wait as they would for a first check in progress.
+func NewFakeClient(workspace string) *FakeClient {
+	return &FakeClient{workspace: workspace, results: newResults()}
+}
+
+// SetResult completes a check with result, as FakeServer.SetResult does.
+func (c *FakeClient) SetResult(result CheckResult) {
+	c.results.set(result)
+}
+
+// SetStatus sets the status Status returns.
+func (c *FakeClient) SetStatus(status client.Status) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status = status
+}
+
+// SetError makes every method that can fail return err, e.g.
+// client.ErrNotRunning; nil clears it.
+func (c *FakeClient) SetError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+// Restarts returns the project of each Restart call, oldest first.
+func (c *FakeClient) Restarts() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.restarts...)
+}
+
+// Stopped reports whether Stop was called.
+func (c *FakeClient) Stopped() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopped
+}
+
+func (c *FakeClient) failure() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Workspace returns the workspace given to NewFakeClient.
+func (c *FakeClient) Workspace() string {
+	return c.workspace
+}
+
+// SocketPath returns "", as there is no socket.
+func (c *FakeClient) SocketPath() string {
+	return ""
+}
+
+// Check returns the latest result, formatted and judged as the daemon
+// would, waiting for the first unless opts.AllowStale is set and there is
+// one.
+func (c *FakeClient) Check(ctx context.Context, opts client.CheckOptions) (client.CheckResponse, error) {
+	result, err := c.latest(ctx, opts.AllowStale)
+	if err != nil {
+		return client.CheckResponse{}, err
+	}
+	result = opts.Filter.Apply(result, identity)
+	result.SchemaVersion = types.SchemaVersion
+
+	policy := internal.DefaultCheckPolicy
+	verdict := policy.Verdict(result)
+	output := internal.FormatHuman(result)
+	if opts.Format == "json" {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return client.CheckResponse{}, err
+		}
+		output = string(data) + "\n"
+	}
+	return client.CheckResponse{Output: output, Verdict: verdict, ExitCode: policy.ExitCode(verdict)}, nil
+}
+
+// CheckTyped is like Check but returns the result itself.
+func (c *FakeClient) CheckTyped(ctx context.Context, opts client.CheckOptions) (client.TypedCheckResponse, error) {
+	result, err := c.latest(ctx, opts.AllowStale)
+	if err != nil {
+		return client.TypedCheckResponse{}, err
+	}
+	result = opts.Filter.Apply(result, identity)
+	result.SchemaVersion = types.SchemaVersion
+
+	policy := internal.DefaultCheckPolicy
+	verdict := policy.Verdict(result)
+	return client.TypedCheckResponse{Result: result, Verdict: verdict, ExitCode: policy.ExitCode(verdict)}, nil
+}
+
+// latest returns the latest result, or waits for the first.
+func (c *FakeClient) latest(ctx context.Context, allowStale bool) (CheckResult, error) {
+	if err := c.failure(); err != nil {
+		return CheckResult{}, err
+	}
+	if result, ok, _ := c.results.peek(); ok && allowStale {
+		return result, nil
+	}
+	return c.results.wait(ctx)
+}
+
+func identity(result CheckResult) CheckResult { return result }
+
+// Diagnostics returns the diagnostics of the latest result that pass f, and
+// their counts.
+func (c *FakeClient) Diagnostics(ctx context.Context, f client.Filter) ([]client.Diagnostic, client.Summary, error) {
+	result, err := c.latest(ctx, false)
+	if err != nil {
+		return nil, client.Summary{}, err
+	}
+	result = f.Apply(result, identity)
+	return result.Diagnostics, client.Summary{
+		FileCount:         result.FileCount,
+		ErrorCount:        result.ErrorCount,
+		WarningCount:      result.WarningCount,
+		FilesWithProblems: result.FilesWithProblems,
+	}, nil
+}
+
+// Subscribe delivers the latest result and each one set after it. The
+// channel is closed when ctx is done.
+func (c *FakeClient) Subscribe(ctx context.Context) (<-chan CheckResult, error) {
+	if err := c.failure(); err != nil {
+		return nil, err
+	}
+	return c.results.subscribe(ctx), nil
+}
+
+// SubscribeProject is Subscribe; the project is ignored.
+func (c *FakeClient) SubscribeProject(ctx context.Context, _ string) (<-chan CheckResult, error) {
+	return c.Subscribe(ctx)
+}
+
+// WaitForClean waits until a result passes the check policy and returns it.
+// If ctx is done first, it returns the last result seen and ctx's error.
+func (c *FakeClient) WaitForClean(ctx context.Context) (CheckResult, error) {
+	results, err := c.Subscribe(ctx)
+	if err != nil {
+		return CheckResult{}, err
+	}
+	var last CheckResult
+	for result := range results {
+		if internal.DefaultCheckPolicy.Verdict(result) == "" {
+			return result, nil
+		}
+		last = result
+	}
+	return last, ctx.Err()
+}
+
+// Status returns the status set with SetStatus.
+func (c *FakeClient) Status(ctx context.Context) (client.Status, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status, c.err
+}
+
+// Restart records a restart of project; see Restarts.
+func (c *FakeClient) Restart(ctx context.Context, project string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.restarts = append(c.restarts, project)
+	return nil
+}
+
+// Stop records that Stop was called; see Stopped.
+func (c *FakeClient) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.stopped = true
+	return nil
+}
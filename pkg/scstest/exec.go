@@ -0,0 +1,265 @@
+package scstest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	kexec "k8s.io/utils/exec"
+)
+
+// =============================================================================
+// Fake Executor
+// =============================================================================
+
+// FakeCmd implements exec.Cmd from k8s.io/utils/exec with canned output.
+// Like a real svelte-check --watch process, Wait blocks until the command
+// is stopped, or until Exit simulates the process dying on its own.
+type FakeCmd struct {
+	stdout io.Reader
+	stderr io.Reader
+
+	mu       sync.Mutex
+	dir      string
+	env      []string
+	started  bool
+	stopped  bool
+	exited   chan struct{}
+	waitErr  error
+	stdoutW  io.Writer
+	stderrW  io.Writer
+	copyDone chan struct{}
+}
+
+// NewFakeCmd returns a command that writes stdout and stderr once started.
+func NewFakeCmd(stdout, stderr string) *FakeCmd {
+	return &FakeCmd{
+		stdout: strings.NewReader(stdout),
+		stderr: strings.NewReader(stderr),
+		exited: make(chan struct{}),
+	}
+}
+
+// Exit simulates the process exiting with err, which Wait returns. Safe to
+// call more than once; only the first call has an effect.
+func (c *FakeCmd) Exit(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.exited:
+	default:
+		c.waitErr = err
+		close(c.exited)
+	}
+}
+
+// Started reports whether the command was started.
+func (c *FakeCmd) Started() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.started
+}
+
+// Stopped reports whether Stop was called.
+func (c *FakeCmd) Stopped() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopped
+}
+
+// Dir returns the working directory set with SetDir.
+func (c *FakeCmd) Dir() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dir
+}
+
+// Env returns the environment set with SetEnv; nil means the command
+// inherits the caller's.
+func (c *FakeCmd) Env() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.env
+}
+
+func (c *FakeCmd) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started = true
+
+	// Emulate os/exec copying output into writers set via SetStdout/SetStderr.
+	if c.stdoutW != nil || c.stderrW != nil {
+		done := make(chan struct{})
+		c.copyDone = done
+		go func() {
+			defer close(done)
+			if c.stdoutW != nil {
+				_, _ = io.Copy(c.stdoutW, c.stdout)
+			}
+			if c.stderrW != nil {
+				_, _ = io.Copy(c.stderrW, c.stderr)
+			}
+		}()
+	}
+	return nil
+}
+
+// Wait blocks until the process exits and, as with os/exec, until output
+// sent to writers from SetStdout/SetStderr has been fully copied.
+func (c *FakeCmd) Wait() error {
+	<-c.exited
+	c.mu.Lock()
+	copyDone, err := c.copyDone, c.waitErr
+	c.mu.Unlock()
+	if copyDone != nil {
+		<-copyDone
+	}
+	return err
+}
+
+func (c *FakeCmd) Stop() {
+	c.mu.Lock()
+	c.stopped = true
+	c.mu.Unlock()
+	c.Exit(nil)
+}
+
+// Run starts the command and returns at once with the error set by Exit, as
+// a one-shot command would.
+func (c *FakeCmd) Run() error {
+	_, err := c.Output()
+	return err
+}
+
+// Output returns the canned stdout and the error set with Exit.
+func (c *FakeCmd) Output() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started = true
+	out, err := io.ReadAll(c.stdout)
+	if err != nil {
+		return nil, err
+	}
+	return out, c.waitErr
+}
+
+// CombinedOutput returns the canned stdout followed by stderr, and the error
+// set with Exit.
+func (c *FakeCmd) CombinedOutput() ([]byte, error) {
+	out, err := c.Output()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stderr, _ := io.ReadAll(c.stderr)
+	return append(out, stderr...), err
+}
+
+func (c *FakeCmd) Pid() int { return 0 }
+
+func (c *FakeCmd) SetDir(dir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dir = dir
+}
+
+func (c *FakeCmd) SetEnv(env []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.env = env
+}
+
+func (c *FakeCmd) SetStdin(in io.Reader)              {}
+func (c *FakeCmd) SetStdout(out io.Writer)            { c.stdoutW = out }
+func (c *FakeCmd) SetStderr(out io.Writer)            { c.stderrW = out }
+func (c *FakeCmd) StdoutPipe() (io.ReadCloser, error) { return io.NopCloser(c.stdout), nil }
+func (c *FakeCmd) StderrPipe() (io.ReadCloser, error) { return io.NopCloser(c.stderr), nil }
+
+func (c *FakeCmd) SetTerminateGracePeriod(_ time.Duration)              {}
+func (c *FakeCmd) SetTerminateGracePeriodWithContext(_ context.Context) {}
+func (c *FakeCmd) SetTerminateGracePeriodWithTimer(_ *time.Timer)       {}
+func (c *FakeCmd) SetTerminateGracePeriodWithoutKilling()               {}
+
+// FakeExecutor implements exec.Interface from k8s.io/utils/exec, for
+// server.Options.Executor. Each command it is asked for is a new FakeCmd with
+// the same canned output, unless NewCmd says otherwise.
+type FakeExecutor struct {
+	// NewCmd, if set, creates the command for each invocation, e.g. to give
+	// git and svelte-check different output.
+	NewCmd func(name string, args ...string) *FakeCmd
+
+	stdout, stderr string
+
+	mu    sync.Mutex
+	cmds  []*FakeCmd
+	lines []string
+}
+
+// NewFakeExecutor returns an executor whose commands write stdout and stderr.
+// WatchOutput renders check results as svelte-check would.
+func NewFakeExecutor(stdout, stderr string) *FakeExecutor {
+	return &FakeExecutor{stdout: stdout, stderr: stderr}
+}
+
+func (e *FakeExecutor) Command(name string, args ...string) kexec.Cmd {
+	var cmd *FakeCmd
+	if e.NewCmd != nil {
+		cmd = e.NewCmd(name, args...)
+	} else {
+		cmd = NewFakeCmd(e.stdout, e.stderr)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cmds = append(e.cmds, cmd)
+	e.lines = append(e.lines, strings.Join(append([]string{name}, args...), " "))
+	return cmd
+}
+
+func (e *FakeExecutor) CommandContext(_ context.Context, name string, args ...string) kexec.Cmd {
+	return e.Command(name, args...)
+}
+
+func (e *FakeExecutor) LookPath(file string) (string, error) {
+	return file, nil
+}
+
+// Commands returns the command lines requested so far, oldest first, with
+// arguments joined by spaces.
+func (e *FakeExecutor) Commands() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.lines...)
+}
+
+// Cmds returns the commands handed out so far, oldest first, in the order of
+// Commands.
+func (e *FakeExecutor) Cmds() []*FakeCmd {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*FakeCmd(nil), e.cmds...)
+}
+
+// WatchOutput renders results as the machine-verbose output of svelte-check
+// --watch, one check cycle per result, for NewFakeExecutor. Results without
+// a Timestamp are given increasing ones.
+func WatchOutput(workspace string, results ...CheckResult) string {
+	var b bytes.Buffer
+	ts := time.Now().UnixMilli()
+	for _, r := range results {
+		if r.Timestamp != 0 {
+			ts = r.Timestamp
+		}
+		fmt.Fprintf(&b, "%d START %q\n", ts, workspace)
+		for _, d := range r.Diagnostics {
+			data, _ := json.Marshal(d)
+			fmt.Fprintf(&b, "%d %s\n", ts, data)
+		}
+		fmt.Fprintf(&b, "%d COMPLETED %d FILES %d ERRORS %d WARNINGS %d FILES_WITH_PROBLEMS\n",
+			ts, r.FileCount, r.ErrorCount, r.WarningCount, r.FilesWithProblems)
+		ts++
+	}
+	return b.String()
+}
@@ -0,0 +1,104 @@
+// Package scstest provides test doubles for Go programs that use
+// svelte-check-server, so their tests run without bun, svelte-check, or a
+// real daemon:
+//
+//   - FakeServer serves the daemon's API on a temporary socket with results
+//     the test sets, for code that uses pkg/client.
+//   - FakeClient has the methods of pkg/client's Client without any socket,
+//     for code that takes an interface of the ones it uses.
+//   - FakeExecutor and FakeFSWatcher stand in for processes and the
+//     filesystem in a daemon embedded with pkg/server.
+//
+// The package follows semantic versioning, like pkg/client.
+package scstest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tylergannon/svelte-check-server/pkg/client"
+)
+
+// CheckResult is a completed check, as served by the daemon.
+type CheckResult = client.CheckResult
+
+// =============================================================================
+// Results
+// =============================================================================
+
+// results holds the latest result a test has set, for FakeServer and
+// FakeClient.
+type results struct {
+	mu      sync.Mutex
+	result  CheckResult
+	ok      bool          // a result has been set
+	changed chan struct{} // closed and replaced by each set
+}
+
+func newResults() *results {
+	return &results{changed: make(chan struct{})}
+}
+
+// set publishes result, giving it a Timestamp later than the previous
+// result's if it has none, so that subscribers see it as new.
+func (r *results) set(result CheckResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if result.Timestamp == 0 {
+		result.Timestamp = max(time.Now().UnixMilli(), r.result.Timestamp+1)
+	}
+	r.result, r.ok = result, true
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// peek returns the latest result, whether there is one, and a channel closed
+// when it changes.
+func (r *results) peek() (CheckResult, bool, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.result, r.ok, r.changed
+}
+
+// wait returns the latest result, waiting for the first to be set.
+func (r *results) wait(ctx context.Context) (CheckResult, error) {
+	for {
+		result, ok, changed := r.peek()
+		if ok {
+			return result, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return CheckResult{}, ctx.Err()
+		}
+	}
+}
+
+// subscribe delivers the latest result and each one set after it until ctx
+// is done, then closes the channel.
+func (r *results) subscribe(ctx context.Context) <-chan CheckResult {
+	ch := make(chan CheckResult, 1)
+	go func() {
+		defer close(ch)
+		var last int64 = -1
+		for {
+			result, ok, changed := r.peek()
+			if ok && result.Timestamp != last {
+				last = result.Timestamp
+				select {
+				case ch <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,153 @@
+package scstest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tylergannon/svelte-check-server/pkg/client"
+	"github.com/tylergannon/svelte-check-server/pkg/server"
+	"github.com/tylergannon/svelte-check-server/pkg/types"
+)
+
+// checker is the part of client.Client a tool might depend on.
+type checker interface {
+	Check(ctx context.Context, opts client.CheckOptions) (client.CheckResponse, error)
+	CheckTyped(ctx context.Context, opts client.CheckOptions) (client.TypedCheckResponse, error)
+	Diagnostics(ctx context.Context, f client.Filter) ([]client.Diagnostic, client.Summary, error)
+	WaitForClean(ctx context.Context) (client.CheckResult, error)
+	Restart(ctx context.Context, project string) error
+}
+
+var (
+	_ checker = (*client.Client)(nil)
+	_ checker = (*FakeClient)(nil)
+)
+
+var (
+	failing = CheckResult{
+		Diagnostics: []types.Diagnostic{{Type: "ERROR", Filename: "src/lib/a.ts", Message: "Type mismatch", Code: float64(2322)}},
+		FileCount:   10, ErrorCount: 1, FilesWithProblems: 1,
+	}
+	clean = CheckResult{FileCount: 10}
+)
+
+// testChecker runs the checks common to FakeServer's client and FakeClient.
+func testChecker(t *testing.T, c checker, setResult func(CheckResult)) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	short, cancelShort := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelShort()
+	if _, err := c.Check(short, client.CheckOptions{}); err == nil {
+		t.Error("Check before any result succeeded, want it to wait")
+	}
+
+	setResult(failing)
+	resp, err := c.Check(ctx, client.CheckOptions{})
+	if err != nil || resp.ExitCode != 1 || !strings.Contains(resp.Output, "Type mismatch") {
+		t.Errorf("Check() = %+v, %v; want the failing result", resp, err)
+	}
+	typed, err := c.CheckTyped(ctx, client.CheckOptions{})
+	if err != nil || typed.ExitCode != 1 || typed.Result.ErrorCount != 1 || typed.Result.SchemaVersion != types.SchemaVersion {
+		t.Errorf("CheckTyped() = %+v, %v; want the failing result", typed, err)
+	}
+	diags, summary, err := c.Diagnostics(ctx, client.Filter{Glob: "src/routes/**"})
+	if err != nil || len(diags) != 0 || summary.FileCount != 10 {
+		t.Errorf("Diagnostics(src/routes) = %v, %+v, %v; want none of 10 files", diags, summary, err)
+	}
+
+	done := make(chan CheckResult)
+	go func() {
+		result, _ := c.WaitForClean(ctx)
+		done <- result
+	}()
+	time.Sleep(50 * time.Millisecond)
+	setResult(clean)
+	if result := <-done; result.ErrorCount != 0 || result.FileCount != 10 {
+		t.Errorf("WaitForClean() = %+v, want the clean result", result)
+	}
+
+	if err := c.Restart(ctx, ""); err != nil {
+		t.Errorf("Restart failed: %v", err)
+	}
+}
+
+func TestFakeServer(t *testing.T) {
+	s := NewFakeServer(t)
+	c := s.Client()
+	if c.Workspace() != s.Workspace() {
+		t.Errorf("Workspace() = %s, want %s", c.Workspace(), s.Workspace())
+	}
+	testChecker(t, c, s.SetResult)
+	if s.Restarts() != 1 {
+		t.Errorf("Restarts() = %d, want 1", s.Restarts())
+	}
+
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	select {
+	case <-s.StopRequested():
+	case <-time.After(time.Second):
+		t.Error("StopRequested not closed after Stop")
+	}
+}
+
+func TestFakeClient(t *testing.T) {
+	c := NewFakeClient("/workspace")
+	testChecker(t, c, c.SetResult)
+	if restarts := c.Restarts(); !slices.Equal(restarts, []string{""}) {
+		t.Errorf("Restarts() = %q, want one of every project", restarts)
+	}
+
+	c.SetError(client.ErrNotRunning)
+	if _, err := c.Check(context.Background(), client.CheckOptions{}); !errors.Is(err, client.ErrNotRunning) {
+		t.Errorf("Check() error = %v, want ErrNotRunning", err)
+	}
+}
+
+// TestFakeExecutor tests embedding a daemon whose svelte-check and
+// filesystem are fakes.
+func TestFakeExecutor(t *testing.T) {
+	// The socket is named after the workspace, so keep its path short.
+	workspace, err := os.MkdirTemp("", "scs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(workspace) })
+	config := `{"syncOnStart": false, "monitorInterval": "0"}`
+	if err := os.WriteFile(filepath.Join(workspace, ".svelte-check-server.json"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	executor := NewFakeExecutor(WatchOutput(workspace, failing), "")
+	fsWatcher := NewFakeFSWatcher()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h, err := server.Run(ctx, server.Options{Workspace: workspace, Executor: executor, FSWatcher: fsWatcher})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	defer func() { _ = h.Stop(context.Background()) }()
+
+	c, err := client.Connect(workspace)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	diags, _, err := c.Diagnostics(ctx, client.Filter{})
+	if err != nil || len(diags) != 1 || diags[0].Message != "Type mismatch" {
+		t.Errorf("Diagnostics() = %+v, %v; want the error from WatchOutput", diags, err)
+	}
+	if !slices.ContainsFunc(executor.Commands(), func(line string) bool { return strings.Contains(line, "svelte-check --watch") }) {
+		t.Errorf("Commands() = %q, want svelte-check --watch", executor.Commands())
+	}
+	if !slices.Contains(fsWatcher.Paths(), workspace) {
+		t.Errorf("Paths() = %q, want the workspace", fsWatcher.Paths())
+	}
+}
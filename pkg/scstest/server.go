@@ -0,0 +1,136 @@
+package scstest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tylergannon/svelte-check-server/internal"
+	"github.com/tylergannon/svelte-check-server/pkg/client"
+)
+
+// =============================================================================
+// Fake Server
+// =============================================================================
+
+// FakeServer serves the daemon's HTTP API on a Unix socket, with the same
+// handlers as a real daemon, but with results the test sets in place of
+// svelte-check's. It serves a single unnamed project under the default
+// check policy: a result with errors fails.
+type FakeServer struct {
+	t         testing.TB
+	workspace string
+	srv       *internal.Server
+	checker   *fakeChecker
+}
+
+// fakeServerStopTimeout bounds how long stopping a FakeServer may take.
+const fakeServerStopTimeout = 5 * time.Second
+
+// NewFakeServer starts a FakeServer for a new, empty workspace directory and
+// stops it when the test ends. Until SetResult is called, checks wait as
+// they would for a first check in progress.
+func NewFakeServer(t testing.TB) *FakeServer {
+	t.Helper()
+	// Socket paths are limited to about 100 bytes, too few for t.TempDir.
+	workspace, err := os.MkdirTemp("", "scstest")
+	if err != nil {
+		t.Fatalf("scstest: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(workspace) })
+
+	checker := &fakeChecker{results: newResults()}
+	srv := internal.NewServer(filepath.Join(workspace, "server.sock"), checker)
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatalf("scstest: starting server: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), fakeServerStopTimeout)
+		defer cancel()
+		_ = srv.Stop(ctx)
+	})
+	return &FakeServer{t: t, workspace: workspace, srv: srv, checker: checker}
+}
+
+// SetResult completes a check with result. Clients waiting for a check
+// receive it, and subscribers are sent it. A result without a Timestamp is
+// given one.
+func (s *FakeServer) SetResult(result CheckResult) {
+	s.checker.results.set(result)
+}
+
+// Client returns a client connected to the server, failing the test if it
+// cannot connect.
+func (s *FakeServer) Client(opts ...client.Option) *client.Client {
+	s.t.Helper()
+	c, err := client.Connect(s.workspace, append([]client.Option{client.WithSocket(s.SocketPath())}, opts...)...)
+	if err != nil {
+		s.t.Fatalf("scstest: connecting to server: %v", err)
+	}
+	return c
+}
+
+// Workspace returns the server's workspace directory.
+func (s *FakeServer) Workspace() string {
+	return s.workspace
+}
+
+// SocketPath returns the path of the server's socket.
+func (s *FakeServer) SocketPath() string {
+	return s.srv.SocketPath()
+}
+
+// Restarts returns how many restarts clients have requested.
+func (s *FakeServer) Restarts() int {
+	s.checker.mu.Lock()
+	defer s.checker.mu.Unlock()
+	return s.checker.restarts
+}
+
+// StopRequested returns a channel that is closed when a client requests
+// POST /stop. The server keeps serving until the test ends.
+func (s *FakeServer) StopRequested() <-chan struct{} {
+	return s.srv.ShutdownCh()
+}
+
+// fakeChecker is the checker behind a FakeServer, serving the results the
+// test sets.
+type fakeChecker struct {
+	results *results
+
+	mu       sync.Mutex
+	restarts int
+}
+
+func (c *fakeChecker) Name() string                    { return string(internal.CheckerSvelteCheck) }
+func (c *fakeChecker) Start(ctx context.Context) error { return nil }
+func (c *fakeChecker) Stop()                           {}
+func (c *fakeChecker) LastCrash() *internal.CrashReport {
+	return nil
+}
+
+func (c *fakeChecker) Restart(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.restarts++
+	return nil
+}
+
+func (c *fakeChecker) GetLatestEvent(ctx context.Context) (CheckResult, error) {
+	return c.results.wait(ctx)
+}
+
+func (c *fakeChecker) PeekLatest() (CheckResult, bool) {
+	result, ok, _ := c.results.peek()
+	return result, ok
+}
+
+func (c *fakeChecker) Status() internal.RunnerStatus {
+	if _, ok := c.PeekLatest(); !ok {
+		return internal.RunnerStatus{State: internal.RunnerStateChecking}
+	}
+	return internal.RunnerStatus{State: internal.RunnerStateReady}
+}
@@ -0,0 +1,83 @@
+package scstest
+
+import (
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// =============================================================================
+// Fake Filesystem Watcher
+// =============================================================================
+
+// FakeFSWatcher is a filesystem watcher for server.Options.FSWatcher that
+// reports only the events a test sends.
+type FakeFSWatcher struct {
+	events chan fsnotify.Event
+	errors chan error
+
+	mu      sync.Mutex
+	paths   []string
+	rescans int
+	closed  bool
+}
+
+// NewFakeFSWatcher returns a watcher with no events pending.
+func NewFakeFSWatcher() *FakeFSWatcher {
+	return &FakeFSWatcher{
+		events: make(chan fsnotify.Event),
+		errors: make(chan error),
+	}
+}
+
+// Send delivers event to the daemon, blocking until it is received. Events
+// within 20ms of each other are handled as one batch.
+func (f *FakeFSWatcher) Send(event fsnotify.Event) {
+	f.events <- event
+}
+
+// SendError delivers a watcher error to the daemon.
+func (f *FakeFSWatcher) SendError(err error) {
+	f.errors <- err
+}
+
+// Paths returns the absolute paths the daemon asked to watch.
+func (f *FakeFSWatcher) Paths() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.paths...)
+}
+
+// Rescans returns how many times the daemon asked for a rescan.
+func (f *FakeFSWatcher) Rescans() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rescans
+}
+
+func (f *FakeFSWatcher) Events() <-chan fsnotify.Event { return f.events }
+func (f *FakeFSWatcher) Errors() <-chan error          { return f.errors }
+
+func (f *FakeFSWatcher) Add(path string, recursive bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paths = append(f.paths, path)
+	return nil
+}
+
+func (f *FakeFSWatcher) Rescan() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rescans++
+	return nil
+}
+
+func (f *FakeFSWatcher) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.closed {
+		f.closed = true
+		close(f.events)
+	}
+	return nil
+}
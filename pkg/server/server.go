@@ -0,0 +1,57 @@
+// Package server runs a svelte-check-server daemon inside another Go
+// program, such as an integration test or an editor helper, wired exactly as
+// the start command wires it.
+//
+// The package follows semantic versioning, like pkg/client.
+package server
+
+import (
+	"context"
+
+	"github.com/tylergannon/svelte-check-server/internal"
+)
+
+// Options configures Run. Workspace is required; everything else comes from
+// the workspace's .svelte-check-server.json, as for the start command.
+type Options = internal.DaemonOptions
+
+// Status is the daemon's health as reported by GET /status.
+type Status = internal.Status
+
+// Handle controls a daemon started by Run.
+type Handle struct {
+	d *internal.Daemon
+}
+
+// Run starts a daemon for opts.Workspace and returns once its checkers have
+// started their first check. Clients reach it like one started with the
+// start command, e.g. with pkg/client's Connect. It runs until Stop is
+// called or ctx is done.
+func Run(ctx context.Context, opts Options) (*Handle, error) {
+	d, err := internal.RunDaemon(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Handle{d: d}, nil
+}
+
+// Stop shuts the daemon down and removes its socket.
+func (h *Handle) Stop(ctx context.Context) error {
+	return h.d.Stop(ctx)
+}
+
+// Status returns the daemon's health.
+func (h *Handle) Status() Status {
+	return h.d.Status()
+}
+
+// SocketPath returns the path of the socket the daemon serves on.
+func (h *Handle) SocketPath() string {
+	return h.d.SocketPath()
+}
+
+// Done returns a channel that is closed when a client asks the daemon to
+// stop. The daemon keeps running until Stop is called.
+func (h *Handle) Done() <-chan struct{} {
+	return h.d.Done()
+}
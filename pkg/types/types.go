@@ -0,0 +1,207 @@
+// Package types defines the JSON the svelte-check-server daemon serves, such
+// as GET /check?format=json results, for Go programs that consume it.
+//
+// The JSON field names and meanings are a stable schema: fields are only
+// added, and are removed or changed incompatibly only in a new major
+// version, which also increments SchemaVersion. Decode reads results of the
+// current and previous schema versions.
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// =============================================================================
+// Diagnostics
+// =============================================================================
+
+// Position represents a location in a file.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Diagnostic represents a single error or warning from svelte-check.
+// The Timestamp field is extracted from the machine-verbose output prefix
+// and added to the struct for clean JSONL output.
+type Diagnostic struct {
+	Timestamp int64    `json:"timestamp"`
+	Type      string   `json:"type"` // "ERROR" or "WARNING"
+	Filename  string   `json:"filename"`
+	Start     Position `json:"start"`
+	End       Position `json:"end"`
+	Message   string   `json:"message"`
+	Code      any      `json:"code"`              // int for TS errors, string for Svelte warnings
+	Source    string   `json:"source,omitempty"`  // "js", "ts", "svelte", "css", or empty
+	Project   string   `json:"project,omitempty"` // set in results merged from several projects
+	Checker   string   `json:"checker,omitempty"` // "svelte-check", "tsc", or "eslint" in merged results
+}
+
+// GitState is the branch and commit checked out in the workspace.
+type GitState struct {
+	Branch string `json:"branch,omitempty"` // empty for a detached HEAD
+	Commit string `json:"commit,omitempty"` // abbreviated
+}
+
+// String returns e.g. "main@1a2b3c4", or "1a2b3c4 (detached)".
+func (g GitState) String() string {
+	if g.Branch == "" {
+		return g.Commit + " (detached)"
+	}
+	if g.Commit == "" {
+		return g.Branch
+	}
+	return g.Branch + "@" + g.Commit
+}
+
+// =============================================================================
+// Events
+// =============================================================================
+
+// Event is an event of a checker's output stream or the daemon: one of
+// SvelteWatchCheckStart, SvelteWatchCheckComplete, SvelteWatchFailure, or
+// DependenciesChanged.
+type Event interface {
+	implementsEvent()
+}
+
+// SvelteWatchCheckStart is emitted when svelte-check begins a new check cycle.
+type SvelteWatchCheckStart struct {
+	Timestamp int64  `json:"timestamp"`
+	Workspace string `json:"workspace"`
+}
+
+func (SvelteWatchCheckStart) implementsEvent() {}
+
+// SvelteWatchCheckComplete is emitted when svelte-check finishes a check
+// cycle. It is also the result GET /check?format=json serves.
+type SvelteWatchCheckComplete struct {
+	// SchemaVersion, set by the server when serving the result, is the
+	// version of this schema it follows; see Decode.
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+
+	Timestamp         int64        `json:"timestamp"`
+	Diagnostics       []Diagnostic `json:"diagnostics"`
+	FileCount         int          `json:"fileCount"`
+	ErrorCount        int          `json:"errorCount"`
+	WarningCount      int          `json:"warningCount"`
+	FilesWithProblems int          `json:"filesWithProblems"`
+
+	// Failures holds the messages of FAILURE events reported during the
+	// cycle, e.g. a crashed language server.
+	Failures []string `json:"failures,omitempty"`
+
+	// Introduced and Resolved are the diagnostics that appeared and
+	// disappeared since the checker's previous result, or, for its first
+	// result, since the one persisted by the previous server. Both are empty
+	// when there is neither.
+	Introduced []Diagnostic `json:"introduced,omitempty"`
+	Resolved   []Diagnostic `json:"resolved,omitempty"`
+
+	// Baselined is how many diagnostics the server removed because they are
+	// listed in the workspace's baseline file.
+	Baselined int `json:"baselined,omitempty"`
+
+	// Suppressed is how many diagnostics the server removed because they
+	// match an ignore rule of the workspace config.
+	Suppressed int `json:"suppressed,omitempty"`
+
+	// Stale is set by the server when the result may be outdated, e.g.
+	// because svelte-kit sync failed and generated types were not updated.
+	Stale       bool   `json:"stale"`
+	StaleReason string `json:"staleReason,omitempty"`
+
+	// Freshness, set by the server when serving the result: when the check
+	// completed, how long ago that was, and whether a newer check is running.
+	CheckedAt  time.Time `json:"checkedAt,omitzero"`
+	AgeSeconds float64   `json:"ageSeconds"`
+	InProgress bool      `json:"inProgress"`
+
+	// Git, set by the server when serving the result, is the branch and
+	// commit checked out when the check completed, if known.
+	Git *GitState `json:"git,omitempty"`
+}
+
+func (SvelteWatchCheckComplete) implementsEvent() {}
+
+// SvelteWatchFailure is emitted when svelte-check encounters a runtime error.
+type SvelteWatchFailure struct {
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+func (SvelteWatchFailure) implementsEvent() {}
+
+// DependenciesChanged is sent to subscribers when a package manager lockfile
+// changes, e.g. after an install. Results checked before it may report
+// modules that are now installed as missing.
+type DependenciesChanged struct {
+	Timestamp int64  `json:"timestamp"`
+	Lockfile  string `json:"lockfile"` // workspace-relative path
+}
+
+func (DependenciesChanged) implementsEvent() {}
+
+// FileDiagnostics is pushed to a GET /events/files subscriber when the
+// diagnostics of one of its files change. Version counts the file's
+// notifications on the stream, from 1, so an editor can discard any that
+// arrive out of order.
+type FileDiagnostics struct {
+	File        string       `json:"file"` // workspace-relative path
+	Version     int          `json:"version"`
+	Cleared     bool         `json:"cleared,omitempty"` // the file has no diagnostics left
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Timestamp   int64        `json:"timestamp"` // of the result
+}
+
+// =============================================================================
+// Schema Versions
+// =============================================================================
+
+// SchemaVersion is the version of the result schema that the daemon serves
+// and Decode returns. Adding fields does not change it; it is incremented
+// when a field is removed or changes meaning, and Decode then converts
+// results of the previous version.
+//
+// Version 1 is the schema of daemons from before the schemaVersion field,
+// which served no version.
+const SchemaVersion = 2
+
+// Result is a check result as GET /check?format=json and GET /events serve
+// it.
+type Result = SvelteWatchCheckComplete
+
+// UnsupportedVersionError is returned by Decode for a result of a schema
+// newer than SchemaVersion, served by a daemon newer than the program
+// decoding it.
+type UnsupportedVersionError struct {
+	Version int
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("result schema version %d is newer than the supported version %d; upgrade svelte-check-server", e.Version, SchemaVersion)
+}
+
+// Decode parses a result of schema version 1 or SchemaVersion and returns
+// it in the current schema, with SchemaVersion set. Version 1 results lack
+// checkedAt; it is taken from timestamp, when the check completed.
+func Decode(data []byte) (Result, error) {
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return Result{}, err
+	}
+	switch version := result.SchemaVersion; {
+	case version == 0 || version == 1:
+		if result.CheckedAt.IsZero() && result.Timestamp != 0 {
+			result.CheckedAt = time.UnixMilli(result.Timestamp).UTC()
+		}
+	case version > SchemaVersion:
+		return Result{}, &UnsupportedVersionError{Version: version}
+	case version < 0:
+		return Result{}, fmt.Errorf("invalid result schema version %d", version)
+	}
+	result.SchemaVersion = SchemaVersion
+	return result, nil
+}
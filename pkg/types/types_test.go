@@ -0,0 +1,88 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// The JSON of a result is a stable schema: changing this test means
+// breaking consumers.
+func TestSvelteWatchCheckComplete_Schema(t *testing.T) {
+	result := SvelteWatchCheckComplete{
+		Timestamp: 1770310077701,
+		Diagnostics: []Diagnostic{{
+			Timestamp: 1770310077701,
+			Type:      "ERROR",
+			Filename:  "src/routes/+page.svelte",
+			Start:     Position{Line: 3, Character: 4},
+			End:       Position{Line: 3, Character: 9},
+			Message:   "Cannot find name 'foo'.",
+			Code:      2304,
+			Source:    "ts",
+		}},
+		FileCount:         12,
+		ErrorCount:        1,
+		FilesWithProblems: 1,
+		CheckedAt:         time.UnixMilli(1770310077701).UTC(),
+		AgeSeconds:        1.5,
+		Git:               &GitState{Branch: "main", Commit: "1a2b3c4"},
+	}
+	got, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"timestamp":1770310077701,"diagnostics":[{"timestamp":1770310077701,"type":"ERROR",` +
+		`"filename":"src/routes/+page.svelte","start":{"line":3,"character":4},"end":{"line":3,"character":9},` +
+		`"message":"Cannot find name 'foo'.","code":2304,"source":"ts"}],"fileCount":12,"errorCount":1,` +
+		`"warningCount":0,"filesWithProblems":1,"stale":false,"checkedAt":"2026-02-05T16:47:57.701Z",` +
+		`"ageSeconds":1.5,"inProgress":false,"git":{"branch":"main","commit":"1a2b3c4"}}`
+	if string(got) != want {
+		t.Errorf("JSON =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGitState_String(t *testing.T) {
+	for _, tt := range []struct {
+		state GitState
+		want  string
+	}{
+		{GitState{Branch: "main", Commit: "1a2b3c4"}, "main@1a2b3c4"},
+		{GitState{Commit: "1a2b3c4"}, "1a2b3c4 (detached)"},
+		{GitState{Branch: "main"}, "main"},
+	} {
+		if got := tt.state.String(); got != tt.want {
+			t.Errorf("%#v.String() = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	// A version 1 result, from before schemaVersion and checkedAt.
+	v1 := `{"timestamp":1770310077701,"diagnostics":[],"fileCount":12,"errorCount":0,"warningCount":0,"filesWithProblems":0,"stale":false}`
+	result, err := Decode([]byte(v1))
+	if err != nil {
+		t.Fatalf("Decode(v1) failed: %v", err)
+	}
+	if result.SchemaVersion != SchemaVersion || result.FileCount != 12 || !result.CheckedAt.Equal(time.UnixMilli(1770310077701)) {
+		t.Errorf("Decode(v1) = %+v, want it upgraded with checkedAt from timestamp", result)
+	}
+
+	checkedAt := time.UnixMilli(1770310080000).UTC()
+	data, err := json.Marshal(Result{SchemaVersion: SchemaVersion, Timestamp: 1770310077701, CheckedAt: checkedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := Decode(data); err != nil || !result.CheckedAt.Equal(checkedAt) {
+		t.Errorf("Decode(v%d) = %+v, %v; want it unchanged", SchemaVersion, result, err)
+	}
+
+	var unsupported *UnsupportedVersionError
+	if _, err := Decode([]byte(`{"schemaVersion":3,"timestamp":1}`)); !errors.As(err, &unsupported) || unsupported.Version != 3 {
+		t.Errorf("Decode(v3) error = %v, want an UnsupportedVersionError", err)
+	}
+	if _, err := Decode([]byte(`{"timestamp":`)); err == nil {
+		t.Error("Decode of invalid JSON succeeded")
+	}
+}